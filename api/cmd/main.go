@@ -16,13 +16,18 @@ import (
 	"github.com/streamspace/streamspace/api/internal/activity"
 	"github.com/streamspace/streamspace/api/internal/api"
 	"github.com/streamspace/streamspace/api/internal/auth"
+	"github.com/streamspace/streamspace/api/internal/bandwidth"
 	"github.com/streamspace/streamspace/api/internal/cache"
+	"github.com/streamspace/streamspace/api/internal/cost"
 	"github.com/streamspace/streamspace/api/internal/db"
 	"github.com/streamspace/streamspace/api/internal/events"
 	"github.com/streamspace/streamspace/api/internal/handlers"
 	"github.com/streamspace/streamspace/api/internal/k8s"
 	"github.com/streamspace/streamspace/api/internal/middleware"
+	"github.com/streamspace/streamspace/api/internal/plugins"
+	"github.com/streamspace/streamspace/api/internal/prewarm"
 	"github.com/streamspace/streamspace/api/internal/quota"
+	"github.com/streamspace/streamspace/api/internal/rightsize"
 	"github.com/streamspace/streamspace/api/internal/sync"
 	"github.com/streamspace/streamspace/api/internal/tracker"
 	internalWebsocket "github.com/streamspace/streamspace/api/internal/websocket"
@@ -86,6 +91,16 @@ func main() {
 	}
 	defer redisCache.Close()
 
+	// Share rate limits across API servers through Redis if requested
+	if getEnv("RATE_LIMIT_STORE", "memory") == "redis" {
+		if redisCache.IsEnabled() {
+			middleware.UseRateLimiterStore(middleware.NewRedisRateLimiterStore(redisCache.Client(), "ratelimit:"))
+			log.Println("Rate limits stored in Redis")
+		} else {
+			log.Println("Warning: RATE_LIMIT_STORE=redis requires Redis caching (CACHE_ENABLED=true), keeping rate limits in memory")
+		}
+	}
+
 	// Initialize Kubernetes client
 	log.Println("Initializing Kubernetes client...")
 	k8sClient, err := k8s.NewClient()
@@ -138,11 +153,9 @@ func main() {
 		}
 	}()
 
-	// Initialize connection tracker
-	log.Println("Starting connection tracker...")
+	// Initialize connection tracker (started once the WebSocket manager can
+	// count viewers)
 	connTracker := tracker.NewConnectionTracker(database, k8sClient, eventPublisher, platform)
-	go connTracker.Start()
-	defer connTracker.Stop()
 
 	// Initialize sync service
 	log.Println("Initializing repository sync service...")
@@ -168,10 +181,18 @@ func main() {
 	log.Println("Initializing WebSocket manager...")
 	wsManager := internalWebsocket.NewManager(database, k8sClient)
 	wsManager.Start()
+	eventSubscriber.SetSessionErrorNotifier(wsManager.GetNotifier())
+
+	// Start the connection tracker; it feeds each session's viewer count
+	log.Println("Starting connection tracker...")
+	connTracker.SetListener(wsManager.GetNotifier())
+	go connTracker.Start()
+	defer connTracker.Stop()
 
 	// Initialize activity tracker
 	log.Println("Initializing activity tracker...")
 	activityTracker := activity.NewTracker(k8sClient, eventPublisher, platform)
+	activityTracker.SetDatabase(database.DB())
 
 	// Start idle session monitor (check every 1 minute)
 	idleCheckInterval := getEnv("IDLE_CHECK_INTERVAL", "1m")
@@ -227,6 +248,15 @@ func main() {
 	auditLogger := middleware.NewAuditLogger(database, false) // Don't log request bodies by default
 	router.Use(auditLogger.Middleware())
 
+	// SECURITY: Record rejected WebSocket origins as audited security alerts
+	handlers.InitOriginRejectionReporter(database)
+
+	// SECURITY: Warn when a release build accepts unconfigured WebSocket origins
+	middleware.WarnWebSocketOriginPolicy(gin.Mode() == gin.ReleaseMode)
+
+	// SECURITY: Require a fresh MFA check for destructive operations (opt-in)
+	middleware.InitMFAStepUp(database)
+
 	// Add gzip compression (exclude WebSocket, auth, and metrics endpoints)
 	router.Use(middleware.GzipWithExclusions(
 		middleware.BestSpeed, // Use best speed for balance of compression vs CPU
@@ -247,6 +277,9 @@ func main() {
 	// Initialize quota enforcer
 	quotaEnforcer := quota.NewEnforcer(userDB, groupDB)
 
+	// Per-user limit on session creates/terminates (quotas only cap concurrency)
+	sessionRateLimit := middleware.SessionRateLimitFromEnv()
+
 	// Initialize JWT manager for authentication
 	// SECURITY: JWT_SECRET must be set in production - no fallback allowed
 	jwtSecret := os.Getenv("JWT_SECRET")
@@ -279,6 +312,13 @@ func main() {
 		clearCancel()
 	}
 
+	// Token validation backend: StreamSpace JWTs, optionally also the ID
+	// tokens of a corporate identity provider (AUTH_PROVIDER=oidc)
+	authProvider, err := auth.ProviderFromEnv(context.Background(), jwtManager, userDB)
+	if err != nil {
+		log.Fatalf("Failed to initialize authentication provider: %v", err)
+	}
+
 	// Initialize SAML authentication (optional)
 	var samlAuth *auth.SAMLAuthenticator
 	samlEnabled := os.Getenv("SAML_ENABLED")
@@ -297,7 +337,52 @@ func main() {
 
 	// Initialize API handlers
 	apiHandler := api.NewHandler(database, k8sClient, eventPublisher, connTracker, syncService, wsManager, quotaEnforcer, platform)
+	apiHandler.SetEventSubscriber(eventSubscriber)
+
+	// Plugin runtime: loads the enabled plugins so their BeforeSessionCreate
+	// hooks can veto or adjust session launches
+	pluginRuntime := plugins.NewRuntimeV2(database, pluginDir)
+	if err := pluginRuntime.Start(context.Background()); err != nil {
+		log.Fatalf("Failed to start plugin runtime: %v", err)
+	}
+	apiHandler.SetSessionHooks(pluginRuntime)
+	apiHandler.SetQuotaWarningNotifier(handlers.BroadcastQuotaWarning)
+
+	// Session right-sizing: sample pod usage from metrics-server and
+	// recommend requests/limits from it (Kubernetes only)
+	var cancelRightsize context.CancelFunc = func() {}
+	if platform == events.PlatformKubernetes {
+		rightsizer := rightsize.NewRecommender(database, rightsize.ConfigFromEnv())
+		apiHandler.SetRightsizeRecommender(rightsizer)
+
+		sampler := rightsize.NewSampler(database, rightsize.NewPodMetricsSource(k8sClient, getEnv("NAMESPACE", "streamspace")), rightsizer)
+		sampler.SetApplier(apiHandler.ApplyRightsizeRecommendation)
+
+		var rightsizeCtx context.Context
+		rightsizeCtx, cancelRightsize = context.WithCancel(context.Background())
+		go sampler.Start(rightsizeCtx)
+	}
+	defer cancelRightsize()
+
+	// Session pre-warming: wake hibernated sessions shortly before their
+	// users usually start them (opt-in per user)
+	prewarmPredictor := prewarm.NewPredictor(database, prewarm.ConfigFromEnv())
+	apiHandler.SetPrewarmPredictor(prewarmPredictor)
+	prewarmer := prewarm.NewPrewarmer(database, prewarmPredictor)
+	prewarmer.SetWaker(apiHandler.PrewarmSession)
+	prewarmCtx, cancelPrewarm := context.WithCancel(context.Background())
+	defer cancelPrewarm()
+	go prewarmer.Start(prewarmCtx)
+
+	// Bandwidth accounting for traffic through port-forward tunnels
+	bandwidthMeter := bandwidth.NewMeter(database)
+	apiHandler.SetBandwidthMeter(bandwidthMeter)
+	bandwidthCtx, cancelBandwidth := context.WithCancel(context.Background())
+	defer cancelBandwidth()
+	go bandwidthMeter.Start(bandwidthCtx)
+
 	userHandler := handlers.NewUserHandler(userDB, groupDB)
+	userHandler.SetSessionRateLimit(sessionRateLimit)
 	groupHandler := handlers.NewGroupHandler(groupDB, userDB)
 	authHandler := auth.NewAuthHandler(userDB, jwtManager, samlAuth)
 	activityHandler := handlers.NewActivityHandler(k8sClient, activityTracker)
@@ -315,13 +400,23 @@ func main() {
 	// NOTE: Session snapshots now handled by streamspace-snapshots plugin
 	sessionTemplatesHandler := handlers.NewSessionTemplatesHandler(database, k8sClient, eventPublisher, platform)
 	batchHandler := handlers.NewBatchHandler(database)
+	batchHandler.SetEventPublisher(eventPublisher, eventSubscriber.Controllers(), platform)
 	monitoringHandler := handlers.NewMonitoringHandler(database)
 	quotasHandler := handlers.NewQuotasHandler(database)
 	nodeHandler := handlers.NewNodeHandler(database, k8sClient, eventPublisher, platform)
+	rateLimitHandler := handlers.NewRateLimitHandler(middleware.GetRateLimiter())
+	costHandler := handlers.NewCostHandler(cost.NewAccountant(database, cost.PricesFromEnv()))
+	auditHandler := handlers.NewAuditHandler(database)
 	// NOTE: WebSocket routes now use wsManager directly (see ws.GET routes below)
 	consoleHandler := handlers.NewConsoleHandler(database)
 	collaborationHandler := handlers.NewCollaborationHandler(database)
+	presenceCtx, cancelPresence := context.WithCancel(context.Background())
+	defer cancelPresence()
+	go collaborationHandler.StartPresenceSweep(presenceCtx)
+	go collaborationHandler.StartCursorPersistence(presenceCtx)
+	go handlers.NewAnnotationReaper(database).Start(presenceCtx)
 	integrationsHandler := handlers.NewIntegrationsHandler(database)
+	eventSubscriber.SetLifecycleDispatcher(integrationsHandler)
 	loadBalancingHandler := handlers.NewLoadBalancingHandler(database)
 	schedulingHandler := handlers.NewSchedulingHandler(database)
 	securityHandler := handlers.NewSecurityHandler(database)
@@ -338,7 +433,7 @@ func main() {
 	}
 
 	// Setup routes
-	setupRoutes(router, apiHandler, userHandler, groupHandler, authHandler, activityHandler, catalogHandler, sharingHandler, pluginHandler, dashboardHandler, sessionActivityHandler, apiKeyHandler, teamHandler, preferencesHandler, notificationsHandler, searchHandler, sessionTemplatesHandler, batchHandler, monitoringHandler, quotasHandler, nodeHandler, wsManager, consoleHandler, collaborationHandler, integrationsHandler, loadBalancingHandler, schedulingHandler, securityHandler, templateVersioningHandler, setupHandler, applicationHandler, jwtManager, userDB, redisCache, webhookSecret)
+	setupRoutes(router, apiHandler, userHandler, groupHandler, authHandler, activityHandler, catalogHandler, sharingHandler, pluginHandler, dashboardHandler, sessionActivityHandler, apiKeyHandler, teamHandler, preferencesHandler, notificationsHandler, searchHandler, sessionTemplatesHandler, batchHandler, monitoringHandler, quotasHandler, nodeHandler, rateLimitHandler, costHandler, auditHandler, wsManager, consoleHandler, collaborationHandler, integrationsHandler, loadBalancingHandler, schedulingHandler, securityHandler, templateVersioningHandler, setupHandler, applicationHandler, authProvider, userDB, redisCache, webhookSecret, sessionRateLimit)
 
 	// Create HTTP server with security timeouts
 	srv := &http.Server{
@@ -390,8 +485,15 @@ func main() {
 		log.Println("HTTP server stopped gracefully")
 	}
 
-	// Close WebSocket connections
+	// Unload plugins
+	log.Println("Stopping plugin runtime...")
+	if err := pluginRuntime.Stop(ctx); err != nil {
+		log.Printf("Error stopping plugin runtime: %v", err)
+	}
+
+	// Close WebSocket connections, telling enterprise clients to reconnect
 	log.Println("Closing WebSocket connections...")
+	handlers.GetWebSocketHub().Shutdown(ctx)
 	if wsManager != nil {
 		wsManager.CloseAll()
 	}
@@ -419,9 +521,9 @@ func main() {
 	log.Println("Graceful shutdown completed")
 }
 
-func setupRoutes(router *gin.Engine, h *api.Handler, userHandler *handlers.UserHandler, groupHandler *handlers.GroupHandler, authHandler *auth.AuthHandler, activityHandler *handlers.ActivityHandler, catalogHandler *handlers.CatalogHandler, sharingHandler *handlers.SharingHandler, pluginHandler *handlers.PluginHandler, dashboardHandler *handlers.DashboardHandler, sessionActivityHandler *handlers.SessionActivityHandler, apiKeyHandler *handlers.APIKeyHandler, teamHandler *handlers.TeamHandler, preferencesHandler *handlers.PreferencesHandler, notificationsHandler *handlers.NotificationsHandler, searchHandler *handlers.SearchHandler, sessionTemplatesHandler *handlers.SessionTemplatesHandler, batchHandler *handlers.BatchHandler, monitoringHandler *handlers.MonitoringHandler, quotasHandler *handlers.QuotasHandler, nodeHandler *handlers.NodeHandler, wsManager *internalWebsocket.Manager, consoleHandler *handlers.ConsoleHandler, collaborationHandler *handlers.CollaborationHandler, integrationsHandler *handlers.IntegrationsHandler, loadBalancingHandler *handlers.LoadBalancingHandler, schedulingHandler *handlers.SchedulingHandler, securityHandler *handlers.SecurityHandler, templateVersioningHandler *handlers.TemplateVersioningHandler, setupHandler *handlers.SetupHandler, applicationHandler *handlers.ApplicationHandler, jwtManager *auth.JWTManager, userDB *db.UserDB, redisCache *cache.Cache, webhookSecret string) {
+func setupRoutes(router *gin.Engine, h *api.Handler, userHandler *handlers.UserHandler, groupHandler *handlers.GroupHandler, authHandler *auth.AuthHandler, activityHandler *handlers.ActivityHandler, catalogHandler *handlers.CatalogHandler, sharingHandler *handlers.SharingHandler, pluginHandler *handlers.PluginHandler, dashboardHandler *handlers.DashboardHandler, sessionActivityHandler *handlers.SessionActivityHandler, apiKeyHandler *handlers.APIKeyHandler, teamHandler *handlers.TeamHandler, preferencesHandler *handlers.PreferencesHandler, notificationsHandler *handlers.NotificationsHandler, searchHandler *handlers.SearchHandler, sessionTemplatesHandler *handlers.SessionTemplatesHandler, batchHandler *handlers.BatchHandler, monitoringHandler *handlers.MonitoringHandler, quotasHandler *handlers.QuotasHandler, nodeHandler *handlers.NodeHandler, rateLimitHandler *handlers.RateLimitHandler, costHandler *handlers.CostHandler, auditHandler *handlers.AuditHandler, wsManager *internalWebsocket.Manager, consoleHandler *handlers.ConsoleHandler, collaborationHandler *handlers.CollaborationHandler, integrationsHandler *handlers.IntegrationsHandler, loadBalancingHandler *handlers.LoadBalancingHandler, schedulingHandler *handlers.SchedulingHandler, securityHandler *handlers.SecurityHandler, templateVersioningHandler *handlers.TemplateVersioningHandler, setupHandler *handlers.SetupHandler, applicationHandler *handlers.ApplicationHandler, authProvider auth.AuthProvider, userDB *db.UserDB, redisCache *cache.Cache, webhookSecret string, sessionRateLimit middleware.SessionRateLimit) {
 	// SECURITY: Create authentication middleware
-	authMiddleware := auth.Middleware(jwtManager, userDB)
+	authMiddleware := auth.MiddlewareWithProvider(authProvider, userDB)
 	adminMiddleware := auth.RequireRole("admin")
 	operatorMiddleware := auth.RequireAnyRole("admin", "operator")
 
@@ -436,13 +538,19 @@ func setupRoutes(router *gin.Engine, h *api.Handler, userHandler *handlers.UserH
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
 		CheckOrigin: func(r *http.Request) bool {
-			// Allow all origins for development (should be restricted in production)
-			return true
+			// Allow all origins for development; strict mode
+			// (WEBSOCKET_ENFORCE_ORIGIN=strict) checks CORS_ALLOWED_ORIGINS
+			if !middleware.WebSocketOriginPolicyFromEnv().Strict {
+				return true
+			}
+			return handlers.CheckWebSocketOrigin(r)
 		},
 	}
 
 	// Health check (public - no auth required)
 	router.GET("/health", h.Health)
+	router.GET("/healthz", h.Healthz)
+	router.GET("/readyz", h.Readyz)
 	router.GET("/version", h.Version)
 
 	// API v1
@@ -465,14 +573,28 @@ func setupRoutes(router *gin.Engine, h *api.Handler, userHandler *handlers.UserH
 			{
 				// Cache session lists for 30 seconds (frequently changing)
 				sessions.GET("", cache.CacheMiddleware(redisCache, 30*time.Second), h.ListSessions)
-				sessions.POST("", cache.InvalidateCacheMiddleware(redisCache, cache.SessionPattern()), h.CreateSession)
+				sessions.POST("", middleware.SessionChurnLimit(sessionRateLimit), cache.InvalidateCacheMiddleware(redisCache, cache.SessionPattern()), h.CreateSession)
 				sessions.GET("/by-tags", cache.CacheMiddleware(redisCache, 30*time.Second), h.ListSessionsByTags)
+				sessions.GET("/prewarm", h.GetSessionPrewarm)
+				sessions.PUT("/prewarm", h.UpdateSessionPrewarm)
 				sessions.GET("/:id", cache.CacheMiddleware(redisCache, 30*time.Second), h.GetSession)
 				sessions.PATCH("/:id", cache.InvalidateCacheMiddleware(redisCache, cache.SessionPattern()), h.UpdateSession)
-				sessions.DELETE("/:id", cache.InvalidateCacheMiddleware(redisCache, cache.SessionPattern()), h.DeleteSession)
+				sessions.DELETE("/:id", middleware.RequireMFAStepUp(), middleware.SessionChurnLimit(sessionRateLimit), cache.InvalidateCacheMiddleware(redisCache, cache.SessionPattern()), h.DeleteSession)
 				sessions.PATCH("/:id/tags", cache.InvalidateCacheMiddleware(redisCache, cache.SessionPattern()), h.UpdateSessionTags)
 				sessions.GET("/:id/connect", h.ConnectSession)
 				sessions.POST("/:id/disconnect", h.DisconnectSession)
+				sessions.GET("/:id/viewers", h.GetSessionViewers)
+
+				// TCP tunnel to template-allowlisted ports (WebSocket upgrade)
+				sessions.GET("/:id/forward/:port", h.ForwardSessionPort)
+
+				// Home volume export/import as a tar archive (streamed)
+				sessions.POST("/:id/home/export", h.ExportSessionHome)
+				sessions.POST("/:id/home/import", h.ImportSessionHome)
+
+				// Resource right-sizing from observed usage
+				sessions.GET("/:id/rightsize", h.GetSessionRightsize)
+				sessions.POST("/:id/rightsize/apply", cache.InvalidateCacheMiddleware(redisCache, cache.SessionPattern()), h.ApplySessionRightsize)
 
 				// NOTE: Session heartbeat is registered by ActivityHandler.RegisterRoutes()
 				// NOTE: Session recording is now handled by the streamspace-recording plugin
@@ -527,6 +649,8 @@ func setupRoutes(router *gin.Engine, h *api.Handler, userHandler *handlers.UserH
 				collaboration.POST("/sessions/:sessionId", collaborationHandler.CreateCollaborationSession)
 				collaboration.POST("/:collabId/join", collaborationHandler.JoinCollaborationSession)
 				collaboration.POST("/:collabId/leave", collaborationHandler.LeaveCollaborationSession)
+				collaboration.POST("/:collabId/heartbeat", collaborationHandler.ParticipantHeartbeat)
+				collaboration.GET("/:collabId/ws", collaborationHandler.CollaborationWebSocket)
 
 				// Participant management
 				collaboration.GET("/:collabId/participants", collaborationHandler.GetCollaborationParticipants)
@@ -583,6 +707,10 @@ func setupRoutes(router *gin.Engine, h *api.Handler, userHandler *handlers.UserH
 			security.DELETE("/mfa/:mfaId", securityHandler.DisableMFA)
 			security.POST("/mfa/backup-codes", securityHandler.GenerateBackupCodes)
 
+			// Backup code count and length (admins only)
+			security.GET("/mfa/backup-code-policy", securityHandler.GetBackupCodePolicy)
+			security.PUT("/mfa/backup-code-policy", securityHandler.UpdateBackupCodePolicy)
+
 			// IP Whitelisting (users can manage their own, admins can manage all)
 			security.POST("/ip-whitelist", securityHandler.CreateIPWhitelist)
 			security.GET("/ip-whitelist", securityHandler.ListIPWhitelist)
@@ -660,7 +788,10 @@ func setupRoutes(router *gin.Engine, h *api.Handler, userHandler *handlers.UserH
 		{
 			// Read-only template endpoints (all authenticated users)
 			templates.GET("", cache.CacheMiddleware(redisCache, 5*time.Minute), h.ListTemplates)
+			templates.GET("/categories", h.ListTemplateCategories)
 			templates.GET("/:id", cache.CacheMiddleware(redisCache, 5*time.Minute), h.GetTemplate)
+			templates.GET("/:id/launch-defaults", h.GetLaunchDefaults)
+			templates.DELETE("/:id/launch-defaults", h.ResetLaunchDefaults)
 
 			// Write operations require operator or admin role
 				templatesWrite := templates.Group("")
@@ -670,6 +801,10 @@ func setupRoutes(router *gin.Engine, h *api.Handler, userHandler *handlers.UserH
 					templatesWrite.PATCH("/:id", cache.InvalidateCacheMiddleware(redisCache, cache.TemplatePattern()), h.UpdateTemplate)
 					templatesWrite.DELETE("/:id", cache.InvalidateCacheMiddleware(redisCache, cache.TemplatePattern()), h.DeleteTemplate)
 
+					// Bulk export/import for promoting templates between environments
+					templatesWrite.GET("/export", h.ExportTemplates)
+					templatesWrite.POST("/import", cache.InvalidateCacheMiddleware(redisCache, cache.TemplatePattern()), h.ImportTemplates)
+
 					// Template Versioning (operator only)
 					templatesWrite.POST("/:id/versions", templateVersioningHandler.CreateTemplateVersion)
 					templatesWrite.GET("/:id/versions", templateVersioningHandler.ListTemplateVersions)
@@ -771,6 +906,9 @@ func setupRoutes(router *gin.Engine, h *api.Handler, userHandler *handlers.UserH
 			//	audit.GET("/users/:userId/logs", auditLogHandler.GetUserAuditLogs)
 			// }
 
+			// Field-level diff of a single audit entry (admin only)
+			auditHandler.RegisterRoutes(protected.Group("", adminMiddleware))
+
 			// Dashboard and resource usage (operators and admins can view platform stats)
 			dashboard := protected.Group("/dashboard")
 			{
@@ -851,6 +989,9 @@ func setupRoutes(router *gin.Engine, h *api.Handler, userHandler *handlers.UserH
 			// Resource quotas and limits enforcement - using dedicated handler (operators/admins only)
 			quotasHandler.RegisterRoutes(protected.Group("", operatorMiddleware))
 
+			// Cost reporting (operators/admins only)
+			costHandler.RegisterRoutes(protected.Group("", operatorMiddleware))
+
 			// Node Management (admin only)
 			admin := protected.Group("/admin")
 			admin.Use(adminMiddleware)
@@ -865,6 +1006,28 @@ func setupRoutes(router *gin.Engine, h *api.Handler, userHandler *handlers.UserH
 				admin.POST("/nodes/:name/cordon", nodeHandler.CordonNode)
 				admin.POST("/nodes/:name/uncordon", nodeHandler.UncordonNode)
 				admin.POST("/nodes/:name/drain", nodeHandler.DrainNode)
+
+				// Platform controllers and their reported capabilities
+				admin.GET("/controllers", h.ListControllers)
+
+				// Cluster-wide session over-provisioning
+				admin.GET("/rightsize", h.GetRightsizeOverview)
+
+				// Cluster capacity and headroom for new sessions
+				admin.GET("/capacity", h.GetCapacity)
+
+				// Port-forward traffic by session and user
+				admin.GET("/bandwidth", h.GetBandwidthReport)
+
+				// Sessions of any user (abuse handling, offboarding)
+				admin.GET("/sessions", h.AdminListSessions)
+				admin.POST("/sessions/:id/terminate", h.AdminTerminateSession)
+				admin.PUT("/sessions/:id/pin", h.AdminPinSession)
+				admin.POST("/users/:id/sessions/terminate", h.AdminTerminateUserSessions)
+
+				// Rate limiter introspection (support/incident triage)
+				admin.GET("/ratelimit/:key", rateLimitHandler.GetRateLimit)
+				admin.DELETE("/ratelimit/:key", rateLimitHandler.ResetRateLimit)
 			}
 
 			// NOTE: Billing is now handled by the streamspace-billing plugin
@@ -895,20 +1058,24 @@ func setupRoutes(router *gin.Engine, h *api.Handler, userHandler *handlers.UserH
 			}
 
 			// Upgrade HTTP connection to WebSocket
-			conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+			// CountWireBytes lets the hub report bytes actually sent on the network
+			conn, err := upgrader.Upgrade(internalWebsocket.CountWireBytes(c.Writer), c.Request, nil)
 			if err != nil {
 				log.Printf("Failed to upgrade WebSocket connection: %v", err)
 				return
 			}
 
 			// Delegate to wsManager which broadcasts sessions every 3 seconds
-			wsManager.HandleSessionsWebSocket(conn, userIDStr, "")
+			viewer := internalWebsocket.Viewer{UserID: userIDStr, Role: c.GetString("userRole")}
+			// ?since=<seq> replays the events missed while disconnected
+			since := internalWebsocket.ParseReplaySince(c.Query("since"))
+			wsManager.HandleSessionsWebSocket(conn, viewer, userIDStr, "", since)
 		})
 
 		// Metrics WebSocket - connects to wsManager for real-time metrics broadcasts
 		ws.GET("/cluster", operatorMiddleware, func(c *gin.Context) {
 			// Upgrade HTTP connection to WebSocket
-			conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+			conn, err := upgrader.Upgrade(internalWebsocket.CountWireBytes(c.Writer), c.Request, nil)
 			if err != nil {
 				log.Printf("Failed to upgrade WebSocket connection: %v", err)
 				return
@@ -920,6 +1087,15 @@ func setupRoutes(router *gin.Engine, h *api.Handler, userHandler *handlers.UserH
 
 		ws.GET("/logs/:namespace/:pod", operatorMiddleware, h.LogsWebSocket)
 		ws.GET("/enterprise", handlers.HandleEnterpriseWebSocket) // Real-time enterprise features
+
+		// Hub delivery stats (batching and compression effectiveness) and
+		// notifier subscription counts
+		ws.GET("/stats", operatorMiddleware, func(c *gin.Context) {
+			c.JSON(http.StatusOK, gin.H{
+				"hubs":          wsManager.Stats(),
+				"subscriptions": wsManager.GetNotifier().Stats(),
+			})
+		})
 	}
 
 	// Webhook endpoints (HMAC signature validation required)
@@ -37,6 +37,7 @@ package activity
 
 import (
 	"context"
+	"database/sql"
 	"fmt"
 	"log"
 	"time"
@@ -68,6 +69,9 @@ type Tracker struct {
 	publisher *events.Publisher
 	// platform identifies the target platform (kubernetes, docker, etc.)
 	platform string
+	// db records idle hibernations as requested by the idle reconciler
+	// (optional, see SetDatabase).
+	db *sql.DB
 }
 
 // NewTracker creates a new activity tracker instance.
@@ -89,6 +93,12 @@ func NewTracker(k8sClient *k8s.Client, publisher *events.Publisher, platform str
 	}
 }
 
+// SetDatabase sets the database that idle hibernations are recorded in, so
+// they are audited as caused by the idle reconciler.
+func (t *Tracker) SetDatabase(db *sql.DB) {
+	t.db = db
+}
+
 // ActivityStatus represents the current activity state of a session.
 //
 // This status is calculated from:
@@ -237,6 +247,10 @@ func (t *Tracker) HibernateIdleSession(ctx context.Context, namespace, sessionNa
 		return fmt.Errorf("session %s is not idle enough to hibernate", sessionName)
 	}
 
+	if err := events.RequestSessionState(ctx, t.db, sessionName, "hibernated", events.ActorIdleReconciler); err != nil {
+		log.Printf("Warning: Failed to record idle hibernation of session %s: %v", sessionName, err)
+	}
+
 	// Update session state to hibernated
 	session.State = "hibernated"
 	if err := t.k8sClient.UpdateSession(ctx, session); err != nil {
@@ -245,9 +259,10 @@ func (t *Tracker) HibernateIdleSession(ctx context.Context, namespace, sessionNa
 
 	// Publish hibernate event for controllers
 	event := &events.SessionHibernateEvent{
-		SessionID: sessionName,
-		UserID:    session.User,
-		Platform:  t.platform,
+		SessionID:        sessionName,
+		UserID:           session.User,
+		Platform:         t.platform,
+		TargetController: session.PinToAgent,
 	}
 	if err := t.publisher.PublishSessionHibernate(ctx, event); err != nil {
 		log.Printf("Warning: Failed to publish session hibernate event: %v", err)
@@ -0,0 +1,323 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/audit"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/events"
+	"github.com/streamspace/streamspace/api/internal/k8s"
+)
+
+// adminTerminateRequest is the body of the admin terminate endpoints. The
+// reason is required: it is recorded in the audit log and shown to the
+// session's owner.
+type adminTerminateRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// AdminListSessions lists sessions of every user, optionally filtered by
+// owner, state and tag.
+//
+// HTTP Method: GET
+// Path: /api/v1/admin/sessions?user=&state=&tag=
+// Authorization: Admin only
+func (h *Handler) AdminListSessions(c *gin.Context) {
+	if c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	filter := db.SessionFilter{
+		UserID: c.Query("user"),
+		State:  c.Query("state"),
+		Tag:    c.Query("tag"),
+	}
+	dbSessions, err := h.sessionDB.ListSessionsFiltered(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("Failed to list sessions for admin: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+		return
+	}
+
+	sessions := h.convertDBSessionsToResponse(dbSessions)
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": sessions,
+		"total":    len(sessions),
+	})
+}
+
+// AdminTerminateSession terminates any user's session, bypassing the
+// ownership check. The admin and reason are audited and the owner is
+// notified.
+//
+// HTTP Method: POST
+// Path: /api/v1/admin/sessions/:id/terminate
+// Authorization: Admin only
+func (h *Handler) AdminTerminateSession(c *gin.Context) {
+	if c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	var req adminTerminateRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	sessionID := c.Param("id")
+	session, err := h.sessionDB.GetSession(ctx, sessionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+	if isTerminalSessionState(session.State) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Session already terminated",
+			"message": fmt.Sprintf("Session %s is %s", sessionID, session.State),
+		})
+		return
+	}
+
+	if err := h.adminTerminate(ctx, session, c.GetString("userID"), strings.TrimSpace(req.Reason), c.ClientIP()); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to terminate session",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"name":    sessionID,
+		"user":    session.UserID,
+		"message": "Session termination requested, waiting for controller",
+	})
+}
+
+// adminPinRequest is the body of the admin pin endpoint. Empty values
+// remove the pin.
+type adminPinRequest struct {
+	Node  string `json:"node"`
+	Agent string `json:"agent"`
+}
+
+// AdminPinSession pins a session to a node and/or controller while it is
+// being debugged, so it restarts where its logs and local state are, or
+// unpins it. A pinned node that can't take the pod is reported in the
+// session's Pinned condition.
+//
+// HTTP Method: PUT
+// Path: /api/v1/admin/sessions/:id/pin
+// Authorization: Admin only
+func (h *Handler) AdminPinSession(c *gin.Context) {
+	if c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	var req adminPinRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request", "message": err.Error()})
+		return
+	}
+	req.Node = strings.TrimSpace(req.Node)
+	req.Agent = strings.TrimSpace(req.Agent)
+
+	ctx := c.Request.Context()
+	sessionID := c.Param("id")
+	session, err := h.k8sClient.GetSession(ctx, h.namespace, sessionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	if req.Node != "" && h.platform == events.PlatformKubernetes {
+		if _, err := h.k8sClient.GetNode(ctx, req.Node); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Unknown node",
+				"message": fmt.Sprintf("Node %s not found", req.Node),
+			})
+			return
+		}
+	}
+	if req.Agent != "" && !h.knownController(req.Agent) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Unknown controller",
+			"message": fmt.Sprintf("No %s controller %s has reported in (see /api/v1/admin/controllers)", h.platform, req.Agent),
+		})
+		return
+	}
+
+	pinned, err := h.k8sClient.SetSessionPin(ctx, h.namespace, sessionID, req.Node, req.Agent)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to pin session",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	log.Printf("Admin %s pinned session %s to node %q, agent %q", c.GetString("userID"), sessionID, req.Node, req.Agent)
+	h.auditAdminPin(ctx, session, pinned, c.GetString("userID"), c.ClientIP())
+
+	c.JSON(http.StatusOK, gin.H{
+		"name":       sessionID,
+		"pinToNode":  pinned.PinToNode,
+		"pinToAgent": pinned.PinToAgent,
+	})
+}
+
+// knownController reports whether a controller of the handler's platform
+// with the given ID has sent a heartbeat.
+func (h *Handler) knownController(id string) bool {
+	for _, controller := range h.subscriber.Controllers().List() {
+		if controller.ControllerID == id && controller.Platform == h.platform {
+			return true
+		}
+	}
+	return false
+}
+
+// auditAdminPin records an admin changing a session's pin. Failures are
+// logged; the pin is already applied.
+func (h *Handler) auditAdminPin(ctx context.Context, before, after *k8s.Session, adminID, ipAddress string) {
+	if h.db == nil {
+		return
+	}
+
+	changes := audit.Diff(
+		map[string]interface{}{"pinToNode": before.PinToNode, "pinToAgent": before.PinToAgent},
+		map[string]interface{}{"pinToNode": after.PinToNode, "pinToAgent": after.PinToAgent},
+	)
+	details, _ := json.Marshal(changes)
+
+	_, err := h.db.DB().ExecContext(ctx, `
+		INSERT INTO audit_log (user_id, action, resource_type, resource_id, changes, timestamp, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, adminID, "admin.session.pin", "session", before.Name, details, time.Now(), ipAddress)
+	if err != nil {
+		log.Printf("Failed to audit pinning of session %s: %v", before.Name, err)
+	}
+}
+
+// AdminTerminateUserSessions terminates every live session of a user, e.g.
+// when offboarding an account.
+//
+// HTTP Method: POST
+// Path: /api/v1/admin/users/:id/sessions/terminate
+// Authorization: Admin only
+func (h *Handler) AdminTerminateUserSessions(c *gin.Context) {
+	if c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	var req adminTerminateRequest
+	if err := c.ShouldBindJSON(&req); err != nil || strings.TrimSpace(req.Reason) == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A reason is required"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID := c.Param("id")
+	sessions, err := h.sessionDB.ListSessionsByUser(ctx, userID)
+	if err != nil {
+		log.Printf("Failed to list sessions of user %s for admin terminate: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+		return
+	}
+
+	terminated := []string{}
+	failed := map[string]string{}
+	for _, session := range sessions {
+		if isTerminalSessionState(session.State) {
+			continue
+		}
+		if err := h.adminTerminate(ctx, session, c.GetString("userID"), strings.TrimSpace(req.Reason), c.ClientIP()); err != nil {
+			failed[session.ID] = err.Error()
+			continue
+		}
+		terminated = append(terminated, session.ID)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"user":       userID,
+		"terminated": terminated,
+		"failed":     failed,
+	})
+}
+
+// adminTerminate requests termination of a session on an admin's behalf,
+// records it in the audit log and notifies the session's owner.
+func (h *Handler) adminTerminate(ctx context.Context, session *db.Session, adminID, reason, ipAddress string) error {
+	cancelled := false
+	if h.db != nil {
+		var err error
+		cancelled, err = events.CancelQueuedSessionCreate(ctx, h.db.DB(), session.ID)
+		if err != nil {
+			log.Printf("Failed to cancel queued create for session %s: %v", session.ID, err)
+		}
+	}
+
+	if cancelled {
+		if err := h.sessionDB.UpdateSessionState(ctx, session.ID, "terminated"); err != nil {
+			log.Printf("Failed to mark queued session %s terminated (non-fatal): %v", session.ID, err)
+		}
+	} else {
+		h.requestSessionState(ctx, session.ID, "terminated", events.AdminActor(adminID))
+		event := &events.SessionDeleteEvent{
+			SessionID: session.ID,
+			UserID:    session.UserID,
+			Platform:  h.platform,
+		}
+		if err := h.publisher.PublishSessionDelete(ctx, event); err != nil {
+			return fmt.Errorf("failed to publish delete event: %w", err)
+		}
+	}
+
+	log.Printf("Admin %s terminated session %s of user %s: %s", adminID, session.ID, session.UserID, reason)
+	h.auditAdminTerminate(ctx, session, adminID, reason, ipAddress)
+
+	if h.wsManager != nil {
+		h.wsManager.GetNotifier().NotifySessionAdminTerminated(session.ID, session.UserID, reason)
+	}
+	return nil
+}
+
+// auditAdminTerminate records an admin terminating a session. Failures are
+// logged; the session is already being terminated.
+func (h *Handler) auditAdminTerminate(ctx context.Context, session *db.Session, adminID, reason, ipAddress string) {
+	if h.db == nil {
+		return
+	}
+
+	changes := audit.Values(map[string]interface{}{
+		"owner":  session.UserID,
+		"reason": reason,
+	})
+	changes["state"] = audit.FieldChange{Old: session.State, New: "terminated"}
+	details, _ := json.Marshal(changes)
+
+	_, err := h.db.DB().ExecContext(ctx, `
+		INSERT INTO audit_log (user_id, action, resource_type, resource_id, changes, timestamp, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, adminID, "admin.session.terminate", "session", session.ID, details, time.Now(), ipAddress)
+	if err != nil {
+		log.Printf("Failed to audit admin termination of session %s: %v", session.ID, err)
+	}
+}
+
+// isTerminalSessionState reports whether a session is already gone or
+// going away.
+func isTerminalSessionState(state string) bool {
+	return state == "terminated" || state == "deleted" || state == "cancelled"
+}
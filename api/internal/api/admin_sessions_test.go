@@ -0,0 +1,134 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var adminSessionColumns = []string{
+	"id", "user_id", "team_id", "template_name", "state", "app_type", "active_connections", "url",
+	"namespace", "platform", "pod_name", "memory", "cpu", "persistent_home", "idle_timeout",
+	"max_session_duration", "created_at", "updated_at", "last_connection", "last_disconnect", "last_activity",
+}
+
+func adminSessionContext(path, param, body, role string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", path, strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: param}}
+	c.Set("userID", "root")
+	c.Set("userRole", role)
+	return c, w
+}
+
+func adminSessionRow(rows *sqlmock.Rows, id, owner, state string) *sqlmock.Rows {
+	now := time.Now()
+	return rows.AddRow(id, owner, "", "firefox", state, "desktop", 0, "", "streamspace", "kubernetes", "",
+		"2Gi", "1000m", false, "", "", now, now, nil, nil, nil)
+}
+
+func newAdminSessionHandler(t *testing.T) (*Handler, sqlmock.Sqlmock) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	return &Handler{
+		db:        db.NewDatabaseForTesting(sqlDB),
+		sessionDB: db.NewSessionDB(sqlDB),
+		publisher: &events.Publisher{},
+		platform:  "kubernetes",
+	}, mock
+}
+
+func TestAdminSessions_AdminOnly(t *testing.T) {
+	h := &Handler{}
+
+	c, w := adminSessionContext("/api/v1/admin/sessions", "", "", "user")
+	h.AdminListSessions(c)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	c, w = adminSessionContext("/api/v1/admin/sessions/s1/terminate", "s1", `{"reason":"abuse"}`, "user")
+	h.AdminTerminateSession(c)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	c, w = adminSessionContext("/api/v1/admin/users/alice/sessions/terminate", "alice", `{"reason":"offboarding"}`, "operator")
+	h.AdminTerminateUserSessions(c)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	c, w = adminSessionContext("/api/v1/admin/sessions/s1/pin", "s1", `{"node":"worker-3"}`, "user")
+	h.AdminPinSession(c)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestAdminTerminateSession_RequiresReason(t *testing.T) {
+	c, w := adminSessionContext("/api/v1/admin/sessions/s1/terminate", "s1", `{"reason":"  "}`, "admin")
+	(&Handler{}).AdminTerminateSession(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAdminTerminateSession_AuditsOtherUsersSession(t *testing.T) {
+	h, mock := newAdminSessionHandler(t)
+
+	mock.ExpectQuery("FROM sessions").WithArgs("s1").
+		WillReturnRows(adminSessionRow(sqlmock.NewRows(adminSessionColumns), "s1", "alice", "running"))
+	mock.ExpectExec("DELETE FROM pending_session_creates").WithArgs("s1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs("root", "admin.session.terminate", "session", "s1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	c, w := adminSessionContext("/api/v1/admin/sessions/s1/terminate", "s1", `{"reason":"abuse report #42"}`, "admin")
+	h.AdminTerminateSession(c)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Body.String(), `"user":"alice"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminTerminateSession_AlreadyTerminated(t *testing.T) {
+	h, mock := newAdminSessionHandler(t)
+
+	mock.ExpectQuery("FROM sessions").WithArgs("s1").
+		WillReturnRows(adminSessionRow(sqlmock.NewRows(adminSessionColumns), "s1", "alice", "terminated"))
+
+	c, w := adminSessionContext("/api/v1/admin/sessions/s1/terminate", "s1", `{"reason":"abuse"}`, "admin")
+	h.AdminTerminateSession(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminTerminateUserSessions_SkipsTerminated(t *testing.T) {
+	h, mock := newAdminSessionHandler(t)
+
+	rows := sqlmock.NewRows(adminSessionColumns)
+	adminSessionRow(rows, "s1", "alice", "running")
+	adminSessionRow(rows, "s2", "alice", "terminated")
+	mock.ExpectQuery("FROM sessions WHERE user_id").WithArgs("alice").WillReturnRows(rows)
+
+	// Only the live session is terminated and audited
+	mock.ExpectExec("DELETE FROM pending_session_creates").WithArgs("s1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs("root", "admin.session.terminate", "session", "s1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	c, w := adminSessionContext("/api/v1/admin/users/alice/sessions/terminate", "alice", `{"reason":"offboarding"}`, "admin")
+	h.AdminTerminateUserSessions(c)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.JSONEq(t, `{"user":"alice","terminated":["s1"],"failed":{}}`, w.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,62 @@
+// Package api - bandwidth.go
+//
+// This file implements the port-forward bandwidth report for admins.
+//
+// Traffic carried by port-forward tunnels is counted per session and user (see
+// package bandwidth). Admins can see the totals and the heaviest sessions and
+// users over the last days:
+//
+//	GET /api/v1/admin/bandwidth?days=7&limit=20 → bandwidth report
+//
+// days counts today (UTC) as the first day and defaults to 1; limit bounds
+// the sessions and users listed and defaults to 20. Bytes not yet flushed by
+// the API replicas (BANDWIDTH_FLUSH_INTERVAL) are not included.
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetBandwidthReport returns the port-forward bandwidth report (admin only).
+func (h *Handler) GetBandwidthReport(c *gin.Context) {
+	if c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+	if h.bandwidth == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Bandwidth report unavailable",
+			"message": "Bandwidth accounting is not enabled",
+		})
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", "1"))
+	if err != nil || days < 1 || days > 366 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 366"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit < 1 || limit > 500 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "limit must be between 1 and 500"})
+		return
+	}
+
+	since := time.Now().UTC().AddDate(0, 0, -(days - 1))
+	report, err := h.bandwidth.Report(c.Request.Context(), since, limit)
+	if err != nil {
+		log.Printf("Failed to build bandwidth report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to build bandwidth report",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,120 @@
+// Package api - capacity.go
+//
+// This file implements the cluster capacity report for admins.
+//
+// Operators sizing the cluster need to know how much is allocatable, how
+// much is committed to pods (and to sessions among them), how much sessions
+// actually use, and how many more sessions of each template category fit
+// (see package capacity):
+//
+//	GET /api/v1/admin/capacity → capacity report
+//
+// CPU is reported in millicores and memory in MiB. "used" is omitted when
+// metrics-server is unavailable.
+//
+// Backends:
+//   - Kubernetes: nodes, pods and metrics-server
+//   - Docker: not yet supported
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/capacity"
+	"github.com/streamspace/streamspace/api/internal/events"
+	"github.com/streamspace/streamspace/api/internal/rightsize"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// sessionPodApp is the app label of session pods.
+const sessionPodApp = "streamspace-session"
+
+// GetCapacity returns the cluster capacity report (admin only).
+func (h *Handler) GetCapacity(c *gin.Context) {
+	if c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+	if h.platform != events.PlatformKubernetes {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Capacity report unavailable",
+			"message": "The capacity report is only supported on Kubernetes",
+		})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	report, err := h.capacityReport(ctx)
+	if err != nil {
+		log.Printf("Failed to build capacity report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to build capacity report",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// capacityReport gathers nodes, pods, templates and session usage.
+func (h *Handler) capacityReport(ctx context.Context) (*capacity.Report, error) {
+	nodes, err := h.k8sClient.GetNodes(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// All namespaces: every pod on a node takes from its allocatable
+	pods, err := h.k8sClient.GetPods(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	sessionPods := make([]corev1.Pod, 0)
+	for _, pod := range pods.Items {
+		if pod.Namespace == h.namespace && pod.Labels["app"] == sessionPodApp {
+			sessionPods = append(sessionPods, pod)
+		}
+	}
+
+	templateList, err := h.k8sClient.ListTemplates(ctx, h.namespace)
+	if err != nil {
+		return nil, err
+	}
+	templates := make([]capacity.Template, 0, len(templateList))
+	for _, template := range templateList {
+		// Sized like a launch that doesn't specify resources
+		memory, cpu, _ := h.launchResources(ctx, "", template)
+		templates = append(templates, capacity.Template{
+			Name:     template.Name,
+			Category: template.Category,
+			CPU:      cpu,
+			Memory:   memory,
+		})
+	}
+
+	report, err := capacity.Build(nodes.Items, pods.Items, h.quotaEnforcer.CalculateUsage(sessionPods), templates)
+	if err != nil {
+		return nil, err
+	}
+
+	// Actual usage is best effort: metrics-server may not be installed
+	samples, err := rightsize.NewPodMetricsSource(h.k8sClient, h.namespace).Samples(ctx)
+	if err != nil {
+		log.Printf("Session usage unavailable for capacity report: %v", err)
+		return report, nil
+	}
+	used := &capacity.Resources{}
+	for _, sample := range samples {
+		used.CPU += sample.CPUMillis
+		used.Memory += sample.MemoryBytes / (1024 * 1024)
+	}
+	report.Used = used
+
+	return report, nil
+}
@@ -0,0 +1,44 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/events"
+	"github.com/streamspace/streamspace/api/internal/k8s"
+)
+
+// ListControllers returns the platform controllers known from their
+// heartbeats, with the capabilities each reported.
+//
+// HTTP Method: GET
+// Path: /api/v1/admin/controllers
+// Authorization: Admin only
+func (h *Handler) ListControllers(c *gin.Context) {
+	controllers := h.subscriber.Controllers().List()
+	if controllers == nil {
+		controllers = []events.ControllerInfo{}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"controllers": controllers,
+		"total":       len(controllers),
+	})
+}
+
+// sessionRequirements describes what a session of the template needs from
+// the controller that creates it.
+func sessionRequirements(template *k8s.Template, memory, cpu string) events.SessionRequirements {
+	req := events.SessionRequirements{
+		Action:    "session.create",
+		Resources: events.ResourceSpec{Memory: memory, CPU: cpu},
+	}
+	if template != nil {
+		for _, capability := range template.Capabilities {
+			if strings.EqualFold(capability, "GPU") {
+				req.GPU = true
+			}
+		}
+	}
+	return req
+}
@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/k8s"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionRequirements(t *testing.T) {
+	req := sessionRequirements(&k8s.Template{Capabilities: []string{"Network", "gpu"}}, "8Gi", "4")
+	assert.Equal(t, "session.create", req.Action)
+	assert.True(t, req.GPU)
+	assert.Equal(t, "8Gi", req.Resources.Memory)
+	assert.Equal(t, "4", req.Resources.CPU)
+
+	assert.False(t, sessionRequirements(&k8s.Template{Capabilities: []string{"Audio"}}, "", "").GPU)
+	assert.False(t, sessionRequirements(nil, "", "").GPU)
+}
+
+func TestListControllers_NoSubscriber(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+
+	handler := &Handler{}
+	handler.ListControllers(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"controllers":[],"total":0}`, w.Body.String())
+}
@@ -95,6 +95,7 @@ package api
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -105,10 +106,16 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/streamspace/streamspace/api/internal/bandwidth"
 	"github.com/streamspace/streamspace/api/internal/db"
 	"github.com/streamspace/streamspace/api/internal/events"
 	"github.com/streamspace/streamspace/api/internal/k8s"
+	"github.com/streamspace/streamspace/api/internal/middleware"
+	"github.com/streamspace/streamspace/api/internal/plugins"
+	"github.com/streamspace/streamspace/api/internal/prewarm"
 	"github.com/streamspace/streamspace/api/internal/quota"
+	"github.com/streamspace/streamspace/api/internal/rightsize"
 	"github.com/streamspace/streamspace/api/internal/sync"
 	"github.com/streamspace/streamspace/api/internal/tracker"
 	"github.com/streamspace/streamspace/api/internal/websocket"
@@ -158,14 +165,33 @@ type Handler struct {
 	sessionDB      *db.SessionDB                // Session database operations
 	k8sClient      *k8s.Client                  // Kubernetes client for CRD operations
 	publisher      *events.Publisher            // NATS event publisher
+	subscriber     *events.Subscriber           // NATS status subscriber (health reporting only)
 	connTracker    *tracker.ConnectionTracker   // Active connection tracking
 	syncService    *sync.SyncService            // Repository synchronization
 	wsManager      *websocket.Manager           // WebSocket connection manager
 	quotaEnforcer  *quota.Enforcer              // Resource quota enforcement
+	sessionHooks   SessionCreateHooks           // Plugin before-hooks (optional)
+	quotaWarnings  QuotaWarningNotifier         // Tells users nearing their quota (optional)
+	rightsizer     *rightsize.Recommender       // Right-sizing recommendations (optional)
+	prewarmer      *prewarm.Predictor           // Pre-warm settings and predictions (optional)
+	bandwidth      *bandwidth.Meter             // Proxied traffic accounting (optional)
+	stateCommands  *events.StateCommands        // Coalesces rapid state changes (optional)
 	namespace      string                       // Kubernetes namespace for resources
 	platform       string                       // Target platform (kubernetes, docker, etc.)
 }
 
+// SessionCreateHooks runs plugin before-hooks on the session create path.
+//
+// Implemented by plugins.Runtime and plugins.RuntimeV2. Declared here so the
+// handler does not depend on a concrete runtime.
+type SessionCreateHooks interface {
+	RunBeforeSessionCreate(req *plugins.SessionCreateRequest) error
+}
+
+// QuotaWarningNotifier tells a user that a session they created brought
+// their usage near their quota.
+type QuotaWarningNotifier func(userID string, warnings []quota.QuotaWarning)
+
 // NewHandler creates a new API handler with injected dependencies.
 //
 // PARAMETERS:
@@ -208,11 +234,46 @@ func NewHandler(database *db.Database, k8sClient *k8s.Client, publisher *events.
 		syncService:   syncService,
 		wsManager:     wsManager,
 		quotaEnforcer: quotaEnforcer,
+		stateCommands: events.StateCommandsFromEnv(),
 		namespace:     namespace,
 		platform:      platform,
 	}
 }
 
+// SetEventSubscriber attaches the NATS subscriber so its connection state is
+// reported by /healthz and /readyz.
+func (h *Handler) SetEventSubscriber(subscriber *events.Subscriber) {
+	h.subscriber = subscriber
+}
+
+// SetRightsizeRecommender enables the session right-sizing endpoints.
+func (h *Handler) SetRightsizeRecommender(recommender *rightsize.Recommender) {
+	h.rightsizer = recommender
+}
+
+// SetPrewarmPredictor enables the session pre-warm endpoints.
+func (h *Handler) SetPrewarmPredictor(predictor *prewarm.Predictor) {
+	h.prewarmer = predictor
+}
+
+// SetBandwidthMeter enables bandwidth accounting of port-forward tunnels.
+func (h *Handler) SetBandwidthMeter(meter *bandwidth.Meter) {
+	h.bandwidth = meter
+}
+
+// SetSessionHooks attaches the plugin runtime whose BeforeSessionCreate hooks
+// are consulted by CreateSession. Passing nil disables the hooks.
+func (h *Handler) SetSessionHooks(hooks SessionCreateHooks) {
+	h.sessionHooks = hooks
+}
+
+// SetQuotaWarningNotifier sets the function told when a new session brings
+// a user's usage near their quota. Without one, warnings are only returned
+// in the create response.
+func (h *Handler) SetQuotaWarningNotifier(notifier QuotaWarningNotifier) {
+	h.quotaWarnings = notifier
+}
+
 // ============================================================================
 // Session Endpoints
 // ============================================================================
@@ -304,7 +365,9 @@ func (h *Handler) ListSessions(c *gin.Context) {
 	})
 }
 
-// GetSession returns a single session by ID
+// GetSession returns a single session by ID.
+//
+// status.url is only included once the session is serving (status.ready).
 func (h *Handler) GetSession(c *gin.Context) {
 	// SECURITY FIX: Use request context for proper cancellation and timeout handling
 	ctx := c.Request.Context()
@@ -321,12 +384,16 @@ func (h *Handler) GetSession(c *gin.Context) {
 			return
 		}
 		enriched := h.enrichSessionWithDBInfo(ctx, k8sSession)
+		enriched["status"] = sessionStatusResponse(k8sSession)
+		h.applyReadinessGate(ctx, sessionID, enriched)
 		c.JSON(http.StatusOK, enriched)
 		return
 	}
 
-	// Convert to API response format
+	// Convert to API response format, withholding the URL until the
+	// session is actually serving (see session_readiness.go)
 	session := h.convertDBSessionToResponse(dbSession)
+	h.applyReadinessGate(ctx, sessionID, session)
 	c.JSON(http.StatusOK, session)
 }
 
@@ -345,7 +412,8 @@ func (h *Handler) GetSession(c *gin.Context) {
 //     "persistentHome": true,              // OPTIONAL: Mount persistent storage
 //     "idleTimeout": "30m",                // OPTIONAL: Auto-hibernate timeout
 //     "maxSessionDuration": "8h",          // OPTIONAL: Maximum lifetime
-//     "tags": ["project-a", "dev"]         // OPTIONAL: Organization tags
+//     "tags": ["project-a", "dev"],        // OPTIONAL: Organization tags
+//     "forkFrom": "user123-firefox-1a2b"   // OPTIONAL: Fork this session's home
 //   }
 //
 // SECURITY: Quota Enforcement
@@ -389,6 +457,12 @@ func (h *Handler) CreateSession(c *gin.Context) {
 		IdleTimeout        string   `json:"idleTimeout"`
 		MaxSessionDuration string   `json:"maxSessionDuration"`
 		Tags               []string `json:"tags"`
+		// Values for the template's parameters, substituted into its env and args
+		Parameters map[string]string `json:"parameters"`
+		// Scheduling priority class: Low, Normal or High
+		Priority string `json:"priority"`
+		// Session whose home volume is forked into the new session
+		ForkFrom string `json:"forkFrom"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -570,10 +644,71 @@ func (h *Handler) CreateSession(c *gin.Context) {
 		return
 	}
 
+	// Step 2a: Templates tagged restricted:<group> may only be launched by
+	// members of that group (see template_access.go). Admins may launch
+	// them for anyone.
+	// Privileged templates (root, extra capabilities) need the same: a
+	// restricted:<group> tag, or an admin.
+	if !h.templateAccessFor(ctx, req.User, c.GetString("userRole")).allowed(template) {
+		message := fmt.Sprintf("Template '%s' is restricted to the groups %s", templateName, strings.Join(template.RestrictedGroups(), ", "))
+		if len(template.RestrictedGroups()) == 0 {
+			message = fmt.Sprintf("Template '%s' runs privileged and may only be launched by admins", templateName)
+		}
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Template access denied",
+			"message": message,
+		})
+		return
+	}
+
+	// Step 2b: Reject templates that can't run on this platform
+	// (e.g. GPU or privileged templates are Kubernetes-only). Dispatching
+	// them would only fail later inside the controller.
+	backends := &events.TemplateConfig{SupportedBackends: template.SupportedBackends}
+	if !backends.SupportsPlatform(h.platform) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Template not supported on this platform",
+			"message": fmt.Sprintf("Template '%s' runs on %s, but sessions are created on %s", templateName, strings.Join(template.SupportedBackends, ", "), h.platform),
+		})
+		return
+	}
+
+	// Step 2c: Check parameter values against the template's schema
+	params, err := template.ValidateParameters(req.Parameters)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid template parameters",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Step 2d: Resolve the priority class. An explicit request must be within
+	// the user's quota; a template default above it is lowered to the cap.
+	priority := req.Priority
+	if priority != "" {
+		if err := h.quotaEnforcer.CheckPriority(ctx, req.User, priority); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Priority not allowed",
+				"message": err.Error(),
+			})
+			return
+		}
+	} else if template.DefaultPriority != "" {
+		priority = template.DefaultPriority
+		if h.quotaEnforcer.CheckPriority(ctx, req.User, priority) != nil {
+			maxPriority, err := h.quotaEnforcer.GetMaxPriority(ctx, req.User)
+			if err != nil {
+				log.Printf("Failed to get priority quota for %s, using Normal: %v", req.User, err)
+				maxPriority = quota.PriorityNormal
+			}
+			priority = maxPriority
+		}
+	}
+
 	// Step 3: Determine resource allocation (memory/CPU)
-	// Priority: request > template defaults > system defaults
-	memory := "2Gi"   // System default
-	cpu := "1000m"    // System default (1 core)
+	// Priority: request > user's last choice > template defaults > system defaults
+	memory, cpu, resourceSource := h.launchResources(ctx, req.User, template)
 	if req.Resources != nil {
 		// User explicitly specified resources
 		if req.Resources.Memory != "" {
@@ -582,14 +717,101 @@ func (h *Handler) CreateSession(c *gin.Context) {
 		if req.Resources.CPU != "" {
 			cpu = req.Resources.CPU
 		}
-	} else if template.DefaultResources.Memory != "" || template.DefaultResources.CPU != "" {
-		// Fall back to template-defined defaults
-		if template.DefaultResources.Memory != "" {
-			memory = template.DefaultResources.Memory
+		resourceSource = resourceSourceRequest
+	}
+
+	// Session-level lifecycle settings override the template defaults
+	idleTimeout := req.IdleTimeout
+	if idleTimeout == "" {
+		idleTimeout = template.DefaultIdleTimeout
+	}
+	maxSessionDuration := req.MaxSessionDuration
+	if maxSessionDuration == "" {
+		maxSessionDuration = template.DefaultMaxSessionDuration
+	}
+
+	persistentHome := true // Default
+	if req.PersistentHome != nil {
+		persistentHome = *req.PersistentHome
+	}
+
+	// Step 3a: A fork starts with a copy of the home of one of the user's
+	// sessions (see session_fork.go)
+	var forkWarning string
+	if req.ForkFrom != "" {
+		source, err := h.sessionDB.GetSession(ctx, req.ForkFrom)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Session to fork not found",
+				"message": fmt.Sprintf("No session found with ID: %s", req.ForkFrom),
+			})
+			return
 		}
-		if template.DefaultResources.CPU != "" {
-			cpu = template.DefaultResources.CPU
+		if err := validateForkSource(source, req.User, persistentHome, h.platform); err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, errForkForbidden) {
+				status = http.StatusForbidden
+			}
+			c.JSON(status, gin.H{
+				"error":   "Cannot fork session",
+				"message": err.Error(),
+			})
+			return
+		}
+		if source.State == "running" {
+			forkWarning = forkWhileRunningWarning
+		}
+	}
+
+	tags := req.Tags
+	var labels map[string]string
+
+	// Step 3b: Run plugin before-hooks
+	// Plugins may veto the request or adjust it. Mutations are applied before
+	// resource validation and quota checks so plugins can't bypass them.
+	if h.sessionHooks != nil {
+		hookReq := &plugins.SessionCreateRequest{
+			User:               req.User,
+			Template:           templateName,
+			Memory:             memory,
+			CPU:                cpu,
+			PersistentHome:     persistentHome,
+			IdleTimeout:        idleTimeout,
+			MaxSessionDuration: maxSessionDuration,
+			Tags:               append([]string(nil), tags...),
 		}
+		if err := h.sessionHooks.RunBeforeSessionCreate(hookReq); err != nil {
+			log.Printf("Session creation for user %s (template %s) vetoed: %v", req.User, templateName, err)
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Session creation rejected",
+				"message": err.Error(),
+			})
+			return
+		}
+		memory = hookReq.Memory
+		cpu = hookReq.CPU
+		idleTimeout = hookReq.IdleTimeout
+		maxSessionDuration = hookReq.MaxSessionDuration
+		tags = hookReq.Tags
+		labels = hookReq.Labels
+	}
+
+	// Labels only come from plugins; the controller owns streamspace.io/*
+	if err := validateSessionLabels(labels); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid session spec",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	// Reject durations the controller can't parse before anything is created
+	if err := validateSessionDurations(idleTimeout, maxSessionDuration); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid session spec",
+			"message": err.Error(),
+		})
+		return
 	}
 
 	// Step 4: Validate and parse resource specifications
@@ -622,8 +844,57 @@ func (h *Handler) CreateSession(c *gin.Context) {
 
 	// Calculate current usage and check if new session would exceed quota
 	currentUsage := h.quotaEnforcer.CalculateUsage(userPods)
+	currentUsage.CountCategories(func(name string) string {
+		if name == templateName {
+			return template.Category
+		}
+		if t, err := h.k8sClient.GetTemplate(ctx, h.namespace, name); err == nil {
+			return t.Category
+		}
+		return ""
+	})
 
-	if err := h.quotaEnforcer.CheckSessionCreation(ctx, req.User, requestedCPU, requestedMemory, 0, currentUsage); err != nil {
+	// Storage is charged for the user's home PVCs; the first persistent-home
+	// session also requests the home the controller will create for it, and
+	// a fork requests its own home the size of the source's
+	var requestedStorage int64
+	homePVCs, err := h.k8sClient.GetUserHomePVCs(ctx, h.namespace, req.User)
+	if err != nil {
+		log.Printf("Failed to get home volumes for quota check: %v", err)
+	} else {
+		currentUsage.TotalStorage = h.quotaEnforcer.CalculateStorageUsage(homePVCs)
+		if persistentHome && req.ForkFrom != "" {
+			if source := forkSourceHome(homePVCs, req.User, req.ForkFrom); source != nil {
+				requestedStorage = h.quotaEnforcer.CalculateStorageUsage([]corev1.PersistentVolumeClaim{*source})
+			}
+		} else if persistentHome && !hasPVC(homePVCs, k8s.HomePVCName(req.User)) {
+			requestedStorage = quota.DefaultHomeStorage
+		}
+	}
+
+	sessionTemplate := quota.SessionTemplate{Name: templateName, Category: template.Category}
+	quotaWarnings, err := h.quotaEnforcer.CheckSessionCreationWithWarnings(ctx, req.User, requestedCPU, requestedMemory, 0, requestedStorage, sessionTemplate, currentUsage)
+	if err != nil {
+		response := gin.H{
+			"error":   "Quota exceeded",
+			"message": err.Error(),
+		}
+		// Say which per-template or per-category limit tripped
+		var templateErr *quota.TemplateLimitExceededError
+		if errors.As(err, &templateErr) {
+			response["limit"] = templateErr
+		}
+		c.JSON(http.StatusForbidden, response)
+		return
+	}
+
+	// Monthly session-hours budget, from the durations of this month's sessions
+	now := time.Now()
+	hoursUsed, err := db.NewUserDB(h.db.DB()).GetSessionHoursUsed(ctx, req.User, quota.SessionHoursPeriodStart(now), now)
+	if err != nil {
+		// Fail open for availability, as for pod usage above
+		log.Printf("Failed to get session hours for quota check: %v", err)
+	} else if err := h.quotaEnforcer.CheckSessionHoursBudget(ctx, req.User, hoursUsed); err != nil {
 		c.JSON(http.StatusForbidden, gin.H{
 			"error":   "Quota exceeded",
 			"message": err.Error(),
@@ -631,6 +902,36 @@ func (h *Handler) CreateSession(c *gin.Context) {
 		return
 	}
 
+	// Step 5a: Templates may cap how many sessions each user runs at once
+	// (licensed applications with per-seat limits). A launch beyond the cap
+	// is rejected, or held until one of the user's sessions of the template
+	// ends (see events.HoldSessionCreate).
+	holdForSlot := false
+	if limit := template.UserSessionLimit; limit != nil && limit.MaxConcurrent > 0 {
+		active, err := events.CountActiveTemplateSessions(ctx, h.db.DB(), req.User, templateName)
+		if err != nil {
+			log.Printf("Failed to check session limit of %s for %s: %v", templateName, req.User, err)
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to create session",
+				"message": "Could not check the template's per-user session limit",
+			})
+			return
+		}
+		if active >= int(limit.MaxConcurrent) {
+			if !limit.Queues() {
+				c.JSON(http.StatusConflict, gin.H{
+					"error": "Session limit reached",
+					"message": fmt.Sprintf("Template '%s' allows %d session(s) per user and you already have %d; end one of them before launching another",
+						templateName, limit.MaxConcurrent, active),
+					"limit":  limit.MaxConcurrent,
+					"active": active,
+				})
+				return
+			}
+			holdForSlot = true
+		}
+	}
+
 	// Generate session name: {user}-{template}-{random}
 	// Use resolved templateName (from applicationId lookup or req.Template)
 	sessionName := fmt.Sprintf("%s-%s-%s", req.User, templateName, uuid.New().String()[:8])
@@ -646,34 +947,33 @@ func (h *Handler) CreateSession(c *gin.Context) {
 	session.Resources.Memory = memory
 	session.Resources.CPU = cpu
 
-	if req.PersistentHome != nil {
-		session.PersistentHome = *req.PersistentHome
-	} else {
-		session.PersistentHome = true // Default
-	}
+	session.PersistentHome = persistentHome
+	session.IdleTimeout = idleTimeout
+	session.MaxSessionDuration = maxSessionDuration
 
-	if req.IdleTimeout != "" {
-		session.IdleTimeout = req.IdleTimeout
-	}
-
-	if req.MaxSessionDuration != "" {
-		session.MaxSessionDuration = req.MaxSessionDuration
-	}
-
-	if len(req.Tags) > 0 {
-		session.Tags = req.Tags
+	if len(tags) > 0 {
+		session.Tags = tags
 	}
+	session.Labels = labels
+	session.Parameters = req.Parameters
+	session.Priority = priority
 
 	// Publish session create event for controller to handle
 	// The controller will create the Session CRD in Kubernetes
 	createEvent := &events.SessionCreateEvent{
-		SessionID:      sessionName,
-		UserID:         req.User,
-		TemplateID:     templateName,
-		Platform:       h.platform,
-		Resources:      events.ResourceSpec{Memory: memory, CPU: cpu},
-		PersistentHome: session.PersistentHome,
-		IdleTimeout:    session.IdleTimeout,
+		SessionID:          sessionName,
+		UserID:             req.User,
+		TemplateID:         templateName,
+		Platform:           h.platform,
+		Resources:          events.ResourceSpec{Memory: memory, CPU: cpu},
+		PersistentHome:     session.PersistentHome,
+		IdleTimeout:        session.IdleTimeout,
+		MaxSessionDuration: session.MaxSessionDuration,
+		Tags:               session.Tags,
+		Labels:             session.Labels,
+		Parameters:         session.Parameters,
+		Priority:           session.Priority,
+		ForkFrom:           req.ForkFrom,
 	}
 
 	// Add template configuration for controller
@@ -683,10 +983,11 @@ func (h *Handler) CreateSession(c *gin.Context) {
 			vncPort = int(template.VNC.Port)
 		}
 
-		// Convert env vars to map
+		// Convert env vars to map, with parameters substituted for
+		// controllers that only receive the template config
 		envMap := make(map[string]string)
 		for _, env := range template.Env {
-			envMap[env.Name] = env.Value
+			envMap[env.Name] = k8s.SubstituteParameters(env.Value, params)
 		}
 
 		createEvent.TemplateConfig = &events.TemplateConfig{
@@ -695,9 +996,46 @@ func (h *Handler) CreateSession(c *gin.Context) {
 			DisplayName: template.DisplayName,
 			Env:         envMap,
 		}
+		createEvent.TemplateConfig.SupportedBackends = template.SupportedBackends
+		for _, port := range template.ForwardablePorts {
+			createEvent.TemplateConfig.ForwardPorts = append(createEvent.TemplateConfig.ForwardPorts, int(port))
+		}
 	}
 
-	if err := h.publisher.PublishSessionCreate(ctx, createEvent); err != nil {
+	// Match the session against controller capabilities so e.g. a GPU
+	// session is never dispatched to a controller without GPUs
+	targetController, err := h.subscriber.Controllers().Select(h.platform, sessionRequirements(template, memory, cpu))
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "No controller can run this session",
+			"message": err.Error(),
+		})
+		return
+	}
+	createEvent.TargetController = targetController
+
+	var queued bool
+	var queuePosition int
+	if holdForSlot {
+		queuePosition, err = events.HoldSessionCreate(ctx, h.db.DB(), createEvent, template.UserSessionLimit.MaxConcurrent)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to create session",
+				"message": fmt.Sprintf("Failed to queue session: %v", err),
+			})
+			return
+		}
+	} else {
+		queued, err = h.dispatchSessionCreate(ctx, createEvent)
+	}
+	if errors.Is(err, events.ErrControllersUnavailable) {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Controllers unavailable",
+			"message": "No controller is available to start the session, try again later",
+		})
+		return
+	}
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create session",
 			"message": fmt.Sprintf("Failed to publish session create event: %v", err),
@@ -722,6 +1060,18 @@ func (h *Handler) CreateSession(c *gin.Context) {
 	}
 	if err := h.sessionDB.CreateSession(ctx, dbSession); err != nil {
 		log.Printf("Failed to cache session %s in database (non-fatal): %v", sessionName, err)
+	} else if len(tags) > 0 {
+		if err := h.sessionDB.SetSessionTags(ctx, sessionName, tags); err != nil {
+			log.Printf("Failed to record tags for session %s (non-fatal): %v", sessionName, err)
+		}
+	}
+
+	// Remember explicit choices so the next launch of this template is
+	// pre-filled with them
+	if req.Resources != nil {
+		if err := h.sessionDB.SetLastResources(ctx, req.User, templateName, memory, cpu); err != nil {
+			log.Printf("Failed to remember resources for %s/%s (non-fatal): %v", req.User, templateName, err)
+		}
 	}
 
 	// Return the session info immediately
@@ -739,16 +1089,85 @@ func (h *Handler) CreateSession(c *gin.Context) {
 			"memory": memory,
 			"cpu":    cpu,
 		},
+		"resourceSource": resourceSource,
+		"priority":       session.Priority,
 		"status": map[string]string{
 			"phase":   "Pending",
 			"message": "Session creation requested, waiting for controller",
 		},
 	}
+	if req.ForkFrom != "" {
+		response["forkFrom"] = req.ForkFrom
+		if forkWarning != "" {
+			response["warning"] = forkWarning
+		}
+	}
+	if queued {
+		response["queued"] = true
+		response["status"] = map[string]string{
+			"phase":   "Pending",
+			"message": "Controllers are unavailable; the session is queued and will start when a controller picks it up",
+		}
+	}
+	// Warn users nearing their quota so they can act before a session is
+	// rejected
+	if len(quotaWarnings) > 0 {
+		response["quotaWarnings"] = quotaWarnings
+		if h.quotaWarnings != nil {
+			h.quotaWarnings(req.User, quotaWarnings)
+		}
+	}
+	if holdForSlot {
+		response["queued"] = true
+		response["queuePosition"] = queuePosition
+		response["status"] = map[string]string{
+			"phase": "Pending",
+			"message": fmt.Sprintf("Template '%s' allows %d session(s) per user; the session is queued (position %d) and will start when one of your sessions of it ends",
+				templateName, template.UserSessionLimit.MaxConcurrent, queuePosition),
+		}
+	}
 
 	log.Printf("Published session create event for %s (controller will create resources)", sessionName)
 	c.JSON(http.StatusAccepted, response)
 }
 
+// dispatchSessionCreate sends a session create event to the controllers.
+//
+// By default the event is published fire-and-forget. With
+// SESSION_CREATE_ACK_TIMEOUT set (e.g. "5s") the API waits for a controller
+// to acknowledge it instead. If none answers in time, or the publisher's
+// circuit breaker is open because controllers have stopped answering, the
+// event is stored in the database and replayed when a controller syncs or
+// heartbeats, and the session is reported as queued. Controllers subscribe
+// with core NATS, so the event would otherwise be lost. If it cannot be
+// stored either, ErrControllersUnavailable is returned.
+func (h *Handler) dispatchSessionCreate(ctx context.Context, event *events.SessionCreateEvent) (queued bool, err error) {
+	timeout, parseErr := time.ParseDuration(os.Getenv("SESSION_CREATE_ACK_TIMEOUT"))
+	if parseErr != nil || timeout <= 0 || !h.publisher.IsEnabled() {
+		return false, h.publisher.PublishSessionCreate(ctx, event)
+	}
+
+	_, err = h.publisher.RequestSessionCreate(ctx, event, timeout)
+	switch {
+	case err == nil:
+		return false, nil
+	case errors.Is(err, events.ErrControllersUnavailable),
+		errors.Is(err, nats.ErrTimeout),
+		errors.Is(err, nats.ErrNoResponders):
+		log.Printf("No controller acknowledged session %s, queueing: %v", event.SessionID, err)
+		var sqlDB *sql.DB
+		if h.db != nil {
+			sqlDB = h.db.DB()
+		}
+		if qerr := events.QueueSessionCreate(ctx, sqlDB, event); qerr != nil {
+			return false, fmt.Errorf("%w: %v", events.ErrControllersUnavailable, qerr)
+		}
+		return true, nil
+	default:
+		return false, err
+	}
+}
+
 // UpdateSession updates a session (typically state changes)
 func (h *Handler) UpdateSession(c *gin.Context) {
 	// SECURITY FIX: Use request context for proper cancellation and timeout handling
@@ -770,6 +1189,11 @@ func (h *Handler) UpdateSession(c *gin.Context) {
 		return
 	}
 
+	// SECURITY: Terminating requires a fresh MFA check (if step-up is enabled)
+	if req.State == "terminated" && !middleware.CheckMFAStepUp(c) {
+		return
+	}
+
 	// Get current session info for the event
 	session, err := h.k8sClient.GetSession(ctx, h.namespace, sessionID)
 	if err != nil {
@@ -777,31 +1201,41 @@ func (h *Handler) UpdateSession(c *gin.Context) {
 		return
 	}
 
-	// Publish state change event for controller to handle
-	var publishErr error
-	switch req.State {
-	case "hibernated":
-		event := &events.SessionHibernateEvent{
-			SessionID: sessionID,
-			UserID:    session.User,
-			Platform:  h.platform,
-		}
-		publishErr = h.publisher.PublishSessionHibernate(ctx, event)
-	case "running":
-		event := &events.SessionWakeEvent{
-			SessionID: sessionID,
-			UserID:    session.User,
-			Platform:  h.platform,
-		}
-		publishErr = h.publisher.PublishSessionWake(ctx, event)
-	case "terminated":
-		event := &events.SessionDeleteEvent{
-			SessionID: sessionID,
-			UserID:    session.User,
-			Platform:  h.platform,
+	h.requestSessionState(ctx, sessionID, req.State, sessionActor(c, session.User))
+
+	// Publish state change event for controller to handle, unless the user
+	// changes the state again right away (see events/state_commands.go).
+	// Commands carry when they were requested so the controller applies
+	// the latest one even if they arrive out of order.
+	requested := time.Now()
+	superseded, publishErr := h.stateCommands.Dispatch(sessionID, func() error {
+		switch req.State {
+		case "hibernated":
+			return h.publisher.PublishSessionHibernate(ctx, &events.SessionHibernateEvent{
+				Timestamp:        requested,
+				SessionID:        sessionID,
+				UserID:           session.User,
+				Platform:         h.platform,
+				TargetController: session.PinToAgent,
+			})
+		case "running":
+			return h.publisher.PublishSessionWake(ctx, &events.SessionWakeEvent{
+				Timestamp:        requested,
+				SessionID:        sessionID,
+				UserID:           session.User,
+				Platform:         h.platform,
+				TargetController: session.PinToAgent,
+			})
+		default:
+			return h.publisher.PublishSessionDelete(ctx, &events.SessionDeleteEvent{
+				Timestamp:        requested,
+				SessionID:        sessionID,
+				UserID:           session.User,
+				Platform:         h.platform,
+				TargetController: session.PinToAgent,
+			})
 		}
-		publishErr = h.publisher.PublishSessionDelete(ctx, event)
-	}
+	})
 
 	if publishErr != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -811,6 +1245,17 @@ func (h *Handler) UpdateSession(c *gin.Context) {
 		return
 	}
 
+	if superseded {
+		log.Printf("Dropped session %s event for %s, superseded by a later state change", req.State, sessionID)
+		c.JSON(http.StatusAccepted, gin.H{
+			"name":       sessionID,
+			"state":      req.State,
+			"superseded": true,
+			"message":    "State change superseded by a later one",
+		})
+		return
+	}
+
 	log.Printf("Published session %s event for %s (controller will update resources)", req.State, sessionID)
 	c.JSON(http.StatusAccepted, gin.H{
 		"name":    sessionID,
@@ -819,12 +1264,62 @@ func (h *Handler) UpdateSession(c *gin.Context) {
 	})
 }
 
+// sessionActor is who is acting on a session owned by owner, for the audit
+// log: the user, or "admin:<id>" for an admin acting on someone else's
+// session.
+func sessionActor(c *gin.Context, owner string) string {
+	userID := c.GetString("userID")
+	if c.GetString("userRole") == "admin" && userID != owner {
+		return events.AdminActor(userID)
+	}
+	return userID
+}
+
+// requestSessionState records that actor asked for the session's state to
+// change, so the change is audited with them once the controller reports
+// it. Failures are logged; the change is still requested.
+func (h *Handler) requestSessionState(ctx context.Context, sessionID, state, actor string) {
+	if h.db == nil {
+		return
+	}
+	if err := events.RequestSessionState(ctx, h.db.DB(), sessionID, state, actor); err != nil {
+		log.Printf("Failed to record state request of session %s (non-fatal): %v", sessionID, err)
+	}
+}
+
 // DeleteSession deletes a session
 func (h *Handler) DeleteSession(c *gin.Context) {
 	// SECURITY FIX: Use request context for proper cancellation and timeout handling
 	ctx := c.Request.Context()
 	sessionID := c.Param("id")
 
+	// A session that is still starting is cancelled rather than terminated
+	if h.db != nil {
+		if session, err := h.sessionDB.GetSession(ctx, sessionID); err == nil && isCancelableSessionState(session.State) {
+			h.cancelSessionCreate(c, session)
+			return
+		}
+	}
+
+	// A session whose create is still queued has no resources yet, so
+	// dropping the queued create is all there is to delete
+	if h.db != nil {
+		cancelled, err := events.CancelQueuedSessionCreate(ctx, h.db.DB(), sessionID)
+		if err != nil {
+			log.Printf("Failed to cancel queued create for session %s: %v", sessionID, err)
+		} else if cancelled {
+			if err := h.sessionDB.UpdateSessionState(ctx, sessionID, "terminated"); err != nil {
+				log.Printf("Failed to mark queued session %s terminated (non-fatal): %v", sessionID, err)
+			}
+			log.Printf("Cancelled queued session create for %s", sessionID)
+			c.JSON(http.StatusOK, gin.H{
+				"name":    sessionID,
+				"message": "Queued session cancelled",
+			})
+			return
+		}
+	}
+
 	// Verify session exists before deletion and get user info for event
 	session, err := h.k8sClient.GetSession(ctx, h.namespace, sessionID)
 	if err != nil {
@@ -832,11 +1327,14 @@ func (h *Handler) DeleteSession(c *gin.Context) {
 		return
 	}
 
+	h.requestSessionState(ctx, sessionID, "terminated", sessionActor(c, session.User))
+
 	// Publish session delete event for controller to handle
 	deleteEvent := &events.SessionDeleteEvent{
-		SessionID: sessionID,
-		UserID:    session.User,
-		Platform:  h.platform,
+		SessionID:        sessionID,
+		UserID:           session.User,
+		Platform:         h.platform,
+		TargetController: session.PinToAgent,
 	}
 	if err := h.publisher.PublishSessionDelete(ctx, deleteEvent); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -846,6 +1344,8 @@ func (h *Handler) DeleteSession(c *gin.Context) {
 		return
 	}
 
+	h.auditSessionEnd(ctx, "session.terminate", sessionID, session.User, session.State, "terminated", c.GetString("userID"), c.ClientIP())
+
 	log.Printf("Published session delete event for %s (controller will delete resources)", sessionID)
 	c.JSON(http.StatusAccepted, gin.H{
 		"name":    sessionID,
@@ -884,6 +1384,13 @@ func (h *Handler) ConnectSession(c *gin.Context) {
 	}
 
 	if err := h.connTracker.AddConnection(ctx, conn); err != nil {
+		if errors.Is(err, tracker.ErrSessionHibernating) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Session is hibernating",
+				"message": "The session is closing its connections before it hibernates. Wake it to connect again.",
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
@@ -1022,6 +1529,11 @@ func (h *Handler) UpdateSessionTags(c *gin.Context) {
 		return
 	}
 
+	// Keep the database copy in sync for cost attribution
+	if err := h.sessionDB.SetSessionTags(ctx, sessionID, req.Tags); err != nil {
+		log.Printf("Failed to record tags for session %s (non-fatal): %v", sessionID, err)
+	}
+
 	// Get the updated session using the k8s client
 	session, err := h.k8sClient.GetSession(ctx, h.namespace, sessionID)
 	if err != nil {
@@ -1115,6 +1627,9 @@ func (h *Handler) ListTemplates(c *gin.Context) {
 		return
 	}
 
+	// Hide templates restricted to groups the user isn't in
+	templates = h.templateAccessFor(ctx, c.GetString("userID"), c.GetString("userRole")).filter(templates)
+
 	// Apply search filter
 	if search != "" {
 		filtered := make([]*k8s.Template, 0)
@@ -1233,6 +1748,12 @@ func (h *Handler) GetTemplate(c *gin.Context) {
 		return
 	}
 
+	// Restricted templates are hidden from the catalog, so don't reveal them here either
+	if !h.templateAccessFor(ctx, c.GetString("userID"), c.GetString("userRole")).allowed(template) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
 	c.JSON(http.StatusOK, template)
 }
 
@@ -1249,6 +1770,17 @@ func (h *Handler) CreateTemplate(c *gin.Context) {
 
 	template.Namespace = h.namespace
 
+	// Store the registered category name so lookups by category don't miss
+	category, err := quota.NormalizeCategory(template.Category)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid category",
+			"message": err.Error() + " (see GET /api/v1/templates/categories)",
+		})
+		return
+	}
+	template.Category = category
+
 	created, err := h.k8sClient.CreateTemplate(ctx, &template)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -1458,15 +1990,22 @@ func (h *Handler) ListUserFavoriteTemplates(c *gin.Context) {
 		templateNames = append(templateNames, entry.Name)
 	}
 
-	// Fetch full template details from Kubernetes
+	// Fetch full template details from Kubernetes, skipping templates that
+	// are gone or now restricted to groups the user isn't in
+	access := h.templateAccessFor(ctx, userIDStr, c.GetString("userRole"))
 	templates := make([]*k8s.Template, 0, len(templateNames))
-	for _, name := range templateNames {
+	favoritedAt := make([]time.Time, 0, len(templateNames))
+	for i, name := range templateNames {
 		template, err := h.k8sClient.GetTemplate(ctx, h.namespace, name)
 		if err != nil {
 			log.Printf("Warning: Favorite template %s not found in cluster: %v", name, err)
 			continue
 		}
+		if !access.allowed(template) {
+			continue
+		}
 		templates = append(templates, template)
+		favoritedAt = append(favoritedAt, favorites[i].FavoritedAt)
 	}
 
 	// Enrich templates with favorited_at timestamp
@@ -1480,7 +2019,7 @@ func (h *Handler) ListUserFavoriteTemplates(c *gin.Context) {
 			"icon":        tmpl.Icon,
 			"tags":        tmpl.Tags,
 			"favorited":   true,
-			"favoritedAt": favorites[i].FavoritedAt,
+			"favoritedAt": favoritedAt[i],
 		}
 		enriched = append(enriched, enrichedTemplate)
 	}
@@ -2200,6 +2739,19 @@ func (h *Handler) deleteSessionFromDB(ctx context.Context, sessionID string) err
 	_, err := h.db.DB().ExecContext(ctx, `
 		DELETE FROM sessions WHERE id = $1
 	`, sessionID)
+	if err != nil {
+		return err
+	}
 
-	return err
+	return events.ForgetSessionLifecycle(ctx, h.db.DB(), sessionID)
+}
+
+// hasPVC reports whether pvcs contains a PVC named name.
+func hasPVC(pvcs []corev1.PersistentVolumeClaim, name string) bool {
+	for _, pvc := range pvcs {
+		if pvc.Name == name {
+			return true
+		}
+	}
+	return false
 }
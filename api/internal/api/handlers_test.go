@@ -4,11 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/streamspace/streamspace/api/internal/bandwidth"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -183,3 +189,72 @@ func BenchmarkVersion(b *testing.B) {
 		handler.Version(c)
 	}
 }
+
+func TestForwardSessionPort_InvalidPort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for _, port := range []string{"abc", "0", "70000"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Params = gin.Params{{Key: "id", Value: "sess-1"}, {Key: "port", Value: port}}
+		c.Request = httptest.NewRequest("GET", "/api/v1/sessions/sess-1/forward/"+port, nil)
+
+		handler := &Handler{}
+		handler.ForwardSessionPort(c)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, "port %s", port)
+	}
+}
+
+func TestIsForwardablePort(t *testing.T) {
+	allowed := []int32{22, 5432}
+
+	assert.True(t, isForwardablePort(allowed, 22))
+	assert.True(t, isForwardablePort(allowed, 5432))
+	assert.False(t, isForwardablePort(allowed, 3000))
+	assert.False(t, isForwardablePort(nil, 22))
+}
+
+func TestPipePortForward_Echo(t *testing.T) {
+	// Backend: an echo server on one end of an in-memory pipe
+	backend, remote := net.Pipe()
+	go func() {
+		defer remote.Close()
+		io.Copy(remote, remote)
+	}()
+
+	counter := &bandwidth.Counter{SessionID: "sess-1", UserID: "alice"}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := portForwardUpgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		pipePortForward(conn, backend, counter)
+	}))
+	defer server.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer client.Close()
+
+	payload := []byte("SSH-2.0-OpenSSH_9.6\r\n")
+	if err := client.WriteMessage(websocket.BinaryMessage, payload); err != nil {
+		t.Fatalf("failed to write: %v", err)
+	}
+
+	client.SetReadDeadline(time.Now().Add(5 * time.Second))
+	messageType, data, err := client.ReadMessage()
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	assert.Equal(t, websocket.BinaryMessage, messageType)
+	assert.Equal(t, payload, data)
+
+	// Both directions are counted
+	assert.Eventually(t, func() bool {
+		in, out := counter.Bytes()
+		return in == int64(len(payload)) && out == int64(len(payload))
+	}, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,304 @@
+// Package api - homevolume.go
+//
+// This file implements export and import of a session's home volume.
+//
+// Users moving between clusters or backing up their workspace can download
+// their persistent home directory as a tar archive and restore it into an
+// empty home volume elsewhere:
+//
+//	POST /api/v1/sessions/{id}/home/export   → application/x-tar download
+//	POST /api/v1/sessions/{id}/home/import   ← tar archive as request body
+//
+// The archive is streamed through a short-lived helper pod that mounts the
+// user's home PVC (read-only for export) and runs tar; nothing is buffered in
+// the API. The helper is scheduled next to a running session pod so
+// ReadWriteOnce volumes work, and is deleted when the request ends.
+//
+// Access control:
+//   - Only the session owner (or an admin) may export or import
+//   - Import only writes into an empty home volume, never merging
+//   - Archives are limited to HOME_ARCHIVE_MAX_BYTES (default 10Gi)
+//
+// Backends:
+//   - Kubernetes: helper pod (HOME_ARCHIVE_IMAGE, default busybox)
+//   - Docker: not yet supported
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/events"
+	"github.com/streamspace/streamspace/api/internal/k8s"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+const (
+	// defaultHomeArchiveMaxBytes is the archive size limit when
+	// HOME_ARCHIVE_MAX_BYTES is unset.
+	defaultHomeArchiveMaxBytes = int64(10 << 30)
+
+	// defaultHomeArchiveImage provides tar and du for the helper pod.
+	defaultHomeArchiveImage = "busybox:1.36"
+)
+
+// errHomeArchiveTooLarge is returned once an import exceeds the size limit.
+var errHomeArchiveTooLarge = errors.New("home archive exceeds size limit")
+
+// homeArchiveMaxBytes returns the archive size limit, configured with
+// HOME_ARCHIVE_MAX_BYTES as a byte count or quantity ("20Gi").
+func homeArchiveMaxBytes() int64 {
+	if v := os.Getenv("HOME_ARCHIVE_MAX_BYTES"); v != "" {
+		if q, err := resource.ParseQuantity(v); err == nil && q.Value() > 0 {
+			return q.Value()
+		}
+		log.Printf("Ignoring invalid HOME_ARCHIVE_MAX_BYTES %q", v)
+	}
+	return defaultHomeArchiveMaxBytes
+}
+
+// homeArchiveImage returns the helper pod image (HOME_ARCHIVE_IMAGE).
+func homeArchiveImage() string {
+	if v := os.Getenv("HOME_ARCHIVE_IMAGE"); v != "" {
+		return v
+	}
+	return defaultHomeArchiveImage
+}
+
+// ExportSessionHome streams the session's home volume as a tar archive.
+func (h *Handler) ExportSessionHome(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	session, ok := h.homeVolumeSession(c)
+	if !ok {
+		return
+	}
+
+	helper, ok := h.startHomeVolumeHelper(c, session, false)
+	if !ok {
+		return
+	}
+	defer h.k8sClient.DeleteHomeVolumeHelper(h.namespace, helper)
+
+	// Check the size up front: once streaming starts the status is sent
+	var du strings.Builder
+	if err := h.k8sClient.ExecInPod(ctx, h.namespace, helper, []string{"du", "-sk", k8s.HomeMountPath}, nil, &du); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to export home volume",
+			"message": fmt.Sprintf("Failed to measure home volume: %v", err),
+		})
+		return
+	}
+	size, err := parseDuKilobytes(du.String())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export home volume", "message": err.Error()})
+		return
+	}
+	if maxBytes := homeArchiveMaxBytes(); size > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":   "Home volume too large",
+			"message": fmt.Sprintf("Home volume holds %d bytes, the export limit is %d", size, maxBytes),
+		})
+		return
+	}
+
+	c.Header("Content-Type", "application/x-tar")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-home.tar"`, session.ID))
+	c.Status(http.StatusOK)
+
+	tarCmd := []string{"tar", "-C", k8s.HomeMountPath, "-cf", "-", "."}
+	if err := h.k8sClient.ExecInPod(ctx, h.namespace, helper, tarCmd, nil, c.Writer); err != nil {
+		// Headers are already sent; the truncated archive fails to extract
+		log.Printf("Home export of session %s failed mid-stream: %v", session.ID, err)
+		c.Abort()
+		return
+	}
+	log.Printf("Exported home volume of session %s for user %s", session.ID, session.UserID)
+}
+
+// ImportSessionHome extracts a tar archive from the request body into the
+// session's home volume, which must be empty.
+func (h *Handler) ImportSessionHome(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	session, ok := h.homeVolumeSession(c)
+	if !ok {
+		return
+	}
+
+	maxBytes := homeArchiveMaxBytes()
+	if c.Request.ContentLength > maxBytes {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error":   "Home archive too large",
+			"message": fmt.Sprintf("The import limit is %d bytes", maxBytes),
+		})
+		return
+	}
+
+	helper, ok := h.startHomeVolumeHelper(c, session, true)
+	if !ok {
+		return
+	}
+	defer h.k8sClient.DeleteHomeVolumeHelper(h.namespace, helper)
+
+	var existing strings.Builder
+	if err := h.k8sClient.ExecInPod(ctx, h.namespace, helper, []string{"ls", "-A", k8s.HomeMountPath}, nil, &existing); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to import home volume",
+			"message": fmt.Sprintf("Failed to inspect home volume: %v", err),
+		})
+		return
+	}
+	if strings.TrimSpace(existing.String()) != "" {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Home volume is not empty",
+			"message": "Archives can only be imported into an empty home volume",
+		})
+		return
+	}
+
+	body := &homeArchiveReader{r: c.Request.Body, remaining: maxBytes}
+	tarCmd := []string{"tar", "-C", k8s.HomeMountPath, "-xf", "-"}
+	err := h.k8sClient.ExecInPod(ctx, h.namespace, helper, tarCmd, body, nil)
+	if err == nil && body.exceeded {
+		err = errHomeArchiveTooLarge
+	}
+	if err != nil {
+		// Leave the volume empty rather than half restored
+		cleanup := []string{"find", k8s.HomeMountPath, "-mindepth", "1", "-delete"}
+		if cleanupErr := h.k8sClient.ExecInPod(context.Background(), h.namespace, helper, cleanup, nil, nil); cleanupErr != nil {
+			log.Printf("Failed to clean up partial home import of session %s: %v", session.ID, cleanupErr)
+		}
+
+		if body.exceeded {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error":   "Home archive too large",
+				"message": fmt.Sprintf("The import limit is %d bytes", maxBytes),
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Failed to import home volume",
+			"message": fmt.Sprintf("Archive could not be extracted: %v", err),
+		})
+		return
+	}
+
+	log.Printf("Imported home volume of session %s for user %s", session.ID, session.UserID)
+	c.JSON(http.StatusOK, gin.H{"message": "Home volume imported", "session_id": session.ID})
+}
+
+// homeVolumeSession loads the session of a home export/import request and
+// checks the caller may access its home volume. It writes the error response
+// and returns false otherwise.
+func (h *Handler) homeVolumeSession(c *gin.Context) (*db.Session, bool) {
+	sessionID := c.Param("id")
+
+	session, err := h.sessionDB.GetSession(c.Request.Context(), sessionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return nil, false
+	}
+
+	// SECURITY: Only the session owner or an admin may access the home volume
+	userID := c.GetString("userID")
+	username := c.GetString("username")
+	if session.UserID != userID && session.UserID != username && c.GetString("userRole") != "admin" {
+		log.Printf("Denied home volume access to session %s for user %s", sessionID, userID)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, false
+	}
+
+	if !session.PersistentHome {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Session has no home volume",
+			"message": "Home export and import require a session with persistent home",
+		})
+		return nil, false
+	}
+
+	if session.Platform == events.PlatformDocker {
+		c.JSON(http.StatusNotImplemented, gin.H{
+			"error":   "Not supported on this platform",
+			"message": "Home export and import are not yet available for Docker sessions",
+		})
+		return nil, false
+	}
+
+	return session, true
+}
+
+// startHomeVolumeHelper starts the helper pod for a session's home volume,
+// next to the session pod if it is running. It writes the error response
+// and returns false on failure.
+func (h *Handler) startHomeVolumeHelper(c *gin.Context, session *db.Session, writable bool) (string, bool) {
+	ctx := c.Request.Context()
+
+	var nodeName string
+	if pod, err := h.k8sClient.FindSessionPod(ctx, h.namespace, session.ID); err == nil {
+		nodeName = pod.Spec.NodeName
+	}
+
+	helper, err := h.k8sClient.StartHomeVolumeHelper(ctx, h.namespace, session.UserID, homeArchiveImage(), nodeName, writable)
+	if err != nil {
+		log.Printf("Failed to start home archive helper for session %s: %v", session.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to access home volume",
+			"message": err.Error(),
+		})
+		return "", false
+	}
+	return helper.Name, true
+}
+
+// parseDuKilobytes parses the output of `du -sk` into bytes.
+func parseDuKilobytes(output string) (int64, error) {
+	fields := strings.Fields(output)
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("unexpected du output %q", output)
+	}
+	kb, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected du output %q", output)
+	}
+	return kb * 1024, nil
+}
+
+// homeArchiveReader passes through up to remaining bytes and then fails
+// with errHomeArchiveTooLarge, recording that the limit was hit.
+//
+// The exec stream swallows stdin errors, so the flag is how the handler
+// learns the import was cut short.
+type homeArchiveReader struct {
+	r         io.Reader
+	remaining int64
+	exceeded  bool
+}
+
+func (l *homeArchiveReader) Read(p []byte) (int, error) {
+	if l.remaining <= 0 {
+		// Anything beyond the limit means the archive is too large
+		var probe [1]byte
+		n, err := l.r.Read(probe[:])
+		if n > 0 {
+			l.exceeded = true
+			return 0, errHomeArchiveTooLarge
+		}
+		return 0, err
+	}
+	if int64(len(p)) > l.remaining {
+		p = p[:l.remaining]
+	}
+	n, err := l.r.Read(p)
+	l.remaining -= int64(n)
+	return n, err
+}
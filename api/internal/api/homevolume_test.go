@@ -0,0 +1,52 @@
+package api
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHomeArchiveMaxBytes(t *testing.T) {
+	t.Setenv("HOME_ARCHIVE_MAX_BYTES", "")
+	assert.Equal(t, defaultHomeArchiveMaxBytes, homeArchiveMaxBytes())
+
+	t.Setenv("HOME_ARCHIVE_MAX_BYTES", "2Gi")
+	assert.Equal(t, int64(2<<30), homeArchiveMaxBytes())
+
+	t.Setenv("HOME_ARCHIVE_MAX_BYTES", "1048576")
+	assert.Equal(t, int64(1<<20), homeArchiveMaxBytes())
+
+	t.Setenv("HOME_ARCHIVE_MAX_BYTES", "lots")
+	assert.Equal(t, defaultHomeArchiveMaxBytes, homeArchiveMaxBytes())
+}
+
+func TestParseDuKilobytes(t *testing.T) {
+	size, err := parseDuKilobytes("2048\t/config\n")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2048*1024), size)
+
+	_, err = parseDuKilobytes("")
+	assert.Error(t, err)
+	_, err = parseDuKilobytes("du: can't open '/config': Permission denied")
+	assert.Error(t, err)
+}
+
+func TestHomeArchiveReader(t *testing.T) {
+	t.Run("within limit", func(t *testing.T) {
+		r := &homeArchiveReader{r: strings.NewReader("archive"), remaining: 7}
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, "archive", string(data))
+		assert.False(t, r.exceeded)
+	})
+
+	t.Run("over limit", func(t *testing.T) {
+		r := &homeArchiveReader{r: strings.NewReader("archive!"), remaining: 7}
+		_, err := io.ReadAll(r)
+		assert.ErrorIs(t, err, errHomeArchiveTooLarge)
+		assert.True(t, r.exceeded)
+	})
+}
@@ -0,0 +1,122 @@
+// Package api provides HTTP handlers and WebSocket endpoints for the StreamSpace API.
+// This file implements per-user launch resource defaults.
+//
+// Users don't want to re-enter the same memory/CPU every time they launch a
+// template. When a session is created with explicit resources, they are
+// remembered per user and template and used for the next launch that
+// doesn't specify any. Remembered values are still validated and checked
+// against the user's quota on every launch. Templates without defaults get
+// the defaults of their category (see quota.GetDefaultResources).
+//
+// Endpoints:
+//
+//	GET    /api/v1/templates/:id/launch-defaults  - resources the next launch will use
+//	DELETE /api/v1/templates/:id/launch-defaults  - forget them (use template defaults)
+package api
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/k8s"
+	"github.com/streamspace/streamspace/api/internal/quota"
+)
+
+// Where a launch's resources came from.
+const (
+	resourceSourceRequest    = "request"    // specified in the create request
+	resourceSourceRemembered = "remembered" // the user's last choice for the template
+	resourceSourceTemplate   = "template"   // template defaultResources
+	resourceSourceCategory   = "category"   // defaults of the template's category
+	resourceSourceSystem     = "system"     // built-in defaults
+)
+
+// System resource defaults when the template doesn't define any.
+const (
+	defaultSessionMemory = "2Gi"
+	defaultSessionCPU    = "1000m"
+)
+
+// launchResources returns the resources a launch uses when the request
+// doesn't specify any: the user's last choice for the template if it is
+// still valid, else the template defaults, else the defaults of the
+// template's category, else the system defaults.
+func (h *Handler) launchResources(ctx context.Context, user string, template *k8s.Template) (memory, cpu, source string) {
+	if h.sessionDB != nil && user != "" {
+		last, err := h.sessionDB.GetLastResources(ctx, user, template.Name)
+		if err != nil {
+			log.Printf("Failed to get remembered resources for %s/%s: %v", user, template.Name, err)
+		} else if last != nil {
+			// Limits may have changed since they were chosen
+			if _, _, err := h.quotaEnforcer.ValidateResourceRequest(last.CPU, last.Memory); err == nil {
+				return last.Memory, last.CPU, resourceSourceRemembered
+			}
+			log.Printf("Ignoring remembered resources for %s/%s: %v", user, template.Name, err)
+		}
+	}
+
+	memory, cpu, source = defaultSessionMemory, defaultSessionCPU, resourceSourceSystem
+	if category, ok := quota.LookupCategory(template.Category); ok {
+		memory, cpu, source = category.Memory, category.CPU, resourceSourceCategory
+	}
+	if template.DefaultResources.Memory != "" || template.DefaultResources.CPU != "" {
+		source = resourceSourceTemplate
+		if template.DefaultResources.Memory != "" {
+			memory = template.DefaultResources.Memory
+		}
+		if template.DefaultResources.CPU != "" {
+			cpu = template.DefaultResources.CPU
+		}
+	}
+	return memory, cpu, source
+}
+
+// GetLaunchDefaults returns the resources the user's next launch of a
+// template will use, so the UI can pre-fill the launch form.
+func (h *Handler) GetLaunchDefaults(c *gin.Context) {
+	ctx := c.Request.Context()
+	templateID := c.Param("id")
+
+	template, err := h.k8sClient.GetTemplate(ctx, h.namespace, templateID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+
+	memory, cpu, source := h.launchResources(ctx, c.GetString("username"), template)
+	c.JSON(http.StatusOK, gin.H{
+		"template": templateID,
+		"resources": gin.H{
+			"memory": memory,
+			"cpu":    cpu,
+		},
+		"source": source,
+		"templateDefaults": gin.H{
+			"memory": template.DefaultResources.Memory,
+			"cpu":    template.DefaultResources.CPU,
+		},
+	})
+}
+
+// ResetLaunchDefaults forgets the user's remembered resources for a
+// template so launches use the template defaults again.
+func (h *Handler) ResetLaunchDefaults(c *gin.Context) {
+	username := c.GetString("username")
+	if username == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+		return
+	}
+
+	if err := h.sessionDB.ResetLastResources(c.Request.Context(), username, c.Param("id")); err != nil {
+		log.Printf("Failed to reset launch defaults: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to reset launch defaults",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Launch defaults reset to template defaults"})
+}
@@ -0,0 +1,56 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/k8s"
+	"github.com/streamspace/streamspace/api/internal/quota"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLaunchResources_Priority(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	h := &Handler{sessionDB: db.NewSessionDB(sqlDB), quotaEnforcer: quota.NewEnforcer(nil, nil)}
+	ctx := context.Background()
+
+	template := &k8s.Template{Name: "firefox"}
+	template.DefaultResources.Memory = "1Gi"
+	template.DefaultResources.CPU = "500m"
+
+	lastResources := func(memory, cpu string) {
+		mock.ExpectQuery("SELECT memory, cpu, updated_at").
+			WithArgs("alice", "firefox").
+			WillReturnRows(sqlmock.NewRows([]string{"memory", "cpu", "updated_at"}).AddRow(memory, cpu, time.Now()))
+	}
+
+	// Remembered choice wins over template defaults
+	lastResources("4Gi", "2000m")
+	memory, cpu, source := h.launchResources(ctx, "alice", template)
+	assert.Equal(t, []string{"4Gi", "2000m", resourceSourceRemembered}, []string{memory, cpu, source})
+
+	// Remembered values that are no longer valid are ignored
+	lastResources("4Gi", "10m")
+	memory, cpu, source = h.launchResources(ctx, "alice", template)
+	assert.Equal(t, []string{"1Gi", "500m", resourceSourceTemplate}, []string{memory, cpu, source})
+
+	// Nothing remembered, no template defaults
+	mock.ExpectQuery("SELECT memory, cpu, updated_at").WithArgs("alice", "bare").WillReturnError(sql.ErrNoRows)
+	memory, cpu, source = h.launchResources(ctx, "alice", &k8s.Template{Name: "bare"})
+	assert.Equal(t, []string{defaultSessionMemory, defaultSessionCPU, resourceSourceSystem}, []string{memory, cpu, source})
+
+	// No template defaults: the category's, whatever alias the template uses
+	mock.ExpectQuery("SELECT memory, cpu, updated_at").WithArgs("alice", "vscode").WillReturnError(sql.ErrNoRows)
+	memory, cpu, source = h.launchResources(ctx, "alice", &k8s.Template{Name: "vscode", Category: "IDE"})
+	assert.Equal(t, []string{"4096Mi", "2000m", resourceSourceCategory}, []string{memory, cpu, source})
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,272 @@
+// Package api - portforward.go
+//
+// This file implements the session port-forward endpoint.
+//
+// Browsers reach a session through its VNC/web URL, but some workloads expose
+// other TCP services (SSH, databases, language servers, debuggers). The
+// port-forward endpoint tunnels a raw TCP stream to one of those ports over a
+// WebSocket, so CLI tools can reach them without exposing the port publicly:
+//
+//	GET /api/v1/sessions/{id}/forward/{port}   (WebSocket upgrade)
+//
+// Every binary WebSocket message carries bytes for the TCP stream in either
+// direction. Closing either side closes the other.
+//
+// Bandwidth:
+//   - The bytes carried in each direction are counted per session and user
+//     (see the bandwidth package)
+//   - Tunnels are refused (429) once the owner reached the daily
+//     maxBandwidthPerDay of their user or group quota
+//
+// Access control:
+//   - Only the session owner (or an admin) may open a tunnel
+//   - The port must be listed in the template's forwardablePorts allowlist
+//
+// Backends:
+//   - Kubernetes: dials the session pod through the port-forward API
+//   - Docker: dials the host port the docker-controller published for the
+//     container port (DOCKER_FORWARD_HOST, default localhost)
+package api
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/streamspace/streamspace/api/internal/bandwidth"
+	"github.com/streamspace/streamspace/api/internal/events"
+	"github.com/streamspace/streamspace/api/internal/middleware"
+	"github.com/streamspace/streamspace/api/internal/quota"
+)
+
+const (
+	// portForwardBufferSize is the maximum payload of a single tunnel message.
+	portForwardBufferSize = 32 * 1024
+
+	// portForwardDialTimeout bounds how long we wait for the backend.
+	portForwardDialTimeout = 10 * time.Second
+)
+
+// portForwardUpgrader accepts clients that send no Origin header.
+//
+// Browsers always send Origin on WebSocket handshakes, so a missing Origin
+// means a non-browser client (CLI, SSH ProxyCommand) that authenticated with
+// a bearer token and can't be the victim of cross-site WebSocket hijacking,
+// unless strict mode rejects them (WEBSOCKET_REJECT_EMPTY_ORIGIN). Browser
+// requests are still checked against ALLOWED_ORIGINS.
+var portForwardUpgrader = websocket.Upgrader{
+	ReadBufferSize:  portForwardBufferSize,
+	WriteBufferSize: portForwardBufferSize,
+	CheckOrigin: func(r *http.Request) bool {
+		if r.Header.Get("Origin") == "" {
+			return middleware.WebSocketOriginPolicyFromEnv().AllowEmpty()
+		}
+		return upgrader.CheckOrigin(r)
+	},
+}
+
+// ForwardSessionPort tunnels a WebSocket to a TCP port inside a session.
+func (h *Handler) ForwardSessionPort(c *gin.Context) {
+	ctx := c.Request.Context()
+	sessionID := c.Param("id")
+
+	port, err := strconv.Atoi(c.Param("port"))
+	if err != nil || port < 1 || port > 65535 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid port"})
+		return
+	}
+
+	session, err := h.sessionDB.GetSession(ctx, sessionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	// SECURITY: Only the session owner or an admin may tunnel into a session
+	userID := c.GetString("userID")
+	username := c.GetString("username")
+	if session.UserID != userID && session.UserID != username && c.GetString("userRole") != "admin" {
+		log.Printf("Denied port-forward to session %s port %d for user %s", sessionID, port, userID)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	if session.State != "running" {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Session is not running",
+			"message": fmt.Sprintf("Session is %s", session.State),
+		})
+		return
+	}
+
+	// SECURITY: Only ports the template explicitly allows can be forwarded
+	template, err := h.k8sClient.GetTemplate(ctx, h.namespace, session.TemplateName)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Template not found"})
+		return
+	}
+	if !isForwardablePort(template.ForwardablePorts, port) {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Port not forwardable",
+			"message": fmt.Sprintf("Port %d is not in the template's forwardablePorts", port),
+		})
+		return
+	}
+
+	if h.bandwidth != nil && h.quotaEnforcer != nil {
+		used, err := h.bandwidth.UserDailyUsage(ctx, session.UserID, time.Now())
+		if err == nil {
+			err = h.quotaEnforcer.CheckBandwidth(ctx, session.UserID, used)
+		}
+		if quota.IsQuotaExceeded(err) {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":   "Bandwidth quota exceeded",
+				"message": err.Error(),
+			})
+			return
+		} else if err != nil {
+			log.Printf("Failed to check bandwidth quota for user %s: %v", session.UserID, err)
+		}
+	}
+
+	// Dial the backend before upgrading so failures surface as HTTP errors
+	dialCtx, cancel := context.WithTimeout(ctx, portForwardDialTimeout)
+	defer cancel()
+
+	backend, err := h.dialSessionPort(dialCtx, session.ID, session.Platform, port)
+	if err != nil {
+		log.Printf("Port-forward to session %s port %d failed: %v", sessionID, port, err)
+		c.JSON(http.StatusBadGateway, gin.H{
+			"error":   "Failed to connect to session port",
+			"message": err.Error(),
+		})
+		return
+	}
+	defer backend.Close()
+
+	conn, err := portForwardUpgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade port-forward connection: %v", err)
+		return
+	}
+	defer conn.Close()
+
+	var counter *bandwidth.Counter
+	if h.bandwidth != nil {
+		counter = h.bandwidth.Open(session.ID, session.UserID)
+		defer h.bandwidth.Close(counter)
+	}
+
+	log.Printf("Port-forward opened: session=%s port=%d user=%s", sessionID, port, userID)
+	pipePortForward(conn, backend, counter)
+	log.Printf("Port-forward closed: session=%s port=%d user=%s", sessionID, port, userID)
+}
+
+// dialSessionPort opens a TCP stream to a port inside a session.
+//
+// The dial context only bounds connection setup; the returned stream lives
+// until it is closed.
+func (h *Handler) dialSessionPort(ctx context.Context, sessionID, platform string, port int) (io.ReadWriteCloser, error) {
+	switch platform {
+	case events.PlatformDocker:
+		ports, err := h.sessionDB.GetForwardedPorts(ctx, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		hostPort, ok := ports[strconv.Itoa(port)]
+		if !ok {
+			return nil, fmt.Errorf("port %d is not published for session %s", port, sessionID)
+		}
+
+		host := os.Getenv("DOCKER_FORWARD_HOST")
+		if host == "" {
+			host = "localhost"
+		}
+
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(hostPort)))
+
+	default:
+		pod, err := h.k8sClient.FindSessionPod(ctx, h.namespace, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		// The stream must outlive the dial timeout, so don't tie it to ctx
+		return h.k8sClient.PortForward(context.Background(), h.namespace, pod.Name, int32(port))
+	}
+}
+
+// pipePortForward copies data between the WebSocket and the backend stream
+// until either side closes, counting the bytes on counter if it is non-nil.
+func pipePortForward(conn *websocket.Conn, backend io.ReadWriteCloser, counter *bandwidth.Counter) {
+	var once sync.Once
+	done := make(chan struct{})
+	closeBoth := func() {
+		once.Do(func() {
+			close(done)
+			backend.Close()
+			conn.Close()
+		})
+	}
+
+	// Backend -> client
+	go func() {
+		defer closeBoth()
+		buf := make([]byte, portForwardBufferSize)
+		for {
+			n, err := backend.Read(buf)
+			if n > 0 {
+				if writeErr := conn.WriteMessage(websocket.BinaryMessage, buf[:n]); writeErr != nil {
+					return
+				}
+				if counter != nil {
+					counter.AddOut(n)
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// Client -> backend
+	go func() {
+		defer closeBoth()
+		for {
+			messageType, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if messageType != websocket.BinaryMessage {
+				continue
+			}
+			if _, err := backend.Write(data); err != nil {
+				return
+			}
+			if counter != nil {
+				counter.AddIn(len(data))
+			}
+		}
+	}()
+
+	<-done
+}
+
+// isForwardablePort reports whether port is in the template allowlist.
+func isForwardablePort(allowed []int32, port int) bool {
+	for _, p := range allowed {
+		if int(p) == port {
+			return true
+		}
+	}
+	return false
+}
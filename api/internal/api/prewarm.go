@@ -0,0 +1,139 @@
+// Package api - prewarm.go
+//
+// This file implements session pre-warming settings and the pre-warm wake.
+//
+// Users who start the same template at about the same time every workday
+// can opt in to having their hibernated session woken shortly before then,
+// so it is ready when they sit down (see package prewarm):
+//
+//	GET /api/v1/sessions/prewarm   → settings and predicted start times
+//	PUT /api/v1/sessions/prewarm   → update settings
+//
+// Pre-warm wakes count against the user's quota like any other start and
+// are skipped when the user is at their limit. They are audited with the
+// actor "reconciler:prewarm".
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/events"
+	"github.com/streamspace/streamspace/api/internal/prewarm"
+	"github.com/streamspace/streamspace/api/internal/quota"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// GetSessionPrewarm returns the user's pre-warm settings and predictions.
+func (h *Handler) GetSessionPrewarm(c *gin.Context) {
+	if !h.prewarmAvailable(c) {
+		return
+	}
+
+	forecast, err := h.prewarmer.Forecast(c.Request.Context(), c.GetString("userID"), time.Now())
+	if err != nil {
+		log.Printf("Failed to load pre-warm forecast: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to load pre-warm settings",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, forecast)
+}
+
+// UpdateSessionPrewarm updates the user's pre-warm settings. Saving the
+// settings lifts suspensions caused by unused pre-warms.
+func (h *Handler) UpdateSessionPrewarm(c *gin.Context) {
+	if !h.prewarmAvailable(c) {
+		return
+	}
+
+	var settings prewarm.Settings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetString("userID")
+	if err := h.prewarmer.SetSettings(c.Request.Context(), userID, &settings); err != nil {
+		log.Printf("Failed to save pre-warm settings of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to save pre-warm settings",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	h.GetSessionPrewarm(c)
+}
+
+// prewarmAvailable reports whether pre-warming is enabled, responding with
+// 503 if not.
+func (h *Handler) prewarmAvailable(c *gin.Context) bool {
+	if h.prewarmer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Pre-warming unavailable",
+			"message": "Session pre-warming is not enabled",
+		})
+		return false
+	}
+	return true
+}
+
+// PrewarmSession wakes a hibernated session ahead of its predicted start.
+// It is the prewarm.Waker used by the pre-warmer: the wake is checked
+// against the user's quota like a new session.
+func (h *Handler) PrewarmSession(ctx context.Context, candidate *prewarm.Candidate) error {
+	if h.quotaEnforcer != nil {
+		cpu, memory, err := h.quotaEnforcer.ValidateResourceRequest(candidate.CPU, candidate.Memory)
+		if err != nil {
+			return fmt.Errorf("invalid session resources: %w", err)
+		}
+
+		podList, err := h.k8sClient.GetPods(ctx, h.namespace)
+		if err != nil {
+			log.Printf("Failed to get pods for quota check: %v", err)
+			podList = &corev1.PodList{}
+		}
+		userPods := make([]corev1.Pod, 0)
+		for _, pod := range podList.Items {
+			if user, ok := pod.Labels["user"]; ok && user == candidate.UserID {
+				userPods = append(userPods, pod)
+			}
+		}
+
+		category := ""
+		if template, err := h.k8sClient.GetTemplate(ctx, h.namespace, candidate.TemplateName); err == nil {
+			category = template.Category
+		}
+		usage := h.quotaEnforcer.CalculateUsage(userPods)
+		usage.CountCategories(func(name string) string {
+			if name == candidate.TemplateName {
+				return category
+			}
+			if t, err := h.k8sClient.GetTemplate(ctx, h.namespace, name); err == nil {
+				return t.Category
+			}
+			return ""
+		})
+
+		sessionTemplate := quota.SessionTemplate{Name: candidate.TemplateName, Category: category}
+		if err := h.quotaEnforcer.CheckSessionCreation(ctx, candidate.UserID, cpu, memory, 0, 0, sessionTemplate, usage); err != nil {
+			return fmt.Errorf("quota exceeded: %w", err)
+		}
+	}
+
+	h.requestSessionState(ctx, candidate.SessionID, "running", events.ActorPrewarm)
+
+	return h.publisher.PublishSessionWake(ctx, &events.SessionWakeEvent{
+		SessionID: candidate.SessionID,
+		UserID:    candidate.UserID,
+		Platform:  h.platform,
+	})
+}
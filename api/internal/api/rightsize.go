@@ -0,0 +1,217 @@
+// Package api - rightsize.go
+//
+// This file implements session right-sizing recommendations.
+//
+// Sessions are often requested far larger than they are used (request 8Gi,
+// use 1Gi). The API samples each session pod's usage from metrics-server and
+// recommends requests and limits from the p95 and peak of what the session
+// actually used (see package rightsize):
+//
+//	GET  /api/v1/sessions/{id}/rightsize         → recommendation
+//	POST /api/v1/sessions/{id}/rightsize/apply   → apply recommendation
+//	GET  /api/v1/admin/rightsize?limit=50        → cluster-wide over-provisioning
+//
+// Applying updates the Session's spec.resources and the requested CPU/memory
+// recorded for quotas and cost. Running pods are not resized in place: the
+// new resources take effect the next time the session is started or woken.
+//
+// Access control:
+//   - Only the session owner (or an admin) may view or apply a recommendation
+//   - Only confident recommendations (enough samples) can be applied
+//   - The cluster-wide overview is admin only
+//
+// Backends:
+//   - Kubernetes: metrics-server pod metrics
+//   - Docker: not yet supported
+package api
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/events"
+	"github.com/streamspace/streamspace/api/internal/rightsize"
+)
+
+// rightsizeOverviewDefaultLimit is the number of sessions listed in the
+// cluster-wide overview when no limit is given
+const rightsizeOverviewDefaultLimit = 50
+
+// GetSessionRightsize returns the right-sizing recommendation for a session.
+func (h *Handler) GetSessionRightsize(c *gin.Context) {
+	session, ok := h.rightsizeSession(c)
+	if !ok {
+		return
+	}
+
+	rec, ok := h.sessionRecommendation(c, session)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, rec)
+}
+
+// ApplySessionRightsize applies the right-sizing recommendation to a session.
+func (h *Handler) ApplySessionRightsize(c *gin.Context) {
+	session, ok := h.rightsizeSession(c)
+	if !ok {
+		return
+	}
+
+	rec, ok := h.sessionRecommendation(c, session)
+	if !ok {
+		return
+	}
+
+	if !rec.Confident {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   "Not enough usage data",
+			"message": "The session has not been observed long enough for a reliable recommendation",
+		})
+		return
+	}
+
+	if h.quotaEnforcer != nil {
+		if _, _, err := h.quotaEnforcer.ValidateResourceRequest(rec.Requests.CPU, rec.Requests.Memory); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid resource request",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := h.ApplyRightsizeRecommendation(c.Request.Context(), rec); err != nil {
+		log.Printf("Failed to apply right-sizing to session %s: %v", session.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to apply recommendation",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"recommendation": rec,
+		"applied":        true,
+		"message":        "New resources take effect the next time the session starts or wakes",
+	})
+}
+
+// GetRightsizeOverview returns cluster-wide over-provisioning (admin only).
+func (h *Handler) GetRightsizeOverview(c *gin.Context) {
+	if c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+	if h.rightsizer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Right-sizing unavailable",
+			"message": "Session usage sampling is not enabled",
+		})
+		return
+	}
+
+	limit := rightsizeOverviewDefaultLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid limit",
+				"message": "limit must be a positive integer",
+			})
+			return
+		}
+		limit = n
+	}
+
+	overview, err := h.rightsizer.Overview(c.Request.Context(), limit)
+	if err != nil {
+		log.Printf("Failed to build right-sizing overview: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to build right-sizing overview",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, overview)
+}
+
+// ApplyRightsizeRecommendation sets a session's requests and limits to the
+// recommendation. It is also used by the sampler to auto-apply.
+func (h *Handler) ApplyRightsizeRecommendation(ctx context.Context, rec *rightsize.Recommendation) error {
+	requests := map[string]string{"cpu": rec.Requests.CPU, "memory": rec.Requests.Memory}
+	limits := map[string]string{"cpu": rec.Limits.CPU, "memory": rec.Limits.Memory}
+
+	if _, err := h.k8sClient.UpdateSessionResources(ctx, h.namespace, rec.SessionID, requests, limits); err != nil {
+		return err
+	}
+
+	return h.sessionDB.UpdateSessionResources(ctx, rec.SessionID, rec.Requests.CPU, rec.Requests.Memory)
+}
+
+// rightsizeSession loads the session for a right-sizing request and checks
+// access. It writes the error response and returns false on failure.
+func (h *Handler) rightsizeSession(c *gin.Context) (*db.Session, bool) {
+	if h.rightsizer == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error":   "Right-sizing unavailable",
+			"message": "Session usage sampling is not enabled",
+		})
+		return nil, false
+	}
+
+	sessionID := c.Param("id")
+	session, err := h.sessionDB.GetSession(c.Request.Context(), sessionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return nil, false
+	}
+
+	// SECURITY: Only the session owner or an admin may see or change sizing
+	userID := c.GetString("userID")
+	username := c.GetString("username")
+	if session.UserID != userID && session.UserID != username && c.GetString("userRole") != "admin" {
+		log.Printf("Denied right-sizing access to session %s for user %s", sessionID, userID)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return nil, false
+	}
+
+	if session.Platform == events.PlatformDocker {
+		c.JSON(http.StatusNotImplemented, gin.H{
+			"error":   "Not supported on this platform",
+			"message": "Right-sizing is not yet available for Docker sessions",
+		})
+		return nil, false
+	}
+
+	return session, true
+}
+
+// sessionRecommendation computes the recommendation for a session. It writes
+// the error response and returns false on failure.
+func (h *Handler) sessionRecommendation(c *gin.Context, session *db.Session) (*rightsize.Recommendation, bool) {
+	rec, err := h.rightsizer.Recommend(c.Request.Context(), session.ID)
+	if errors.Is(err, rightsize.ErrNoSamples) {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "No usage data",
+			"message": "No resource usage has been recorded for this session yet",
+		})
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Failed to compute right-sizing for session %s: %v", session.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to compute recommendation",
+			"message": err.Error(),
+		})
+		return nil, false
+	}
+	return rec, true
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/rightsize"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rightsizeContext(method, sessionID, userID, role string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(method, "/api/v1/sessions/"+sessionID+"/rightsize", nil)
+	c.Params = gin.Params{{Key: "id", Value: sessionID}}
+	c.Set("userID", userID)
+	c.Set("userRole", role)
+	return c, w
+}
+
+func expectRightsizeSession(mock sqlmock.Sqlmock, sessionID, owner string) {
+	now := time.Now()
+	mock.ExpectQuery("FROM sessions").WithArgs(sessionID).WillReturnRows(sqlmock.NewRows([]string{
+		"id", "user_id", "team_id", "template_name", "state", "app_type", "active_connections", "url",
+		"namespace", "platform", "pod_name", "memory", "cpu", "persistent_home", "idle_timeout",
+		"max_session_duration", "created_at", "updated_at", "last_connection", "last_disconnect", "last_activity",
+	}).AddRow(sessionID, owner, "", "firefox", "running", "desktop", 0, "", "streamspace", "kubernetes", "",
+		"8Gi", "2000m", false, "", "", now, now, nil, nil, nil))
+}
+
+func TestGetSessionRightsize_Unavailable(t *testing.T) {
+	c, w := rightsizeContext("GET", "s1", "alice", "user")
+	(&Handler{}).GetSessionRightsize(c)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
+
+func TestGetSessionRightsize_OtherUsersSession(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	h := &Handler{
+		sessionDB:  db.NewSessionDB(sqlDB),
+		rightsizer: rightsize.NewRecommender(db.NewDatabaseForTesting(sqlDB), rightsize.Config{MinSamples: 10}),
+	}
+	expectRightsizeSession(mock, "s1", "alice")
+
+	c, w := rightsizeContext("GET", "s1", "mallory", "user")
+	h.GetSessionRightsize(c)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestApplySessionRightsize_NotConfident(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	h := &Handler{
+		sessionDB:  db.NewSessionDB(sqlDB),
+		rightsizer: rightsize.NewRecommender(db.NewDatabaseForTesting(sqlDB), rightsize.Config{MinSamples: 10}),
+	}
+	expectRightsizeSession(mock, "s1", "alice")
+	now := time.Now()
+	mock.ExpectQuery("FROM sessions s").WithArgs("s1").WillReturnRows(sqlmock.NewRows([]string{
+		"id", "user_id", "template_name", "cpu", "memory", "count", "min", "max", "p95_cpu", "p95_mem", "max_cpu", "max_mem",
+	}).AddRow("s1", "alice", "firefox", "2000m", "8Gi", 3, now, now, 100.0, float64(512<<20), int64(200), int64(600<<20)))
+
+	// Too few samples: nothing is applied to the Session or the database
+	c, w := rightsizeContext("POST", "s1", "alice", "user")
+	h.ApplySessionRightsize(c)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetRightsizeOverview_AdminOnly(t *testing.T) {
+	h := &Handler{rightsizer: &rightsize.Recommender{}}
+
+	c, w := rightsizeContext("GET", "", "alice", "user")
+	h.GetRightsizeOverview(c)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	c, w = rightsizeContext("GET", "", "root", "admin")
+	c.Request = httptest.NewRequest("GET", "/api/v1/admin/rightsize?limit=0", nil)
+	h.GetRightsizeOverview(c)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
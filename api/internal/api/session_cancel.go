@@ -0,0 +1,95 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/audit"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/events"
+)
+
+// isCancelableSessionState reports whether a session is still starting, so
+// deleting it cancels its creation.
+func isCancelableSessionState(state string) bool {
+	return state == events.StatusPending || state == events.StatusCreating
+}
+
+// cancelSessionCreate aborts a session that isn't ready yet.
+//
+// A queued create is simply dropped. Otherwise the controller is sent a
+// cancelling delete: it stops the create and removes the resources already
+// made, including a home PVC the launch just created. The session ends in
+// the cancelled state, so it can be told apart from a terminated one.
+func (h *Handler) cancelSessionCreate(c *gin.Context, session *db.Session) {
+	ctx := c.Request.Context()
+
+	queued, err := events.CancelQueuedSessionCreate(ctx, h.db.DB(), session.ID)
+	if err != nil {
+		log.Printf("Failed to cancel queued create for session %s: %v", session.ID, err)
+	}
+	if !queued {
+		h.requestSessionState(ctx, session.ID, "terminated", sessionActor(c, session.UserID))
+		event := &events.SessionDeleteEvent{
+			SessionID: session.ID,
+			UserID:    session.UserID,
+			Platform:  h.platform,
+			Cancel:    true,
+		}
+		if err := h.publisher.PublishSessionDelete(ctx, event); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to cancel session",
+				"message": fmt.Sprintf("Failed to publish cancel event: %v", err),
+			})
+			return
+		}
+	}
+
+	if err := h.sessionDB.UpdateSessionState(ctx, session.ID, "cancelled"); err != nil {
+		log.Printf("Failed to mark session %s cancelled (non-fatal): %v", session.ID, err)
+	}
+	h.auditSessionEnd(ctx, "session.cancel", session.ID, session.UserID, session.State, "cancelled", c.GetString("userID"), c.ClientIP())
+
+	if queued {
+		log.Printf("Cancelled queued session create for %s", session.ID)
+		c.JSON(http.StatusOK, gin.H{
+			"name":    session.ID,
+			"state":   "cancelled",
+			"message": "Queued session cancelled",
+		})
+		return
+	}
+
+	log.Printf("Published session cancel event for %s (controller will clean up resources)", session.ID)
+	c.JSON(http.StatusAccepted, gin.H{
+		"name":    session.ID,
+		"state":   "cancelled",
+		"message": "Session creation cancelled, waiting for controller to clean up",
+	})
+}
+
+// auditSessionEnd records a user ending a session, as session.cancel for a
+// session that was still starting or session.terminate for one that was
+// running. Failures are logged; the session is already going away.
+func (h *Handler) auditSessionEnd(ctx context.Context, action, sessionID, owner, oldState, newState, userID, ipAddress string) {
+	if h.db == nil {
+		return
+	}
+
+	changes := audit.Values(map[string]interface{}{"owner": owner})
+	changes["state"] = audit.FieldChange{Old: oldState, New: newState}
+	details, _ := json.Marshal(changes)
+
+	_, err := h.db.DB().ExecContext(ctx, `
+		INSERT INTO audit_log (user_id, action, resource_type, resource_id, changes, timestamp, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, userID, action, "session", sessionID, details, time.Now(), ipAddress)
+	if err != nil {
+		log.Printf("Failed to audit %s of session %s: %v", action, sessionID, err)
+	}
+}
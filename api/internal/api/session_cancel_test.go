@@ -0,0 +1,58 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeleteSession_CancelsStartingSession(t *testing.T) {
+	h, mock := newAdminSessionHandler(t)
+
+	mock.ExpectQuery("FROM sessions").WithArgs("s1").
+		WillReturnRows(adminSessionRow(sqlmock.NewRows(adminSessionColumns), "s1", "alice", "pending"))
+	mock.ExpectExec("DELETE FROM pending_session_creates").WithArgs("s1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE sessions").WithArgs("cancelled", sqlmock.AnyArg(), "s1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs("root", "session.cancel", "session", "s1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	c, w := adminSessionContext("/api/v1/sessions/s1", "s1", "", "user")
+	h.DeleteSession(c)
+
+	assert.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Body.String(), `"state":"cancelled"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteSession_CancelsQueuedSession(t *testing.T) {
+	h, mock := newAdminSessionHandler(t)
+
+	mock.ExpectQuery("FROM sessions").WithArgs("s1").
+		WillReturnRows(adminSessionRow(sqlmock.NewRows(adminSessionColumns), "s1", "alice", "pending"))
+	mock.ExpectExec("DELETE FROM pending_session_creates").WithArgs("s1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE sessions").WithArgs("cancelled", sqlmock.AnyArg(), "s1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs("root", "session.cancel", "session", "s1", sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	c, w := adminSessionContext("/api/v1/sessions/s1", "s1", "", "user")
+	h.DeleteSession(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Queued session cancelled")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIsCancelableSessionState(t *testing.T) {
+	assert.True(t, isCancelableSessionState("pending"))
+	assert.True(t, isCancelableSessionState("creating"))
+	assert.False(t, isCancelableSessionState("running"))
+	assert.False(t, isCancelableSessionState("cancelled"))
+}
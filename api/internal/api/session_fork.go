@@ -0,0 +1,59 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/k8s"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// forkWhileRunningWarning is returned when a session is forked from a
+// running source: files it writes during the fork may be copied
+// inconsistently.
+const forkWhileRunningWarning = "The source session is running; files it writes during the fork may be inconsistent. Hibernate it first for an exact copy."
+
+// errForkForbidden is returned when forking another user's session.
+var errForkForbidden = errors.New("sessions can only be forked by their owner")
+
+// validateForkSource checks that a new session of user may fork the home
+// volume of source. Only the owner's own sessions with a persistent home can
+// be forked, into a session that has one too, and only the Kubernetes
+// controller can snapshot or copy volumes.
+func validateForkSource(source *db.Session, user string, persistentHome bool, platform string) error {
+	if platform != "kubernetes" {
+		return fmt.Errorf("forking a session's home is only supported on kubernetes, not %s", platform)
+	}
+	if source.UserID != user {
+		return errForkForbidden
+	}
+	if isTerminalSessionState(source.State) {
+		return fmt.Errorf("session %s is %s", source.ID, source.State)
+	}
+	if !source.PersistentHome {
+		return fmt.Errorf("session %s has no persistent home to fork", source.ID)
+	}
+	if !persistentHome {
+		return errors.New("a fork needs persistentHome to receive the copy of the home")
+	}
+	return nil
+}
+
+// forkSourceHome returns the home PVC a fork of session source would copy,
+// from the user's home PVCs (see k8s.GetUserHomePVCs): the source's own home
+// if it is a fork itself, otherwise the user's shared home. It returns nil
+// if the source has no home, in which case the controller won't fork it.
+func forkSourceHome(pvcs []corev1.PersistentVolumeClaim, user, source string) *corev1.PersistentVolumeClaim {
+	for i := range pvcs {
+		if pvcs[i].Labels["session"] == source {
+			return &pvcs[i]
+		}
+	}
+	for i := range pvcs {
+		if pvcs[i].Name == k8s.HomePVCName(user) {
+			return &pvcs[i]
+		}
+	}
+	return nil
+}
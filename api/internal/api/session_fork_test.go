@@ -0,0 +1,102 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/quota"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestValidateForkSource(t *testing.T) {
+	source := &db.Session{ID: "alice-vscode-1", UserID: "alice", State: "hibernated", PersistentHome: true}
+
+	assert.NoError(t, validateForkSource(source, "alice", true, "kubernetes"))
+
+	assert.ErrorIs(t, validateForkSource(source, "mallory", true, "kubernetes"), errForkForbidden)
+	assert.ErrorContains(t, validateForkSource(source, "alice", false, "kubernetes"), "persistentHome")
+	assert.ErrorContains(t, validateForkSource(source, "alice", true, "docker"), "only supported on kubernetes")
+
+	noHome := *source
+	noHome.PersistentHome = false
+	assert.ErrorContains(t, validateForkSource(&noHome, "alice", true, "kubernetes"), "no persistent home")
+
+	terminated := *source
+	terminated.State = "terminated"
+	assert.ErrorContains(t, validateForkSource(&terminated, "alice", true, "kubernetes"), "is terminated")
+}
+
+func homePVC(name, session, size string) corev1.PersistentVolumeClaim {
+	pvc := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:   name,
+			Labels: map[string]string{"app": "streamspace-user-home", "user": "alice"},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)},
+			},
+		},
+	}
+	if session != "" {
+		pvc.Labels["session"] = session
+	}
+	return pvc
+}
+
+func TestForkSourceHome(t *testing.T) {
+	pvcs := []corev1.PersistentVolumeClaim{
+		homePVC("home-alice", "", "50Gi"),
+		homePVC("home-alice-vscode-2", "alice-vscode-2", "20Gi"),
+	}
+
+	// A fork of a fork copies the source's own home, others the shared one
+	assert.Equal(t, "home-alice-vscode-2", forkSourceHome(pvcs, "alice", "alice-vscode-2").Name)
+	assert.Equal(t, "home-alice", forkSourceHome(pvcs, "alice", "alice-vscode-1").Name)
+	assert.Nil(t, forkSourceHome(pvcs[1:], "alice", "alice-vscode-1"))
+}
+
+func TestForkRejectedAtStorageLimit(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	// alice has the default limits: 50Gi of storage
+	expectLimits := func() {
+		mock.ExpectQuery("SELECT (.+) FROM users").WithArgs("alice").
+			WillReturnRows(sqlmock.NewRows([]string{
+				"id", "username", "email", "full_name", "role", "provider", "password_hash", "active", "created_at", "updated_at", "last_login",
+			}).AddRow("user-1", "alice", "alice@example.com", "Alice", "user", "local", "", true, time.Now(), time.Now(), nil))
+		mock.ExpectQuery("SELECT template_session_limits FROM user_quotas").WillReturnRows(sqlmock.NewRows([]string{"template_session_limits"}))
+		mock.ExpectQuery("SELECT gq.template_session_limits").WillReturnRows(sqlmock.NewRows([]string{"template_session_limits"}))
+		mock.ExpectQuery("SELECT max_session_hours_per_month FROM user_quotas").WillReturnRows(sqlmock.NewRows([]string{"max_session_hours_per_month"}))
+		mock.ExpectQuery("SELECT gq.max_session_hours_per_month").WillReturnRows(sqlmock.NewRows([]string{"max_session_hours_per_month"}))
+	}
+	enforcer := quota.NewEnforcer(db.NewUserDB(sqlDB), nil)
+	check := func(pvcs []corev1.PersistentVolumeClaim, source string) error {
+		expectLimits()
+		requested := enforcer.CalculateStorageUsage([]corev1.PersistentVolumeClaim{*forkSourceHome(pvcs, "alice", source)})
+		usage := &quota.Usage{TotalStorage: enforcer.CalculateStorageUsage(pvcs)}
+		return enforcer.CheckSessionCreation(context.Background(), "alice", 1000, 1024, 0, requested, quota.SessionTemplate{Name: "vscode"}, usage)
+	}
+
+	// Forking the 30Gi shared home needs another 30Gi: 60Gi is over the limit
+	err = check([]corev1.PersistentVolumeClaim{homePVC("home-alice", "", "30Gi")}, "alice-vscode-1")
+	assert.EqualError(t, err, "storage quota exceeded: would use 60Gi, limit is 50Gi")
+
+	// A fork of a small forked home still fits
+	err = check([]corev1.PersistentVolumeClaim{
+		homePVC("home-alice", "", "30Gi"),
+		homePVC("home-alice-vscode-2", "alice-vscode-2", "5Gi"),
+	}, "alice-vscode-2")
+	assert.NoError(t, err)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,86 @@
+// Package api provides HTTP handlers and WebSocket endpoints for the StreamSpace API.
+// This file implements the readiness gate for session access URLs.
+//
+// The controller sets status.url as soon as the Service and Ingress exist,
+// but the app behind it may still be starting. Handing that URL out early
+// gives users a broken first load, so GET /api/v1/sessions/:id only returns
+// the URL once the session's Ready condition is True:
+//
+//	"status": {"phase": "Running", "url": "https://...", "ready": true}
+//	"status": {"phase": "Running", "ready": false, "readyMessage": "Waiting for ..."}
+package api
+
+import (
+	"context"
+	"strings"
+
+	"github.com/streamspace/streamspace/api/internal/k8s"
+	"k8s.io/apimachinery/pkg/api/meta"
+)
+
+// sessionReadyCondition is the Session condition set by the controller once
+// the session pod is ready.
+const sessionReadyCondition = "Ready"
+
+// sessionReady reports whether a session's URL is serving.
+//
+// Sessions reconciled by controllers that don't set the Ready condition yet
+// are treated as ready once they are Running.
+func sessionReady(session *k8s.Session) (ready bool, message string) {
+	condition := meta.FindStatusCondition(session.Status.Conditions, sessionReadyCondition)
+	if condition == nil {
+		return strings.EqualFold(session.Status.Phase, "running"), ""
+	}
+	return condition.Status == "True", condition.Message
+}
+
+// sessionStatusResponse converts a Session CRD status to the response format
+// used by convertDBSessionToResponse.
+func sessionStatusResponse(session *k8s.Session) map[string]interface{} {
+	status := map[string]interface{}{
+		"phase":   session.Status.Phase,
+		"url":     session.Status.URL,
+		"podName": session.Status.PodName,
+	}
+	if session.Status.LastActivity != nil {
+		status["lastActivity"] = session.Status.LastActivity
+	}
+	if session.Status.Priority != "" {
+		status["priority"] = session.Status.Priority
+	}
+	if session.Status.Hibernation != nil {
+		status["hibernation"] = session.Status.Hibernation
+	}
+	if session.Status.StreamingFeatures != nil {
+		status["streamingFeatures"] = session.Status.StreamingFeatures
+	}
+	return status
+}
+
+// applyReadinessGate marks a session response ready or not and removes the
+// URL until it is.
+//
+// Readiness comes from the Session CRD. Sessions without one (other
+// platforms) are ready once their phase is Running.
+func (h *Handler) applyReadinessGate(ctx context.Context, sessionID string, result map[string]interface{}) {
+	status, ok := result["status"].(map[string]interface{})
+	if !ok {
+		return
+	}
+
+	phase, _ := status["phase"].(string)
+	ready, message := strings.EqualFold(phase, "running"), ""
+	if h.k8sClient != nil {
+		if session, err := h.k8sClient.GetSession(ctx, h.namespace, sessionID); err == nil {
+			ready, message = sessionReady(session)
+		}
+	}
+
+	status["ready"] = ready
+	if !ready {
+		delete(status, "url")
+		if message != "" {
+			status["readyMessage"] = message
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/streamspace/streamspace/api/internal/k8s"
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSessionReady(t *testing.T) {
+	tests := []struct {
+		name       string
+		phase      string
+		conditions []metav1.Condition
+		want       bool
+	}{
+		{"ready condition true", "Running", []metav1.Condition{{Type: "Ready", Status: metav1.ConditionTrue}}, true},
+		{"pod still starting", "Running", []metav1.Condition{{Type: "Ready", Status: metav1.ConditionFalse, Message: "Waiting"}}, false},
+		{"no condition, running", "Running", nil, true},
+		{"no condition, pending", "Pending", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &k8s.Session{}
+			session.Status.Phase = tt.phase
+			session.Status.Conditions = tt.conditions
+
+			ready, _ := sessionReady(session)
+			assert.Equal(t, tt.want, ready)
+		})
+	}
+}
+
+func TestApplyReadinessGate_WithholdsURLUntilReady(t *testing.T) {
+	h := &Handler{}
+
+	pending := map[string]interface{}{
+		"status": map[string]interface{}{"phase": "Pending", "url": "https://s1.example.com"},
+	}
+	h.applyReadinessGate(context.Background(), "s1", pending)
+	status := pending["status"].(map[string]interface{})
+	assert.Equal(t, false, status["ready"])
+	assert.NotContains(t, status, "url")
+
+	running := map[string]interface{}{
+		"status": map[string]interface{}{"phase": "Running", "url": "https://s2.example.com"},
+	}
+	h.applyReadinessGate(context.Background(), "s2", running)
+	status = running["status"].(map[string]interface{})
+	assert.Equal(t, true, status["ready"])
+	assert.Equal(t, "https://s2.example.com", status["url"])
+}
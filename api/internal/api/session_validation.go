@@ -0,0 +1,75 @@
+package api
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// reservedLabelPrefix is owned by the controllers, which label sessions
+// with their user and template.
+const reservedLabelPrefix = "streamspace.io/"
+
+// sessionDurationPattern is the pattern both Session CRDs enforce on
+// idleTimeout and maxSessionDuration. time.ParseDuration also accepts forms
+// such as ".5h" or "1.h" that the schema rejects, so durations must match
+// both.
+var sessionDurationPattern = regexp.MustCompile(`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`)
+
+// validateSessionDurations checks the resolved idle timeout and maximum
+// session duration before the Session is created. A value the controller
+// can't parse (e.g. "30" with no unit) would otherwise be accepted here and
+// silently ignored by the hibernation controller.
+func validateSessionDurations(idleTimeout, maxSessionDuration string) error {
+	if err := validateSessionDuration(idleTimeout); err != nil {
+		return fmt.Errorf("idleTimeout: %w", err)
+	}
+	if err := validateSessionDuration(maxSessionDuration); err != nil {
+		return fmt.Errorf("maxSessionDuration: %w", err)
+	}
+	return nil
+}
+
+// validateSessionDuration accepts an empty value (unset) or a positive
+// Go duration with units.
+func validateSessionDuration(value string) error {
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err == nil && d <= 0 {
+		return fmt.Errorf("%q must be positive", value)
+	}
+	if err != nil || !sessionDurationPattern.MatchString(value) {
+		return fmt.Errorf("%q is not a duration (use units, e.g. \"30m\" or \"2h\")", value)
+	}
+	return nil
+}
+
+// validateSessionLabels checks the labels plugins added to a session: they
+// must be valid Kubernetes labels and may not use the reserved
+// streamspace.io/ prefix.
+func validateSessionLabels(labels map[string]string) error {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if strings.HasPrefix(key, reservedLabelPrefix) {
+			return fmt.Errorf("label %q: the %s prefix is reserved", key, reservedLabelPrefix)
+		}
+		if errs := validation.IsQualifiedName(key); len(errs) > 0 {
+			return fmt.Errorf("label %q: %s", key, strings.Join(errs, "; "))
+		}
+		if errs := validation.IsValidLabelValue(labels[key]); len(errs) > 0 {
+			return fmt.Errorf("label %q value %q: %s", key, labels[key], strings.Join(errs, "; "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateSessionDurations(t *testing.T) {
+	tests := []struct {
+		name        string
+		idle        string
+		max         string
+		errContains string
+	}{
+		{name: "unset", idle: "", max: ""},
+		{name: "valid", idle: "30m", max: "1h30m"},
+		{name: "decimal", idle: "1.5h", max: "8h"},
+		{name: "leading dot", idle: ".5h", max: "", errContains: "idleTimeout"},
+		{name: "trailing dot", idle: "", max: "1.h", errContains: "maxSessionDuration"},
+		{name: "missing unit", idle: "30", max: "8h", errContains: "idleTimeout"},
+		{name: "garbage", idle: "30m", max: "forever", errContains: "maxSessionDuration"},
+		{name: "zero", idle: "0s", max: "", errContains: "must be positive"},
+		{name: "negative", idle: "", max: "-1h", errContains: "must be positive"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSessionDurations(tt.idle, tt.max)
+			if tt.errContains == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.errContains)
+		})
+	}
+}
+
+func TestValidateSessionLabels(t *testing.T) {
+	tests := []struct {
+		name        string
+		labels      map[string]string
+		errContains string
+	}{
+		{name: "none", labels: nil},
+		{name: "valid", labels: map[string]string{"compliance.example.com/reviewed": "true", "team": "web"}},
+		{name: "reserved prefix", labels: map[string]string{"streamspace.io/user": "mallory"}, errContains: "reserved"},
+		{name: "invalid key", labels: map[string]string{"bad key": "x"}, errContains: `label "bad key"`},
+		{name: "invalid value", labels: map[string]string{"team": "not valid!"}, errContains: "value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateSessionLabels(tt.labels)
+			if tt.errContains == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.errContains)
+		})
+	}
+}
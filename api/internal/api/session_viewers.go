@@ -0,0 +1,40 @@
+package api
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetSessionViewers returns how many people are connected to a session's
+// stream right now. Each user counts once, however many connections
+// (browser tabs) they have open. Changes are also pushed to the session's
+// WebSocket subscribers as session.viewers events.
+//
+// HTTP Method: GET
+// Path: /api/v1/sessions/:id/viewers
+// Authorization: Session owner or admin
+func (h *Handler) GetSessionViewers(c *gin.Context) {
+	sessionID := c.Param("id")
+	session, err := h.sessionDB.GetSession(c.Request.Context(), sessionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	// SECURITY: Only the session owner or an admin may see who is watching
+	userID := c.GetString("userID")
+	username := c.GetString("username")
+	if session.UserID != userID && session.UserID != username && c.GetString("userRole") != "admin" {
+		log.Printf("Denied viewer list of session %s to user %s", sessionID, userID)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Access denied"})
+		return
+	}
+
+	if h.wsManager == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Viewer tracking unavailable"})
+		return
+	}
+	c.JSON(http.StatusOK, h.wsManager.GetNotifier().SessionViewers(sessionID))
+}
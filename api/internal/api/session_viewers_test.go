@@ -0,0 +1,51 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/streamspace/streamspace/api/internal/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetSessionViewers_OtherUsersSession(t *testing.T) {
+	h, mock := newAdminSessionHandler(t)
+	h.wsManager = websocket.NewManager(nil, nil)
+
+	mock.ExpectQuery("FROM sessions").WithArgs("s1").
+		WillReturnRows(adminSessionRow(sqlmock.NewRows(adminSessionColumns), "s1", "alice", "running"))
+
+	c, w := adminSessionContext("/api/v1/sessions/s1/viewers", "s1", "", "user")
+	c.Set("userID", "mallory")
+	h.GetSessionViewers(c)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSessionViewers_CountsUsersOnce(t *testing.T) {
+	h, mock := newAdminSessionHandler(t)
+	h.wsManager = websocket.NewManager(nil, nil)
+	notifier := h.wsManager.GetNotifier()
+	notifier.NotifySessionConnected("s1", "alice", "c1")
+	notifier.NotifySessionConnected("s1", "alice", "c2")
+	notifier.NotifySessionConnected("s1", "bob", "c3")
+
+	mock.ExpectQuery("FROM sessions").WithArgs("s1").
+		WillReturnRows(adminSessionRow(sqlmock.NewRows(adminSessionColumns), "s1", "alice", "running"))
+
+	c, w := adminSessionContext("/api/v1/sessions/s1/viewers", "s1", "", "user")
+	c.Set("userID", "alice")
+	h.GetSessionViewers(c)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var viewers websocket.SessionViewers
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &viewers))
+	assert.Equal(t, 2, viewers.Viewers)
+	assert.Equal(t, 3, viewers.Connections)
+	assert.Equal(t, []string{"alice", "bob"}, viewers.Users)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
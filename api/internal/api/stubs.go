@@ -52,6 +52,9 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/streamspace/streamspace/api/internal/events"
+	"github.com/streamspace/streamspace/api/internal/middleware"
+	internalWebsocket "github.com/streamspace/streamspace/api/internal/websocket"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -85,20 +88,29 @@ var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
 		// Get allowed origins from environment variable
 		allowedOrigins := os.Getenv("ALLOWED_ORIGINS")
+		policy := middleware.WebSocketOriginPolicyFromEnv()
 
-		// If not set, allow localhost for development only
+		// If not set, allow localhost for development only (never in strict mode)
 		if allowedOrigins == "" {
-			allowedOrigins = "http://localhost:3000,http://localhost:5173"
+			allowedOrigins = strings.Join(policy.DevOrigins("http://localhost:3000", "http://localhost:5173"), ",")
 		}
 
-		// Special case: "*" means allow all (use with caution)
+		// Special case: "*" means allow all (use with caution, refused in strict mode)
 		if allowedOrigins == "*" {
+			if policy.Strict {
+				log.Println("WebSocket connection rejected: ALLOWED_ORIGINS=* is ignored with WEBSOCKET_ENFORCE_ORIGIN=strict")
+				return false
+			}
 			log.Println("WARNING: WebSocket accepting connections from all origins")
 			return true
 		}
 
 		// Check if request origin is in allowed list
+		// (an empty Origin never matches, not even an empty allowlist)
 		origin := r.Header.Get("Origin")
+		if origin == "" {
+			return false
+		}
 		for _, allowed := range strings.Split(allowedOrigins, ",") {
 			if strings.TrimSpace(allowed) == origin {
 				return true
@@ -122,6 +134,52 @@ func (h *Handler) Health(c *gin.Context) {
 	})
 }
 
+// Healthz is the liveness probe. It always returns 200 while the process can
+// serve requests, but includes NATS state so degraded event delivery is
+// visible in the response.
+func (h *Handler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"status":  "healthy",
+		"service": "streamspace-api",
+		"nats":    h.natsHealth(),
+	})
+}
+
+// Readyz is the readiness probe. It returns 503 when NATS is configured but
+// the publisher or subscriber connection is down, since session state
+// silently desyncs from the controllers until it comes back.
+//
+// Disabled NATS (NATS_URL unset) is reported but does not fail readiness.
+func (h *Handler) Readyz(c *gin.Context) {
+	natsStatus := h.natsHealth()
+
+	status := http.StatusOK
+	state := "ready"
+	for _, health := range natsStatus {
+		if !health.Healthy() {
+			status = http.StatusServiceUnavailable
+			state = "degraded"
+		}
+	}
+
+	c.JSON(status, gin.H{
+		"status": state,
+		"nats":   natsStatus,
+	})
+}
+
+// natsHealth collects the health of the event publisher and subscriber.
+func (h *Handler) natsHealth() map[string]events.HealthStatus {
+	result := make(map[string]events.HealthStatus)
+	if h.publisher != nil {
+		result["publisher"] = h.publisher.Health()
+	}
+	if h.subscriber != nil {
+		result["subscriber"] = h.subscriber.Health()
+	}
+	return result
+}
+
 // Version returns API version
 func (h *Handler) Version(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
@@ -856,10 +914,11 @@ func (h *Handler) SessionsWebSocket(c *gin.Context) {
 
 	// Allow overriding user_id from query param (for admins/operators)
 	// But for security, regular users can only subscribe to their own events
+	viewer := internalWebsocket.Viewer{UserID: userIDStr, Role: c.GetString("userRole")}
 	queryUserID := c.Query("user_id")
 	if queryUserID != "" && queryUserID != userIDStr {
 		// Check if user has admin or operator role
-		role := c.GetString("role")
+		role := viewer.Role
 		if role != "admin" && role != "operator" {
 			// Regular users can only subscribe to their own events
 			log.Printf("Unauthorized attempt to subscribe to user %s by user %s (role: %s)", queryUserID, userIDStr, role)
@@ -873,10 +932,14 @@ func (h *Handler) SessionsWebSocket(c *gin.Context) {
 		userIDStr = queryUserID
 	}
 
-	// Get session ID from query params (optional)
+	// Get session ID from query params (optional); the manager checks the
+	// viewer owns the session or has it shared with them
 	sessionID := c.Query("session_id")
 
-	h.wsManager.HandleSessionsWebSocket(conn, userIDStr, sessionID)
+	// ?since=<seq> replays the user's events missed while disconnected
+	since := internalWebsocket.ParseReplaySince(c.Query("since"))
+
+	h.wsManager.HandleSessionsWebSocket(conn, viewer, userIDStr, sessionID, since)
 }
 
 // ClusterWebSocket handles WebSocket for real-time cluster updates
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"log"
+
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/k8s"
+)
+
+// templateAccess decides which templates a user may see and launch, based
+// on restricted:<group> template tags (see k8s.RestrictedTagPrefix) and
+// whether the template is privileged. The user's groups are only looked up
+// once a restricted template is checked.
+type templateAccess struct {
+	ctx    context.Context
+	userDB *db.UserDB
+	userID string
+	admin  bool
+
+	loaded bool
+	groups []string
+}
+
+// templateAccessFor returns the template access of userID. Admins may use
+// every template.
+func (h *Handler) templateAccessFor(ctx context.Context, userID, role string) *templateAccess {
+	access := &templateAccess{ctx: ctx, userID: userID, admin: role == "admin"}
+	if h.db != nil {
+		access.userDB = db.NewUserDB(h.db.DB())
+	}
+	return access
+}
+
+// allowed reports whether the user may see and launch template. If the
+// user's groups can't be loaded, restricted templates are denied.
+func (a *templateAccess) allowed(template *k8s.Template) bool {
+	if a.admin {
+		return true
+	}
+	if len(template.RestrictedGroups()) == 0 {
+		return template.AccessibleTo(nil)
+	}
+
+	if !a.loaded {
+		a.loaded = true
+		if a.userDB != nil && a.userID != "" {
+			groups, err := a.userDB.GetUserGroupNames(a.ctx, a.userID)
+			if err != nil {
+				log.Printf("Failed to load groups of user %s for template access: %v", a.userID, err)
+			}
+			a.groups = groups
+		}
+	}
+	return template.AccessibleTo(a.groups)
+}
+
+// filter returns the templates the user may see.
+func (a *templateAccess) filter(templates []*k8s.Template) []*k8s.Template {
+	visible := make([]*k8s.Template, 0, len(templates))
+	for _, template := range templates {
+		if a.allowed(template) {
+			visible = append(visible, template)
+		}
+	}
+	return visible
+}
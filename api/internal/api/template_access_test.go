@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/k8s"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTemplateAccess_FiltersRestrictedTemplates(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	h := &Handler{db: db.NewDatabaseForTesting(sqlDB)}
+	ctx := context.Background()
+
+	firefox := &k8s.Template{Name: "firefox", Tags: []string{"browser"}}
+	ledger := &k8s.Template{Name: "ledger", Tags: []string{"restricted:finance"}}
+	audit := &k8s.Template{Name: "audit", Tags: []string{"restricted:audit"}}
+	templates := []*k8s.Template{firefox, ledger, audit}
+
+	// Groups are loaded once, on the first restricted template
+	mock.ExpectQuery("SELECT g.name").
+		WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Finance"))
+
+	visible := h.templateAccessFor(ctx, "alice", "user").filter(templates)
+	assert.Equal(t, []*k8s.Template{firefox, ledger}, visible)
+
+	// Admins see everything without a lookup
+	assert.Equal(t, templates, h.templateAccessFor(ctx, "root", "admin").filter(templates))
+
+	// Unrestricted templates need no lookup either
+	assert.True(t, h.templateAccessFor(ctx, "bob", "user").allowed(firefox))
+
+	// Restricted templates are denied if the groups can't be loaded
+	mock.ExpectQuery("SELECT g.name").WithArgs("carol").WillReturnError(assert.AnError)
+	assert.False(t, h.templateAccessFor(ctx, "carol", "user").allowed(ledger))
+
+	// Privileged templates without restricted tags are admin-only
+	privileged := &k8s.Template{Name: "docker-in-docker", Privileged: true}
+	assert.False(t, h.templateAccessFor(ctx, "bob", "user").allowed(privileged))
+	assert.True(t, h.templateAccessFor(ctx, "root", "admin").allowed(privileged))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,376 @@
+// Package api provides HTTP handlers and WebSocket endpoints for the StreamSpace API.
+// This file implements bulk template export and import.
+//
+// Admins managing templates across environments (dev → staging → prod) can
+// export every Template in one cluster and import the bundle into another:
+//
+//	GET  /api/v1/templates/export
+//	POST /api/v1/templates/import?dryRun=true&strategy=skip|overwrite|rename
+//
+// BUNDLE FORMAT:
+//
+// A bundle is portable: it carries each template's name, labels, annotations
+// and spec, but no cluster-specific metadata (uid, resourceVersion, ...) or
+// status.
+//
+//	{
+//	  "apiVersion": "stream.space/v1alpha1",
+//	  "kind": "TemplateBundle",
+//	  "exportedAt": "2025-01-01T00:00:00Z",
+//	  "templates": [
+//	    {"name": "firefox", "labels": {...}, "spec": {"displayName": "Firefox", ...}}
+//	  ]
+//	}
+//
+// NAME COLLISIONS (strategy):
+//
+//   - skip (default): keep the existing template
+//   - overwrite: replace the existing template's spec
+//   - rename: import as "{name}-imported" ("{name}-imported-2", ...)
+//
+// With dryRun=true nothing is changed; the response shows what each template
+// would do ("create", "update", "unchanged", "skip" or "invalid") and, for
+// updates, which spec fields would change.
+package api
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/audit"
+	"github.com/streamspace/streamspace/api/internal/events"
+	"github.com/streamspace/streamspace/api/internal/quota"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// TemplateBundleKind identifies template bundles.
+const TemplateBundleKind = "TemplateBundle"
+
+// Name collision strategies for template import.
+const (
+	ImportStrategySkip      = "skip"
+	ImportStrategyOverwrite = "overwrite"
+	ImportStrategyRename    = "rename"
+)
+
+// Template import actions.
+const (
+	ImportActionCreate    = "create"
+	ImportActionUpdate    = "update"
+	ImportActionUnchanged = "unchanged"
+	ImportActionSkip      = "skip"
+	ImportActionInvalid   = "invalid"
+)
+
+// lastAppliedAnnotation is cluster-specific kubectl bookkeeping.
+const lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// TemplateBundle is a portable set of templates.
+type TemplateBundle struct {
+	APIVersion string           `json:"apiVersion"`
+	Kind       string           `json:"kind"`
+	ExportedAt time.Time        `json:"exportedAt"`
+	Templates  []BundleTemplate `json:"templates"`
+}
+
+// BundleTemplate is a template without cluster-specific metadata or status.
+type BundleTemplate struct {
+	Name        string                 `json:"name"`
+	Labels      map[string]string      `json:"labels,omitempty"`
+	Annotations map[string]string      `json:"annotations,omitempty"`
+	Spec        map[string]interface{} `json:"spec"`
+}
+
+// TemplateImportResult describes what importing one template did (or, in a
+// dry run, would do).
+type TemplateImportResult struct {
+	Name       string        `json:"name"`
+	TargetName string        `json:"targetName,omitempty"`
+	Action     string        `json:"action"`
+	Changes    audit.Changes `json:"changes,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// bundleTemplateFrom strips cluster-specific fields from a Template object.
+func bundleTemplateFrom(obj *unstructured.Unstructured) BundleTemplate {
+	tmpl := BundleTemplate{
+		Name:   obj.GetName(),
+		Labels: obj.GetLabels(),
+	}
+	if annotations := obj.GetAnnotations(); len(annotations) > 0 {
+		tmpl.Annotations = make(map[string]string, len(annotations))
+		for k, v := range annotations {
+			if k != lastAppliedAnnotation {
+				tmpl.Annotations[k] = v
+			}
+		}
+		if len(tmpl.Annotations) == 0 {
+			tmpl.Annotations = nil
+		}
+	}
+	if spec, ok := obj.Object["spec"].(map[string]interface{}); ok {
+		tmpl.Spec = spec
+	}
+	return tmpl
+}
+
+// validateBundleTemplate checks a bundle entry can be created as a Template,
+// and replaces its category with the registered name (see
+// quota.NormalizeCategory).
+func validateBundleTemplate(tmpl BundleTemplate) error {
+	if errs := validation.IsDNS1123Subdomain(tmpl.Name); len(errs) > 0 {
+		return fmt.Errorf("invalid name %q: %s", tmpl.Name, strings.Join(errs, ", "))
+	}
+	if tmpl.Spec == nil {
+		return fmt.Errorf("spec is required")
+	}
+	for _, field := range []string{"displayName", "baseImage"} {
+		if value, _ := tmpl.Spec[field].(string); value == "" {
+			return fmt.Errorf("spec.%s is required", field)
+		}
+	}
+	if value, _ := tmpl.Spec["category"].(string); value != "" {
+		category, err := quota.NormalizeCategory(value)
+		if err != nil {
+			return fmt.Errorf("spec.category: %w", err)
+		}
+		tmpl.Spec["category"] = category
+	}
+	return nil
+}
+
+// renameTarget returns the first "{name}-imported[-N]" not taken.
+func renameTarget(name string, taken map[string]bool) string {
+	candidate := name + "-imported"
+	for i := 2; taken[candidate]; i++ {
+		candidate = fmt.Sprintf("%s-imported-%d", name, i)
+	}
+	return candidate
+}
+
+// planTemplateImport decides what importing each bundle template does given
+// the templates that already exist (by name).
+func planTemplateImport(bundle *TemplateBundle, existing map[string]*unstructured.Unstructured, strategy string) []TemplateImportResult {
+	taken := make(map[string]bool, len(existing)+len(bundle.Templates))
+	for name := range existing {
+		taken[name] = true
+	}
+
+	results := make([]TemplateImportResult, 0, len(bundle.Templates))
+	for _, tmpl := range bundle.Templates {
+		result := TemplateImportResult{Name: tmpl.Name, TargetName: tmpl.Name}
+
+		if err := validateBundleTemplate(tmpl); err != nil {
+			result.Action = ImportActionInvalid
+			result.TargetName = ""
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		current, exists := existing[tmpl.Name]
+		switch {
+		case !exists && !taken[tmpl.Name]:
+			result.Action = ImportActionCreate
+		case !exists:
+			// Earlier entry in the same bundle has this name
+			result.Action = ImportActionSkip
+			result.Error = "duplicate name in bundle"
+		case strategy == ImportStrategyRename:
+			result.TargetName = renameTarget(tmpl.Name, taken)
+			result.Action = ImportActionCreate
+		case strategy == ImportStrategyOverwrite:
+			before := bundleTemplateFrom(current)
+			result.Changes = audit.Diff(before.Spec, tmpl.Spec)
+			if len(result.Changes) == 0 && reflect.DeepEqual(before.Labels, tmpl.Labels) {
+				result.Action = ImportActionUnchanged
+			} else {
+				result.Action = ImportActionUpdate
+			}
+		default:
+			result.Action = ImportActionSkip
+		}
+
+		if result.Action == ImportActionCreate {
+			taken[result.TargetName] = true
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// ExportTemplates returns every template in the namespace as a portable bundle.
+func (h *Handler) ExportTemplates(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	list, err := h.k8sClient.GetDynamicClient().Resource(templateGVR).Namespace(h.namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Printf("Failed to list templates for export: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to export templates",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	bundle := TemplateBundle{
+		APIVersion: templateGVR.GroupVersion().String(),
+		Kind:       TemplateBundleKind,
+		ExportedAt: time.Now().UTC(),
+		Templates:  make([]BundleTemplate, 0, len(list.Items)),
+	}
+	for i := range list.Items {
+		bundle.Templates = append(bundle.Templates, bundleTemplateFrom(&list.Items[i]))
+	}
+	sort.Slice(bundle.Templates, func(i, j int) bool {
+		return bundle.Templates[i].Name < bundle.Templates[j].Name
+	})
+
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=templates-%s.json", bundle.ExportedAt.Format("20060102-150405")))
+	c.JSON(http.StatusOK, bundle)
+}
+
+// ImportTemplates validates a bundle and creates or updates its templates.
+func (h *Handler) ImportTemplates(c *gin.Context) {
+	ctx := c.Request.Context()
+
+	strategy := c.DefaultQuery("strategy", ImportStrategySkip)
+	if strategy != ImportStrategySkip && strategy != ImportStrategyOverwrite && strategy != ImportStrategyRename {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid strategy",
+			"message": "strategy must be one of: skip, overwrite, rename",
+		})
+		return
+	}
+	dryRun := c.Query("dryRun") == "true"
+
+	var bundle TemplateBundle
+	if err := c.ShouldBindJSON(&bundle); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid bundle",
+			"message": err.Error(),
+		})
+		return
+	}
+	if bundle.Kind != TemplateBundleKind {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid bundle",
+			"message": fmt.Sprintf("kind must be %s", TemplateBundleKind),
+		})
+		return
+	}
+
+	resource := h.k8sClient.GetDynamicClient().Resource(templateGVR).Namespace(h.namespace)
+	list, err := resource.List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Printf("Failed to list templates for import: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to import templates",
+			"message": err.Error(),
+		})
+		return
+	}
+	existing := make(map[string]*unstructured.Unstructured, len(list.Items))
+	for i := range list.Items {
+		existing[list.Items[i].GetName()] = &list.Items[i]
+	}
+
+	results := planTemplateImport(&bundle, existing, strategy)
+
+	if !dryRun {
+		for i := range results {
+			result := &results[i]
+			if result.Action != ImportActionCreate && result.Action != ImportActionUpdate {
+				continue
+			}
+			if err := h.applyBundleTemplate(ctx, bundle.Templates[i], result, existing); err != nil {
+				log.Printf("Failed to import template %s: %v", result.Name, err)
+				result.Error = err.Error()
+			}
+		}
+	}
+
+	summary := make(map[string]int)
+	failed := 0
+	for _, result := range results {
+		summary[result.Action]++
+		if result.Error != "" && result.Action != ImportActionSkip {
+			failed++
+		}
+	}
+
+	status := http.StatusOK
+	if failed > 0 && !dryRun {
+		status = http.StatusMultiStatus
+	}
+	c.JSON(status, gin.H{
+		"dryRun":   dryRun,
+		"strategy": strategy,
+		"summary":  summary,
+		"failed":   failed,
+		"results":  results,
+	})
+}
+
+// applyBundleTemplate creates or overwrites the Template for a planned import.
+func (h *Handler) applyBundleTemplate(ctx context.Context, tmpl BundleTemplate, result *TemplateImportResult, existing map[string]*unstructured.Unstructured) error {
+	resource := h.k8sClient.GetDynamicClient().Resource(templateGVR).Namespace(h.namespace)
+
+	if result.Action == ImportActionUpdate {
+		obj := existing[result.TargetName].DeepCopy()
+		obj.Object["spec"] = tmpl.Spec
+		obj.SetLabels(tmpl.Labels)
+		annotations := obj.GetAnnotations()
+		if annotations == nil && len(tmpl.Annotations) > 0 {
+			annotations = make(map[string]string, len(tmpl.Annotations))
+		}
+		for k, v := range tmpl.Annotations {
+			annotations[k] = v
+		}
+		obj.SetAnnotations(annotations)
+		if _, err := resource.Update(ctx, obj, metav1.UpdateOptions{}); err != nil {
+			return fmt.Errorf("failed to update template: %w", err)
+		}
+		return nil
+	}
+
+	obj := &unstructured.Unstructured{
+		Object: map[string]interface{}{
+			"apiVersion": templateGVR.GroupVersion().String(),
+			"kind":       "Template",
+			"spec":       tmpl.Spec,
+		},
+	}
+	obj.SetName(result.TargetName)
+	obj.SetNamespace(h.namespace)
+	obj.SetLabels(tmpl.Labels)
+	obj.SetAnnotations(tmpl.Annotations)
+	if _, err := resource.Create(ctx, obj, metav1.CreateOptions{}); err != nil {
+		return fmt.Errorf("failed to create template: %w", err)
+	}
+
+	// Publish template create event for controllers
+	displayName, _ := tmpl.Spec["displayName"].(string)
+	category, _ := tmpl.Spec["category"].(string)
+	baseImage, _ := tmpl.Spec["baseImage"].(string)
+	createEvent := &events.TemplateCreateEvent{
+		TemplateID:  result.TargetName,
+		DisplayName: displayName,
+		Category:    category,
+		BaseImage:   baseImage,
+		Platform:    h.platform,
+	}
+	if err := h.publisher.PublishTemplateCreate(ctx, createEvent); err != nil {
+		log.Printf("Warning: Failed to publish template create event: %v", err)
+	}
+	return nil
+}
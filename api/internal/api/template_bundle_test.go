@@ -0,0 +1,117 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+func existingTemplate(name string, spec map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "stream.space/v1alpha1",
+		"kind":       "Template",
+		"spec":       spec,
+		"status":     map[string]interface{}{"valid": true},
+	}}
+	obj.SetName(name)
+	obj.SetResourceVersion("42")
+	obj.SetUID("abc")
+	return obj
+}
+
+func firefoxSpec(image string) map[string]interface{} {
+	return map[string]interface{}{"displayName": "Firefox", "baseImage": image}
+}
+
+func TestBundleTemplateFrom_StripsClusterFields(t *testing.T) {
+	obj := existingTemplate("firefox", firefoxSpec("firefox:1"))
+	obj.SetAnnotations(map[string]string{lastAppliedAnnotation: "{}", "owner": "platform"})
+
+	tmpl := bundleTemplateFrom(obj)
+
+	assert.Equal(t, "firefox", tmpl.Name)
+	assert.Equal(t, map[string]string{"owner": "platform"}, tmpl.Annotations)
+	assert.Equal(t, firefoxSpec("firefox:1"), tmpl.Spec)
+}
+
+func TestPlanTemplateImport_Strategies(t *testing.T) {
+	existing := map[string]*unstructured.Unstructured{
+		"firefox":          existingTemplate("firefox", firefoxSpec("firefox:1")),
+		"firefox-imported": existingTemplate("firefox-imported", firefoxSpec("firefox:1")),
+	}
+	bundle := &TemplateBundle{Kind: TemplateBundleKind, Templates: []BundleTemplate{
+		{Name: "firefox", Spec: firefoxSpec("firefox:2")},
+		{Name: "chrome", Spec: map[string]interface{}{"displayName": "Chrome", "baseImage": "chrome:1"}},
+		{Name: "Bad_Name", Spec: firefoxSpec("x")},
+		{Name: "noimage", Spec: map[string]interface{}{"displayName": "No image"}},
+	}}
+
+	tests := []struct {
+		strategy      string
+		firefox       string
+		firefoxTarget string
+	}{
+		{ImportStrategySkip, ImportActionSkip, "firefox"},
+		{ImportStrategyOverwrite, ImportActionUpdate, "firefox"},
+		{ImportStrategyRename, ImportActionCreate, "firefox-imported-2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.strategy, func(t *testing.T) {
+			results := planTemplateImport(bundle, existing, tt.strategy)
+			require.Len(t, results, 4)
+
+			assert.Equal(t, tt.firefox, results[0].Action)
+			assert.Equal(t, tt.firefoxTarget, results[0].TargetName)
+
+			assert.Equal(t, ImportActionCreate, results[1].Action)
+			assert.Equal(t, "chrome", results[1].TargetName)
+
+			assert.Equal(t, ImportActionInvalid, results[2].Action)
+			assert.Contains(t, results[2].Error, "invalid name")
+
+			assert.Equal(t, ImportActionInvalid, results[3].Action)
+			assert.Contains(t, results[3].Error, "spec.baseImage")
+		})
+	}
+}
+
+func TestPlanTemplateImport_OverwriteReportsChanges(t *testing.T) {
+	existing := map[string]*unstructured.Unstructured{
+		"firefox": existingTemplate("firefox", firefoxSpec("firefox:1")),
+		"chrome":  existingTemplate("chrome", map[string]interface{}{"displayName": "Chrome", "baseImage": "chrome:1"}),
+	}
+	bundle := &TemplateBundle{Kind: TemplateBundleKind, Templates: []BundleTemplate{
+		{Name: "firefox", Spec: firefoxSpec("firefox:2")},
+		{Name: "chrome", Spec: map[string]interface{}{"displayName": "Chrome", "baseImage": "chrome:1"}},
+	}}
+
+	results := planTemplateImport(bundle, existing, ImportStrategyOverwrite)
+
+	require.Len(t, results, 2)
+	assert.Equal(t, ImportActionUpdate, results[0].Action)
+	require.Contains(t, results[0].Changes, "baseImage")
+	assert.Equal(t, "firefox:1", results[0].Changes["baseImage"].Old)
+	assert.Equal(t, "firefox:2", results[0].Changes["baseImage"].New)
+	assert.Equal(t, ImportActionUnchanged, results[1].Action)
+}
+
+func TestPlanTemplateImport_Categories(t *testing.T) {
+	spec := func(category string) map[string]interface{} {
+		return map[string]interface{}{"displayName": "VS Code", "baseImage": "vscode:1", "category": category}
+	}
+	bundle := &TemplateBundle{Kind: TemplateBundleKind, Templates: []BundleTemplate{
+		{Name: "vscode", Spec: spec("IDE")},
+		{Name: "typo", Spec: spec("developmnet")},
+	}}
+
+	results := planTemplateImport(bundle, nil, ImportStrategySkip)
+
+	require.Len(t, results, 2)
+	assert.Equal(t, ImportActionCreate, results[0].Action)
+	assert.Equal(t, "Development", bundle.Templates[0].Spec["category"])
+	assert.Equal(t, ImportActionInvalid, results[1].Action)
+	assert.Contains(t, results[1].Error, "spec.category")
+}
@@ -0,0 +1,29 @@
+// Package api provides HTTP handlers and WebSocket endpoints for the StreamSpace API.
+// This file implements the template category listing.
+//
+// Template categories come from a fixed registry (see quota.Category): each
+// has a name, a label slug, aliases and the resources its sessions get when
+// neither the launch nor the template specifies any. Templates are created
+// with the registered name; aliases and slugs are accepted and normalized,
+// and unknown categories are rejected.
+//
+// Endpoints:
+//
+//	GET /api/v1/templates/categories - known template categories
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/quota"
+)
+
+// ListTemplateCategories returns the known template categories.
+func (h *Handler) ListTemplateCategories(c *gin.Context) {
+	categories := quota.ListCategories()
+	c.JSON(http.StatusOK, gin.H{
+		"categories": categories,
+		"total":      len(categories),
+	})
+}
@@ -0,0 +1,179 @@
+// Package audit defines the structured shape of audit log changes.
+//
+// Every row in the audit_log table stores what changed in its JSONB
+// "changes" column. Historically writers stored arbitrary maps, so a UI had
+// to guess whether a value was the new state, the old state or request
+// metadata. Changes are now always stored field by field:
+//
+//	{
+//	  "role":   {"old": "user", "new": "admin"},
+//	  "quota":  {"old": 5,      "new": 10},
+//	  "method": {"old": null,   "new": "POST"}
+//	}
+//
+// A nil Old means the field was set (or only the new value is known), a nil
+// New means the field was removed.
+//
+// Writers build Changes with Diff (before/after snapshots) or Values (only
+// the resulting values are known). Rows written before this format are
+// converted by the audit_log normalization migration, and Normalize converts
+// anything else on read.
+//
+// Example usage:
+//
+//	changes := audit.Diff(oldUser, newUser)
+//	data, _ := json.Marshal(changes)
+//	// INSERT INTO audit_log (..., changes, ...) VALUES (..., data, ...)
+//
+//	for _, line := range audit.Render(changes) {
+//	    fmt.Println(line.Text) // role: "user" → "admin"
+//	}
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// FieldChange is the before and after value of one field.
+type FieldChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// Changes maps field names to their change.
+type Changes map[string]FieldChange
+
+// Change kinds reported by Render.
+const (
+	ChangeAdded    = "added"
+	ChangeRemoved  = "removed"
+	ChangeModified = "modified"
+)
+
+// DiffLine is the rendered change of one field.
+type DiffLine struct {
+	Field string      `json:"field"`
+	Kind  string      `json:"kind"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+	Text  string      `json:"text"`
+}
+
+// Diff returns the fields that differ between two snapshots.
+//
+// Fields with equal values are omitted. Either snapshot may be nil.
+func Diff(before, after map[string]interface{}) Changes {
+	changes := make(Changes)
+	for field, old := range before {
+		newValue, ok := after[field]
+		if !ok {
+			changes[field] = FieldChange{Old: old}
+			continue
+		}
+		if !reflect.DeepEqual(old, newValue) {
+			changes[field] = FieldChange{Old: old, New: newValue}
+		}
+	}
+	for field, newValue := range after {
+		if _, ok := before[field]; !ok {
+			changes[field] = FieldChange{New: newValue}
+		}
+	}
+	return changes
+}
+
+// Values records values whose previous state is unknown (e.g. request
+// details) as changes with a nil Old.
+func Values(values map[string]interface{}) Changes {
+	changes := make(Changes, len(values))
+	for field, value := range values {
+		changes[field] = FieldChange{New: value}
+	}
+	return changes
+}
+
+// Normalize converts a decoded changes payload to the structured shape.
+//
+// Values that already are {"old": ..., "new": ...} objects are kept; any
+// other (legacy) value is treated as the field's new value.
+func Normalize(raw map[string]interface{}) Changes {
+	changes := make(Changes, len(raw))
+	for field, value := range raw {
+		if change, ok := asFieldChange(value); ok {
+			changes[field] = change
+		} else {
+			changes[field] = FieldChange{New: value}
+		}
+	}
+	return changes
+}
+
+// ParseChanges decodes a changes column value, normalizing legacy rows.
+func ParseChanges(data []byte) (Changes, error) {
+	if len(data) == 0 || string(data) == "null" {
+		return Changes{}, nil
+	}
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid changes payload: %w", err)
+	}
+	return Normalize(raw), nil
+}
+
+// asFieldChange reports whether value is an object with exactly old and new keys.
+func asFieldChange(value interface{}) (FieldChange, bool) {
+	m, ok := value.(map[string]interface{})
+	if !ok || len(m) != 2 {
+		return FieldChange{}, false
+	}
+	old, hasOld := m["old"]
+	newValue, hasNew := m["new"]
+	if !hasOld || !hasNew {
+		return FieldChange{}, false
+	}
+	return FieldChange{Old: old, New: newValue}, true
+}
+
+// Render produces a human-readable diff, one line per field, sorted by field.
+//
+//	role: "user" → "admin"
+//	quota: + 10
+//	team_id: - "eng"
+func Render(changes Changes) []DiffLine {
+	fields := make([]string, 0, len(changes))
+	for field := range changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	lines := make([]DiffLine, 0, len(fields))
+	for _, field := range fields {
+		change := changes[field]
+		line := DiffLine{Field: field, Old: change.Old, New: change.New}
+		switch {
+		case change.Old == nil:
+			line.Kind = ChangeAdded
+			line.Text = fmt.Sprintf("%s: + %s", field, formatValue(change.New))
+		case change.New == nil:
+			line.Kind = ChangeRemoved
+			line.Text = fmt.Sprintf("%s: - %s", field, formatValue(change.Old))
+		default:
+			line.Kind = ChangeModified
+			line.Text = fmt.Sprintf("%s: %s → %s", field, formatValue(change.Old), formatValue(change.New))
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// formatValue renders a value as compact JSON.
+func formatValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(data)
+}
@@ -0,0 +1,68 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	before := map[string]interface{}{"role": "user", "quota": 5, "team_id": "eng"}
+	after := map[string]interface{}{"role": "admin", "quota": 5, "email": "a@example.com"}
+
+	changes := Diff(before, after)
+
+	assert.Equal(t, Changes{
+		"role":    {Old: "user", New: "admin"},
+		"team_id": {Old: "eng"},
+		"email":   {New: "a@example.com"},
+	}, changes)
+}
+
+func TestParseChanges_NormalizesLegacyRows(t *testing.T) {
+	data := []byte(`{"method":"POST","status_code":201,"role":{"old":"user","new":"admin"},"metadata":{"new":"x"}}`)
+
+	changes, err := ParseChanges(data)
+	require.NoError(t, err)
+
+	assert.Equal(t, FieldChange{New: "POST"}, changes["method"])
+	assert.Equal(t, FieldChange{New: float64(201)}, changes["status_code"])
+	assert.Equal(t, FieldChange{Old: "user", New: "admin"}, changes["role"])
+	// Objects that merely contain "new" are legacy values, not changes
+	assert.Equal(t, FieldChange{New: map[string]interface{}{"new": "x"}}, changes["metadata"])
+
+	empty, err := ParseChanges(nil)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+
+	_, err = ParseChanges([]byte(`[1,2]`))
+	assert.Error(t, err)
+}
+
+func TestRender(t *testing.T) {
+	lines := Render(Changes{
+		"role":    {Old: "user", New: "admin"},
+		"quota":   {New: 10},
+		"team_id": {Old: "eng"},
+	})
+
+	require.Len(t, lines, 3)
+	assert.Equal(t, "quota: + 10", lines[0].Text)
+	assert.Equal(t, ChangeAdded, lines[0].Kind)
+	assert.Equal(t, `role: "user" → "admin"`, lines[1].Text)
+	assert.Equal(t, ChangeModified, lines[1].Kind)
+	assert.Equal(t, `team_id: - "eng"`, lines[2].Text)
+	assert.Equal(t, ChangeRemoved, lines[2].Kind)
+}
+
+func TestStructuredShapeRoundTrip(t *testing.T) {
+	data, err := json.Marshal(Values(map[string]interface{}{"path": "/api/v1/sessions"}))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"path":{"old":null,"new":"/api/v1/sessions"}}`, string(data))
+
+	changes, err := ParseChanges(data)
+	require.NoError(t, err)
+	assert.Equal(t, FieldChange{New: "/api/v1/sessions"}, changes["path"])
+}
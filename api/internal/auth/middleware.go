@@ -124,7 +124,7 @@
 // - "userEmail": string - User's email address
 // - "userRole": string - Role (admin, operator, user)
 // - "userGroups": []string - Group memberships
-// - "claims": *Claims - Full JWT claims object
+// - "claims": *Claims - Full JWT claims object (StreamSpace tokens only)
 //
 // THREAD SAFETY:
 //
@@ -133,11 +133,13 @@
 package auth
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/models"
 )
 
 // Middleware creates an authentication middleware that validates JWT tokens
@@ -153,6 +155,12 @@ import (
 // This dual-response approach was added to fix WebSocket connection issues where
 // JSON error responses would interfere with the WebSocket handshake protocol.
 func Middleware(jwtManager *JWTManager, userDB *db.UserDB) gin.HandlerFunc {
+	return MiddlewareWithProvider(NewJWTProvider(jwtManager), userDB)
+}
+
+// MiddlewareWithProvider is Middleware validating tokens with provider
+// instead of only accepting StreamSpace-issued JWTs (see AuthProvider).
+func MiddlewareWithProvider(provider AuthProvider, userDB *db.UserDB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Check if this is a WebSocket upgrade request
 		// WebSocket requests need special error handling (status code only, no JSON body)
@@ -201,40 +209,30 @@ func Middleware(jwtManager *JWTManager, userDB *db.UserDB) gin.HandlerFunc {
 			tokenString = parts[1]
 		}
 
-		// Validate token
-		claims, err := jwtManager.ValidateToken(tokenString)
+		// Validate token (for JWTs, also that the server-side session in
+		// Redis exists, so tokens can be invalidated on logout or restart)
+		identity, err := provider.Authenticate(c.Request.Context(), tokenString)
 		if err != nil {
 			if isWebSocket {
 				c.AbortWithStatus(http.StatusUnauthorized)
 				return
 			}
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error":   "Invalid or expired token",
-				"message": err.Error(),
-			})
-			c.Abort()
-			return
-		}
-
-		// Validate session exists in Redis (server-side session tracking)
-		// This ensures tokens can be invalidated on logout or server restart
-		if claims.ID != "" {
-			valid, err := jwtManager.ValidateSession(c.Request.Context(), claims.ID)
-			if err != nil || !valid {
-				if isWebSocket {
-					c.AbortWithStatus(http.StatusUnauthorized)
-					return
-				}
+			if errors.Is(err, ErrSessionInvalid) {
 				c.JSON(http.StatusUnauthorized, gin.H{
 					"error": "Session expired or invalidated",
 				})
-				c.Abort()
-				return
+			} else {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error":   "Invalid or expired token",
+					"message": err.Error(),
+				})
 			}
+			c.Abort()
+			return
 		}
 
 		// Verify user still exists and is active
-		user, err := userDB.GetUser(c.Request.Context(), claims.UserID)
+		user, err := userDB.GetUser(c.Request.Context(), identity.UserID)
 		if err != nil {
 			if isWebSocket {
 				c.AbortWithStatus(http.StatusUnauthorized)
@@ -260,20 +258,38 @@ func Middleware(jwtManager *JWTManager, userDB *db.UserDB) gin.HandlerFunc {
 		}
 
 		// Set user info in context for handlers to use
-		c.Set("userID", claims.UserID)
-		c.Set("username", claims.Username)
-		c.Set("userEmail", claims.Email)
-		c.Set("userRole", claims.Role)
-		c.Set("userGroups", claims.Groups)
-		c.Set("claims", claims)
-		c.Set("sessionID", claims.ID) // For logout/session management
+		setIdentity(c, identity, user)
 
 		c.Next()
 	}
 }
 
+// setIdentity stores the authenticated user in the Gin context (see CONTEXT
+// KEYS). A provider that asserts no role gets the user's StreamSpace role.
+func setIdentity(c *gin.Context, identity *Identity, user *models.User) {
+	role := identity.Role
+	if role == "" {
+		role = user.Role
+	}
+
+	c.Set("userID", identity.UserID)
+	c.Set("username", identity.Username)
+	c.Set("userEmail", identity.Email)
+	c.Set("userRole", role)
+	c.Set("userGroups", identity.Groups)
+	if identity.Claims != nil {
+		c.Set("claims", identity.Claims)
+	}
+	c.Set("sessionID", identity.SessionID) // For logout/session management
+}
+
 // OptionalAuth middleware allows both authenticated and unauthenticated requests
 func OptionalAuth(jwtManager *JWTManager, userDB *db.UserDB) gin.HandlerFunc {
+	return OptionalAuthWithProvider(NewJWTProvider(jwtManager), userDB)
+}
+
+// OptionalAuthWithProvider is OptionalAuth validating tokens with provider.
+func OptionalAuthWithProvider(provider AuthProvider, userDB *db.UserDB) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -288,32 +304,17 @@ func OptionalAuth(jwtManager *JWTManager, userDB *db.UserDB) gin.HandlerFunc {
 			return
 		}
 
-		tokenString := parts[1]
-		claims, err := jwtManager.ValidateToken(tokenString)
+		// Invalid token or session: continue without user context
+		identity, err := provider.Authenticate(c.Request.Context(), parts[1])
 		if err != nil {
 			c.Next()
 			return
 		}
 
-		// Validate session exists in Redis
-		if claims.ID != "" {
-			valid, err := jwtManager.ValidateSession(c.Request.Context(), claims.ID)
-			if err != nil || !valid {
-				// Session invalid, continue without user context
-				c.Next()
-				return
-			}
-		}
-
 		// Set user info if valid
-		user, err := userDB.GetUser(c.Request.Context(), claims.UserID)
+		user, err := userDB.GetUser(c.Request.Context(), identity.UserID)
 		if err == nil && user.Active {
-			c.Set("userID", claims.UserID)
-			c.Set("username", claims.Username)
-			c.Set("userEmail", claims.Email)
-			c.Set("userRole", claims.Role)
-			c.Set("userGroups", claims.Groups)
-			c.Set("sessionID", claims.ID)
+			setIdentity(c, identity, user)
 		}
 
 		c.Next()
@@ -0,0 +1,171 @@
+// Package auth provides authentication and authorization mechanisms for StreamSpace.
+// This file implements the AuthProvider for ID tokens of an OIDC identity provider.
+//
+// Unlike OIDCAuthenticator (the browser login flow, which ends in a
+// StreamSpace token), OIDCTokenProvider accepts the IdP's ID token itself as
+// the bearer token. The token is verified against the provider's published
+// keys, issuer and the configured client ID.
+//
+// USER PROVISIONING:
+//
+// A user is identified by the username claim. The first valid token of an
+// unknown user creates them (provider "oidc", role "user"); a local or SAML
+// user of the same name is never taken over. The groups claim is synced into
+// StreamSpace group memberships (source "oidc") for groups that exist in
+// StreamSpace, so group quotas and restricted templates follow the IdP.
+// Provisioning runs when the groups change and at most every
+// oidcProvisionInterval otherwise.
+package auth
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/models"
+)
+
+// OIDCUserProvider is the users.provider of users provisioned from OIDC
+// tokens, and the source of their synced group memberships.
+const OIDCUserProvider = "oidc"
+
+// oidcProvisionInterval is how long a provisioned user's groups are trusted
+// before they are synced again.
+const oidcProvisionInterval = 5 * time.Minute
+
+// provisionedUser is a user provisioned from an OIDC token.
+type provisionedUser struct {
+	userID   string
+	groups   string
+	syncedAt time.Time
+}
+
+// OIDCTokenProvider authenticates ID tokens of an OIDC identity provider.
+type OIDCTokenProvider struct {
+	config   *OIDCConfig
+	verifier *oidc.IDTokenVerifier
+	userDB   *db.UserDB
+
+	mu          sync.Mutex
+	provisioned map[string]provisionedUser
+}
+
+// NewOIDCTokenProvider discovers the identity provider at
+// config.ProviderURL and returns a provider accepting its ID tokens for
+// config.ClientID.
+func NewOIDCTokenProvider(ctx context.Context, config *OIDCConfig, userDB *db.UserDB) (*OIDCTokenProvider, error) {
+	if config.ProviderURL == "" {
+		return nil, fmt.Errorf("OIDC provider URL is required")
+	}
+	if config.ClientID == "" {
+		return nil, fmt.Errorf("OIDC client ID is required")
+	}
+	if config.UsernameClaim == "" {
+		config.UsernameClaim = "preferred_username"
+	}
+	if config.EmailClaim == "" {
+		config.EmailClaim = "email"
+	}
+	if config.GroupsClaim == "" {
+		config.GroupsClaim = "groups"
+	}
+
+	provider, err := oidc.NewProvider(ctx, config.ProviderURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover OIDC provider: %w", err)
+	}
+
+	return &OIDCTokenProvider{
+		config:      config,
+		verifier:    provider.Verifier(&oidc.Config{ClientID: config.ClientID}),
+		userDB:      userDB,
+		provisioned: make(map[string]provisionedUser),
+	}, nil
+}
+
+// Name implements AuthProvider.
+func (p *OIDCTokenProvider) Name() string {
+	return "oidc"
+}
+
+// Authenticate verifies the ID token and returns its user, provisioning
+// them and syncing their groups as needed.
+func (p *OIDCTokenProvider) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	idToken, err := p.verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to verify ID token: %w", err)
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("failed to parse ID token claims: %w", err)
+	}
+
+	email := extractStringClaim(claims, p.config.EmailClaim)
+	username := extractStringClaim(claims, p.config.UsernameClaim)
+	if username == "" {
+		username = email
+	}
+	if username == "" {
+		return nil, fmt.Errorf("ID token has no %s or %s claim", p.config.UsernameClaim, p.config.EmailClaim)
+	}
+	groups := extractArrayClaim(claims, p.config.GroupsClaim)
+
+	userID, err := p.provision(ctx, username, email, extractStringClaim(claims, "name"), groups)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Identity{
+		UserID:   userID,
+		Username: username,
+		Email:    email,
+		Groups:   groups,
+	}, nil
+}
+
+// provision returns the ID of the user, creating them on first use and
+// syncing their group memberships when the groups changed or are due.
+func (p *OIDCTokenProvider) provision(ctx context.Context, username, email, fullName string, groups []string) (string, error) {
+	sorted := append([]string(nil), groups...)
+	sort.Strings(sorted)
+	groupsKey := strings.Join(sorted, "\n")
+
+	p.mu.Lock()
+	known, ok := p.provisioned[username]
+	p.mu.Unlock()
+	if ok && known.groups == groupsKey && time.Since(known.syncedAt) < oidcProvisionInterval {
+		return known.userID, nil
+	}
+
+	user, err := p.userDB.GetUserByUsername(ctx, username)
+	if err != nil {
+		user, err = p.userDB.CreateUser(ctx, &models.CreateUserRequest{
+			Username: username,
+			Email:    email,
+			FullName: fullName,
+			Provider: OIDCUserProvider,
+			Role:     "user",
+		})
+		if err != nil {
+			return "", fmt.Errorf("failed to provision OIDC user %s: %w", username, err)
+		}
+	} else if user.Provider != OIDCUserProvider {
+		return "", fmt.Errorf("user %s is a %s user, not an OIDC user", username, user.Provider)
+	}
+
+	if err := p.userDB.SyncExternalGroups(ctx, user.ID, OIDCUserProvider, groups); err != nil {
+		return "", fmt.Errorf("failed to sync groups of OIDC user %s: %w", username, err)
+	}
+
+	p.mu.Lock()
+	p.provisioned[username] = provisionedUser{userID: user.ID, groups: groupsKey, syncedAt: time.Now()}
+	p.mu.Unlock()
+
+	return user.ID, nil
+}
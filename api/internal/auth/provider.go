@@ -0,0 +1,182 @@
+// Package auth provides authentication and authorization mechanisms for StreamSpace.
+// This file defines the pluggable authentication backend used by the middleware.
+//
+// AUTHENTICATION PROVIDERS:
+//
+// The middleware doesn't validate bearer tokens itself; it asks an
+// AuthProvider for the identity behind the token (user, email, groups).
+// Providers shipped with StreamSpace:
+//
+//   - JWTProvider: tokens issued by StreamSpace (local login, SAML, OIDC
+//     login flow). This is the default.
+//   - OIDCTokenProvider: ID tokens issued by a corporate identity provider
+//     (Keycloak, Okta, Azure AD, ...), so clients that already hold one don't
+//     need a StreamSpace login. Users are provisioned on first use and their
+//     StreamSpace group memberships follow the provider's groups claim, so
+//     group quotas and restricted templates apply to IdP groups.
+//
+// Other backends (LDAP, custom token services) implement AuthProvider and
+// are passed to MiddlewareWithProvider; no fork of the middleware is needed.
+//
+// CONFIGURATION:
+//
+//	AUTH_PROVIDER=jwt                 // jwt (default) or oidc
+//	OIDC_PROVIDER_URL=https://idp/... // Issuer URL (discovery)
+//	OIDC_CLIENT_ID=streamspace        // Expected token audience
+//	OIDC_USERNAME_CLAIM=preferred_username
+//	OIDC_EMAIL_CLAIM=email
+//	OIDC_GROUPS_CLAIM=groups
+//
+// With AUTH_PROVIDER=oidc, StreamSpace-issued tokens keep working (local
+// admin accounts, the SAML flow): each token is tried against StreamSpace
+// first and the identity provider second.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/streamspace/streamspace/api/internal/db"
+)
+
+// ErrSessionInvalid is returned for a valid token whose server-side session
+// was invalidated (logout, restart).
+var ErrSessionInvalid = errors.New("session expired or invalidated")
+
+// Identity is the user behind a validated token.
+type Identity struct {
+	// UserID is the StreamSpace user ID; the user must exist and be active.
+	UserID   string
+	Username string
+	Email    string
+
+	// Role is the role asserted by the token. Empty means the user's role
+	// in StreamSpace applies.
+	Role string
+
+	// Groups are the user's group memberships as reported by the provider.
+	Groups []string
+
+	// SessionID identifies the server-side session of the token (optional).
+	SessionID string
+
+	// Claims are the StreamSpace JWT claims (JWTProvider only).
+	Claims *Claims
+}
+
+// AuthProvider validates bearer tokens.
+type AuthProvider interface {
+	// Name identifies the provider in logs.
+	Name() string
+
+	// Authenticate returns the identity behind the token, or an error if
+	// the token isn't valid for this provider.
+	Authenticate(ctx context.Context, token string) (*Identity, error)
+}
+
+// JWTProvider authenticates tokens issued by StreamSpace.
+type JWTProvider struct {
+	jwtManager *JWTManager
+}
+
+// NewJWTProvider creates the provider of StreamSpace-issued tokens.
+func NewJWTProvider(jwtManager *JWTManager) *JWTProvider {
+	return &JWTProvider{jwtManager: jwtManager}
+}
+
+// Name implements AuthProvider.
+func (p *JWTProvider) Name() string {
+	return "jwt"
+}
+
+// Authenticate validates the token's signature and expiry and that its
+// server-side session still exists.
+func (p *JWTProvider) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	claims, err := p.jwtManager.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.ID != "" {
+		valid, err := p.jwtManager.ValidateSession(ctx, claims.ID)
+		if err != nil || !valid {
+			return nil, ErrSessionInvalid
+		}
+	}
+
+	return &Identity{
+		UserID:    claims.UserID,
+		Username:  claims.Username,
+		Email:     claims.Email,
+		Role:      claims.Role,
+		Groups:    claims.Groups,
+		SessionID: claims.ID,
+		Claims:    claims,
+	}, nil
+}
+
+// chainProvider tries providers in order.
+type chainProvider struct {
+	providers []AuthProvider
+}
+
+// ChainProviders returns a provider accepting a token any of providers
+// accepts, trying them in order.
+func ChainProviders(providers ...AuthProvider) AuthProvider {
+	return &chainProvider{providers: providers}
+}
+
+// Name implements AuthProvider.
+func (p *chainProvider) Name() string {
+	name := ""
+	for i, provider := range p.providers {
+		if i > 0 {
+			name += "+"
+		}
+		name += provider.Name()
+	}
+	return name
+}
+
+// Authenticate returns the identity from the first provider accepting the
+// token, or all providers' errors.
+func (p *chainProvider) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	var errs []error
+	for _, provider := range p.providers {
+		identity, err := provider.Authenticate(ctx, token)
+		if err == nil {
+			return identity, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", provider.Name(), err))
+	}
+	return nil, errors.Join(errs...)
+}
+
+// ProviderFromEnv returns the provider selected by AUTH_PROVIDER.
+func ProviderFromEnv(ctx context.Context, jwtManager *JWTManager, userDB *db.UserDB) (AuthProvider, error) {
+	jwtProvider := NewJWTProvider(jwtManager)
+
+	switch name := os.Getenv("AUTH_PROVIDER"); name {
+	case "", "jwt":
+		return jwtProvider, nil
+	case "oidc":
+		oidcProvider, err := NewOIDCTokenProvider(ctx, &OIDCConfig{
+			Enabled:       true,
+			ProviderURL:   os.Getenv("OIDC_PROVIDER_URL"),
+			ClientID:      os.Getenv("OIDC_CLIENT_ID"),
+			UsernameClaim: os.Getenv("OIDC_USERNAME_CLAIM"),
+			EmailClaim:    os.Getenv("OIDC_EMAIL_CLAIM"),
+			GroupsClaim:   os.Getenv("OIDC_GROUPS_CLAIM"),
+		}, userDB)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize OIDC authentication: %w", err)
+		}
+		log.Printf("Authenticating StreamSpace and OIDC tokens (issuer %s)", os.Getenv("OIDC_PROVIDER_URL"))
+		return ChainProviders(jwtProvider, oidcProvider), nil
+	default:
+		return nil, fmt.Errorf("unknown AUTH_PROVIDER %q (want jwt or oidc)", name)
+	}
+}
@@ -0,0 +1,110 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider accepts a single token.
+type fakeProvider struct {
+	name     string
+	token    string
+	identity *Identity
+	err      error
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	if token != p.token {
+		return nil, p.err
+	}
+	return p.identity, nil
+}
+
+func expectActiveUser(mock sqlmock.Sqlmock, userID, role string) {
+	mock.ExpectQuery("SELECT (.+) FROM users WHERE id").
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "full_name", "role", "provider", "active", "created_at", "updated_at", "last_login"}).
+			AddRow(userID, "alice", "alice@example.com", "Alice", role, "oidc", true, time.Now(), time.Now(), sql.NullTime{}))
+}
+
+func TestMiddlewareWithProvider(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	provider := &fakeProvider{
+		name:  "idp",
+		token: "idp-token",
+		identity: &Identity{
+			UserID:   "user1",
+			Username: "alice",
+			Groups:   []string{"developers"},
+		},
+		err: errors.New("unknown token"),
+	}
+
+	var role string
+	var groups []string
+	router := gin.New()
+	router.GET("/me", MiddlewareWithProvider(provider, db.NewUserDB(sqlDB)), func(c *gin.Context) {
+		role = c.GetString("userRole")
+		groups = c.GetStringSlice("userGroups")
+		c.Status(http.StatusOK)
+	})
+
+	// The provider asserts no role, so the user's StreamSpace role applies
+	expectActiveUser(mock, "user1", "operator")
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer idp-token")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "operator", role)
+	assert.Equal(t, []string{"developers"}, groups)
+
+	req = httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer forged-token")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid or expired token")
+}
+
+func TestChainProviders(t *testing.T) {
+	jwt := &fakeProvider{name: "jwt", token: "jwt-token", identity: &Identity{UserID: "local"}, err: ErrSessionInvalid}
+	idp := &fakeProvider{name: "oidc", token: "idp-token", identity: &Identity{UserID: "external"}, err: errors.New("bad signature")}
+	chain := ChainProviders(jwt, idp)
+
+	assert.Equal(t, "jwt+oidc", chain.Name())
+
+	identity, err := chain.Authenticate(context.Background(), "jwt-token")
+	require.NoError(t, err)
+	assert.Equal(t, "local", identity.UserID)
+
+	identity, err = chain.Authenticate(context.Background(), "idp-token")
+	require.NoError(t, err)
+	assert.Equal(t, "external", identity.UserID)
+
+	// A rejected token reports every provider's reason
+	_, err = chain.Authenticate(context.Background(), "other-token")
+	require.Error(t, err)
+	assert.True(t, errors.Is(err, ErrSessionInvalid))
+	assert.Contains(t, err.Error(), "oidc: bad signature")
+}
@@ -0,0 +1,184 @@
+// Package bandwidth accounts for the traffic of sessions' port-forward
+// tunnels.
+//
+// The port-forward tunnel (WebSocket to the client, raw TCP to the session)
+// counts the payload bytes it carries in each direction on a Counter. VNC and
+// web traffic reaches sessions through their ingress, not the API, and is
+// not counted. The
+// Meter keeps the counters of open tunnels in memory and periodically adds
+// what they counted to the session_bandwidth table, one row per session and
+// UTC day, so every API replica contributes and nothing is counted twice.
+//
+// Directions are seen from the session: "in" is client to session, "out" is
+// session to client.
+//
+// Environment:
+//   - BANDWIDTH_FLUSH_INTERVAL: how often counters are written (default 30s)
+package bandwidth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/streamspace/streamspace/api/internal/db"
+)
+
+// defaultFlushInterval is how often counters are written by default.
+const defaultFlushInterval = 30 * time.Second
+
+// Counter counts the bytes of one session's port-forward tunnels.
+type Counter struct {
+	SessionID string
+	UserID    string
+
+	in, out atomic.Int64
+	refs    int // open connections, guarded by Meter.mu
+}
+
+// AddIn counts bytes sent from the client to the session.
+func (c *Counter) AddIn(n int) {
+	c.in.Add(int64(n))
+}
+
+// AddOut counts bytes sent from the session to the client.
+func (c *Counter) AddOut(n int) {
+	c.out.Add(int64(n))
+}
+
+// Bytes returns the bytes counted in each direction since the last flush.
+func (c *Counter) Bytes() (in, out int64) {
+	return c.in.Load(), c.out.Load()
+}
+
+// Meter collects the counters of open connections and writes them to the
+// database.
+type Meter struct {
+	db            *db.Database
+	flushInterval time.Duration
+
+	mu       sync.Mutex
+	counters map[string]*Counter
+}
+
+// NewMeter creates a meter writing to database.
+func NewMeter(database *db.Database) *Meter {
+	interval := defaultFlushInterval
+	if d, err := time.ParseDuration(os.Getenv("BANDWIDTH_FLUSH_INTERVAL")); err == nil && d > 0 {
+		interval = d
+	}
+	return &Meter{
+		db:            database,
+		flushInterval: interval,
+		counters:      make(map[string]*Counter),
+	}
+}
+
+// Open returns the counter of a session for a new connection. Connections
+// to the same session share it; call Close when the connection ends.
+func (m *Meter) Open(sessionID, userID string) *Counter {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	counter, ok := m.counters[sessionID]
+	if !ok {
+		counter = &Counter{SessionID: sessionID, UserID: userID}
+		m.counters[sessionID] = counter
+	}
+	counter.refs++
+	return counter
+}
+
+// Close ends a connection opened with Open. Its bytes are written with the
+// next flush.
+func (m *Meter) Close(counter *Counter) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counter.refs--
+}
+
+// Start flushes counters every flush interval until ctx is cancelled, and
+// once more before it returns.
+func (m *Meter) Start(ctx context.Context) {
+	ticker := time.NewTicker(m.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := m.Flush(context.Background(), time.Now()); err != nil {
+				log.Printf("Failed to flush session bandwidth: %v", err)
+			}
+			return
+		case now := <-ticker.C:
+			if err := m.Flush(ctx, now); err != nil {
+				log.Printf("Failed to flush session bandwidth: %v", err)
+			}
+		}
+	}
+}
+
+// Flush adds the bytes counted since the last flush to the day of now, and
+// forgets the counters of sessions without open connections.
+func (m *Meter) Flush(ctx context.Context, now time.Time) error {
+	m.mu.Lock()
+	counters := make([]*Counter, 0, len(m.counters))
+	for id, counter := range m.counters {
+		counters = append(counters, counter)
+		if counter.refs <= 0 {
+			delete(m.counters, id)
+		}
+	}
+	m.mu.Unlock()
+
+	day := now.UTC().Format("2006-01-02")
+	var firstErr error
+	for _, counter := range counters {
+		in, out := counter.in.Swap(0), counter.out.Swap(0)
+		if in == 0 && out == 0 {
+			continue
+		}
+		if _, err := m.db.DB().ExecContext(ctx, `
+			INSERT INTO session_bandwidth (session_id, user_id, day, bytes_in, bytes_out, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			ON CONFLICT (session_id, day) DO UPDATE
+			SET bytes_in = session_bandwidth.bytes_in + EXCLUDED.bytes_in,
+			    bytes_out = session_bandwidth.bytes_out + EXCLUDED.bytes_out,
+			    updated_at = EXCLUDED.updated_at
+		`, counter.SessionID, counter.UserID, day, in, out, now); err != nil {
+			// Keep the bytes for the next flush
+			counter.in.Add(in)
+			counter.out.Add(out)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("session %s: %w", counter.SessionID, err)
+			}
+		}
+	}
+	return firstErr
+}
+
+// UserDailyUsage returns the bytes a user's sessions transferred on the day
+// of now, including bytes not yet flushed on this replica.
+func (m *Meter) UserDailyUsage(ctx context.Context, userID string, now time.Time) (int64, error) {
+	var used sql.NullInt64
+	if err := m.db.DB().QueryRowContext(ctx, `
+		SELECT SUM(bytes_in + bytes_out) FROM session_bandwidth
+		WHERE user_id = $1 AND day = $2
+	`, userID, now.UTC().Format("2006-01-02")).Scan(&used); err != nil {
+		return 0, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, counter := range m.counters {
+		if counter.UserID == userID {
+			used.Int64 += counter.in.Load() + counter.out.Load()
+		}
+	}
+	return used.Int64, nil
+}
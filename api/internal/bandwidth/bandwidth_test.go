@@ -0,0 +1,79 @@
+package bandwidth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeter_Flush(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	meter := NewMeter(db.NewDatabaseForTesting(sqlDB))
+	now := time.Date(2026, time.October, 12, 23, 30, 0, 0, time.UTC)
+
+	// Two connections to one session share its counter
+	first := meter.Open("sess-1", "alice")
+	second := meter.Open("sess-1", "alice")
+	assert.Same(t, first, second)
+	first.AddIn(100)
+	second.AddOut(4000)
+	meter.Close(first)
+
+	idle := meter.Open("sess-2", "bob")
+	meter.Close(idle)
+
+	mock.ExpectExec("INSERT INTO session_bandwidth").
+		WithArgs("sess-1", "alice", "2026-10-12", int64(100), int64(4000), now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, meter.Flush(context.Background(), now))
+
+	// Closed sessions are forgotten once flushed; open ones are kept
+	assert.Contains(t, meter.counters, "sess-1")
+	assert.NotContains(t, meter.counters, "sess-2")
+
+	// Failed writes are retried with the next flush
+	second.AddOut(50)
+	mock.ExpectExec("INSERT INTO session_bandwidth").
+		WithArgs("sess-1", "alice", "2026-10-12", int64(0), int64(50), now).
+		WillReturnError(errors.New("connection refused"))
+	assert.Error(t, meter.Flush(context.Background(), now))
+	second.AddOut(10)
+	mock.ExpectExec("INSERT INTO session_bandwidth").
+		WithArgs("sess-1", "alice", "2026-10-12", int64(0), int64(60), now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, meter.Flush(context.Background(), now))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMeter_UserDailyUsage(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	meter := NewMeter(db.NewDatabaseForTesting(sqlDB))
+	now := time.Date(2026, time.October, 12, 9, 0, 0, 0, time.UTC)
+
+	counter := meter.Open("sess-1", "alice")
+	counter.AddOut(500)
+	meter.Open("sess-2", "bob").AddOut(7000)
+
+	// Flushed bytes plus what this replica hasn't written yet
+	mock.ExpectQuery("SELECT SUM").
+		WithArgs("alice", "2026-10-12").
+		WillReturnRows(sqlmock.NewRows([]string{"sum"}).AddRow(1000))
+
+	used, err := meter.UserDailyUsage(context.Background(), "alice", now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1500), used)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,88 @@
+package bandwidth
+
+import (
+	"context"
+	"time"
+)
+
+// Usage is the traffic of a session or user.
+type Usage struct {
+	SessionID    string `json:"sessionId,omitempty"`
+	UserID       string `json:"userId"`
+	TemplateName string `json:"templateName,omitempty"`
+	BytesIn      int64  `json:"bytesIn"`
+	BytesOut     int64  `json:"bytesOut"`
+	Total        int64  `json:"total"`
+}
+
+// Report is the heaviest sessions and users since a day.
+type Report struct {
+	Since    string  `json:"since"`
+	BytesIn  int64   `json:"bytesIn"`
+	BytesOut int64   `json:"bytesOut"`
+	Sessions []Usage `json:"sessions"`
+	Users    []Usage `json:"users"`
+}
+
+// Report returns the traffic since the day of since (UTC), with the limit
+// sessions and users that transferred the most. Bytes not yet flushed are
+// not included.
+func (m *Meter) Report(ctx context.Context, since time.Time, limit int) (*Report, error) {
+	day := since.UTC().Format("2006-01-02")
+	report := &Report{Since: day, Sessions: []Usage{}, Users: []Usage{}}
+
+	if err := m.db.DB().QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(bytes_in), 0), COALESCE(SUM(bytes_out), 0)
+		FROM session_bandwidth WHERE day >= $1
+	`, day).Scan(&report.BytesIn, &report.BytesOut); err != nil {
+		return nil, err
+	}
+
+	rows, err := m.db.DB().QueryContext(ctx, `
+		SELECT b.session_id, b.user_id, COALESCE(s.template_name, ''),
+		       SUM(b.bytes_in), SUM(b.bytes_out)
+		FROM session_bandwidth b
+		LEFT JOIN sessions s ON s.id = b.session_id
+		WHERE b.day >= $1
+		GROUP BY b.session_id, b.user_id, s.template_name
+		ORDER BY SUM(b.bytes_in + b.bytes_out) DESC
+		LIMIT $2
+	`, day, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var usage Usage
+		if err := rows.Scan(&usage.SessionID, &usage.UserID, &usage.TemplateName, &usage.BytesIn, &usage.BytesOut); err != nil {
+			return nil, err
+		}
+		usage.Total = usage.BytesIn + usage.BytesOut
+		report.Sessions = append(report.Sessions, usage)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	userRows, err := m.db.DB().QueryContext(ctx, `
+		SELECT user_id, SUM(bytes_in), SUM(bytes_out)
+		FROM session_bandwidth
+		WHERE day >= $1
+		GROUP BY user_id
+		ORDER BY SUM(bytes_in + bytes_out) DESC
+		LIMIT $2
+	`, day, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer userRows.Close()
+	for userRows.Next() {
+		var usage Usage
+		if err := userRows.Scan(&usage.UserID, &usage.BytesIn, &usage.BytesOut); err != nil {
+			return nil, err
+		}
+		usage.Total = usage.BytesIn + usage.BytesOut
+		report.Users = append(report.Users, usage)
+	}
+	return report, userRows.Err()
+}
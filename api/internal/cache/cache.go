@@ -141,6 +141,12 @@ func (c *Cache) IsEnabled() bool {
 	return c.client != nil
 }
 
+// Client returns the underlying Redis client for features that share the
+// cache's connection, or nil if caching is disabled
+func (c *Cache) Client() *redis.Client {
+	return c.client
+}
+
 // Get retrieves a value from cache and unmarshals it into target
 func (c *Cache) Get(ctx context.Context, key string, target interface{}) error {
 	if !c.IsEnabled() {
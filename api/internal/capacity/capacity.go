@@ -0,0 +1,247 @@
+// Package capacity computes how much room the cluster has for new sessions.
+//
+// The report compares three figures:
+//   - allocatable: what the schedulable nodes offer to pods
+//   - committed: resource requests of all pods on those nodes, and the
+//     quota usage of the session pods among them (limits where set, see
+//     quota.Enforcer.CalculateUsage, cluster-wide)
+//   - used: what session pods actually use (metrics-server, if available)
+//
+// Headroom is answered per template category: how many more sessions of the
+// category's largest template fit. Sessions can't span nodes, so fits are
+// counted per node (free CPU, memory, GPUs and pod slots) and summed; the
+// cluster-wide free totals overstate what fits when they are fragmented.
+package capacity
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/streamspace/streamspace/api/internal/quota"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// gpuResource is the extended resource name of NVIDIA GPUs.
+const gpuResource corev1.ResourceName = "nvidia.com/gpu"
+
+// uncategorized is the category reported for templates without one.
+const uncategorized = "uncategorized"
+
+// Resources is an amount of CPU (millicores), memory (MiB) and GPUs.
+type Resources struct {
+	CPU    int64 `json:"cpu"`
+	Memory int64 `json:"memory"`
+	GPU    int64 `json:"gpu"`
+}
+
+func (r Resources) add(o Resources) Resources {
+	return Resources{CPU: r.CPU + o.CPU, Memory: r.Memory + o.Memory, GPU: r.GPU + o.GPU}
+}
+
+func (r Resources) sub(o Resources) Resources {
+	return Resources{CPU: r.CPU - o.CPU, Memory: r.Memory - o.Memory, GPU: r.GPU - o.GPU}
+}
+
+// larger reports whether r needs more than o (memory first, then CPU, GPUs).
+func (r Resources) larger(o Resources) bool {
+	if r.Memory != o.Memory {
+		return r.Memory > o.Memory
+	}
+	if r.CPU != o.CPU {
+		return r.CPU > o.CPU
+	}
+	return r.GPU > o.GPU
+}
+
+// Template is a template's session size.
+type Template struct {
+	Name     string
+	Category string
+	CPU      string // e.g. "1000m"
+	Memory   string // e.g. "2Gi"
+}
+
+// CategoryHeadroom is how many more sessions of a category fit.
+type CategoryHeadroom struct {
+	Category string `json:"category"`
+	// Template is the category's largest template, which sizes the estimate
+	Template  string    `json:"template"`
+	Size      Resources `json:"size"`
+	Templates int       `json:"templates"`
+	Fits      int       `json:"fits"`
+}
+
+// Report is the cluster's capacity for sessions.
+type Report struct {
+	Nodes            int `json:"nodes"`
+	SchedulableNodes int `json:"schedulableNodes"`
+
+	// Allocatable is the total allocatable of schedulable nodes
+	Allocatable Resources `json:"allocatable"`
+	// Committed is the requests of all pods on schedulable nodes
+	Committed Resources `json:"committed"`
+	// Free is Allocatable minus Committed
+	Free Resources `json:"free"`
+
+	// Sessions is the quota usage of running session pods (limits, or
+	// requests where no limit is set); above their requests when the
+	// controller overcommits them
+	Sessions       Resources `json:"sessions"`
+	ActiveSessions int       `json:"activeSessions"`
+	// Used is what session pods use; nil without metrics-server
+	Used *Resources `json:"used,omitempty"`
+
+	Categories  []CategoryHeadroom `json:"categories"`
+	GeneratedAt time.Time          `json:"generatedAt"`
+}
+
+// nodeCapacity is the free resources and pod slots of a node.
+type nodeCapacity struct {
+	free Resources
+	pods int64
+}
+
+// fits returns how many sessions of size fit in the node.
+func (n nodeCapacity) fits(size Resources) int64 {
+	count := n.pods
+	for _, dim := range []struct{ free, size int64 }{
+		{n.free.CPU, size.CPU},
+		{n.free.Memory, size.Memory},
+		{n.free.GPU, size.GPU},
+	} {
+		if dim.size <= 0 {
+			continue
+		}
+		if c := dim.free / dim.size; c < count {
+			count = c
+		}
+	}
+	if count < 0 {
+		return 0
+	}
+	return count
+}
+
+// Build computes the report from the cluster's nodes, all pods in the
+// cluster, the usage of session pods (quota.Enforcer.CalculateUsage) and the
+// templates. Pods not bound to a schedulable node don't commit capacity.
+func Build(nodes []corev1.Node, pods []corev1.Pod, sessions *quota.Usage, templates []Template) (*Report, error) {
+	report := &Report{Nodes: len(nodes), Categories: []CategoryHeadroom{}, GeneratedAt: time.Now()}
+
+	// Requests and pod counts per node
+	committed := make(map[string]Resources)
+	podCounts := make(map[string]int64)
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" || pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+			continue
+		}
+		committed[pod.Spec.NodeName] = committed[pod.Spec.NodeName].add(podRequests(&pod))
+		podCounts[pod.Spec.NodeName]++
+	}
+
+	var capacities []nodeCapacity
+	for _, node := range nodes {
+		if !schedulable(&node) {
+			continue
+		}
+		report.SchedulableNodes++
+
+		allocatable := Resources{
+			CPU:    node.Status.Allocatable.Cpu().MilliValue(),
+			Memory: node.Status.Allocatable.Memory().Value() / (1024 * 1024),
+		}
+		if gpu, ok := node.Status.Allocatable[gpuResource]; ok {
+			allocatable.GPU = gpu.Value()
+		}
+		report.Allocatable = report.Allocatable.add(allocatable)
+		report.Committed = report.Committed.add(committed[node.Name])
+
+		capacities = append(capacities, nodeCapacity{
+			free: allocatable.sub(committed[node.Name]),
+			pods: node.Status.Allocatable.Pods().Value() - podCounts[node.Name],
+		})
+	}
+	report.Free = report.Allocatable.sub(report.Committed)
+
+	report.Sessions = Resources{CPU: sessions.TotalCPU, Memory: sessions.TotalMemory, GPU: int64(sessions.TotalGPU)}
+	report.ActiveSessions = sessions.ActiveSessions
+
+	// Size each category by its largest template
+	categories := make(map[string]*CategoryHeadroom)
+	for _, template := range templates {
+		size, err := templateSize(template)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", template.Name, err)
+		}
+		category := template.Category
+		if category == "" {
+			category = uncategorized
+		}
+		headroom, ok := categories[category]
+		if !ok {
+			headroom = &CategoryHeadroom{Category: category}
+			categories[category] = headroom
+		}
+		headroom.Templates++
+		if headroom.Template == "" || size.larger(headroom.Size) {
+			headroom.Template = template.Name
+			headroom.Size = size
+		}
+	}
+
+	for _, headroom := range categories {
+		for _, node := range capacities {
+			headroom.Fits += int(node.fits(headroom.Size))
+		}
+		report.Categories = append(report.Categories, *headroom)
+	}
+	sort.Slice(report.Categories, func(i, j int) bool {
+		return report.Categories[i].Category < report.Categories[j].Category
+	})
+
+	return report, nil
+}
+
+// schedulable reports whether new pods can be placed on the node.
+func schedulable(node *corev1.Node) bool {
+	if node.Spec.Unschedulable {
+		return false
+	}
+	for _, condition := range node.Status.Conditions {
+		if condition.Type == corev1.NodeReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// podRequests sums the resource requests of a pod's containers.
+func podRequests(pod *corev1.Pod) Resources {
+	var requests Resources
+	for _, container := range pod.Spec.Containers {
+		requests.CPU += container.Resources.Requests.Cpu().MilliValue()
+		requests.Memory += container.Resources.Requests.Memory().Value() / (1024 * 1024)
+		if gpu, ok := container.Resources.Requests[gpuResource]; ok {
+			requests.GPU += gpu.Value()
+		}
+	}
+	return requests
+}
+
+// templateSize parses a template's session size.
+func templateSize(template Template) (Resources, error) {
+	var size Resources
+	var err error
+	if template.CPU != "" {
+		if size.CPU, err = quota.ParseResourceQuantity(template.CPU, "cpu"); err != nil {
+			return size, err
+		}
+	}
+	if template.Memory != "" {
+		if size.Memory, err = quota.ParseResourceQuantity(template.Memory, "memory"); err != nil {
+			return size, err
+		}
+	}
+	return size, nil
+}
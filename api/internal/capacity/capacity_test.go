@@ -0,0 +1,93 @@
+package capacity
+
+import (
+	"testing"
+
+	"github.com/streamspace/streamspace/api/internal/quota"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testNode(name, cpu, memory string, ready, unschedulable bool) corev1.Node {
+	status := corev1.ConditionTrue
+	if !ready {
+		status = corev1.ConditionFalse
+	}
+	return corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name},
+		Spec:       corev1.NodeSpec{Unschedulable: unschedulable},
+		Status: corev1.NodeStatus{
+			Allocatable: corev1.ResourceList{
+				corev1.ResourceCPU:    resource.MustParse(cpu),
+				corev1.ResourceMemory: resource.MustParse(memory),
+				corev1.ResourcePods:   resource.MustParse("110"),
+			},
+			Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: status}},
+		},
+	}
+}
+
+func testPod(node, cpu, memory string, phase corev1.PodPhase) corev1.Pod {
+	return corev1.Pod{
+		Spec: corev1.PodSpec{
+			NodeName: node,
+			Containers: []corev1.Container{{
+				Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse(cpu),
+					corev1.ResourceMemory: resource.MustParse(memory),
+				}},
+			}},
+		},
+		Status: corev1.PodStatus{Phase: phase},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	nodes := []corev1.Node{
+		testNode("node-a", "4", "16Gi", true, false),
+		testNode("node-b", "4", "16Gi", true, false),
+		testNode("node-c", "8", "32Gi", false, false), // not ready
+		testNode("node-d", "8", "32Gi", true, true),   // cordoned
+	}
+	sessionPod := testPod("node-a", "1", "4Gi", corev1.PodRunning)
+	pods := []corev1.Pod{
+		sessionPod,
+		testPod("node-b", "3", "2Gi", corev1.PodRunning),
+		testPod("node-b", "2", "2Gi", corev1.PodSucceeded), // completed: no longer committed
+		testPod("node-c", "4", "4Gi", corev1.PodRunning),   // node not schedulable
+	}
+	templates := []Template{
+		{Name: "firefox", Category: "browsers", CPU: "500m", Memory: "1Gi"},
+		{Name: "chrome", Category: "browsers", CPU: "1", Memory: "2Gi"},
+		{Name: "vscode", Category: "development", CPU: "2", Memory: "8Gi"},
+		{Name: "tool", Memory: "20Gi"},
+	}
+
+	sessions := (&quota.Enforcer{}).CalculateUsage([]corev1.Pod{sessionPod})
+	report, err := Build(nodes, pods, sessions, templates)
+	require.NoError(t, err)
+
+	assert.Equal(t, 4, report.Nodes)
+	assert.Equal(t, 2, report.SchedulableNodes)
+	assert.Equal(t, Resources{CPU: 8000, Memory: 32768}, report.Allocatable)
+	assert.Equal(t, Resources{CPU: 4000, Memory: 6144}, report.Committed)
+	assert.Equal(t, Resources{CPU: 4000, Memory: 26624}, report.Free)
+	assert.Equal(t, Resources{CPU: 1000, Memory: 4096}, report.Sessions)
+	assert.Equal(t, 1, report.ActiveSessions)
+
+	// Free: node-a 3 CPU/12Gi, node-b 1 CPU/14Gi
+	assert.Equal(t, []CategoryHeadroom{
+		{Category: "browsers", Template: "chrome", Size: Resources{CPU: 1000, Memory: 2048}, Templates: 2, Fits: 4},
+		{Category: "development", Template: "vscode", Size: Resources{CPU: 2000, Memory: 8192}, Templates: 1, Fits: 1},
+		// 20Gi fits on no single node though 26Gi are free in total
+		{Category: "uncategorized", Template: "tool", Size: Resources{Memory: 20480}, Templates: 1, Fits: 0},
+	}, report.Categories)
+}
+
+func TestBuild_InvalidTemplate(t *testing.T) {
+	_, err := Build(nil, nil, &quota.Usage{}, []Template{{Name: "broken", CPU: "lots"}})
+	assert.ErrorContains(t, err, "template broken")
+}
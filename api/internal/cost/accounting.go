@@ -0,0 +1,316 @@
+// Package cost provides resource-hour cost accounting for StreamSpace sessions.
+//
+// Costs are attributed from the session lifecycle data the API already keeps
+// in the sessions table: when a session was created, when it last changed
+// state, and the CPU/memory it requested. Like quotas, accounting is based on
+// REQUESTED resources (reservations), not measured usage: a session that
+// requests 2 CPUs for an hour costs 2 CPU-hours even if it was idle.
+//
+// Runtime model:
+//   - A session starts accruing at created_at
+//   - Sessions in a stopped state (hibernated, terminated, deleted, failed)
+//     stop accruing at updated_at, the time of their last state change
+//   - Running and pending sessions accrue until the end of the report range
+//   - Runtime is clipped to the report range
+//
+// Hibernate/wake cycles are not tracked individually, so a session that was
+// woken and hibernated again is charged from creation to its last
+// hibernation.
+//
+// Prices are configured per unit:
+//   - CPU-hour (1 core for 1 hour)
+//   - GB-hour (1 GiB of memory for 1 hour)
+//   - GPU-hour (1 GPU for 1 hour)
+//
+// Example usage:
+//
+//	accountant := cost.NewAccountant(database, cost.PricesFromEnv())
+//	report, err := accountant.Report(ctx, cost.GroupByUser, from, to)
+package cost
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/quota"
+)
+
+// Report grouping dimensions.
+const (
+	GroupByUser     = "user"
+	GroupByGroup    = "group"
+	GroupByTemplate = "template"
+	GroupByTag      = "tag"
+)
+
+// Keys used for sessions without a group or tags.
+const (
+	UnassignedGroup = "(unassigned)"
+	UntaggedTag     = "(untagged)"
+)
+
+// Prices are the unit prices used to turn resource-hours into cost.
+type Prices struct {
+	CPUHour      float64 `json:"cpuHour"`
+	MemoryGBHour float64 `json:"memoryGBHour"`
+	GPUHour      float64 `json:"gpuHour"`
+	Currency     string  `json:"currency"`
+}
+
+// PricesFromEnv reads unit prices from the environment.
+//
+//   - COST_PRICE_CPU_HOUR (default 0.04)
+//   - COST_PRICE_GB_HOUR (default 0.005)
+//   - COST_PRICE_GPU_HOUR (default 0.90)
+//   - COST_CURRENCY (default USD)
+func PricesFromEnv() Prices {
+	prices := Prices{
+		CPUHour:      envFloat("COST_PRICE_CPU_HOUR", 0.04),
+		MemoryGBHour: envFloat("COST_PRICE_GB_HOUR", 0.005),
+		GPUHour:      envFloat("COST_PRICE_GPU_HOUR", 0.90),
+		Currency:     os.Getenv("COST_CURRENCY"),
+	}
+	if prices.Currency == "" {
+		prices.Currency = "USD"
+	}
+	return prices
+}
+
+func envFloat(name string, def float64) float64 {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		log.Printf("Invalid %s %q, using %g", name, v, def)
+		return def
+	}
+	return f
+}
+
+// SessionUsage is the runtime and reservation of one session.
+type SessionUsage struct {
+	SessionID    string
+	UserID       string
+	GroupID      string
+	TemplateName string
+	Tags         []string
+	CPUMillis    int64 // Requested CPU in millicores
+	MemoryMiB    int64 // Requested memory in MiB
+	GPUs         int
+	Start        time.Time
+	End          *time.Time // nil while the session is still accruing
+}
+
+// LineItem is the resource-hours and cost attributed to one key.
+type LineItem struct {
+	Key           string  `json:"key"`
+	Sessions      int     `json:"sessions"`
+	Hours         float64 `json:"hours"`
+	CPUHours      float64 `json:"cpuHours"`
+	MemoryGBHours float64 `json:"memoryGBHours"`
+	GPUHours      float64 `json:"gpuHours"`
+	Cost          float64 `json:"cost"`
+}
+
+// Report is a cost report for a time range.
+type Report struct {
+	GroupBy string     `json:"groupBy"`
+	From    time.Time  `json:"from"`
+	To      time.Time  `json:"to"`
+	Prices  Prices     `json:"prices"`
+	Items   []LineItem `json:"items"`
+	Total   LineItem   `json:"total"`
+}
+
+// ValidGroupBy reports whether groupBy is a supported grouping dimension.
+func ValidGroupBy(groupBy string) bool {
+	switch groupBy {
+	case GroupByUser, GroupByGroup, GroupByTemplate, GroupByTag:
+		return true
+	}
+	return false
+}
+
+// Accountant computes cost reports from the sessions table.
+type Accountant struct {
+	db     *db.Database
+	prices Prices
+}
+
+// NewAccountant creates an accountant that prices usage with prices.
+func NewAccountant(database *db.Database, prices Prices) *Accountant {
+	return &Accountant{db: database, prices: prices}
+}
+
+// Prices returns the configured unit prices.
+func (a *Accountant) Prices() Prices {
+	return a.prices
+}
+
+// Report computes resource-hours and cost per groupBy key for [from, to).
+func (a *Accountant) Report(ctx context.Context, groupBy string, from, to time.Time) (*Report, error) {
+	if !ValidGroupBy(groupBy) {
+		return nil, fmt.Errorf("unsupported group_by %q", groupBy)
+	}
+
+	usages, err := a.loadUsage(ctx, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildReport(usages, groupBy, from, to, a.prices), nil
+}
+
+// loadUsage loads every session whose runtime overlaps [from, to).
+func (a *Accountant) loadUsage(ctx context.Context, from, to time.Time) ([]SessionUsage, error) {
+	rows, err := a.db.DB().QueryContext(ctx, `
+		SELECT id, user_id, COALESCE(team_id, ''), COALESCE(template_name, ''),
+		       COALESCE(cpu, ''), COALESCE(memory, ''), COALESCE(tags, '[]'),
+		       state, created_at, updated_at
+		FROM sessions
+		WHERE created_at < $2
+		  AND (state NOT IN ('hibernated', 'terminated', 'deleted', 'failed') OR updated_at > $1)
+	`, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session usage: %w", err)
+	}
+	defer rows.Close()
+
+	var usages []SessionUsage
+	for rows.Next() {
+		var u SessionUsage
+		var cpu, memory, state string
+		var tags []byte
+		var updatedAt time.Time
+
+		if err := rows.Scan(&u.SessionID, &u.UserID, &u.GroupID, &u.TemplateName,
+			&cpu, &memory, &tags, &state, &u.Start, &updatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan session usage: %w", err)
+		}
+
+		if cpu != "" {
+			if u.CPUMillis, err = quota.ParseResourceQuantity(cpu, "cpu"); err != nil {
+				log.Printf("Cost accounting: ignoring invalid CPU %q for session %s", cpu, u.SessionID)
+			}
+		}
+		if memory != "" {
+			if u.MemoryMiB, err = quota.ParseResourceQuantity(memory, "memory"); err != nil {
+				log.Printf("Cost accounting: ignoring invalid memory %q for session %s", memory, u.SessionID)
+			}
+		}
+		if len(tags) > 0 {
+			_ = json.Unmarshal(tags, &u.Tags)
+		}
+
+		switch state {
+		case "hibernated", "terminated", "deleted", "cancelled", "failed":
+			end := updatedAt
+			u.End = &end
+		}
+
+		usages = append(usages, u)
+	}
+
+	return usages, rows.Err()
+}
+
+// BuildReport aggregates session usage into a report.
+//
+// With GroupByTag a session counts fully toward each of its tags, so tag
+// line items can add up to more than the total.
+func BuildReport(usages []SessionUsage, groupBy string, from, to time.Time, prices Prices) *Report {
+	items := make(map[string]*LineItem)
+	report := &Report{
+		GroupBy: groupBy,
+		From:    from,
+		To:      to,
+		Prices:  prices,
+		Total:   LineItem{Key: "total"},
+	}
+
+	for _, u := range usages {
+		start := u.Start
+		if start.Before(from) {
+			start = from
+		}
+		end := to
+		if u.End != nil && u.End.Before(to) {
+			end = *u.End
+		}
+		if !end.After(start) {
+			continue
+		}
+
+		hours := end.Sub(start).Hours()
+		usage := LineItem{
+			Sessions:      1,
+			Hours:         hours,
+			CPUHours:      float64(u.CPUMillis) / 1000 * hours,
+			MemoryGBHours: float64(u.MemoryMiB) / 1024 * hours,
+			GPUHours:      float64(u.GPUs) * hours,
+		}
+		usage.Cost = usage.CPUHours*prices.CPUHour + usage.MemoryGBHours*prices.MemoryGBHour + usage.GPUHours*prices.GPUHour
+
+		for _, key := range groupKeys(u, groupBy) {
+			item, ok := items[key]
+			if !ok {
+				item = &LineItem{Key: key}
+				items[key] = item
+			}
+			item.add(usage)
+		}
+		report.Total.add(usage)
+	}
+
+	report.Items = make([]LineItem, 0, len(items))
+	for _, item := range items {
+		report.Items = append(report.Items, *item)
+	}
+	// Most expensive first
+	sort.Slice(report.Items, func(i, j int) bool {
+		if report.Items[i].Cost != report.Items[j].Cost {
+			return report.Items[i].Cost > report.Items[j].Cost
+		}
+		return report.Items[i].Key < report.Items[j].Key
+	})
+
+	return report
+}
+
+// groupKeys returns the keys a session is attributed to.
+func groupKeys(u SessionUsage, groupBy string) []string {
+	switch groupBy {
+	case GroupByGroup:
+		if u.GroupID == "" {
+			return []string{UnassignedGroup}
+		}
+		return []string{u.GroupID}
+	case GroupByTemplate:
+		return []string{u.TemplateName}
+	case GroupByTag:
+		if len(u.Tags) == 0 {
+			return []string{UntaggedTag}
+		}
+		return u.Tags
+	default:
+		return []string{u.UserID}
+	}
+}
+
+func (l *LineItem) add(other LineItem) {
+	l.Sessions += other.Sessions
+	l.Hours += other.Hours
+	l.CPUHours += other.CPUHours
+	l.MemoryGBHours += other.MemoryGBHours
+	l.GPUHours += other.GPUHours
+	l.Cost += other.Cost
+}
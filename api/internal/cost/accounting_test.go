@@ -0,0 +1,73 @@
+package cost
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildReport_GroupByUser(t *testing.T) {
+	to := time.Date(2025, 11, 30, 0, 0, 0, 0, time.UTC)
+	from := to.Add(-10 * time.Hour)
+	stopped := from.Add(2 * time.Hour)
+	stoppedBefore := from.Add(-time.Hour)
+	prices := Prices{CPUHour: 1, MemoryGBHour: 0.5, GPUHour: 10}
+
+	usages := []SessionUsage{
+		// Still running, started before the range: clipped to 10h
+		{SessionID: "a", UserID: "alice", CPUMillis: 2000, MemoryMiB: 4096, Start: from.Add(-5 * time.Hour)},
+		// Stopped after 2h inside the range
+		{SessionID: "b", UserID: "alice", CPUMillis: 1000, MemoryMiB: 1024, Start: from, End: &stopped},
+		// Stopped before the range: contributes nothing
+		{SessionID: "c", UserID: "bob", CPUMillis: 1000, Start: from.Add(-3 * time.Hour), End: &stoppedBefore},
+		// GPU session
+		{SessionID: "d", UserID: "bob", GPUs: 1, Start: to.Add(-time.Hour)},
+	}
+
+	report := BuildReport(usages, GroupByUser, from, to, prices)
+	require.Len(t, report.Items, 2)
+
+	alice := report.Items[0]
+	assert.Equal(t, "alice", alice.Key)
+	assert.Equal(t, 2, alice.Sessions)
+	assert.InDelta(t, 12, alice.Hours, 0.001)
+	assert.InDelta(t, 22, alice.CPUHours, 0.001)      // 2*10 + 1*2
+	assert.InDelta(t, 42, alice.MemoryGBHours, 0.001) // 4*10 + 1*2
+	assert.InDelta(t, 43, alice.Cost, 0.001)          // 22*1 + 42*0.5
+
+	bob := report.Items[1]
+	assert.Equal(t, "bob", bob.Key)
+	assert.Equal(t, 1, bob.Sessions)
+	assert.InDelta(t, 1, bob.GPUHours, 0.001)
+	assert.InDelta(t, 10, bob.Cost, 0.001)
+
+	assert.Equal(t, 3, report.Total.Sessions)
+	assert.InDelta(t, 53, report.Total.Cost, 0.001)
+}
+
+func TestBuildReport_GroupByTagAndGroup(t *testing.T) {
+	to := time.Date(2025, 11, 30, 0, 0, 0, 0, time.UTC)
+	from := to.Add(-time.Hour)
+	prices := Prices{CPUHour: 1}
+
+	usages := []SessionUsage{
+		{SessionID: "a", UserID: "alice", GroupID: "eng", Tags: []string{"proj-x", "gpu"}, CPUMillis: 1000, Start: from},
+		{SessionID: "b", UserID: "bob", CPUMillis: 1000, Start: from},
+	}
+
+	byTag := BuildReport(usages, GroupByTag, from, to, prices)
+	keys := map[string]float64{}
+	for _, item := range byTag.Items {
+		keys[item.Key] = item.Cost
+	}
+	assert.Equal(t, map[string]float64{"proj-x": 1, "gpu": 1, UntaggedTag: 1}, keys)
+	// Tagged sessions count fully toward each tag, but once in the total
+	assert.InDelta(t, 2, byTag.Total.Cost, 0.001)
+
+	byGroup := BuildReport(usages, GroupByGroup, from, to, prices)
+	require.Len(t, byGroup.Items, 2)
+	assert.Equal(t, UnassignedGroup, byGroup.Items[0].Key)
+	assert.Equal(t, "eng", byGroup.Items[1].Key)
+}
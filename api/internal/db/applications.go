@@ -413,17 +413,31 @@ func (a *ApplicationDB) UpdateApplication(ctx context.Context, appID string, req
 	return nil
 }
 
-// DeleteApplication deletes an installed application
+// DeleteApplication deletes an installed application and its group access
+// rules. Either both are deleted or, on error, neither.
 func (a *ApplicationDB) DeleteApplication(ctx context.Context, appID string) error {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if we don't commit
+
 	// Delete group access first
-	_, err := a.db.ExecContext(ctx, "DELETE FROM application_group_access WHERE application_id = $1", appID)
+	_, err = tx.ExecContext(ctx, "DELETE FROM application_group_access WHERE application_id = $1", appID)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to delete group access: %w", err)
 	}
 
 	// Delete application
-	_, err = a.db.ExecContext(ctx, "DELETE FROM installed_applications WHERE id = $1", appID)
-	return err
+	_, err = tx.ExecContext(ctx, "DELETE FROM installed_applications WHERE id = $1", appID)
+	if err != nil {
+		return fmt.Errorf("failed to delete application: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
 }
 
 // SetApplicationEnabled enables or disables an application
@@ -468,6 +482,65 @@ func (a *ApplicationDB) RemoveGroupAccess(ctx context.Context, appID, groupID st
 	return err
 }
 
+// GroupAccessRemovalError reports which group a bulk access removal failed
+// on. The removal was rolled back: no group lost access.
+type GroupAccessRemovalError struct {
+	GroupID string
+	Err     error
+}
+
+func (e *GroupAccessRemovalError) Error() string {
+	return fmt.Sprintf("failed to remove access for group %s: %v", e.GroupID, e.Err)
+}
+
+func (e *GroupAccessRemovalError) Unwrap() error {
+	return e.Err
+}
+
+// RemoveGroupsAccess removes several groups' access to an application in one
+// transaction and returns the groups that had access and lost it (groups
+// without access are skipped).
+//
+// If any removal fails the transaction is rolled back, so access rules are
+// never left half removed; the error is a *GroupAccessRemovalError when a
+// specific group failed.
+func (a *ApplicationDB) RemoveGroupsAccess(ctx context.Context, appID string, groupIDs []string) ([]string, error) {
+	tx, err := a.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() // Rollback if we don't commit
+
+	removed := []string{}
+	seen := make(map[string]bool, len(groupIDs))
+	for _, groupID := range groupIDs {
+		if seen[groupID] {
+			continue
+		}
+		seen[groupID] = true
+
+		result, err := tx.ExecContext(ctx, `
+			DELETE FROM application_group_access
+			WHERE application_id = $1 AND group_id = $2
+		`, appID, groupID)
+		if err != nil {
+			return nil, &GroupAccessRemovalError{GroupID: groupID, Err: err}
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return nil, &GroupAccessRemovalError{GroupID: groupID, Err: err}
+		}
+		if affected > 0 {
+			removed = append(removed, groupID)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return removed, nil
+}
+
 // GetApplicationGroups retrieves all groups with access to an application
 func (a *ApplicationDB) GetApplicationGroups(ctx context.Context, appID string) ([]*models.ApplicationGroupAccess, error) {
 	query := `
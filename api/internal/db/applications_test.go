@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"testing"
 	"time"
@@ -170,6 +171,8 @@ func TestDeleteApplication_Success(t *testing.T) {
 
 	appID := "app-123"
 
+	mock.ExpectBegin()
+
 	// Expect delete group access
 	mock.ExpectExec("DELETE FROM application_group_access").
 		WithArgs(appID).
@@ -180,12 +183,99 @@ func TestDeleteApplication_Success(t *testing.T) {
 		WithArgs(appID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
+	mock.ExpectCommit()
+
 	err = appDB.DeleteApplication(ctx, appID)
 
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestDeleteApplication_RollsBackOnError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	appDB := NewApplicationDB(db)
+	ctx := context.Background()
+
+	appID := "app-123"
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM application_group_access").
+		WithArgs(appID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM installed_applications").
+		WithArgs(appID).
+		WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+
+	err = appDB.DeleteApplication(ctx, appID)
+
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveGroupsAccess_Success(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	appDB := NewApplicationDB(db)
+	ctx := context.Background()
+
+	appID := "app-123"
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM application_group_access").
+		WithArgs(appID, "group-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM application_group_access").
+		WithArgs(appID, "group-2").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM application_group_access").
+		WithArgs(appID, "group-3").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	removed, err := appDB.RemoveGroupsAccess(ctx, appID, []string{"group-1", "group-2", "group-1", "group-3"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"group-1", "group-3"}, removed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRemoveGroupsAccess_RollsBackMidBatchError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	appDB := NewApplicationDB(db)
+	ctx := context.Background()
+
+	appID := "app-123"
+
+	// The first group is removed inside the transaction, the second fails:
+	// the transaction must be rolled back, never committed
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM application_group_access").
+		WithArgs(appID, "group-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM application_group_access").
+		WithArgs(appID, "group-2").
+		WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+
+	removed, err := appDB.RemoveGroupsAccess(ctx, appID, []string{"group-1", "group-2", "group-3"})
+
+	assert.Nil(t, removed)
+	var removalErr *GroupAccessRemovalError
+	require.ErrorAs(t, err, &removalErr)
+	assert.Equal(t, "group-2", removalErr.GroupID)
+	assert.ErrorIs(t, err, sql.ErrConnDone)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestAddGroupAccess_Success(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -202,6 +202,12 @@ func (d *Database) Close() error {
 	return d.db.Close()
 }
 
+// NewDatabaseForTesting wraps an existing connection (e.g. sqlmock) without
+// validating config or pinging. For use in tests only.
+func NewDatabaseForTesting(db *sql.DB) *Database {
+	return &Database{db: db}
+}
+
 // DB returns the underlying sql.DB
 func (d *Database) DB() *sql.DB {
 	return d.db
@@ -277,6 +283,14 @@ func (d *Database) Migrate() error {
 			UNIQUE(user_id, group_id)
 		)`,
 
+		// Highest session priority class a user or group may request
+		`ALTER TABLE user_quotas ADD COLUMN IF NOT EXISTS max_priority VARCHAR(20)`,
+		`ALTER TABLE group_quotas ADD COLUMN IF NOT EXISTS max_priority VARCHAR(20)`,
+
+		// Per-template and per-category session limits ({"templates": {...}, "categories": {...}})
+		`ALTER TABLE user_quotas ADD COLUMN IF NOT EXISTS template_session_limits JSONB`,
+		`ALTER TABLE group_quotas ADD COLUMN IF NOT EXISTS template_session_limits JSONB`,
+
 		// Create indexes for user/group management
 		`CREATE INDEX IF NOT EXISTS idx_users_username ON users(username)`,
 		`CREATE INDEX IF NOT EXISTS idx_users_email ON users(email)`,
@@ -897,6 +911,18 @@ func (d *Database) Migrate() error {
 		`CREATE INDEX IF NOT EXISTS idx_user_favorite_templates_user_id ON user_favorite_templates(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_user_favorite_templates_template ON user_favorite_templates(template_name)`,
 
+		// Resources each user last launched each template with (pre-filled on
+		// the next launch; deleting the row resets to template defaults).
+		// user_id matches sessions.user_id
+		`CREATE TABLE IF NOT EXISTS user_template_resources (
+			user_id VARCHAR(255) NOT NULL,
+			template_name VARCHAR(255) NOT NULL,
+			memory VARCHAR(50) NOT NULL,
+			cpu VARCHAR(50) NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (user_id, template_name)
+		)`,
+
 		// ========== Notifications System ==========
 
 		// In-app notifications (stored notifications for users)
@@ -1596,6 +1622,16 @@ func (d *Database) Migrate() error {
 		// Create indexes for collaboration
 		`CREATE INDEX IF NOT EXISTS idx_collaboration_sessions_session_id ON collaboration_sessions(session_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_collaboration_sessions_status ON collaboration_sessions(status)`,
+
+		// Only one active collaboration per session. End any duplicates left by
+		// concurrent creates (keeping the newest) before adding the constraint.
+		`UPDATE collaboration_sessions c SET status = 'ended', ended_at = CURRENT_TIMESTAMP
+		WHERE c.status = 'active' AND EXISTS (
+			SELECT 1 FROM collaboration_sessions d
+			WHERE d.session_id = c.session_id AND d.status = 'active'
+			AND (d.created_at > c.created_at OR (d.created_at = c.created_at AND d.id > c.id))
+		)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_collaboration_sessions_one_active ON collaboration_sessions(session_id) WHERE status = 'active'`,
 		`CREATE INDEX IF NOT EXISTS idx_collaboration_participants_collab_id ON collaboration_participants(collaboration_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_collaboration_participants_user_id ON collaboration_participants(user_id)`,
 		`CREATE INDEX IF NOT EXISTS idx_collaboration_participants_is_active ON collaboration_participants(is_active) WHERE is_active = true`,
@@ -1664,6 +1700,25 @@ func (d *Database) Migrate() error {
 		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_status ON webhook_deliveries(status)`,
 		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_next_retry ON webhook_deliveries(next_retry_at) WHERE next_retry_at IS NOT NULL`,
 		`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_created_at ON webhook_deliveries(created_at DESC)`,
+
+		// Last lifecycle state per session, used to fire session.ready /
+		// session.terminated / session.failed webhooks once per transition
+		`CREATE TABLE IF NOT EXISTS session_lifecycle_states (
+			session_id VARCHAR(255) PRIMARY KEY,
+			state VARCHAR(50) NOT NULL,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+
+		// Session creates no controller acknowledged, replayed on controller
+		// sync or heartbeat (core NATS drops messages nobody is listening for)
+		`CREATE TABLE IF NOT EXISTS pending_session_creates (
+			session_id VARCHAR(255) PRIMARY KEY,
+			platform VARCHAR(50) NOT NULL,
+			target_controller VARCHAR(255) NOT NULL DEFAULT '',
+			event TEXT NOT NULL,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_pending_session_creates_platform ON pending_session_creates(platform)`,
 		`CREATE INDEX IF NOT EXISTS idx_integrations_type ON integrations(type)`,
 		`CREATE INDEX IF NOT EXISTS idx_integrations_enabled ON integrations(enabled) WHERE enabled = true`,
 
@@ -2098,8 +2153,102 @@ func (d *Database) Migrate() error {
 		`ALTER TABLE sessions ADD COLUMN IF NOT EXISTS max_session_duration VARCHAR(50)`,
 		`ALTER TABLE sessions ADD COLUMN IF NOT EXISTS last_activity TIMESTAMP`,
 
+		// Host port mappings for port-forwarding (Docker sessions)
+		`ALTER TABLE sessions ADD COLUMN IF NOT EXISTS forwarded_ports JSONB`,
+
+		// Session tags (for cost attribution by tag)
+		`ALTER TABLE sessions ADD COLUMN IF NOT EXISTS tags JSONB DEFAULT '[]'`,
+
+		// Pending state request and its actor (for auditing who caused a state change)
+		`ALTER TABLE sessions ADD COLUMN IF NOT EXISTS requested_state VARCHAR(50)`,
+		`ALTER TABLE sessions ADD COLUMN IF NOT EXISTS requested_by VARCHAR(255)`,
+
+		// Identity provider a group membership was synced from (NULL: added in StreamSpace)
+		`ALTER TABLE group_memberships ADD COLUMN IF NOT EXISTS source VARCHAR(100)`,
+
+		// Session pre-warming: per-user opt-in and the pre-warms made
+		`CREATE TABLE IF NOT EXISTS session_prewarm_settings (
+			user_id VARCHAR(255) PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+			enabled BOOLEAN NOT NULL DEFAULT false,
+			disabled_templates JSONB DEFAULT '[]',
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		)`,
+		`CREATE TABLE IF NOT EXISTS session_prewarms (
+			id SERIAL PRIMARY KEY,
+			user_id VARCHAR(255) NOT NULL,
+			template_name VARCHAR(255) NOT NULL,
+			session_id VARCHAR(255),
+			predicted_at TIMESTAMP NOT NULL,
+			prewarmed_at TIMESTAMP,
+			outcome VARCHAR(20) NOT NULL DEFAULT 'pending',
+			UNIQUE(user_id, template_name, predicted_at)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_session_prewarms_outcome ON session_prewarms(outcome, predicted_at)`,
+
+		// Time of the user's last successful MFA verification (MFA step-up)
+		`ALTER TABLE users ADD COLUMN IF NOT EXISTS mfa_verified_at TIMESTAMP`,
+
+		// Normalize legacy audit_log.changes to the {field: {old, new}} shape
+		// (see package audit). Values that aren't already an {old, new} object
+		// become the field's new value. Already-normalized rows are skipped.
+		`UPDATE audit_log SET changes = (
+			SELECT COALESCE(jsonb_object_agg(e.key,
+				CASE WHEN jsonb_typeof(e.value) = 'object' AND e.value ?& array['old', 'new'] AND e.value - 'old' - 'new' = '{}'::jsonb
+					THEN e.value
+					ELSE jsonb_build_object('old', NULL, 'new', e.value)
+				END), '{}'::jsonb)
+			FROM jsonb_each(changes) e
+		)
+		WHERE jsonb_typeof(changes) = 'object'
+		  AND EXISTS (
+			SELECT 1 FROM jsonb_each(changes) e
+			WHERE NOT (jsonb_typeof(e.value) = 'object' AND e.value ?& array['old', 'new'] AND e.value - 'old' - 'new' = '{}'::jsonb)
+		  )`,
+
 		// Create index for idle session queries
 		`CREATE INDEX IF NOT EXISTS idx_sessions_last_activity ON sessions(last_activity)`,
+
+		// Observed session resource usage for right-sizing recommendations
+		// (see package rightsize). Sampled from metrics-server.
+		`CREATE TABLE IF NOT EXISTS session_resource_samples (
+			session_id VARCHAR(255) NOT NULL,
+			sampled_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			cpu_millicores BIGINT NOT NULL,
+			memory_bytes BIGINT NOT NULL
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_session_resource_samples_session ON session_resource_samples(session_id, sampled_at)`,
+		`CREATE INDEX IF NOT EXISTS idx_session_resource_samples_sampled_at ON session_resource_samples(sampled_at)`,
+
+		// Bytes tunneled to and from sessions per UTC day (see package bandwidth)
+		`CREATE TABLE IF NOT EXISTS session_bandwidth (
+			session_id VARCHAR(255) NOT NULL,
+			user_id VARCHAR(255) NOT NULL,
+			day DATE NOT NULL,
+			bytes_in BIGINT NOT NULL DEFAULT 0,
+			bytes_out BIGINT NOT NULL DEFAULT 0,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (session_id, day)
+		)`,
+		`CREATE INDEX IF NOT EXISTS idx_session_bandwidth_user_day ON session_bandwidth(user_id, day)`,
+		`CREATE INDEX IF NOT EXISTS idx_session_bandwidth_day ON session_bandwidth(day)`,
+
+		// Daily port-forward traffic cap in MiB (NULL or 0: unlimited)
+		`ALTER TABLE user_quotas ADD COLUMN IF NOT EXISTS max_bandwidth_per_day BIGINT`,
+		`ALTER TABLE group_quotas ADD COLUMN IF NOT EXISTS max_bandwidth_per_day BIGINT`,
+
+		// Whether a group quota lowers (restrict) or raises (grant) members' limits
+		`ALTER TABLE group_quotas ADD COLUMN IF NOT EXISTS quota_mode VARCHAR(20) NOT NULL DEFAULT 'restrict'`,
+
+		// Monthly session-hours budget (NULL or 0: unlimited)
+		`ALTER TABLE user_quotas ADD COLUMN IF NOT EXISTS max_session_hours_per_month BIGINT`,
+		`ALTER TABLE group_quotas ADD COLUMN IF NOT EXISTS max_session_hours_per_month BIGINT`,
+		// Launches held for a template's per-user session limit
+		// (see events.HoldSessionCreate)
+		`ALTER TABLE pending_session_creates ADD COLUMN IF NOT EXISTS user_id VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE pending_session_creates ADD COLUMN IF NOT EXISTS template_name VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE pending_session_creates ADD COLUMN IF NOT EXISTS max_concurrent INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE pending_session_creates ADD COLUMN IF NOT EXISTS held BOOLEAN NOT NULL DEFAULT false`,
+		`CREATE INDEX IF NOT EXISTS idx_pending_session_creates_held ON pending_session_creates(user_id, template_name, created_at) WHERE held`,
 	}
 
 	// Execute migrations
@@ -37,6 +37,7 @@
 //   - group_quotas table: Resource limits per group
 //     - group_id: Foreign key to groups
 //     - max_sessions, max_cpu, max_memory, max_storage: Limits
+//     - quota_mode: "restrict" (lowers members' limits) or "grant" (raises them)
 //     - used_sessions, used_cpu, used_memory, used_storage: Current usage
 //
 // Quota Hierarchy:
@@ -46,7 +47,8 @@
 //
 // Implementation Details:
 // - Groups can have resource quotas that apply to all members
-// - Most restrictive quota wins (user vs group vs platform)
+// - Most restrictive quota wins (user vs group vs platform), except that
+//   grant-mode group quotas raise limits (see models.GroupQuotaModeGrant)
 // - Quota stored as separate table with foreign key constraint
 // - Supports hierarchical groups with parent_id
 // - Member counts calculated via JOIN for efficiency
@@ -87,6 +89,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -400,6 +403,7 @@ func (g *GroupDB) GetGroupQuota(ctx context.Context, groupID string) (*models.Gr
 	quota := &models.GroupQuota{}
 	query := `
 		SELECT group_id, max_sessions, max_cpu, max_memory, max_storage,
+		       COALESCE(quota_mode, 'restrict'),
 		       used_sessions, used_cpu, used_memory, used_storage,
 		       created_at, updated_at
 		FROM group_quotas
@@ -408,6 +412,7 @@ func (g *GroupDB) GetGroupQuota(ctx context.Context, groupID string) (*models.Gr
 
 	err := g.db.QueryRowContext(ctx, query, groupID).Scan(
 		&quota.GroupID, &quota.MaxSessions, &quota.MaxCPU, &quota.MaxMemory, &quota.MaxStorage,
+		&quota.QuotaMode,
 		&quota.UsedSessions, &quota.UsedCPU, &quota.UsedMemory, &quota.UsedStorage,
 		&quota.CreatedAt, &quota.UpdatedAt,
 	)
@@ -457,6 +462,40 @@ func (g *GroupDB) SetGroupQuota(ctx context.Context, groupID string, req *models
 			argIdx++
 		}
 
+		if req.MaxPriority != nil {
+			updates = append(updates, fmt.Sprintf("max_priority = $%d", argIdx))
+			args = append(args, *req.MaxPriority)
+			argIdx++
+		}
+
+		if req.MaxBandwidthPerDay != nil {
+			updates = append(updates, fmt.Sprintf("max_bandwidth_per_day = $%d", argIdx))
+			args = append(args, *req.MaxBandwidthPerDay)
+			argIdx++
+		}
+
+		if req.MaxSessionHoursPerMonth != nil {
+			updates = append(updates, fmt.Sprintf("max_session_hours_per_month = $%d", argIdx))
+			args = append(args, *req.MaxSessionHoursPerMonth)
+			argIdx++
+		}
+
+		if req.QuotaMode != nil {
+			updates = append(updates, fmt.Sprintf("quota_mode = $%d", argIdx))
+			args = append(args, *req.QuotaMode)
+			argIdx++
+		}
+
+		if req.TemplateSessionLimits != nil {
+			limits, err := json.Marshal(req.TemplateSessionLimits)
+			if err != nil {
+				return fmt.Errorf("failed to encode template session limits: %w", err)
+			}
+			updates = append(updates, fmt.Sprintf("template_session_limits = $%d", argIdx))
+			args = append(args, string(limits))
+			argIdx++
+		}
+
 		if len(updates) == 0 {
 			return nil
 		}
@@ -503,8 +542,16 @@ func (g *GroupDB) SetGroupQuota(ctx context.Context, groupID string, req *models
 			groupID, maxSessions, maxCPU, maxMemory, maxStorage,
 			time.Now(), time.Now(),
 		)
-
-		return err
+		if err != nil || (req.MaxPriority == nil && req.TemplateSessionLimits == nil && req.MaxBandwidthPerDay == nil && req.MaxSessionHoursPerMonth == nil && req.QuotaMode == nil) {
+			return err
+		}
+		return g.SetGroupQuota(ctx, groupID, &models.SetQuotaRequest{
+			MaxPriority:             req.MaxPriority,
+			TemplateSessionLimits:   req.TemplateSessionLimits,
+			MaxBandwidthPerDay:      req.MaxBandwidthPerDay,
+			MaxSessionHoursPerMonth: req.MaxSessionHoursPerMonth,
+			QuotaMode:               req.QuotaMode,
+		})
 	}
 }
 
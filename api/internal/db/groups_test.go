@@ -307,10 +307,12 @@ func TestGetGroupQuota_Success(t *testing.T) {
 	groupID := "group-123"
 	rows := sqlmock.NewRows([]string{
 		"group_id", "max_sessions", "max_cpu", "max_memory", "max_storage",
+		"quota_mode",
 		"used_sessions", "used_cpu", "used_memory", "used_storage",
 		"created_at", "updated_at",
 	}).AddRow(
 		groupID, 10, "8000m", "32Gi", "500Gi",
+		"grant",
 		2, "2000m", "8Gi", "100Gi",
 		time.Now(), time.Now(),
 	)
@@ -325,5 +327,6 @@ func TestGetGroupQuota_Success(t *testing.T) {
 	assert.NotNil(t, quota)
 	assert.Equal(t, 10, quota.MaxSessions)
 	assert.Equal(t, 2, quota.UsedSessions)
+	assert.Equal(t, models.GroupQuotaModeGrant, quota.QuotaMode)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -7,6 +7,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -192,6 +193,51 @@ func (s *SessionDB) ListSessionsByState(ctx context.Context, state string) ([]*S
 	return sessions, nil
 }
 
+// SessionFilter narrows ListSessionsFiltered. Empty fields match everything.
+type SessionFilter struct {
+	UserID string
+	State  string
+	Tag    string
+}
+
+// ListSessionsFiltered retrieves sessions of any user matching the filter.
+// Deleted sessions are only returned when filtering on that state.
+func (s *SessionDB) ListSessionsFiltered(ctx context.Context, filter SessionFilter) ([]*Session, error) {
+	query := `
+		SELECT
+			id, user_id, COALESCE(team_id, ''), template_name, state, COALESCE(app_type, 'desktop'),
+			active_connections, COALESCE(url, ''), COALESCE(namespace, 'streamspace'),
+			COALESCE(platform, 'kubernetes'), COALESCE(pod_name, ''),
+			COALESCE(memory, ''), COALESCE(cpu, ''), COALESCE(persistent_home, false),
+			COALESCE(idle_timeout, ''), COALESCE(max_session_duration, ''),
+			created_at, updated_at, last_connection, last_disconnect, last_activity
+		FROM sessions
+		WHERE 1=1`
+
+	var args []interface{}
+	if filter.UserID != "" {
+		args = append(args, filter.UserID)
+		query += fmt.Sprintf(" AND user_id = $%d", len(args))
+	}
+	if filter.State != "" {
+		args = append(args, filter.State)
+		query += fmt.Sprintf(" AND state = $%d", len(args))
+	} else {
+		query += " AND state != 'deleted'"
+	}
+	if filter.Tag != "" {
+		tag, err := json.Marshal([]string{filter.Tag})
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode tag filter: %w", err)
+		}
+		args = append(args, string(tag))
+		query += fmt.Sprintf(" AND COALESCE(tags, '[]'::jsonb) @> $%d::jsonb", len(args))
+	}
+	query += " ORDER BY created_at DESC"
+
+	return s.querySessions(ctx, query, args...)
+}
+
 // UpdateSessionState updates the state of a session.
 func (s *SessionDB) UpdateSessionState(ctx context.Context, sessionID, state string) error {
 	query := `
@@ -249,6 +295,55 @@ func (s *SessionDB) UpdateSessionStatus(ctx context.Context, sessionID, state, u
 	return nil
 }
 
+// GetForwardedPorts returns the container port to host port mappings the
+// controller reported for a session. Only Docker sessions have them; the
+// result is empty for Kubernetes sessions.
+func (s *SessionDB) GetForwardedPorts(ctx context.Context, sessionID string) (map[string]int, error) {
+	var raw []byte
+	err := s.db.QueryRowContext(ctx, `SELECT forwarded_ports FROM sessions WHERE id = $1`, sessionID).Scan(&raw)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("session not found: %s", sessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get forwarded ports for session %s: %w", sessionID, err)
+	}
+
+	ports := make(map[string]int)
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &ports); err != nil {
+			return nil, fmt.Errorf("failed to parse forwarded ports for session %s: %w", sessionID, err)
+		}
+	}
+	return ports, nil
+}
+
+// SetSessionTags replaces the tags recorded for a session.
+func (s *SessionDB) SetSessionTags(ctx context.Context, sessionID string, tags []string) error {
+	if tags == nil {
+		tags = []string{}
+	}
+	data, err := json.Marshal(tags)
+	if err != nil {
+		return fmt.Errorf("failed to encode tags for session %s: %w", sessionID, err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `UPDATE sessions SET tags = $1 WHERE id = $2`, data, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to set tags for session %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+// UpdateSessionResources records a session's requested CPU and memory.
+func (s *SessionDB) UpdateSessionResources(ctx context.Context, sessionID, cpu, memory string) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE sessions SET cpu = $1, memory = $2, updated_at = $3 WHERE id = $4`,
+		cpu, memory, time.Now(), sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to update resources for session %s: %w", sessionID, err)
+	}
+	return nil
+}
+
 // UpdateLastActivity updates the last activity timestamp.
 func (s *SessionDB) UpdateLastActivity(ctx context.Context, sessionID string) error {
 	query := `
@@ -295,12 +390,17 @@ func (s *SessionDB) DeleteSession(ctx context.Context, sessionID string) error {
 	return nil
 }
 
-// HardDeleteSession permanently removes a session from the database.
+// HardDeleteSession permanently removes a session from the database,
+// together with its lifecycle webhook state.
 func (s *SessionDB) HardDeleteSession(ctx context.Context, sessionID string) error {
 	_, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE id = $1", sessionID)
 	if err != nil {
 		return fmt.Errorf("failed to permanently delete session %s: %w", sessionID, err)
 	}
+	_, err = s.db.ExecContext(ctx, "DELETE FROM session_lifecycle_states WHERE session_id = $1", sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to delete lifecycle state of session %s: %w", sessionID, err)
+	}
 	return nil
 }
 
@@ -385,3 +485,55 @@ func nullString(s string) sql.NullString {
 	}
 	return sql.NullString{String: s, Valid: true}
 }
+
+// LastResources is the resource request a user last launched a template with.
+type LastResources struct {
+	Memory    string    `json:"memory"`
+	CPU       string    `json:"cpu"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GetLastResources returns the resources a user last chose for a template,
+// or nil if they never chose any (or reset them).
+func (s *SessionDB) GetLastResources(ctx context.Context, userID, templateName string) (*LastResources, error) {
+	var last LastResources
+	err := s.db.QueryRowContext(ctx, `
+		SELECT memory, cpu, updated_at
+		FROM user_template_resources
+		WHERE user_id = $1 AND template_name = $2
+	`, userID, templateName).Scan(&last.Memory, &last.CPU, &last.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last resources for %s/%s: %w", userID, templateName, err)
+	}
+	return &last, nil
+}
+
+// SetLastResources remembers the resources a user chose for a template.
+func (s *SessionDB) SetLastResources(ctx context.Context, userID, templateName, memory, cpu string) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO user_template_resources (user_id, template_name, memory, cpu, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (user_id, template_name)
+		DO UPDATE SET memory = EXCLUDED.memory, cpu = EXCLUDED.cpu, updated_at = NOW()
+	`, userID, templateName, memory, cpu)
+	if err != nil {
+		return fmt.Errorf("failed to set last resources for %s/%s: %w", userID, templateName, err)
+	}
+	return nil
+}
+
+// ResetLastResources forgets a user's resources for a template so launches
+// use the template defaults again.
+func (s *SessionDB) ResetLastResources(ctx context.Context, userID, templateName string) error {
+	_, err := s.db.ExecContext(ctx, `
+		DELETE FROM user_template_resources
+		WHERE user_id = $1 AND template_name = $2
+	`, userID, templateName)
+	if err != nil {
+		return fmt.Errorf("failed to reset last resources for %s/%s: %w", userID, templateName, err)
+	}
+	return nil
+}
@@ -129,6 +129,36 @@ func TestListSessions_ByUser(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestListSessionsFiltered(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sessionDB := NewSessionDB(db)
+	ctx := context.Background()
+
+	columns := []string{"id", "user_id", "team_id", "template_name", "state", "app_type", "active_connections", "url", "namespace", "platform", "pod_name", "memory", "cpu", "persistent_home", "idle_timeout", "max_session_duration", "created_at", "updated_at", "last_connection", "last_disconnect", "last_activity"}
+
+	mock.ExpectQuery(`FROM sessions WHERE 1=1 AND user_id = \$1 AND state = \$2 AND COALESCE\(tags, '\[\]'::jsonb\) @> \$3::jsonb ORDER BY`).
+		WithArgs("user123", "running", `["abuse"]`).
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("session1", "user123", "", "ubuntu", "running", "desktop", 0, "", "streamspace", "kubernetes", "", "2Gi", "1000m", false, "", "", time.Now(), time.Now(), nil, nil, nil))
+
+	sessions, err := sessionDB.ListSessionsFiltered(ctx, SessionFilter{UserID: "user123", State: "running", Tag: "abuse"})
+	assert.NoError(t, err)
+	assert.Len(t, sessions, 1)
+
+	// Without a state filter, deleted sessions are left out
+	mock.ExpectQuery(`FROM sessions WHERE 1=1 AND state != 'deleted' ORDER BY`).
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	sessions, err = sessionDB.ListSessionsFiltered(ctx, SessionFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, sessions)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestUpdateSessionStatus_Success(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -214,3 +244,52 @@ func TestCountUserSessions_Success(t *testing.T) {
 
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestGetLastResources(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sessionDB := NewSessionDB(db)
+	ctx := context.Background()
+
+	mock.ExpectQuery("SELECT memory, cpu, updated_at").
+		WithArgs("alice", "firefox").
+		WillReturnRows(sqlmock.NewRows([]string{"memory", "cpu", "updated_at"}).AddRow("4Gi", "2000m", time.Now()))
+
+	last, err := sessionDB.GetLastResources(ctx, "alice", "firefox")
+	require.NoError(t, err)
+	require.NotNil(t, last)
+	assert.Equal(t, "4Gi", last.Memory)
+	assert.Equal(t, "2000m", last.CPU)
+
+	mock.ExpectQuery("SELECT memory, cpu, updated_at").
+		WithArgs("bob", "firefox").
+		WillReturnError(sql.ErrNoRows)
+
+	last, err = sessionDB.GetLastResources(ctx, "bob", "firefox")
+	require.NoError(t, err)
+	assert.Nil(t, last)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetAndResetLastResources(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	sessionDB := NewSessionDB(db)
+	ctx := context.Background()
+
+	mock.ExpectExec("INSERT INTO user_template_resources").
+		WithArgs("alice", "firefox", "4Gi", "2000m").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM user_template_resources").
+		WithArgs("alice", "firefox").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, sessionDB.SetLastResources(ctx, "alice", "firefox", "4Gi", "2000m"))
+	require.NoError(t, sessionDB.ResetLastResources(ctx, "alice", "firefox"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -97,6 +97,7 @@ package db
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -556,6 +557,34 @@ func (u *UserDB) SetUserQuota(ctx context.Context, userID string, req *models.Se
 			argIdx++
 		}
 
+		if req.MaxPriority != nil {
+			updates = append(updates, fmt.Sprintf("max_priority = $%d", argIdx))
+			args = append(args, *req.MaxPriority)
+			argIdx++
+		}
+
+		if req.MaxBandwidthPerDay != nil {
+			updates = append(updates, fmt.Sprintf("max_bandwidth_per_day = $%d", argIdx))
+			args = append(args, *req.MaxBandwidthPerDay)
+			argIdx++
+		}
+
+		if req.MaxSessionHoursPerMonth != nil {
+			updates = append(updates, fmt.Sprintf("max_session_hours_per_month = $%d", argIdx))
+			args = append(args, *req.MaxSessionHoursPerMonth)
+			argIdx++
+		}
+
+		if req.TemplateSessionLimits != nil {
+			limits, err := json.Marshal(req.TemplateSessionLimits)
+			if err != nil {
+				return fmt.Errorf("failed to encode template session limits: %w", err)
+			}
+			updates = append(updates, fmt.Sprintf("template_session_limits = $%d", argIdx))
+			args = append(args, string(limits))
+			argIdx++
+		}
+
 		if len(updates) == 0 {
 			return nil
 		}
@@ -573,7 +602,18 @@ func (u *UserDB) SetUserQuota(ctx context.Context, userID string, req *models.Se
 		return err
 	} else {
 		// Create new quota
-		return u.createQuota(ctx, userID, req)
+		if err := u.createQuota(ctx, userID, req); err != nil {
+			return err
+		}
+		if req.MaxPriority == nil && req.TemplateSessionLimits == nil && req.MaxBandwidthPerDay == nil && req.MaxSessionHoursPerMonth == nil {
+			return nil
+		}
+		return u.SetUserQuota(ctx, userID, &models.SetQuotaRequest{
+			MaxPriority:             req.MaxPriority,
+			TemplateSessionLimits:   req.TemplateSessionLimits,
+			MaxBandwidthPerDay:      req.MaxBandwidthPerDay,
+			MaxSessionHoursPerMonth: req.MaxSessionHoursPerMonth,
+		})
 	}
 }
 
@@ -704,6 +744,28 @@ func (u *UserDB) DeleteUserQuota(ctx context.Context, userID string) error {
 	return u.createDefaultQuota(ctx, userID)
 }
 
+// GetSessionHoursUsed returns the hours, rounded down, the user's sessions
+// ran between since and now. userID is the sessions' user_id (the username).
+//
+// Running and pending sessions count until now; other sessions count until
+// their last update, which for deleted sessions is their deletion. A
+// session resumed from hibernation counts from its creation.
+func (u *UserDB) GetSessionHoursUsed(ctx context.Context, userID string, since, now time.Time) (int64, error) {
+	var seconds float64
+	err := u.db.QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(GREATEST(EXTRACT(EPOCH FROM (
+			LEAST(CASE WHEN state IN ('running', 'pending') THEN $3 ELSE updated_at END, $3)
+			- GREATEST(created_at, $2)
+		)), 0)), 0)
+		FROM sessions
+		WHERE user_id = $1 AND created_at < $3
+	`, userID, since, now).Scan(&seconds)
+	if err != nil {
+		return 0, fmt.Errorf("failed to sum session hours for user %s: %w", userID, err)
+	}
+	return int64(seconds / 3600), nil
+}
+
 // GetUserGroups retrieves all groups a user belongs to
 func (u *UserDB) GetUserGroups(ctx context.Context, userID string) ([]string, error) {
 	query := `
@@ -732,6 +794,94 @@ func (u *UserDB) GetUserGroups(ctx context.Context, userID string) ([]string, er
 	return groupIDs, nil
 }
 
+// GetUserGroupNames retrieves the names of all groups a user belongs to
+func (u *UserDB) GetUserGroupNames(ctx context.Context, userID string) ([]string, error) {
+	query := `
+		SELECT g.name
+		FROM groups g
+		JOIN group_memberships gm ON g.id = gm.group_id
+		WHERE gm.user_id = $1
+		ORDER BY g.name ASC
+	`
+
+	rows, err := u.db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// SyncExternalGroups makes the user's memberships granted by source (an
+// external identity provider) match groupNames. Groups that don't exist in
+// StreamSpace are skipped, and memberships added in StreamSpace are left
+// alone.
+func (u *UserDB) SyncExternalGroups(ctx context.Context, userID, source string, groupNames []string) error {
+	rows, err := u.db.QueryContext(ctx, `
+		SELECT g.name, gm.group_id
+		FROM group_memberships gm
+		JOIN groups g ON g.id = gm.group_id
+		WHERE gm.user_id = $1 AND gm.source = $2
+	`, userID, source)
+	if err != nil {
+		return err
+	}
+	current := map[string]string{}
+	for rows.Next() {
+		var name, groupID string
+		if err := rows.Scan(&name, &groupID); err != nil {
+			rows.Close()
+			return err
+		}
+		current[name] = groupID
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	wanted := map[string]bool{}
+	for _, name := range groupNames {
+		wanted[name] = true
+		if _, ok := current[name]; ok {
+			continue
+		}
+		_, err := u.db.ExecContext(ctx, `
+			INSERT INTO group_memberships (id, user_id, group_id, role, created_at, source)
+			SELECT $1, $2, id, 'member', NOW(), $3
+			FROM groups WHERE name = $4
+			ON CONFLICT (user_id, group_id) DO NOTHING
+		`, uuid.New().String(), userID, source, name)
+		if err != nil {
+			return fmt.Errorf("failed to add user to group %s: %w", name, err)
+		}
+	}
+
+	for name, groupID := range current {
+		if wanted[name] {
+			continue
+		}
+		_, err := u.db.ExecContext(ctx, `
+			DELETE FROM group_memberships
+			WHERE user_id = $1 AND group_id = $2 AND source = $3
+		`, userID, groupID, source)
+		if err != nil {
+			return fmt.Errorf("failed to remove user from group %s: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
 // Helper function to join strings
 func join(strs []string, sep string) string {
 	if len(strs) == 0 {
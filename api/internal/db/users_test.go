@@ -471,6 +471,41 @@ func TestSetUserQuota_Success(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestSetUserQuota_MaxPriorityOnNewQuota(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	userDB := NewUserDB(db)
+	ctx := context.Background()
+
+	userID := "user123"
+	maxPriority := "High"
+	req := &models.SetQuotaRequest{MaxPriority: &maxPriority}
+
+	mock.ExpectQuery("SELECT (.+) FROM user_quotas WHERE user_id").
+		WithArgs(userID).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec("INSERT INTO user_quotas").
+		WithArgs(userID, 5, "4000m", "16Gi", "100Gi", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// The new quota is then updated with the priority cap
+	rows := sqlmock.NewRows([]string{"user_id", "max_sessions", "max_cpu", "max_memory", "max_storage", "used_sessions", "used_cpu", "used_memory", "used_storage", "created_at", "updated_at"}).
+		AddRow(userID, 5, "4000m", "16Gi", "100Gi", 0, "0", "0", "0", time.Now(), time.Now())
+	mock.ExpectQuery("SELECT (.+) FROM user_quotas WHERE user_id").
+		WithArgs(userID).
+		WillReturnRows(rows)
+	mock.ExpectExec("UPDATE user_quotas SET max_priority = \\$1, updated_at = \\$2 WHERE user_id = \\$3").
+		WithArgs("High", sqlmock.AnyArg(), userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = userDB.SetUserQuota(ctx, userID, req)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestAddUserToGroup_Success(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -497,3 +532,57 @@ func TestAddUserToGroup_Success(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestSyncExternalGroups(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	userDB := NewUserDB(db)
+	ctx := context.Background()
+
+	userID := "user123"
+
+	// The IdP granted developers and ops last time
+	mock.ExpectQuery("SELECT g.name, gm.group_id").
+		WithArgs(userID, "oidc").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "group_id"}).
+			AddRow("developers", "group1").
+			AddRow("ops", "group2"))
+
+	// Now it grants developers and designers: designers is added...
+	mock.ExpectExec("INSERT INTO group_memberships").
+		WithArgs(sqlmock.AnyArg(), userID, "oidc", "designers").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// ...and ops removed
+	mock.ExpectExec("DELETE FROM group_memberships").
+		WithArgs(userID, "group2", "oidc").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = userDB.SyncExternalGroups(ctx, userID, "oidc", []string{"developers", "designers"})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSessionHoursUsed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	userDB := NewUserDB(db)
+	since := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2026, 3, 20, 12, 0, 0, 0, time.UTC)
+
+	// 99.9 hours rounds down to 99
+	mock.ExpectQuery("SELECT COALESCE\\(SUM(.+) FROM sessions").
+		WithArgs("alice", since, now).
+		WillReturnRows(sqlmock.NewRows([]string{"seconds"}).AddRow(99.9 * 3600))
+
+	hours, err := userDB.GetSessionHoursUsed(context.Background(), "alice", since, now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(99), hours)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,153 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Batched session commands.
+//
+// Mass operations (bulk hibernate, wake or terminate) would otherwise send
+// one event per session. Controllers that advertise the session.batch
+// action in their heartbeat receive them as a single SessionBatchEvent and
+// reply with the result of every command. Each command keeps its own event
+// ID, so its outcome is tracked on its own and a command resent
+// individually is replayed by the controller rather than run again.
+//
+// If any live controller of the platform doesn't handle session.batch (or
+// none has reported its capabilities), or the batch request fails, the
+// commands are published one by one as before.
+
+// ActionSessionBatch is the capability action of controllers that accept
+// SessionBatchEvents.
+const ActionSessionBatch = "session.batch"
+
+// MaxBatchCommands is the most commands sent in one batch; larger sets are
+// split.
+const MaxBatchCommands = 100
+
+// DefaultBatchTimeout is how long to wait for a controller to run a batch.
+const DefaultBatchTimeout = 60 * time.Second
+
+// SendSessionCommands sends session commands to platform's controllers and
+// returns their results in the order of commands. With batch set they go
+// out as SessionBatchEvents; otherwise, or if a batch fails, each is
+// published as its own event, whose result is only whether it was sent.
+func (p *Publisher) SendSessionCommands(ctx context.Context, platform string, commands []SessionCommand, batch bool, timeout time.Duration) []CommandResult {
+	if timeout <= 0 {
+		timeout = DefaultBatchTimeout
+	}
+	for i := range commands {
+		if commands[i].EventID == "" {
+			commands[i].EventID = uuid.New().String()
+		}
+	}
+
+	results := make([]CommandResult, 0, len(commands))
+	for start := 0; start < len(commands); start += MaxBatchCommands {
+		chunk := commands[start:min(start+MaxBatchCommands, len(commands))]
+		if batch {
+			batchResults, err := p.requestSessionBatch(platform, chunk, timeout)
+			if err == nil {
+				results = append(results, batchResults...)
+				continue
+			}
+			log.Printf("Session batch of %d commands failed, sending individually: %v", len(chunk), err)
+		}
+		for _, command := range chunk {
+			results = append(results, commandResult(command, p.publishSessionCommand(ctx, platform, command)))
+		}
+	}
+	return results
+}
+
+// requestSessionBatch sends commands as one batch and waits for their
+// results.
+func (p *Publisher) requestSessionBatch(platform string, commands []SessionCommand, timeout time.Duration) ([]CommandResult, error) {
+	event := &SessionBatchEvent{
+		EventID:   uuid.New().String(),
+		Timestamp: time.Now(),
+		Platform:  platform,
+		Commands:  commands,
+	}
+	msg, err := p.Request(SubjectWithPlatform(SubjectSessionBatch, platform), event, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var ack EventAck
+	if err := json.Unmarshal(msg.Data, &ack); err != nil {
+		return nil, fmt.Errorf("invalid acknowledgement: %w", err)
+	}
+	if !ack.Success && len(ack.Results) == 0 {
+		return nil, fmt.Errorf("controller %s rejected batch: %s", ack.ControllerID, ack.Error)
+	}
+	return batchResults(commands, &ack), nil
+}
+
+// batchResults matches a batch ack's results to its commands. A command the
+// controller didn't report on failed.
+func batchResults(commands []SessionCommand, ack *EventAck) []CommandResult {
+	byID := make(map[string]CommandResult, len(ack.Results))
+	for _, result := range ack.Results {
+		byID[result.EventID] = result
+	}
+
+	results := make([]CommandResult, 0, len(commands))
+	for _, command := range commands {
+		result, ok := byID[command.EventID]
+		if !ok {
+			result = commandResult(command, fmt.Errorf("no result from controller %s", ack.ControllerID))
+		}
+		result.SessionID = command.SessionID
+		results = append(results, result)
+	}
+	return results
+}
+
+// publishSessionCommand publishes a command as its individual event.
+func (p *Publisher) publishSessionCommand(ctx context.Context, platform string, command SessionCommand) error {
+	switch command.Action {
+	case "session.hibernate":
+		return p.PublishSessionHibernate(ctx, &SessionHibernateEvent{
+			EventID:   command.EventID,
+			SessionID: command.SessionID,
+			UserID:    command.UserID,
+			Platform:  platform,
+		})
+	case "session.wake":
+		return p.PublishSessionWake(ctx, &SessionWakeEvent{
+			EventID:   command.EventID,
+			SessionID: command.SessionID,
+			UserID:    command.UserID,
+			Platform:  platform,
+		})
+	case "session.delete":
+		return p.PublishSessionDelete(ctx, &SessionDeleteEvent{
+			EventID:   command.EventID,
+			SessionID: command.SessionID,
+			UserID:    command.UserID,
+			Platform:  platform,
+			Force:     command.Force,
+		})
+	default:
+		return fmt.Errorf("unsupported session command %q", command.Action)
+	}
+}
+
+func commandResult(command SessionCommand, err error) CommandResult {
+	result := CommandResult{
+		EventID:   command.EventID,
+		SessionID: command.SessionID,
+		Success:   err == nil,
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+	return result
+}
@@ -0,0 +1,56 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendSessionCommands_FallsBackToIndividualEvents(t *testing.T) {
+	p := &Publisher{enabled: false}
+
+	commands := make([]SessionCommand, MaxBatchCommands+1)
+	for i := range commands {
+		commands[i] = SessionCommand{Action: "session.hibernate", SessionID: fmt.Sprintf("s%d", i), UserID: "alice"}
+	}
+	commands[0].Action = "session.snapshot"
+
+	// The batch request can't be sent, so every command goes out on its own
+	results := p.SendSessionCommands(context.Background(), PlatformKubernetes, commands, true, time.Second)
+	require.Len(t, results, len(commands))
+
+	assert.False(t, results[0].Success)
+	assert.Contains(t, results[0].Error, "unsupported")
+	for i, result := range results[1:] {
+		assert.True(t, result.Success)
+		assert.Equal(t, commands[i+1].SessionID, result.SessionID)
+		assert.NotEmpty(t, result.EventID, "commands get event IDs to track them by")
+	}
+}
+
+func TestBatchResults(t *testing.T) {
+	commands := []SessionCommand{
+		{EventID: "e1", SessionID: "s1"},
+		{EventID: "e2", SessionID: "s2"},
+		{EventID: "e3", SessionID: "s3"},
+	}
+	ack := &EventAck{
+		ControllerID: "k8s-1",
+		Success:      false,
+		Results: []CommandResult{
+			{EventID: "e2", Success: false, Error: "session not found"},
+			{EventID: "e1", Success: true},
+		},
+	}
+
+	results := batchResults(commands, ack)
+	require.Len(t, results, 3)
+	assert.Equal(t, CommandResult{EventID: "e1", SessionID: "s1", Success: true}, results[0])
+	assert.Equal(t, CommandResult{EventID: "e2", SessionID: "s2", Error: "session not found"}, results[1])
+	assert.False(t, results[2].Success)
+	assert.Contains(t, results[2].Error, "no result from controller k8s-1")
+}
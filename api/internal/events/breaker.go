@@ -0,0 +1,158 @@
+package events
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// ErrControllersUnavailable is returned by request/reply calls while the
+// circuit breaker is open: recent requests timed out, so controllers are
+// assumed to be down and callers fail fast instead of waiting.
+var ErrControllersUnavailable = errors.New("controllers unavailable")
+
+// Circuit breaker defaults.
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = 30 * time.Second
+)
+
+// BreakerState is the state of a CircuitBreaker.
+type BreakerState string
+
+const (
+	// BreakerClosed lets requests through (normal operation).
+	BreakerClosed BreakerState = "closed"
+
+	// BreakerOpen fails requests immediately until the cooldown expires.
+	BreakerOpen BreakerState = "open"
+
+	// BreakerHalfOpen lets a single trial request through after the
+	// cooldown; its outcome closes or re-opens the breaker.
+	BreakerHalfOpen BreakerState = "half-open"
+)
+
+// BreakerStatus reports the state of a CircuitBreaker (for health checks).
+type BreakerStatus struct {
+	State               BreakerState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	OpenedAt            *time.Time   `json:"opened_at,omitempty"`
+	RetryAt             *time.Time   `json:"retry_at,omitempty"`
+}
+
+// CircuitBreaker stops request/reply calls from piling up when nobody
+// answers.
+//
+// After Threshold consecutive timeouts the breaker opens and Allow fails
+// fast with ErrControllersUnavailable for Cooldown. Then one trial request
+// is let through: success closes the breaker, another timeout re-opens it.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	failures int
+	openedAt time.Time
+	trial    bool // a half-open trial request is in flight
+}
+
+// NewCircuitBreaker creates a breaker that opens after threshold consecutive
+// failures and stays open for cooldown.
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// newCircuitBreakerFromEnv creates the publisher's breaker.
+//
+// Environment:
+//   - NATS_REQUEST_BREAKER_THRESHOLD: consecutive timeouts that open the
+//     breaker (default 5)
+//   - NATS_REQUEST_BREAKER_COOLDOWN: how long it stays open (default 30s)
+func newCircuitBreakerFromEnv() *CircuitBreaker {
+	threshold := defaultBreakerThreshold
+	if v, err := strconv.Atoi(os.Getenv("NATS_REQUEST_BREAKER_THRESHOLD")); err == nil && v > 0 {
+		threshold = v
+	}
+	cooldown := defaultBreakerCooldown
+	if d, err := time.ParseDuration(os.Getenv("NATS_REQUEST_BREAKER_COOLDOWN")); err == nil && d > 0 {
+		cooldown = d
+	}
+	return NewCircuitBreaker(threshold, cooldown)
+}
+
+// state returns the current state. Caller must hold mu.
+func (b *CircuitBreaker) state() BreakerState {
+	if b.failures < b.threshold {
+		return BreakerClosed
+	}
+	if b.now().Sub(b.openedAt) < b.cooldown {
+		return BreakerOpen
+	}
+	return BreakerHalfOpen
+}
+
+// Allow reports whether a request may be attempted.
+//
+// It returns ErrControllersUnavailable while the breaker is open, and while
+// a half-open trial request is already in flight.
+func (b *CircuitBreaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state() {
+	case BreakerOpen:
+		return ErrControllersUnavailable
+	case BreakerHalfOpen:
+		if b.trial {
+			return ErrControllersUnavailable
+		}
+		b.trial = true
+	}
+	return nil
+}
+
+// Record records the outcome of an allowed request.
+//
+// Only "nobody answered" errors (timeouts, no responders) count as failures;
+// any other result means a controller is alive.
+func (b *CircuitBreaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if !isUnansweredError(err) {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.failures >= b.threshold {
+		// Opening, or re-opening after a failed trial
+		b.openedAt = b.now()
+	}
+}
+
+// Status returns the breaker state for health reporting.
+func (b *CircuitBreaker) Status() BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := BreakerStatus{State: b.state(), ConsecutiveFailures: b.failures}
+	if status.State != BreakerClosed {
+		openedAt := b.openedAt
+		retryAt := b.openedAt.Add(b.cooldown)
+		status.OpenedAt = &openedAt
+		status.RetryAt = &retryAt
+	}
+	return status
+}
+
+// isUnansweredError reports whether a request failed because nobody replied.
+func isUnansweredError(err error) bool {
+	return errors.Is(err, nats.ErrTimeout) || errors.Is(err, nats.ErrNoResponders)
+}
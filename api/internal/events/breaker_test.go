@@ -0,0 +1,81 @@
+package events
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testBreaker(threshold int, cooldown time.Duration) (*CircuitBreaker, *time.Time) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	b := NewCircuitBreaker(threshold, cooldown)
+	b.now = func() time.Time { return now }
+	return b, &now
+}
+
+// Test that consecutive timeouts open the breaker and it fails fast
+func TestCircuitBreaker_OpensAfterConsecutiveTimeouts(t *testing.T) {
+	b, _ := testBreaker(3, 30*time.Second)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, b.Allow())
+		b.Record(nats.ErrTimeout)
+	}
+
+	assert.ErrorIs(t, b.Allow(), ErrControllersUnavailable)
+	status := b.Status()
+	assert.Equal(t, BreakerOpen, status.State)
+	assert.Equal(t, 3, status.ConsecutiveFailures)
+	require.NotNil(t, status.RetryAt)
+}
+
+// Test that a success resets the failure count
+func TestCircuitBreaker_SuccessResets(t *testing.T) {
+	b, _ := testBreaker(2, 30*time.Second)
+
+	b.Record(nats.ErrTimeout)
+	b.Record(nil)
+	b.Record(nats.ErrTimeout)
+
+	assert.NoError(t, b.Allow())
+	assert.Equal(t, BreakerClosed, b.Status().State)
+}
+
+// Test that non-timeout errors don't count as controllers being down
+func TestCircuitBreaker_IgnoresOtherErrors(t *testing.T) {
+	b, _ := testBreaker(1, 30*time.Second)
+
+	b.Record(errors.New("invalid subject"))
+
+	assert.NoError(t, b.Allow())
+}
+
+// Test the half-open trial after the cooldown
+func TestCircuitBreaker_HalfOpenTrial(t *testing.T) {
+	b, now := testBreaker(1, 30*time.Second)
+	b.Record(nats.ErrNoResponders)
+	require.ErrorIs(t, b.Allow(), ErrControllersUnavailable)
+
+	*now = now.Add(31 * time.Second)
+	assert.Equal(t, BreakerHalfOpen, b.Status().State)
+
+	// Only one trial request at a time
+	require.NoError(t, b.Allow())
+	assert.ErrorIs(t, b.Allow(), ErrControllersUnavailable)
+
+	// Failed trial re-opens for another cooldown
+	b.Record(nats.ErrTimeout)
+	assert.Equal(t, BreakerOpen, b.Status().State)
+	assert.ErrorIs(t, b.Allow(), ErrControllersUnavailable)
+
+	// Successful trial closes it
+	*now = now.Add(31 * time.Second)
+	require.NoError(t, b.Allow())
+	b.Record(nil)
+	assert.Equal(t, BreakerClosed, b.Status().State)
+	assert.NoError(t, b.Allow())
+}
@@ -0,0 +1,245 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// DefaultControllerStaleAfter is how long a controller counts as live after
+// its last heartbeat.
+const DefaultControllerStaleAfter = 90 * time.Second
+
+// ControllerCapabilities is what a controller reports it can do in its
+// heartbeat. Empty fields mean "not reported" and don't restrict dispatch.
+type ControllerCapabilities struct {
+	// Backend is the platform implementation (kubernetes, docker, ...)
+	Backend string `json:"backend,omitempty"`
+	// Actions are the event actions handled, e.g. "session.create"
+	Actions []string `json:"actions,omitempty"`
+	// GPU is true if the controller can schedule GPU sessions
+	GPU bool `json:"gpu"`
+	// MaxResources is the largest session the controller can place
+	MaxResources ResourceSpec `json:"max_resources"`
+}
+
+// UnmarshalJSON also accepts the original heartbeat format, a plain list of
+// capability names, which are read as actions.
+func (c *ControllerCapabilities) UnmarshalJSON(data []byte) error {
+	var names []string
+	if err := json.Unmarshal(data, &names); err == nil {
+		*c = ControllerCapabilities{Actions: names}
+		return nil
+	}
+	type plain ControllerCapabilities
+	return json.Unmarshal(data, (*plain)(c))
+}
+
+// SessionRequirements describe what a session needs from the controller
+// that runs it.
+type SessionRequirements struct {
+	Action    string
+	GPU       bool
+	Resources ResourceSpec
+}
+
+// Check returns why the capabilities don't meet the requirements, or nil.
+func (c ControllerCapabilities) Check(req SessionRequirements) error {
+	if req.Action != "" && len(c.Actions) > 0 && !containsString(c.Actions, req.Action) {
+		return fmt.Errorf("does not handle %s", req.Action)
+	}
+	if req.GPU && !c.GPU {
+		return fmt.Errorf("has no GPU")
+	}
+	if exceedsQuantity(req.Resources.Memory, c.MaxResources.Memory) {
+		return fmt.Errorf("memory %s exceeds its maximum %s", req.Resources.Memory, c.MaxResources.Memory)
+	}
+	if exceedsQuantity(req.Resources.CPU, c.MaxResources.CPU) {
+		return fmt.Errorf("CPU %s exceeds its maximum %s", req.Resources.CPU, c.MaxResources.CPU)
+	}
+	return nil
+}
+
+// exceedsQuantity reports whether requested is above limit. Unset or
+// unparsable values never exceed.
+func exceedsQuantity(requested, limit string) bool {
+	if requested == "" || limit == "" {
+		return false
+	}
+	req, err := resource.ParseQuantity(requested)
+	if err != nil {
+		return false
+	}
+	max, err := resource.ParseQuantity(limit)
+	if err != nil {
+		return false
+	}
+	return req.Cmp(max) > 0
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// ControllerInfo is a controller known from its heartbeats.
+type ControllerInfo struct {
+	ControllerID  string                 `json:"controller_id"`
+	Platform      string                 `json:"platform"`
+	Status        string                 `json:"status"`
+	Version       string                 `json:"version,omitempty"`
+	Capabilities  ControllerCapabilities `json:"capabilities"`
+	LastHeartbeat time.Time              `json:"last_heartbeat"`
+	Live          bool                   `json:"live"`
+}
+
+// ErrNoCapableController is returned by Select when live controllers exist
+// for the platform but none can run the session.
+type ErrNoCapableController struct {
+	Platform string
+	Reasons  []string
+}
+
+func (e *ErrNoCapableController) Error() string {
+	return fmt.Sprintf("no %s controller can run this session: %s", e.Platform, strings.Join(e.Reasons, "; "))
+}
+
+// ControllerRegistry tracks controllers and their capabilities from
+// heartbeats, and picks the controller a session is dispatched to.
+//
+// Controllers that never sent a heartbeat are invisible here; as long as no
+// controller of a platform is live, sessions go to the platform's shared
+// queue as before.
+type ControllerRegistry struct {
+	mu          sync.RWMutex
+	controllers map[string]*ControllerInfo
+	staleAfter  time.Duration
+	next        int
+	now         func() time.Time
+}
+
+// NewControllerRegistry creates a registry that treats controllers as gone
+// staleAfter after their last heartbeat.
+func NewControllerRegistry(staleAfter time.Duration) *ControllerRegistry {
+	if staleAfter <= 0 {
+		staleAfter = DefaultControllerStaleAfter
+	}
+	return &ControllerRegistry{
+		controllers: make(map[string]*ControllerInfo),
+		staleAfter:  staleAfter,
+		now:         time.Now,
+	}
+}
+
+// Record stores a controller heartbeat.
+func (r *ControllerRegistry) Record(event ControllerHeartbeatEvent) {
+	if r == nil || event.ControllerID == "" {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.controllers[event.ControllerID] = &ControllerInfo{
+		ControllerID:  event.ControllerID,
+		Platform:      event.Platform,
+		Status:        event.Status,
+		Version:       event.Version,
+		Capabilities:  event.Capabilities,
+		LastHeartbeat: r.now(),
+	}
+}
+
+// List returns all known controllers sorted by ID.
+func (r *ControllerRegistry) List() []ControllerInfo {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list := make([]ControllerInfo, 0, len(r.controllers))
+	for _, info := range r.controllers {
+		entry := *info
+		entry.Live = r.liveLocked(info)
+		list = append(list, entry)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ControllerID < list[j].ControllerID })
+	return list
+}
+
+// Select picks the controller to dispatch a session to.
+//
+// It returns "" when the session can go to the platform's shared queue:
+// either no controller of the platform is live (capabilities unknown) or
+// every live one can run it. If only some can, one of them is returned
+// (round robin). If none can, an *ErrNoCapableController explains why.
+func (r *ControllerRegistry) Select(platform string, req SessionRequirements) (string, error) {
+	if r == nil {
+		return "", nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var live, capable []string
+	var reasons []string
+	for id, info := range r.controllers {
+		if info.Platform != platform || !r.liveLocked(info) {
+			continue
+		}
+		live = append(live, id)
+		if err := info.Capabilities.Check(req); err != nil {
+			reasons = append(reasons, fmt.Sprintf("%s %v", id, err))
+			continue
+		}
+		capable = append(capable, id)
+	}
+
+	if len(live) == 0 || len(capable) == len(live) {
+		return "", nil
+	}
+	if len(capable) == 0 {
+		sort.Strings(reasons)
+		return "", &ErrNoCapableController{Platform: platform, Reasons: reasons}
+	}
+
+	sort.Strings(capable)
+	id := capable[r.next%len(capable)]
+	r.next++
+	return id, nil
+}
+
+// SupportsAction reports whether every live controller of platform reports
+// handling action. Unlike Select it is false when nothing is known, so it
+// suits optional actions that older controllers would ignore.
+func (r *ControllerRegistry) SupportsAction(platform, action string) bool {
+	if r == nil {
+		return false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	live := 0
+	for _, info := range r.controllers {
+		if info.Platform != platform || !r.liveLocked(info) {
+			continue
+		}
+		if !containsString(info.Capabilities.Actions, action) {
+			return false
+		}
+		live++
+	}
+	return live > 0
+}
+
+// liveLocked reports whether a controller sent a healthy heartbeat recently.
+func (r *ControllerRegistry) liveLocked(info *ControllerInfo) bool {
+	return info.Status != "unhealthy" && r.now().Sub(info.LastHeartbeat) <= r.staleAfter
+}
@@ -0,0 +1,104 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestControllerCapabilities_Check(t *testing.T) {
+	caps := ControllerCapabilities{
+		Actions:      []string{"session.create"},
+		GPU:          false,
+		MaxResources: ResourceSpec{Memory: "16Gi", CPU: "8"},
+	}
+
+	assert.NoError(t, caps.Check(SessionRequirements{Action: "session.create", Resources: ResourceSpec{Memory: "8Gi", CPU: "2000m"}}))
+	assert.ErrorContains(t, caps.Check(SessionRequirements{Action: "session.snapshot"}), "does not handle")
+	assert.ErrorContains(t, caps.Check(SessionRequirements{GPU: true}), "no GPU")
+	assert.ErrorContains(t, caps.Check(SessionRequirements{Resources: ResourceSpec{Memory: "32Gi"}}), "memory")
+	assert.ErrorContains(t, caps.Check(SessionRequirements{Resources: ResourceSpec{CPU: "12"}}), "CPU")
+
+	// Unreported capabilities don't restrict anything but GPU
+	assert.NoError(t, ControllerCapabilities{}.Check(SessionRequirements{Action: "session.create", Resources: ResourceSpec{Memory: "1Ti"}}))
+}
+
+func TestControllerRegistry_Select(t *testing.T) {
+	now := time.Now()
+	registry := NewControllerRegistry(time.Minute)
+	registry.now = func() time.Time { return now }
+
+	gpuReq := SessionRequirements{Action: "session.create", GPU: true}
+
+	// Nothing known: dispatch to the shared platform queue
+	id, err := registry.Select(PlatformKubernetes, gpuReq)
+	require.NoError(t, err)
+	assert.Empty(t, id)
+
+	registry.Record(ControllerHeartbeatEvent{ControllerID: "k8s-cpu", Platform: PlatformKubernetes, Status: "healthy"})
+
+	// The only live controller has no GPU
+	_, err = registry.Select(PlatformKubernetes, gpuReq)
+	var noCapable *ErrNoCapableController
+	require.ErrorAs(t, err, &noCapable)
+	assert.Contains(t, err.Error(), "k8s-cpu has no GPU")
+
+	registry.Record(ControllerHeartbeatEvent{
+		ControllerID: "k8s-gpu",
+		Platform:     PlatformKubernetes,
+		Status:       "healthy",
+		Capabilities: ControllerCapabilities{GPU: true},
+	})
+
+	// GPU sessions go to the GPU controller only
+	id, err = registry.Select(PlatformKubernetes, gpuReq)
+	require.NoError(t, err)
+	assert.Equal(t, "k8s-gpu", id)
+
+	// Sessions every controller can run use the shared queue
+	id, err = registry.Select(PlatformKubernetes, SessionRequirements{Action: "session.create"})
+	require.NoError(t, err)
+	assert.Empty(t, id)
+
+	// A stale GPU controller no longer counts
+	now = now.Add(2 * time.Minute)
+	registry.Record(ControllerHeartbeatEvent{ControllerID: "k8s-cpu", Platform: PlatformKubernetes, Status: "healthy"})
+	_, err = registry.Select(PlatformKubernetes, gpuReq)
+	assert.Error(t, err)
+
+	list := registry.List()
+	require.Len(t, list, 2)
+	assert.Equal(t, "k8s-cpu", list[0].ControllerID)
+	assert.True(t, list[0].Live)
+	assert.False(t, list[1].Live)
+}
+
+func TestControllerRegistry_SupportsAction(t *testing.T) {
+	now := time.Now()
+	registry := NewControllerRegistry(time.Minute)
+	registry.now = func() time.Time { return now }
+
+	// Unknown capabilities don't opt in
+	assert.False(t, registry.SupportsAction(PlatformKubernetes, ActionSessionBatch))
+
+	batching := ControllerCapabilities{Actions: []string{"session.hibernate", ActionSessionBatch}}
+	registry.Record(ControllerHeartbeatEvent{ControllerID: "k8s-new", Platform: PlatformKubernetes, Status: "healthy", Capabilities: batching})
+	assert.True(t, registry.SupportsAction(PlatformKubernetes, ActionSessionBatch))
+	assert.False(t, registry.SupportsAction(PlatformDocker, ActionSessionBatch))
+
+	// One older controller in the queue group is enough to fall back
+	registry.Record(ControllerHeartbeatEvent{
+		ControllerID: "k8s-old",
+		Platform:     PlatformKubernetes,
+		Status:       "healthy",
+		Capabilities: ControllerCapabilities{Actions: []string{"session.hibernate"}},
+	})
+	assert.False(t, registry.SupportsAction(PlatformKubernetes, ActionSessionBatch))
+
+	// Until it goes stale
+	now = now.Add(2 * time.Minute)
+	registry.Record(ControllerHeartbeatEvent{ControllerID: "k8s-new", Platform: PlatformKubernetes, Status: "healthy", Capabilities: batching})
+	assert.True(t, registry.SupportsAction(PlatformKubernetes, ActionSessionBatch))
+}
@@ -0,0 +1,92 @@
+package events
+
+import (
+	"github.com/nats-io/nats.go"
+)
+
+// ConnectionState describes the live state of a NATS connection.
+type ConnectionState string
+
+const (
+	// StateDisabled means events were never enabled (NATS_URL unset). This
+	// is a configuration state, not an outage.
+	StateDisabled ConnectionState = "disabled"
+
+	// StateConnected means the connection is up and events are flowing.
+	StateConnected ConnectionState = "connected"
+
+	// StateReconnecting means the connection dropped and the client is
+	// retrying. Events published now are buffered or lost.
+	StateReconnecting ConnectionState = "reconnecting"
+
+	// StateDisconnected means the connection is down or closed (e.g. after
+	// MaxReconnects was exhausted, or NATS_URL is set but the initial
+	// connection failed). Event delivery is degraded.
+	StateDisconnected ConnectionState = "disconnected"
+)
+
+// HealthStatus reports the state of a Publisher or Subscriber connection.
+type HealthStatus struct {
+	State   ConnectionState `json:"state"`
+	URL     string          `json:"url,omitempty"`
+	Message string          `json:"message,omitempty"`
+
+	// Breaker is the publisher's request circuit breaker. An open breaker
+	// means controllers aren't answering; it doesn't affect Healthy since
+	// the API itself keeps serving (session creates are queued).
+	Breaker *BreakerStatus `json:"breaker,omitempty"`
+
+	// Queue is the subscriber's status event backlog. A deep queue means
+	// database writes are falling behind controller events.
+	Queue *QueueStatus `json:"queue,omitempty"`
+}
+
+// Healthy returns true unless the connection is enabled but not connected.
+// A disabled connection is healthy: it's a deliberate configuration.
+func (h HealthStatus) Healthy() bool {
+	return h.State == StateDisabled || h.State == StateConnected
+}
+
+// connectionHealth maps a nats.Conn status to a HealthStatus. failedURL is
+// the configured URL if the initial connection failed.
+func connectionHealth(conn *nats.Conn, enabled bool, disabledReason, failedURL string) HealthStatus {
+	if failedURL != "" {
+		return HealthStatus{State: StateDisconnected, URL: failedURL, Message: disabledReason}
+	}
+	if !enabled || conn == nil {
+		return HealthStatus{State: StateDisabled, Message: disabledReason}
+	}
+
+	switch conn.Status() {
+	case nats.CONNECTED:
+		return HealthStatus{State: StateConnected, URL: conn.ConnectedUrl()}
+	case nats.RECONNECTING, nats.CONNECTING:
+		return HealthStatus{State: StateReconnecting, Message: "connection lost, reconnecting"}
+	default:
+		msg := "connection closed"
+		if err := conn.LastError(); err != nil {
+			msg = err.Error()
+		}
+		return HealthStatus{State: StateDisconnected, Message: msg}
+	}
+}
+
+// Health returns the live state of the publisher's NATS connection.
+func (p *Publisher) Health() HealthStatus {
+	health := connectionHealth(p.conn, p.enabled, p.disabledReason, p.failedURL)
+	if p.enabled && p.breaker != nil {
+		status := p.breaker.Status()
+		health.Breaker = &status
+	}
+	return health
+}
+
+// Health returns the live state of the subscriber's NATS connection.
+func (s *Subscriber) Health() HealthStatus {
+	health := connectionHealth(s.conn, s.enabled, s.disabledReason, s.failedURL)
+	if s.enabled && s.workers != nil {
+		status := s.workers.status()
+		health.Queue = &status
+	}
+	return health
+}
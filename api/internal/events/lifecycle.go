@@ -0,0 +1,168 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+)
+
+// Session lifecycle webhook events.
+const (
+	LifecycleSessionReady       = "session.ready"
+	LifecycleSessionHibernating = "session.hibernating"
+	LifecycleSessionTerminated  = "session.terminated"
+	LifecycleSessionFailed      = "session.failed"
+)
+
+// lifecycleStateRetention is how long the lifecycle state of a terminated
+// or removed session is kept. Controllers keep reporting Terminated while
+// they tear a session down, and the row is what stops those repeats from
+// firing session.terminated again.
+const lifecycleStateRetention = time.Hour
+
+// SessionLifecycleDispatcher delivers session lifecycle events to webhooks.
+// It is implemented by the integrations handler.
+type SessionLifecycleDispatcher interface {
+	DispatchSessionLifecycle(ctx context.Context, event string, data map[string]interface{})
+}
+
+// SetLifecycleDispatcher sets the dispatcher for session lifecycle webhooks.
+func (s *Subscriber) SetLifecycleDispatcher(dispatcher SessionLifecycleDispatcher) {
+	s.notifierMu.Lock()
+	defer s.notifierMu.Unlock()
+	s.lifecycleDispatcher = dispatcher
+}
+
+// lifecycleState maps a status report to the session's lifecycle state.
+//
+// Ready, hibernating (draining connections before the scale-down),
+// terminated and failed are the states that fire webhooks; any other
+// phase (pending, hibernated, running but not ready yet) is recorded too so
+// that a later return to ready counts as a new transition. Controllers that
+// don't report readiness are ready once Running.
+func lifecycleState(event *SessionStatusEvent) string {
+	switch event.Phase {
+	case "Running":
+		if event.Ready == nil || *event.Ready {
+			return LifecycleSessionReady
+		}
+		return "starting"
+	case "Hibernating":
+		return LifecycleSessionHibernating
+	case "Terminated":
+		return LifecycleSessionTerminated
+	case "Failed":
+		return LifecycleSessionFailed
+	}
+	return strings.ToLower(event.Phase)
+}
+
+// isLifecycleEvent reports whether a lifecycle state fires a webhook.
+func isLifecycleEvent(state string) bool {
+	switch state {
+	case LifecycleSessionReady, LifecycleSessionHibernating, LifecycleSessionTerminated, LifecycleSessionFailed:
+		return true
+	}
+	return false
+}
+
+// recordLifecycleTransition stores a session's lifecycle state and reports
+// whether it changed.
+//
+// Controllers repeat the same status on every reconcile, and every API
+// replica receives each status event. The conditional upsert is atomic, so
+// exactly one report - on exactly one replica - sees each transition.
+func recordLifecycleTransition(ctx context.Context, db *sql.DB, sessionID, state string) (bool, error) {
+	result, err := db.ExecContext(ctx, `
+		INSERT INTO session_lifecycle_states (session_id, state, updated_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (session_id) DO UPDATE
+		SET state = EXCLUDED.state, updated_at = EXCLUDED.updated_at
+		WHERE session_lifecycle_states.state <> EXCLUDED.state
+	`, sessionID, state, time.Now())
+	if err != nil {
+		return false, err
+	}
+	rows, err := result.RowsAffected()
+	return rows > 0, err
+}
+
+// ForgetSessionLifecycle deletes the lifecycle state of a session that has
+// been removed.
+func ForgetSessionLifecycle(ctx context.Context, db *sql.DB, sessionID string) error {
+	_, err := db.ExecContext(ctx, `DELETE FROM session_lifecycle_states WHERE session_id = $1`, sessionID)
+	return err
+}
+
+// pruneLifecycleStates deletes the lifecycle states of sessions that
+// terminated, or were removed from the sessions table, more than
+// lifecycleStateRetention ago. Sessions that are gone never report again,
+// so without this the table grows with every session ever launched.
+func pruneLifecycleStates(ctx context.Context, db *sql.DB, now time.Time) (int64, error) {
+	result, err := db.ExecContext(ctx, `
+		DELETE FROM session_lifecycle_states
+		WHERE updated_at < $1
+		  AND (state = $2 OR NOT EXISTS (
+			SELECT 1 FROM sessions
+			WHERE sessions.id = session_lifecycle_states.session_id
+			  AND sessions.state NOT IN ('terminated', 'deleted')
+		  ))
+	`, now.Add(-lifecycleStateRetention), LifecycleSessionTerminated)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// dispatchLifecycleWebhook fires session.ready/hibernating/terminated/failed
+// once per transition.
+func (s *Subscriber) dispatchLifecycleWebhook(ctx context.Context, event *SessionStatusEvent) {
+	s.notifierMu.RLock()
+	dispatcher := s.lifecycleDispatcher
+	s.notifierMu.RUnlock()
+	if dispatcher == nil || event.Phase == "" {
+		return
+	}
+
+	state := lifecycleState(event)
+	changed, err := recordLifecycleTransition(ctx, s.db, event.SessionID, state)
+	if err != nil {
+		log.Printf("Failed to record lifecycle state of session %s: %v", event.SessionID, err)
+		return
+	}
+	if !changed || !isLifecycleEvent(state) {
+		return
+	}
+
+	// Each termination is a good moment to drop the states of sessions
+	// that ended earlier; only the replica that saw the transition does it
+	if state == LifecycleSessionTerminated {
+		if pruned, err := pruneLifecycleStates(ctx, s.db, time.Now()); err != nil {
+			log.Printf("Failed to prune session lifecycle states: %v", err)
+		} else if pruned > 0 {
+			log.Printf("Pruned %d lifecycle states of ended sessions", pruned)
+		}
+	}
+
+	data := map[string]interface{}{
+		"session_id":    event.SessionID,
+		"phase":         event.Phase,
+		"controller_id": event.ControllerID,
+		"transition_id": event.EventID,
+	}
+	var userID, template sql.NullString
+	if err := s.db.QueryRowContext(ctx, `SELECT user_id, template_name FROM sessions WHERE id = $1`, event.SessionID).Scan(&userID, &template); err == nil {
+		data["user_id"] = userID.String
+		data["template"] = template.String
+	}
+	if state == LifecycleSessionReady {
+		data["url"] = event.URL
+	} else {
+		data["reason"] = event.Message
+	}
+
+	log.Printf("Session %s transitioned to %s, dispatching webhooks", event.SessionID, state)
+	dispatcher.DispatchSessionLifecycle(ctx, state, data)
+}
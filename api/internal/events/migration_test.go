@@ -0,0 +1,91 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/stretchr/testify/assert"
+)
+
+const testNewSessionStatus = "streamspace.v2.session.status"
+
+func TestSubjectMigrationsFromEnv(t *testing.T) {
+	t.Setenv("NATS_SUBJECT_MIGRATIONS", " streamspace.session.status = streamspace.v2.session.status ,bad,=x,same=same")
+	t.Setenv("NATS_SUBJECT_MIGRATION_PUBLISH", "OLD")
+
+	m := subjectMigrationsFromEnv()
+	assert.Equal(t, []SubjectMigration{{Old: SubjectSessionStatus, New: testNewSessionStatus}}, m.Pairs())
+	assert.Equal(t, MigrationPublishOld, m.publish)
+
+	t.Setenv("NATS_SUBJECT_MIGRATIONS", "")
+	t.Setenv("NATS_SUBJECT_MIGRATION_PUBLISH", "sideways")
+	m = subjectMigrationsFromEnv()
+	assert.Empty(t, m.Pairs())
+	assert.Equal(t, MigrationPublishBoth, m.publish)
+}
+
+func TestSubjectMigrations_Subjects(t *testing.T) {
+	pairs := []SubjectMigration{
+		{Old: SubjectSessionStatus, New: testNewSessionStatus},
+		{Old: SubjectSessionCreate, New: "streamspace.v2.session.create"},
+	}
+	m := NewSubjectMigrations(pairs, MigrationPublishBoth)
+
+	// Either name of a pair resolves to both
+	both := []string{SubjectSessionStatus, testNewSessionStatus}
+	assert.Equal(t, both, m.SubscribeSubjects(SubjectSessionStatus))
+	assert.Equal(t, both, m.SubscribeSubjects(testNewSessionStatus))
+	assert.Equal(t, both, m.PublishSubjects(SubjectSessionStatus))
+
+	// Subjects below a pair migrate with it; lookalikes don't
+	platform := SubjectWithPlatform(SubjectSessionCreate, PlatformKubernetes)
+	assert.Equal(t, []string{platform, "streamspace.v2.session.create.kubernetes"}, m.SubscribeSubjects(platform))
+	assert.Equal(t, []string{"streamspace.session.statusx"}, m.SubscribeSubjects("streamspace.session.statusx"))
+	assert.Equal(t, []string{SubjectAppStatus}, m.PublishSubjects(SubjectAppStatus))
+
+	// Requests use the old name unless publishing to the new one
+	assert.Equal(t, SubjectSessionStatus, m.RequestSubject(testNewSessionStatus))
+	assert.Equal(t, []string{testNewSessionStatus}, NewSubjectMigrations(pairs, MigrationPublishNew).PublishSubjects(SubjectSessionStatus))
+	assert.Equal(t, testNewSessionStatus, NewSubjectMigrations(pairs, MigrationPublishNew).RequestSubject(SubjectSessionStatus))
+	assert.Equal(t, []string{SubjectSessionStatus}, NewSubjectMigrations(pairs, MigrationPublishOld).PublishSubjects(testNewSessionStatus))
+
+	// Without migrations subjects are used as is
+	var none *SubjectMigrations
+	assert.Equal(t, []string{SubjectSessionStatus}, none.PublishSubjects(SubjectSessionStatus))
+	assert.Equal(t, SubjectSessionStatus, none.RequestSubject(SubjectSessionStatus))
+}
+
+func TestSubjectMigrations_DeliversOneCopy(t *testing.T) {
+	m := NewSubjectMigrations([]SubjectMigration{{Old: SubjectSessionStatus, New: testNewSessionStatus}}, MigrationPublishBoth)
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+
+	var delivered []string
+	handler := m.wrap(SubjectSessionStatus, testNewSessionStatus, func(msg *nats.Msg) {
+		delivered = append(delivered, msg.Subject)
+	})
+	message := func(subject, id string) *nats.Msg {
+		msg := nats.NewMsg(subject)
+		if id != "" {
+			msg.Header.Set(MigrationIDHeader, id)
+		}
+		return msg
+	}
+
+	// Both copies of a dual-published message: the first one wins
+	handler(message(testNewSessionStatus, "a"))
+	handler(message(SubjectSessionStatus, "a"))
+	assert.Equal(t, []string{testNewSessionStatus}, delivered)
+
+	// Messages from components that publish to one name have no ID
+	handler(message(SubjectSessionStatus, ""))
+	handler(message(SubjectSessionStatus, ""))
+	assert.Len(t, delivered, 3)
+
+	// IDs are forgotten after the dedup window
+	now = now.Add(migrationDedupWindow)
+	handler(message(SubjectSessionStatus, "a"))
+	assert.Len(t, delivered, 4)
+	assert.Len(t, m.seen, 1)
+}
@@ -0,0 +1,112 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Controllers subscribe to session create subjects with core NATS, so a
+// create published while no controller is listening is dropped. Creates that
+// no controller acknowledged are therefore kept in pending_session_creates
+// and replayed when a controller of the same platform sends a sync request
+// or heartbeat. Rows are claimed with DELETE ... RETURNING so that only one
+// API replica replays each create. Held rows wait for a per-user session
+// slot instead (see user_session_limit.go).
+
+// QueueSessionCreate stores a session create event for replay once a
+// controller of its platform is reachable again.
+func QueueSessionCreate(ctx context.Context, db *sql.DB, event *SessionCreateEvent) error {
+	if db == nil {
+		return fmt.Errorf("no database to queue session %s", event.SessionID)
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal session create event: %w", err)
+	}
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO pending_session_creates (session_id, platform, target_controller, event, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (session_id) DO UPDATE SET
+			platform = EXCLUDED.platform, target_controller = EXCLUDED.target_controller,
+			event = EXCLUDED.event
+	`, event.SessionID, event.Platform, event.TargetController, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to queue session create for %s: %w", event.SessionID, err)
+	}
+	return nil
+}
+
+// CancelQueuedSessionCreate removes a queued session create. It reports
+// whether a create was still queued, i.e. no controller has received it.
+func CancelQueuedSessionCreate(ctx context.Context, db *sql.DB, sessionID string) (bool, error) {
+	if db == nil {
+		return false, nil
+	}
+	result, err := db.ExecContext(ctx,
+		`DELETE FROM pending_session_creates WHERE session_id = $1`, sessionID)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel queued session create for %s: %w", sessionID, err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected > 0, nil
+}
+
+// replayQueuedSessionCreates publishes the queued session creates that the
+// given controller can handle, addressing them to that controller. Creates
+// that fail to publish are queued again.
+func (s *Subscriber) replayQueuedSessionCreates(controllerID, platform string) {
+	if s.db == nil || s.publisher == nil || !s.publisher.enabled || platform == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		DELETE FROM pending_session_creates
+		WHERE platform = $1 AND (target_controller = '' OR target_controller = $2) AND NOT held
+		RETURNING session_id, event
+	`, platform, controllerID)
+	if err != nil {
+		log.Printf("Failed to claim queued session creates for controller %s: %v", controllerID, err)
+		return
+	}
+
+	var claimed []*SessionCreateEvent
+	for rows.Next() {
+		var sessionID, data string
+		if err := rows.Scan(&sessionID, &data); err != nil {
+			log.Printf("Failed to scan queued session create: %v", err)
+			continue
+		}
+		var event SessionCreateEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			log.Printf("Dropping unreadable queued session create for %s: %v", sessionID, err)
+			continue
+		}
+		claimed = append(claimed, &event)
+	}
+	if err := rows.Err(); err != nil {
+		log.Printf("Error iterating queued session creates: %v", err)
+	}
+	rows.Close()
+
+	for _, event := range claimed {
+		event.TargetController = controllerID
+		if err := s.publisher.PublishSessionCreate(ctx, event); err != nil {
+			log.Printf("Failed to replay session create for %s: %v", event.SessionID, err)
+			if err := QueueSessionCreate(ctx, s.db, event); err != nil {
+				log.Printf("Lost queued session create for %s: %v", event.SessionID, err)
+			}
+			continue
+		}
+		log.Printf("Replayed queued session create for %s to controller %s", event.SessionID, controllerID)
+	}
+}
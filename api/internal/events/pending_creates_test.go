@@ -0,0 +1,77 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that a queued create stores the full event for its platform
+func TestQueueSessionCreate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	event := &SessionCreateEvent{
+		SessionID:        "sess-1",
+		UserID:           "alice",
+		Platform:         "kubernetes",
+		TargetController: "ctrl-a",
+	}
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	mock.ExpectExec("INSERT INTO pending_session_creates").
+		WithArgs("sess-1", "kubernetes", "ctrl-a", string(data)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	require.NoError(t, QueueSessionCreate(context.Background(), db, event))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test that queueing without a database fails so the caller can reject the request
+func TestQueueSessionCreate_NoDatabase(t *testing.T) {
+	err := QueueSessionCreate(context.Background(), nil, &SessionCreateEvent{SessionID: "sess-1"})
+	assert.Error(t, err)
+}
+
+// Test that cancelling reports whether the create was still queued
+func TestCancelQueuedSessionCreate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectExec("DELETE FROM pending_session_creates").
+		WithArgs("sess-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM pending_session_creates").
+		WithArgs("sess-2").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	cancelled, err := CancelQueuedSessionCreate(context.Background(), db, "sess-1")
+	require.NoError(t, err)
+	assert.True(t, cancelled)
+
+	cancelled, err = CancelQueuedSessionCreate(context.Background(), db, "sess-2")
+	require.NoError(t, err)
+	assert.False(t, cancelled)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test that queued creates are left in place while nothing can publish them
+func TestReplayQueuedSessionCreates_PublisherDisabled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := &Subscriber{db: db, enabled: true, publisher: &Publisher{enabled: false}}
+	s.replayQueuedSessionCreates("ctrl-a", "kubernetes")
+
+	// No claim query was issued
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -17,6 +18,19 @@ type Publisher struct {
 	conn    *nats.Conn
 	js      nats.JetStreamContext
 	enabled bool
+
+	// disabledReason explains why enabled is false (reported by Health)
+	disabledReason string
+
+	// failedURL is the configured NATS URL if the initial connection to it
+	// failed, so Health reports an outage rather than a disabled connection
+	failedURL string
+
+	// breaker fails request/reply calls fast while controllers don't answer
+	breaker *CircuitBreaker
+
+	// migrations maps renamed subjects to their old and new names (migration.go)
+	migrations *SubjectMigrations
 }
 
 // Config holds NATS connection configuration.
@@ -35,7 +49,7 @@ func NewPublisher(cfg Config) (*Publisher, error) {
 	}
 	if cfg.URL == "" {
 		log.Println("Warning: NATS_URL not configured, event publishing disabled")
-		return &Publisher{enabled: false}, nil
+		return &Publisher{enabled: false, disabledReason: "NATS_URL not configured"}, nil
 	}
 
 	// Build connection options
@@ -66,7 +80,7 @@ func NewPublisher(cfg Config) (*Publisher, error) {
 	if err != nil {
 		log.Printf("Warning: Failed to connect to NATS at %s: %v", cfg.URL, err)
 		log.Println("Event publishing disabled - controllers will not receive events")
-		return &Publisher{enabled: false}, nil
+		return &Publisher{enabled: false, disabledReason: fmt.Sprintf("initial connection failed: %v", err), failedURL: cfg.URL}, nil
 	}
 
 	log.Printf("Connected to NATS at %s", conn.ConnectedUrl())
@@ -87,9 +101,11 @@ func NewPublisher(cfg Config) (*Publisher, error) {
 	}
 
 	return &Publisher{
-		conn:    conn,
-		js:      js,
-		enabled: true,
+		conn:       conn,
+		js:         js,
+		enabled:    true,
+		breaker:    newCircuitBreakerFromEnv(),
+		migrations: subjectMigrationsFromEnv(),
 	}, nil
 }
 
@@ -176,11 +192,11 @@ func (p *Publisher) Publish(subject string, event interface{}) error {
 		return fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	if err := p.conn.Publish(subject, data); err != nil {
+	if err := p.migrations.Publish(p.conn, subject, data); err != nil {
 		return fmt.Errorf("failed to publish to %s: %w", subject, err)
 	}
 
-	log.Printf("Published event to %s", subject)
+	log.Printf("Published event to %s", strings.Join(p.migrations.PublishSubjects(subject), ", "))
 	return nil
 }
 
@@ -194,17 +210,33 @@ func (p *Publisher) PublishWithPlatform(subject, platform string, event interfac
 }
 
 // Request publishes a request and waits for a response.
+//
+// Requests go through the publisher's circuit breaker: after repeated
+// timeouts it returns ErrControllersUnavailable immediately instead of
+// blocking every caller for the full timeout.
+//
+// A request for a migrating subject goes to only one of its names (see
+// SubjectMigrations.RequestSubject).
 func (p *Publisher) Request(subject string, event interface{}, timeout time.Duration) (*nats.Msg, error) {
 	if !p.enabled {
 		return nil, fmt.Errorf("event publishing disabled")
 	}
+	subject = p.migrations.RequestSubject(subject)
 
 	data, err := json.Marshal(event)
 	if err != nil {
 		return nil, fmt.Errorf("failed to marshal event: %w", err)
 	}
 
-	return p.conn.Request(subject, data, timeout)
+	if p.breaker == nil {
+		return p.conn.Request(subject, data, timeout)
+	}
+	if err := p.breaker.Allow(); err != nil {
+		return nil, err
+	}
+	msg, err := p.conn.Request(subject, data, timeout)
+	p.breaker.Record(err)
+	return msg, err
 }
 
 // Subscribe subscribes to a subject with a handler.
@@ -226,14 +258,72 @@ func (p *Publisher) QueueSubscribe(subject, queue string, handler nats.MsgHandle
 // Helper methods for publishing specific events
 
 // PublishSessionCreate publishes a session create event.
+//
+// Events for templates that don't support the target platform are rejected
+// rather than routed to a controller that can't run them.
 func (p *Publisher) PublishSessionCreate(ctx context.Context, event *SessionCreateEvent) error {
+	if !event.TemplateConfig.SupportsPlatform(event.Platform) {
+		return fmt.Errorf("template %s does not support platform %s (supported: %s)",
+			event.TemplateID, event.Platform, strings.Join(event.TemplateConfig.SupportedBackends, ", "))
+	}
+	if event.EventID == "" {
+		event.EventID = uuid.New().String()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+	if err := p.Publish(SubjectSessionCreate, event); err != nil {
+		return err
+	}
+	return p.Publish(SessionCreateSubject(event), event)
+}
+
+// SessionCreateSubject returns the subject controllers receive a session
+// create event on: the chosen controller's own subject, or the platform's.
+func SessionCreateSubject(event *SessionCreateEvent) string {
+	if event.TargetController != "" {
+		return SubjectForController(SubjectSessionCreate, event.Platform, event.TargetController)
+	}
+	return SubjectWithPlatform(SubjectSessionCreate, event.Platform)
+}
+
+// RequestSessionCreate publishes a session create event and waits for a
+// controller to acknowledge it.
+//
+// Returns ErrControllersUnavailable without waiting while the circuit
+// breaker is open, and nats.ErrTimeout if no controller answered in time.
+// In both cases the caller should queue the event with QueueSessionCreate
+// rather than fail the session.
+func (p *Publisher) RequestSessionCreate(ctx context.Context, event *SessionCreateEvent, timeout time.Duration) (*EventAck, error) {
+	if !event.TemplateConfig.SupportsPlatform(event.Platform) {
+		return nil, fmt.Errorf("template %s does not support platform %s (supported: %s)",
+			event.TemplateID, event.Platform, strings.Join(event.TemplateConfig.SupportedBackends, ", "))
+	}
 	if event.EventID == "" {
 		event.EventID = uuid.New().String()
 	}
 	if event.Timestamp.IsZero() {
 		event.Timestamp = time.Now()
 	}
-	return p.PublishWithPlatform(SubjectSessionCreate, event.Platform, event)
+
+	// Controllers subscribe to the platform (or their own) subject; the
+	// generic one is published for observers as usual
+	if err := p.Publish(SubjectSessionCreate, event); err != nil {
+		return nil, err
+	}
+	msg, err := p.Request(SessionCreateSubject(event), event, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	var ack EventAck
+	if err := json.Unmarshal(msg.Data, &ack); err != nil {
+		return nil, fmt.Errorf("invalid acknowledgement: %w", err)
+	}
+	if !ack.Success {
+		return &ack, fmt.Errorf("controller %s rejected session: %s", ack.ControllerID, ack.Error)
+	}
+	return &ack, nil
 }
 
 // PublishSessionDelete publishes a session delete event.
@@ -244,7 +334,7 @@ func (p *Publisher) PublishSessionDelete(ctx context.Context, event *SessionDele
 	if event.Timestamp.IsZero() {
 		event.Timestamp = time.Now()
 	}
-	return p.PublishWithPlatform(SubjectSessionDelete, event.Platform, event)
+	return p.publishToSessionController(SubjectSessionDelete, event.Platform, event.TargetController, event)
 }
 
 // PublishSessionHibernate publishes a session hibernate event.
@@ -255,7 +345,7 @@ func (p *Publisher) PublishSessionHibernate(ctx context.Context, event *SessionH
 	if event.Timestamp.IsZero() {
 		event.Timestamp = time.Now()
 	}
-	return p.PublishWithPlatform(SubjectSessionHibernate, event.Platform, event)
+	return p.publishToSessionController(SubjectSessionHibernate, event.Platform, event.TargetController, event)
 }
 
 // PublishSessionWake publishes a session wake event.
@@ -266,7 +356,26 @@ func (p *Publisher) PublishSessionWake(ctx context.Context, event *SessionWakeEv
 	if event.Timestamp.IsZero() {
 		event.Timestamp = time.Now()
 	}
-	return p.PublishWithPlatform(SubjectSessionWake, event.Platform, event)
+	return p.publishToSessionController(SubjectSessionWake, event.Platform, event.TargetController, event)
+}
+
+// publishToSessionController publishes a session command to the generic
+// subject and to the subject its controller receives it on.
+func (p *Publisher) publishToSessionController(subject, platform, targetController string, event interface{}) error {
+	if err := p.Publish(subject, event); err != nil {
+		return err
+	}
+	return p.Publish(SessionCommandSubject(subject, platform, targetController), event)
+}
+
+// SessionCommandSubject returns the subject controllers receive a session
+// command on: the subject of the controller the session is pinned to, or
+// the platform's.
+func SessionCommandSubject(subject, platform, targetController string) string {
+	if targetController != "" {
+		return SubjectForController(subject, platform, targetController)
+	}
+	return SubjectWithPlatform(subject, platform)
 }
 
 // PublishAppInstall publishes an application install event.
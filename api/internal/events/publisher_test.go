@@ -212,7 +212,12 @@ func TestControllerHeartbeatEvent_JSONMarshaling(t *testing.T) {
 		Timestamp:    time.Now(),
 		Status:       "healthy",
 		Version:      "1.0.0",
-		Capabilities: []string{"sessions", "templates", "scaling"},
+		Capabilities: ControllerCapabilities{
+			Backend:      PlatformKubernetes,
+			Actions:      []string{"session.create", "session.delete", "template.create"},
+			GPU:          true,
+			MaxResources: ResourceSpec{Memory: "64Gi", CPU: "16"},
+		},
 		ClusterInfo: map[string]interface{}{
 			"nodes": 5,
 			"cpu":   "32000m",
@@ -228,7 +233,15 @@ func TestControllerHeartbeatEvent_JSONMarshaling(t *testing.T) {
 
 	assert.Equal(t, event.ControllerID, decoded.ControllerID)
 	assert.Equal(t, event.Status, decoded.Status)
-	assert.Len(t, decoded.Capabilities, 3)
+	assert.Equal(t, event.Capabilities, decoded.Capabilities)
+}
+
+func TestControllerCapabilities_UnmarshalLegacyList(t *testing.T) {
+	var event ControllerHeartbeatEvent
+	err := json.Unmarshal([]byte(`{"controller_id":"c1","capabilities":["sessions","templates"]}`), &event)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"sessions", "templates"}, event.Capabilities.Actions)
+	assert.False(t, event.Capabilities.GPU)
 }
 
 func TestNodeEvents_JSONMarshaling(t *testing.T) {
@@ -328,3 +341,63 @@ func TestInstallStatusConstants(t *testing.T) {
 	assert.Equal(t, "ready", InstallStatusReady)
 	assert.Equal(t, "failed", InstallStatusFailed)
 }
+
+func TestPublisherHealth_Disabled(t *testing.T) {
+	t.Setenv("NATS_URL", "")
+
+	pub, err := NewPublisher(Config{URL: ""})
+	require.NoError(t, err)
+
+	health := pub.Health()
+	assert.Equal(t, StateDisabled, health.State)
+	assert.Equal(t, "NATS_URL not configured", health.Message)
+	assert.True(t, health.Healthy(), "disabled NATS should not fail readiness")
+}
+
+func TestHealth_InitialConnectionFailed(t *testing.T) {
+	// NATS_URL is set but nothing listens there
+	cfg := Config{URL: "nats://127.0.0.1:1"}
+
+	pub, err := NewPublisher(cfg)
+	require.NoError(t, err)
+	health := pub.Health()
+	assert.Equal(t, StateDisconnected, health.State)
+	assert.Equal(t, cfg.URL, health.URL)
+	assert.Contains(t, health.Message, "initial connection failed")
+	assert.False(t, health.Healthy(), "unreachable NATS must fail readiness")
+
+	sub, err := NewSubscriber(cfg, nil, pub)
+	require.NoError(t, err)
+	assert.Equal(t, StateDisconnected, sub.Health().State)
+	assert.False(t, sub.Health().Healthy())
+}
+
+func TestHealthStatus_Healthy(t *testing.T) {
+	assert.True(t, HealthStatus{State: StateConnected}.Healthy())
+	assert.True(t, HealthStatus{State: StateDisabled}.Healthy())
+	assert.False(t, HealthStatus{State: StateReconnecting}.Healthy())
+	assert.False(t, HealthStatus{State: StateDisconnected}.Healthy())
+}
+
+func TestTemplateConfig_SupportsPlatform(t *testing.T) {
+	var nilConfig *TemplateConfig
+	assert.True(t, nilConfig.SupportsPlatform(PlatformDocker), "no template config runs everywhere")
+	assert.True(t, (&TemplateConfig{}).SupportsPlatform(PlatformDocker), "no backends declared runs everywhere")
+
+	k8sOnly := &TemplateConfig{SupportedBackends: []string{PlatformKubernetes}}
+	assert.True(t, k8sOnly.SupportsPlatform(PlatformKubernetes))
+	assert.False(t, k8sOnly.SupportsPlatform(PlatformDocker))
+}
+
+func TestPublishSessionCreate_UnsupportedPlatform(t *testing.T) {
+	publisher := &Publisher{enabled: false}
+
+	err := publisher.PublishSessionCreate(context.Background(), &SessionCreateEvent{
+		SessionID:      "sess-1",
+		TemplateID:     "gpu-workstation",
+		Platform:       PlatformDocker,
+		TemplateConfig: &TemplateConfig{SupportedBackends: []string{PlatformKubernetes}},
+	})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "does not support platform docker")
+}
@@ -0,0 +1,92 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	"github.com/streamspace/streamspace/api/internal/audit"
+)
+
+// Session state changes are audited with the actor that caused them, so
+// "why did my session hibernate?" has a definite answer. Actors are:
+//   - a user ID: the user acted (hibernate, wake, terminate, connecting to
+//     a hibernated session)
+//   - "admin:<id>": an admin acted on someone else's session
+//   - "reconciler:idle": the session was idle too long
+//   - "reconciler:prewarm": the session was woken ahead of its usual start
+//   - "controller:<id>": the controller changed the state on its own
+//     (failed, crash looping, evicted) or no actor was recorded
+//
+// Most transitions are requested by the API and carried out by a
+// controller, which reports the new state later. The API therefore records
+// the requested state and its actor on the session (RequestSessionState)
+// and the status subscriber attributes the reported transition to it. A
+// controller that changes the state itself sends its actor in the status
+// event.
+
+// ActorIdleReconciler is the actor of idle hibernation.
+const ActorIdleReconciler = "reconciler:idle"
+
+// ActorPrewarm is the actor of a pre-warm wake.
+const ActorPrewarm = "reconciler:prewarm"
+
+// AuditActionSessionStateChange is the audit_log action of a state change.
+const AuditActionSessionStateChange = "session.state_change"
+
+// AdminActor is the actor of an admin acting on a session.
+func AdminActor(adminID string) string {
+	return "admin:" + adminID
+}
+
+// ControllerActor is the actor of a transition made by a controller.
+func ControllerActor(controllerID string) string {
+	return "controller:" + controllerID
+}
+
+// RequestSessionState records that actor asked for the session to move to
+// state. The actor is attributed when the controller reports the state.
+func RequestSessionState(ctx context.Context, db *sql.DB, sessionID, state, actor string) error {
+	if db == nil {
+		return nil
+	}
+	_, err := db.ExecContext(ctx, `
+		UPDATE sessions SET requested_state = $1, requested_by = $2 WHERE id = $3
+	`, state, actor, sessionID)
+	return err
+}
+
+// satisfiesStateRequest reports whether a reported state is the outcome of
+// a requested one. A termination may end in any terminal state.
+func satisfiesStateRequest(requested, state string) bool {
+	if requested == state {
+		return true
+	}
+	return requested == "terminated" && (state == StatusDeleted || state == "cancelled")
+}
+
+// RecordSessionStateChange writes the audit entry of a session moving from
+// oldState to newState.
+func RecordSessionStateChange(ctx context.Context, db *sql.DB, sessionID, oldState, newState, actor, message string) error {
+	if db == nil {
+		return nil
+	}
+
+	values := map[string]interface{}{"actor": actor}
+	if message != "" {
+		values["message"] = message
+	}
+	changes := audit.Values(values)
+	changes["state"] = audit.FieldChange{Old: oldState, New: newState}
+	details, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	_, err = db.ExecContext(ctx, `
+		INSERT INTO audit_log (user_id, action, resource_type, resource_id, changes, timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, actor, AuditActionSessionStateChange, "session", sessionID, details, time.Now())
+	return err
+}
@@ -0,0 +1,77 @@
+package events
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+// Session state command coalescing.
+//
+// A user toggling a session quickly sends hibernate, wake, hibernate...
+// faster than the controller applies them, and only the last command
+// matters. Each state command waits for the coalesce window and is only
+// published if no later command for the same session arrived meanwhile;
+// the earlier ones are dropped as superseded. Commands carry the time they
+// were requested, which controllers use to ignore a command that arrives
+// after a later one (commands on different subjects aren't ordered).
+//
+// Environment:
+//   - SESSION_STATE_COALESCE_WINDOW: how long a state command waits for a
+//     later one (default 250ms, 0 publishes commands immediately)
+
+// defaultStateCoalesceWindow is the default SESSION_STATE_COALESCE_WINDOW.
+const defaultStateCoalesceWindow = 250 * time.Millisecond
+
+// StateCommands coalesces rapid state commands per session. A nil
+// StateCommands publishes every command immediately.
+type StateCommands struct {
+	window time.Duration
+
+	mu     sync.Mutex
+	seq    uint64
+	latest map[string]uint64 // session → sequence of its latest command
+}
+
+// NewStateCommands creates a coalescer that holds commands for window.
+func NewStateCommands(window time.Duration) *StateCommands {
+	return &StateCommands{window: window, latest: make(map[string]uint64)}
+}
+
+// StateCommandsFromEnv creates the coalescer configured by
+// SESSION_STATE_COALESCE_WINDOW.
+func StateCommandsFromEnv() *StateCommands {
+	window := defaultStateCoalesceWindow
+	if d, err := time.ParseDuration(os.Getenv("SESSION_STATE_COALESCE_WINDOW")); err == nil && d >= 0 {
+		window = d
+	}
+	return NewStateCommands(window)
+}
+
+// Dispatch calls publish for a state command of session unless a later
+// command for the session is dispatched within the window. It reports
+// whether the command was superseded, and otherwise returns publish's
+// error. Dispatch blocks for the window.
+func (c *StateCommands) Dispatch(sessionID string, publish func() error) (superseded bool, err error) {
+	if c == nil || c.window <= 0 {
+		return false, publish()
+	}
+
+	c.mu.Lock()
+	c.seq++
+	seq := c.seq
+	c.latest[sessionID] = seq
+	c.mu.Unlock()
+
+	time.Sleep(c.window)
+
+	c.mu.Lock()
+	if c.latest[sessionID] != seq {
+		c.mu.Unlock()
+		return true, nil
+	}
+	delete(c.latest, sessionID)
+	c.mu.Unlock()
+
+	return false, publish()
+}
@@ -0,0 +1,60 @@
+package events
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Test that rapid state flips publish only the final command
+func TestStateCommands_DispatchesFinalIntent(t *testing.T) {
+	c := NewStateCommands(50 * time.Millisecond)
+
+	var mu sync.Mutex
+	var published []string
+	publish := func(state string) func() error {
+		return func() error {
+			mu.Lock()
+			defer mu.Unlock()
+			published = append(published, state)
+			return nil
+		}
+	}
+
+	states := []string{"hibernated", "running", "hibernated", "running"}
+	superseded := make([]bool, len(states))
+	var wg sync.WaitGroup
+	for i, state := range states {
+		wg.Add(1)
+		go func(i int, state string) {
+			defer wg.Done()
+			superseded[i], _ = c.Dispatch("sess-1", publish(state))
+		}(i, state)
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Other sessions are not affected
+	other, err := c.Dispatch("sess-2", publish("hibernated"))
+	assert.NoError(t, err)
+	assert.False(t, other)
+	wg.Wait()
+
+	assert.Equal(t, []bool{true, true, true, false}, superseded)
+	assert.ElementsMatch(t, []string{"hibernated", "running"}, published)
+	assert.Empty(t, c.latest)
+}
+
+func TestStateCommands_PublishesImmediatelyWithoutWindow(t *testing.T) {
+	var none *StateCommands
+	superseded, err := none.Dispatch("sess-1", func() error { return errors.New("nats down") })
+	assert.False(t, superseded)
+	assert.EqualError(t, err, "nats down")
+
+	t.Setenv("SESSION_STATE_COALESCE_WINDOW", "0")
+	assert.Zero(t, StateCommandsFromEnv().window)
+	t.Setenv("SESSION_STATE_COALESCE_WINDOW", "bogus")
+	assert.Equal(t, defaultStateCoalesceWindow, StateCommandsFromEnv().window)
+}
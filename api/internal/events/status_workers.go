@@ -0,0 +1,179 @@
+package events
+
+import (
+	"encoding/json"
+	"hash/fnv"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// Status events are written to the database by a pool of workers instead of
+// in the NATS delivery callback.
+//
+// Each status event costs a synchronous database update (up to 5s). Done
+// inline, a burst of events from a busy controller backs up in the client's
+// pending buffer until NATS disconnects the API as a slow consumer and the
+// events are lost. With the pool, the callback only decodes the event key
+// and enqueues; N workers do the updates.
+//
+// Ordering: events are sharded by key (session ID, install ID), so every
+// event for the same session is handled by the same worker, in the order
+// NATS delivered it. A session can never be set back to an older status by
+// a worker that raced ahead.
+//
+// Backpressure: each worker has a bounded queue. When a queue is full the
+// callback blocks until the worker catches up, so the backlog stays in NATS'
+// pending buffer (which is far larger than any storm) rather than growing
+// without bound in the API.
+const (
+	defaultStatusWorkers   = 8
+	defaultStatusQueueSize = 256
+)
+
+// QueueStatus reports the subscriber's status worker queues.
+type QueueStatus struct {
+	Workers  int `json:"workers"`
+	Depth    int `json:"depth"`
+	Capacity int `json:"capacity"`
+	// Waits counts events whose enqueue had to wait for a full queue
+	Waits int64 `json:"waits"`
+}
+
+// statusWorkerPool runs status updates off the NATS delivery goroutine.
+// Jobs with the same key always run on the same worker, in arrival order.
+type statusWorkerPool struct {
+	queues []chan func()
+	wg     sync.WaitGroup
+
+	// mu guards closed; enqueue holds it for reading while sending so stop
+	// never closes a queue under a pending send
+	mu     sync.RWMutex
+	closed bool
+
+	waits atomic.Int64
+}
+
+// newStatusWorkerPool creates a pool of workers, each with a queue of
+// queueSize jobs. Call start to run it.
+func newStatusWorkerPool(workers, queueSize int) *statusWorkerPool {
+	p := &statusWorkerPool{queues: make([]chan func(), workers)}
+	for i := range p.queues {
+		p.queues[i] = make(chan func(), queueSize)
+	}
+	return p
+}
+
+// newStatusWorkerPoolFromEnv creates the subscriber's worker pool.
+//
+// Environment:
+//   - NATS_STATUS_WORKERS: number of workers (default 8)
+//   - NATS_STATUS_QUEUE_SIZE: queued events per worker (default 256)
+func newStatusWorkerPoolFromEnv() *statusWorkerPool {
+	workers := defaultStatusWorkers
+	if v, err := strconv.Atoi(os.Getenv("NATS_STATUS_WORKERS")); err == nil && v > 0 {
+		workers = v
+	}
+	queueSize := defaultStatusQueueSize
+	if v, err := strconv.Atoi(os.Getenv("NATS_STATUS_QUEUE_SIZE")); err == nil && v > 0 {
+		queueSize = v
+	}
+	return newStatusWorkerPool(workers, queueSize)
+}
+
+// start runs one goroutine per queue.
+func (p *statusWorkerPool) start() {
+	for _, queue := range p.queues {
+		p.wg.Add(1)
+		go func(queue chan func()) {
+			defer p.wg.Done()
+			for job := range queue {
+				job()
+			}
+		}(queue)
+	}
+}
+
+// enqueue queues job on the worker owning key, blocking while that worker's
+// queue is full. It returns false if the pool has been stopped.
+func (p *statusWorkerPool) enqueue(key string, job func()) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return false
+	}
+
+	queue := p.queues[p.shard(key)]
+	select {
+	case queue <- job:
+	default:
+		if waits := p.waits.Add(1); waits == 1 || waits%1000 == 0 {
+			log.Printf("Status event queue full, applying backpressure to NATS (%d waits so far; raise NATS_STATUS_WORKERS if this persists)", waits)
+		}
+		queue <- job
+	}
+	return true
+}
+
+// shard maps a key to a worker index.
+func (p *statusWorkerPool) shard(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return int(h.Sum32() % uint32(len(p.queues)))
+}
+
+// stop stops accepting jobs and waits for queued jobs to finish.
+func (p *statusWorkerPool) stop() {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return
+	}
+	p.closed = true
+	for _, queue := range p.queues {
+		close(queue)
+	}
+	p.mu.Unlock()
+	p.wg.Wait()
+}
+
+// status reports queue depth and capacity.
+func (p *statusWorkerPool) status() QueueStatus {
+	status := QueueStatus{Workers: len(p.queues), Waits: p.waits.Load()}
+	for _, queue := range p.queues {
+		status.Depth += len(queue)
+		status.Capacity += cap(queue)
+	}
+	return status
+}
+
+// dispatch runs job on the status worker pool, keyed for ordering. Without
+// a pool (disabled subscriber, tests) the job runs inline.
+func (s *Subscriber) dispatch(key string, job func()) {
+	if s.workers == nil {
+		job()
+		return
+	}
+	if !s.workers.enqueue(key, job) {
+		log.Printf("Dropping status event for %s: subscriber is shutting down", key)
+	}
+}
+
+// statusEventKey extracts the ordering key of a status event: the session
+// ID, or the install ID for app events. Undecodable events get an empty key;
+// their handler logs the decode error.
+func statusEventKey(data []byte) string {
+	var key struct {
+		SessionID string `json:"session_id"`
+		InstallID string `json:"install_id"`
+	}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return ""
+	}
+	if key.SessionID != "" {
+		return key.SessionID
+	}
+	return key.InstallID
+}
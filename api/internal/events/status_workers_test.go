@@ -0,0 +1,106 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that events for the same key are handled in arrival order
+func TestStatusWorkerPool_PerKeyOrdering(t *testing.T) {
+	pool := newStatusWorkerPool(4, 8)
+	pool.start()
+
+	var mu sync.Mutex
+	seen := map[string][]int{}
+	for i := 0; i < 200; i++ {
+		key := fmt.Sprintf("session-%d", i%5)
+		seq := i
+		require.True(t, pool.enqueue(key, func() {
+			mu.Lock()
+			defer mu.Unlock()
+			seen[key] = append(seen[key], seq)
+		}))
+	}
+	pool.stop()
+
+	require.Len(t, seen, 5)
+	for key, seqs := range seen {
+		assert.Len(t, seqs, 40, key)
+		assert.IsIncreasing(t, seqs, key)
+	}
+}
+
+// Test that a full queue blocks the sender until the worker catches up
+func TestStatusWorkerPool_Backpressure(t *testing.T) {
+	pool := newStatusWorkerPool(1, 1)
+	pool.start()
+	defer pool.stop()
+
+	release := make(chan struct{})
+	started := make(chan struct{})
+	pool.enqueue("s", func() { close(started); <-release })
+	<-started
+	pool.enqueue("s", func() {}) // fills the queue
+
+	enqueued := make(chan struct{})
+	go func() {
+		pool.enqueue("s", func() {})
+		close(enqueued)
+	}()
+
+	select {
+	case <-enqueued:
+		t.Fatal("enqueue should block while the queue is full")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, QueueStatus{Workers: 1, Depth: 1, Capacity: 1, Waits: 1}, pool.status())
+
+	close(release)
+	select {
+	case <-enqueued:
+	case <-time.After(time.Second):
+		t.Fatal("enqueue should proceed once the worker catches up")
+	}
+}
+
+// Test that stop finishes queued events and rejects new ones
+func TestStatusWorkerPool_StopDrains(t *testing.T) {
+	pool := newStatusWorkerPool(2, 16)
+	pool.start()
+
+	var mu sync.Mutex
+	done := 0
+	for i := 0; i < 10; i++ {
+		pool.enqueue(fmt.Sprint(i), func() {
+			time.Sleep(time.Millisecond)
+			mu.Lock()
+			done++
+			mu.Unlock()
+		})
+	}
+	pool.stop()
+
+	assert.Equal(t, 10, done)
+	assert.False(t, pool.enqueue("late", func() {}))
+	pool.stop() // idempotent
+}
+
+func TestStatusEventKey(t *testing.T) {
+	assert.Equal(t, "sess-1", statusEventKey([]byte(`{"session_id":"sess-1","status":"running"}`)))
+	assert.Equal(t, "install-1", statusEventKey([]byte(`{"install_id":"install-1"}`)))
+	assert.Equal(t, "", statusEventKey([]byte(`not json`)))
+}
+
+func TestNewStatusWorkerPoolFromEnv(t *testing.T) {
+	t.Setenv("NATS_STATUS_WORKERS", "3")
+	t.Setenv("NATS_STATUS_QUEUE_SIZE", "bogus")
+
+	status := newStatusWorkerPoolFromEnv().status()
+	assert.Equal(t, 3, status.Workers)
+	assert.Equal(t, 3*defaultStatusQueueSize, status.Capacity)
+}
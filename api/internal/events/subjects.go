@@ -10,6 +10,7 @@ const (
 	SubjectSessionHibernate = "streamspace.session.hibernate"
 	SubjectSessionWake      = "streamspace.session.wake"
 	SubjectSessionStatus    = "streamspace.session.status"
+	SubjectSessionBatch     = "streamspace.session.batch"
 
 	// Application events
 	SubjectAppInstall   = "streamspace.app.install"
@@ -19,6 +20,7 @@ const (
 	// Template events
 	SubjectTemplateCreate = "streamspace.template.create"
 	SubjectTemplateDelete = "streamspace.template.delete"
+	SubjectTemplateStatus = "streamspace.template.status"
 
 	// Node management events
 	SubjectNodeCordon   = "streamspace.node.cordon"
@@ -40,6 +42,14 @@ func SubjectWithPlatform(subject, platform string) string {
 	return subject + "." + platform
 }
 
+// SubjectForController returns the subject of one specific controller.
+// Controllers subscribe to it besides their platform's shared queue.
+// Example: SubjectForController(SubjectSessionCreate, PlatformKubernetes, "k8s-gpu-1")
+// Returns: "streamspace.session.create.kubernetes.k8s-gpu-1"
+func SubjectForController(subject, platform, controllerID string) string {
+	return SubjectWithPlatform(subject, platform) + "." + controllerID
+}
+
 // DLQSubject returns the dead letter queue subject for a given subject.
 // Example: DLQSubject(SubjectSessionCreate)
 // Returns: "streamspace.dlq.streamspace.session.create"
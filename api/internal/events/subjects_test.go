@@ -87,3 +87,10 @@ func TestSubjectParsing(t *testing.T) {
 		assert.Contains(t, SubjectNodeDrain, ".node.")
 	})
 }
+
+func TestSessionCommandSubject(t *testing.T) {
+	assert.Equal(t, "streamspace.session.hibernate.docker",
+		SessionCommandSubject(SubjectSessionHibernate, PlatformDocker, ""))
+	assert.Equal(t, "streamspace.session.hibernate.docker.docker-2",
+		SessionCommandSubject(SubjectSessionHibernate, PlatformDocker, "docker-2"))
+}
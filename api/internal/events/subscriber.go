@@ -11,6 +11,7 @@ import (
 	"fmt"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -24,6 +25,43 @@ type Subscriber struct {
 	enabled      bool
 	controllerID string
 	subs         []*nats.Subscription
+
+	// disabledReason explains why enabled is false (reported by Health)
+	disabledReason string
+
+	// failedURL is the configured NATS URL if the initial connection to it
+	// failed, so Health reports an outage rather than a disabled connection
+	failedURL string
+
+	// errorNotifier pushes session errors (e.g. crash loops) to the UI
+	notifierMu    sync.RWMutex
+	errorNotifier SessionErrorNotifier
+
+	// lifecycleDispatcher fires session lifecycle webhooks (lifecycle.go)
+	lifecycleDispatcher SessionLifecycleDispatcher
+
+	// controllers tracks controller capabilities from heartbeats
+	controllers *ControllerRegistry
+
+	// workers applies status events to the database (status_workers.go)
+	workers *statusWorkerPool
+
+	// migrations maps renamed subjects to their old and new names (migration.go)
+	migrations *SubjectMigrations
+}
+
+// SessionErrorNotifier delivers session errors to connected clients.
+// It is implemented by the WebSocket notifier.
+type SessionErrorNotifier interface {
+	NotifySessionError(sessionID, userID string, errorMsg string)
+}
+
+// SetSessionErrorNotifier sets the notifier used to report session errors
+// received from controllers.
+func (s *Subscriber) SetSessionErrorNotifier(notifier SessionErrorNotifier) {
+	s.notifierMu.Lock()
+	defer s.notifierMu.Unlock()
+	s.errorNotifier = notifier
 }
 
 // NewSubscriber creates a new NATS event subscriber.
@@ -31,7 +69,7 @@ type Subscriber struct {
 func NewSubscriber(cfg Config, db *sql.DB, publisher *Publisher) (*Subscriber, error) {
 	if cfg.URL == "" {
 		log.Println("Warning: NATS_URL not configured, event subscription disabled")
-		return &Subscriber{enabled: false}, nil
+		return &Subscriber{enabled: false, disabledReason: "NATS_URL not configured"}, nil
 	}
 
 	// Build connection options
@@ -62,7 +100,7 @@ func NewSubscriber(cfg Config, db *sql.DB, publisher *Publisher) (*Subscriber, e
 	if err != nil {
 		log.Printf("Warning: Failed to connect subscriber to NATS at %s: %v", cfg.URL, err)
 		log.Println("Event subscription disabled - API will not receive controller status updates")
-		return &Subscriber{enabled: false}, nil
+		return &Subscriber{enabled: false, disabledReason: fmt.Sprintf("initial connection failed: %v", err), failedURL: cfg.URL}, nil
 	}
 
 	log.Printf("API subscriber connected to NATS at %s", conn.ConnectedUrl())
@@ -73,9 +111,22 @@ func NewSubscriber(cfg Config, db *sql.DB, publisher *Publisher) (*Subscriber, e
 		publisher: publisher,
 		enabled:   true,
 		subs:      make([]*nats.Subscription, 0),
+
+		controllers: NewControllerRegistry(DefaultControllerStaleAfter),
+		workers:     newStatusWorkerPoolFromEnv(),
+		migrations:  subjectMigrationsFromEnv(),
 	}, nil
 }
 
+// Controllers returns the registry of controllers known from heartbeats
+// (nil if the subscriber is disabled).
+func (s *Subscriber) Controllers() *ControllerRegistry {
+	if s == nil {
+		return nil
+	}
+	return s.controllers
+}
+
 // Start begins subscribing to status events from controllers.
 func (s *Subscriber) Start(ctx context.Context) error {
 	if !s.enabled {
@@ -83,45 +134,44 @@ func (s *Subscriber) Start(ctx context.Context) error {
 		return nil
 	}
 
+	// Status events are applied by the worker pool so a burst of them
+	// can't make this subscriber a NATS slow consumer
+	s.workers.start()
+
 	// Subscribe to session status events (from all platforms)
-	sessionSub, err := s.conn.Subscribe(SubjectSessionStatus, func(msg *nats.Msg) {
-		s.handleSessionStatus(msg.Data)
-	})
-	if err != nil {
+	if err := s.subscribe(SubjectSessionStatus, func(msg *nats.Msg) {
+		s.dispatch(statusEventKey(msg.Data), func() { s.handleSessionStatus(msg.Data) })
+	}); err != nil {
 		return fmt.Errorf("failed to subscribe to session status: %w", err)
 	}
-	s.subs = append(s.subs, sessionSub)
-	log.Printf("Subscribed to %s", SubjectSessionStatus)
 
 	// Subscribe to app status events (from all platforms)
-	appSub, err := s.conn.Subscribe(SubjectAppStatus, func(msg *nats.Msg) {
-		s.handleAppStatus(msg.Data)
-	})
-	if err != nil {
+	if err := s.subscribe(SubjectAppStatus, func(msg *nats.Msg) {
+		s.dispatch(statusEventKey(msg.Data), func() { s.handleAppStatus(msg.Data) })
+	}); err != nil {
 		return fmt.Errorf("failed to subscribe to app status: %w", err)
 	}
-	s.subs = append(s.subs, appSub)
-	log.Printf("Subscribed to %s", SubjectAppStatus)
+
+	// Subscribe to template validity changes (from revalidation)
+	if err := s.subscribe(SubjectTemplateStatus, func(msg *nats.Msg) {
+		s.handleTemplateStatus(msg.Data)
+	}); err != nil {
+		return fmt.Errorf("failed to subscribe to template status: %w", err)
+	}
 
 	// Subscribe to controller heartbeats
-	heartbeatSub, err := s.conn.Subscribe(SubjectControllerHeartbeat, func(msg *nats.Msg) {
+	if err := s.subscribe(SubjectControllerHeartbeat, func(msg *nats.Msg) {
 		s.handleControllerHeartbeat(msg.Data)
-	})
-	if err != nil {
+	}); err != nil {
 		return fmt.Errorf("failed to subscribe to controller heartbeat: %w", err)
 	}
-	s.subs = append(s.subs, heartbeatSub)
-	log.Printf("Subscribed to %s", SubjectControllerHeartbeat)
 
 	// Subscribe to controller sync requests
-	syncSub, err := s.conn.Subscribe(SubjectControllerSyncRequest, func(msg *nats.Msg) {
+	if err := s.subscribe(SubjectControllerSyncRequest, func(msg *nats.Msg) {
 		s.handleControllerSyncRequest(msg.Data)
-	})
-	if err != nil {
+	}); err != nil {
 		return fmt.Errorf("failed to subscribe to controller sync request: %w", err)
 	}
-	s.subs = append(s.subs, syncSub)
-	log.Printf("Subscribed to %s", SubjectControllerSyncRequest)
 
 	log.Println("API event subscriber started, listening for controller status events")
 
@@ -130,7 +180,20 @@ func (s *Subscriber) Start(ctx context.Context) error {
 	return nil
 }
 
+// subscribe subscribes handler to subject, and to its other name while the
+// subject is migrating (see migration.go).
+func (s *Subscriber) subscribe(subject string, handler nats.MsgHandler) error {
+	subs, err := s.migrations.Subscribe(s.conn, subject, "", handler)
+	if err != nil {
+		return err
+	}
+	s.subs = append(s.subs, subs...)
+	log.Printf("Subscribed to %s", strings.Join(s.migrations.SubscribeSubjects(subject), ", "))
+	return nil
+}
+
 // Close closes the NATS connection and unsubscribes from all subjects.
+// Status events already received are written before it returns.
 func (s *Subscriber) Close() {
 	if s.conn != nil {
 		for _, sub := range s.subs {
@@ -139,6 +202,9 @@ func (s *Subscriber) Close() {
 		s.conn.Drain()
 		s.conn.Close()
 	}
+	if s.workers != nil {
+		s.workers.stop()
+	}
 }
 
 // IsEnabled returns whether event subscription is enabled.
@@ -162,30 +228,117 @@ func (s *Subscriber) handleSessionStatus(data []byte) {
 	defer cancel()
 
 	// Update the session state (using Phase which is the Kubernetes phase like "Running", "Pending"),
-	// URL, and pod_name
+	// URL, and pod_name. The previous state and the pending state request
+	// are returned to audit the transition.
 	query := `
-		UPDATE sessions
+		UPDATE sessions s
 		SET state = $1, url = $2, pod_name = $3, updated_at = $4
-		WHERE id = $5
+		FROM (SELECT id, state, requested_state, requested_by FROM sessions WHERE id = $5 FOR UPDATE) old
+		WHERE s.id = old.id
+		RETURNING COALESCE(old.state, ''), COALESCE(old.requested_state, ''), COALESCE(old.requested_by, '')
 	`
 
 	// Convert Phase to lowercase for state field (running, hibernated, pending, failed)
 	// The UI expects lowercase state values for session lifecycle checks
 	state := strings.ToLower(event.Phase)
-	result, err := s.db.ExecContext(ctx, query, state, event.URL, event.PodName, time.Now(), event.SessionID)
-	if err != nil {
+	var oldState, requestedState, requestedBy string
+	err := s.db.QueryRowContext(ctx, query, state, event.URL, event.PodName, time.Now(), event.SessionID).
+		Scan(&oldState, &requestedState, &requestedBy)
+	switch {
+	case err == sql.ErrNoRows:
+		log.Printf("Session %s not found in database (may not be created yet)", event.SessionID)
+	case err != nil:
 		log.Printf("Failed to update session %s status: %v", event.SessionID, err)
 		return
+	default:
+		log.Printf("Updated session %s to state=%s url=%s", event.SessionID, state, event.URL)
+		if oldState != state {
+			s.auditStateChange(ctx, &event, oldState, state, requestedState, requestedBy)
+		}
+		// An ended session frees a slot of its template's user session
+		// limit for the next queued launch (user_session_limit.go)
+		if oldState != state && (sessionEnded(state) || event.Status == StatusDeleted) {
+			s.releaseHeldSessionCreate(event.SessionID)
+		}
 	}
 
-	rows, _ := result.RowsAffected()
-	if rows == 0 {
-		log.Printf("Session %s not found in database (may not be created yet)", event.SessionID)
-	} else {
-		log.Printf("Updated session %s to state=%s url=%s", event.SessionID, state, event.URL)
+	// A crash-looping, evicted or failed session is otherwise just a broken
+	// screen; tell the owner why it went away (or never came up)
+	if event.Phase == "CrashLooping" || event.Phase == "Evicted" || event.Phase == "Failed" {
+		s.notifySessionError(ctx, event.SessionID, event.Message)
+	}
+
+	// Automation wants one callback when the session is ready, terminated
+	// or failed, not the stream of repeated status reports
+	s.dispatchLifecycleWebhook(ctx, &event)
+
+	// A deleted session reports nothing more, so its lifecycle state is done
+	if event.Status == StatusDeleted {
+		if err := ForgetSessionLifecycle(ctx, s.db, event.SessionID); err != nil {
+			log.Printf("Failed to delete lifecycle state of session %s: %v", event.SessionID, err)
+		}
+	}
+
+	// Host port mappings change whenever a Docker container restarts, so
+	// replace them on every report that includes them
+	if len(event.ForwardedPorts) > 0 {
+		ports, err := json.Marshal(event.ForwardedPorts)
+		if err != nil {
+			log.Printf("Failed to marshal forwarded ports for session %s: %v", event.SessionID, err)
+			return
+		}
+		if _, err := s.db.ExecContext(ctx, `UPDATE sessions SET forwarded_ports = $1 WHERE id = $2`, ports, event.SessionID); err != nil {
+			log.Printf("Failed to update forwarded ports for session %s: %v", event.SessionID, err)
+		}
+	}
+}
+
+// auditStateChange records who caused a reported state change: the actor
+// the controller sent, else whoever requested the new state, else the
+// controller itself. A satisfied request is cleared.
+func (s *Subscriber) auditStateChange(ctx context.Context, event *SessionStatusEvent, oldState, state, requestedState, requestedBy string) {
+	satisfied := requestedState != "" && satisfiesStateRequest(requestedState, state)
+	// Draining before hibernation is on the way to the requested state; the
+	// request is cleared once the session has hibernated
+	draining := requestedState == "hibernated" && state == "hibernating"
+	actor := event.Actor
+	if actor == "" && (satisfied || draining) {
+		actor = requestedBy
+	}
+	if actor == "" {
+		actor = ControllerActor(event.ControllerID)
+	}
+
+	if err := RecordSessionStateChange(ctx, s.db, event.SessionID, oldState, state, actor, event.Message); err != nil {
+		log.Printf("Failed to audit state change of session %s: %v", event.SessionID, err)
+	}
+	if satisfied {
+		if _, err := s.db.ExecContext(ctx, `
+			UPDATE sessions SET requested_state = NULL, requested_by = NULL
+			WHERE id = $1 AND requested_state = $2
+		`, event.SessionID, requestedState); err != nil {
+			log.Printf("Failed to clear state request of session %s: %v", event.SessionID, err)
+		}
 	}
 }
 
+// notifySessionError reports a session error to the session owner.
+func (s *Subscriber) notifySessionError(ctx context.Context, sessionID, message string) {
+	s.notifierMu.RLock()
+	notifier := s.errorNotifier
+	s.notifierMu.RUnlock()
+	if notifier == nil {
+		return
+	}
+
+	var userID string
+	if err := s.db.QueryRowContext(ctx, `SELECT user_id FROM sessions WHERE id = $1`, sessionID).Scan(&userID); err != nil {
+		log.Printf("Failed to look up owner of session %s: %v", sessionID, err)
+		return
+	}
+	notifier.NotifySessionError(sessionID, userID, message)
+}
+
 // handleAppStatus processes application installation status events from controllers.
 func (s *Subscriber) handleAppStatus(data []byte) {
 	var event AppStatusEvent
@@ -232,13 +385,37 @@ func (s *Subscriber) handleControllerHeartbeat(data []byte) {
 	log.Printf("Controller heartbeat: id=%s platform=%s status=%s",
 		event.ControllerID, event.Platform, event.Status)
 
-	// Could update a controllers table here to track controller health
-	// For now, just log it
+	s.controllers.Record(event)
+
+	if s.db == nil {
+		return
+	}
+	defer s.replayQueuedSessionCreates(event.ControllerID, event.Platform)
+	capabilities, err := json.Marshal(event.Capabilities)
+	if err != nil {
+		return
+	}
+	clusterInfo, err := json.Marshal(event.ClusterInfo)
+	if err != nil || event.ClusterInfo == nil {
+		clusterInfo = []byte("{}")
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO platform_controllers (id, controller_id, platform, status, version, capabilities, cluster_info, last_heartbeat)
+		VALUES ($1, $1, $2, $3, $4, $5, $6, NOW())
+		ON CONFLICT (controller_id) DO UPDATE SET
+			platform = EXCLUDED.platform, status = EXCLUDED.status, version = EXCLUDED.version,
+			capabilities = EXCLUDED.capabilities, cluster_info = EXCLUDED.cluster_info,
+			last_heartbeat = NOW(), updated_at = NOW()
+	`, event.ControllerID, event.Platform, event.Status, event.Version, string(capabilities), string(clusterInfo))
+	if err != nil {
+		log.Printf("Failed to record heartbeat of controller %s: %v", event.ControllerID, err)
+	}
 }
 
 // handleControllerSyncRequest processes sync requests from controllers.
 // It queries the database for installed applications and publishes AppInstallEvent
-// for each one so the controller can create the necessary resources.
+// for each one so the controller can create the necessary resources, then
+// replays any session creates queued while no controller was available.
 func (s *Subscriber) handleControllerSyncRequest(data []byte) {
 	var event ControllerSyncRequestEvent
 	if err := json.Unmarshal(data, &event); err != nil {
@@ -330,4 +507,6 @@ func (s *Subscriber) handleControllerSyncRequest(data []byte) {
 	}
 
 	log.Printf("Sync complete: sent %d app install events to controller %s", count, event.ControllerID)
+
+	s.replayQueuedSessionCreates(event.ControllerID, event.Platform)
 }
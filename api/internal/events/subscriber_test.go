@@ -0,0 +1,398 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedSessionError struct {
+	sessionID, userID, message string
+}
+
+type fakeSessionErrorNotifier struct {
+	errors []recordedSessionError
+}
+
+func (f *fakeSessionErrorNotifier) NotifySessionError(sessionID, userID string, errorMsg string) {
+	f.errors = append(f.errors, recordedSessionError{sessionID, userID, errorMsg})
+}
+
+// previousSessionState is the row the session status update returns: the
+// state before the update and the pending state request.
+func previousSessionState(state, requestedState, requestedBy string) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"state", "requested_state", "requested_by"}).
+		AddRow(state, requestedState, requestedBy)
+}
+
+// Test that crash-looping sessions are reported to their owner
+func TestHandleSessionStatus_CrashLoopingNotifiesOwner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	notifier := &fakeSessionErrorNotifier{}
+	s := &Subscriber{db: db, enabled: true}
+	s.SetSessionErrorNotifier(notifier)
+
+	mock.ExpectQuery("UPDATE sessions").
+		WithArgs("crashlooping", "", "ss-pod", sqlmock.AnyArg(), "sess-1").
+		WillReturnRows(previousSessionState("crashlooping", "", ""))
+	mock.ExpectQuery("SELECT user_id FROM sessions").
+		WithArgs("sess-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice"))
+
+	data, err := json.Marshal(SessionStatusEvent{
+		SessionID: "sess-1",
+		Status:    "crashlooping",
+		Phase:     "CrashLooping",
+		PodName:   "ss-pod",
+		Message:   "Session container restarted 3 times in 10m0s: OOMKilled (exit code 137)",
+	})
+	require.NoError(t, err)
+
+	s.handleSessionStatus(data)
+
+	require.Len(t, notifier.errors, 1)
+	assert.Equal(t, "sess-1", notifier.errors[0].sessionID)
+	assert.Equal(t, "alice", notifier.errors[0].userID)
+	assert.Contains(t, notifier.errors[0].message, "OOMKilled")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test that healthy status updates don't produce session errors
+func TestHandleSessionStatus_RunningDoesNotNotify(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	notifier := &fakeSessionErrorNotifier{}
+	s := &Subscriber{db: db, enabled: true}
+	s.SetSessionErrorNotifier(notifier)
+
+	mock.ExpectQuery("UPDATE sessions").WillReturnRows(previousSessionState("running", "", ""))
+
+	data, err := json.Marshal(SessionStatusEvent{SessionID: "sess-1", Status: "running", Phase: "Running"})
+	require.NoError(t, err)
+
+	s.handleSessionStatus(data)
+
+	assert.Empty(t, notifier.errors)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test that evicted sessions are reported to their owner
+func TestHandleSessionStatus_EvictedNotifiesOwner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	notifier := &fakeSessionErrorNotifier{}
+	s := &Subscriber{db: db, enabled: true}
+	s.SetSessionErrorNotifier(notifier)
+
+	mock.ExpectQuery("UPDATE sessions").WillReturnRows(previousSessionState("evicted", "", ""))
+	mock.ExpectQuery("SELECT user_id FROM sessions").
+		WithArgs("sess-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice"))
+
+	data, err := json.Marshal(SessionStatusEvent{
+		SessionID: "sess-1",
+		Status:    "evicted",
+		Phase:     "Evicted",
+		Message:   "Session pod was disrupted (Evicted): The node was low on resource: memory. Rescheduling the session.",
+	})
+	require.NoError(t, err)
+
+	s.handleSessionStatus(data)
+
+	require.Len(t, notifier.errors, 1)
+	assert.Equal(t, "alice", notifier.errors[0].userID)
+	assert.Contains(t, notifier.errors[0].message, "low on resource")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test that a session that never started is reported to its owner
+func TestHandleSessionStatus_FailedNotifiesOwner(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	notifier := &fakeSessionErrorNotifier{}
+	s := &Subscriber{db: db, enabled: true}
+	s.SetSessionErrorNotifier(notifier)
+
+	mock.ExpectQuery("UPDATE sessions").WillReturnRows(previousSessionState("failed", "", ""))
+	mock.ExpectQuery("SELECT user_id FROM sessions").
+		WithArgs("sess-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice"))
+
+	data, err := json.Marshal(SessionStatusEvent{
+		SessionID: "sess-1",
+		Status:    "failed",
+		Phase:     "Failed",
+		Message:   "Session did not become ready within 15m0s: container session: image registry.example.com/app:v2 could not be pulled (ImagePullBackOff)",
+	})
+	require.NoError(t, err)
+
+	s.handleSessionStatus(data)
+
+	require.Len(t, notifier.errors, 1)
+	assert.Equal(t, "alice", notifier.errors[0].userID)
+	assert.Contains(t, notifier.errors[0].message, "ImagePullBackOff")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+type recordedLifecycleEvent struct {
+	event string
+	data  map[string]interface{}
+}
+
+type fakeLifecycleDispatcher struct {
+	events []recordedLifecycleEvent
+}
+
+func (f *fakeLifecycleDispatcher) DispatchSessionLifecycle(_ context.Context, event string, data map[string]interface{}) {
+	f.events = append(f.events, recordedLifecycleEvent{event, data})
+}
+
+func boolPtr(b bool) *bool { return &b }
+
+// Test the status to lifecycle state mapping
+func TestLifecycleState(t *testing.T) {
+	assert.Equal(t, LifecycleSessionReady, lifecycleState(&SessionStatusEvent{Phase: "Running", Ready: boolPtr(true)}))
+	assert.Equal(t, "starting", lifecycleState(&SessionStatusEvent{Phase: "Running", Ready: boolPtr(false)}))
+	assert.Equal(t, LifecycleSessionReady, lifecycleState(&SessionStatusEvent{Phase: "Running"}))
+	assert.Equal(t, LifecycleSessionTerminated, lifecycleState(&SessionStatusEvent{Phase: "Terminated"}))
+	assert.Equal(t, LifecycleSessionFailed, lifecycleState(&SessionStatusEvent{Phase: "Failed"}))
+	assert.Equal(t, LifecycleSessionHibernating, lifecycleState(&SessionStatusEvent{Phase: "Hibernating"}))
+	assert.Equal(t, "hibernated", lifecycleState(&SessionStatusEvent{Phase: "Hibernated"}))
+}
+
+// Test that session.ready fires on the transition with the final URL
+func TestHandleSessionStatus_ReadyTransitionDispatchesWebhook(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	dispatcher := &fakeLifecycleDispatcher{}
+	s := &Subscriber{db: db, enabled: true}
+	s.SetLifecycleDispatcher(dispatcher)
+
+	mock.ExpectQuery("UPDATE sessions").WillReturnRows(previousSessionState("running", "", ""))
+	mock.ExpectExec("INSERT INTO session_lifecycle_states").
+		WithArgs("sess-1", LifecycleSessionReady, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT user_id, template_name FROM sessions").
+		WithArgs("sess-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "template_name"}).AddRow("alice", "firefox"))
+
+	data, err := json.Marshal(SessionStatusEvent{
+		SessionID: "sess-1",
+		Phase:     "Running",
+		URL:       "https://sess-1.example.com",
+		Ready:     boolPtr(true),
+	})
+	require.NoError(t, err)
+
+	s.handleSessionStatus(data)
+
+	require.Len(t, dispatcher.events, 1)
+	assert.Equal(t, LifecycleSessionReady, dispatcher.events[0].event)
+	assert.Equal(t, "https://sess-1.example.com", dispatcher.events[0].data["url"])
+	assert.Equal(t, "alice", dispatcher.events[0].data["user_id"])
+	assert.Equal(t, "firefox", dispatcher.events[0].data["template"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test that repeated reports of the same state don't fire again
+func TestHandleSessionStatus_RepeatedStateIsDeduped(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	dispatcher := &fakeLifecycleDispatcher{}
+	s := &Subscriber{db: db, enabled: true}
+	s.SetLifecycleDispatcher(dispatcher)
+
+	mock.ExpectQuery("UPDATE sessions").WillReturnRows(previousSessionState("failed", "", ""))
+	// State unchanged: the conditional upsert touches no rows
+	mock.ExpectExec("INSERT INTO session_lifecycle_states").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	data, err := json.Marshal(SessionStatusEvent{SessionID: "sess-1", Phase: "Failed", Message: "ImagePullBackOff"})
+	require.NoError(t, err)
+
+	s.handleSessionStatus(data)
+
+	assert.Empty(t, dispatcher.events)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test that a terminated transition prunes the states of ended sessions
+func TestHandleSessionStatus_TerminatedPrunesLifecycleStates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	dispatcher := &fakeLifecycleDispatcher{}
+	s := &Subscriber{db: db, enabled: true}
+	s.SetLifecycleDispatcher(dispatcher)
+
+	mock.ExpectQuery("UPDATE sessions").WillReturnRows(previousSessionState("terminated", "", ""))
+	mock.ExpectExec("INSERT INTO session_lifecycle_states").
+		WithArgs("sess-1", LifecycleSessionTerminated, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM session_lifecycle_states").
+		WithArgs(sqlmock.AnyArg(), LifecycleSessionTerminated).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectQuery("SELECT user_id, template_name FROM sessions").
+		WithArgs("sess-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "template_name"}).AddRow("alice", "firefox"))
+
+	data, err := json.Marshal(SessionStatusEvent{SessionID: "sess-1", Phase: "Terminated"})
+	require.NoError(t, err)
+
+	s.handleSessionStatus(data)
+
+	require.Len(t, dispatcher.events, 1)
+	assert.Equal(t, LifecycleSessionTerminated, dispatcher.events[0].event)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test that a deleted session's lifecycle state is removed
+func TestHandleSessionStatus_DeletedForgetsLifecycleState(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := &Subscriber{db: db, enabled: true}
+
+	// Not in the database: the update returns no row
+	mock.ExpectQuery("UPDATE sessions").WillReturnRows(sqlmock.NewRows([]string{"state", "requested_state", "requested_by"}))
+	mock.ExpectExec("DELETE FROM session_lifecycle_states WHERE session_id").
+		WithArgs("sess-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	data, err := json.Marshal(SessionStatusEvent{SessionID: "sess-1", Status: StatusDeleted})
+	require.NoError(t, err)
+
+	s.handleSessionStatus(data)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test that a template turning invalid notifies every admin
+func TestHandleTemplateStatus_InvalidNotifiesAdmins(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := &Subscriber{db: db, enabled: true}
+
+	mock.ExpectExec("INSERT INTO notifications").
+		WithArgs("evt-1", "template.invalid", "Template firefox is invalid",
+			"base image lscr.io/linuxserver/firefox:old no longer exists in its registry", sqlmock.AnyArg(), "high").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	data, err := json.Marshal(TemplateStatusEvent{
+		EventID:      "evt-1",
+		TemplateName: "firefox",
+		Namespace:    "streamspace",
+		Valid:        false,
+		Reason:       "ImageNotFound",
+		Message:      "base image lscr.io/linuxserver/firefox:old no longer exists in its registry",
+	})
+	require.NoError(t, err)
+
+	s.handleTemplateStatus(data)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test that a requested transition is audited with the requesting user
+func TestHandleSessionStatus_AuditsRequestedTransition(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	s := &Subscriber{db: db, enabled: true}
+
+	mock.ExpectQuery("UPDATE sessions").
+		WithArgs("hibernated", "", "", sqlmock.AnyArg(), "sess-1").
+		WillReturnRows(previousSessionState("running", "hibernated", "alice"))
+	mock.ExpectExec("INSERT INTO audit_log").
+		WithArgs("alice", AuditActionSessionStateChange, "session", "sess-1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("UPDATE sessions SET requested_state = NULL").
+		WithArgs("sess-1", "hibernated").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	data, err := json.Marshal(SessionStatusEvent{SessionID: "sess-1", Phase: "Hibernated", ControllerID: "k8s-1"})
+	require.NoError(t, err)
+
+	s.handleSessionStatus(data)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test that transitions nobody requested are attributed to the controller,
+// or to the actor it reports
+func TestHandleSessionStatus_AuditsControllerTransition(t *testing.T) {
+	tests := []struct {
+		name      string
+		event     SessionStatusEvent
+		requested string
+		wantActor string
+	}{
+		{
+			name:      "idle hibernation",
+			event:     SessionStatusEvent{SessionID: "sess-1", Phase: "Hibernated", ControllerID: "k8s-1", Actor: ActorIdleReconciler},
+			wantActor: ActorIdleReconciler,
+		},
+		{
+			name:      "crash while a wake is pending",
+			event:     SessionStatusEvent{SessionID: "sess-1", Phase: "Failed", ControllerID: "k8s-1"},
+			requested: "running",
+			wantActor: "controller:k8s-1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer db.Close()
+
+			s := &Subscriber{db: db, enabled: true}
+
+			requestedBy := ""
+			if tt.requested != "" {
+				requestedBy = "alice"
+			}
+			mock.ExpectQuery("UPDATE sessions").
+				WillReturnRows(previousSessionState("running", tt.requested, requestedBy))
+			mock.ExpectExec("INSERT INTO audit_log").
+				WithArgs(tt.wantActor, AuditActionSessionStateChange, "session", "sess-1", sqlmock.AnyArg(), sqlmock.AnyArg()).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			data, err := json.Marshal(tt.event)
+			require.NoError(t, err)
+
+			s.handleSessionStatus(data)
+
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// Test which reported states satisfy a state request
+func TestSatisfiesStateRequest(t *testing.T) {
+	assert.True(t, satisfiesStateRequest("hibernated", "hibernated"))
+	assert.True(t, satisfiesStateRequest("terminated", StatusDeleted))
+	assert.False(t, satisfiesStateRequest("running", "failed"))
+	assert.False(t, satisfiesStateRequest("hibernated", "running"))
+}
@@ -0,0 +1,53 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// handleTemplateStatus notifies admins when a controller's periodic
+// revalidation finds a template broken (e.g. its base image was deleted
+// from the registry), so it can be fixed before users' launches fail, and
+// when it recovers.
+func (s *Subscriber) handleTemplateStatus(data []byte) {
+	var event TemplateStatusEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		log.Printf("Failed to unmarshal template status event: %v", err)
+		return
+	}
+
+	log.Printf("Received template status: template=%s/%s valid=%t reason=%s",
+		event.Namespace, event.TemplateName, event.Valid, event.Reason)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	notifType, priority := "template.invalid", "high"
+	title := fmt.Sprintf("Template %s is invalid", event.TemplateName)
+	if event.Valid {
+		notifType, priority = "template.valid", "normal"
+		title = fmt.Sprintf("Template %s is valid again", event.TemplateName)
+	}
+	details, _ := json.Marshal(map[string]interface{}{
+		"template_name":     event.TemplateName,
+		"namespace":         event.Namespace,
+		"reason":            event.Reason,
+		"last_validated_at": event.LastValidatedAt,
+	})
+
+	// One in-app notification per active admin
+	result, err := s.db.ExecContext(ctx, `
+		INSERT INTO notifications (id, user_id, type, title, message, data, priority)
+		SELECT 'notif_' || $1 || '_' || id, id, $2, $3, $4, $5, $6
+		FROM users WHERE role = 'admin' AND active = true
+	`, event.EventID, notifType, title, event.Message, details, priority)
+	if err != nil {
+		log.Printf("Failed to notify admins about template %s: %v", event.TemplateName, err)
+		return
+	}
+	rows, _ := result.RowsAffected()
+	log.Printf("Notified %d admins about template %s (%s)", rows, event.TemplateName, notifType)
+}
@@ -23,6 +23,23 @@ type SessionCreateEvent struct {
 	PersistentHome bool              `json:"persistent_home"`
 	IdleTimeout    string            `json:"idle_timeout"`
 	Metadata       map[string]string `json:"metadata,omitempty"`
+	// Parameters are the values for the template's parameters, validated
+	// against its schema (see k8s.ValidateParameters)
+	Parameters map[string]string `json:"parameters,omitempty"`
+	// Priority is the session's priority class (empty = template default)
+	Priority string `json:"priority,omitempty"`
+	// MaxSessionDuration is the session's maximum lifetime (empty = template default)
+	MaxSessionDuration string `json:"max_session_duration,omitempty"`
+	// Tags are the session's tags, including those added by plugins
+	Tags []string `json:"tags,omitempty"`
+	// Labels are set on the Session resource (added by plugin before-hooks)
+	Labels map[string]string `json:"labels,omitempty"`
+	// ForkFrom is the session (of the same user) whose home volume is
+	// forked into the new session's home volume
+	ForkFrom string `json:"fork_from,omitempty"`
+	// TargetController is the controller chosen for the session's
+	// requirements (empty = any controller of the platform)
+	TargetController string `json:"target_controller,omitempty"`
 	// Template configuration - used by controllers to create sessions
 	TemplateConfig *TemplateConfig `json:"template_config,omitempty"`
 }
@@ -33,6 +50,25 @@ type TemplateConfig struct {
 	VNCPort     int               `json:"vnc_port"`
 	DisplayName string            `json:"display_name,omitempty"`
 	Env         map[string]string `json:"env,omitempty"`
+	// ForwardPorts are container ports the controller should make reachable
+	// for the port-forward endpoint (Docker publishes them on the host)
+	ForwardPorts []int `json:"forward_ports,omitempty"`
+	// SupportedBackends are the platforms the template can run on (empty = all)
+	SupportedBackends []string `json:"supported_backends,omitempty"`
+}
+
+// SupportsPlatform returns true if the template can run on the platform.
+// Templates that don't declare SupportedBackends run everywhere.
+func (t *TemplateConfig) SupportsPlatform(platform string) bool {
+	if t == nil || len(t.SupportedBackends) == 0 {
+		return true
+	}
+	for _, backend := range t.SupportedBackends {
+		if backend == platform {
+			return true
+		}
+	}
+	return false
 }
 
 // SessionDeleteEvent is published when a session should be deleted.
@@ -43,6 +79,14 @@ type SessionDeleteEvent struct {
 	UserID    string    `json:"user_id"`
 	Platform  string    `json:"platform"`
 	Force     bool      `json:"force"`
+
+	// Cancel marks the delete of a session that is still starting: the
+	// controller aborts its create and removes what it already made.
+	Cancel bool `json:"cancel,omitempty"`
+
+	// TargetController is the controller the session is pinned to
+	// (empty = any controller of the platform)
+	TargetController string `json:"target_controller,omitempty"`
 }
 
 // SessionHibernateEvent is published when a session should be hibernated.
@@ -52,6 +96,9 @@ type SessionHibernateEvent struct {
 	SessionID string    `json:"session_id"`
 	UserID    string    `json:"user_id"`
 	Platform  string    `json:"platform"`
+	// TargetController is the controller the session is pinned to
+	// (empty = any controller of the platform)
+	TargetController string `json:"target_controller,omitempty"`
 }
 
 // SessionWakeEvent is published when a hibernated session should be woken.
@@ -61,6 +108,47 @@ type SessionWakeEvent struct {
 	SessionID string    `json:"session_id"`
 	UserID    string    `json:"user_id"`
 	Platform  string    `json:"platform"`
+	// TargetController is the controller the session is pinned to
+	// (empty = any controller of the platform)
+	TargetController string `json:"target_controller,omitempty"`
+}
+
+// SessionBatchEvent carries several session commands to one controller,
+// which runs them and acknowledges each in EventAck.Results.
+type SessionBatchEvent struct {
+	EventID   string           `json:"event_id"`
+	Timestamp time.Time        `json:"timestamp"`
+	Platform  string           `json:"platform"`
+	Commands  []SessionCommand `json:"commands"`
+}
+
+// SessionCommand is one command of a SessionBatchEvent. Its EventID is the
+// one the command would have as an individual event, so a command resent
+// on its own after a lost batch ack isn't executed twice.
+type SessionCommand struct {
+	EventID   string `json:"event_id"`
+	Action    string `json:"action"` // session.hibernate, session.wake or session.delete
+	SessionID string `json:"session_id"`
+	UserID    string `json:"user_id"`
+	Force     bool   `json:"force,omitempty"`
+}
+
+// CommandResult is a controller's result for one command of a batch.
+type CommandResult struct {
+	EventID   string `json:"event_id"`
+	SessionID string `json:"session_id"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
+// EventAck is a controller's reply to an event sent with request/reply.
+type EventAck struct {
+	EventID      string `json:"event_id"`
+	ControllerID string `json:"controller_id"`
+	Success      bool   `json:"success"`
+	Error        string `json:"error,omitempty"`
+	// Results are the per-command results of a SessionBatchEvent
+	Results []CommandResult `json:"results,omitempty"`
 }
 
 // SessionStatusEvent is published by controllers when session status changes.
@@ -75,6 +163,15 @@ type SessionStatusEvent struct {
 	Message       string        `json:"message,omitempty"`
 	ResourceUsage *ResourceSpec `json:"resource_usage,omitempty"`
 	ControllerID  string        `json:"controller_id"`
+	// ForwardedPorts maps container port to published host port
+	// (Docker only; Kubernetes forwards through the API server)
+	ForwardedPorts map[string]int `json:"forwarded_ports,omitempty"`
+	// Ready reports whether a Running session is serving (unset by
+	// controllers that don't report readiness)
+	Ready *bool `json:"ready,omitempty"`
+	// Actor is who made the controller change the state on its own, e.g.
+	// "reconciler:idle" (see RecordSessionStateChange)
+	Actor string `json:"actor,omitempty"`
 }
 
 // AppInstallEvent is published when an application should be installed.
@@ -114,6 +211,19 @@ type AppStatusEvent struct {
 	ControllerID      string    `json:"controller_id"`
 }
 
+// TemplateStatusEvent is published by controllers when a template turns
+// invalid on revalidation (its image or secrets disappeared) or valid again.
+type TemplateStatusEvent struct {
+	EventID         string    `json:"event_id"`
+	Timestamp       time.Time `json:"timestamp"`
+	TemplateName    string    `json:"template_name"`
+	Namespace       string    `json:"namespace"`
+	Valid           bool      `json:"valid"`
+	Reason          string    `json:"reason"`
+	Message         string    `json:"message"`
+	LastValidatedAt time.Time `json:"last_validated_at"`
+}
+
 // TemplateCreateEvent is published when a template is created.
 type TemplateCreateEvent struct {
 	EventID     string    `json:"event_id"`
@@ -167,7 +277,7 @@ type ControllerHeartbeatEvent struct {
 	Timestamp    time.Time              `json:"timestamp"`
 	Status       string                 `json:"status"` // healthy, unhealthy
 	Version      string                 `json:"version"`
-	Capabilities []string               `json:"capabilities"`
+	Capabilities ControllerCapabilities `json:"capabilities"`
 	ClusterInfo  map[string]interface{} `json:"cluster_info,omitempty"`
 }
 
@@ -0,0 +1,160 @@
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Templates may cap how many sessions each user runs at once (a licensed
+// application with per-seat limits). When the template queues launches
+// beyond the cap, the create is held in pending_session_creates instead of
+// being dispatched. Held creates are not replayed to controllers; when one
+// of the user's sessions of the template ends, the oldest held create is
+// released if the cap allows it and published like a replayed create.
+
+// activeTemplateSessionsQuery counts the sessions of template $2 that hold
+// one of user $1's slots: launched, not ended, and not held themselves.
+const activeTemplateSessionsQuery = `
+	SELECT COUNT(*) FROM sessions
+	WHERE user_id = $1 AND template_name = $2
+	  AND state NOT IN ('terminated', 'deleted', 'failed')
+	  AND id NOT IN (SELECT session_id FROM pending_session_creates WHERE held)`
+
+// CountActiveTemplateSessions returns how many sessions of a template count
+// against the user's session limit for it.
+func CountActiveTemplateSessions(ctx context.Context, db *sql.DB, userID, templateName string) (int, error) {
+	if db == nil {
+		return 0, fmt.Errorf("no database to count sessions of %s", templateName)
+	}
+	var count int
+	if err := db.QueryRowContext(ctx, activeTemplateSessionsQuery, userID, templateName).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count %s sessions of user %s: %w", templateName, userID, err)
+	}
+	return count, nil
+}
+
+// HoldSessionCreate stores a session create until fewer than maxConcurrent
+// of the user's sessions of the template are active. It returns the
+// create's position among the user's held launches of the template.
+func HoldSessionCreate(ctx context.Context, db *sql.DB, event *SessionCreateEvent, maxConcurrent int32) (int, error) {
+	if db == nil {
+		return 0, fmt.Errorf("no database to queue session %s", event.SessionID)
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal session create event: %w", err)
+	}
+	if _, err := db.ExecContext(ctx, `
+		INSERT INTO pending_session_creates
+			(session_id, platform, target_controller, event, created_at, user_id, template_name, max_concurrent, held)
+		VALUES ($1, $2, $3, $4, NOW(), $5, $6, $7, true)
+	`, event.SessionID, event.Platform, event.TargetController, string(data),
+		event.UserID, event.TemplateID, maxConcurrent); err != nil {
+		return 0, fmt.Errorf("failed to queue session create for %s: %w", event.SessionID, err)
+	}
+
+	var position int
+	if err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM pending_session_creates
+		WHERE held AND user_id = $1 AND template_name = $2
+	`, event.UserID, event.TemplateID).Scan(&position); err != nil {
+		return 0, fmt.Errorf("failed to get queue position of session %s: %w", event.SessionID, err)
+	}
+	return position, nil
+}
+
+// claimHeldSessionCreate removes and returns the user's oldest held create
+// of a template if its limit now allows another session, nil otherwise.
+//
+// The advisory lock serializes claims for the same user and template, so
+// two sessions ending at once on different replicas can't both release a
+// create into a single free slot.
+func claimHeldSessionCreate(ctx context.Context, db *sql.DB, userID, templateName string) (*SessionCreateEvent, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1 || '/' || $2))`, userID, templateName); err != nil {
+		return nil, err
+	}
+
+	var sessionID, data string
+	err = tx.QueryRowContext(ctx, `
+		DELETE FROM pending_session_creates
+		WHERE session_id = (
+			SELECT session_id FROM pending_session_creates
+			WHERE held AND user_id = $1 AND template_name = $2
+			ORDER BY created_at
+			LIMIT 1
+		)
+		AND max_concurrent > (`+activeTemplateSessionsQuery+`)
+		RETURNING session_id, event
+	`, userID, templateName).Scan(&sessionID, &data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	var event SessionCreateEvent
+	if err := json.Unmarshal([]byte(data), &event); err != nil {
+		return nil, fmt.Errorf("dropping unreadable held session create for %s: %w", sessionID, err)
+	}
+	return &event, nil
+}
+
+// releaseHeldSessionCreate starts the next held launch of the template of a
+// session that just ended. A create that fails to publish is queued for
+// replay to the next controller that checks in.
+func (s *Subscriber) releaseHeldSessionCreate(sessionID string) {
+	if s.db == nil || s.publisher == nil || !s.publisher.enabled {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var userID, templateName sql.NullString
+	if err := s.db.QueryRowContext(ctx, `SELECT user_id, template_name FROM sessions WHERE id = $1`, sessionID).
+		Scan(&userID, &templateName); err != nil {
+		return
+	}
+
+	event, err := claimHeldSessionCreate(ctx, s.db, userID.String, templateName.String)
+	if err != nil {
+		log.Printf("Failed to release held session create of %s/%s: %v", userID.String, templateName.String, err)
+		return
+	}
+	if event == nil {
+		return
+	}
+
+	if err := s.publisher.PublishSessionCreate(ctx, event); err != nil {
+		log.Printf("Failed to publish released session create for %s: %v", event.SessionID, err)
+		if err := QueueSessionCreate(ctx, s.db, event); err != nil {
+			log.Printf("Lost released session create for %s: %v", event.SessionID, err)
+		}
+		return
+	}
+	log.Printf("Released held session create for %s after session %s ended", event.SessionID, sessionID)
+}
+
+// sessionEnded reports whether a session in state no longer counts against
+// its template's user session limit.
+func sessionEnded(state string) bool {
+	switch state {
+	case "terminated", "deleted", "failed":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Test that a held create is stored with its limit and reports its position
+func TestHoldSessionCreate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	event := &SessionCreateEvent{SessionID: "sess-2", UserID: "alice", TemplateID: "cad-pro", Platform: "kubernetes"}
+	data, err := json.Marshal(event)
+	require.NoError(t, err)
+
+	mock.ExpectExec("INSERT INTO pending_session_creates").
+		WithArgs("sess-2", "kubernetes", "", string(data), "alice", "cad-pro", int32(1)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery("SELECT COUNT").
+		WithArgs("alice", "cad-pro").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	position, err := HoldSessionCreate(context.Background(), db, event, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, position)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test that held launches don't count as active sessions
+func TestCountActiveTemplateSessions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM sessions(.|\n)*NOT IN \(SELECT session_id FROM pending_session_creates WHERE held\)`).
+		WithArgs("alice", "cad-pro").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	count, err := CountActiveTemplateSessions(context.Background(), db, "alice", "cad-pro")
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = CountActiveTemplateSessions(context.Background(), nil, "alice", "cad-pro")
+	assert.Error(t, err)
+}
+
+// Test that the oldest held create is claimed once the limit allows it
+func TestClaimHeldSessionCreate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	data, err := json.Marshal(&SessionCreateEvent{SessionID: "sess-2", UserID: "alice", TemplateID: "cad-pro"})
+	require.NoError(t, err)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("pg_advisory_xact_lock").
+		WithArgs("alice", "cad-pro").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("DELETE FROM pending_session_creates(.|\n)*max_concurrent >").
+		WithArgs("alice", "cad-pro").
+		WillReturnRows(sqlmock.NewRows([]string{"session_id", "event"}).AddRow("sess-2", string(data)))
+	mock.ExpectCommit()
+
+	event, err := claimHeldSessionCreate(context.Background(), db, "alice", "cad-pro")
+	require.NoError(t, err)
+	require.NotNil(t, event)
+	assert.Equal(t, "sess-2", event.SessionID)
+
+	// Still at the limit (or nothing held): nothing is claimed
+	mock.ExpectBegin()
+	mock.ExpectExec("pg_advisory_xact_lock").
+		WithArgs("alice", "cad-pro").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("DELETE FROM pending_session_creates").
+		WithArgs("alice", "cad-pro").
+		WillReturnRows(sqlmock.NewRows([]string{"session_id", "event"}))
+	mock.ExpectRollback()
+
+	event, err = claimHeldSessionCreate(context.Background(), db, "alice", "cad-pro")
+	require.NoError(t, err)
+	assert.Nil(t, event)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionEnded(t *testing.T) {
+	for _, state := range []string{"terminated", "deleted", "failed"} {
+		assert.True(t, sessionEnded(state), state)
+	}
+	for _, state := range []string{"running", "pending", "hibernated", "starting"} {
+		assert.False(t, sessionEnded(state), state)
+	}
+}
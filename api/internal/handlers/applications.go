@@ -41,6 +41,8 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 
@@ -92,6 +94,7 @@ func (h *ApplicationHandler) RegisterRoutes(router *gin.RouterGroup) {
 		apps.GET("/:id/groups", h.GetApplicationGroups)
 		apps.POST("/:id/groups", h.AddGroupAccess)
 		apps.PUT("/:id/groups/:groupId", h.UpdateGroupAccess)
+		apps.DELETE("/:id/groups", h.RemoveGroupsAccess)
 		apps.DELETE("/:id/groups/:groupId", h.RemoveGroupAccess)
 		apps.GET("/:id/config", h.GetTemplateConfig)
 	}
@@ -660,6 +663,64 @@ func (h *ApplicationHandler) RemoveGroupAccess(c *gin.Context) {
 	})
 }
 
+// RemoveGroupsAccess godoc
+// @Summary Remove several groups' access to an application
+// @Description Revoke access for a list of groups in one transaction. Either all listed groups lose access or, on error, none do.
+// @Tags applications
+// @Accept json
+// @Produce json
+// @Param id path string true "Application ID"
+// @Param request body models.RemoveGroupsAccessRequest true "Groups to remove"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Router /api/v1/applications/{id}/groups [delete]
+func (h *ApplicationHandler) RemoveGroupsAccess(c *gin.Context) {
+	appID := c.Param("id")
+
+	var req models.RemoveGroupsAccessRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "Invalid request",
+			Message: err.Error(),
+		})
+		return
+	}
+
+	removed, err := h.appDB.RemoveGroupsAccess(c.Request.Context(), appID, req.GroupIDs)
+	if err != nil {
+		response := gin.H{
+			"error":   "Failed to remove access",
+			"message": fmt.Sprintf("%v; no group access was changed", err),
+		}
+		var removalErr *db.GroupAccessRemovalError
+		if errors.As(err, &removalErr) {
+			response["failedGroupId"] = removalErr.GroupID
+		}
+		c.JSON(http.StatusInternalServerError, response)
+		return
+	}
+
+	// Report which of the requested groups actually had access
+	removedSet := make(map[string]bool, len(removed))
+	for _, groupID := range removed {
+		removedSet[groupID] = true
+	}
+	unchanged := []string{}
+	for _, groupID := range req.GroupIDs {
+		if !removedSet[groupID] {
+			unchanged = append(unchanged, groupID)
+			removedSet[groupID] = true // report duplicates once
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Group access removed successfully",
+		"removed":   removed,
+		"unchanged": unchanged,
+	})
+}
+
 // GetTemplateConfig godoc
 // @Summary Get application template configuration options
 // @Description Get the configurable options from the template manifest
@@ -0,0 +1,121 @@
+// Package handlers provides HTTP handlers for the StreamSpace API.
+// This file implements audit log diff rendering.
+//
+// Audit log listing and statistics are provided by the streamspace-audit
+// plugin. The core API renders the field-level diff of a single entry so any
+// UI can show before/after values without guessing the payload shape:
+//
+//	GET /api/v1/audit/:id/diff
+//
+// Response:
+//
+//	{
+//	  "entry": {"id": 42, "action": "PATCH", ...},
+//	  "diff": [
+//	    {"field": "role", "kind": "modified", "old": "user", "new": "admin",
+//	     "text": "role: \"user\" → \"admin\""}
+//	  ]
+//	}
+//
+// SECURITY:
+//
+// - Admin only (audit entries may contain other users' data)
+package handlers
+
+import (
+	"database/sql"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/audit"
+	"github.com/streamspace/streamspace/api/internal/db"
+)
+
+// AuditLogEntry is a row of the audit_log table with normalized changes.
+type AuditLogEntry struct {
+	ID           int64         `json:"id"`
+	UserID       string        `json:"userId"`
+	Action       string        `json:"action"`
+	ResourceType string        `json:"resourceType"`
+	ResourceID   string        `json:"resourceId"`
+	Changes      audit.Changes `json:"changes"`
+	Timestamp    time.Time     `json:"timestamp"`
+	IPAddress    string        `json:"ipAddress"`
+}
+
+// AuditHandler handles audit log diff requests
+type AuditHandler struct {
+	db *db.Database
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(database *db.Database) *AuditHandler {
+	return &AuditHandler{db: database}
+}
+
+// RegisterRoutes registers audit routes
+func (h *AuditHandler) RegisterRoutes(router *gin.RouterGroup) {
+	auditGroup := router.Group("/audit")
+	{
+		auditGroup.GET("/:id/diff", h.GetAuditDiff)
+	}
+}
+
+// GetAuditDiff returns the rendered field-level diff of an audit log entry
+func (h *AuditHandler) GetAuditDiff(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid audit log ID",
+			"message": "ID must be a number",
+		})
+		return
+	}
+
+	var entry AuditLogEntry
+	var userID, resourceType, resourceID, ipAddress sql.NullString
+	var changes []byte
+	err = h.db.DB().QueryRowContext(c.Request.Context(), `
+		SELECT id, user_id, action, resource_type, resource_id, changes, timestamp, ip_address
+		FROM audit_log
+		WHERE id = $1
+	`, id).Scan(&entry.ID, &userID, &entry.Action, &resourceType, &resourceID, &changes, &entry.Timestamp, &ipAddress)
+	if err == sql.ErrNoRows {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Audit log entry not found",
+			"message": "No audit log entry with ID " + c.Param("id"),
+		})
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to get audit log entry %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get audit log entry",
+			"message": err.Error(),
+		})
+		return
+	}
+	entry.UserID = userID.String
+	entry.ResourceType = resourceType.String
+	entry.ResourceID = resourceID.String
+	entry.IPAddress = ipAddress.String
+
+	// Rows written before the structured format are normalized on read too
+	entry.Changes, err = audit.ParseChanges(changes)
+	if err != nil {
+		log.Printf("Audit log entry %d has unreadable changes: %v", id, err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":   "Unreadable audit log changes",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entry": entry,
+		"diff":  audit.Render(entry.Changes),
+	})
+}
@@ -0,0 +1,263 @@
+// Package handlers - backup_codes.go
+//
+// This file implements MFA backup code generation and its admin policy.
+//
+// Backup codes are drawn uniformly from the base32 alphabet (A-Z, 2-7, 5 bits
+// per character) with crypto/rand, are unique within a batch, and are stored
+// as SHA-256 hashes of their canonical form (upper case, no separators), so
+// users may type them with or without dashes.
+//
+// Admins control how many codes are issued and how long they are:
+//
+//	GET /api/v1/security/mfa/backup-code-policy → current policy
+//	PUT /api/v1/security/mfa/backup-code-policy → update policy
+//
+// The policy is kept in the configuration table (security.mfaBackupCodeCount,
+// security.mfaBackupCodeLength, security.mfaBackupCodeGroupSize). Policies
+// with less than MinBackupCodeEntropyBits per code are rejected.
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// backupCodeAlphabet is the base32 alphabet; 32 symbols keep byte&31 unbiased.
+const backupCodeAlphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+
+// Configuration keys of the backup code policy.
+const (
+	configBackupCodeCount     = "security.mfaBackupCodeCount"
+	configBackupCodeLength    = "security.mfaBackupCodeLength"
+	configBackupCodeGroupSize = "security.mfaBackupCodeGroupSize"
+)
+
+// BackupCodePolicy controls the backup codes issued to users.
+type BackupCodePolicy struct {
+	// Count is the number of codes issued at once.
+	Count int `json:"count"`
+
+	// Length is the number of characters of each code, separators excluded.
+	Length int `json:"length"`
+
+	// GroupSize splits codes into dash-separated groups for readability
+	// (6 → "XXXXXX-XXXXXX"); 0 disables grouping.
+	GroupSize int `json:"group_size"`
+}
+
+// DefaultBackupCodePolicy returns the policy used until an admin sets one.
+func DefaultBackupCodePolicy() BackupCodePolicy {
+	return BackupCodePolicy{
+		Count:     BackupCodesCount,
+		Length:    BackupCodeLength,
+		GroupSize: BackupCodeGroupSize,
+	}
+}
+
+// EntropyBits returns the entropy of a single code.
+func (p BackupCodePolicy) EntropyBits() int {
+	return p.Length * 5
+}
+
+// Validate checks the policy's bounds and minimum entropy.
+func (p BackupCodePolicy) Validate() error {
+	if p.Count < MinBackupCodesCount || p.Count > MaxBackupCodesCount {
+		return fmt.Errorf("count must be between %d and %d", MinBackupCodesCount, MaxBackupCodesCount)
+	}
+	if p.Length < 1 || p.Length > MaxBackupCodeLength {
+		return fmt.Errorf("length must be between 1 and %d", MaxBackupCodeLength)
+	}
+	if p.EntropyBits() < MinBackupCodeEntropyBits {
+		return fmt.Errorf("codes of length %d have %d bits of entropy, at least %d are required (length %d or more)",
+			p.Length, p.EntropyBits(), MinBackupCodeEntropyBits, (MinBackupCodeEntropyBits+4)/5)
+	}
+	if p.GroupSize < 0 || p.GroupSize > p.Length {
+		return fmt.Errorf("group_size must be between 0 and length")
+	}
+	return nil
+}
+
+// format inserts dashes between groups of a canonical code.
+func (p BackupCodePolicy) format(code string) string {
+	if p.GroupSize <= 0 || p.GroupSize >= len(code) {
+		return code
+	}
+	var b strings.Builder
+	for i := 0; i < len(code); i += p.GroupSize {
+		if i > 0 {
+			b.WriteByte('-')
+		}
+		end := i + p.GroupSize
+		if end > len(code) {
+			end = len(code)
+		}
+		b.WriteString(code[i:end])
+	}
+	return b.String()
+}
+
+// generateBackupCodeSet returns count unique formatted codes and the hashes
+// to store for them.
+func generateBackupCodeSet(policy BackupCodePolicy) (codes, hashes []string, err error) {
+	seen := make(map[string]bool, policy.Count)
+	buf := make([]byte, policy.Length)
+	for len(codes) < policy.Count {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, fmt.Errorf("failed to read random bytes: %w", err)
+		}
+		canonical := make([]byte, policy.Length)
+		for i, b := range buf {
+			canonical[i] = backupCodeAlphabet[b&31]
+		}
+		code := string(canonical)
+		if seen[code] {
+			continue
+		}
+		seen[code] = true
+		codes = append(codes, policy.format(code))
+		hashes = append(hashes, hashBackupCode(code))
+	}
+	return codes, hashes, nil
+}
+
+// hashBackupCode returns the stored hash of a code as the user typed it.
+func hashBackupCode(code string) string {
+	canonical := strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(code))
+	hash := sha256.Sum256([]byte(canonical))
+	return hex.EncodeToString(hash[:])
+}
+
+// loadBackupCodePolicy reads the policy from the configuration table,
+// falling back to the default for missing or invalid settings.
+func (h *SecurityHandler) loadBackupCodePolicy(ctx context.Context) BackupCodePolicy {
+	policy := DefaultBackupCodePolicy()
+
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT key, value FROM configuration WHERE key IN ($1, $2, $3)
+	`, configBackupCodeCount, configBackupCodeLength, configBackupCodeGroupSize)
+	if err != nil {
+		return policy
+	}
+	defer rows.Close()
+
+	configured := policy
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return policy
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			continue
+		}
+		switch key {
+		case configBackupCodeCount:
+			configured.Count = n
+		case configBackupCodeLength:
+			configured.Length = n
+		case configBackupCodeGroupSize:
+			configured.GroupSize = n
+		}
+	}
+	if err := configured.Validate(); err != nil {
+		log.Printf("Ignoring invalid MFA backup code policy: %v", err)
+		return policy
+	}
+	return configured
+}
+
+// GetBackupCodePolicy returns the backup code policy (admin only).
+func (h *SecurityHandler) GetBackupCodePolicy(c *gin.Context) {
+	if c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	policy := h.loadBackupCodePolicy(c.Request.Context())
+	c.JSON(http.StatusOK, gin.H{
+		"policy":       policy,
+		"entropy_bits": policy.EntropyBits(),
+	})
+}
+
+// UpdateBackupCodePolicy sets the backup code policy (admin only). Codes
+// already issued keep working.
+func (h *SecurityHandler) UpdateBackupCodePolicy(c *gin.Context) {
+	if c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+		return
+	}
+
+	var policy BackupCodePolicy
+	if err := c.ShouldBindJSON(&policy); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := policy.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid backup code policy",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	tx, err := h.DB.BeginTx(c.Request.Context(), nil)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update backup code policy"})
+		return
+	}
+	defer tx.Rollback()
+
+	updatedBy := c.GetString("userID")
+	for key, value := range map[string]int{
+		configBackupCodeCount:     policy.Count,
+		configBackupCodeLength:    policy.Length,
+		configBackupCodeGroupSize: policy.GroupSize,
+	} {
+		if _, err := tx.Exec(`
+			INSERT INTO configuration (key, value, type, category, description, updated_at, updated_by)
+			VALUES ($1, $2, 'int', 'security', 'MFA backup code policy', NOW(), $3)
+			ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value, updated_at = NOW(), updated_by = EXCLUDED.updated_by
+		`, key, strconv.Itoa(value), updatedBy); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to update backup code policy",
+				"message": fmt.Sprintf("Database update failed for %s: %v", key, err),
+			})
+			return
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update backup code policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"policy":       policy,
+		"entropy_bits": policy.EntropyBits(),
+	})
+}
+
+// insertBackupCodes stores the hashes of new codes for a user.
+func insertBackupCodes(exec interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}, userID string, hashes []string) error {
+	for i, hash := range hashes {
+		if _, err := exec.Exec(`
+			INSERT INTO backup_codes (user_id, code)
+			VALUES ($1, $2)
+		`, userID, hash); err != nil {
+			return fmt.Errorf("backup code %d of %d: %w", i+1, len(hashes), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupCodePolicy_Validate(t *testing.T) {
+	assert.NoError(t, DefaultBackupCodePolicy().Validate())
+	assert.Equal(t, 60, DefaultBackupCodePolicy().EntropyBits())
+
+	// 8 base32 characters are exactly 40 bits
+	assert.NoError(t, BackupCodePolicy{Count: 10, Length: 8}.Validate())
+
+	invalid := []BackupCodePolicy{
+		{Count: 0, Length: 12},
+		{Count: MaxBackupCodesCount + 1, Length: 12},
+		{Count: 10, Length: 7},
+		{Count: 10, Length: MaxBackupCodeLength + 1},
+		{Count: 10, Length: 12, GroupSize: -1},
+		{Count: 10, Length: 12, GroupSize: 13},
+	}
+	for _, policy := range invalid {
+		assert.Error(t, policy.Validate(), "%+v", policy)
+	}
+}
+
+func TestGenerateBackupCodeSet(t *testing.T) {
+	policy := BackupCodePolicy{Count: 20, Length: 12, GroupSize: 4}
+	codes, hashes, err := generateBackupCodeSet(policy)
+	require.NoError(t, err)
+	require.Len(t, codes, 20)
+	require.Len(t, hashes, 20)
+
+	format := regexp.MustCompile(`^[A-Z2-7]{4}-[A-Z2-7]{4}-[A-Z2-7]{4}$`)
+	seen := make(map[string]bool)
+	for i, code := range codes {
+		assert.Regexp(t, format, code)
+		assert.False(t, seen[code], "duplicate code %s", code)
+		seen[code] = true
+
+		// Stored hashes match the code with or without separators
+		assert.Equal(t, hashes[i], hashBackupCode(code))
+		assert.Equal(t, hashes[i], hashBackupCode(strings.ReplaceAll(code, "-", "")))
+	}
+
+	// Ungrouped codes have no separators
+	codes, _, err = generateBackupCodeSet(BackupCodePolicy{Count: 1, Length: 10})
+	require.NoError(t, err)
+	assert.Regexp(t, `^[A-Z2-7]{10}$`, codes[0])
+}
+
+func TestHashBackupCode_Normalizes(t *testing.T) {
+	assert.Equal(t, hashBackupCode("ABCDEF234567"), hashBackupCode("abcdef-234567"))
+	assert.Equal(t, hashBackupCode("ABCDEF234567"), hashBackupCode("ABCDEF 234567"))
+	assert.NotEqual(t, hashBackupCode("ABCDEF234567"), hashBackupCode("ABCDEF234566"))
+}
+
+func TestLoadBackupCodePolicy(t *testing.T) {
+	handler, mock, cleanup := setupSecurityTest(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT key, value FROM configuration`).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).
+			AddRow(configBackupCodeCount, "16").
+			AddRow(configBackupCodeLength, "10").
+			AddRow(configBackupCodeGroupSize, "5"))
+	assert.Equal(t, BackupCodePolicy{Count: 16, Length: 10, GroupSize: 5}, handler.loadBackupCodePolicy(context.Background()))
+
+	// Settings below the minimum entropy fall back to the default
+	mock.ExpectQuery(`SELECT key, value FROM configuration`).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value"}).
+			AddRow(configBackupCodeLength, "4"))
+	assert.Equal(t, DefaultBackupCodePolicy(), handler.loadBackupCodePolicy(context.Background()))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateBackupCodePolicy(t *testing.T) {
+	handler, mock, cleanup := setupSecurityTest(t)
+	defer cleanup()
+
+	call := func(role string, payload map[string]interface{}) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("userID", "admin")
+		c.Set("userRole", role)
+		body, _ := json.Marshal(payload)
+		c.Request = httptest.NewRequest("PUT", "/api/v1/security/mfa/backup-code-policy", bytes.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handler.UpdateBackupCodePolicy(c)
+		return w
+	}
+
+	w := call("user", map[string]interface{}{"count": 8, "length": 16})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Too little entropy
+	w = call("admin", map[string]interface{}{"count": 8, "length": 6})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "bits of entropy")
+
+	mock.ExpectBegin()
+	for i := 0; i < 3; i++ {
+		mock.ExpectExec(`INSERT INTO configuration`).
+			WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), "admin").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	w = call("admin", map[string]interface{}{"count": 8, "length": 16, "group_size": 4})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"entropy_bits":80`)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -62,7 +62,7 @@
 //
 // Dependencies:
 // - Database: batch_operations, sessions, snapshots, templates tables
-// - External Services: None
+// - External Services: NATS (controller commands for terminate, hibernate and wake)
 //
 // Example Usage:
 //
@@ -72,6 +72,7 @@ package handlers
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"log"
@@ -80,11 +81,15 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/events"
 )
 
 // BatchHandler handles batch operations on multiple resources
 type BatchHandler struct {
-	db *db.Database
+	db          *db.Database
+	publisher   *events.Publisher
+	controllers *events.ControllerRegistry
+	platform    string
 }
 
 // NewBatchHandler creates a new batch handler
@@ -94,6 +99,15 @@ func NewBatchHandler(database *db.Database) *BatchHandler {
 	}
 }
 
+// SetEventPublisher makes batch terminate, hibernate and wake notify the
+// platform's controllers. Commands are sent as one batch event when every
+// live controller handles session.batch, and individually otherwise.
+func (h *BatchHandler) SetEventPublisher(publisher *events.Publisher, controllers *events.ControllerRegistry, platform string) {
+	h.publisher = publisher
+	h.controllers = controllers
+	h.platform = platform
+}
+
 // BatchOperation represents a batch operation job
 type BatchOperation struct {
 	ID             string     `json:"id"`
@@ -635,21 +649,21 @@ func (h *BatchHandler) executeBatchTerminate(jobID, userID string, sessionIDs []
 	successCount := 0
 	failureCount := 0
 	var errors []string
+	var updated []string
 
 	for _, sessionID := range sessionIDs {
 		// Update session state to terminated
-		result, err := h.db.DB().ExecContext(ctx, `
-			UPDATE sessions SET state = 'terminated' WHERE id = $1 AND user_id = $2
-		`, sessionID, userID)
+		found, err := h.setSessionState(ctx, sessionID, userID, "terminated")
 
 		if err != nil {
 			failureCount++
 			errors = append(errors, fmt.Sprintf("session %s: %v", sessionID, err))
-		} else if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		} else if !found {
 			failureCount++
 			errors = append(errors, fmt.Sprintf("session %s: not found or not owned by user", sessionID))
 		} else {
 			successCount++
+			updated = append(updated, sessionID)
 		}
 
 		// Update progress
@@ -658,13 +672,22 @@ func (h *BatchHandler) executeBatchTerminate(jobID, userID string, sessionIDs []
 		`, successCount, failureCount, jobID)
 	}
 
+	// Tell the controllers; a session they failed to terminate counts as failed
+	for _, result := range h.sendSessionCommands(ctx, "session.delete", userID, updated) {
+		if !result.Success {
+			successCount--
+			failureCount++
+			errors = append(errors, fmt.Sprintf("session %s: %s", result.SessionID, result.Error))
+		}
+	}
+
 	// Marshal errors to JSON
 	errorsJSON, _ := json.Marshal(errors)
 
 	// Mark as completed with final error count
 	h.db.DB().ExecContext(ctx, `
-		UPDATE batch_operations SET status = 'completed', completed_at = CURRENT_TIMESTAMP, errors = $1 WHERE id = $2
-	`, string(errorsJSON), jobID)
+		UPDATE batch_operations SET status = 'completed', completed_at = CURRENT_TIMESTAMP, success_count = $1, failure_count = $2, errors = $3 WHERE id = $4
+	`, successCount, failureCount, string(errorsJSON), jobID)
 }
 
 func (h *BatchHandler) executeBatchHibernate(jobID, userID string, sessionIDs []string) {
@@ -673,20 +696,20 @@ func (h *BatchHandler) executeBatchHibernate(jobID, userID string, sessionIDs []
 	successCount := 0
 	failureCount := 0
 	var errors []string
+	var updated []string
 
 	for _, sessionID := range sessionIDs {
-		result, err := h.db.DB().ExecContext(ctx, `
-			UPDATE sessions SET state = 'hibernated' WHERE id = $1 AND user_id = $2
-		`, sessionID, userID)
+		found, err := h.setSessionState(ctx, sessionID, userID, "hibernated")
 
 		if err != nil {
 			failureCount++
 			errors = append(errors, fmt.Sprintf("session %s: %v", sessionID, err))
-		} else if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		} else if !found {
 			failureCount++
 			errors = append(errors, fmt.Sprintf("session %s: not found or not owned by user", sessionID))
 		} else {
 			successCount++
+			updated = append(updated, sessionID)
 		}
 
 		h.db.DB().ExecContext(ctx, `
@@ -694,10 +717,19 @@ func (h *BatchHandler) executeBatchHibernate(jobID, userID string, sessionIDs []
 		`, successCount, failureCount, jobID)
 	}
 
+	// Tell the controllers; a session they failed to hibernate counts as failed
+	for _, result := range h.sendSessionCommands(ctx, "session.hibernate", userID, updated) {
+		if !result.Success {
+			successCount--
+			failureCount++
+			errors = append(errors, fmt.Sprintf("session %s: %s", result.SessionID, result.Error))
+		}
+	}
+
 	errorsJSON, _ := json.Marshal(errors)
 	h.db.DB().ExecContext(ctx, `
-		UPDATE batch_operations SET status = 'completed', completed_at = CURRENT_TIMESTAMP, errors = $1 WHERE id = $2
-	`, string(errorsJSON), jobID)
+		UPDATE batch_operations SET status = 'completed', completed_at = CURRENT_TIMESTAMP, success_count = $1, failure_count = $2, errors = $3 WHERE id = $4
+	`, successCount, failureCount, string(errorsJSON), jobID)
 }
 
 func (h *BatchHandler) executeBatchWake(jobID, userID string, sessionIDs []string) {
@@ -706,20 +738,20 @@ func (h *BatchHandler) executeBatchWake(jobID, userID string, sessionIDs []strin
 	successCount := 0
 	failureCount := 0
 	var errors []string
+	var updated []string
 
 	for _, sessionID := range sessionIDs {
-		result, err := h.db.DB().ExecContext(ctx, `
-			UPDATE sessions SET state = 'running' WHERE id = $1 AND user_id = $2
-		`, sessionID, userID)
+		found, err := h.setSessionState(ctx, sessionID, userID, "running")
 
 		if err != nil {
 			failureCount++
 			errors = append(errors, fmt.Sprintf("session %s: %v", sessionID, err))
-		} else if rowsAffected, _ := result.RowsAffected(); rowsAffected == 0 {
+		} else if !found {
 			failureCount++
 			errors = append(errors, fmt.Sprintf("session %s: not found or not owned by user", sessionID))
 		} else {
 			successCount++
+			updated = append(updated, sessionID)
 		}
 
 		h.db.DB().ExecContext(ctx, `
@@ -727,10 +759,77 @@ func (h *BatchHandler) executeBatchWake(jobID, userID string, sessionIDs []strin
 		`, successCount, failureCount, jobID)
 	}
 
+	// Tell the controllers; a session they failed to wake counts as failed
+	for _, result := range h.sendSessionCommands(ctx, "session.wake", userID, updated) {
+		if !result.Success {
+			successCount--
+			failureCount++
+			errors = append(errors, fmt.Sprintf("session %s: %s", result.SessionID, result.Error))
+		}
+	}
+
 	errorsJSON, _ := json.Marshal(errors)
 	h.db.DB().ExecContext(ctx, `
-		UPDATE batch_operations SET status = 'completed', completed_at = CURRENT_TIMESTAMP, errors = $1 WHERE id = $2
-	`, string(errorsJSON), jobID)
+		UPDATE batch_operations SET status = 'completed', completed_at = CURRENT_TIMESTAMP, success_count = $1, failure_count = $2, errors = $3 WHERE id = $4
+	`, successCount, failureCount, string(errorsJSON), jobID)
+}
+
+// setSessionState moves a session of the user to state and audits the
+// change as the user's. Returns false if the user has no such session.
+func (h *BatchHandler) setSessionState(ctx context.Context, sessionID, userID, state string) (bool, error) {
+	var oldState string
+	err := h.db.DB().QueryRowContext(ctx, `
+		UPDATE sessions s SET state = $1
+		FROM (SELECT id, state FROM sessions WHERE id = $2 AND user_id = $3 FOR UPDATE) old
+		WHERE s.id = old.id
+		RETURNING COALESCE(old.state, '')
+	`, state, sessionID, userID).Scan(&oldState)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if oldState != state {
+		if err := events.RecordSessionStateChange(ctx, h.db.DB(), sessionID, oldState, state, userID, ""); err != nil {
+			log.Printf("Failed to audit state change of session %s: %v", sessionID, err)
+		}
+	}
+	// The controller reports the terminated session deleted later
+	if state == "terminated" {
+		if err := events.RequestSessionState(ctx, h.db.DB(), sessionID, state, userID); err != nil {
+			log.Printf("Failed to record state request of session %s: %v", sessionID, err)
+		}
+	}
+	return true, nil
+}
+
+// sendSessionCommands sends the controllers action for each session and
+// returns every command's result. Without a publisher there is nothing to
+// send and no results.
+func (h *BatchHandler) sendSessionCommands(ctx context.Context, action, userID string, sessionIDs []string) []events.CommandResult {
+	if h.publisher == nil || len(sessionIDs) == 0 {
+		return nil
+	}
+
+	commands := make([]events.SessionCommand, 0, len(sessionIDs))
+	for _, sessionID := range sessionIDs {
+		commands = append(commands, events.SessionCommand{
+			Action:    action,
+			SessionID: sessionID,
+			UserID:    userID,
+		})
+	}
+
+	batch := h.controllers.SupportsAction(h.platform, events.ActionSessionBatch)
+	results := h.publisher.SendSessionCommands(ctx, h.platform, commands, batch, events.DefaultBatchTimeout)
+	for _, result := range results {
+		if !result.Success {
+			log.Printf("Batch %s of session %s (event %s) failed: %s", action, result.SessionID, result.EventID, result.Error)
+		}
+	}
+	return results
 }
 
 func (h *BatchHandler) executeBatchDelete(jobID, userID string, sessionIDs []string) {
@@ -753,6 +852,9 @@ func (h *BatchHandler) executeBatchDelete(jobID, userID string, sessionIDs []str
 			errors = append(errors, fmt.Sprintf("session %s: not found or not owned by user", sessionID))
 		} else {
 			successCount++
+			if err := events.ForgetSessionLifecycle(ctx, h.db.DB(), sessionID); err != nil {
+				log.Printf("Failed to delete lifecycle state of session %s: %v", sessionID, err)
+			}
 		}
 
 		h.db.DB().ExecContext(ctx, `
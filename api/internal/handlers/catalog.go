@@ -56,10 +56,12 @@ import (
 	"database/sql"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/lib/pq"
 	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/k8s"
 )
 
 // CatalogHandler handles template catalog-related endpoints
@@ -132,6 +134,17 @@ func (h *CatalogHandler) ListTemplates(c *gin.Context) {
 
 	offset := (page - 1) * limit
 
+	// Templates tagged restricted:<group> are only listed to members of
+	// those groups, as on /templates
+	admin, groups, err := h.viewerGroups(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "Database error",
+			Message: err.Error(),
+		})
+		return
+	}
+
 	// Build query
 	query := `
 		SELECT
@@ -178,6 +191,12 @@ func (h *CatalogHandler) ListTemplates(c *gin.Context) {
 		query += ` AND ct.is_featured = true`
 	}
 
+	if !admin {
+		query += restrictedTemplateFilter(argIdx)
+		args = append(args, pq.Array(groups))
+		argIdx++
+	}
+
 	// Apply sorting
 	switch sortBy {
 	case "rating":
@@ -284,6 +303,11 @@ func (h *CatalogHandler) ListTemplates(c *gin.Context) {
 	if featured {
 		countQuery += ` AND ct.is_featured = true`
 	}
+	if !admin {
+		countQuery += restrictedTemplateFilter(countArgIdx)
+		countArgs = append(countArgs, pq.Array(groups))
+		countArgIdx++
+	}
 
 	var total int
 	h.db.DB().QueryRowContext(c.Request.Context(), countQuery, countArgs...).Scan(&total)
@@ -350,6 +374,26 @@ func (h *CatalogHandler) GetTemplateDetails(c *gin.Context) {
 		return
 	}
 
+	// Restricted templates are hidden from the listing, so don't reveal them here either
+	template := &k8s.Template{Tags: tags}
+	if len(template.RestrictedGroups()) > 0 {
+		admin, groups, err := h.viewerGroups(c)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:   "Database error",
+				Message: err.Error(),
+			})
+			return
+		}
+		if !admin && !template.AccessibleTo(groups) {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:   "Template not found",
+				Message: "The requested template does not exist",
+			})
+			return
+		}
+	}
+
 	c.JSON(http.StatusOK, map[string]interface{}{
 		"id":           id,
 		"repositoryId": repositoryID,
@@ -376,6 +420,47 @@ func (h *CatalogHandler) GetTemplateDetails(c *gin.Context) {
 	})
 }
 
+// viewerGroups returns whether the requesting user is an admin and, if not,
+// the lowercased names of their groups, for restricted:<group> checks.
+func (h *CatalogHandler) viewerGroups(c *gin.Context) (admin bool, groups []string, err error) {
+	if c.GetString("userRole") == "admin" {
+		return true, nil, nil
+	}
+	userID := c.GetString("userID")
+	if userID == "" {
+		return false, []string{}, nil
+	}
+	names, err := db.NewUserDB(h.db.DB()).GetUserGroupNames(c.Request.Context(), userID)
+	if err != nil {
+		return false, nil, err
+	}
+	groups = make([]string, 0, len(names))
+	for _, name := range names {
+		groups = append(groups, strings.ToLower(name))
+	}
+	return false, groups, nil
+}
+
+// restrictedTemplateFilter returns the WHERE condition that hides catalog
+// templates tagged restricted:<group> unless one of their groups is in the
+// text[] parameter $argIdx. It mirrors k8s.Template.RestrictedGroups:
+// the prefix and group names match case-insensitively.
+func restrictedTemplateFilter(argIdx int) string {
+	param := `$` + strconv.Itoa(argIdx)
+	return ` AND (
+		NOT EXISTS (
+			SELECT 1 FROM unnest(ct.tags) AS rt(tag)
+			WHERE lower(rt.tag) LIKE '` + k8s.RestrictedTagPrefix + `_%'
+			  AND btrim(substr(rt.tag, ` + strconv.Itoa(len(k8s.RestrictedTagPrefix)+1) + `)) <> ''
+		)
+		OR EXISTS (
+			SELECT 1 FROM unnest(ct.tags) AS rt(tag)
+			WHERE lower(rt.tag) LIKE '` + k8s.RestrictedTagPrefix + `_%'
+			  AND lower(btrim(substr(rt.tag, ` + strconv.Itoa(len(k8s.RestrictedTagPrefix)+1) + `))) = ANY(` + param + `::text[])
+		)
+	)`
+}
+
 // GetFeaturedTemplates godoc
 // @Summary Get featured templates
 // @Description Get curated featured templates
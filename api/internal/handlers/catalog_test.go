@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCatalogTest(t *testing.T) (*CatalogHandler, sqlmock.Sqlmock, func()) {
+	gin.SetMode(gin.TestMode)
+
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	return NewCatalogHandler(db.NewDatabaseForTesting(sqlDB)), mock, func() { sqlDB.Close() }
+}
+
+func newCatalogContext(path, userID, role string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("userID", userID)
+	c.Set("userRole", role)
+	c.Request = httptest.NewRequest("GET", path, nil)
+	return c, w
+}
+
+func catalogDetailRows(tags []string) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows([]string{
+		"id", "repository_id", "name", "display_name", "description",
+		"category", "app_type", "icon_url", "manifest", "tags",
+		"install_count", "is_featured", "version", "view_count",
+		"avg_rating", "rating_count", "created_at", "updated_at",
+		"repository_name", "repository_url",
+	}).AddRow(
+		1, 1, "ledger", "Ledger", "Accounting", "Finance", "desktop", "", "", pq.StringArray(tags),
+		0, false, "1.0", 0, 0.0, 0, now, now, "official", "https://example.com/repo",
+	)
+}
+
+// Test that catalog listings filter restricted templates by the user's groups
+func TestCatalogListTemplates_FiltersRestrictedTemplates(t *testing.T) {
+	handler, mock, cleanup := setupCatalogTest(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT g.name\s+FROM groups g`).
+		WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Finance"))
+	mock.ExpectQuery(`FROM catalog_templates ct .* unnest\(ct.tags\) .* LIMIT \$2 OFFSET \$3`).
+		WithArgs(pq.Array([]string{"finance"}), 20, 0).
+		WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectQuery(`SELECT COUNT\(\*\) .* unnest\(ct.tags\)`).
+		WithArgs(pq.Array([]string{"finance"})).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	c, w := newCatalogContext("/api/v1/catalog/templates", "alice", "user")
+	handler.ListTemplates(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test that admins see every catalog template without a group lookup
+func TestCatalogListTemplates_AdminSeesAll(t *testing.T) {
+	handler, mock, cleanup := setupCatalogTest(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`FROM catalog_templates ct`).
+		WithArgs(12, 0).
+		WillReturnRows(sqlmock.NewRows(nil))
+	mock.ExpectQuery(`SELECT COUNT\(\*\)`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	c, w := newCatalogContext("/api/v1/catalog/templates/popular", "admin", "admin")
+	handler.GetPopularTemplates(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test that a restricted template's details are hidden from non-members
+func TestCatalogGetTemplateDetails_RestrictedTemplate(t *testing.T) {
+	handler, mock, cleanup := setupCatalogTest(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`FROM catalog_templates ct`).
+		WithArgs("1").
+		WillReturnRows(catalogDetailRows([]string{"accounting", "restricted:finance"}))
+	mock.ExpectQuery(`SELECT g.name\s+FROM groups g`).
+		WithArgs("bob").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("engineering"))
+
+	c, w := newCatalogContext("/api/v1/catalog/templates/1", "bob", "user")
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+	handler.GetTemplateDetails(c)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// Test that group members see a restricted template's details
+func TestCatalogGetTemplateDetails_RestrictedTemplateMember(t *testing.T) {
+	handler, mock, cleanup := setupCatalogTest(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`FROM catalog_templates ct`).
+		WithArgs("1").
+		WillReturnRows(catalogDetailRows([]string{"restricted:finance"}))
+	mock.ExpectQuery(`SELECT g.name\s+FROM groups g`).
+		WithArgs("alice").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("Finance"))
+
+	c, w := newCatalogContext("/api/v1/catalog/templates/1", "alice", "user")
+	c.Params = gin.Params{{Key: "id", Value: "1"}}
+	handler.GetTemplateDetails(c)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
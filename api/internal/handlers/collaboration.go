@@ -53,10 +53,12 @@
 //	 (Full access)  (Can control)  (Can chat)   (Read-only)
 //
 // **WebSocket Integration**:
-//   - Cursor movements broadcast to all participants
+//   - Cursor movements broadcast to all participants, throttled per user
+//     (see collaboration_cursors.go)
 //   - Chat messages delivered in real-time
 //   - Annotations synced across all viewers
-//   - Presence updates (user joined/left)
+//   - Presence updates (user joined/left, timed out after missed
+//     heartbeats; see collaboration_presence.go)
 //
 // **Database Persistence**:
 //   - Collaboration sessions stored in collaboration_sessions table
@@ -102,6 +104,12 @@
 //   - can_manage: Change settings
 //   - can_record: Start recording
 //
+// The role new joiners get and each role's permission set are configurable
+// per collaboration (settings.default_role, settings.role_permissions). A
+// webinar is created "view-only by default" and the owner promotes speakers
+// with UpdateParticipantRole. lock_on_presenter keeps control with the owner
+// and presenters; auto_mute_joiners takes chat away from new joiners.
+//
 // # Real-Time Features
 //
 // **Cursor Tracking**:
@@ -121,7 +129,9 @@
 //   - Drawing tools: line, arrow, rectangle, circle, freehand
 //   - Text annotations
 //   - Color and thickness customization
-//   - Persistent vs temporary (expires after 30s)
+//   - Persistent vs temporary (expires after ttl_seconds, default 5 minutes)
+//   - Rate limited and capped per collaboration and per user, with a
+//     stricter cap on persistent ones (see collaboration_annotation_limits.go)
 //   - Can be cleared by owner/presenter
 //
 // **Follow Mode**:
@@ -190,7 +200,8 @@
 // **collaboration_annotations**:
 //   - id, collaboration_id, user_id, type, points, is_persistent, created_at
 //
-// **collaboration_cursors** (in-memory only, not persisted):
+// **collaboration_cursors** (in-memory; the last position is saved to
+// collaboration_participants.cursor_position periodically):
 //   - user_id, x, y, timestamp, color
 //
 // # Known Limitations
@@ -223,6 +234,18 @@
 //	    }
 //	}
 //
+// **Creating a view-only webinar**:
+//
+//	POST /api/sessions/{sessionId}/collaboration
+//	{
+//	    "settings": {
+//	        "max_participants": 200,
+//	        "default_role": "viewer",
+//	        "role_permissions": {"viewer": {"can_view_only": true}},
+//	        "lock_on_presenter": true
+//	    }
+//	}
+//
 // **Joining a collaboration session**:
 //
 //	POST /api/collaboration/{collabId}/join
@@ -244,7 +267,8 @@
 //	    "type": "arrow",
 //	    "points": [{"x": 100, "y": 100}, {"x": 200, "y": 200}],
 //	    "color": "#FF0000",
-//	    "is_persistent": true
+//	    "is_persistent": false,
+//	    "ttl_seconds": 1800
 //	}
 package handlers
 
@@ -252,23 +276,291 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
+	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/streamspace/streamspace/api/internal/db"
 )
 
+// Lifetime of non-persistent annotations: ttl_seconds on create, or the
+// default when omitted.
+const (
+	defaultAnnotationTTL = 5 * time.Minute
+	maxAnnotationTTL     = time.Hour
+)
+
+// DefaultCollaborationColors is the palette used to auto-assign participant
+// colors when COLLABORATION_COLOR_PALETTE is not set. The first entry is the
+// owner's color.
+var DefaultCollaborationColors = []string{
+	"#0066FF", "#FF6B6B", "#4ECDC4", "#45B7D1", "#FFA07A",
+	"#98D8C8", "#F7DC6F", "#BB8FCE", "#85C1E2",
+}
+
 // Handler handles collaboration-related HTTP requests.
 type CollaborationHandler struct {
 	// DB is the database connection for collaboration queries and updates.
 	DB *db.Database
+
+	// ColorPalette is the set of colors auto-assigned to participants.
+	// The owner always gets the first color.
+	ColorPalette []string
+
+	// PresenceTimeout is how long a participant may miss heartbeats before
+	// the presence sweep marks them inactive.
+	PresenceTimeout time.Duration
+
+	// AnnotationLimits bound annotation creation (see
+	// collaboration_annotation_limits.go).
+	AnnotationLimits AnnotationLimits
+
+	// CursorPersistInterval is how often moved cursor positions are saved
+	// (see collaboration_cursors.go).
+	CursorPersistInterval time.Duration
+
+	// cursors tracks live cursor connections and positions.
+	cursors *cursorRooms
 }
 
 // NewCollaborationHandler creates a new collaboration handler.
+//
+// The participant color palette can be overridden with a comma-separated
+// list in COLLABORATION_COLOR_PALETTE (e.g. "#0066FF,#FF6B6B,#4ECDC4"), the
+// presence timeout with COLLABORATION_PRESENCE_TIMEOUT, the annotation
+// limits with the COLLABORATION_*ANNOTATION* variables and the cursor
+// persist interval with COLLABORATION_CURSOR_PERSIST_INTERVAL.
 func NewCollaborationHandler(database *db.Database) *CollaborationHandler {
-	return &CollaborationHandler{DB: database}
+	palette := DefaultCollaborationColors
+	if env := os.Getenv("COLLABORATION_COLOR_PALETTE"); env != "" {
+		custom := make([]string, 0)
+		for _, color := range strings.Split(env, ",") {
+			if color = strings.TrimSpace(color); color != "" {
+				custom = append(custom, color)
+			}
+		}
+		if len(custom) > 0 {
+			palette = custom
+		}
+	}
+
+	return &CollaborationHandler{
+		DB:               database,
+		ColorPalette:     palette,
+		PresenceTimeout:  collaborationPresenceTimeoutFromEnv(),
+		AnnotationLimits: annotationLimitsFromEnv(),
+
+		CursorPersistInterval: collaborationCursorPersistIntervalFromEnv(),
+		cursors:               newCursorRooms(CollaborationCursorMaxRate),
+	}
+}
+
+// palette returns the configured color palette, falling back to the default.
+func (h *CollaborationHandler) palette() []string {
+	if len(h.ColorPalette) == 0 {
+		return DefaultCollaborationColors
+	}
+	return h.ColorPalette
+}
+
+// pickLeastUsedColor returns the palette color used by the fewest active
+// participants, preferring earlier palette entries on ties.
+//
+// BUG FIX: Colors were assigned by participantCount % len(colors), so the
+// 9th participant reused an existing color and participants leaving shifted
+// the modulo onto colors still in use. Picking the least-used color keeps
+// every active participant distinct up to the palette size, and beyond that
+// spreads duplicates evenly instead of piling onto one color.
+func pickLeastUsedColor(palette []string, activeColors []string) string {
+	usage := make(map[string]int, len(palette))
+	for _, color := range activeColors {
+		usage[color]++
+	}
+
+	best := palette[0]
+	for _, color := range palette[1:] {
+		if usage[color] < usage[best] {
+			best = color
+		}
+	}
+	return best
+}
+
+// participantColors returns the colors of a collaboration's participants,
+// excluding the given user, split into active and inactive participants.
+//
+// Inactive participants keep their color for when they rejoin.
+func (h *CollaborationHandler) participantColors(collabID, excludeUserID string) (active, inactive []string, err error) {
+	rows, err := h.DB.DB().Query(`
+		SELECT COALESCE(color, ''), is_active FROM collaboration_participants
+		WHERE collaboration_id = $1 AND user_id != $2
+	`, collabID, excludeUserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	active = make([]string, 0)
+	for rows.Next() {
+		var color string
+		var isActive bool
+		if err := rows.Scan(&color, &isActive); err != nil {
+			return nil, nil, err
+		}
+		if isActive {
+			active = append(active, color)
+		} else {
+			inactive = append(inactive, color)
+		}
+	}
+	return active, inactive, rows.Err()
+}
+
+// pickJoinColor picks a color for a new participant.
+//
+// Colors held by participants who left are reserved for their return as
+// long as the palette has colors nobody holds; after that the least-used
+// color among active participants is picked.
+func pickJoinColor(palette []string, activeColors, inactiveColors []string) string {
+	held := make(map[string]bool, len(activeColors)+len(inactiveColors))
+	for _, color := range activeColors {
+		held[color] = true
+	}
+	for _, color := range inactiveColors {
+		held[color] = true
+	}
+	for _, color := range palette {
+		if !held[color] {
+			return color
+		}
+	}
+	return pickLeastUsedColor(palette, activeColors)
+}
+
+// defaultCollaborationPermissions returns the permissions a role starts with.
+func defaultCollaborationPermissions(role string) CollaborationPermissions {
+	switch role {
+	case "owner":
+		return CollaborationPermissions{
+			CanControl:  true,
+			CanAnnotate: true,
+			CanChat:     true,
+			CanInvite:   true,
+			CanManage:   true,
+			CanRecord:   true,
+		}
+	case "viewer":
+		return CollaborationPermissions{
+			CanChat:     true,
+			CanViewOnly: true,
+		}
+	default:
+		return CollaborationPermissions{
+			CanControl:  true,
+			CanAnnotate: true,
+			CanChat:     true,
+		}
+	}
+}
+
+// collaborationRoles are the roles a participant can hold, highest first.
+var collaborationRoles = []string{"owner", "presenter", "participant", "viewer"}
+
+// validCollaborationRole reports whether role is a known collaboration role.
+func validCollaborationRole(role string) bool {
+	for _, r := range collaborationRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// validate checks the role settings of a collaboration.
+func (s CollaborationSettings) validate() error {
+	if s.DefaultRole != "" && (s.DefaultRole == "owner" || !validCollaborationRole(s.DefaultRole)) {
+		return fmt.Errorf("invalid default_role %q: must be presenter, participant or viewer", s.DefaultRole)
+	}
+	for role := range s.RolePermissions {
+		if role == "owner" || !validCollaborationRole(role) {
+			return fmt.Errorf("invalid role %q in role_permissions: must be presenter, participant or viewer", role)
+		}
+	}
+	return nil
+}
+
+// joinRole returns the role new participants are given.
+func (s CollaborationSettings) joinRole() string {
+	if s.DefaultRole != "" {
+		return s.DefaultRole
+	}
+	return "participant"
+}
+
+// permissionsFor returns the permissions a role is given in this
+// collaboration: the configured set from role_permissions, else the built-in
+// defaults. With lock_on_presenter only the owner and presenters can control
+// the session. The owner's permissions are not configurable.
+func (s CollaborationSettings) permissionsFor(role string) CollaborationPermissions {
+	perms, ok := s.RolePermissions[role]
+	if !ok || role == "owner" {
+		perms = defaultCollaborationPermissions(role)
+	}
+	if s.LockOnPresenter && role != "owner" && role != "presenter" {
+		perms.CanControl = false
+	}
+	return perms
+}
+
+// joinPermissions returns the permissions a new participant starts with.
+// With auto_mute_joiners they cannot chat until the owner grants it through
+// UpdateParticipantRole.
+func (s CollaborationSettings) joinPermissions() CollaborationPermissions {
+	perms := s.permissionsFor(s.joinRole())
+	if s.AutoMuteJoiners {
+		perms.CanChat = false
+	}
+	return perms
+}
+
+// broadcastPresence notifies a collaboration's active participants that a
+// participant joined, rejoined or stopped sending heartbeats ("joined",
+// "rejoined", "timed_out").
+func (h *CollaborationHandler) broadcastPresence(collabID, userID, event, role, color string) {
+	rows, err := h.DB.DB().Query(`
+		SELECT user_id FROM collaboration_participants
+		WHERE collaboration_id = $1 AND is_active = true
+	`, collabID)
+	if err != nil {
+		log.Printf("Failed to load participants of collaboration %s for presence event: %v", collabID, err)
+		return
+	}
+	defer rows.Close()
+
+	msg := WebSocketMessage{
+		Type:      "collaboration.presence",
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"collaboration_id": collabID,
+			"user_id":          userID,
+			"event":            event,
+			"role":             role,
+			"color":            color,
+		},
+	}
+
+	hub := GetWebSocketHub()
+	for rows.Next() {
+		var participantID string
+		if err := rows.Scan(&participantID); err != nil {
+			return
+		}
+		hub.BroadcastToUser(participantID, msg)
+	}
 }
 
 // canAccessSession checks if a user has access to a session.
@@ -313,7 +605,8 @@ func toJSONB(v interface{}) string {
 //  1. Owner creates collaboration session from their StreamSpace session
 //  2. Participants join via invitation or link
 //  3. Real-time interaction via WebSocket (chat, cursors, annotations)
-//  4. Owner ends collaboration (session continues, collaboration stops)
+//  4. Owner ends collaboration (session continues, collaboration stops), or
+//     it ends once the owner and everyone else left
 //
 // State transitions:
 //   - "active": Collaboration in progress, users can join
@@ -323,7 +616,7 @@ func toJSONB(v interface{}) string {
 // Persistence:
 //   - Session metadata stored in collaboration_sessions table
 //   - Chat history, annotations preserved after session ends
-//   - Cursor positions ephemeral (not stored in database)
+//   - Cursor positions kept in memory; the last one is saved periodically
 type CollaborationSession struct {
 	ID                 string                `json:"id"`
 	SessionID          string                `json:"session_id"`
@@ -369,10 +662,23 @@ type CollaborationSettings struct {
 	MaxParticipants  int    `json:"max_participants"`
 	RequireApproval  bool   `json:"require_approval"`
 	AllowAnonymous   bool   `json:"allow_anonymous"`
-	LockOnPresenter  bool   `json:"lock_on_presenter"`
-	AutoMuteJoiners  bool   `json:"auto_mute_joiners"`
+	LockOnPresenter  bool   `json:"lock_on_presenter"` // Only owner and presenters can control
+	AutoMuteJoiners  bool   `json:"auto_mute_joiners"` // Joiners start without chat
 	ShowCursorLabels bool   `json:"show_cursor_labels"`
 	EnableHandRaise  bool   `json:"enable_hand_raise"`
+
+	// DefaultRole is the role new joiners get ("participant" if empty).
+	// Webinars use "viewer" and promote speakers with UpdateParticipantRole.
+	DefaultRole string `json:"default_role,omitempty"`
+
+	// RolePermissions overrides the built-in permissions of the presenter,
+	// participant and viewer roles.
+	RolePermissions map[string]CollaborationPermissions `json:"role_permissions,omitempty"`
+
+	// PromoteOnOwnerLeave makes the earliest-joined active presenter the
+	// owner when the owner leaves or times out (see
+	// collaboration_owner_departure.go).
+	PromoteOnOwnerLeave bool `json:"promote_on_owner_leave,omitempty"`
 }
 
 // CursorPosition represents cursor location
@@ -407,6 +713,10 @@ type Annotation struct {
 	IsPersistent bool       `json:"is_persistent"`
 	CreatedAt    time.Time  `json:"created_at"`
 	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+
+	// TTLSeconds sets how long a non-persistent annotation lives (create
+	// only; default 5 minutes, max 1 hour).
+	TTLSeconds int `json:"ttl_seconds,omitempty"`
 }
 
 // Point represents a coordinate point
@@ -436,34 +746,44 @@ func (h *CollaborationHandler) CreateCollaborationSession(c *gin.Context) {
 		}
 	}
 
-	// Verify session ownership
-	if !h.canAccessSession(userID, sessionID) {
-		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
+	if err := req.Settings.validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collaboration settings", "message": err.Error()})
 		return
 	}
 
-	// Check if collaboration already exists
-	var existingID string
-	err := h.DB.DB().QueryRow(`
-		SELECT id FROM collaboration_sessions
-		WHERE session_id = $1 AND status = 'active'
-	`, sessionID).Scan(&existingID)
-
-	if err == nil {
-		c.JSON(http.StatusConflict, gin.H{"error": "collaboration already active", "collaboration_id": existingID})
+	// Verify session ownership
+	if !h.canAccessSession(userID, sessionID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "access denied"})
 		return
 	}
 
 	// Create collaboration session
-	collabID := fmt.Sprintf("collab-%s-%d", sessionID, time.Now().Unix())
-	err = h.DB.DB().QueryRow(`
+	// BUG FIX: A check-then-insert let two concurrent requests both create an
+	// active collaboration for the same session. The partial unique index
+	// idx_collaboration_sessions_one_active makes the insert itself the check:
+	// the loser gets no row back and is sent the winner's ID via the 409 path.
+	collabID := fmt.Sprintf("collab-%s-%d", sessionID, time.Now().UnixNano())
+	err := h.DB.DB().QueryRow(`
 		INSERT INTO collaboration_sessions (
 			id, session_id, owner_id, settings, chat_enabled,
 			annotations_enabled, cursor_tracking, status
 		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (session_id) WHERE status = 'active' DO NOTHING
 		RETURNING id
 	`, collabID, sessionID, userID, toJSONB(req.Settings), true, true, true, "active").Scan(&collabID)
 
+	if err == sql.ErrNoRows {
+		// Another collaboration is already active for this session
+		var existingID string
+		h.DB.DB().QueryRow(`
+			SELECT id FROM collaboration_sessions
+			WHERE session_id = $1 AND status = 'active'
+		`, sessionID).Scan(&existingID)
+
+		c.JSON(http.StatusConflict, gin.H{"error": "collaboration already active", "collaboration_id": existingID})
+		return
+	}
+
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create collaboration session",
@@ -473,21 +793,13 @@ func (h *CollaborationHandler) CreateCollaborationSession(c *gin.Context) {
 	}
 
 	// Add owner as first participant
-	ownerPerms := CollaborationPermissions{
-		CanControl:  true,
-		CanAnnotate: true,
-		CanChat:     true,
-		CanInvite:   true,
-		CanManage:   true,
-		CanRecord:   true,
-		CanViewOnly: false,
-	}
+	ownerPerms := defaultCollaborationPermissions("owner")
 
 	h.DB.DB().Exec(`
 		INSERT INTO collaboration_participants (
 			collaboration_id, user_id, role, permissions, color, is_active
 		) VALUES ($1, $2, $3, $4, $5, $6)
-	`, collabID, userID, "owner", toJSONB(ownerPerms), "#0066FF", true)
+	`, collabID, userID, "owner", toJSONB(ownerPerms), h.palette()[0], true)
 
 	c.JSON(http.StatusCreated, gin.H{
 		"collaboration_id": collabID,
@@ -537,58 +849,49 @@ func (h *CollaborationHandler) JoinCollaborationSession(c *gin.Context) {
 		return
 	}
 
+	// Colors held by the other participants
+	takenColors, reservedColors, err := h.participantColors(collabID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to join collaboration",
+			"message": fmt.Sprintf("Failed to load participants for collaboration %s: %v", collabID, err),
+		})
+		return
+	}
+
 	// Check if already a participant
 	var existingRole string
+	var existingColor, existingPerms sql.NullString
 	h.DB.DB().QueryRow(`
-		SELECT role FROM collaboration_participants
+		SELECT role, color, permissions FROM collaboration_participants
 		WHERE collaboration_id = $1 AND user_id = $2
-	`, collabID, userID).Scan(&existingRole)
+	`, collabID, userID).Scan(&existingRole, &existingColor, &existingPerms)
 
 	if existingRole != "" {
-		// Update to active
-		h.DB.DB().Exec(`
-			UPDATE collaboration_participants
-			SET is_active = true, last_seen_at = $1
-			WHERE collaboration_id = $2 AND user_id = $3
-		`, time.Now(), collabID, userID)
-
-		c.JSON(http.StatusOK, gin.H{"message": "rejoined successfully", "role": existingRole})
+		h.rejoinCollaborationSession(c, collabID, ownerID, userID, existingRole, existingColor.String, existingPerms, takenColors, collabSettings)
 		return
 	}
 
 	// Check participant limit
-	var participantCount int
-	h.DB.DB().QueryRow(`
-		SELECT COUNT(*) FROM collaboration_participants
-		WHERE collaboration_id = $1 AND is_active = true
-	`, collabID).Scan(&participantCount)
-
+	participantCount := len(takenColors)
 	if participantCount >= collabSettings.MaxParticipants {
 		c.JSON(http.StatusForbidden, gin.H{"error": "collaboration is full"})
 		return
 	}
 
-	// Default permissions for participants
-	participantPerms := CollaborationPermissions{
-		CanControl:  true,
-		CanAnnotate: true,
-		CanChat:     true,
-		CanInvite:   false,
-		CanManage:   false,
-		CanRecord:   false,
-		CanViewOnly: false,
-	}
+	// Role and permissions configured for new joiners
+	joinRole := collabSettings.joinRole()
+	joinPerms := collabSettings.joinPermissions()
 
-	// Assign color
-	colors := []string{"#FF6B6B", "#4ECDC4", "#45B7D1", "#FFA07A", "#98D8C8", "#F7DC6F", "#BB8FCE", "#85C1E2"}
-	userColor := colors[participantCount%len(colors)]
+	// Prefer a color no other participant holds
+	userColor := pickJoinColor(h.palette(), takenColors, reservedColors)
 
 	// Add participant
 	_, err = h.DB.DB().Exec(`
 		INSERT INTO collaboration_participants (
 			collaboration_id, user_id, role, permissions, color, is_active
 		) VALUES ($1, $2, $3, $4, $5, $6)
-	`, collabID, userID, "participant", toJSONB(participantPerms), userColor, true)
+	`, collabID, userID, joinRole, toJSONB(joinPerms), userColor, true)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -612,11 +915,73 @@ func (h *CollaborationHandler) JoinCollaborationSession(c *gin.Context) {
 		) VALUES ($1, $2, $3, $4)
 	`, collabID, "system", fmt.Sprintf("User %s joined the session", userID), "system")
 
+	h.broadcastPresence(collabID, userID, "joined", joinRole, userColor)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":                    "joined successfully",
+		"role":                       joinRole,
+		"permissions":                joinPerms,
+		"color":                      userColor,
+		"websocket_url":              fmt.Sprintf("wss://%s/api/v1/collaboration/%s/ws", c.Request.Host, collabID),
+		"heartbeat_interval_seconds": h.heartbeatIntervalSeconds(),
+	})
+}
+
+// rejoinCollaborationSession reactivates a returning participant.
+//
+// BUG FIX: Rejoin used to hand out a new color whenever someone else had
+// taken the old one and returned only the role, so clients rebuilt stale
+// permissions. A returning participant now gets back their persisted color,
+// role and permissions (including promotions made before they left). The
+// owner always comes back as owner, and unreadable permissions are restored
+// from the role's permissions in the collaboration settings.
+func (h *CollaborationHandler) rejoinCollaborationSession(c *gin.Context, collabID, ownerID, userID, role, color string, storedPerms sql.NullString, takenColors []string, settings CollaborationSettings) {
+	var perms CollaborationPermissions
+	permsValid := storedPerms.Valid && storedPerms.String != "" &&
+		json.Unmarshal([]byte(storedPerms.String), &perms) == nil
+
+	if userID == ownerID && role != "owner" {
+		role = "owner"
+		permsValid = false
+	}
+	if !permsValid {
+		perms = settings.permissionsFor(role)
+	}
+
+	// Only participants who never had a color get a new one
+	if color == "" {
+		color = pickLeastUsedColor(h.palette(), takenColors)
+	}
+
+	_, err := h.DB.DB().Exec(`
+		UPDATE collaboration_participants
+		SET is_active = true, last_seen_at = $1, color = $2, role = $3, permissions = $4
+		WHERE collaboration_id = $5 AND user_id = $6
+	`, time.Now(), color, role, toJSONB(perms), collabID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to rejoin collaboration",
+			"message": fmt.Sprintf("Database update failed for user %s rejoining collaboration %s: %v", userID, collabID, err),
+		})
+		return
+	}
+
+	// Update participant count
+	h.DB.DB().Exec(`
+		UPDATE collaboration_sessions
+		SET active_users = (SELECT COUNT(*) FROM collaboration_participants WHERE collaboration_id = $1 AND is_active = true)
+		WHERE id = $1
+	`, collabID)
+
+	h.broadcastPresence(collabID, userID, "rejoined", role, color)
+
 	c.JSON(http.StatusOK, gin.H{
-		"message":       "joined successfully",
-		"role":          "participant",
-		"color":         userColor,
-		"websocket_url": fmt.Sprintf("wss://%s/api/v1/collaboration/%s/ws", c.Request.Host, collabID),
+		"message":                    "rejoined successfully",
+		"role":                       role,
+		"permissions":                perms,
+		"color":                      color,
+		"websocket_url":              fmt.Sprintf("wss://%s/api/v1/collaboration/%s/ws", c.Request.Host, collabID),
+		"heartbeat_interval_seconds": h.heartbeatIntervalSeconds(),
 	})
 }
 
@@ -654,7 +1019,31 @@ func (h *CollaborationHandler) LeaveCollaborationSession(c *gin.Context) {
 		) VALUES ($1, $2, $3, $4)
 	`, collabID, "system", fmt.Sprintf("User %s left the session", userID), "system")
 
-	c.JSON(http.StatusOK, gin.H{"message": "left successfully"})
+	response := gin.H{"message": "left successfully"}
+
+	// End or hand off the collaboration if the owner left
+	var ownerID, settingsJSON sql.NullString
+	h.DB.DB().QueryRow(`
+		SELECT owner_id, settings FROM collaboration_sessions WHERE id = $1
+	`, collabID).Scan(&ownerID, &settingsJSON)
+	if ownerID.Valid && ownerID.String == userID {
+		var settings CollaborationSettings
+		if settingsJSON.Valid && settingsJSON.String != "" {
+			json.Unmarshal([]byte(settingsJSON.String), &settings)
+		}
+		outcome, newOwnerID, err := h.handleOwnerDeparture(collabID, userID, settings)
+		if err != nil {
+			log.Printf("Failed to handle owner departure of collaboration %s: %v", collabID, err)
+		}
+		switch outcome {
+		case ownerDepartureEnded:
+			response["collaboration_status"] = "ended"
+		case ownerDeparturePromoted:
+			response["new_owner_id"] = newOwnerID
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // GetCollaborationParticipants lists all participants
@@ -712,15 +1101,19 @@ func (h *CollaborationHandler) GetCollaborationParticipants(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"participants": participants})
 }
 
-// UpdateParticipantRole updates a participant's role and permissions
+// UpdateParticipantRole updates a participant's role and permissions.
+//
+// Permissions omitted from the request are taken from the role's permission
+// set in the collaboration settings, so promoting a viewer only needs the
+// new role. With lock_on_presenter, control is never granted below presenter.
 func (h *CollaborationHandler) UpdateParticipantRole(c *gin.Context) {
 	collabID := c.Param("collabId")
 	targetUserID := c.Param("userId")
 	userID := c.GetString("user_id")
 
 	var req struct {
-		Role        string                   `json:"role"`
-		Permissions CollaborationPermissions `json:"permissions"`
+		Role        string                    `json:"role" binding:"required"`
+		Permissions *CollaborationPermissions `json:"permissions"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -728,18 +1121,45 @@ func (h *CollaborationHandler) UpdateParticipantRole(c *gin.Context) {
 		return
 	}
 
+	if req.Role == "owner" || !validCollaborationRole(req.Role) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid role", "message": "role must be presenter, participant or viewer"})
+		return
+	}
+
 	// Verify user has manage permissions
 	if !h.canManageCollaboration(collabID, userID) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "permission denied"})
 		return
 	}
 
+	var settingsJSON sql.NullString
+	err := h.DB.DB().QueryRow(`SELECT settings FROM collaboration_sessions WHERE id = $1`, collabID).Scan(&settingsJSON)
+	if err != nil && err != sql.ErrNoRows {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update role",
+			"message": fmt.Sprintf("Failed to load settings of collaboration %s: %v", collabID, err),
+		})
+		return
+	}
+	var settings CollaborationSettings
+	if settingsJSON.Valid && settingsJSON.String != "" {
+		json.Unmarshal([]byte(settingsJSON.String), &settings)
+	}
+
+	perms := settings.permissionsFor(req.Role)
+	if req.Permissions != nil {
+		perms = *req.Permissions
+		if settings.LockOnPresenter && req.Role != "presenter" {
+			perms.CanControl = false
+		}
+	}
+
 	// Update participant
-	_, err := h.DB.DB().Exec(`
+	_, err = h.DB.DB().Exec(`
 		UPDATE collaboration_participants
 		SET role = $1, permissions = $2
 		WHERE collaboration_id = $3 AND user_id = $4
-	`, req.Role, toJSONB(req.Permissions), collabID, targetUserID)
+	`, req.Role, toJSONB(perms), collabID, targetUserID)
 
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -749,7 +1169,7 @@ func (h *CollaborationHandler) UpdateParticipantRole(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "role updated successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "role updated successfully", "role": req.Role, "permissions": perms})
 }
 
 // Chat Operations
@@ -893,6 +1313,12 @@ func (h *CollaborationHandler) CreateAnnotation(c *gin.Context) {
 		return
 	}
 
+	// Every attempt counts against the rate limit, so a flood is cut off
+	// before it reaches the annotations table
+	if !h.allowAnnotationCreate(c, collabID, userID) {
+		return
+	}
+
 	// Get session ID
 	var sessionID string
 	h.DB.DB().QueryRow("SELECT session_id FROM collaboration_sessions WHERE id = $1", collabID).Scan(&sessionID)
@@ -903,13 +1329,24 @@ func (h *CollaborationHandler) CreateAnnotation(c *gin.Context) {
 	req.UserID = userID
 
 	// Calculate expiration if not persistent
+	ttl, err := annotationTTL(req.TTLSeconds)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	var expiresAt *time.Time
 	if !req.IsPersistent {
-		expires := time.Now().Add(5 * time.Minute)
+		expires := time.Now().Add(ttl)
 		expiresAt = &expires
 	}
+	req.ExpiresAt = expiresAt
+	req.TTLSeconds = 0
 
-	_, err := h.DB.DB().Exec(`
+	if !h.checkAnnotationCaps(c, collabID, userID, req.IsPersistent) {
+		return
+	}
+
+	_, err = h.DB.DB().Exec(`
 		INSERT INTO collaboration_annotations (
 			id, collaboration_id, session_id, user_id, type, color, thickness,
 			points, text, is_persistent, expires_at
@@ -928,6 +1365,20 @@ func (h *CollaborationHandler) CreateAnnotation(c *gin.Context) {
 	c.JSON(http.StatusCreated, req)
 }
 
+// annotationTTL returns the lifetime for a non-persistent annotation.
+// Zero means the default; negative values and values above the max are
+// rejected.
+func annotationTTL(ttlSeconds int) (time.Duration, error) {
+	if ttlSeconds == 0 {
+		return defaultAnnotationTTL, nil
+	}
+	ttl := time.Duration(ttlSeconds) * time.Second
+	if ttlSeconds < 0 || ttl > maxAnnotationTTL {
+		return 0, fmt.Errorf("ttl_seconds must be between 1 and %d", int(maxAnnotationTTL.Seconds()))
+	}
+	return ttl, nil
+}
+
 // GetAnnotations retrieves active annotations
 func (h *CollaborationHandler) GetAnnotations(c *gin.Context) {
 	collabID := c.Param("collabId")
@@ -1051,7 +1502,9 @@ func (h *CollaborationHandler) canManageCollaboration(collabID, userID string) b
 	return perms.CanManage
 }
 
-func (h *CollaborationHandler) hasCollaborationPermission(collabID, userID, permission string) bool {
+// activePermissions returns the permissions of an active participant. The
+// second result is false if the user isn't an active participant.
+func (h *CollaborationHandler) activePermissions(collabID, userID string) (CollaborationPermissions, bool) {
 	var permissions sql.NullString
 	h.DB.DB().QueryRow(`
 		SELECT permissions FROM collaboration_participants
@@ -1059,11 +1512,19 @@ func (h *CollaborationHandler) hasCollaborationPermission(collabID, userID, perm
 	`, collabID, userID).Scan(&permissions)
 
 	if !permissions.Valid {
-		return false
+		return CollaborationPermissions{}, false
 	}
 
 	var perms CollaborationPermissions
 	json.Unmarshal([]byte(permissions.String), &perms)
+	return perms, true
+}
+
+func (h *CollaborationHandler) hasCollaborationPermission(collabID, userID, permission string) bool {
+	perms, ok := h.activePermissions(collabID, userID)
+	if !ok {
+		return false
+	}
 
 	switch permission {
 	case "can_chat":
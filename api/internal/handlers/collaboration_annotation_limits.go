@@ -0,0 +1,171 @@
+// Package handlers - collaboration_annotation_limits.go
+//
+// This file caps annotations so a single client can't flood a
+// collaboration.
+//
+// A buggy or malicious client calling CreateAnnotation in a loop would
+// otherwise fill collaboration_annotations and every participant's screen.
+// Creates are limited three ways, and a rejected create stores and
+// broadcasts nothing:
+//
+//   - Rate: creates per user per collaboration per minute (HTTP 429 with
+//     Retry-After)
+//   - Count: live annotations per collaboration and per user (HTTP 409)
+//   - Persistent count: persistent annotations never expire, so they count
+//     against a separate, stricter per-collaboration cap (HTTP 409)
+//
+// Expired annotations don't count. Deleting or clearing annotations frees
+// room under the caps.
+//
+// Configuration (0 disables a limit):
+//
+//	COLLABORATION_MAX_ANNOTATIONS=500            // Live annotations per collaboration
+//	COLLABORATION_MAX_ANNOTATIONS_PER_USER=100   // Live annotations per user per collaboration
+//	COLLABORATION_MAX_PERSISTENT_ANNOTATIONS=50  // Persistent annotations per collaboration
+//	COLLABORATION_ANNOTATION_RATE=60             // Creates per user per collaboration per minute
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/middleware"
+)
+
+// DefaultAnnotationLimits are the annotation limits when not configured.
+var DefaultAnnotationLimits = AnnotationLimits{
+	MaxPerCollaboration: 500,
+	MaxPerUser:          100,
+	MaxPersistent:       50,
+	CreatesPerMinute:    60,
+}
+
+// annotationRateWindow is the window of AnnotationLimits.CreatesPerMinute.
+const annotationRateWindow = time.Minute
+
+// AnnotationLimits bound annotation creation in a collaboration. Zero
+// disables a limit.
+type AnnotationLimits struct {
+	// MaxPerCollaboration caps live annotations in a collaboration.
+	MaxPerCollaboration int
+
+	// MaxPerUser caps a user's live annotations in a collaboration.
+	MaxPerUser int
+
+	// MaxPersistent caps persistent annotations in a collaboration.
+	MaxPersistent int
+
+	// CreatesPerMinute caps a user's annotation creates per collaboration.
+	CreatesPerMinute int
+}
+
+// annotationLimitsFromEnv reads the COLLABORATION_*ANNOTATION* limits.
+func annotationLimitsFromEnv() AnnotationLimits {
+	limits := DefaultAnnotationLimits
+	for env, limit := range map[string]*int{
+		"COLLABORATION_MAX_ANNOTATIONS":            &limits.MaxPerCollaboration,
+		"COLLABORATION_MAX_ANNOTATIONS_PER_USER":   &limits.MaxPerUser,
+		"COLLABORATION_MAX_PERSISTENT_ANNOTATIONS": &limits.MaxPersistent,
+		"COLLABORATION_ANNOTATION_RATE":            &limits.CreatesPerMinute,
+	} {
+		value := os.Getenv(env)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			log.Printf("Invalid %s %q, using default %d", env, value, *limit)
+			continue
+		}
+		*limit = n
+	}
+	return limits
+}
+
+// annotationCreateKey is the rate limit key of a user's annotation creates
+// in a collaboration.
+func annotationCreateKey(collabID, userID string) string {
+	return fmt.Sprintf("collab_annotation:%s:%s", collabID, userID)
+}
+
+// allowAnnotationCreate counts a create against the user's annotation rate
+// limit. If it is over the limit, it responds with HTTP 429 and returns
+// false.
+func (h *CollaborationHandler) allowAnnotationCreate(c *gin.Context, collabID, userID string) bool {
+	limit := h.AnnotationLimits.CreatesPerMinute
+	if limit <= 0 {
+		return true
+	}
+
+	limiter := middleware.GetRateLimiter()
+	key := annotationCreateKey(collabID, userID)
+	if limiter.CheckLimit(key, limit, annotationRateWindow) {
+		return true
+	}
+
+	retryAfter := int(annotationRateWindow.Seconds())
+	if status, ok := limiter.Inspect(key); ok && status.ResetAt != nil {
+		retryAfter = int(math.Ceil(time.Until(*status.ResetAt).Seconds()))
+		if retryAfter < 1 {
+			retryAfter = 1
+		}
+	}
+
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Annotation rate limit exceeded",
+		"message":     fmt.Sprintf("At most %d annotations per minute, please wait %d seconds", limit, retryAfter),
+		"retry_after": retryAfter,
+	})
+	return false
+}
+
+// checkAnnotationCaps checks that the collaboration has room for another
+// annotation by the user. If it doesn't, or the count fails, it responds
+// and returns false.
+func (h *CollaborationHandler) checkAnnotationCaps(c *gin.Context, collabID, userID string, persistent bool) bool {
+	limits := h.AnnotationLimits
+	if limits.MaxPerCollaboration <= 0 && limits.MaxPerUser <= 0 && (!persistent || limits.MaxPersistent <= 0) {
+		return true
+	}
+
+	var total, own, persistentCount int
+	err := h.DB.DB().QueryRow(`
+		SELECT COUNT(*),
+		       COUNT(*) FILTER (WHERE user_id = $2),
+		       COUNT(*) FILTER (WHERE is_persistent)
+		FROM collaboration_annotations
+		WHERE collaboration_id = $1 AND (expires_at IS NULL OR expires_at > $3)
+	`, collabID, userID, time.Now()).Scan(&total, &own, &persistentCount)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create annotation",
+			"message": fmt.Sprintf("Failed to count annotations in collaboration %s: %v", collabID, err),
+		})
+		return false
+	}
+
+	var message string
+	switch {
+	case persistent && limits.MaxPersistent > 0 && persistentCount >= limits.MaxPersistent:
+		message = fmt.Sprintf("The collaboration has the maximum of %d persistent annotations; delete some or create a temporary one", limits.MaxPersistent)
+	case limits.MaxPerCollaboration > 0 && total >= limits.MaxPerCollaboration:
+		message = fmt.Sprintf("The collaboration has the maximum of %d annotations; delete or clear some first", limits.MaxPerCollaboration)
+	case limits.MaxPerUser > 0 && own >= limits.MaxPerUser:
+		message = fmt.Sprintf("You have the maximum of %d annotations in this collaboration; delete some first", limits.MaxPerUser)
+	default:
+		return true
+	}
+
+	c.JSON(http.StatusConflict, gin.H{
+		"error":   "Annotation limit reached",
+		"message": message,
+	})
+	return false
+}
@@ -0,0 +1,128 @@
+// Package handlers - collaboration_annotation_reaper.go
+//
+// This file deletes expired collaboration annotations.
+//
+// CreateAnnotation gives non-persistent annotations an expires_at, and
+// GetAnnotations hides them once it has passed, but the rows stayed in
+// collaboration_annotations forever. The AnnotationReaper deletes them
+// every minute:
+//
+//	reaper := handlers.NewAnnotationReaper(database)
+//	go reaper.Start(ctx) // runs until ctx is cancelled
+//
+// Persistent annotations (no expires_at) are never reaped.
+package handlers
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/streamspace/streamspace/api/internal/db"
+)
+
+// AnnotationReaperInterval is how often expired annotations are deleted.
+const AnnotationReaperInterval = time.Minute
+
+// AnnotationReaper periodically deletes expired collaboration annotations.
+type AnnotationReaper struct {
+	db       *db.Database
+	interval time.Duration
+
+	mu           sync.Mutex
+	lastSweep    time.Time
+	lastRemoved  int64
+	totalRemoved int64
+}
+
+// AnnotationReaperStats describes the reaper's progress.
+type AnnotationReaperStats struct {
+	// PendingExpiry is the number of stored annotations with an expiry,
+	// including expired ones the next sweep will delete.
+	PendingExpiry int `json:"pending_expiry"`
+
+	// LastSweep is when the last sweep ran, nil before the first.
+	LastSweep *time.Time `json:"last_sweep,omitempty"`
+
+	// LastRemoved is the number of annotations the last sweep deleted.
+	LastRemoved int64 `json:"last_removed"`
+
+	// TotalRemoved is the number of annotations deleted since start.
+	TotalRemoved int64 `json:"total_removed"`
+}
+
+// NewAnnotationReaper creates a reaper sweeping every
+// AnnotationReaperInterval.
+func NewAnnotationReaper(database *db.Database) *AnnotationReaper {
+	return &AnnotationReaper{
+		db:       database,
+		interval: AnnotationReaperInterval,
+	}
+}
+
+// Start deletes expired annotations every interval until ctx is cancelled.
+func (r *AnnotationReaper) Start(ctx context.Context) {
+	log.Printf("Starting collaboration annotation reaper (interval %s)", r.interval)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("Collaboration annotation reaper stopped")
+			return
+		case now := <-ticker.C:
+			if _, err := r.Sweep(ctx, now); err != nil {
+				log.Printf("Collaboration annotation reaping failed: %v", err)
+			}
+		}
+	}
+}
+
+// Sweep deletes the annotations that expired before now and returns how
+// many it deleted.
+func (r *AnnotationReaper) Sweep(ctx context.Context, now time.Time) (int64, error) {
+	result, err := r.db.DB().ExecContext(ctx, `
+		DELETE FROM collaboration_annotations
+		WHERE expires_at IS NOT NULL AND expires_at < $1
+	`, now)
+	if err != nil {
+		return 0, err
+	}
+	removed, _ := result.RowsAffected()
+
+	r.mu.Lock()
+	r.lastSweep = now
+	r.lastRemoved = removed
+	r.totalRemoved += removed
+	r.mu.Unlock()
+
+	if removed > 0 {
+		log.Printf("Deleted %d expired collaboration annotations", removed)
+	}
+	return removed, nil
+}
+
+// GetStats returns the number of annotations awaiting expiry and the
+// results of past sweeps.
+func (r *AnnotationReaper) GetStats(ctx context.Context) (AnnotationReaperStats, error) {
+	var stats AnnotationReaperStats
+	err := r.db.DB().QueryRowContext(ctx, `
+		SELECT COUNT(*) FROM collaboration_annotations WHERE expires_at IS NOT NULL
+	`).Scan(&stats.PendingExpiry)
+	if err != nil {
+		return stats, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.lastSweep.IsZero() {
+		lastSweep := r.lastSweep
+		stats.LastSweep = &lastSweep
+	}
+	stats.LastRemoved = r.lastRemoved
+	stats.TotalRemoved = r.totalRemoved
+	return stats, nil
+}
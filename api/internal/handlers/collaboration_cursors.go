@@ -0,0 +1,471 @@
+// Package handlers - collaboration_cursors.go
+//
+// This file implements live cursor sharing for collaboration sessions.
+//
+// Participants watching a collaboration connect to
+//
+//	GET /api/v1/collaboration/:collabId/ws
+//
+// Any active participant may connect. On connect they receive the last
+// known positions of the other connected participants, then every cursor
+// move as
+//
+//	{"type": "collaboration.cursor", "data": {"collaboration_id": ..., "user_id": ..., "x": 120, "y": 340, "timestamp": ...}}
+//
+// and a "collaboration.cursor.left" message when a participant's last
+// connection closes. Participants with can_annotate or can_control move
+// their cursor by sending
+//
+//	{"type": "cursor.move", "x": 120, "y": 340}
+//
+// Moves from other participants, and other message types, are ignored.
+// Each user's moves are fanned out at most CollaborationCursorMaxRate times
+// per second; moves in between update the remembered position but aren't
+// sent. Slow connections drop cursor updates rather than queueing them.
+//
+// Positions live in memory. Only the last position of users who moved is
+// written to collaboration_participants.cursor_position, once per persist
+// interval, so the database sees no per-move writes.
+//
+// Permissions are loaded on connect and rechecked every heartbeat interval;
+// a participant who left or timed out is disconnected, and one who lost
+// can_annotate and can_control can only watch.
+//
+// Configuration:
+//
+//	COLLABORATION_CURSOR_PERSIST_INTERVAL=5s  // How often positions are saved (default: 5s)
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// CollaborationCursorMaxRate is how many cursor moves per second are
+	// fanned out per user.
+	CollaborationCursorMaxRate = 20
+
+	// DefaultCollaborationCursorPersistInterval is how often moved cursor
+	// positions are saved.
+	DefaultCollaborationCursorPersistInterval = 5 * time.Second
+
+	// collaborationCursorBufferSize is the outgoing message buffer of a
+	// cursor connection.
+	collaborationCursorBufferSize = 64
+
+	// collaborationCursorMaxMessageSize caps messages from clients.
+	collaborationCursorMaxMessageSize = 512
+)
+
+// collaborationCursorPersistIntervalFromEnv reads
+// COLLABORATION_CURSOR_PERSIST_INTERVAL.
+func collaborationCursorPersistIntervalFromEnv() time.Duration {
+	env := os.Getenv("COLLABORATION_CURSOR_PERSIST_INTERVAL")
+	if env == "" {
+		return DefaultCollaborationCursorPersistInterval
+	}
+	interval, err := time.ParseDuration(env)
+	if err != nil || interval <= 0 {
+		log.Printf("Invalid COLLABORATION_CURSOR_PERSIST_INTERVAL %q, using default %s", env, DefaultCollaborationCursorPersistInterval)
+		return DefaultCollaborationCursorPersistInterval
+	}
+	return interval
+}
+
+// cursorPersistInterval returns the configured persist interval, falling
+// back to the default.
+func (h *CollaborationHandler) cursorPersistInterval() time.Duration {
+	if h.CursorPersistInterval <= 0 {
+		return DefaultCollaborationCursorPersistInterval
+	}
+	return h.CursorPersistInterval
+}
+
+// cursorKey identifies a user's cursor in a collaboration.
+type cursorKey struct {
+	collabID string
+	userID   string
+}
+
+// cursorConn is a participant's connection to a collaboration's cursors.
+type cursorConn struct {
+	collabID string
+	userID   string
+
+	// send carries encoded messages to the connection's writer.
+	send chan []byte
+
+	// canMove is whether the participant may move their cursor.
+	canMove atomic.Bool
+}
+
+// cursorRooms tracks the cursor connections and positions of all
+// collaborations.
+type cursorRooms struct {
+	mu sync.Mutex
+
+	// minInterval is the minimum time between fanned out moves of a user.
+	minInterval time.Duration
+
+	// rooms maps collabID -> connections.
+	rooms map[string]map[*cursorConn]bool
+
+	// conns counts each user's connections to a collaboration.
+	conns map[cursorKey]int
+
+	// positions is the last known position of each cursor.
+	positions map[cursorKey]CursorPosition
+
+	// lastSent is when each cursor's last move was fanned out.
+	lastSent map[cursorKey]time.Time
+
+	// dirty marks the cursors that moved since they were last saved.
+	dirty map[cursorKey]bool
+
+	// now is the clock; tests replace it.
+	now func() time.Time
+}
+
+// newCursorRooms creates cursor rooms fanning out at most maxRate moves
+// per user per second.
+func newCursorRooms(maxRate int) *cursorRooms {
+	return &cursorRooms{
+		minInterval: time.Second / time.Duration(maxRate),
+		rooms:       make(map[string]map[*cursorConn]bool),
+		conns:       make(map[cursorKey]int),
+		positions:   make(map[cursorKey]CursorPosition),
+		lastSent:    make(map[cursorKey]time.Time),
+		dirty:       make(map[cursorKey]bool),
+		now:         time.Now,
+	}
+}
+
+// join adds a connection to its collaboration's room and returns the last
+// known positions of the other connected users.
+func (r *cursorRooms) join(conn *cursorConn) map[string]CursorPosition {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	room := r.rooms[conn.collabID]
+	if room == nil {
+		room = make(map[*cursorConn]bool)
+		r.rooms[conn.collabID] = room
+	}
+	room[conn] = true
+	r.conns[cursorKey{conn.collabID, conn.userID}]++
+
+	positions := make(map[string]CursorPosition)
+	for key, position := range r.positions {
+		if key.collabID == conn.collabID && key.userID != conn.userID && r.conns[key] > 0 {
+			positions[key.userID] = position
+		}
+	}
+	return positions
+}
+
+// leave removes a connection from its room. It reports whether that was
+// the user's last connection to the collaboration. The connection receives
+// nothing once leave returns.
+func (r *cursorRooms) leave(conn *cursorConn) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	room := r.rooms[conn.collabID]
+	if !room[conn] {
+		return false
+	}
+	delete(room, conn)
+	if len(room) == 0 {
+		delete(r.rooms, conn.collabID)
+	}
+
+	key := cursorKey{conn.collabID, conn.userID}
+	r.conns[key]--
+	if r.conns[key] > 0 {
+		return false
+	}
+	delete(r.conns, key)
+	delete(r.lastSent, key)
+	if !r.dirty[key] {
+		delete(r.positions, key)
+	}
+	return true
+}
+
+// move records a cursor position and reports whether it should be fanned
+// out, i.e. the user's previous fanned out move is at least minInterval
+// old.
+func (r *cursorRooms) move(collabID, userID string, x, y int) (CursorPosition, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	key := cursorKey{collabID, userID}
+	position := CursorPosition{X: x, Y: y, Timestamp: now}
+	r.positions[key] = position
+	r.dirty[key] = true
+
+	if last, ok := r.lastSent[key]; ok && now.Sub(last) < r.minInterval {
+		return position, false
+	}
+	r.lastSent[key] = now
+	return position, true
+}
+
+// fanOut sends data to the connections of the other users in the sender's
+// collaboration, dropping it for connections whose buffer is full. Returns
+// the number of connections it was sent to.
+func (r *cursorRooms) fanOut(sender *cursorConn, data []byte) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sent := 0
+	for conn := range r.rooms[sender.collabID] {
+		if conn.userID == sender.userID {
+			continue
+		}
+		select {
+		case conn.send <- data:
+			sent++
+		default:
+		}
+	}
+	return sent
+}
+
+// takeDirty returns the positions of the cursors that moved since the last
+// call and forgets the positions of users who are no longer connected.
+func (r *cursorRooms) takeDirty() map[cursorKey]CursorPosition {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	positions := make(map[cursorKey]CursorPosition, len(r.dirty))
+	for key := range r.dirty {
+		positions[key] = r.positions[key]
+		if r.conns[key] == 0 {
+			delete(r.positions, key)
+		}
+	}
+	r.dirty = make(map[cursorKey]bool)
+	return positions
+}
+
+// cursorMoveMessage is a message from a cursor connection.
+type cursorMoveMessage struct {
+	Type string `json:"type"`
+	X    int    `json:"x"`
+	Y    int    `json:"y"`
+}
+
+// encodeCursorMessage encodes a cursor message for a collaboration's
+// connections. position is nil for "collaboration.cursor.left".
+func encodeCursorMessage(msgType, collabID, userID string, position *CursorPosition) []byte {
+	data := map[string]interface{}{
+		"collaboration_id": collabID,
+		"user_id":          userID,
+	}
+	if position != nil {
+		data["x"] = position.X
+		data["y"] = position.Y
+		data["timestamp"] = position.Timestamp
+	}
+
+	encoded, err := json.Marshal(WebSocketMessage{
+		Type:      msgType,
+		Timestamp: time.Now(),
+		Data:      data,
+	})
+	if err != nil {
+		log.Printf("Failed to marshal %s message: %v", msgType, err)
+		return nil
+	}
+	return encoded
+}
+
+// canMoveCursor reports whether permissions allow moving the cursor.
+func canMoveCursor(perms CollaborationPermissions) bool {
+	return perms.CanAnnotate || perms.CanControl
+}
+
+// CollaborationWebSocket streams the cursors of a collaboration's
+// participants and accepts the connected participant's cursor moves.
+//
+// Returns 403 before upgrading if the user isn't an active participant.
+func (h *CollaborationHandler) CollaborationWebSocket(c *gin.Context) {
+	collabID := c.Param("collabId")
+	userID := c.GetString("user_id")
+
+	perms, ok := h.activePermissions(collabID, userID)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "not an active participant",
+			"message": "join the collaboration to see cursors",
+		})
+		return
+	}
+
+	ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		log.Printf("Failed to upgrade collaboration %s cursor connection for user %s: %v", collabID, userID, err)
+		return
+	}
+
+	conn := &cursorConn{
+		collabID: collabID,
+		userID:   userID,
+		send:     make(chan []byte, collaborationCursorBufferSize),
+	}
+	conn.canMove.Store(canMoveCursor(perms))
+
+	for otherID, position := range h.cursors.join(conn) {
+		position := position
+		select {
+		case conn.send <- encodeCursorMessage("collaboration.cursor", collabID, otherID, &position):
+		default:
+		}
+	}
+
+	go h.cursorWritePump(ws, conn)
+	h.cursorReadPump(ws, conn)
+
+	if h.cursors.leave(conn) {
+		h.cursors.fanOut(conn, encodeCursorMessage("collaboration.cursor.left", collabID, userID, nil))
+	}
+	close(conn.send)
+}
+
+// cursorReadPump reads cursor moves from a connection until it closes.
+func (h *CollaborationHandler) cursorReadPump(ws *websocket.Conn, conn *cursorConn) {
+	ws.SetReadLimit(collaborationCursorMaxMessageSize)
+	ws.SetReadDeadline(time.Now().Add(WebSocketReadDeadline))
+	ws.SetPongHandler(func(string) error {
+		ws.SetReadDeadline(time.Now().Add(WebSocketReadDeadline))
+		return nil
+	})
+
+	for {
+		_, data, err := ws.ReadMessage()
+		if err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+				log.Printf("Collaboration %s cursor connection of user %s failed: %v", conn.collabID, conn.userID, err)
+			}
+			return
+		}
+
+		var msg cursorMoveMessage
+		if err := json.Unmarshal(data, &msg); err != nil || msg.Type != "cursor.move" {
+			continue
+		}
+		if !conn.canMove.Load() || msg.X < 0 || msg.Y < 0 {
+			continue
+		}
+
+		position, ok := h.cursors.move(conn.collabID, conn.userID, msg.X, msg.Y)
+		if !ok {
+			continue
+		}
+		h.cursors.fanOut(conn, encodeCursorMessage("collaboration.cursor", conn.collabID, conn.userID, &position))
+	}
+}
+
+// cursorWritePump writes queued messages and pings to a connection and
+// rechecks the participant's permissions every heartbeat interval. It
+// closes the connection when the send channel is closed, a write fails or
+// the participant is no longer active.
+func (h *CollaborationHandler) cursorWritePump(ws *websocket.Conn, conn *cursorConn) {
+	ping := time.NewTicker(WebSocketPingInterval)
+	recheck := time.NewTicker(time.Duration(h.heartbeatIntervalSeconds()) * time.Second)
+	defer func() {
+		ping.Stop()
+		recheck.Stop()
+		ws.Close()
+	}()
+
+	for {
+		select {
+		case data, ok := <-conn.send:
+			ws.SetWriteDeadline(time.Now().Add(WebSocketWriteDeadline))
+			if !ok {
+				ws.WriteMessage(websocket.CloseMessage, []byte{})
+				return
+			}
+			if err := ws.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+
+		case <-ping.C:
+			ws.SetWriteDeadline(time.Now().Add(WebSocketWriteDeadline))
+			if err := ws.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+
+		case <-recheck.C:
+			perms, ok := h.activePermissions(conn.collabID, conn.userID)
+			if !ok {
+				ws.SetWriteDeadline(time.Now().Add(WebSocketWriteDeadline))
+				ws.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "not an active participant"))
+				return
+			}
+			conn.canMove.Store(canMoveCursor(perms))
+		}
+	}
+}
+
+// StartCursorPersistence saves moved cursor positions every persist
+// interval until ctx is cancelled, then saves them one last time.
+func (h *CollaborationHandler) StartCursorPersistence(ctx context.Context) {
+	interval := h.cursorPersistInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("Starting collaboration cursor persistence (interval %s)", interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			if _, err := h.PersistCursorPositions(); err != nil {
+				log.Printf("Collaboration cursor persistence failed: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if _, err := h.PersistCursorPositions(); err != nil {
+				log.Printf("Collaboration cursor persistence failed: %v", err)
+			}
+		}
+	}
+}
+
+// PersistCursorPositions saves the last position of every cursor that
+// moved since the previous call to collaboration_participants. Returns the
+// number of positions saved and the first error; positions that fail to
+// save are dropped, the next move saves them again.
+func (h *CollaborationHandler) PersistCursorPositions() (int, error) {
+	var firstErr error
+	saved := 0
+	for key, position := range h.cursors.takeDirty() {
+		_, err := h.DB.DB().Exec(`
+			UPDATE collaboration_participants
+			SET cursor_position = $1
+			WHERE collaboration_id = $2 AND user_id = $3
+		`, toJSONB(position), key.collabID, key.userID)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		saved++
+	}
+	return saved, firstErr
+}
@@ -0,0 +1,209 @@
+// Package handlers - collaboration_owner_departure.go
+//
+// This file ends or hands off collaborations whose owner is gone.
+//
+// A collaboration used to stay "active" forever after its owner left, even
+// with nobody left in it, skewing GetCollaborationStats and leaving its
+// websocket_url advertised. Now, when the owner leaves or times out:
+//
+//   - If no participant is active, the collaboration is ended (status
+//     "ended", ended_at set).
+//   - Otherwise, if the collaboration's promote_on_owner_leave setting is
+//     on, the earliest-joined active presenter becomes the owner and the
+//     previous owner becomes a presenter.
+//   - Otherwise the collaboration stays active without its owner, who can
+//     rejoin as owner.
+//
+// LeaveCollaborationSession handles owners leaving; the presence sweep
+// handles owners who timed out and collaborations everyone left. Once a
+// collaboration is created, it gets a presence timeout of grace before the
+// sweep considers it, so a new collaboration isn't ended before its owner
+// joins.
+//
+// Participants are told with a "collaboration.event" WebSocket message:
+//
+//	{"type": "collaboration.event", "data": {"collaboration_id": ..., "event": "ended" | "owner_changed", "owner_id": ...}}
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Owner departure outcomes.
+const (
+	// ownerDepartureEnded means the collaboration was ended.
+	ownerDepartureEnded = "ended"
+
+	// ownerDeparturePromoted means a presenter became the owner.
+	ownerDeparturePromoted = "owner_changed"
+)
+
+// handleOwnerDeparture ends the collaboration if no participant is active,
+// or promotes the earliest-joined active presenter to owner if settings
+// allow. It returns the outcome ("" if nothing changed) and, after a
+// promotion, the new owner.
+func (h *CollaborationHandler) handleOwnerDeparture(collabID, ownerID string, settings CollaborationSettings) (string, string, error) {
+	now := time.Now()
+	result, err := h.DB.DB().Exec(`
+		UPDATE collaboration_sessions
+		SET status = 'ended', ended_at = $1
+		WHERE id = $2 AND status = 'active'
+		  AND NOT EXISTS (SELECT 1 FROM collaboration_participants WHERE collaboration_id = $2 AND is_active = true)
+	`, now, collabID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to end collaboration %s: %w", collabID, err)
+	}
+	if rows, _ := result.RowsAffected(); rows > 0 {
+		h.broadcastCollaborationEvent(collabID, ownerDepartureEnded, ownerID)
+		return ownerDepartureEnded, "", nil
+	}
+
+	if !settings.PromoteOnOwnerLeave {
+		return "", "", nil
+	}
+
+	var newOwnerID string
+	err = h.DB.DB().QueryRow(`
+		SELECT user_id FROM collaboration_participants
+		WHERE collaboration_id = $1 AND is_active = true AND role = 'presenter'
+		ORDER BY joined_at ASC
+		LIMIT 1
+	`, collabID).Scan(&newOwnerID)
+	if err == sql.ErrNoRows {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to find a presenter of collaboration %s: %w", collabID, err)
+	}
+
+	// The owner check keeps concurrent departures from promoting twice
+	result, err = h.DB.DB().Exec(`
+		UPDATE collaboration_sessions
+		SET owner_id = $1
+		WHERE id = $2 AND owner_id = $3 AND status = 'active'
+	`, newOwnerID, collabID, ownerID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to transfer ownership of collaboration %s: %w", collabID, err)
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return "", "", nil
+	}
+
+	for _, change := range []struct{ userID, role string }{
+		{newOwnerID, "owner"},
+		{ownerID, "presenter"},
+	} {
+		if _, err := h.DB.DB().Exec(`
+			UPDATE collaboration_participants
+			SET role = $1, permissions = $2
+			WHERE collaboration_id = $3 AND user_id = $4
+		`, change.role, toJSONB(settings.permissionsFor(change.role)), collabID, change.userID); err != nil {
+			log.Printf("Failed to make user %s %s of collaboration %s: %v", change.userID, change.role, collabID, err)
+		}
+	}
+
+	h.DB.DB().Exec(`
+		INSERT INTO collaboration_chat (
+			collaboration_id, user_id, message, message_type
+		) VALUES ($1, $2, $3, $4)
+	`, collabID, "system", fmt.Sprintf("User %s is now the owner", newOwnerID), "system")
+
+	h.broadcastCollaborationEvent(collabID, ownerDeparturePromoted, newOwnerID)
+	return ownerDeparturePromoted, newOwnerID, nil
+}
+
+// SweepOwnerlessCollaborations handles active collaborations, created at
+// least a presence timeout ago, whose owner isn't an active participant.
+// Returns the number of collaborations ended.
+func (h *CollaborationHandler) SweepOwnerlessCollaborations(now time.Time) (int, error) {
+	rows, err := h.DB.DB().Query(`
+		SELECT cs.id, cs.owner_id, cs.settings
+		FROM collaboration_sessions cs
+		WHERE cs.status = 'active' AND cs.created_at < $1
+		  AND NOT EXISTS (
+			SELECT 1 FROM collaboration_participants cp
+			WHERE cp.collaboration_id = cs.id AND cp.user_id = cs.owner_id AND cp.is_active = true
+		  )
+	`, now.Add(-h.presenceTimeout()))
+	if err != nil {
+		return 0, err
+	}
+
+	type ownerless struct {
+		collabID string
+		ownerID  string
+		settings CollaborationSettings
+	}
+	var found []ownerless
+	for rows.Next() {
+		var o ownerless
+		var ownerID, settings sql.NullString
+		if err := rows.Scan(&o.collabID, &ownerID, &settings); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		o.ownerID = ownerID.String
+		if settings.Valid && settings.String != "" {
+			json.Unmarshal([]byte(settings.String), &o.settings)
+		}
+		found = append(found, o)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	ended := 0
+	for _, o := range found {
+		outcome, _, err := h.handleOwnerDeparture(o.collabID, o.ownerID, o.settings)
+		if err != nil {
+			log.Printf("Failed to handle owner departure of collaboration %s: %v", o.collabID, err)
+			continue
+		}
+		if outcome == ownerDepartureEnded {
+			ended++
+		}
+	}
+
+	if ended > 0 {
+		log.Printf("Ended %d collaborations without active participants", ended)
+	}
+	return ended, nil
+}
+
+// broadcastCollaborationEvent notifies every participant of a collaboration,
+// active or not, that it ended or changed owner.
+func (h *CollaborationHandler) broadcastCollaborationEvent(collabID, event, ownerID string) {
+	rows, err := h.DB.DB().Query(`
+		SELECT user_id FROM collaboration_participants
+		WHERE collaboration_id = $1
+	`, collabID)
+	if err != nil {
+		log.Printf("Failed to load participants of collaboration %s for %s event: %v", collabID, event, err)
+		return
+	}
+	defer rows.Close()
+
+	msg := WebSocketMessage{
+		Type:      "collaboration.event",
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"collaboration_id": collabID,
+			"event":            event,
+			"owner_id":         ownerID,
+		},
+	}
+
+	hub := GetWebSocketHub()
+	for rows.Next() {
+		var participantID string
+		if err := rows.Scan(&participantID); err != nil {
+			return
+		}
+		hub.BroadcastToUser(participantID, msg)
+	}
+}
@@ -0,0 +1,196 @@
+// Package handlers - collaboration_presence.go
+//
+// This file implements participant presence for collaboration sessions.
+//
+// A participant is active from join until leave, but a client that crashes
+// or loses its network never calls leave. Without presence tracking such a
+// participant stays is_active=true forever: active_users is inflated, and
+// the ghost counts against max_participants, blocking new joiners.
+//
+// Clients therefore send a heartbeat while they are in a collaboration:
+//
+//	POST /api/v1/collaboration/:collabId/heartbeat
+//
+// which refreshes last_seen_at. A background sweep marks participants
+// whose last heartbeat is older than the presence timeout inactive,
+// recomputes active_users and tells the remaining participants with a
+// "timed_out" presence event. The participant can rejoin as usual and gets
+// their role and color back.
+//
+// Configuration:
+//
+//	COLLABORATION_PRESENCE_TIMEOUT=90s  // Missed-heartbeat threshold (default: 90s)
+//
+// Clients should send a heartbeat every third of the timeout; the join and
+// heartbeat responses include the interval (heartbeat_interval_seconds).
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DefaultCollaborationPresenceTimeout is how long a participant may miss
+// heartbeats before being marked inactive.
+const DefaultCollaborationPresenceTimeout = 90 * time.Second
+
+// collaborationPresenceTimeoutFromEnv reads COLLABORATION_PRESENCE_TIMEOUT.
+func collaborationPresenceTimeoutFromEnv() time.Duration {
+	env := os.Getenv("COLLABORATION_PRESENCE_TIMEOUT")
+	if env == "" {
+		return DefaultCollaborationPresenceTimeout
+	}
+	timeout, err := time.ParseDuration(env)
+	if err != nil || timeout <= 0 {
+		log.Printf("Invalid COLLABORATION_PRESENCE_TIMEOUT %q, using default %s", env, DefaultCollaborationPresenceTimeout)
+		return DefaultCollaborationPresenceTimeout
+	}
+	return timeout
+}
+
+// presenceTimeout returns the configured presence timeout, falling back to
+// the default.
+func (h *CollaborationHandler) presenceTimeout() time.Duration {
+	if h.PresenceTimeout <= 0 {
+		return DefaultCollaborationPresenceTimeout
+	}
+	return h.PresenceTimeout
+}
+
+// heartbeatIntervalSeconds is how often clients should send a heartbeat,
+// leaving room for two missed heartbeats before the timeout.
+func (h *CollaborationHandler) heartbeatIntervalSeconds() int {
+	interval := int((h.presenceTimeout() / 3).Seconds())
+	if interval < 1 {
+		return 1
+	}
+	return interval
+}
+
+// ParticipantHeartbeat records that a participant is still present.
+//
+// Returns 404 if the user isn't an active participant (e.g. they were
+// already timed out), so the client knows to rejoin.
+func (h *CollaborationHandler) ParticipantHeartbeat(c *gin.Context) {
+	collabID := c.Param("collabId")
+	userID := c.GetString("user_id")
+
+	result, err := h.DB.DB().Exec(`
+		UPDATE collaboration_participants
+		SET last_seen_at = $1
+		WHERE collaboration_id = $2 AND user_id = $3 AND is_active = true
+	`, time.Now(), collabID, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to record heartbeat",
+			"message": fmt.Sprintf("Database update failed for user %s in collaboration %s: %v", userID, collabID, err),
+		})
+		return
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "not an active participant",
+			"message": "rejoin the collaboration to continue",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"heartbeat_interval_seconds": h.heartbeatIntervalSeconds(),
+	})
+}
+
+// StartPresenceSweep marks participants who stopped sending heartbeats
+// inactive and handles collaborations whose owner is gone (see
+// collaboration_owner_departure.go), checking every heartbeat interval
+// until ctx is cancelled.
+func (h *CollaborationHandler) StartPresenceSweep(ctx context.Context) {
+	interval := time.Duration(h.heartbeatIntervalSeconds()) * time.Second
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	log.Printf("Starting collaboration presence sweep (timeout %s, interval %s)", h.presenceTimeout(), interval)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if _, err := h.SweepStaleParticipants(now); err != nil {
+				log.Printf("Collaboration presence sweep failed: %v", err)
+			}
+			if _, err := h.SweepOwnerlessCollaborations(now); err != nil {
+				log.Printf("Collaboration owner departure sweep failed: %v", err)
+			}
+		}
+	}
+}
+
+// staleParticipant is a participant marked inactive by the sweep.
+type staleParticipant struct {
+	collabID string
+	userID   string
+	role     string
+	color    string
+}
+
+// SweepStaleParticipants marks active participants whose last heartbeat is
+// older than the presence timeout inactive, recomputes active_users of the
+// affected collaborations and broadcasts the departures. Returns the number
+// of participants marked inactive.
+func (h *CollaborationHandler) SweepStaleParticipants(now time.Time) (int, error) {
+	rows, err := h.DB.DB().Query(`
+		UPDATE collaboration_participants
+		SET is_active = false
+		WHERE is_active = true AND last_seen_at < $1
+		RETURNING collaboration_id, user_id, role, COALESCE(color, '')
+	`, now.Add(-h.presenceTimeout()))
+	if err != nil {
+		return 0, err
+	}
+
+	var stale []staleParticipant
+	for rows.Next() {
+		var p staleParticipant
+		if err := rows.Scan(&p.collabID, &p.userID, &p.role, &p.color); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		stale = append(stale, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	recounted := make(map[string]bool)
+	for _, p := range stale {
+		if !recounted[p.collabID] {
+			recounted[p.collabID] = true
+			h.DB.DB().Exec(`
+				UPDATE collaboration_sessions
+				SET active_users = (SELECT COUNT(*) FROM collaboration_participants WHERE collaboration_id = $1 AND is_active = true)
+				WHERE id = $1
+			`, p.collabID)
+		}
+
+		h.DB.DB().Exec(`
+			INSERT INTO collaboration_chat (
+				collaboration_id, user_id, message, message_type
+			) VALUES ($1, $2, $3, $4)
+		`, p.collabID, "system", fmt.Sprintf("User %s lost connection", p.userID), "system")
+
+		h.broadcastPresence(p.collabID, p.userID, "timed_out", p.role, p.color)
+	}
+
+	if len(stale) > 0 {
+		log.Printf("Marked %d collaboration participants inactive after missed heartbeats", len(stale))
+	}
+	return len(stale), nil
+}
@@ -0,0 +1,987 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupCollaborationTest(t *testing.T) (*CollaborationHandler, sqlmock.Sqlmock, func()) {
+	gin.SetMode(gin.TestMode)
+
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+
+	handler := NewCollaborationHandler(db.NewDatabaseForTesting(sqlDB))
+
+	cleanup := func() {
+		sqlDB.Close()
+	}
+
+	return handler, mock, cleanup
+}
+
+func newCreateCollaborationContext(sessionID, userID string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", userID)
+	c.Params = gin.Params{{Key: "sessionId", Value: sessionID}}
+	c.Request = httptest.NewRequest("POST", "/api/v1/collaboration/session/"+sessionID, nil)
+	return c, w
+}
+
+// ============================================================================
+// CREATE COLLABORATION TESTS
+// ============================================================================
+
+func TestCreateCollaborationSession_Success(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT user_id FROM sessions WHERE id = \$1`).
+		WithArgs("sess-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("owner"))
+
+	mock.ExpectQuery(`INSERT INTO collaboration_sessions .* ON CONFLICT \(session_id\) WHERE status = 'active' DO NOTHING`).
+		WithArgs(sqlmock.AnyArg(), "sess-1", "owner", sqlmock.AnyArg(), true, true, true, "active").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("collab-sess-1-1"))
+
+	mock.ExpectExec(`INSERT INTO collaboration_participants`).
+		WithArgs("collab-sess-1-1", "owner", "owner", sqlmock.AnyArg(), "#0066FF", true).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	c, w := newCreateCollaborationContext("sess-1", "owner")
+	handler.CreateCollaborationSession(c)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "collab-sess-1-1", response["collaboration_id"])
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateCollaborationSession_AlreadyActive(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT user_id FROM sessions WHERE id = \$1`).
+		WithArgs("sess-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("owner"))
+
+	// Conflict on the partial unique index: no row returned
+	mock.ExpectQuery(`INSERT INTO collaboration_sessions`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	mock.ExpectQuery(`SELECT id FROM collaboration_sessions\s+WHERE session_id = \$1 AND status = 'active'`).
+		WithArgs("sess-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("collab-existing"))
+
+	c, w := newCreateCollaborationContext("sess-1", "owner")
+	handler.CreateCollaborationSession(c)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "collab-existing", response["collaboration_id"])
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateCollaborationSession_ConcurrentCreates(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+
+	// Requests interleave arbitrarily, so match expectations in any order.
+	// Whichever insert runs first wins; the other hits the unique index.
+	mock.MatchExpectationsInOrder(false)
+
+	for i := 0; i < 2; i++ {
+		mock.ExpectQuery(`SELECT user_id FROM sessions WHERE id = \$1`).
+			WithArgs("sess-1").
+			WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("owner"))
+	}
+
+	mock.ExpectQuery(`INSERT INTO collaboration_sessions`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("collab-winner"))
+	mock.ExpectQuery(`INSERT INTO collaboration_sessions`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	mock.ExpectQuery(`SELECT id FROM collaboration_sessions\s+WHERE session_id = \$1 AND status = 'active'`).
+		WithArgs("sess-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("collab-winner"))
+
+	mock.ExpectExec(`INSERT INTO collaboration_participants`).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	const requests = 2
+	recorders := make([]*httptest.ResponseRecorder, requests)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for i := 0; i < requests; i++ {
+		c, w := newCreateCollaborationContext("sess-1", "owner")
+		recorders[i] = w
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			handler.CreateCollaborationSession(c)
+		}()
+	}
+
+	close(start)
+	wg.Wait()
+
+	statuses := map[int]int{}
+	for _, w := range recorders {
+		statuses[w.Code]++
+
+		var response map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "collab-winner", response["collaboration_id"])
+	}
+
+	assert.Equal(t, 1, statuses[http.StatusCreated], "exactly one request should create the collaboration")
+	assert.Equal(t, 1, statuses[http.StatusConflict], "the other request should get 409 with the existing ID")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// ============================================================================
+// COLOR ASSIGNMENT TESTS
+// ============================================================================
+
+func TestPickLeastUsedColor_PrefersUnusedInPaletteOrder(t *testing.T) {
+	palette := []string{"red", "green", "blue"}
+
+	assert.Equal(t, "red", pickLeastUsedColor(palette, nil))
+	assert.Equal(t, "green", pickLeastUsedColor(palette, []string{"red"}))
+	assert.Equal(t, "red", pickLeastUsedColor(palette, []string{"green", "blue"}))
+
+	// Beyond the palette size, duplicates are spread evenly
+	assert.Equal(t, "blue", pickLeastUsedColor(palette, []string{"red", "green", "blue", "red", "green"}))
+}
+
+func TestPickLeastUsedColor_JoinsAndLeavesKeepColorsDistinct(t *testing.T) {
+	palette := DefaultCollaborationColors
+
+	// Simulate participants joining and leaving in an interleaved pattern.
+	// Every join picks the least-used color among currently active users.
+	active := map[string]string{"owner": palette[0]}
+	join := func(user string) {
+		taken := make([]string, 0, len(active))
+		for _, color := range active {
+			taken = append(taken, color)
+		}
+		active[user] = pickLeastUsedColor(palette, taken)
+	}
+
+	step := 0
+	for round := 0; round < 50; round++ {
+		// Fill up to the palette size
+		for len(active) < len(palette) {
+			step++
+			join(fmt.Sprintf("user-%d", step))
+		}
+
+		// Every active participant must have a distinct color
+		seen := make(map[string]string)
+		for user, color := range active {
+			if other, dup := seen[color]; dup {
+				t.Fatalf("round %d: %s and %s both have color %s", round, user, other, color)
+			}
+			seen[color] = user
+		}
+
+		// Some participants leave (never the owner)
+		left := 0
+		for user := range active {
+			if user != "owner" && left < 1+round%3 {
+				delete(active, user)
+				left++
+			}
+		}
+	}
+}
+
+func TestPickJoinColor_ReservesColorsOfParticipantsWhoLeft(t *testing.T) {
+	palette := []string{"red", "green", "blue"}
+
+	// green left - a newcomer gets blue, not green
+	assert.Equal(t, "blue", pickJoinColor(palette, []string{"red"}, []string{"green"}))
+
+	// Palette exhausted - fall back to least used among active participants
+	assert.Equal(t, "green", pickJoinColor(palette, []string{"red", "blue"}, []string{"green"}))
+}
+
+// ============================================================================
+// REJOIN TESTS
+// ============================================================================
+
+func newCollaborationContext(method, path, userID string, params gin.Params, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", userID)
+	c.Params = params
+	c.Request = httptest.NewRequest(method, path, strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	return c, w
+}
+
+func TestJoinCollaborationSession_PromoteLeaveRejoinKeepsRoleAndColor(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+
+	collabParams := gin.Params{{Key: "collabId", Value: "collab-1"}}
+	ownerPerms := toJSONB(defaultCollaborationPermissions("owner"))
+	presenterPerms := CollaborationPermissions{CanControl: true, CanAnnotate: true, CanChat: true, CanInvite: true}
+
+	// 1. Owner promotes bob to presenter
+	mock.ExpectQuery(`SELECT permissions FROM collaboration_participants`).
+		WithArgs("collab-1", "alice").
+		WillReturnRows(sqlmock.NewRows([]string{"permissions"}).AddRow(ownerPerms))
+	mock.ExpectQuery(`SELECT settings FROM collaboration_sessions`).
+		WithArgs("collab-1").
+		WillReturnRows(sqlmock.NewRows([]string{"settings"}).AddRow(`{"max_participants":10}`))
+	mock.ExpectExec(`UPDATE collaboration_participants\s+SET role = \$1, permissions = \$2`).
+		WithArgs("presenter", toJSONB(presenterPerms), "collab-1", "bob").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	body, err := json.Marshal(map[string]interface{}{"role": "presenter", "permissions": presenterPerms})
+	require.NoError(t, err)
+	c, w := newCollaborationContext("PATCH", "/api/v1/collaboration/collab-1/participants/bob", "alice",
+		gin.Params{{Key: "collabId", Value: "collab-1"}, {Key: "userId", Value: "bob"}}, string(body))
+	handler.UpdateParticipantRole(c)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// 2. Bob leaves
+	mock.ExpectExec(`UPDATE collaboration_participants\s+SET is_active = false`).
+		WithArgs(sqlmock.AnyArg(), "collab-1", "bob").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE collaboration_sessions`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO collaboration_chat`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT owner_id, settings FROM collaboration_sessions`).
+		WithArgs("collab-1").
+		WillReturnRows(sqlmock.NewRows([]string{"owner_id", "settings"}).AddRow("alice", `{"max_participants":10}`))
+
+	c, w = newCollaborationContext("POST", "/api/v1/collaboration/collab-1/leave", "bob", collabParams, "")
+	handler.LeaveCollaborationSession(c)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	// 3. Bob rejoins after carol took his color
+	mock.ExpectQuery(`SELECT session_id, owner_id, settings, status`).
+		WithArgs("collab-1").
+		WillReturnRows(sqlmock.NewRows([]string{"session_id", "owner_id", "settings", "status"}).
+			AddRow("sess-1", "alice", `{"max_participants":10}`, "active"))
+	mock.ExpectQuery(`SELECT user_id FROM sessions WHERE id = \$1`).
+		WithArgs("sess-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice"))
+	mock.ExpectQuery(`SELECT 1 FROM session_shares`).
+		WithArgs("sess-1", "bob").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT COALESCE\(color, ''\), is_active FROM collaboration_participants`).
+		WithArgs("collab-1", "bob").
+		WillReturnRows(sqlmock.NewRows([]string{"color", "is_active"}).
+			AddRow("#0066FF", true).
+			AddRow("#4ECDC4", true))
+	mock.ExpectQuery(`SELECT role, color, permissions FROM collaboration_participants`).
+		WithArgs("collab-1", "bob").
+		WillReturnRows(sqlmock.NewRows([]string{"role", "color", "permissions"}).
+			AddRow("presenter", "#4ECDC4", toJSONB(presenterPerms)))
+	mock.ExpectExec(`UPDATE collaboration_participants\s+SET is_active = true`).
+		WithArgs(sqlmock.AnyArg(), "#4ECDC4", "presenter", toJSONB(presenterPerms), "collab-1", "bob").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE collaboration_sessions`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT user_id FROM collaboration_participants`).
+		WithArgs("collab-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice").AddRow("bob").AddRow("carol"))
+
+	c, w = newCollaborationContext("POST", "/api/v1/collaboration/collab-1/join", "bob", collabParams, "{}")
+	handler.JoinCollaborationSession(c)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Message     string                   `json:"message"`
+		Role        string                   `json:"role"`
+		Color       string                   `json:"color"`
+		Permissions CollaborationPermissions `json:"permissions"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "rejoined successfully", response.Message)
+	assert.Equal(t, "presenter", response.Role, "promotion must survive leave/rejoin")
+	assert.Equal(t, presenterPerms, response.Permissions)
+	assert.Equal(t, "#4ECDC4", response.Color, "color must be stable across rejoin")
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJoinCollaborationSession_RejoinRestoresOwnerAndDefaultPermissions(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT session_id, owner_id, settings, status`).
+		WillReturnRows(sqlmock.NewRows([]string{"session_id", "owner_id", "settings", "status"}).
+			AddRow("sess-1", "alice", `{"max_participants":10}`, "active"))
+	mock.ExpectQuery(`SELECT user_id FROM sessions WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice"))
+	mock.ExpectQuery(`SELECT COALESCE\(color, ''\), is_active FROM collaboration_participants`).
+		WillReturnRows(sqlmock.NewRows([]string{"color", "is_active"}))
+	// Owner row drifted to "participant" with unreadable permissions
+	mock.ExpectQuery(`SELECT role, color, permissions FROM collaboration_participants`).
+		WillReturnRows(sqlmock.NewRows([]string{"role", "color", "permissions"}).
+			AddRow("participant", "#0066FF", "not-json"))
+	mock.ExpectExec(`UPDATE collaboration_participants\s+SET is_active = true`).
+		WithArgs(sqlmock.AnyArg(), "#0066FF", "owner", toJSONB(defaultCollaborationPermissions("owner")), "collab-1", "alice").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE collaboration_sessions`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT user_id FROM collaboration_participants`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice"))
+
+	c, w := newCollaborationContext("POST", "/api/v1/collaboration/collab-1/join", "alice",
+		gin.Params{{Key: "collabId", Value: "collab-1"}}, "{}")
+	handler.JoinCollaborationSession(c)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCollaborationSettings_PermissionsFor(t *testing.T) {
+	settings := CollaborationSettings{
+		RolePermissions: map[string]CollaborationPermissions{
+			"viewer": {CanViewOnly: true},
+			"owner":  {},
+		},
+	}
+	assert.Equal(t, CollaborationPermissions{CanViewOnly: true}, settings.permissionsFor("viewer"))
+	assert.Equal(t, defaultCollaborationPermissions("participant"), settings.permissionsFor("participant"))
+	assert.Equal(t, defaultCollaborationPermissions("owner"), settings.permissionsFor("owner"), "owner permissions are not configurable")
+
+	settings.LockOnPresenter = true
+	assert.False(t, settings.permissionsFor("participant").CanControl)
+	assert.True(t, settings.permissionsFor("presenter").CanControl)
+	assert.True(t, settings.permissionsFor("owner").CanControl)
+}
+
+func TestCollaborationSettings_Validate(t *testing.T) {
+	assert.NoError(t, CollaborationSettings{DefaultRole: "viewer"}.validate())
+	assert.Error(t, CollaborationSettings{DefaultRole: "owner"}.validate())
+	assert.Error(t, CollaborationSettings{DefaultRole: "guest"}.validate())
+	assert.Error(t, CollaborationSettings{RolePermissions: map[string]CollaborationPermissions{"owner": {}}}.validate())
+}
+
+func TestCreateCollaborationSession_RejectsInvalidDefaultRole(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+
+	c, w := newCollaborationContext("POST", "/api/v1/collaboration/session/sess-1", "alice",
+		gin.Params{{Key: "sessionId", Value: "sess-1"}}, `{"settings":{"default_role":"owner"}}`)
+	handler.CreateCollaborationSession(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJoinCollaborationSession_WebinarJoinsViewOnlyAndMuted(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+
+	settings := `{"max_participants":100,"default_role":"viewer","auto_mute_joiners":true}`
+	wantPerms := CollaborationPermissions{CanViewOnly: true}
+
+	mock.ExpectQuery(`SELECT session_id, owner_id, settings, status`).
+		WillReturnRows(sqlmock.NewRows([]string{"session_id", "owner_id", "settings", "status"}).
+			AddRow("sess-1", "alice", settings, "active"))
+	mock.ExpectQuery(`SELECT user_id FROM sessions WHERE id = \$1`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice"))
+	mock.ExpectQuery(`SELECT 1 FROM session_shares`).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectQuery(`SELECT COALESCE\(color, ''\), is_active FROM collaboration_participants`).
+		WillReturnRows(sqlmock.NewRows([]string{"color", "is_active"}).AddRow("#0066FF", true))
+	mock.ExpectQuery(`SELECT role, color, permissions FROM collaboration_participants`).
+		WillReturnRows(sqlmock.NewRows([]string{"role", "color", "permissions"}))
+	mock.ExpectExec(`INSERT INTO collaboration_participants`).
+		WithArgs("collab-1", "bob", "viewer", toJSONB(wantPerms), sqlmock.AnyArg(), true).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE collaboration_sessions`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO collaboration_chat`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT user_id FROM collaboration_participants`).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice").AddRow("bob"))
+
+	c, w := newCollaborationContext("POST", "/api/v1/collaboration/collab-1/join", "bob",
+		gin.Params{{Key: "collabId", Value: "collab-1"}}, "{}")
+	handler.JoinCollaborationSession(c)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Role        string                   `json:"role"`
+		Permissions CollaborationPermissions `json:"permissions"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "viewer", response.Role)
+	assert.Equal(t, wantPerms, response.Permissions, "viewer defaults minus chat")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUpdateParticipantRole_UsesRolePermissionsAndLock(t *testing.T) {
+	tests := []struct {
+		name      string
+		body      string
+		wantPerms CollaborationPermissions
+	}{
+		{
+			name:      "role only applies configured set",
+			body:      `{"role":"presenter"}`,
+			wantPerms: defaultCollaborationPermissions("presenter"),
+		},
+		{
+			name:      "explicit control stripped below presenter",
+			body:      `{"role":"participant","permissions":{"can_control":true,"can_chat":true}}`,
+			wantPerms: CollaborationPermissions{CanChat: true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mock, cleanup := setupCollaborationTest(t)
+			defer cleanup()
+
+			mock.ExpectQuery(`SELECT permissions FROM collaboration_participants`).
+				WithArgs("collab-1", "alice").
+				WillReturnRows(sqlmock.NewRows([]string{"permissions"}).AddRow(toJSONB(defaultCollaborationPermissions("owner"))))
+			mock.ExpectQuery(`SELECT settings FROM collaboration_sessions`).
+				WithArgs("collab-1").
+				WillReturnRows(sqlmock.NewRows([]string{"settings"}).AddRow(`{"lock_on_presenter":true}`))
+			mock.ExpectExec(`UPDATE collaboration_participants\s+SET role = \$1, permissions = \$2`).
+				WithArgs(sqlmock.AnyArg(), toJSONB(tt.wantPerms), "collab-1", "bob").
+				WillReturnResult(sqlmock.NewResult(0, 1))
+
+			c, w := newCollaborationContext("PATCH", "/api/v1/collaboration/collab-1/participants/bob", "alice",
+				gin.Params{{Key: "collabId", Value: "collab-1"}, {Key: "userId", Value: "bob"}}, tt.body)
+			handler.UpdateParticipantRole(c)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+// ============================================================================
+// ANNOTATION TESTS
+// ============================================================================
+
+func TestAnnotationTTL(t *testing.T) {
+	ttl, err := annotationTTL(0)
+	require.NoError(t, err)
+	assert.Equal(t, defaultAnnotationTTL, ttl)
+
+	ttl, err = annotationTTL(1800)
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Minute, ttl)
+
+	_, err = annotationTTL(-1)
+	assert.Error(t, err)
+	_, err = annotationTTL(int(maxAnnotationTTL.Seconds()) + 1)
+	assert.Error(t, err)
+}
+
+func TestCreateAnnotation_UsesRequestedTTL(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM collaboration_participants").
+		WillReturnRows(sqlmock.NewRows([]string{"permissions"}).AddRow(`{"can_annotate":true}`))
+	mock.ExpectQuery("SELECT session_id FROM collaboration_sessions").
+		WillReturnRows(sqlmock.NewRows([]string{"session_id"}).AddRow("sess-1"))
+	expectAnnotationCounts(mock, 0, 0, 0)
+	mock.ExpectExec("INSERT INTO collaboration_annotations").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", "user1")
+	c.Params = gin.Params{{Key: "collabId", Value: "collab-1"}}
+	c.Request = httptest.NewRequest("POST", "/api/v1/collaboration/collab-1/annotations",
+		strings.NewReader(`{"type":"arrow","ttl_seconds":1800}`))
+
+	before := time.Now()
+	handler.CreateAnnotation(c)
+
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var resp Annotation
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.ExpiresAt)
+	assert.WithinDuration(t, before.Add(30*time.Minute), *resp.ExpiresAt, 5*time.Second)
+}
+
+func TestCreateAnnotation_RejectsTTLAboveMax(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM collaboration_participants").
+		WillReturnRows(sqlmock.NewRows([]string{"permissions"}).AddRow(`{"can_annotate":true}`))
+	mock.ExpectQuery("SELECT session_id FROM collaboration_sessions").
+		WillReturnRows(sqlmock.NewRows([]string{"session_id"}).AddRow("sess-1"))
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Set("user_id", "user1")
+	c.Params = gin.Params{{Key: "collabId", Value: "collab-1"}}
+	c.Request = httptest.NewRequest("POST", "/api/v1/collaboration/collab-1/annotations",
+		strings.NewReader(`{"type":"arrow","ttl_seconds":86400}`))
+
+	handler.CreateAnnotation(c)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// expectAnnotationCounts expects the annotation cap count query.
+func expectAnnotationCounts(mock sqlmock.Sqlmock, total, own, persistent int) {
+	mock.ExpectQuery(`SELECT COUNT\(\*\),.*FROM collaboration_annotations`).
+		WillReturnRows(sqlmock.NewRows([]string{"total", "own", "persistent"}).AddRow(total, own, persistent))
+}
+
+// expectAnnotationCreatePrelude expects the permission and session lookups
+// of CreateAnnotation.
+func expectAnnotationCreatePrelude(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT .* FROM collaboration_participants").
+		WillReturnRows(sqlmock.NewRows([]string{"permissions"}).AddRow(`{"can_annotate":true}`))
+	mock.ExpectQuery("SELECT session_id FROM collaboration_sessions").
+		WillReturnRows(sqlmock.NewRows([]string{"session_id"}).AddRow("sess-1"))
+}
+
+func TestCreateAnnotation_Caps(t *testing.T) {
+	tests := []struct {
+		name                   string
+		body                   string
+		total, own, persistent int
+		wantCode               int
+		wantMessageContains    string
+	}{
+		{"room left", `{"type":"arrow"}`, 9, 4, 2, http.StatusCreated, ""},
+		{"collaboration full", `{"type":"arrow"}`, 10, 0, 0, http.StatusConflict, "maximum of 10 annotations"},
+		{"user full", `{"type":"arrow"}`, 5, 5, 0, http.StatusConflict, "You have the maximum of 5"},
+		{"persistent full", `{"type":"arrow","is_persistent":true}`, 3, 0, 3, http.StatusConflict, "maximum of 3 persistent"},
+		{"temporary despite persistent full", `{"type":"arrow"}`, 3, 0, 3, http.StatusCreated, ""},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, mock, cleanup := setupCollaborationTest(t)
+			defer cleanup()
+			handler.AnnotationLimits = AnnotationLimits{MaxPerCollaboration: 10, MaxPerUser: 5, MaxPersistent: 3}
+
+			expectAnnotationCreatePrelude(mock)
+			expectAnnotationCounts(mock, tt.total, tt.own, tt.persistent)
+			if tt.wantCode == http.StatusCreated {
+				mock.ExpectExec("INSERT INTO collaboration_annotations").
+					WillReturnResult(sqlmock.NewResult(1, 1))
+			}
+
+			collabID := fmt.Sprintf("collab-caps-%d", i)
+			c, w := newCollaborationContext("POST", "/api/v1/collaboration/"+collabID+"/annotations", "user1",
+				gin.Params{{Key: "collabId", Value: collabID}}, tt.body)
+			handler.CreateAnnotation(c)
+
+			assert.Equal(t, tt.wantCode, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.wantMessageContains)
+			assert.NoError(t, mock.ExpectationsWereMet(), "rejected creates must not insert")
+		})
+	}
+}
+
+func TestCreateAnnotation_RateLimited(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+	handler.AnnotationLimits = AnnotationLimits{CreatesPerMinute: 2}
+	collabID := fmt.Sprintf("collab-rate-%d", time.Now().UnixNano())
+	params := gin.Params{{Key: "collabId", Value: collabID}}
+
+	for i := 0; i < 2; i++ {
+		expectAnnotationCreatePrelude(mock)
+		mock.ExpectExec("INSERT INTO collaboration_annotations").
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		c, w := newCollaborationContext("POST", "/api/v1/collaboration/"+collabID+"/annotations", "user1", params, `{"type":"arrow"}`)
+		handler.CreateAnnotation(c)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	// The third create is rejected before the annotation is looked at
+	mock.ExpectQuery("SELECT .* FROM collaboration_participants").
+		WillReturnRows(sqlmock.NewRows([]string{"permissions"}).AddRow(`{"can_annotate":true}`))
+	c, w := newCollaborationContext("POST", "/api/v1/collaboration/"+collabID+"/annotations", "user1", params, `{"type":"arrow"}`)
+	handler.CreateAnnotation(c)
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// Other users in the collaboration aren't affected
+	expectAnnotationCreatePrelude(mock)
+	mock.ExpectExec("INSERT INTO collaboration_annotations").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	c, w = newCollaborationContext("POST", "/api/v1/collaboration/"+collabID+"/annotations", "user2", params, `{"type":"arrow"}`)
+	handler.CreateAnnotation(c)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
+
+func TestAnnotationLimitsFromEnv(t *testing.T) {
+	t.Setenv("COLLABORATION_MAX_ANNOTATIONS", "")
+	t.Setenv("COLLABORATION_MAX_ANNOTATIONS_PER_USER", "20")
+	t.Setenv("COLLABORATION_MAX_PERSISTENT_ANNOTATIONS", "0")
+	t.Setenv("COLLABORATION_ANNOTATION_RATE", "lots")
+
+	limits := annotationLimitsFromEnv()
+	assert.Equal(t, DefaultAnnotationLimits.MaxPerCollaboration, limits.MaxPerCollaboration)
+	assert.Equal(t, 20, limits.MaxPerUser)
+	assert.Equal(t, 0, limits.MaxPersistent, "0 disables the cap")
+	assert.Equal(t, DefaultAnnotationLimits.CreatesPerMinute, limits.CreatesPerMinute)
+}
+
+// ============================================================================
+// PRESENCE TESTS
+// ============================================================================
+
+func TestParticipantHeartbeat(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+	handler.PresenceTimeout = 90 * time.Second
+	collabParams := gin.Params{{Key: "collabId", Value: "collab-1"}}
+
+	mock.ExpectExec(`UPDATE collaboration_participants\s+SET last_seen_at = \$1`).
+		WithArgs(sqlmock.AnyArg(), "collab-1", "bob").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	c, w := newCollaborationContext("POST", "/api/v1/collaboration/collab-1/heartbeat", "bob", collabParams, "")
+	handler.ParticipantHeartbeat(c)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"heartbeat_interval_seconds":30}`, w.Body.String())
+
+	// A participant who already timed out is told to rejoin
+	mock.ExpectExec(`UPDATE collaboration_participants\s+SET last_seen_at = \$1`).
+		WithArgs(sqlmock.AnyArg(), "collab-1", "carol").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	c, w = newCollaborationContext("POST", "/api/v1/collaboration/collab-1/heartbeat", "carol", collabParams, "")
+	handler.ParticipantHeartbeat(c)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSweepStaleParticipants_MarksInactiveAndRecounts(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+	handler.PresenceTimeout = 90 * time.Second
+	now := time.Now()
+
+	mock.ExpectQuery(`UPDATE collaboration_participants\s+SET is_active = false\s+WHERE is_active = true AND last_seen_at < \$1`).
+		WithArgs(now.Add(-90 * time.Second)).
+		WillReturnRows(sqlmock.NewRows([]string{"collaboration_id", "user_id", "role", "color"}).
+			AddRow("collab-1", "bob", "participant", "#FF6B6B").
+			AddRow("collab-1", "carol", "viewer", "#4ECDC4"))
+
+	// collab-1 is recounted once; each departure is announced
+	mock.ExpectExec(`UPDATE collaboration_sessions\s+SET active_users`).
+		WithArgs("collab-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO collaboration_chat`).
+		WithArgs("collab-1", "system", "User bob lost connection", "system").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT user_id FROM collaboration_participants`).
+		WithArgs("collab-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice"))
+	mock.ExpectExec(`INSERT INTO collaboration_chat`).
+		WithArgs("collab-1", "system", "User carol lost connection", "system").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT user_id FROM collaboration_participants`).
+		WithArgs("collab-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice"))
+
+	swept, err := handler.SweepStaleParticipants(now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, swept)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCollaborationPresenceTimeoutFromEnv(t *testing.T) {
+	t.Setenv("COLLABORATION_PRESENCE_TIMEOUT", "")
+	assert.Equal(t, DefaultCollaborationPresenceTimeout, collaborationPresenceTimeoutFromEnv())
+
+	t.Setenv("COLLABORATION_PRESENCE_TIMEOUT", "2m")
+	assert.Equal(t, 2*time.Minute, collaborationPresenceTimeoutFromEnv())
+
+	t.Setenv("COLLABORATION_PRESENCE_TIMEOUT", "soon")
+	assert.Equal(t, DefaultCollaborationPresenceTimeout, collaborationPresenceTimeoutFromEnv())
+}
+
+// ============================================================================
+// CURSOR TESTS
+// ============================================================================
+
+func TestCursorRooms_ThrottlesAndFansOutToOthers(t *testing.T) {
+	rooms := newCursorRooms(20)
+	now := time.Now()
+	rooms.now = func() time.Time { return now }
+
+	alice := &cursorConn{collabID: "collab-1", userID: "alice", send: make(chan []byte, 8)}
+	aliceTab := &cursorConn{collabID: "collab-1", userID: "alice", send: make(chan []byte, 8)}
+	bob := &cursorConn{collabID: "collab-1", userID: "bob", send: make(chan []byte, 8)}
+	other := &cursorConn{collabID: "collab-2", userID: "carol", send: make(chan []byte, 8)}
+	assert.Empty(t, rooms.join(alice))
+	rooms.join(aliceTab)
+	rooms.join(bob)
+	rooms.join(other)
+
+	_, ok := rooms.move("collab-1", "alice", 10, 20)
+	assert.True(t, ok, "first move is fanned out")
+	now = now.Add(10 * time.Millisecond)
+	position, ok := rooms.move("collab-1", "alice", 11, 21)
+	assert.False(t, ok, "moves within 50ms are throttled")
+	assert.Equal(t, 11, position.X)
+	now = now.Add(50 * time.Millisecond)
+	_, ok = rooms.move("collab-1", "alice", 12, 22)
+	assert.True(t, ok)
+
+	// Only other users in the same collaboration receive the move
+	assert.Equal(t, 1, rooms.fanOut(alice, []byte("move")))
+	assert.Len(t, bob.send, 1)
+	assert.Empty(t, aliceTab.send)
+	assert.Empty(t, other.send)
+
+	// Joiners get the last known positions of the others
+	late := &cursorConn{collabID: "collab-1", userID: "dave", send: make(chan []byte, 8)}
+	positions := rooms.join(late)
+	require.Contains(t, positions, "alice")
+	assert.Equal(t, 12, positions["alice"].X)
+
+	assert.False(t, rooms.leave(alice), "alice still has another tab")
+	assert.True(t, rooms.leave(aliceTab))
+}
+
+func TestPersistCursorPositions_SavesOnlyMovedCursors(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+
+	bob := &cursorConn{collabID: "collab-1", userID: "bob", send: make(chan []byte, 8)}
+	handler.cursors.join(bob)
+	handler.cursors.move("collab-1", "bob", 1, 2)
+	handler.cursors.move("collab-1", "bob", 3, 4)
+
+	mock.ExpectExec(`UPDATE collaboration_participants\s+SET cursor_position = \$1`).
+		WithArgs(sqlmock.AnyArg(), "collab-1", "bob").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	saved, err := handler.PersistCursorPositions()
+	require.NoError(t, err)
+	assert.Equal(t, 1, saved, "only the last position is saved")
+
+	// Nothing moved since, so nothing is written
+	saved, err = handler.PersistCursorPositions()
+	require.NoError(t, err)
+	assert.Zero(t, saved)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCollaborationWebSocket_RejectsInactiveParticipant(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+
+	mock.ExpectQuery(`SELECT permissions FROM collaboration_participants`).
+		WithArgs("collab-1", "mallory").
+		WillReturnRows(sqlmock.NewRows([]string{"permissions"}))
+
+	c, w := newCollaborationContext("GET", "/api/v1/collaboration/collab-1/ws", "mallory",
+		gin.Params{{Key: "collabId", Value: "collab-1"}}, "")
+	handler.CollaborationWebSocket(c)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCollaborationCursorPersistIntervalFromEnv(t *testing.T) {
+	t.Setenv("COLLABORATION_CURSOR_PERSIST_INTERVAL", "")
+	assert.Equal(t, DefaultCollaborationCursorPersistInterval, collaborationCursorPersistIntervalFromEnv())
+
+	t.Setenv("COLLABORATION_CURSOR_PERSIST_INTERVAL", "30s")
+	assert.Equal(t, 30*time.Second, collaborationCursorPersistIntervalFromEnv())
+
+	t.Setenv("COLLABORATION_CURSOR_PERSIST_INTERVAL", "-1s")
+	assert.Equal(t, DefaultCollaborationCursorPersistInterval, collaborationCursorPersistIntervalFromEnv())
+}
+
+// ============================================================================
+// ANNOTATION REAPER TESTS
+// ============================================================================
+
+func TestAnnotationReaper_DeletesExpiredAnnotations(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+	reaper := NewAnnotationReaper(handler.DB)
+	ctx := context.Background()
+	now := time.Now()
+
+	// One expired and one live temporary annotation are stored
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM collaboration_annotations WHERE expires_at IS NOT NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	stats, err := reaper.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.PendingExpiry)
+	assert.Nil(t, stats.LastSweep)
+
+	mock.ExpectExec(`DELETE FROM collaboration_annotations\s+WHERE expires_at IS NOT NULL AND expires_at < \$1`).
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	removed, err := reaper.Sweep(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+
+	// Only the live annotation is left
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM collaboration_annotations WHERE expires_at IS NOT NULL`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	stats, err = reaper.GetStats(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.PendingExpiry)
+	require.NotNil(t, stats.LastSweep)
+	assert.True(t, stats.LastSweep.Equal(now))
+	assert.Equal(t, int64(1), stats.LastRemoved)
+	assert.Equal(t, int64(1), stats.TotalRemoved)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// ============================================================================
+// OWNER DEPARTURE TESTS
+// ============================================================================
+
+// expectLeave expects the updates of a participant leaving collab-1 whose
+// owner is ownerID.
+func expectLeave(mock sqlmock.Sqlmock, userID, ownerID, settings string) {
+	mock.ExpectExec(`UPDATE collaboration_participants\s+SET is_active = false`).
+		WithArgs(sqlmock.AnyArg(), "collab-1", userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE collaboration_sessions\s+SET active_users`).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO collaboration_chat`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT owner_id, settings FROM collaboration_sessions`).
+		WithArgs("collab-1").
+		WillReturnRows(sqlmock.NewRows([]string{"owner_id", "settings"}).AddRow(ownerID, settings))
+}
+
+func TestLeaveCollaborationSession_LastOwnerEndsCollaboration(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+
+	expectLeave(mock, "alice", "alice", `{"max_participants":10}`)
+	mock.ExpectExec(`UPDATE collaboration_sessions\s+SET status = 'ended', ended_at = \$1`).
+		WithArgs(sqlmock.AnyArg(), "collab-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT user_id FROM collaboration_participants`).
+		WithArgs("collab-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice"))
+
+	c, w := newCollaborationContext("POST", "/api/v1/collaboration/collab-1/leave", "alice",
+		gin.Params{{Key: "collabId", Value: "collab-1"}}, "")
+	handler.LeaveCollaborationSession(c)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"left successfully","collaboration_status":"ended"}`, w.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLeaveCollaborationSession_OwnerWithoutPromotionKeepsCollaboration(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+
+	// bob is still active, so the collaboration isn't ended
+	expectLeave(mock, "alice", "alice", `{"max_participants":10}`)
+	mock.ExpectExec(`UPDATE collaboration_sessions\s+SET status = 'ended'`).
+		WithArgs(sqlmock.AnyArg(), "collab-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	c, w := newCollaborationContext("POST", "/api/v1/collaboration/collab-1/leave", "alice",
+		gin.Params{{Key: "collabId", Value: "collab-1"}}, "")
+	handler.LeaveCollaborationSession(c)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"left successfully"}`, w.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLeaveCollaborationSession_OwnerHandsOffToEarliestPresenter(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+
+	expectLeave(mock, "alice", "alice", `{"max_participants":10,"promote_on_owner_leave":true}`)
+	mock.ExpectExec(`UPDATE collaboration_sessions\s+SET status = 'ended'`).
+		WithArgs(sqlmock.AnyArg(), "collab-1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT user_id FROM collaboration_participants\s+WHERE collaboration_id = \$1 AND is_active = true AND role = 'presenter'\s+ORDER BY joined_at ASC`).
+		WithArgs("collab-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("bob"))
+	mock.ExpectExec(`UPDATE collaboration_sessions\s+SET owner_id = \$1\s+WHERE id = \$2 AND owner_id = \$3`).
+		WithArgs("bob", "collab-1", "alice").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE collaboration_participants\s+SET role = \$1, permissions = \$2`).
+		WithArgs("owner", toJSONB(defaultCollaborationPermissions("owner")), "collab-1", "bob").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE collaboration_participants\s+SET role = \$1, permissions = \$2`).
+		WithArgs("presenter", toJSONB(defaultCollaborationPermissions("presenter")), "collab-1", "alice").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO collaboration_chat`).
+		WithArgs("collab-1", "system", "User bob is now the owner", "system").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectQuery(`SELECT user_id FROM collaboration_participants`).
+		WithArgs("collab-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice").AddRow("bob"))
+
+	c, w := newCollaborationContext("POST", "/api/v1/collaboration/collab-1/leave", "alice",
+		gin.Params{{Key: "collabId", Value: "collab-1"}}, "")
+	handler.LeaveCollaborationSession(c)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"message":"left successfully","new_owner_id":"bob"}`, w.Body.String())
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSweepOwnerlessCollaborations_EndsAbandoned(t *testing.T) {
+	handler, mock, cleanup := setupCollaborationTest(t)
+	defer cleanup()
+	handler.PresenceTimeout = 90 * time.Second
+	now := time.Now()
+
+	mock.ExpectQuery(`SELECT cs.id, cs.owner_id, cs.settings\s+FROM collaboration_sessions cs`).
+		WithArgs(now.Add(-90 * time.Second)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "owner_id", "settings"}).
+			AddRow("collab-1", "alice", `{}`))
+	mock.ExpectExec(`UPDATE collaboration_sessions\s+SET status = 'ended'`).
+		WithArgs(sqlmock.AnyArg(), "collab-1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT user_id FROM collaboration_participants`).
+		WithArgs("collab-1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("alice").AddRow("bob"))
+
+	ended, err := handler.SweepOwnerlessCollaborations(now)
+	require.NoError(t, err)
+	assert.Equal(t, 1, ended)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
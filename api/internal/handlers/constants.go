@@ -25,11 +25,24 @@ import "time"
 // These values balance security (preventing brute force) with usability
 // (not frustrating legitimate users).
 const (
-	// BackupCodesCount is the number of backup codes to generate
+	// BackupCodesCount is the default number of backup codes to generate
 	BackupCodesCount = 10
 
-	// BackupCodeLength is the length of each backup code
-	BackupCodeLength = 8
+	// BackupCodeLength is the default length of each backup code (60 bits)
+	BackupCodeLength = 12
+
+	// BackupCodeGroupSize is the default size of dash-separated code groups
+	BackupCodeGroupSize = 6
+
+	// MinBackupCodesCount and MaxBackupCodesCount bound the configurable count
+	MinBackupCodesCount = 1
+	MaxBackupCodesCount = 50
+
+	// MaxBackupCodeLength is the longest configurable backup code
+	MaxBackupCodeLength = 32
+
+	// MinBackupCodeEntropyBits is the least entropy a backup code may have
+	MinBackupCodeEntropyBits = 40
 
 	// MFAMaxAttemptsPerMinute is the maximum MFA verification attempts per minute
 	MFAMaxAttemptsPerMinute = 5
@@ -57,6 +70,50 @@ const (
 
 	// WebSocketWriteBufferSize is the size of the write buffer
 	WebSocketWriteBufferSize = 1024
+
+	// WebSocketMaxEvictionsPerCycle is the default number of slow clients the
+	// hub removes per broadcast (override with WEBSOCKET_MAX_EVICTIONS_PER_CYCLE)
+	WebSocketMaxEvictionsPerCycle = 100
+
+	// WebSocketCloseIdle is the close code sent to connections closed for
+	// inactivity (4000-4999 are reserved for applications)
+	WebSocketCloseIdle = 4000
+
+	// WebSocketAckTypes are the message types delivered at least once by
+	// default (override with WEBSOCKET_ACK_TYPES, see websocket_ack.go)
+	WebSocketAckTypes = "security.alert,session.terminated"
+
+	// WebSocketAckRetryInterval is the default delay between resends of an
+	// unacknowledged message
+	WebSocketAckRetryInterval = 30 * time.Second
+
+	// WebSocketAckMaxAttempts is the default number of sends before an
+	// unacknowledged message waits for the user to reconnect
+	WebSocketAckMaxAttempts = 5
+
+	// WebSocketAckMaxPending caps unacknowledged messages kept per user
+	WebSocketAckMaxPending = 100
+
+	// WebSocketAckPendingTTL is how long unacknowledged messages are kept
+	WebSocketAckPendingTTL = 24 * time.Hour
+
+	// WebSocketMessagePriorities are the default delivery priorities by
+	// message type; unlisted types are normal (override with
+	// WEBSOCKET_MESSAGE_PRIORITIES, see webSocketPrioritiesFromEnv)
+	WebSocketMessagePriorities = "security.alert=critical,session.terminated=high,session.hibernating=high,compliance.violation=high,node.health=low,metrics=low"
+
+	// WebSocketProtocolVersion is the newest enterprise WebSocket envelope
+	// version the server emits (cap it with WEBSOCKET_MAX_PROTOCOL_VERSION,
+	// see websocket_version.go)
+	WebSocketProtocolVersion = 2
+
+	// WebSocketMaxSubscriptionTypes caps the message types one connection
+	// may subscribe to (see websocket_subscriptions.go)
+	WebSocketMaxSubscriptionTypes = 64
+
+	// WebSocketShutdownPollInterval is how often Shutdown checks whether
+	// client buffers have drained
+	WebSocketShutdownPollInterval = 50 * time.Millisecond
 )
 
 // Webhook Constants
@@ -0,0 +1,148 @@
+// Package handlers provides HTTP handlers for the StreamSpace API.
+// This file implements cost reporting.
+//
+// Finance needs to attribute platform cost to the users, teams and projects
+// that consumed it. The cost report turns session runtime and resource
+// requests into resource-hours and prices them (see package cost):
+//
+//	GET /api/v1/reports/cost?group_by=user&range=30d
+//
+// Query parameters:
+//   - group_by: user (default), group, template, or tag
+//   - range: Look-back window ending now, e.g. "30d", "12h" (default 30d)
+//   - from/to: Explicit RFC3339 range (overrides range)
+//
+// Unit prices come from COST_PRICE_CPU_HOUR, COST_PRICE_GB_HOUR and
+// COST_PRICE_GPU_HOUR.
+//
+// SECURITY:
+//
+// - Operator/admin only (exposes usage of every user)
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/cost"
+)
+
+const (
+	// costReportDefaultRange is the look-back window when none is given
+	costReportDefaultRange = 30 * 24 * time.Hour
+
+	// costReportMaxRange bounds how much history one report can scan
+	costReportMaxRange = 366 * 24 * time.Hour
+)
+
+// CostHandler handles cost reporting
+type CostHandler struct {
+	accountant *cost.Accountant
+}
+
+// NewCostHandler creates a new cost handler
+func NewCostHandler(accountant *cost.Accountant) *CostHandler {
+	return &CostHandler{accountant: accountant}
+}
+
+// RegisterRoutes registers cost reporting routes
+func (h *CostHandler) RegisterRoutes(router *gin.RouterGroup) {
+	reports := router.Group("/reports")
+	{
+		reports.GET("/cost", h.GetCostReport)
+	}
+}
+
+// GetCostReport returns resource-hours and cost grouped by user, group, template or tag
+func (h *CostHandler) GetCostReport(c *gin.Context) {
+	groupBy := c.DefaultQuery("group_by", cost.GroupByUser)
+	if !cost.ValidGroupBy(groupBy) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid group_by",
+			"message": "group_by must be one of: user, group, template, tag",
+		})
+		return
+	}
+
+	from, to, err := parseCostRange(c.Query("range"), c.Query("from"), c.Query("to"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid range",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	report, err := h.accountant.Report(c.Request.Context(), groupBy, from, to)
+	if err != nil {
+		log.Printf("Failed to build cost report: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to build cost report",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// parseCostRange resolves the report time range from query parameters.
+func parseCostRange(rangeParam, fromParam, toParam string, now time.Time) (time.Time, time.Time, error) {
+	if fromParam != "" || toParam != "" {
+		to := now
+		if toParam != "" {
+			t, err := time.Parse(time.RFC3339, toParam)
+			if err != nil {
+				return time.Time{}, time.Time{}, fmt.Errorf("to must be RFC3339: %v", err)
+			}
+			to = t
+		}
+		if fromParam == "" {
+			return to.Add(-costReportDefaultRange), to, nil
+		}
+		from, err := time.Parse(time.RFC3339, fromParam)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("from must be RFC3339: %v", err)
+		}
+		if !from.Before(to) {
+			return time.Time{}, time.Time{}, fmt.Errorf("from must be before to")
+		}
+		if to.Sub(from) > costReportMaxRange {
+			return time.Time{}, time.Time{}, fmt.Errorf("range must not exceed %d days", int(costReportMaxRange.Hours()/24))
+		}
+		return from, to, nil
+	}
+
+	window := costReportDefaultRange
+	if rangeParam != "" {
+		var err error
+		if window, err = parseRangeDuration(rangeParam); err != nil {
+			return time.Time{}, time.Time{}, err
+		}
+	}
+	if window <= 0 || window > costReportMaxRange {
+		return time.Time{}, time.Time{}, fmt.Errorf("range must be between 1h and %d days", int(costReportMaxRange.Hours()/24))
+	}
+	return now.Add(-window), now, nil
+}
+
+// parseRangeDuration parses "30d" style day ranges as well as Go durations.
+func parseRangeDuration(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid range %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid range %q (use e.g. 30d or 12h)", s)
+	}
+	return d, nil
+}
@@ -26,9 +26,11 @@
 //  4. Input Validation: Comprehensive validation for all webhook and integration fields
 //     including URL format, name length, event counts, retry configuration, etc.
 //
-// Webhook Delivery:
+// Webhook Delivery (see webhook_dispatch.go):
 // - Automatic retries with exponential backoff
-// - HMAC-SHA256 signature in X-Webhook-Signature header
+// - HMAC-SHA256 signature over timestamp and body in X-StreamSpace-Signature,
+//   with X-StreamSpace-Timestamp for replay protection (see package webhooksig)
+// - Event ID in X-StreamSpace-Event-ID and the body's "id" for deduplication
 // - 10-second timeout per delivery attempt
 // - Real-time delivery status updates via WebSocket
 //
@@ -41,12 +43,9 @@ package handlers
 
 import (
 	"bytes"
-	"crypto/hmac"
 	"crypto/rand"
-	"crypto/sha256"
 	"database/sql"
 	"encoding/base64"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -61,6 +60,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/webhooksig"
 )
 
 // IntegrationsHandler handles webhook and external integration requests.
@@ -246,6 +246,8 @@ type Integration struct {
 
 // WebhookEvent represents an event that can trigger webhooks
 type WebhookEvent struct {
+	// ID identifies the event; receivers use it to drop redeliveries
+	ID        string                 `json:"id"`
 	Event     string                 `json:"event"`
 	Timestamp time.Time              `json:"timestamp"`
 	Data      map[string]interface{} `json:"data"`
@@ -256,6 +258,8 @@ type WebhookEvent struct {
 var AvailableEvents = []string{
 	"session.created",
 	"session.started",
+	"session.ready",
+	"session.hibernating",
 	"session.hibernated",
 	"session.terminated",
 	"session.failed",
@@ -585,6 +589,7 @@ func (h *IntegrationsHandler) TestWebhook(c *gin.Context) {
 
 	// Create test event
 	testEvent := WebhookEvent{
+		ID:        uuid.New().String(),
 		Event:     "webhook.test",
 		Timestamp: time.Now(),
 		Data: map[string]interface{}{
@@ -594,7 +599,7 @@ func (h *IntegrationsHandler) TestWebhook(c *gin.Context) {
 	}
 
 	// Deliver webhook
-	success, statusCode, responseBody, err := h.deliverWebhook(webhook, testEvent)
+	success, statusCode, responseBody, err := h.deliverWebhook(webhook, testEvent, fmt.Sprintf("%d", time.Now().Unix()))
 
 	response := gin.H{
 		"success":     success,
@@ -904,7 +909,11 @@ func (h *IntegrationsHandler) generateWebhookSecret() string {
 	return "whsec_" + base64.URLEncoding.EncodeToString(b)
 }
 
-func (h *IntegrationsHandler) deliverWebhook(webhook Webhook, event WebhookEvent) (bool, int, string, error) {
+// deliverWebhook makes one delivery attempt. deliveryID is sent as
+// X-StreamSpace-Delivery and is the same for every retry of a delivery.
+// Each attempt is signed with its own timestamp, so a retry after a long
+// backoff is still within the receiver's replay tolerance.
+func (h *IntegrationsHandler) deliverWebhook(webhook Webhook, event WebhookEvent, deliveryID string) (bool, int, string, error) {
 	// Prepare payload
 	payload, _ := json.Marshal(event)
 
@@ -918,17 +927,17 @@ func (h *IntegrationsHandler) deliverWebhook(webhook Webhook, event WebhookEvent
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("User-Agent", "StreamSpace-Webhook/1.0")
 	req.Header.Set("X-StreamSpace-Event", event.Event)
-	req.Header.Set("X-StreamSpace-Delivery", fmt.Sprintf("%d", time.Now().Unix()))
+	req.Header.Set(webhooksig.EventIDHeader, event.ID)
+	req.Header.Set(webhooksig.DeliveryHeader, deliveryID)
 
 	// Add custom headers
 	for key, value := range webhook.Headers {
 		req.Header.Set(key, value)
 	}
 
-	// Calculate HMAC signature
+	// Sign timestamp and body
 	if webhook.Secret != "" {
-		signature := h.calculateHMAC(payload, webhook.Secret)
-		req.Header.Set("X-StreamSpace-Signature", signature)
+		webhooksig.SetHeaders(req.Header, webhook.Secret, time.Now(), payload)
 	}
 
 	// Send request with security restrictions
@@ -952,12 +961,6 @@ func (h *IntegrationsHandler) deliverWebhook(webhook Webhook, event WebhookEvent
 	return success, resp.StatusCode, string(responseBody), nil
 }
 
-func (h *IntegrationsHandler) calculateHMAC(payload []byte, secret string) string {
-	mac := hmac.New(sha256.New, []byte(secret))
-	mac.Write(payload)
-	return hex.EncodeToString(mac.Sum(nil))
-}
-
 func (h *IntegrationsHandler) testIntegration(integration Integration) (bool, string) {
 	// NOTE: Slack, Teams, Discord, PagerDuty, and Email integrations are now handled by plugins.
 	// Users should install the respective plugins from the plugin marketplace instead.
@@ -213,6 +213,43 @@ func (h *MonitoringHandler) PrometheusMetrics(c *gin.Context) {
 		"",
 	)
 
+	// Port-forward tunnel traffic ("in" is client to session)
+	var bytesIn, bytesOut int64
+	h.db.DB().QueryRowContext(ctx, `
+		SELECT COALESCE(SUM(bytes_in), 0), COALESCE(SUM(bytes_out), 0)
+		FROM session_bandwidth
+	`).Scan(&bytesIn, &bytesOut)
+
+	metrics = append(metrics,
+		"# HELP streamspace_proxy_bytes_total Bytes carried by session port-forward tunnels",
+		"# TYPE streamspace_proxy_bytes_total counter",
+		fmt.Sprintf("streamspace_proxy_bytes_total{direction=\"in\"} %d", bytesIn),
+		fmt.Sprintf("streamspace_proxy_bytes_total{direction=\"out\"} %d", bytesOut),
+		"",
+		"# HELP streamspace_user_proxy_bytes_total Bytes carried by session port-forward tunnels per user",
+		"# TYPE streamspace_user_proxy_bytes_total counter",
+	)
+
+	userRows, err := h.db.DB().QueryContext(ctx, `
+		SELECT user_id, SUM(bytes_in), SUM(bytes_out)
+		FROM session_bandwidth
+		GROUP BY user_id
+	`)
+	if err == nil {
+		defer userRows.Close()
+		for userRows.Next() {
+			var userID string
+			var userIn, userOut int64
+			if userRows.Scan(&userID, &userIn, &userOut) == nil {
+				metrics = append(metrics,
+					fmt.Sprintf("streamspace_user_proxy_bytes_total{user=%q,direction=\"in\"} %d", userID, userIn),
+					fmt.Sprintf("streamspace_user_proxy_bytes_total{user=%q,direction=\"out\"} %d", userID, userOut),
+				)
+			}
+		}
+	}
+	metrics = append(metrics, "")
+
 	// System metrics
 	var memStats runtime.MemStats
 	runtime.ReadMemStats(&memStats)
@@ -83,10 +83,7 @@ package handlers
 import (
 	"bytes"
 	"context"
-	"crypto/hmac"
-	"crypto/sha256"
 	"database/sql"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -97,7 +94,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/webhooksig"
 )
 
 // NotificationsHandler handles notification delivery and management
@@ -627,7 +626,9 @@ func (h *NotificationsHandler) sendWebhookNotification(prefs map[string]interfac
 	}
 
 	// Create webhook payload
+	eventID := uuid.New().String()
 	payload := map[string]interface{}{
+		"id":        eventID,
 		"event":     eventType,
 		"userId":    userID,
 		"title":     title,
@@ -638,7 +639,7 @@ func (h *NotificationsHandler) sendWebhookNotification(prefs map[string]interfac
 
 	payloadJSON, _ := json.Marshal(payload)
 
-	// Create signature (HMAC-SHA256)
+	// Signed with timestamp (HMAC-SHA256, see package webhooksig)
 	// SECURITY: WEBHOOK_SECRET must be set for production use
 	webhookSecret := os.Getenv("WEBHOOK_SECRET")
 	if webhookSecret == "" {
@@ -647,10 +648,6 @@ func (h *NotificationsHandler) sendWebhookNotification(prefs map[string]interfac
 		return fmt.Errorf("WEBHOOK_SECRET environment variable must be set for security")
 	}
 
-	mac := hmac.New(sha256.New, []byte(webhookSecret))
-	mac.Write(payloadJSON)
-	signature := hex.EncodeToString(mac.Sum(nil))
-
 	// Send HTTP POST request
 	req, err := http.NewRequest("POST", webhookURL, bytes.NewBuffer(payloadJSON))
 	if err != nil {
@@ -658,8 +655,9 @@ func (h *NotificationsHandler) sendWebhookNotification(prefs map[string]interfac
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-StreamSpace-Signature", signature)
 	req.Header.Set("X-StreamSpace-Event", eventType)
+	req.Header.Set(webhooksig.EventIDHeader, eventID)
+	webhooksig.SetHeaders(req.Header, webhookSecret, time.Now(), payloadJSON)
 
 	client := &http.Client{Timeout: 10 * time.Second}
 	resp, err := client.Do(req)
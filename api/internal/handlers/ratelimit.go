@@ -0,0 +1,72 @@
+// Package handlers provides HTTP handlers for the StreamSpace API.
+// This file implements rate limiter introspection for administrators.
+//
+// When a legitimate user gets caught by a rate limit (e.g. repeated MFA
+// failures), support needs to see the limiter's state and clear it. These
+// endpoints expose a single key at a time:
+//
+//	GET    /api/v1/admin/ratelimit/:key  - attempts, limit, window, reset time
+//	DELETE /api/v1/admin/ratelimit/:key  - clear the key (ResetLimit)
+//
+// Keys are the ones handlers pass to the limiter, e.g. "mfa_verify:{userID}".
+//
+// SECURITY:
+//
+// - Admin-only access (clearing a key lifts brute force protection)
+// - Every reset is logged with the acting admin
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/middleware"
+)
+
+// RateLimitHandler handles rate limiter introspection
+type RateLimitHandler struct {
+	limiter *middleware.RateLimiter
+}
+
+// NewRateLimitHandler creates a new rate limit handler
+func NewRateLimitHandler(limiter *middleware.RateLimiter) *RateLimitHandler {
+	return &RateLimitHandler{limiter: limiter}
+}
+
+// GetRateLimit returns the current state of a rate limit key
+func (h *RateLimitHandler) GetRateLimit(c *gin.Context) {
+	key := c.Param("key")
+
+	status, exists := h.limiter.Inspect(key)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Rate limit key not found",
+			"message": "No attempts have been recorded for this key recently",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+// ResetRateLimit clears all recorded attempts for a rate limit key
+func (h *RateLimitHandler) ResetRateLimit(c *gin.Context) {
+	key := c.Param("key")
+
+	status, exists := h.limiter.Inspect(key)
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rate limit key not found"})
+		return
+	}
+
+	h.limiter.ResetLimit(key)
+
+	log.Printf("Rate limit key %s reset by admin %s (had %d/%d attempts, limited=%t)",
+		key, c.GetString("userID"), status.Attempts, status.MaxAttempts, status.Limited)
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Rate limit reset",
+		"key":     key,
+	})
+}
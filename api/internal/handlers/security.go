@@ -26,12 +26,11 @@
 package handlers
 
 import (
-	"crypto/rand"
 	"crypto/sha256"
 	"database/sql"
-	"encoding/base32"
 	"encoding/hex"
 	"fmt"
+	"log"
 	"net"
 	"net/http"
 	"strings"
@@ -281,7 +280,7 @@ func NewSecurityHandler(database *db.Database) *SecurityHandler {
 }
 
 func (h *SecurityHandler) SetupMFA(c *gin.Context) {
-	userID := c.GetString("user_id")
+	userID := c.GetString("userID")
 
 	var req struct {
 		Type        string `json:"type" binding:"required,oneof=totp sms email"`
@@ -387,7 +386,7 @@ func (h *SecurityHandler) SetupMFA(c *gin.Context) {
 
 // VerifyMFASetup verifies and enables MFA method (Step 2: Confirm setup)
 func (h *SecurityHandler) VerifyMFASetup(c *gin.Context) {
-	userID := c.GetString("user_id")
+	userID := c.GetString("userID")
 	mfaID := c.Param("mfaId")
 
 	var req struct {
@@ -431,6 +430,8 @@ func (h *SecurityHandler) VerifyMFASetup(c *gin.Context) {
 		return
 	}
 
+	policy := h.loadBackupCodePolicy(c.Request.Context())
+
 	// SECURITY: Use transaction to ensure atomicity
 	// Either both MFA enable AND backup codes succeed, or neither
 	tx, err := h.DB.Begin()
@@ -459,27 +460,20 @@ func (h *SecurityHandler) VerifyMFASetup(c *gin.Context) {
 	}
 
 	// Generate backup codes within transaction
-	backupCodes := make([]string, BackupCodesCount)
-	for i := 0; i < BackupCodesCount; i++ {
-		code := generateRandomCode(BackupCodeLength)
-		backupCodes[i] = code
-
-		// Hash and store
-		hash := sha256.Sum256([]byte(code))
-		hashStr := hex.EncodeToString(hash[:])
-
-		_, err := tx.Exec(`
-			INSERT INTO backup_codes (user_id, code)
-			VALUES ($1, $2)
-		`, userID, hashStr)
-
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error":   "Failed to generate backup codes",
-				"message": fmt.Sprintf("Database insert failed for backup code %d of %d, user %s: %v", i+1, BackupCodesCount, userID, err),
-			})
-			return
-		}
+	backupCodes, hashes, err := generateBackupCodeSet(policy)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to generate backup codes",
+			"message": fmt.Sprintf("Backup code generation failed for user %s: %v", userID, err),
+		})
+		return
+	}
+	if err := insertBackupCodes(tx, userID, hashes); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to generate backup codes",
+			"message": fmt.Sprintf("Database insert failed for user %s: %v", userID, err),
+		})
+		return
 	}
 
 	// Commit transaction
@@ -492,8 +486,9 @@ func (h *SecurityHandler) VerifyMFASetup(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":      "MFA enabled successfully",
-		"backup_codes": backupCodes,
+		"message":            "MFA enabled successfully",
+		"backup_codes":       backupCodes,
+		"backup_codes_count": len(backupCodes),
 	})
 }
 
@@ -523,7 +518,7 @@ func (h *SecurityHandler) VerifyMFASetup(c *gin.Context) {
 //
 // Parameters:
 //   - userID: From authentication context (JWT)
-//   - req.Code: 6-digit TOTP code or 8-character backup code
+//   - req.Code: 6-digit TOTP code or backup code (dashes optional)
 //   - req.MethodType: "totp" (default), "sms" (disabled), "email" (disabled), "backup_code"
 //   - req.TrustDevice: If true, set remember-me cookie for this device
 //
@@ -535,7 +530,7 @@ func (h *SecurityHandler) VerifyMFASetup(c *gin.Context) {
 //   - 429 Too Many Requests: Rate limit exceeded (>5 attempts/minute)
 //   - 501 Not Implemented: SMS/Email MFA requested
 func (h *SecurityHandler) VerifyMFA(c *gin.Context) {
-	userID := c.GetString("user_id")
+	userID := c.GetString("userID")
 
 	var req struct {
 		Code        string `json:"code" binding:"required"`
@@ -620,6 +615,11 @@ func (h *SecurityHandler) VerifyMFA(c *gin.Context) {
 	// SECURITY: Reset rate limit on successful verification
 	middleware.GetRateLimiter().ResetLimit(rateLimitKey)
 
+	// Record the verification for MFA step-up on sensitive operations
+	if err := middleware.RecordMFAVerification(h.DB, userID); err != nil {
+		log.Printf("Failed to record MFA verification for user %s: %v", userID, err)
+	}
+
 	// Trust device if requested
 	if req.TrustDevice {
 		deviceID := h.getDeviceFingerprint(c)
@@ -634,7 +634,7 @@ func (h *SecurityHandler) VerifyMFA(c *gin.Context) {
 
 // ListMFAMethods lists all MFA methods for a user
 func (h *SecurityHandler) ListMFAMethods(c *gin.Context) {
-	userID := c.GetString("user_id")
+	userID := c.GetString("userID")
 
 	rows, err := h.DB.Query(`
 		SELECT id, type, enabled, verified, is_primary, phone_number, email, created_at, last_used_at
@@ -680,7 +680,7 @@ func (h *SecurityHandler) ListMFAMethods(c *gin.Context) {
 
 // DisableMFA disables an MFA method
 func (h *SecurityHandler) DisableMFA(c *gin.Context) {
-	userID := c.GetString("user_id")
+	userID := c.GetString("userID")
 	mfaID := c.Param("mfaId")
 
 	result, err := h.DB.Exec(`
@@ -708,7 +708,7 @@ func (h *SecurityHandler) DisableMFA(c *gin.Context) {
 
 // GenerateBackupCodes generates new backup codes
 func (h *SecurityHandler) GenerateBackupCodes(c *gin.Context) {
-	userID := c.GetString("user_id")
+	userID := c.GetString("userID")
 
 	// Clean up expired trusted devices
 	go func() {
@@ -716,39 +716,28 @@ func (h *SecurityHandler) GenerateBackupCodes(c *gin.Context) {
 	}()
 
 	// Generate new codes
-	codes := h.generateBackupCodes(userID, BackupCodesCount)
+	codes, hashes, err := generateBackupCodeSet(h.loadBackupCodePolicy(c.Request.Context()))
+	if err == nil {
+		err = insertBackupCodes(h.DB, userID, hashes)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to generate backup codes",
+			"message": fmt.Sprintf("Backup code generation failed for user %s: %v", userID, err),
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"backup_codes": codes,
-		"message":      "Store these codes in a safe place. Each code can only be used once.",
+		"backup_codes":       codes,
+		"backup_codes_count": len(codes),
+		"message":            "Store these codes in a safe place. Each code can only be used once.",
 	})
 }
 
-// Helper: Generate backup codes
-func (h *SecurityHandler) generateBackupCodes(userID string, count int) []string {
-	codes := make([]string, count)
-
-	for i := 0; i < count; i++ {
-		code := generateRandomCode(BackupCodeLength)
-		codes[i] = code
-
-		// Hash and store
-		hash := sha256.Sum256([]byte(code))
-		hashStr := hex.EncodeToString(hash[:])
-
-		h.DB.Exec(`
-			INSERT INTO backup_codes (user_id, code)
-			VALUES ($1, $2)
-		`, userID, hashStr)
-	}
-
-	return codes
-}
-
 // Helper: Verify backup code
 func (h *SecurityHandler) verifyBackupCode(userID, code string) bool {
-	hash := sha256.Sum256([]byte(code))
-	hashStr := hex.EncodeToString(hash[:])
+	hashStr := hashBackupCode(code)
 
 	var codeID int64
 	err := h.DB.QueryRow(`
@@ -793,7 +782,7 @@ type GeoRestriction struct {
 
 // CreateIPWhitelist adds an IP to whitelist
 func (h *SecurityHandler) CreateIPWhitelist(c *gin.Context) {
-	createdBy := c.GetString("user_id")
+	createdBy := c.GetString("userID")
 	role := c.GetString("role")
 
 	var req struct {
@@ -920,8 +909,8 @@ func (h *SecurityHandler) ListIPWhitelist(c *gin.Context) {
 	role := c.GetString("role")
 
 	// Non-admins can only see their own rules
-	if userID == "" || (userID != c.GetString("user_id") && role != "admin") {
-		userID = c.GetString("user_id")
+	if userID == "" || (userID != c.GetString("userID") && role != "admin") {
+		userID = c.GetString("userID")
 	}
 
 	query := `
@@ -998,7 +987,7 @@ func (h *SecurityHandler) ListIPWhitelist(c *gin.Context) {
 //   - 500 Internal Server Error: Database error
 func (h *SecurityHandler) DeleteIPWhitelist(c *gin.Context) {
 	entryID := c.Param("entryId")
-	userID := c.GetString("user_id")
+	userID := c.GetString("userID")
 	role := c.GetString("role")
 
 	// SECURITY: Combine authorization check with query to prevent enumeration
@@ -1076,7 +1065,7 @@ type DevicePosture struct {
 // VerifySession performs continuous session verification
 func (h *SecurityHandler) VerifySession(c *gin.Context) {
 	sessionID := c.Param("sessionId")
-	userID := c.GetString("user_id")
+	userID := c.GetString("userID")
 
 	deviceID := h.getDeviceFingerprint(c)
 	ipAddress := c.ClientIP()
@@ -1163,7 +1152,7 @@ func (h *SecurityHandler) CheckDevicePosture(c *gin.Context) {
 
 // GetSecurityAlerts gets security alerts for a user
 func (h *SecurityHandler) GetSecurityAlerts(c *gin.Context) {
-	userID := c.GetString("user_id")
+	userID := c.GetString("userID")
 
 	rows, err := h.DB.Query(`
 		SELECT type, severity, message, details, created_at
@@ -1291,13 +1280,6 @@ func isValidIPOrCIDR(s string) bool {
 	return net.ParseIP(s) != nil
 }
 
-// Generate random code
-func generateRandomCode(length int) string {
-	bytes := make([]byte, length)
-	rand.Read(bytes)
-	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(bytes)[:length]
-}
-
 // Mask phone number
 func maskPhone(phone string) string {
 	if len(phone) < 4 {
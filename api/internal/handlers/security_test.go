@@ -7,9 +7,11 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -53,7 +55,7 @@ func TestSetupMFA_TOTP_Success(t *testing.T) {
 	// Create test context
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Set("user_id", userID)
+	c.Set("userID", userID)
 
 	payload := map[string]interface{}{
 		"type": "totp",
@@ -85,7 +87,7 @@ func TestSetupMFA_SMS_NotImplemented(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Set("user_id", "test-user")
+	c.Set("userID", "test-user")
 
 	payload := map[string]interface{}{
 		"type": "sms",
@@ -118,7 +120,7 @@ func TestSetupMFA_AlreadyExists(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Set("user_id", userID)
+	c.Set("userID", userID)
 
 	payload := map[string]interface{}{
 		"type": "totp",
@@ -197,7 +199,7 @@ func TestVerifyMFASetup_NotFound(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Set("user_id", userID)
+	c.Set("userID", userID)
 	c.Params = gin.Params{{Key: "mfaId", Value: mfaID}}
 
 	payload := map[string]interface{}{
@@ -215,6 +217,87 @@ func TestVerifyMFASetup_NotFound(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+// TestMFA_EnrollThenVerify enrolls TOTP and then verifies a code with the
+// same authenticated user, as the auth middleware sets it ("userID").
+func TestMFA_EnrollThenVerify(t *testing.T) {
+	handler, mock, cleanup := setupSecurityTest(t)
+	defer cleanup()
+
+	userID := "enroll-user"
+	call := func(method, path string, params gin.Params, payload map[string]interface{}, handle func(*gin.Context)) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Set("userID", userID)
+		c.Params = params
+		body, _ := json.Marshal(payload)
+		c.Request = httptest.NewRequest(method, path, bytes.NewReader(body))
+		c.Request.Header.Set("Content-Type", "application/json")
+		handle(c)
+		return w
+	}
+
+	// Step 1: set up TOTP
+	mock.ExpectQuery(`SELECT id FROM mfa_methods WHERE user_id = \$1 AND type = \$2`).
+		WithArgs(userID, "totp").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`INSERT INTO mfa_methods`).
+		WithArgs(userID, "totp", sqlmock.AnyArg(), "", "").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+
+	w := call("POST", "/api/v1/security/mfa/setup", nil, map[string]interface{}{"type": "totp"}, handler.SetupMFA)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var setup MFASetupResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &setup))
+
+	code, err := totp.GenerateCode(setup.Secret, time.Now())
+	assert.NoError(t, err)
+
+	// Step 2: confirm the setup with a code from the authenticator
+	mock.ExpectQuery(`SELECT id, user_id, type, secret, phone_number, email FROM mfa_methods WHERE id = \$1 AND user_id = \$2`).
+		WithArgs("7", userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "type", "secret", "phone_number", "email"}).
+			AddRow(7, userID, "totp", setup.Secret, "", ""))
+	mock.ExpectQuery(`SELECT key, value FROM configuration`).
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value"}))
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE mfa_methods SET verified = true, enabled = true WHERE id = \$1`).
+		WithArgs("7").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	for i := 0; i < BackupCodesCount; i++ {
+		mock.ExpectExec(`INSERT INTO backup_codes`).
+			WithArgs(userID, sqlmock.AnyArg()).
+			WillReturnResult(sqlmock.NewResult(int64(i+1), 1))
+	}
+	mock.ExpectCommit()
+
+	w = call("POST", "/api/v1/security/mfa/7/verify", gin.Params{{Key: "mfaId", Value: "7"}},
+		map[string]interface{}{"code": code}, handler.VerifyMFASetup)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var verified struct {
+		BackupCodes      []string `json:"backup_codes"`
+		BackupCodesCount int      `json:"backup_codes_count"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &verified))
+	assert.Len(t, verified.BackupCodes, BackupCodesCount)
+	assert.Equal(t, BackupCodesCount, verified.BackupCodesCount)
+
+	// Step 3: verify, which records the time for MFA step-up
+	mock.ExpectQuery(`SELECT secret FROM mfa_methods WHERE user_id = \$1 AND type = \$2 AND enabled = true`).
+		WithArgs(userID, "totp").
+		WillReturnRows(sqlmock.NewRows([]string{"secret"}).AddRow(setup.Secret))
+	mock.ExpectExec(`UPDATE mfa_methods SET last_used_at = NOW\(\)`).
+		WithArgs(userID, "totp").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE users SET mfa_verified_at = NOW\(\) WHERE id = \$1`).
+		WithArgs(userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	w = call("POST", "/api/v1/security/mfa/verify", nil, map[string]interface{}{"code": code}, handler.VerifyMFA)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 // ============================================================================
 // LIST MFA METHODS TESTS
 // ============================================================================
@@ -235,7 +318,7 @@ func TestListMFAMethods_Success(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Set("user_id", userID)
+	c.Set("userID", userID)
 	req := httptest.NewRequest("GET", "/api/v1/security/mfa/methods", nil)
 	c.Request = req
 
@@ -269,7 +352,7 @@ func TestDisableMFA_Success(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Set("user_id", userID)
+	c.Set("userID", userID)
 	c.Params = gin.Params{{Key: "mfaId", Value: mfaID}}
 	req := httptest.NewRequest("PUT", "/api/v1/security/mfa/"+mfaID+"/disable", nil)
 	c.Request = req
@@ -300,7 +383,7 @@ func TestDisableMFA_NotFound(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Set("user_id", userID)
+	c.Set("userID", userID)
 	c.Params = gin.Params{{Key: "mfaId", Value: mfaID}}
 	req := httptest.NewRequest("PUT", "/api/v1/security/mfa/"+mfaID+"/disable", nil)
 	c.Request = req
@@ -330,7 +413,7 @@ func TestCreateIPWhitelist_ValidIP_Success(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Set("user_id", userID)
+	c.Set("userID", userID)
 	c.Set("role", "user")
 
 	payload := map[string]interface{}{
@@ -369,7 +452,7 @@ func TestCreateIPWhitelist_ValidCIDR_Success(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Set("user_id", userID)
+	c.Set("userID", userID)
 	c.Set("role", "user")
 
 	payload := map[string]interface{}{
@@ -395,7 +478,7 @@ func TestCreateIPWhitelist_InvalidIP_BadRequest(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Set("user_id", "test-user")
+	c.Set("userID", "test-user")
 	c.Set("role", "user")
 
 	payload := map[string]interface{}{
@@ -422,7 +505,7 @@ func TestCreateIPWhitelist_InvalidCIDR_BadRequest(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Set("user_id", "test-user")
+	c.Set("userID", "test-user")
 	c.Set("role", "user")
 
 	payload := map[string]interface{}{
@@ -458,7 +541,7 @@ func TestListIPWhitelist_Success(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Set("user_id", userID)
+	c.Set("userID", userID)
 	c.Set("role", "user")
 	req := httptest.NewRequest("GET", "/api/v1/security/ip-whitelist", nil)
 	c.Request = req
@@ -493,7 +576,7 @@ func TestDeleteIPWhitelist_Success(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Set("user_id", userID)
+	c.Set("userID", userID)
 	c.Set("role", "user")
 	c.Params = gin.Params{{Key: "entryId", Value: entryID}}
 	req := httptest.NewRequest("DELETE", "/api/v1/security/ip-whitelist/"+entryID, nil)
@@ -525,7 +608,7 @@ func TestDeleteIPWhitelist_NotFound(t *testing.T) {
 
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
-	c.Set("user_id", userID)
+	c.Set("userID", userID)
 	c.Set("role", "user")
 	c.Params = gin.Params{{Key: "entryId", Value: entryID}}
 	req := httptest.NewRequest("DELETE", "/api/v1/security/ip-whitelist/"+entryID, nil)
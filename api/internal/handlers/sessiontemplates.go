@@ -547,6 +547,31 @@ func (h *SessionTemplatesHandler) UseSessionTemplate(c *gin.Context) {
 		return
 	}
 
+	// Privileged templates without restricted tags are admin-only
+	if k8sTemplate.Privileged && len(k8sTemplate.RestrictedGroups()) == 0 && c.GetString("userRole") != "admin" {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":   "Template access denied",
+			"message": fmt.Sprintf("Template '%s' runs privileged and may only be launched by admins", baseTemplate),
+		})
+		return
+	}
+
+	// Templates tagged restricted:<group> may only be launched by members
+	if groups := k8sTemplate.RestrictedGroups(); len(groups) > 0 && c.GetString("userRole") != "admin" {
+		userGroups, err := db.NewUserDB(h.db.DB()).GetUserGroupNames(ctx, userIDStr)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check template access"})
+			return
+		}
+		if !k8sTemplate.AccessibleTo(userGroups) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Template access denied",
+				"message": fmt.Sprintf("Template '%s' is restricted to the groups %s", baseTemplate, strings.Join(groups, ", ")),
+			})
+			return
+		}
+	}
+
 	// Generate session name
 	sessionName := fmt.Sprintf("%s-%s-%s", userIDStr, baseTemplate, uuid.New().String()[:8])
 
@@ -82,6 +82,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/middleware"
 )
 
 // SharingHandler handles session sharing and collaboration
@@ -101,7 +102,7 @@ func (h *SharingHandler) RegisterRoutes(router *gin.RouterGroup) {
 	router.POST("/sessions/:id/share", h.CreateShare)
 	router.GET("/sessions/:id/shares", h.ListShares)
 	router.DELETE("/sessions/:id/shares/:shareId", h.RevokeShare)
-	router.POST("/sessions/:id/transfer", h.TransferOwnership)
+	router.POST("/sessions/:id/transfer", middleware.RequireMFAStepUp(), h.TransferOwnership)
 
 	router.POST("/sessions/:id/invitations", h.CreateInvitation)
 	router.GET("/sessions/:id/invitations", h.ListInvitations)
@@ -137,6 +138,11 @@ func (h *SharingHandler) CreateShare(c *gin.Context) {
 		return
 	}
 
+	// SECURITY: Granting control requires a fresh MFA check (if step-up is enabled)
+	if req.PermissionLevel == "control" && !middleware.CheckMFAStepUp(c) {
+		return
+	}
+
 	// Get session owner
 	var ownerUserId string
 	err := h.db.DB().QueryRowContext(ctx, `SELECT user_id FROM sessions WHERE id = $1`, sessionID).Scan(&ownerUserId)
@@ -339,6 +345,11 @@ func (h *SharingHandler) CreateInvitation(c *gin.Context) {
 		return
 	}
 
+	// SECURITY: Granting control requires a fresh MFA check (if step-up is enabled)
+	if req.PermissionLevel == "control" && !middleware.CheckMFAStepUp(c) {
+		return
+	}
+
 	if req.MaxUses == 0 {
 		req.MaxUses = 1
 	}
@@ -58,6 +58,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/middleware"
 	"github.com/streamspace/streamspace/api/internal/models"
 )
 
@@ -65,6 +66,9 @@ import (
 type UserHandler struct {
 	userDB  *db.UserDB
 	groupDB *db.GroupDB
+
+	// sessionRateLimit is reported with the current user's quota
+	sessionRateLimit middleware.SessionRateLimit
 }
 
 // NewUserHandler creates a new user handler
@@ -75,6 +79,20 @@ func NewUserHandler(userDB *db.UserDB, groupDB *db.GroupDB) *UserHandler {
 	}
 }
 
+// SetSessionRateLimit sets the session create/terminate rate limit reported
+// in GET /users/me/quota.
+func (h *UserHandler) SetSessionRateLimit(limit middleware.SessionRateLimit) {
+	h.sessionRateLimit = limit
+}
+
+// currentUserQuotaResponse is the current user's quota together with their
+// state against the session rate limit, which quotas don't cover: quotas
+// cap concurrent sessions, the rate limit how fast they are created.
+type currentUserQuotaResponse struct {
+	*models.UserQuota
+	SessionRateLimit *middleware.SessionRateLimitStatus `json:"sessionRateLimit,omitempty"`
+}
+
 // RegisterRoutes registers user management routes
 func (h *UserHandler) RegisterRoutes(router *gin.RouterGroup) {
 	userRoutes := router.Group("/users")
@@ -276,11 +294,11 @@ func (h *UserHandler) GetCurrentUser(c *gin.Context) {
 
 // GetCurrentUserQuota godoc
 // @Summary Get current user quota
-// @Description Get quota information for the currently authenticated user
+// @Description Get quota information for the currently authenticated user, including the session create/terminate rate limit
 // @Tags users, quotas
 // @Accept json
 // @Produce json
-// @Success 200 {object} models.UserQuota
+// @Success 200 {object} currentUserQuotaResponse
 // @Failure 401 {object} ErrorResponse
 // @Failure 404 {object} ErrorResponse
 // @Router /api/v1/users/me/quota [get]
@@ -314,7 +332,10 @@ func (h *UserHandler) GetCurrentUserQuota(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, quota)
+	c.JSON(http.StatusOK, currentUserQuotaResponse{
+		UserQuota:        quota,
+		SessionRateLimit: middleware.SessionRateLimitStatusFor(h.sessionRateLimit, userIDStr),
+	})
 }
 
 // UpdateUser godoc
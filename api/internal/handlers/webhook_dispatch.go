@@ -0,0 +1,203 @@
+// Package handlers provides HTTP handlers for the StreamSpace API.
+// This file implements webhook delivery for platform events.
+//
+// DispatchEvent finds the enabled webhooks subscribed to an event, records a
+// webhook_deliveries row for each and delivers it in the background,
+// retrying failures per the webhook's retry policy. Every attempt for a
+// delivery carries the same X-StreamSpace-Delivery ID, and every delivery of
+// an event the same X-StreamSpace-Event-ID, so receivers can discard a
+// retry of a request they already processed. Attempts are signed as
+// described in package webhooksig.
+//
+// Session lifecycle webhooks:
+//
+// session.ready, session.hibernating, session.terminated and session.failed
+// are fired by the NATS subscriber (see events/lifecycle.go) exactly once per
+// transition: a session that reports Running many times fires session.ready
+// once, and again only after it has left the ready state (e.g. hibernated and
+// woken).
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/streamspace/streamspace/api/internal/events"
+)
+
+// maxStoredResponseBody caps the response body kept on a delivery record.
+const maxStoredResponseBody = 4096
+
+// DispatchEvent delivers an event to every enabled webhook subscribed to it.
+func (h *IntegrationsHandler) DispatchEvent(ctx context.Context, event WebhookEvent) {
+	if event.ID == "" {
+		event.ID = uuid.New().String()
+	}
+
+	webhooks, err := h.subscribedWebhooks(ctx, event.Event)
+	if err != nil {
+		log.Printf("Failed to load webhooks for event %s: %v", event.Event, err)
+		return
+	}
+
+	for _, webhook := range webhooks {
+		if !webhook.Filters.matches(event.Data) {
+			continue
+		}
+
+		var deliveryID int64
+		err := h.DB.DB().QueryRowContext(ctx, `
+			INSERT INTO webhook_deliveries (webhook_id, event, payload, status, attempts)
+			VALUES ($1, $2, $3, 'pending', 0)
+			RETURNING id
+		`, webhook.ID, event.Event, toJSONB(event)).Scan(&deliveryID)
+		if err != nil {
+			log.Printf("Failed to record delivery of %s to webhook %d: %v", event.Event, webhook.ID, err)
+			continue
+		}
+
+		go h.deliverWithRetries(webhook, event, deliveryID)
+	}
+}
+
+// DispatchSessionLifecycle delivers a session lifecycle event
+// (implements events.SessionLifecycleDispatcher).
+func (h *IntegrationsHandler) DispatchSessionLifecycle(ctx context.Context, event string, data map[string]interface{}) {
+	h.DispatchEvent(ctx, WebhookEvent{
+		Event:     event,
+		Timestamp: time.Now(),
+		Data:      data,
+	})
+
+	// The owner's open dashboards learn about the termination, or the
+	// coming hibernation, right away
+	userID, _ := data["user_id"].(string)
+	if userID == "" {
+		return
+	}
+	sessionID, _ := data["session_id"].(string)
+	reason, _ := data["reason"].(string)
+	switch event {
+	case events.LifecycleSessionTerminated:
+		BroadcastSessionTerminated(userID, sessionID, reason)
+	case events.LifecycleSessionHibernating:
+		BroadcastSessionHibernating(userID, sessionID, reason)
+	}
+}
+
+// subscribedWebhooks returns the enabled webhooks subscribed to an event.
+func (h *IntegrationsHandler) subscribedWebhooks(ctx context.Context, event string) ([]Webhook, error) {
+	rows, err := h.DB.DB().QueryContext(ctx, `
+		SELECT id, url, secret, headers, retry_policy, filters, created_by
+		FROM webhooks
+		WHERE enabled = true AND events @> $1::jsonb
+	`, toJSONB([]string{event}))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []Webhook{}
+	for rows.Next() {
+		var w Webhook
+		var secret, headers, retryPolicy, filters, createdBy sql.NullString
+		if err := rows.Scan(&w.ID, &w.URL, &secret, &headers, &retryPolicy, &filters, &createdBy); err != nil {
+			return nil, err
+		}
+		w.Secret = secret.String
+		w.CreatedBy = createdBy.String
+		if headers.Valid && headers.String != "" {
+			json.Unmarshal([]byte(headers.String), &w.Headers)
+		}
+		if retryPolicy.Valid && retryPolicy.String != "" {
+			json.Unmarshal([]byte(retryPolicy.String), &w.RetryPolicy)
+		}
+		if filters.Valid && filters.String != "" {
+			json.Unmarshal([]byte(filters.String), &w.Filters)
+		}
+		webhooks = append(webhooks, w)
+	}
+	return webhooks, rows.Err()
+}
+
+// deliverWithRetries delivers an event, retrying with exponential backoff,
+// and records the outcome of each attempt on the delivery record.
+func (h *IntegrationsHandler) deliverWithRetries(webhook Webhook, event WebhookEvent, deliveryID int64) {
+	policy := webhook.RetryPolicy
+	if policy.RetryDelay <= 0 {
+		policy.RetryDelay = WebhookDefaultRetryDelay
+	}
+	if policy.BackoffMultiplier < 1 {
+		policy.BackoffMultiplier = WebhookDefaultBackoffMultiplier
+	}
+
+	for attempt := 1; ; attempt++ {
+		success, statusCode, body, err := h.deliverWebhook(webhook, event, strconv.FormatInt(deliveryID, 10))
+
+		status := "success"
+		var errorMessage string
+		var nextRetryAt, deliveredAt *time.Time
+		if success {
+			now := time.Now()
+			deliveredAt = &now
+		} else {
+			status = "failed"
+			if err != nil {
+				errorMessage = err.Error()
+			}
+			if attempt <= policy.MaxRetries {
+				status = "retrying"
+				delay := time.Duration(float64(policy.RetryDelay)*math.Pow(policy.BackoffMultiplier, float64(attempt-1))) * time.Second
+				next := time.Now().Add(delay)
+				nextRetryAt = &next
+			}
+		}
+		if len(body) > maxStoredResponseBody {
+			body = body[:maxStoredResponseBody]
+		}
+
+		if _, dbErr := h.DB.DB().Exec(`
+			UPDATE webhook_deliveries
+			SET status = $1, status_code = $2, response_body = $3, error_message = $4,
+			    attempts = $5, next_retry_at = $6, delivered_at = $7
+			WHERE id = $8
+		`, status, statusCode, body, errorMessage, attempt, nextRetryAt, deliveredAt, deliveryID); dbErr != nil {
+			log.Printf("Failed to update webhook delivery %d: %v", deliveryID, dbErr)
+		}
+		if webhook.CreatedBy != "" {
+			BroadcastWebhookDelivery(webhook.CreatedBy, int(webhook.ID), int(deliveryID), status)
+		}
+
+		if status != "retrying" {
+			return
+		}
+		time.Sleep(time.Until(*nextRetryAt))
+	}
+}
+
+// matches reports whether event data passes the webhook's filters.
+// Empty filters match everything; data without the filtered field matches.
+func (f WebhookFilters) matches(data map[string]interface{}) bool {
+	return matchesFilter(f.Users, data["user_id"]) &&
+		matchesFilter(f.Templates, data["template"]) &&
+		matchesFilter(f.SessionStates, data["state"])
+}
+
+func matchesFilter(allowed []string, value interface{}) bool {
+	s, ok := value.(string)
+	if len(allowed) == 0 || !ok || s == "" {
+		return true
+	}
+	for _, a := range allowed {
+		if a == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookFilters_Matches(t *testing.T) {
+	data := map[string]interface{}{"user_id": "alice", "template": "firefox"}
+
+	assert.True(t, WebhookFilters{}.matches(data))
+	assert.True(t, WebhookFilters{Users: []string{"alice"}}.matches(data))
+	assert.False(t, WebhookFilters{Users: []string{"bob"}}.matches(data))
+	assert.False(t, WebhookFilters{Templates: []string{"chrome"}}.matches(data))
+	// Data without the filtered field isn't excluded
+	assert.True(t, WebhookFilters{SessionStates: []string{"running"}}.matches(data))
+}
+
+func TestDispatchEvent_DeliversToSubscribedWebhooks(t *testing.T) {
+	received := make(chan *http.Request, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		received <- r
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	h := NewIntegrationsHandler(db.NewDatabaseForTesting(sqlDB))
+
+	mock.ExpectQuery("SELECT id, url, secret, headers, retry_policy, filters, created_by FROM webhooks").
+		WithArgs(`["session.ready"]`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "secret", "headers", "retry_policy", "filters", "created_by"}).
+			AddRow(1, server.URL, "whsec_test", nil, nil, nil, nil).
+			AddRow(2, server.URL, "", nil, nil, `{"users":["bob"]}`, nil))
+	mock.ExpectQuery("INSERT INTO webhook_deliveries").
+		WithArgs(int64(1), "session.ready", sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(42))
+	mock.ExpectExec("UPDATE webhook_deliveries").
+		WithArgs("success", http.StatusOK, "", "", 1, nil, sqlmock.AnyArg(), int64(42)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	h.DispatchSessionLifecycle(context.Background(), "session.ready", map[string]interface{}{
+		"session_id": "sess-1",
+		"user_id":    "alice",
+	})
+
+	select {
+	case r := <-received:
+		assert.Equal(t, "session.ready", r.Header.Get("X-StreamSpace-Event"))
+		assert.Equal(t, "42", r.Header.Get("X-StreamSpace-Delivery"))
+		assert.NotEmpty(t, r.Header.Get("X-StreamSpace-Signature"))
+		assert.NotEmpty(t, r.Header.Get("X-StreamSpace-Timestamp"))
+		assert.NotEmpty(t, r.Header.Get("X-StreamSpace-Event-ID"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook was not delivered")
+	}
+
+	// Only the unfiltered webhook is delivered, and its outcome recorded
+	assert.Eventually(t, func() bool { return mock.ExpectationsWereMet() == nil }, 5*time.Second, 10*time.Millisecond)
+}
@@ -216,6 +216,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/middleware"
 )
 
 // WebSocketHandler handles WebSocket connections for real-time platform updates.
@@ -302,7 +303,7 @@ func NewWebSocketHandler(database *db.Database) *WebSocketHandler {
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
-			CheckOrigin:     checkWebSocketOrigin,
+			CheckOrigin:     CheckWebSocketOrigin,
 		},
 		sessions:   make(map[string]*WebSocketSession),
 		broadcast:  make(chan *BroadcastMessage, 256),
@@ -316,12 +317,19 @@ func NewWebSocketHandler(database *db.Database) *WebSocketHandler {
 	return h
 }
 
-// checkWebSocketOrigin validates the origin of WebSocket upgrade requests
-func checkWebSocketOrigin(r *http.Request) bool {
+// CheckWebSocketOrigin validates the origin of WebSocket upgrade requests
+// against CORS_ALLOWED_ORIGINS. Outside strict mode (WEBSOCKET_ENFORCE_ORIGIN)
+// localhost origins are accepted for development.
+func CheckWebSocketOrigin(r *http.Request) bool {
 	origin := r.Header.Get("Origin")
+	policy := middleware.WebSocketOriginPolicyFromEnv()
 	if origin == "" {
 		// Allow requests without origin header (for non-browser clients)
-		return true
+		// unless strict mode rejects them
+		if !policy.AllowEmpty() {
+			reportOriginRejection(r, origin)
+		}
+		return policy.AllowEmpty()
 	}
 
 	// Get allowed origins from environment variable (same as CORS middleware)
@@ -338,7 +346,7 @@ func checkWebSocketOrigin(r *http.Request) bool {
 
 	// If no origins specified, use localhost only for development (same as CORS middleware)
 	if len(allowedOrigins) == 0 {
-		allowedOrigins = []string{"http://localhost:3000", "http://localhost:8000"}
+		allowedOrigins = policy.DevOrigins("http://localhost:3000", "http://localhost:8000")
 	}
 
 	// Check if origin is in allowed list
@@ -349,11 +357,12 @@ func checkWebSocketOrigin(r *http.Request) bool {
 	}
 
 	// Also allow any localhost or 127.0.0.1 origin for development
-	if strings.Contains(origin, "localhost") || strings.Contains(origin, "127.0.0.1") {
+	if !policy.Strict && (strings.Contains(origin, "localhost") || strings.Contains(origin, "127.0.0.1")) {
 		return true
 	}
 
 	// Reject all other origins
+	reportOriginRejection(r, origin)
 	return false
 }
 
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// At-least-once delivery for critical WebSocket messages.
+//
+// Messages are normally fire-and-forget: a client whose buffer is full is
+// skipped, and a user who is offline never sees the message. That is fine
+// for dashboard updates but not for a security alert or a terminated
+// session. Messages of the types in WebSocketAckTypes therefore get an ID
+// and "ack_required": true, and the client answers with
+//
+//	{"type": "ack", "id": 42}
+//
+// Until then the message is resent every retry interval, up to the maximum
+// number of attempts. Whatever is still unacknowledged when one of the
+// user's connections (re)connects is sent to it again with "missed": true.
+// Pending messages are kept per user, not per connection, so an ack from
+// any of the user's tabs settles the message.
+//
+// Environment:
+//   - WEBSOCKET_ACK_TYPES: comma-separated message types that require an
+//     ack (default "security.alert,session.terminated", empty disables)
+//   - WEBSOCKET_ACK_RETRY_INTERVAL: delay between resends (default 30s)
+//   - WEBSOCKET_ACK_MAX_ATTEMPTS: sends per message before it waits for the
+//     next reconnect (default 5)
+
+// pendingAck is a critical message the user hasn't acknowledged.
+type pendingAck struct {
+	message  WebSocketMessage
+	attempts int
+	lastSent time.Time
+}
+
+// ackTracker keeps unacknowledged critical messages per user.
+type ackTracker struct {
+	mu      sync.Mutex
+	nextID  uint64
+	types   map[string]bool
+	pending map[string][]*pendingAck // userID -> messages in send order
+
+	// RetryInterval is the delay between resends of an unacknowledged message.
+	RetryInterval time.Duration
+
+	// MaxAttempts caps how often a message is sent before it waits for the
+	// user to reconnect.
+	MaxAttempts int
+}
+
+// newAckTracker creates a tracker requiring acks for the given message types.
+func newAckTracker(types []string) *ackTracker {
+	t := &ackTracker{
+		types:         make(map[string]bool),
+		pending:       make(map[string][]*pendingAck),
+		RetryInterval: WebSocketAckRetryInterval,
+		MaxAttempts:   WebSocketAckMaxAttempts,
+	}
+	for _, msgType := range types {
+		if msgType = strings.TrimSpace(msgType); msgType != "" {
+			t.types[msgType] = true
+		}
+	}
+	return t
+}
+
+// ackTrackerFromEnv creates a tracker configured from WEBSOCKET_ACK_*.
+func ackTrackerFromEnv() *ackTracker {
+	types := strings.Split(WebSocketAckTypes, ",")
+	if v, ok := os.LookupEnv("WEBSOCKET_ACK_TYPES"); ok {
+		types = strings.Split(v, ",")
+	}
+	t := newAckTracker(types)
+
+	if v := os.Getenv("WEBSOCKET_ACK_RETRY_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			t.RetryInterval = d
+		} else {
+			log.Printf("Invalid WEBSOCKET_ACK_RETRY_INTERVAL %q, using %s", v, t.RetryInterval)
+		}
+	}
+	if v := os.Getenv("WEBSOCKET_ACK_MAX_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			t.MaxAttempts = n
+		} else {
+			log.Printf("Invalid WEBSOCKET_ACK_MAX_ATTEMPTS %q, using %d", v, t.MaxAttempts)
+		}
+	}
+	return t
+}
+
+// requiresAck reports whether messages of msgType are delivered at least once.
+func (t *ackTracker) requiresAck(msgType string) bool {
+	return t != nil && t.types[msgType]
+}
+
+// track assigns message an ID and keeps it until userID acknowledges it.
+// The returned message is the one to send. The oldest message is dropped
+// once the user has WebSocketAckMaxPending outstanding.
+func (t *ackTracker) track(userID string, message WebSocketMessage, now time.Time) WebSocketMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.nextID++
+	message.ID = t.nextID
+	message.AckRequired = true
+
+	pending := append(t.pending[userID], &pendingAck{message: message, attempts: 1, lastSent: now})
+	if len(pending) > WebSocketAckMaxPending {
+		dropped := pending[0].message
+		log.Printf("Dropping unacknowledged %s message %d for user %s (%d pending)", dropped.Type, dropped.ID, userID, len(pending))
+		pending = pending[1:]
+	}
+	t.pending[userID] = pending
+	return message
+}
+
+// ack settles message id of userID. Unknown IDs are ignored.
+func (t *ackTracker) ack(userID string, id uint64) bool {
+	if t == nil {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pending := t.pending[userID]
+	for i, p := range pending {
+		if p.message.ID != id {
+			continue
+		}
+		pending = append(pending[:i], pending[i+1:]...)
+		if len(pending) == 0 {
+			delete(t.pending, userID)
+		} else {
+			t.pending[userID] = pending
+		}
+		return true
+	}
+	return false
+}
+
+// due returns the messages to resend now, by user. Messages older than
+// WebSocketAckPendingTTL are forgotten.
+func (t *ackTracker) due(now time.Time) map[string][]WebSocketMessage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	resend := make(map[string][]WebSocketMessage)
+	for userID, pending := range t.pending {
+		kept := pending[:0]
+		for _, p := range pending {
+			if now.Sub(p.message.Timestamp) > WebSocketAckPendingTTL {
+				continue
+			}
+			kept = append(kept, p)
+			if p.attempts < t.MaxAttempts && now.Sub(p.lastSent) >= t.RetryInterval {
+				p.attempts++
+				p.lastSent = now
+				resend[userID] = append(resend[userID], p.message)
+			}
+		}
+		if len(kept) == 0 {
+			delete(t.pending, userID)
+		} else {
+			t.pending[userID] = kept
+		}
+	}
+	return resend
+}
+
+// missed returns the user's unacknowledged messages, marked as missed, for
+// a connection that just (re)connected. Their retries start over.
+func (t *ackTracker) missed(userID string, now time.Time) []WebSocketMessage {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var messages []WebSocketMessage
+	for _, p := range t.pending[userID] {
+		p.attempts = 1
+		p.lastSent = now
+		message := p.message
+		message.Missed = true
+		messages = append(messages, message)
+	}
+	return messages
+}
+
+// retryUnacked resends due critical messages until the process exits.
+func (h *WebSocketHub) retryUnacked() {
+	ticker := time.NewTicker(h.acks.RetryInterval)
+	defer ticker.Stop()
+	for now := range ticker.C {
+		for userID, messages := range h.acks.due(now) {
+			for _, message := range messages {
+				h.sendToUser(userID, message)
+			}
+		}
+	}
+}
+
+// clientMessage is a message sent by the client over the enterprise WebSocket.
+type clientMessage struct {
+	Type    string `json:"type"`
+	ID      uint64 `json:"id"`
+	Version int    `json:"version"`
+
+	// Action and Types carry subscription requests (see
+	// websocket_subscriptions.go)
+	Action string   `json:"action"`
+	Types  []string `json:"types"`
+}
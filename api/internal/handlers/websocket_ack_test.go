@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAckTracker_RequiresAck(t *testing.T) {
+	tracker := newAckTracker([]string{"security.alert", " session.terminated ", ""})
+	assert.True(t, tracker.requiresAck("security.alert"))
+	assert.True(t, tracker.requiresAck("session.terminated"))
+	assert.False(t, tracker.requiresAck("webhook.delivery"))
+
+	var disabled *ackTracker
+	assert.False(t, disabled.requiresAck("security.alert"))
+}
+
+func TestAckTracker_AckSettlesMessage(t *testing.T) {
+	tracker := newAckTracker([]string{"security.alert"})
+	now := time.Now()
+
+	first := tracker.track("alice", WebSocketMessage{Type: "security.alert", Timestamp: now}, now)
+	second := tracker.track("alice", WebSocketMessage{Type: "security.alert", Timestamp: now}, now)
+	assert.True(t, first.AckRequired)
+	assert.NotEqual(t, first.ID, second.ID)
+
+	// Another user can't settle alice's messages
+	assert.False(t, tracker.ack("bob", first.ID))
+	assert.True(t, tracker.ack("alice", first.ID))
+	assert.False(t, tracker.ack("alice", first.ID), "already acked")
+
+	missed := tracker.missed("alice", now)
+	require.Len(t, missed, 1)
+	assert.Equal(t, second.ID, missed[0].ID)
+	assert.True(t, missed[0].Missed)
+
+	tracker.ack("alice", second.ID)
+	assert.Empty(t, tracker.pending)
+}
+
+func TestAckTracker_RetriesAreBounded(t *testing.T) {
+	tracker := newAckTracker([]string{"security.alert"})
+	tracker.RetryInterval = time.Second
+	tracker.MaxAttempts = 3
+	now := time.Now()
+
+	sent := tracker.track("alice", WebSocketMessage{Type: "security.alert", Timestamp: now}, now)
+
+	// Not due before the retry interval
+	assert.Empty(t, tracker.due(now.Add(500*time.Millisecond)))
+
+	resends := 0
+	for i := 1; i <= 5; i++ {
+		due := tracker.due(now.Add(time.Duration(i) * time.Second))
+		if msgs := due["alice"]; len(msgs) > 0 {
+			assert.Equal(t, sent.ID, msgs[0].ID)
+			resends++
+		}
+	}
+	assert.Equal(t, 2, resends, "3 attempts = first send + 2 resends")
+
+	// Still pending: surfaced on reconnect, and retries start over
+	require.Len(t, tracker.missed("alice", now.Add(10*time.Second)), 1)
+	assert.Len(t, tracker.due(now.Add(11 * time.Second))["alice"], 1)
+}
+
+func TestAckTracker_Expiry(t *testing.T) {
+	tracker := newAckTracker([]string{"security.alert"})
+	old := time.Now().Add(-WebSocketAckPendingTTL - time.Minute)
+	tracker.track("alice", WebSocketMessage{Type: "security.alert", Timestamp: old}, old)
+
+	assert.Empty(t, tracker.due(time.Now()))
+	assert.Empty(t, tracker.missed("alice", time.Now()))
+}
+
+func TestAckTracker_MaxPending(t *testing.T) {
+	tracker := newAckTracker([]string{"security.alert"})
+	now := time.Now()
+	var first WebSocketMessage
+	for i := 0; i < WebSocketAckMaxPending+1; i++ {
+		msg := tracker.track("alice", WebSocketMessage{Type: "security.alert", Timestamp: now}, now)
+		if i == 0 {
+			first = msg
+		}
+	}
+	assert.Len(t, tracker.pending["alice"], WebSocketAckMaxPending)
+	assert.False(t, tracker.ack("alice", first.ID), "oldest message dropped")
+}
+
+func TestBroadcastToUser_CriticalMessages(t *testing.T) {
+	hub := newWebSocketHub()
+	go hub.Run()
+
+	client := &WebSocketClient{ID: "alice-1", UserID: "alice", Send: make(chan WebSocketMessage, 4), Hub: hub}
+	hub.Register(client)
+	assert.Eventually(t, func() bool { return hub.ClientCount() == 1 }, time.Second, 5*time.Millisecond)
+
+	hub.BroadcastToUser("alice", WebSocketMessage{Type: "webhook.delivery", Timestamp: time.Now()})
+	hub.BroadcastToUser("alice", WebSocketMessage{Type: "security.alert", Timestamp: time.Now()})
+
+	plain := <-client.Send
+	assert.Zero(t, plain.ID)
+	assert.False(t, plain.AckRequired)
+
+	critical := <-client.Send
+	assert.True(t, critical.AckRequired)
+	assert.NotZero(t, critical.ID)
+
+	// The client's ack, as read by readPump, settles it
+	ack, err := json.Marshal(map[string]interface{}{"type": "ack", "id": critical.ID})
+	require.NoError(t, err)
+	client.handleClientMessage([]byte("not json"))
+	assert.Len(t, hub.acks.missed("alice", time.Now()), 1)
+	client.handleClientMessage(ack)
+	assert.Empty(t, hub.acks.missed("alice", time.Now()))
+}
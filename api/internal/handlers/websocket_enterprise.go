@@ -15,17 +15,23 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"github.com/streamspace/streamspace/api/internal/middleware"
+	"github.com/streamspace/streamspace/api/internal/quota"
+	"github.com/streamspace/streamspace/api/internal/wshub"
 )
 
 // WebSocketMessage represents a real-time update message sent to clients.
@@ -34,6 +40,13 @@ import (
 // Timestamp is set server-side to ensure accurate event timing.
 // Data contains the message payload as a flexible map.
 //
+// Critical types (see websocket_ack.go) also carry an ID and ack_required,
+// and are resent until the client acks them; missed is set when they are
+// resent after a reconnect.
+//
+// Clients that negotiated a versioned envelope (see websocket_version.go)
+// also get a version field.
+//
 // Example message:
 //   {
 //     "type": "security.alert",
@@ -48,6 +61,14 @@ type WebSocketMessage struct {
 	Type      string                 `json:"type"`      // Message type/category for client-side routing
 	Timestamp time.Time              `json:"timestamp"` // Server timestamp for accurate event ordering
 	Data      map[string]interface{} `json:"data"`      // Flexible payload containing event-specific data
+
+	ID          uint64 `json:"id,omitempty"`           // Sequence number of messages that must be acked
+	AckRequired bool   `json:"ack_required,omitempty"` // Client must reply {"type": "ack", "id": ID}
+	Missed      bool   `json:"missed,omitempty"`       // Resent on reconnect because it was never acked
+
+	// Version is the envelope version, set per client when the message is
+	// written (see websocket_version.go). Legacy clients never see it.
+	Version int `json:"version,omitempty"`
 }
 
 // WebSocketClient represents a single connected WebSocket client.
@@ -58,40 +79,109 @@ type WebSocketMessage struct {
 // - WebSocket connection (Conn)
 // - Buffered send channel to prevent blocking
 // - Reference to hub for broadcasting
-// - Mutex for thread-safe operations (currently unused but available for future state)
+// - Mutex guarding the client's message type subscriptions
 //
 // The Send channel is buffered (256 messages) to handle burst traffic without blocking.
 // If the buffer fills, the client is considered slow/disconnected and removed.
 type WebSocketClient struct {
 	ID     string              // Unique client identifier (format: "userID-unixnano")
 	UserID string              // User ID for authorization and targeted broadcasts
+	Role   string              // User role, used to exempt admins from the idle timeout
 	Conn   *websocket.Conn     // Underlying WebSocket connection
 	Send   chan WebSocketMessage // Buffered channel for outbound messages (prevents blocking)
 	Hub    *WebSocketHub       // Reference to hub for broadcasting
 	Mu     sync.Mutex          // Mutex for thread-safe client state operations
+
+	// lastActivity is the UnixNano time of the last application message sent
+	// or received. Pings and pongs don't count (see idleFor).
+	lastActivity atomic.Int64
+
+	// version is the envelope version negotiated with the client (0 until
+	// negotiated, which means legacy).
+	version atomic.Int32
+
+	// subscriptions are the message types the client asked for (nil = all,
+	// see websocket_subscriptions.go). Guarded by Mu.
+	subscriptions map[string]bool
+}
+
+// markActivity records that an application message was sent or received.
+func (c *WebSocketClient) markActivity(now time.Time) {
+	c.lastActivity.Store(now.UnixNano())
 }
 
-// WebSocketHub is the central manager for all WebSocket connections.
+// idleFor returns how long the connection has carried no application
+// messages. A connection that never carried one is idle since it registered.
+func (c *WebSocketClient) idleFor(now time.Time) time.Duration {
+	return now.Sub(time.Unix(0, c.lastActivity.Load()))
+}
+
+// idleExpired reports whether the hub's idle timeout closes this connection.
+func (c *WebSocketClient) idleExpired(now time.Time) bool {
+	if c.Hub == nil || c.Hub.IdleTimeout <= 0 {
+		return false
+	}
+	if c.Hub.IdleExemptAdmins && c.Role == "admin" {
+		return false
+	}
+	return c.idleFor(now) > c.Hub.IdleTimeout
+}
+
+// WebSocketHub is the central manager for all enterprise WebSocket connections.
 //
-// It uses a hub-and-spoke architecture:
-// - Hub maintains all active clients in a map
-// - Clients register/unregister via channels
-// - Broadcast channel for sending to all clients
-// - RWMutex for safe concurrent access to the clients map
+// Registration, broadcasting and slow-client eviction (capped by
+// MaxEvictionsPerCycle) come from the shared wshub.Hub, which the session
+// hubs in internal/websocket are built on as well. WebSocketHub adds
+// per-user delivery, at-least-once delivery of critical messages and the
+// idle timeout.
 //
 // Thread Safety:
-// - Register/Unregister: Processed sequentially in Run() with write lock
-// - Broadcast: Uses read lock for iteration, write lock for cleanup
-// - BroadcastToUser: Uses read lock only (no modifications)
-//
-// The hub runs in a single goroutine (via Run()) to avoid race conditions
-// when modifying the clients map.
+// - Register/Unregister/Broadcast: Processed sequentially by Run()
+// - BroadcastToUser: Read lock only, non-blocking sends
 type WebSocketHub struct {
-	Clients    map[string]*WebSocketClient // All connected clients (key: client ID)
-	Register   chan *WebSocketClient       // Channel for new client registrations
-	Unregister chan *WebSocketClient       // Channel for client disconnections
-	Broadcast  chan WebSocketMessage       // Buffered channel for broadcast messages
-	Mu         sync.RWMutex                // Read-write mutex for thread-safe map access
+	*wshub.Hub[*WebSocketClient, WebSocketMessage]
+
+	// IdleTimeout closes connections that carried no application message
+	// for this long, e.g. abandoned dashboard tabs (0 = never). Keep-alive
+	// pings don't count as activity. Checked at every ping interval.
+	IdleTimeout time.Duration
+
+	// IdleExemptAdmins keeps admin connections open regardless of IdleTimeout.
+	IdleExemptAdmins bool
+
+	// MaxVersion is the newest envelope version sent to clients that ask
+	// for it (see websocket_version.go).
+	MaxVersion int
+
+	// acks tracks critical messages until the user acknowledges them.
+	acks *ackTracker
+
+	// shuttingDown is set by Shutdown so write pumps close connections
+	// with "going away" rather than an abnormal close.
+	shuttingDown atomic.Bool
+}
+
+// HubID implements wshub.Client.
+func (c *WebSocketClient) HubID() string {
+	return c.ID
+}
+
+// Outbox implements wshub.Client.
+func (c *WebSocketClient) Outbox() chan WebSocketMessage {
+	return c.Send
+}
+
+// newWebSocketHub creates a hub that isn't running yet.
+func newWebSocketHub() *WebSocketHub {
+	h := &WebSocketHub{
+		Hub:        wshub.New[*WebSocketClient, WebSocketMessage]("Enterprise", WebSocketBufferSize),
+		MaxVersion: WebSocketProtocolVersion,
+		acks:       newAckTracker(strings.Split(WebSocketAckTypes, ",")),
+	}
+	h.Accepts = func(client *WebSocketClient, message WebSocketMessage) bool {
+		return client.wants(message.Type)
+	}
+	return h
 }
 
 var (
@@ -108,14 +198,16 @@ var (
 	//
 	// Protection:
 	// - Validates Origin header against whitelist
-	// - Environment variables for production origins
-	// - Localhost defaults for development
-	// - Logs rejected connections for security monitoring
+	// - Environment variable for production origins, with wildcard subdomains
+	// - Localhost defaults for development (disabled in strict mode)
+	// - Records rejected connections as security events (see websocket_security.go)
 	//
 	// Configuration:
-	//   export ALLOWED_WEBSOCKET_ORIGIN_1="https://streamspace.yourdomain.com"
-	//   export ALLOWED_WEBSOCKET_ORIGIN_2="https://app.yourdomain.com"
-	//   export ALLOWED_WEBSOCKET_ORIGIN_3="https://admin.yourdomain.com"
+	//   export ALLOWED_WEBSOCKET_ORIGINS="https://streamspace.yourdomain.com,https://*.preview.yourdomain.com"
+	//   export WEBSOCKET_ENFORCE_ORIGIN=strict  # Production: no localhost defaults
+	//
+	// ALLOWED_WEBSOCKET_ORIGIN_1..3 are still read while ALLOWED_WEBSOCKET_ORIGINS
+	// is unset (see middleware.WebSocketAllowedOriginsFromEnv).
 	upgrader = websocket.Upgrader{
 		ReadBufferSize:  WebSocketReadBufferSize,  // 1024 bytes - buffer for incoming messages
 		WriteBufferSize: WebSocketWriteBufferSize, // 1024 bytes - buffer for outgoing messages
@@ -123,40 +215,43 @@ var (
 			// Get the Origin header from the HTTP request
 			// This header is automatically set by browsers and cannot be modified by JavaScript
 			origin := r.Header.Get("Origin")
+			policy := middleware.WebSocketOriginPolicyFromEnv()
 
 			// Allow same-origin requests (no Origin header)
 			// This happens when the WebSocket connection is initiated from the same domain
 			// Example: ws://localhost:8080 from page at http://localhost:8080
+			// Strict mode can reject them (WEBSOCKET_REJECT_EMPTY_ORIGIN)
 			if origin == "" {
-				return true // Same-origin, safe to allow
+				if !policy.AllowEmpty() {
+					reportOriginRejection(r, origin)
+				}
+				return policy.AllowEmpty()
 			}
 
-			// Get allowed origins from environment variables
-			// In production, set these to your actual domains
-			// Empty strings are ignored (allows partial configuration)
-			allowedOrigins := []string{
-				os.Getenv("ALLOWED_WEBSOCKET_ORIGIN_1"), // Production domain 1
-				os.Getenv("ALLOWED_WEBSOCKET_ORIGIN_2"), // Production domain 2 (e.g., admin panel)
-				os.Getenv("ALLOWED_WEBSOCKET_ORIGIN_3"), // Production domain 3 (e.g., mobile app)
-				"http://localhost:5173",                 // Development default (Vite dev server)
-				"http://localhost:3000",                 // Development default (Create React App)
-			}
+			// Allowed origins are read from the environment once, at the
+			// first handshake; in production, set them to your actual domains
+			allowedOrigins := append([]string{}, webSocketAllowedOrigins()...)
+			allowedOrigins = append(allowedOrigins, policy.DevOrigins(
+				"http://localhost:5173", // Development default (Vite dev server)
+				"http://localhost:3000", // Development default (Create React App)
+			)...)
 
-			// Check if the request's origin matches any allowed origin
-			// TrimSpace handles whitespace in environment variables
-			for _, allowed := range allowedOrigins {
-				if allowed != "" && strings.TrimSpace(allowed) == strings.TrimSpace(origin) {
-					return true // Origin is whitelisted, allow connection
-				}
+			// Check if the request's origin matches any allowed origin,
+			// exactly or by wildcard subdomain
+			if middleware.OriginAllowed(origin, allowedOrigins) {
+				return true // Origin is whitelisted, allow connection
 			}
 
-			// Origin not in whitelist - reject connection and log for security monitoring
-			// This log entry helps detect potential CSWSH attack attempts
-			log.Printf("[WebSocket Security] Rejected connection from unauthorized origin: %s", origin)
+			// Origin not in whitelist - reject connection and record a security event
+			// Repeated rejections help detect CSWSH attack attempts
+			reportOriginRejection(r, origin)
 			return false // Reject connection
 		},
 	}
 
+	// webSocketAllowedOrigins is the configured origin allowlist, parsed once
+	webSocketAllowedOrigins = sync.OnceValue(middleware.WebSocketAllowedOriginsFromEnv)
+
 	// Global hub instance - singleton pattern ensures all connections use the same hub
 	hub *WebSocketHub
 
@@ -184,120 +279,72 @@ func GetWebSocketHub() *WebSocketHub {
 	// once.Do executes the function exactly once, even with concurrent calls
 	// Subsequent calls to GetWebSocketHub() will skip this and return existing hub
 	once.Do(func() {
-		// Initialize the hub with empty maps and channels
-		hub = &WebSocketHub{
-			Clients:    make(map[string]*WebSocketClient),          // Initially empty, clients added via Register channel
-			Register:   make(chan *WebSocketClient),                // Unbuffered - blocks until Run() processes
-			Unregister: make(chan *WebSocketClient),                // Unbuffered - blocks until Run() processes
-			Broadcast:  make(chan WebSocketMessage, WebSocketBufferSize), // Buffered (256) - non-blocking sends
+		hub = newWebSocketHub()
+		hub.MaxEvictionsPerCycle = WebSocketMaxEvictionsPerCycle
+		if v := os.Getenv("WEBSOCKET_MAX_EVICTIONS_PER_CYCLE"); v != "" {
+			if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+				hub.MaxEvictionsPerCycle = n
+			} else {
+				log.Printf("Invalid WEBSOCKET_MAX_EVICTIONS_PER_CYCLE %q, using %d", v, hub.MaxEvictionsPerCycle)
+			}
 		}
+		hub.IdleTimeout, hub.IdleExemptAdmins = webSocketIdleConfigFromEnv()
+		hub.acks = ackTrackerFromEnv()
+		hub.Priority = webSocketPrioritiesFromEnv()
+		hub.MaxVersion = webSocketMaxVersionFromEnv()
 		// Start the hub's main event loop in a background goroutine
-		// This goroutine runs for the lifetime of the application
+		// This goroutine runs until Shutdown
 		go hub.Run()
+		go hub.retryUnacked()
 	})
 	return hub
 }
 
-// Run is the main event loop for the WebSocket hub.
-//
-// This function runs in a dedicated goroutine for the lifetime of the application.
-// It processes three types of events via select statement:
-//
-// 1. Register: Add new client connections
-// 2. Unregister: Remove disconnected clients
-// 3. Broadcast: Send message to all connected clients
-//
-// CRITICAL RACE CONDITION FIX:
-// The broadcast case uses a two-phase approach to prevent race conditions:
-//   Phase 1: Read lock - Iterate clients and collect slow/disconnected ones
-//   Phase 2: Write lock - Remove collected clients from map
-//
-// This prevents:
-// - Concurrent map read/write errors
-// - Deadlocks from holding write lock during iteration
-// - Message loss from blocking sends
-//
-// Why not hold write lock during broadcast?
-// - Broadcasting can be slow (network I/O)
-// - Write lock blocks ALL reads (BroadcastToUser, Register, Unregister)
-// - This would freeze the entire system during broadcasts
-//
-// Thread Safety:
-// - All map modifications use write lock (h.Mu.Lock)
-// - Map iteration uses read lock (h.Mu.RLock)
-// - Locks are held for minimum time necessary
-func (h *WebSocketHub) Run() {
-	// Infinite loop - runs for application lifetime
-	for {
-		// Block until one of the channels has data
-		select {
-		// New client wants to connect
-		case client := <-h.Register:
-			// Acquire write lock to modify clients map
-			h.Mu.Lock()
-			h.Clients[client.ID] = client // Add client to map
-			h.Mu.Unlock()
-			log.Printf("WebSocket client registered: %s (user: %s)", client.ID, client.UserID)
-
-		// Client disconnected (called from readPump when connection closes)
-		case client := <-h.Unregister:
-			// Acquire write lock to modify clients map
-			h.Mu.Lock()
-			// Check if client still exists (could have been removed elsewhere)
-			if _, ok := h.Clients[client.ID]; ok {
-				close(client.Send)          // Close send channel to stop writePump
-				delete(h.Clients, client.ID) // Remove from map
-			}
-			h.Mu.Unlock()
-			log.Printf("WebSocket client unregistered: %s", client.ID)
-
-		// Broadcast message to all clients
-		case message := <-h.Broadcast:
-			// PHASE 1: Iterate with READ lock to find slow clients
-			// We use read lock here because:
-			// - Multiple goroutines can broadcast simultaneously
-			// - We're only reading the map, not modifying it
-			// - Sending to channels doesn't require write lock
-			clientsToRemove := make([]*WebSocketClient, 0)
-
-			h.Mu.RLock() // Acquire read lock - allows concurrent reads
-			for _, client := range h.Clients {
-				// Try to send message to client
-				select {
-				case client.Send <- message:
-					// Message sent successfully to client's buffer
-					// Client's writePump goroutine will send it over WebSocket
-				default:
-					// Client's send buffer is full (256 messages backlog)
-					// This indicates:
-					// - Client is too slow (network issues)
-					// - Client has disconnected but cleanup hasn't finished
-					// - Client is unresponsive
-					// Mark for removal instead of blocking here
-					clientsToRemove = append(clientsToRemove, client)
-				}
-			}
-			h.Mu.RUnlock() // Release read lock
-
-			// PHASE 2: Remove slow clients with WRITE lock
-			// This two-phase approach prevents race conditions:
-			// - We don't modify map while holding read lock (would panic)
-			// - We don't hold write lock during iteration (would block everything)
-			// - We double-check existence (client might have been removed in Phase 1)
-			if len(clientsToRemove) > 0 {
-				h.Mu.Lock() // Acquire write lock for map modification
-				for _, client := range clientsToRemove {
-					// Double-check client still exists (might have been removed by Unregister)
-					if _, exists := h.Clients[client.ID]; exists {
-						close(client.Send)                                      // Stop writePump goroutine
-						delete(h.Clients, client.ID)                            // Remove from map
-						log.Printf("WebSocket client removed (buffer full): %s", client.ID) // Log for monitoring
-					}
-				}
-				h.Mu.Unlock() // Release write lock
-			}
+// webSocketIdleConfigFromEnv reads the idle timeout from
+// WEBSOCKET_IDLE_TIMEOUT (a duration such as "2h", unset or "0" disables it)
+// and whether admins are exempt from WEBSOCKET_IDLE_EXEMPT_ADMINS (default
+// true).
+func webSocketIdleConfigFromEnv() (time.Duration, bool) {
+	var timeout time.Duration
+	if v := os.Getenv("WEBSOCKET_IDLE_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			timeout = d
+		} else {
+			log.Printf("Invalid WEBSOCKET_IDLE_TIMEOUT %q, idle connections are not closed", v)
 		}
 	}
+
+	exemptAdmins := true
+	if v := os.Getenv("WEBSOCKET_IDLE_EXEMPT_ADMINS"); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			exemptAdmins = b
+		} else {
+			log.Printf("Invalid WEBSOCKET_IDLE_EXEMPT_ADMINS %q, using %t", v, exemptAdmins)
+		}
+	}
+	return timeout, exemptAdmins
+}
+
+// webSocketPrioritiesFromEnv returns the hub's message classifier. Priorities
+// are read from WEBSOCKET_MESSAGE_PRIORITIES ("type=priority,...", with
+// priorities low, normal, high or critical); types not listed are normal.
+// A lagging client drops low-priority messages to make room for more
+// important ones instead of being evicted.
+func webSocketPrioritiesFromEnv() func(WebSocketMessage) wshub.Priority {
+	priorities, _ := wshub.ParsePriorities(WebSocketMessagePriorities)
+	if v, ok := os.LookupEnv("WEBSOCKET_MESSAGE_PRIORITIES"); ok {
+		if parsed, err := wshub.ParsePriorities(v); err == nil {
+			priorities = parsed
+		} else {
+			log.Printf("Invalid WEBSOCKET_MESSAGE_PRIORITIES %q (%v), using %q", v, err, WebSocketMessagePriorities)
+		}
+	}
+	return func(message WebSocketMessage) wshub.Priority {
+		if p, ok := priorities[message.Type]; ok {
+			return p
+		}
+		return wshub.PriorityNormal
+	}
 }
 
 // BroadcastToUser sends a message to all connections belonging to a specific user.
@@ -311,6 +358,11 @@ func (h *WebSocketHub) Run() {
 // - Account security alerts (new login detected, password changed, etc.)
 // - Personal updates (quota warnings, scheduled session reminders, etc.)
 //
+// Critical message types are tracked until the user acks them and resent
+// if they don't (see websocket_ack.go); all others are fire-and-forget.
+// Connections subscribed to other types are skipped (see
+// websocket_subscriptions.go).
+//
 // Thread Safety:
 // - Uses read lock only (no map modifications)
 // - Non-blocking send via select/default
@@ -320,38 +372,42 @@ func (h *WebSocketHub) Run() {
 //   - userID: The user ID to target (from authentication context)
 //   - message: The WebSocketMessage to send
 func (h *WebSocketHub) BroadcastToUser(userID string, message WebSocketMessage) {
-	// Acquire read lock - allows concurrent reads
-	h.Mu.RLock()
-	defer h.Mu.RUnlock() // Release lock when function returns
-
-	// Iterate all clients looking for matching userID
-	for _, client := range h.Clients {
-		if client.UserID == userID {
-			// Try to send message without blocking
-			select {
-			case client.Send <- message:
-				// Message sent successfully to client's buffer
-			default:
-				// Client's buffer is full - skip this client
-				// The Run() goroutine will remove them during next broadcast
-				log.Printf("Failed to send to client %s (buffer full)", client.ID)
-			}
-		}
+	if h.acks.requiresAck(message.Type) {
+		message = h.acks.track(userID, message, time.Now())
 	}
+	h.sendToUser(userID, message)
+}
+
+// sendToUser delivers a message to the user's connections once.
+func (h *WebSocketHub) sendToUser(userID string, message WebSocketMessage) {
+	// Clients with a full buffer are skipped; the next broadcast evicts them
+	h.SendTo(func(client *WebSocketClient) bool {
+		return client.UserID == userID
+	}, message)
+}
+
+// BroadcastToRole sends a message to all connections of users with a role,
+// e.g. "admin" for node health, scaling and system-wide compliance events,
+// so other users never receive them. Like BroadcastToUser, clients with a
+// full buffer are skipped.
+func (h *WebSocketHub) BroadcastToRole(role string, message WebSocketMessage) {
+	h.SendTo(func(client *WebSocketClient) bool {
+		return client.Role == role
+	}, message)
 }
 
-// BroadcastToAll sends a message to all connected clients (typically for admin-level events).
+// BroadcastToAll sends a message to all connected clients.
 //
 // This function sends the message to the hub's broadcast channel, where it's
 // processed by the Run() goroutine and distributed to all clients.
 //
 // Use cases:
 // - System-wide notifications (maintenance window, new features, etc.)
-// - Admin-level events (node health changes, scaling events, etc.)
 // - Platform status updates (high load warnings, service degradation, etc.)
 //
 // IMPORTANT: This sends to ALL users regardless of role. For admin-only messages,
-// the client-side code should filter based on the user's role.
+// use BroadcastToRole. Clients that subscribed to specific types only get
+// those (see websocket_subscriptions.go).
 //
 // Thread Safety:
 // - Broadcast channel is buffered (256 messages)
@@ -361,9 +417,44 @@ func (h *WebSocketHub) BroadcastToUser(userID string, message WebSocketMessage)
 // Parameters:
 //   - message: The WebSocketMessage to broadcast to all clients
 func (h *WebSocketHub) BroadcastToAll(message WebSocketMessage) {
-	// Send message to broadcast channel
-	// The Run() goroutine will process it and send to all clients
-	h.Broadcast <- message
+	// Queue the message; the Run() goroutine sends it to all clients
+	h.Broadcast(message)
+}
+
+// Shutdown disconnects all clients gracefully, e.g. during a rolling deploy.
+//
+// It stops the Run() loop, so new connections are refused and later
+// broadcasts dropped, then sends every client a "server.shutdown" message
+// so the frontend can show "reconnecting" instead of an error. Every client
+// whose Register succeeded gets the notice; registrations racing with
+// Shutdown are either notified or refused. Once every
+// Send buffer has drained, or ctx expires, all connections are closed with
+// a "going away" close frame and Shutdown returns.
+func (h *WebSocketHub) Shutdown(ctx context.Context) {
+	h.shuttingDown.Store(true)
+	h.Stop()
+
+	notified := h.SendTo(func(*WebSocketClient) bool { return true }, WebSocketMessage{
+		Type:      "server.shutdown",
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"message": "Server is shutting down, reconnect shortly",
+		},
+	})
+	log.Printf("Enterprise WebSocket shutdown: notified %d clients, draining", notified)
+
+	ticker := time.NewTicker(WebSocketShutdownPollInterval)
+	defer ticker.Stop()
+	for h.Queued() > 0 {
+		select {
+		case <-ctx.Done():
+			log.Printf("Enterprise WebSocket shutdown: %d messages undelivered: %v", h.Queued(), ctx.Err())
+			h.CloseAll()
+			return
+		case <-ticker.C:
+		}
+	}
+	h.CloseAll()
 }
 
 // HandleEnterpriseWebSocket is the HTTP handler for WebSocket upgrade requests.
@@ -430,14 +521,25 @@ func HandleEnterpriseWebSocket(c *gin.Context) {
 	client := &WebSocketClient{
 		ID:     fmt.Sprintf("%s-%d", userID, time.Now().UnixNano()), // Unique ID: user123-1699999999999999999
 		UserID: userID.(string),                                     // Type assertion safe because auth middleware sets this
+		Role:   c.GetString("userRole"),                             // Admins may be exempt from the idle timeout
 		Conn:   conn,                                                // WebSocket connection
 		Send:   make(chan WebSocketMessage, WebSocketBufferSize),    // Buffered channel (256 messages)
 		Hub:    GetWebSocketHub(),                                   // Reference to global hub
 	}
+	client.markActivity(time.Now())
+
+	// Use the newest envelope both sides understand; clients that don't
+	// say get the legacy one
+	version := client.negotiateVersion(requestedVersion(c.Query("version")))
 
 	// Register client with hub (thread-safe via channel)
 	// This blocks until the hub's Run() goroutine processes it
-	client.Hub.Register <- client
+	if !client.Hub.Register(client) {
+		// The server is shutting down; the client should reconnect elsewhere
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
+		conn.Close()
+		return
+	}
 
 	// Start two goroutines for bidirectional communication:
 	// - writePump: Reads from Send channel and writes to WebSocket
@@ -452,10 +554,17 @@ func HandleEnterpriseWebSocket(c *gin.Context) {
 		Type:      "connection",
 		Timestamp: time.Now(),
 		Data: map[string]interface{}{
-			"status":  "connected",
-			"message": "Enterprise WebSocket connected",
+			"status":           "connected",
+			"message":          "Enterprise WebSocket connected",
+			"protocol_version": version,
 		},
 	}
+
+	// Resend critical messages the user hasn't acked, e.g. ones sent while
+	// they were offline or whose delivery failed
+	for _, message := range client.Hub.acks.missed(client.UserID, time.Now()) {
+		client.Hub.SendTo(func(other *WebSocketClient) bool { return other == client }, message)
+	}
 }
 
 // writePump is a goroutine that reads messages from the client's Send channel
@@ -510,7 +619,11 @@ func (c *WebSocketClient) writePump() {
 			if !ok {
 				// Hub closed our Send channel (client being removed)
 				// Send close message to client and exit gracefully
-				c.Conn.WriteMessage(websocket.CloseMessage, []byte{})
+				closeFrame := []byte{}
+				if c.Hub != nil && c.Hub.shuttingDown.Load() {
+					closeFrame = websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+				}
+				c.Conn.WriteMessage(websocket.CloseMessage, closeFrame)
 				return
 			}
 
@@ -522,8 +635,8 @@ func (c *WebSocketClient) writePump() {
 				return
 			}
 
-			// Marshal message to JSON
-			data, err := json.Marshal(message)
+			// Marshal message to JSON in the client's envelope version
+			data, err := c.encode(message)
 			if err != nil {
 				// This shouldn't happen unless Data contains un-marshalable types
 				log.Printf("Failed to marshal message: %v", err)
@@ -540,7 +653,7 @@ func (c *WebSocketClient) writePump() {
 			for i := 0; i < n; i++ {
 				w.Write([]byte{'\n'})     // Newline separator between messages
 				msg := <-c.Send           // Get next message from channel
-				data, _ := json.Marshal(msg) // Marshal to JSON (ignore error for batching)
+				data, _ := c.encode(msg)  // Marshal to JSON (ignore error for batching)
 				w.Write(data)              // Add to current frame
 			}
 
@@ -549,9 +662,19 @@ func (c *WebSocketClient) writePump() {
 				// Connection error during write - client probably disconnected
 				return
 			}
+			c.markActivity(time.Now())
 
 		// Ticker fired - time to send ping message
 		case <-ticker.C:
+			// Close abandoned connections (e.g. a dashboard tab left open
+			// for days) instead of keeping them alive with pings
+			if now := time.Now(); c.idleExpired(now) {
+				log.Printf("Closing idle WebSocket client %s (user: %s, idle %s)", c.ID, c.UserID, c.idleFor(now).Round(time.Second))
+				c.Conn.SetWriteDeadline(now.Add(WebSocketWriteDeadline))
+				c.Conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(WebSocketCloseIdle, "idle timeout"))
+				return
+			}
+
 			// Set write deadline for ping message
 			c.Conn.SetWriteDeadline(time.Now().Add(WebSocketWriteDeadline))
 
@@ -568,7 +691,7 @@ func (c *WebSocketClient) writePump() {
 // readPump is a goroutine that reads messages from the WebSocket connection.
 //
 // This function runs for the lifetime of the WebSocket connection and handles:
-// 1. Reading messages from the client (currently not processed, reserved for future)
+// 1. Reading messages from the client (acks, hellos and subscriptions)
 // 2. Responding to ping messages with pong (keep-alive mechanism)
 // 3. Detecting client disconnections
 // 4. Unregistering client from hub on disconnect
@@ -579,9 +702,12 @@ func (c *WebSocketClient) writePump() {
 // - Distinguishes between expected closes (user navigated away) and errors
 //
 // Current Implementation:
-// Currently, this is a "read and discard" pump. Client-to-server messages are
-// received but not processed. This could be extended in the future to:
-// - Allow clients to subscribe to specific event types
+// Clients send {"type": "ack", "id": N}, which settles a critical message
+// (see websocket_ack.go), and {"type": "hello", "version": N}, which
+// renegotiates the envelope version (see websocket_version.go), and
+// {"action": "subscribe", "types": [...]}, which narrows the message types
+// they receive (see websocket_subscriptions.go). Anything else is ignored.
+// This could be extended in the future to:
 // - Let clients request specific data updates
 // - Enable two-way communication for interactive features
 //
@@ -597,7 +723,7 @@ func (c *WebSocketClient) writePump() {
 func (c *WebSocketClient) readPump() {
 	// Cleanup when this goroutine exits
 	defer func() {
-		c.Hub.Unregister <- c  // Tell hub to remove us (thread-safe via channel)
+		c.Hub.Unregister(c)    // Tell hub to remove us (thread-safe via channel)
 		c.Conn.Close()         // Close WebSocket connection
 	}()
 
@@ -617,10 +743,9 @@ func (c *WebSocketClient) readPump() {
 	// Infinite loop - read messages until connection closes
 	for {
 		// Read a message from the client
-		// Currently we discard the message (_, _) because we're using
-		// WebSocket primarily for server-to-client updates.
-		// This could be extended to handle client messages if needed.
-		_, _, err := c.Conn.ReadMessage()
+		// WebSocket is used primarily for server-to-client updates; clients
+		// only send acks, version hellos and type subscriptions
+		_, data, err := c.Conn.ReadMessage()
 		if err != nil {
 			// Check if this is an unexpected error
 			// Expected closes include:
@@ -634,16 +759,35 @@ func (c *WebSocketClient) readPump() {
 			// defer will handle cleanup (unregister + close)
 			break
 		}
+		c.markActivity(time.Now())
+		c.handleClientMessage(data)
 
-		// Client messages can be handled here if needed
+		// Other client messages can be handled here if needed
 		// Example future use cases:
-		// - Parse JSON command from client
-		// - Subscribe to specific event types
 		// - Request data updates
 		// - Send client-side metrics/telemetry
 	}
 }
 
+// handleClientMessage processes a message read from the client. Malformed
+// and unknown messages are ignored.
+func (c *WebSocketClient) handleClientMessage(data []byte) {
+	var msg clientMessage
+	if err := json.Unmarshal(data, &msg); err != nil {
+		return
+	}
+	if msg.Action == "subscribe" {
+		c.handleSubscribe(msg.Types)
+		return
+	}
+	switch msg.Type {
+	case "ack":
+		c.Hub.acks.ack(c.UserID, msg.ID)
+	case "hello":
+		c.hello(msg.Version)
+	}
+}
+
 // ============================================================================
 // Helper Functions for Broadcasting Enterprise Events
 // ============================================================================
@@ -729,6 +873,82 @@ func BroadcastSecurityAlert(userID string, alertType string, severity string, me
 	GetWebSocketHub().BroadcastToUser(userID, msg)
 }
 
+// BroadcastQuotaWarning tells a user that their usage is nearing their quota.
+//
+// This lets users upgrade or clean up before a session is rejected. It is
+// sent when a session is created that brings a quota dimension (sessions,
+// CPU, memory, storage) to or above the warning threshold.
+//
+// Parameters:
+//   - userID: The user nearing their quota
+//   - warnings: The dimensions at or above the threshold
+//
+// Example usage:
+//   BroadcastQuotaWarning("user123", []quota.QuotaWarning{{Resource: "cpu", Current: 3500, Limit: 4000, Percentage: 87.5}})
+func BroadcastQuotaWarning(userID string, warnings []quota.QuotaWarning) {
+	messages := make([]string, 0, len(warnings))
+	for _, warning := range warnings {
+		messages = append(messages, warning.Message())
+	}
+	msg := WebSocketMessage{
+		Type:      "quota.warning", // Message type for client-side routing
+		Timestamp: time.Now(),      // Server timestamp
+		Data: map[string]interface{}{
+			"warnings": warnings,                     // Current, limit and percentage per dimension
+			"message":  strings.Join(messages, "; "), // Human-readable summary
+		},
+	}
+	// Send only to the affected user
+	GetWebSocketHub().BroadcastToUser(userID, msg)
+}
+
+// BroadcastSessionTerminated tells a user that one of their sessions was
+// terminated. The message is delivered at least once (see websocket_ack.go).
+//
+// Parameters:
+//   - userID: The session owner
+//   - sessionID: The terminated session
+//   - reason: Why the session ended (may be empty)
+//
+// Example usage:
+//   BroadcastSessionTerminated("user123", "user123-firefox-abc", "idle timeout")
+func BroadcastSessionTerminated(userID string, sessionID string, reason string) {
+	message := WebSocketMessage{
+		Type:      "session.terminated", // Message type for client-side routing
+		Timestamp: time.Now(),           // Server timestamp
+		Data: map[string]interface{}{
+			"session_id": sessionID, // Kubernetes session ID
+			"reason":     reason,    // Why the session ended
+		},
+	}
+	// Send only to the session owner
+	GetWebSocketHub().BroadcastToUser(userID, message)
+}
+
+// BroadcastSessionHibernating warns a user that one of their sessions is
+// about to hibernate: it no longer accepts connections and is scaled down
+// once the open ones close or the hibernation grace has passed.
+//
+// Parameters:
+//   - userID: The session owner
+//   - sessionID: The hibernating session
+//   - reason: When the session hibernates (may be empty)
+//
+// Example usage:
+//   BroadcastSessionHibernating("user123", "user123-firefox-abc", "Session hibernates when its connections close, at most in 5m0s")
+func BroadcastSessionHibernating(userID string, sessionID string, reason string) {
+	message := WebSocketMessage{
+		Type:      "session.hibernating", // Message type for client-side routing
+		Timestamp: time.Now(),            // Server timestamp
+		Data: map[string]interface{}{
+			"session_id": sessionID, // Kubernetes session ID
+			"reason":     reason,    // When the session hibernates
+		},
+	}
+	// Send only to the session owner
+	GetWebSocketHub().BroadcastToUser(userID, message)
+}
+
 // BroadcastScheduledSessionEvent sends updates about scheduled session execution.
 //
 // This notifies users when their scheduled sessions start, complete, or fail.
@@ -761,8 +981,7 @@ func BroadcastScheduledSessionEvent(userID string, scheduleID int, event string,
 // This provides real-time cluster monitoring in the admin dashboard. Admins
 // can see node health, CPU, and memory usage updating live without refreshing.
 //
-// SECURITY: This is broadcast to ALL connected clients. The frontend should
-// filter this message type to only display for admin users.
+// SECURITY: This is sent only to admin connections, never to other users.
 //
 // Parameters:
 //   - nodeName: Kubernetes node name (e.g., "worker-01")
@@ -783,8 +1002,8 @@ func BroadcastNodeHealthUpdate(nodeName string, status string, cpu float64, memo
 			"memory_percent": memory,   // Memory usage percentage
 		},
 	}
-	// Broadcast to all connected clients (frontend filters for admins)
-	GetWebSocketHub().BroadcastToAll(message)
+	// Send only to admins
+	GetWebSocketHub().BroadcastToRole("admin", message)
 }
 
 // BroadcastScalingEvent sends auto-scaling events to admins.
@@ -793,8 +1012,7 @@ func BroadcastNodeHealthUpdate(nodeName string, status string, cpu float64, memo
 // in response to resource usage or scaling policies. Admins see these events
 // live in the admin dashboard.
 //
-// SECURITY: This is broadcast to ALL connected clients. The frontend should
-// filter this message type to only display for admin users.
+// SECURITY: This is sent only to admin connections, never to other users.
 //
 // Parameters:
 //   - policyID: The scaling policy ID that triggered this event
@@ -813,8 +1031,8 @@ func BroadcastScalingEvent(policyID int, action string, result string) {
 			"result":    result,   // "success", "failed"
 		},
 	}
-	// Broadcast to all connected clients (frontend filters for admins)
-	GetWebSocketHub().BroadcastToAll(message)
+	// Send only to admins
+	GetWebSocketHub().BroadcastToRole("admin", message)
 }
 
 // BroadcastComplianceViolation sends compliance violation alerts.
@@ -856,8 +1074,7 @@ func BroadcastComplianceViolation(userID string, violationID int, policyID int,
 		// User-specific violation - send only to that user
 		GetWebSocketHub().BroadcastToUser(userID, message)
 	} else {
-		// System-wide violation - broadcast to all admins
-		// Frontend should filter this to only show to admin users
-		GetWebSocketHub().BroadcastToAll(message)
+		// System-wide violation - send only to admins
+		GetWebSocketHub().BroadcastToRole("admin", message)
 	}
 }
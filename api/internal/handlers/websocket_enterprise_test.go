@@ -1,21 +1,20 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
+	"os"
 	"testing"
 	"time"
 
+	"github.com/streamspace/streamspace/api/internal/wshub"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestWebSocketHub(t *testing.T) {
-	hub := &WebSocketHub{
-		Clients:    make(map[string]*WebSocketClient),
-		Register:   make(chan *WebSocketClient),
-		Unregister: make(chan *WebSocketClient),
-		Broadcast:  make(chan WebSocketMessage, 256),
-	}
+	hub := newWebSocketHub()
 
 	// Start hub in goroutine
 	go hub.Run()
@@ -31,12 +30,10 @@ func TestWebSocketHub(t *testing.T) {
 			Hub:    hub,
 		}
 
-		hub.Register <- client
+		hub.Register(client)
 		time.Sleep(50 * time.Millisecond)
 
-		hub.Mu.RLock()
-		_, exists := hub.Clients[client.ID]
-		hub.Mu.RUnlock()
+		_, exists := hub.Client(client.ID)
 
 		assert.True(t, exists, "Client should be registered")
 	})
@@ -49,15 +46,13 @@ func TestWebSocketHub(t *testing.T) {
 			Hub:    hub,
 		}
 
-		hub.Register <- client
+		hub.Register(client)
 		time.Sleep(50 * time.Millisecond)
 
-		hub.Unregister <- client
+		hub.Unregister(client)
 		time.Sleep(50 * time.Millisecond)
 
-		hub.Mu.RLock()
-		_, exists := hub.Clients[client.ID]
-		hub.Mu.RUnlock()
+		_, exists := hub.Client(client.ID)
 
 		assert.False(t, exists, "Client should be unregistered")
 	})
@@ -77,8 +72,8 @@ func TestWebSocketHub(t *testing.T) {
 			Hub:    hub,
 		}
 
-		hub.Register <- client1
-		hub.Register <- client2
+		hub.Register(client1)
+		hub.Register(client2)
 		time.Sleep(50 * time.Millisecond)
 
 		message := WebSocketMessage{
@@ -89,7 +84,7 @@ func TestWebSocketHub(t *testing.T) {
 			},
 		}
 
-		hub.Broadcast <- message
+		hub.Broadcast(message)
 		time.Sleep(50 * time.Millisecond)
 
 		// Check both clients received the message
@@ -111,12 +106,7 @@ func TestWebSocketHub(t *testing.T) {
 }
 
 func TestBroadcastToUser(t *testing.T) {
-	hub := &WebSocketHub{
-		Clients:    make(map[string]*WebSocketClient),
-		Register:   make(chan *WebSocketClient),
-		Unregister: make(chan *WebSocketClient),
-		Broadcast:  make(chan WebSocketMessage, 256),
-	}
+	hub := newWebSocketHub()
 
 	go hub.Run()
 	time.Sleep(50 * time.Millisecond)
@@ -144,9 +134,9 @@ func TestBroadcastToUser(t *testing.T) {
 		Hub:    hub,
 	}
 
-	hub.Register <- client1
-	hub.Register <- client2
-	hub.Register <- client3
+	hub.Register(client1)
+	hub.Register(client2)
+	hub.Register(client3)
 	time.Sleep(50 * time.Millisecond)
 
 	message := WebSocketMessage{
@@ -303,12 +293,7 @@ func TestWebSocketEventTypes(t *testing.T) {
 }
 
 func TestConcurrentClientRegistration(t *testing.T) {
-	hub := &WebSocketHub{
-		Clients:    make(map[string]*WebSocketClient),
-		Register:   make(chan *WebSocketClient),
-		Unregister: make(chan *WebSocketClient),
-		Broadcast:  make(chan WebSocketMessage, 256),
-	}
+	hub := newWebSocketHub()
 
 	go hub.Run()
 	time.Sleep(50 * time.Millisecond)
@@ -323,7 +308,7 @@ func TestConcurrentClientRegistration(t *testing.T) {
 				Send:   make(chan WebSocketMessage, 256),
 				Hub:    hub,
 			}
-			hub.Register <- client
+			hub.Register(client)
 			done <- true
 		}(i)
 	}
@@ -334,20 +319,13 @@ func TestConcurrentClientRegistration(t *testing.T) {
 	}
 	time.Sleep(100 * time.Millisecond)
 
-	hub.Mu.RLock()
-	clientCount := len(hub.Clients)
-	hub.Mu.RUnlock()
+	clientCount := hub.ClientCount()
 
 	assert.Equal(t, 100, clientCount, "All 100 clients should be registered")
 }
 
 func TestMessageDeliveryReliability(t *testing.T) {
-	hub := &WebSocketHub{
-		Clients:    make(map[string]*WebSocketClient),
-		Register:   make(chan *WebSocketClient),
-		Unregister: make(chan *WebSocketClient),
-		Broadcast:  make(chan WebSocketMessage, 256),
-	}
+	hub := newWebSocketHub()
 
 	go hub.Run()
 	time.Sleep(50 * time.Millisecond)
@@ -359,7 +337,7 @@ func TestMessageDeliveryReliability(t *testing.T) {
 		Hub:    hub,
 	}
 
-	hub.Register <- client
+	hub.Register(client)
 	time.Sleep(50 * time.Millisecond)
 
 	// Send 100 messages
@@ -395,3 +373,174 @@ loop:
 
 	assert.Equal(t, 100, receivedCount, "All 100 messages should be received")
 }
+
+func TestOriginRejectionReporter_Aggregation(t *testing.T) {
+	reporter := NewOriginRejectionReporter(nil, time.Minute)
+	var events []originRejectionEvent
+	reporter.emit = func(event originRejectionEvent) {
+		events = append(events, event)
+	}
+
+	start := time.Now()
+
+	// First rejection from an IP is reported immediately
+	reporter.Record("203.0.113.42", "https://evil.com", "/api/v1/ws/enterprise", start)
+	assert.Len(t, events, 1)
+	assert.False(t, events[0].Summary)
+	assert.Equal(t, "203.0.113.42", events[0].RemoteIP)
+	assert.Equal(t, []string{"https://evil.com"}, events[0].Origins)
+
+	// Repeats within the window are suppressed
+	for i := 0; i < 11; i++ {
+		reporter.Record("203.0.113.42", "https://evil2.com", "/api/v1/ws/enterprise", start.Add(time.Second))
+	}
+	assert.Len(t, events, 1)
+
+	// Other IPs are tracked independently
+	reporter.Record("198.51.100.7", "https://evil.com", "/api/v1/ws/enterprise", start.Add(time.Second))
+	assert.Len(t, events, 2)
+
+	// Flush before the window closes reports nothing
+	reporter.Flush(start.Add(30 * time.Second))
+	assert.Len(t, events, 2)
+
+	// Flush after the window reports one summary for the repeated IP only
+	reporter.Flush(start.Add(2 * time.Minute))
+	assert.Len(t, events, 3)
+	summary := events[2]
+	assert.True(t, summary.Summary)
+	assert.Equal(t, "203.0.113.42", summary.RemoteIP)
+	assert.Equal(t, 12, summary.Count)
+	assert.Equal(t, []string{"https://evil.com", "https://evil2.com"}, summary.Origins)
+	assert.Equal(t, "high", summary.severity())
+
+	// After flushing, the next rejection starts a new window
+	reporter.Record("203.0.113.42", "https://evil.com", "/api/v1/ws/enterprise", start.Add(3*time.Minute))
+	assert.Len(t, events, 4)
+	assert.False(t, events[3].Summary)
+	assert.Equal(t, "medium", events[3].severity())
+}
+
+func TestWebSocketClient_IdleExpired(t *testing.T) {
+	now := time.Now()
+	hub := &WebSocketHub{IdleTimeout: time.Hour, IdleExemptAdmins: true}
+
+	newClient := func(role string, lastActivity time.Time) *WebSocketClient {
+		client := &WebSocketClient{ID: "c", UserID: "u", Role: role, Hub: hub}
+		client.markActivity(lastActivity)
+		return client
+	}
+
+	assert.False(t, newClient("user", now.Add(-30*time.Minute)).idleExpired(now), "active within timeout")
+	assert.True(t, newClient("user", now.Add(-2*time.Hour)).idleExpired(now), "idle beyond timeout")
+	assert.False(t, newClient("admin", now.Add(-2*time.Hour)).idleExpired(now), "admins exempt")
+
+	hub.IdleExemptAdmins = false
+	assert.True(t, newClient("admin", now.Add(-2*time.Hour)).idleExpired(now), "admins not exempt")
+
+	hub.IdleTimeout = 0
+	assert.False(t, newClient("user", now.Add(-48*time.Hour)).idleExpired(now), "timeout disabled")
+}
+
+func TestWebSocketIdleConfigFromEnv(t *testing.T) {
+	t.Setenv("WEBSOCKET_IDLE_TIMEOUT", "")
+	t.Setenv("WEBSOCKET_IDLE_EXEMPT_ADMINS", "")
+	timeout, exempt := webSocketIdleConfigFromEnv()
+	assert.Equal(t, time.Duration(0), timeout)
+	assert.True(t, exempt)
+
+	t.Setenv("WEBSOCKET_IDLE_TIMEOUT", "2h")
+	t.Setenv("WEBSOCKET_IDLE_EXEMPT_ADMINS", "false")
+	timeout, exempt = webSocketIdleConfigFromEnv()
+	assert.Equal(t, 2*time.Hour, timeout)
+	assert.False(t, exempt)
+
+	t.Setenv("WEBSOCKET_IDLE_TIMEOUT", "forever")
+	timeout, _ = webSocketIdleConfigFromEnv()
+	assert.Equal(t, time.Duration(0), timeout)
+}
+
+func TestWebSocketPrioritiesFromEnv(t *testing.T) {
+	t.Setenv("WEBSOCKET_MESSAGE_PRIORITIES", "")
+	os.Unsetenv("WEBSOCKET_MESSAGE_PRIORITIES")
+	priority := webSocketPrioritiesFromEnv()
+	assert.Equal(t, wshub.PriorityCritical, priority(WebSocketMessage{Type: "security.alert"}))
+	assert.Equal(t, wshub.PriorityLow, priority(WebSocketMessage{Type: "node.health"}))
+	assert.Equal(t, wshub.PriorityNormal, priority(WebSocketMessage{Type: "webhook.delivery"}))
+
+	t.Setenv("WEBSOCKET_MESSAGE_PRIORITIES", "webhook.delivery=high")
+	priority = webSocketPrioritiesFromEnv()
+	assert.Equal(t, wshub.PriorityHigh, priority(WebSocketMessage{Type: "webhook.delivery"}))
+	assert.Equal(t, wshub.PriorityNormal, priority(WebSocketMessage{Type: "security.alert"}))
+
+	t.Setenv("WEBSOCKET_MESSAGE_PRIORITIES", "node.health=urgent")
+	priority = webSocketPrioritiesFromEnv()
+	assert.Equal(t, wshub.PriorityLow, priority(WebSocketMessage{Type: "node.health"}), "invalid config keeps the defaults")
+}
+
+func TestWebSocketHub_Shutdown(t *testing.T) {
+	hub := newWebSocketHub()
+	go hub.Run()
+
+	// A client whose write pump keeps up, and one that never reads
+	draining := &WebSocketClient{ID: "draining", UserID: "user1", Send: make(chan WebSocketMessage, 4), Hub: hub}
+	stuck := &WebSocketClient{ID: "stuck", UserID: "user2", Send: make(chan WebSocketMessage, 4), Hub: hub}
+	require.True(t, hub.Register(draining))
+	require.True(t, hub.Register(stuck))
+	stuck.subscribe([]string{"security.alert"})
+
+	received := make(chan []string, 1)
+	go func() {
+		var types []string
+		for msg := range draining.Send {
+			types = append(types, msg.Type)
+		}
+		received <- types
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	hub.Shutdown(ctx)
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond, "waits for the stuck client's buffer")
+
+	select {
+	case types := <-received:
+		assert.Equal(t, []string{"server.shutdown"}, types)
+	case <-time.After(time.Second):
+		t.Fatal("draining client was not closed")
+	}
+	msg, ok := <-stuck.Send
+	assert.True(t, ok)
+	assert.Equal(t, "server.shutdown", msg.Type, "shutdown notices ignore subscriptions")
+	_, ok = <-stuck.Send
+	assert.False(t, ok, "stuck client is closed once the context expires")
+
+	// Later connections and broadcasts don't block
+	late := &WebSocketClient{ID: "late", UserID: "user3", Send: make(chan WebSocketMessage, 4), Hub: hub}
+	assert.False(t, hub.Register(late))
+	hub.BroadcastToAll(WebSocketMessage{Type: "node.health", Timestamp: time.Now()})
+	assert.Equal(t, 0, hub.ClientCount())
+}
+
+func TestBroadcastToRole(t *testing.T) {
+	hub := newWebSocketHub()
+	go hub.Run()
+	defer hub.Stop()
+
+	admin := &WebSocketClient{ID: "admin", UserID: "root", Role: "admin", Send: make(chan WebSocketMessage, 4), Hub: hub}
+	user := &WebSocketClient{ID: "user", UserID: "alice", Role: "user", Send: make(chan WebSocketMessage, 4), Hub: hub}
+	hub.Register(admin)
+	hub.Register(user)
+	assert.Eventually(t, func() bool { return hub.ClientCount() == 2 }, time.Second, 5*time.Millisecond)
+
+	hub.BroadcastToRole("admin", WebSocketMessage{Type: "compliance.violation", Timestamp: time.Now()})
+
+	select {
+	case msg := <-admin.Send:
+		assert.Equal(t, "compliance.violation", msg.Type)
+	default:
+		t.Error("Admin did not receive the role broadcast")
+	}
+	assert.Empty(t, user.Send, "Other roles must not receive admin-only events")
+}
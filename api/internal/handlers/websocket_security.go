@@ -0,0 +1,282 @@
+// Package handlers provides HTTP and WebSocket handlers for the StreamSpace API.
+// This file implements security event reporting for rejected WebSocket origins.
+//
+// A CheckOrigin rejection is the visible trace of a Cross-Site WebSocket
+// Hijacking (CSWSH) attempt, so each rejection is recorded as a structured
+// security event:
+// - Written to the audit_log table (action "websocket.origin_rejected")
+// - Sent to every admin as a "security.alert" WebSocket message
+//
+// Aggregation:
+// A hostile page can retry the handshake in a loop, so rejections are
+// aggregated per remote IP. The first rejection from an IP is reported
+// immediately; further rejections within the aggregation window are counted
+// and reported once as a summary when the window closes. Escalation is by
+// count: a summary of OriginRejectionHighSeverityCount or more rejections is
+// reported with "high" severity.
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/streamspace/streamspace/api/internal/audit"
+	"github.com/streamspace/streamspace/api/internal/db"
+)
+
+const (
+	// OriginRejectionWindow is how long repeated rejections from one IP are
+	// aggregated into a single summary event.
+	OriginRejectionWindow = 5 * time.Minute
+
+	// OriginRejectionHighSeverityCount is the number of rejections within one
+	// window at which the summary is reported with "high" severity.
+	OriginRejectionHighSeverityCount = 10
+)
+
+// originRejection tracks rejections from a single remote IP within a window.
+type originRejection struct {
+	RemoteIP   string
+	Origins    map[string]bool
+	Path       string
+	FirstSeen  time.Time
+	LastSeen   time.Time
+	Count      int // Total rejections in this window
+	Suppressed int // Rejections not yet reported
+}
+
+// originRejectionEvent is a single reportable security event.
+type originRejectionEvent struct {
+	RemoteIP  string
+	Origins   []string
+	Path      string
+	FirstSeen time.Time
+	LastSeen  time.Time
+	Count     int
+	Summary   bool // True for aggregated summaries, false for first sightings
+}
+
+// OriginRejectionReporter records rejected WebSocket origins as security events.
+type OriginRejectionReporter struct {
+	database *db.Database
+	window   time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*originRejection
+
+	// emit is replaced in tests to capture events
+	emit func(event originRejectionEvent)
+}
+
+var (
+	// originReporter is the process-wide reporter used by CheckOrigin
+	originReporter     *OriginRejectionReporter
+	originReporterOnce sync.Once
+)
+
+// NewOriginRejectionReporter creates a reporter that aggregates per IP over window.
+//
+// If database is nil, events are only logged and broadcast to admins is skipped
+// (admin user IDs come from the database).
+func NewOriginRejectionReporter(database *db.Database, window time.Duration) *OriginRejectionReporter {
+	r := &OriginRejectionReporter{
+		database: database,
+		window:   window,
+		entries:  make(map[string]*originRejection),
+	}
+	r.emit = r.report
+	return r
+}
+
+// InitOriginRejectionReporter configures the reporter used by WebSocket
+// CheckOrigin functions and starts its flush loop.
+//
+// Call once at startup, after the database is connected. Rejections that
+// occur before initialization are still logged.
+func InitOriginRejectionReporter(database *db.Database) {
+	originReporterOnce.Do(func() {
+		originReporter = NewOriginRejectionReporter(database, OriginRejectionWindow)
+		go originReporter.run()
+	})
+}
+
+// reportOriginRejection records a rejected WebSocket handshake.
+func reportOriginRejection(r *http.Request, origin string) {
+	if originReporter == nil {
+		log.Printf("[WebSocket Security] Rejected connection from unauthorized origin: %s (remote %s)",
+			origin, remoteIP(r))
+		return
+	}
+	originReporter.Record(remoteIP(r), origin, r.URL.Path, time.Now())
+}
+
+// Record registers one rejection and reports it if it starts a new window.
+func (r *OriginRejectionReporter) Record(ip, origin, path string, now time.Time) {
+	var events []originRejectionEvent
+
+	r.mu.Lock()
+	entry, exists := r.entries[ip]
+	if exists && now.Sub(entry.FirstSeen) >= r.window {
+		// Window closed - report anything suppressed and start over
+		if entry.Suppressed > 0 {
+			events = append(events, entry.summary())
+		}
+		exists = false
+	}
+
+	if !exists {
+		entry = &originRejection{
+			RemoteIP:  ip,
+			Origins:   map[string]bool{origin: true},
+			Path:      path,
+			FirstSeen: now,
+			LastSeen:  now,
+			Count:     1,
+		}
+		r.entries[ip] = entry
+		events = append(events, originRejectionEvent{
+			RemoteIP:  ip,
+			Origins:   []string{origin},
+			Path:      path,
+			FirstSeen: now,
+			LastSeen:  now,
+			Count:     1,
+		})
+	} else {
+		entry.Origins[origin] = true
+		entry.LastSeen = now
+		entry.Count++
+		entry.Suppressed++
+	}
+	r.mu.Unlock()
+
+	for _, event := range events {
+		r.emit(event)
+	}
+}
+
+// Flush reports summaries for windows that have closed and forgets them.
+func (r *OriginRejectionReporter) Flush(now time.Time) {
+	var events []originRejectionEvent
+
+	r.mu.Lock()
+	for ip, entry := range r.entries {
+		if now.Sub(entry.FirstSeen) < r.window {
+			continue
+		}
+		if entry.Suppressed > 0 {
+			events = append(events, entry.summary())
+		}
+		delete(r.entries, ip)
+	}
+	r.mu.Unlock()
+
+	for _, event := range events {
+		r.emit(event)
+	}
+}
+
+// run flushes closed windows periodically so summaries are reported even if
+// the attacker stops.
+func (r *OriginRejectionReporter) run() {
+	ticker := time.NewTicker(r.window / 5)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		r.Flush(now)
+	}
+}
+
+// summary builds the aggregated event for an entry. Caller must hold r.mu.
+func (e *originRejection) summary() originRejectionEvent {
+	origins := make([]string, 0, len(e.Origins))
+	for origin := range e.Origins {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+
+	return originRejectionEvent{
+		RemoteIP:  e.RemoteIP,
+		Origins:   origins,
+		Path:      e.Path,
+		FirstSeen: e.FirstSeen,
+		LastSeen:  e.LastSeen,
+		Count:     e.Count,
+		Summary:   true,
+	}
+}
+
+// severity returns the alert severity for an event.
+func (e originRejectionEvent) severity() string {
+	if e.Count >= OriginRejectionHighSeverityCount {
+		return "high"
+	}
+	return "medium"
+}
+
+// report logs, audits, and broadcasts a rejection event.
+func (r *OriginRejectionReporter) report(event originRejectionEvent) {
+	var message string
+	if event.Summary {
+		message = fmt.Sprintf("Rejected %d WebSocket connections from %s with unauthorized origins %v between %s and %s",
+			event.Count, event.RemoteIP, event.Origins,
+			event.FirstSeen.Format(time.RFC3339), event.LastSeen.Format(time.RFC3339))
+	} else {
+		message = fmt.Sprintf("Rejected WebSocket connection from %s with unauthorized origin %s",
+			event.RemoteIP, event.Origins[0])
+	}
+	log.Printf("[WebSocket Security] %s", message)
+
+	if r.database == nil {
+		return
+	}
+
+	details, _ := json.Marshal(audit.Values(map[string]interface{}{
+		"origins":    event.Origins,
+		"remote_ip":  event.RemoteIP,
+		"path":       event.Path,
+		"count":      event.Count,
+		"first_seen": event.FirstSeen,
+		"last_seen":  event.LastSeen,
+		"summary":    event.Summary,
+		"severity":   event.severity(),
+	}))
+
+	_, err := r.database.DB().Exec(`
+		INSERT INTO audit_log (user_id, action, resource_type, resource_id, changes, timestamp, ip_address)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, "", "websocket.origin_rejected", "websocket", event.Path, details, event.LastSeen, event.RemoteIP)
+	if err != nil {
+		log.Printf("[WebSocket Security] Failed to audit origin rejection: %v", err)
+	}
+
+	rows, err := r.database.DB().Query(`SELECT id FROM users WHERE role = 'admin' AND active = true`)
+	if err != nil {
+		log.Printf("[WebSocket Security] Failed to look up admins for origin alert: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var adminID string
+		if err := rows.Scan(&adminID); err != nil {
+			continue
+		}
+		BroadcastSecurityAlert(adminID, "websocket_origin_rejected", event.severity(), message)
+	}
+}
+
+// remoteIP returns the client IP of a request without the port.
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
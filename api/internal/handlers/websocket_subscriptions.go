@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"log"
+	"strings"
+	"time"
+)
+
+// Per-type subscriptions on the enterprise WebSocket.
+//
+// By default every client receives every broadcast and filters by type
+// client-side, so a mobile client showing only security alerts still
+// downloads every node.health and scaling.event update. A client can narrow
+// what it receives with
+//
+//	{"action": "subscribe", "types": ["security.alert", "webhook.delivery"]}
+//
+// which replaces its previous subscription and is answered by a
+// "subscribed" message listing the types now delivered. Messages of other
+// types are not sent to that connection, whether broadcast to everyone or
+// to its user. An empty or missing list restores the default of receiving
+// everything. Control messages (connection, hello, subscribed,
+// server.shutdown) are always delivered.
+//
+// Subscriptions are per connection: a user's other tabs keep their own.
+
+// controlMessageTypes are delivered regardless of subscriptions.
+var controlMessageTypes = map[string]bool{
+	"connection":      true,
+	"hello":           true,
+	"subscribed":      true,
+	"server.shutdown": true,
+}
+
+// subscribe replaces the message types the client receives; none means
+// all. At most WebSocketMaxSubscriptionTypes types are kept.
+func (c *WebSocketClient) subscribe(types []string) []string {
+	var subscriptions map[string]bool
+	for _, msgType := range types {
+		msgType = strings.TrimSpace(msgType)
+		if msgType == "" {
+			continue
+		}
+		if len(subscriptions) == WebSocketMaxSubscriptionTypes {
+			log.Printf("WebSocket client %s subscribed to more than %d types, ignoring the rest", c.ID, WebSocketMaxSubscriptionTypes)
+			break
+		}
+		if subscriptions == nil {
+			subscriptions = make(map[string]bool)
+		}
+		subscriptions[msgType] = true
+	}
+
+	c.Mu.Lock()
+	c.subscriptions = subscriptions
+	c.Mu.Unlock()
+
+	subscribed := make([]string, 0, len(subscriptions))
+	for msgType := range subscriptions {
+		subscribed = append(subscribed, msgType)
+	}
+	return subscribed
+}
+
+// wants reports whether the client receives messages of msgType.
+func (c *WebSocketClient) wants(msgType string) bool {
+	if controlMessageTypes[msgType] {
+		return true
+	}
+	c.Mu.Lock()
+	defer c.Mu.Unlock()
+	return c.subscriptions == nil || c.subscriptions[msgType]
+}
+
+// handleSubscribe applies a subscribe request and tells the client the
+// result. An empty types list in the reply means all types.
+func (c *WebSocketClient) handleSubscribe(types []string) {
+	subscribed := c.subscribe(types)
+	c.Hub.SendTo(func(other *WebSocketClient) bool { return other == c }, WebSocketMessage{
+		Type:      "subscribed",
+		Timestamp: time.Now(),
+		Data:      map[string]interface{}{"types": subscribed},
+	})
+}
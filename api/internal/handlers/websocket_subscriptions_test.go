@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebSocketClient_Subscribe(t *testing.T) {
+	client := &WebSocketClient{ID: "c1"}
+	assert.True(t, client.wants("node.health"), "clients receive everything by default")
+
+	subscribed := client.subscribe([]string{"security.alert", " webhook.delivery ", ""})
+	assert.ElementsMatch(t, []string{"security.alert", "webhook.delivery"}, subscribed)
+	assert.True(t, client.wants("security.alert"))
+	assert.True(t, client.wants("webhook.delivery"))
+	assert.False(t, client.wants("node.health"))
+	assert.True(t, client.wants("hello"), "control messages are always delivered")
+
+	// An empty list restores receive-all
+	assert.Empty(t, client.subscribe(nil))
+	assert.True(t, client.wants("node.health"))
+}
+
+func TestWebSocketHub_SubscriptionFiltering(t *testing.T) {
+	hub := newWebSocketHub()
+	go hub.Run()
+
+	all := &WebSocketClient{ID: "all", UserID: "user1", Send: make(chan WebSocketMessage, 16), Hub: hub}
+	alerts := &WebSocketClient{ID: "alerts", UserID: "user1", Send: make(chan WebSocketMessage, 16), Hub: hub}
+	hub.Register(all)
+	hub.Register(alerts)
+
+	alerts.handleClientMessage([]byte(`{"action":"subscribe","types":["security.alert"]}`))
+	select {
+	case msg := <-alerts.Send:
+		assert.Equal(t, "subscribed", msg.Type)
+		assert.Equal(t, []string{"security.alert"}, msg.Data["types"])
+	case <-time.After(time.Second):
+		t.Fatal("subscribe was not answered")
+	}
+
+	hub.BroadcastToAll(WebSocketMessage{Type: "node.health", Timestamp: time.Now()})
+	hub.BroadcastToUser("user1", WebSocketMessage{Type: "webhook.delivery", Timestamp: time.Now()})
+	hub.BroadcastToAll(WebSocketMessage{Type: "security.alert", Timestamp: time.Now()})
+
+	var received []string
+	for len(received) < 3 {
+		select {
+		case msg := <-all.Send:
+			received = append(received, msg.Type)
+		case <-time.After(time.Second):
+			t.Fatalf("unsubscribed client got only %v", received)
+		}
+	}
+	assert.ElementsMatch(t, []string{"node.health", "webhook.delivery", "security.alert"}, received)
+
+	select {
+	case msg := <-alerts.Send:
+		assert.Equal(t, "security.alert", msg.Type)
+	case <-time.After(time.Second):
+		t.Fatal("subscribed client did not receive its type")
+	}
+	assert.Empty(t, alerts.Send, "subscribed client only receives its types")
+}
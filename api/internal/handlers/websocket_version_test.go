@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebSocketClient_NegotiateVersion(t *testing.T) {
+	client := &WebSocketClient{Hub: newWebSocketHub()}
+
+	assert.Equal(t, 1, client.negotiateVersion(requestedVersion("")), "clients that don't ask get the legacy envelope")
+	assert.Equal(t, 1, client.negotiateVersion(requestedVersion("two")))
+	assert.Equal(t, 2, client.negotiateVersion(requestedVersion("2")))
+	assert.Equal(t, WebSocketProtocolVersion, client.negotiateVersion(99), "newer clients are downgraded")
+
+	// A server pinned to the legacy envelope downgrades everyone
+	client.Hub.MaxVersion = 1
+	assert.Equal(t, 1, client.negotiateVersion(2))
+}
+
+func TestWebSocketClient_EncodeVersion(t *testing.T) {
+	client := &WebSocketClient{Hub: newWebSocketHub()}
+	message := WebSocketMessage{Type: "security.alert", Timestamp: time.Now(), Data: map[string]interface{}{"severity": "high"}}
+
+	client.negotiateVersion(1)
+	data, err := client.encode(message)
+	require.NoError(t, err)
+	var legacy map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &legacy))
+	assert.NotContains(t, legacy, "version")
+	assert.Equal(t, "security.alert", legacy["type"])
+
+	client.negotiateVersion(2)
+	data, err = client.encode(message)
+	require.NoError(t, err)
+	var versioned WebSocketMessage
+	require.NoError(t, json.Unmarshal(data, &versioned))
+	assert.Equal(t, 2, versioned.Version)
+	assert.Equal(t, "high", versioned.Data["severity"])
+
+	// The shared message itself is left alone
+	assert.Zero(t, message.Version)
+}
+
+func TestWebSocketMaxVersionFromEnv(t *testing.T) {
+	t.Setenv("WEBSOCKET_MAX_PROTOCOL_VERSION", "")
+	assert.Equal(t, WebSocketProtocolVersion, webSocketMaxVersionFromEnv())
+
+	t.Setenv("WEBSOCKET_MAX_PROTOCOL_VERSION", "1")
+	assert.Equal(t, 1, webSocketMaxVersionFromEnv())
+
+	t.Setenv("WEBSOCKET_MAX_PROTOCOL_VERSION", "7")
+	assert.Equal(t, WebSocketProtocolVersion, webSocketMaxVersionFromEnv())
+}
@@ -86,6 +86,7 @@ package k8s
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -101,6 +102,8 @@ import (
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsclientset "k8s.io/metrics/pkg/client/clientset/versioned"
 )
 
 // Session represents a StreamSpace Session CRD
@@ -118,7 +121,17 @@ type Session struct {
 	IdleTimeout        string
 	MaxSessionDuration string
 	Tags               []string
-	Status             SessionStatus
+	// Extra labels for the Session resource (set by plugin before-hooks)
+	Labels map[string]string
+	// Values for the template's parameters
+	Parameters map[string]string
+	// Scheduling priority class: Low, Normal or High (empty = template default)
+	Priority string
+	// Node the controller keeps the session's pod on (debugging aid)
+	PinToNode string
+	// Controller ID all of the session's commands are routed to
+	PinToAgent string
+	Status     SessionStatus
 	CreatedAt          time.Time
 }
 
@@ -133,6 +146,34 @@ type SessionStatus struct {
 		CPU    string
 	}
 	Conditions []metav1.Condition
+	// Effective priority class the controller scheduled the session with
+	Priority string
+	// Why the controller last hibernated the session (nil if it hasn't)
+	Hibernation *HibernationStatus
+	// Clipboard, audio and file transfer settings the session runs with
+	// (nil from controllers that don't report them)
+	StreamingFeatures *StreamingFeatures
+}
+
+// HibernationStatus explains an automatic hibernation: the standard idle
+// timeout (IdleTimeout) or one shortened for cost (CostIdleTimeout).
+type HibernationStatus struct {
+	Reason      string `json:"reason"`
+	IdleTimeout string `json:"idleTimeout"`
+	HourlyCost  string `json:"hourlyCost,omitempty"`
+	Message     string `json:"message,omitempty"`
+	Time        string `json:"time,omitempty"`
+}
+
+// StreamingFeatures are the streaming features of a session after security
+// policy. Restricted means clipboard sync and file transfer were disabled
+// because the template is untrusted.
+type StreamingFeatures struct {
+	Clipboard     bool   `json:"clipboard"`
+	Audio         bool   `json:"audio"`
+	FileTransfer  bool   `json:"fileTransfer"`
+	MaxResolution string `json:"maxResolution,omitempty"`
+	Restricted    bool   `json:"restricted,omitempty"`
 }
 
 // Template represents a StreamSpace Template CRD
@@ -162,7 +203,66 @@ type Template struct {
 	Tags         []string
 	Featured     bool // Whether template is featured in catalog
 	UsageCount   int  // Number of times template has been used
-	CreatedAt    time.Time
+	// Session lifecycle defaults applied when a session leaves them unset
+	DefaultIdleTimeout        string
+	DefaultMaxSessionDuration string
+	// Container ports users may tunnel to via the port-forward endpoint
+	ForwardablePorts []int32
+	// Platforms the template can run on (empty = all)
+	SupportedBackends []string
+	// Number of ready pods kept for fast launches (0 = no warm pool)
+	WarmPoolSize int32
+	// Whether the image is pre-pulled onto every node
+	PrePull bool
+	// Container args; may reference parameters with ${name}
+	Args []string
+	// Values sessions may supply at launch (see ValidateParameters)
+	Parameters []TemplateParameter
+	// Priority class for sessions that don't request one (empty = Normal)
+	DefaultPriority string
+	// Shortens the idle timeout of expensive sessions (nil = controller default)
+	HibernationPolicy *HibernationPolicy
+	// Caps the concurrent sessions of the template per user (nil = no cap)
+	UserSessionLimit *UserSessionLimit
+	// Whether the template opts in to running as root, privileged or with
+	// extra capabilities (spec.securityContext.allowPrivileged)
+	Privileged bool
+	CreatedAt  time.Time
+}
+
+// HibernationPolicy scales a session's idle timeout down when its hourly
+// cost exceeds ReferenceHourlyCost, but not below MinIdleTimeout.
+type HibernationPolicy struct {
+	ReferenceHourlyCost string `json:"referenceHourlyCost"`
+	MinIdleTimeout      string `json:"minIdleTimeout,omitempty"`
+}
+
+// What happens to a launch beyond a template's UserSessionLimit.
+const (
+	SessionLimitReject = "Reject"
+	SessionLimitQueue  = "Queue"
+)
+
+// UserSessionLimit caps how many sessions of a template each user may have
+// (e.g. one for a licensed application with per-seat limits). Launches
+// beyond MaxConcurrent are rejected, or queued when WhenFull is Queue.
+type UserSessionLimit struct {
+	MaxConcurrent int32  `json:"maxConcurrent"`
+	WhenFull      string `json:"whenFull,omitempty"`
+}
+
+// Queues reports whether launches beyond the limit wait for a slot.
+func (l *UserSessionLimit) Queues() bool {
+	return l != nil && l.WhenFull == SessionLimitQueue
+}
+
+// TemplateParameter declares a value supplied when a session is launched
+type TemplateParameter struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"` // string (default), number, boolean
+	Default     string `json:"default,omitempty"`
+	Required    bool   `json:"required,omitempty"`
+	Description string `json:"description,omitempty"`
 }
 
 // VNCConfig represents VNC configuration for desktop apps
@@ -177,6 +277,9 @@ type WebAppConfig struct {
 	Enabled bool
 	Port    int32
 	Path    string
+	// Path prefix the app serves from; with path-based session routing the
+	// controller rewrites /sessions/{name}/ to it (empty = "/")
+	BasePath string
 }
 
 // ApplicationInstall represents a request to install an application
@@ -320,6 +423,14 @@ func (c *Client) CreateSession(ctx context.Context, session *Session) (*Session,
 	// Add optional fields
 	spec := obj.Object["spec"].(map[string]interface{})
 
+	if len(session.Labels) > 0 {
+		labels := make(map[string]interface{}, len(session.Labels))
+		for k, v := range session.Labels {
+			labels[k] = v
+		}
+		obj.Object["metadata"].(map[string]interface{})["labels"] = labels
+	}
+
 	if session.Resources.Memory != "" || session.Resources.CPU != "" {
 		resources := make(map[string]interface{})
 		if session.Resources.Memory != "" {
@@ -343,6 +454,26 @@ func (c *Client) CreateSession(ctx context.Context, session *Session) (*Session,
 		spec["tags"] = session.Tags
 	}
 
+	if session.Priority != "" {
+		spec["priority"] = session.Priority
+	}
+
+	if session.PinToNode != "" {
+		spec["pinToNode"] = session.PinToNode
+	}
+
+	if session.PinToAgent != "" {
+		spec["pinToAgent"] = session.PinToAgent
+	}
+
+	if len(session.Parameters) > 0 {
+		params := make(map[string]interface{}, len(session.Parameters))
+		for name, value := range session.Parameters {
+			params[name] = value
+		}
+		spec["parameters"] = params
+	}
+
 	result, err := c.dynamicClient.Resource(sessionGVR).Namespace(session.Namespace).Create(ctx, obj, metav1.CreateOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
@@ -424,6 +555,89 @@ func (c *Client) UpdateSessionState(ctx context.Context, namespace, name, state
 	return parseSession(result)
 }
 
+// UpdateSessionResources replaces a Session's resource requests and limits.
+//
+// The controller applies the change the next time it creates or wakes the
+// session's pod; a running pod keeps its current resources.
+func (c *Client) UpdateSessionResources(ctx context.Context, namespace, name string, requests, limits map[string]string) (*Session, error) {
+	obj, err := c.dynamicClient.Resource(sessionGVR).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	spec, ok := obj.Object["spec"].(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid session spec")
+	}
+
+	resources := make(map[string]interface{})
+	if len(requests) > 0 {
+		resources["requests"] = stringMapToInterface(requests)
+	}
+	if len(limits) > 0 {
+		resources["limits"] = stringMapToInterface(limits)
+	}
+	spec["resources"] = resources
+
+	result, err := c.dynamicClient.Resource(sessionGVR).Namespace(namespace).Update(ctx, obj, metav1.UpdateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update session resources: %w", err)
+	}
+
+	return parseSession(result)
+}
+
+// ActiveConnectionsAnnotation is set on a Session to its number of open
+// connections, so the controller can let a hibernating session's
+// connections drain before scaling it down.
+const ActiveConnectionsAnnotation = "stream.space/active-connections"
+
+// SetSessionActiveConnections records a Session's open connection count in
+// ActiveConnectionsAnnotation.
+func (c *Client) SetSessionActiveConnections(ctx context.Context, namespace, name string, count int) error {
+	patch := fmt.Sprintf(`{"metadata":{"annotations":{%q:"%d"}}}`, ActiveConnectionsAnnotation, count)
+	_, err := c.dynamicClient.Resource(sessionGVR).Namespace(namespace).Patch(ctx, name, types.MergePatchType, []byte(patch), metav1.PatchOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to update session connections: %w", err)
+	}
+	return nil
+}
+
+// SetSessionPin pins a Session to a node and/or controller, or unpins it
+// where the value is empty (see SessionSpec.PinToNode and PinToAgent).
+func (c *Client) SetSessionPin(ctx context.Context, namespace, name, node, agent string) (*Session, error) {
+	// null removes the field in a merge patch
+	pin := func(value string) interface{} {
+		if value == "" {
+			return nil
+		}
+		return value
+	}
+	patch, err := json.Marshal(map[string]interface{}{
+		"spec": map[string]interface{}{
+			"pinToNode":  pin(node),
+			"pinToAgent": pin(agent),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := c.dynamicClient.Resource(sessionGVR).Namespace(namespace).Patch(ctx, name, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to pin session: %w", err)
+	}
+	return parseSession(result)
+}
+
+func stringMapToInterface(m map[string]string) map[string]interface{} {
+	out := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
+
 // UpdateSession updates a Session resource
 func (c *Client) UpdateSession(ctx context.Context, session *Session) error {
 	obj, err := c.dynamicClient.Resource(sessionGVR).Namespace(session.Namespace).Get(ctx, session.Name, metav1.GetOptions{})
@@ -462,6 +676,14 @@ func (c *Client) UpdateSession(ctx context.Context, session *Session) error {
 		spec["tags"] = session.Tags
 	}
 
+	if session.PinToNode != "" {
+		spec["pinToNode"] = session.PinToNode
+	}
+
+	if session.PinToAgent != "" {
+		spec["pinToAgent"] = session.PinToAgent
+	}
+
 	obj.Object["spec"] = spec
 
 	_, err = c.dynamicClient.Resource(sessionGVR).Namespace(session.Namespace).Update(ctx, obj, metav1.UpdateOptions{})
@@ -610,6 +832,27 @@ func parseSession(obj *unstructured.Unstructured) (*Session, error) {
 		}
 	}
 
+	if priority, ok := spec["priority"].(string); ok {
+		session.Priority = priority
+	}
+
+	if pinToNode, ok := spec["pinToNode"].(string); ok {
+		session.PinToNode = pinToNode
+	}
+
+	if pinToAgent, ok := spec["pinToAgent"].(string); ok {
+		session.PinToAgent = pinToAgent
+	}
+
+	if params, ok := spec["parameters"].(map[string]interface{}); ok {
+		session.Parameters = make(map[string]string, len(params))
+		for name, value := range params {
+			if valueStr, ok := value.(string); ok {
+				session.Parameters[name] = valueStr
+			}
+		}
+	}
+
 	// Parse status
 	if status, ok := obj.Object["status"].(map[string]interface{}); ok {
 		if phase, ok := status["phase"].(string); ok {
@@ -621,6 +864,9 @@ func parseSession(obj *unstructured.Unstructured) (*Session, error) {
 		if url, ok := status["url"].(string); ok {
 			session.Status.URL = url
 		}
+		if priority, ok := status["priority"].(string); ok {
+			session.Status.Priority = priority
+		}
 		if lastActivity, ok := status["lastActivity"].(string); ok {
 			t, err := time.Parse(time.RFC3339, lastActivity)
 			if err == nil {
@@ -635,11 +881,62 @@ func parseSession(obj *unstructured.Unstructured) (*Session, error) {
 				session.Status.ResourceUsage.CPU = cpu
 			}
 		}
+		if conditions, ok := status["conditions"].([]interface{}); ok {
+			session.Status.Conditions = parseConditions(conditions)
+		}
+		if hibernation, ok := status["hibernation"].(map[string]interface{}); ok {
+			session.Status.Hibernation = &HibernationStatus{}
+			session.Status.Hibernation.Reason, _ = hibernation["reason"].(string)
+			session.Status.Hibernation.IdleTimeout, _ = hibernation["idleTimeout"].(string)
+			session.Status.Hibernation.HourlyCost, _ = hibernation["hourlyCost"].(string)
+			session.Status.Hibernation.Message, _ = hibernation["message"].(string)
+			session.Status.Hibernation.Time, _ = hibernation["time"].(string)
+		}
+		if features, ok := status["streamingFeatures"].(map[string]interface{}); ok {
+			session.Status.StreamingFeatures = &StreamingFeatures{}
+			session.Status.StreamingFeatures.Clipboard, _ = features["clipboard"].(bool)
+			session.Status.StreamingFeatures.Audio, _ = features["audio"].(bool)
+			session.Status.StreamingFeatures.FileTransfer, _ = features["fileTransfer"].(bool)
+			session.Status.StreamingFeatures.MaxResolution, _ = features["maxResolution"].(string)
+			session.Status.StreamingFeatures.Restricted, _ = features["restricted"].(bool)
+		}
 	}
 
 	return session, nil
 }
 
+// parseConditions converts status.conditions from an unstructured object.
+// Entries without a type are skipped.
+func parseConditions(items []interface{}) []metav1.Condition {
+	conditions := make([]metav1.Condition, 0, len(items))
+	for _, item := range items {
+		c, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		condition := metav1.Condition{}
+		condition.Type, _ = c["type"].(string)
+		if condition.Type == "" {
+			continue
+		}
+		if status, ok := c["status"].(string); ok {
+			condition.Status = metav1.ConditionStatus(status)
+		}
+		condition.Reason, _ = c["reason"].(string)
+		condition.Message, _ = c["message"].(string)
+		if generation, ok := c["observedGeneration"].(int64); ok {
+			condition.ObservedGeneration = generation
+		}
+		if ts, ok := c["lastTransitionTime"].(string); ok {
+			if t, err := time.Parse(time.RFC3339, ts); err == nil {
+				condition.LastTransitionTime = metav1.NewTime(t)
+			}
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions
+}
+
 // ============================================================================
 // Template Operations
 // ============================================================================
@@ -693,6 +990,99 @@ func (c *Client) CreateTemplate(ctx context.Context, template *Template) (*Templ
 		spec["capabilities"] = template.Capabilities
 	}
 
+	if template.DefaultIdleTimeout != "" {
+		spec["defaultIdleTimeout"] = template.DefaultIdleTimeout
+	}
+
+	if template.DefaultMaxSessionDuration != "" {
+		spec["defaultMaxSessionDuration"] = template.DefaultMaxSessionDuration
+	}
+
+	if len(template.SupportedBackends) > 0 {
+		spec["supportedBackends"] = template.SupportedBackends
+	}
+
+	if template.PrePull {
+		spec["prePull"] = true
+	}
+
+	if template.WarmPoolSize > 0 {
+		spec["warmPoolSize"] = int64(template.WarmPoolSize)
+	}
+
+	if len(template.ForwardablePorts) > 0 {
+		ports := make([]interface{}, 0, len(template.ForwardablePorts))
+		for _, port := range template.ForwardablePorts {
+			ports = append(ports, int64(port))
+		}
+		spec["forwardablePorts"] = ports
+	}
+
+	if len(template.Args) > 0 {
+		spec["args"] = template.Args
+	}
+
+	if template.DefaultPriority != "" {
+		spec["defaultPriority"] = template.DefaultPriority
+	}
+
+	if template.HibernationPolicy != nil {
+		policy := map[string]interface{}{
+			"referenceHourlyCost": template.HibernationPolicy.ReferenceHourlyCost,
+		}
+		if template.HibernationPolicy.MinIdleTimeout != "" {
+			policy["minIdleTimeout"] = template.HibernationPolicy.MinIdleTimeout
+		}
+		spec["hibernationPolicy"] = policy
+	}
+
+	if template.UserSessionLimit != nil {
+		limit := map[string]interface{}{
+			"maxConcurrent": int64(template.UserSessionLimit.MaxConcurrent),
+		}
+		if template.UserSessionLimit.WhenFull != "" {
+			limit["whenFull"] = template.UserSessionLimit.WhenFull
+		}
+		spec["userSessionLimit"] = limit
+	}
+
+	if len(template.Parameters) > 0 {
+		params := make([]interface{}, 0, len(template.Parameters))
+		for _, param := range template.Parameters {
+			p := map[string]interface{}{"name": param.Name}
+			if param.Type != "" {
+				p["type"] = param.Type
+			}
+			if param.Default != "" {
+				p["default"] = param.Default
+			}
+			if param.Required {
+				p["required"] = true
+			}
+			if param.Description != "" {
+				p["description"] = param.Description
+			}
+			params = append(params, p)
+		}
+		spec["parameters"] = params
+	}
+
+	if template.WebApp != nil {
+		webapp := map[string]interface{}{
+			"enabled": template.WebApp.Enabled,
+		}
+		if template.WebApp.Port != 0 {
+			webapp["port"] = int64(template.WebApp.Port)
+		}
+		if template.WebApp.Path != "" {
+			webapp["path"] = template.WebApp.Path
+		}
+		if template.WebApp.BasePath != "" {
+			webapp["basePath"] = template.WebApp.BasePath
+		}
+		spec["webapp"] = webapp
+	}
+
 	result, err := c.dynamicClient.Resource(templateGVR).Namespace(template.Namespace).Create(ctx, obj, metav1.CreateOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create template: %w", err)
@@ -843,6 +1233,116 @@ func parseTemplate(obj *unstructured.Unstructured) (*Template, error) {
 		template.UsageCount = int(usageCount)
 	}
 
+	if idleTimeout, ok := spec["defaultIdleTimeout"].(string); ok {
+		template.DefaultIdleTimeout = idleTimeout
+	}
+
+	if maxDuration, ok := spec["defaultMaxSessionDuration"].(string); ok {
+		template.DefaultMaxSessionDuration = maxDuration
+	}
+
+	if backends, ok := spec["supportedBackends"].([]interface{}); ok {
+		template.SupportedBackends = make([]string, 0, len(backends))
+		for _, backend := range backends {
+			if backendStr, ok := backend.(string); ok {
+				template.SupportedBackends = append(template.SupportedBackends, backendStr)
+			}
+		}
+	}
+
+	if prePull, ok := spec["prePull"].(bool); ok {
+		template.PrePull = prePull
+	}
+
+	switch size := spec["warmPoolSize"].(type) {
+	case int64:
+		template.WarmPoolSize = int32(size)
+	case float64:
+		template.WarmPoolSize = int32(size)
+	}
+
+	if ports, ok := spec["forwardablePorts"].([]interface{}); ok {
+		template.ForwardablePorts = make([]int32, 0, len(ports))
+		for _, port := range ports {
+			switch p := port.(type) {
+			case int64:
+				template.ForwardablePorts = append(template.ForwardablePorts, int32(p))
+			case float64:
+				template.ForwardablePorts = append(template.ForwardablePorts, int32(p))
+			}
+		}
+	}
+
+	if priority, ok := spec["defaultPriority"].(string); ok {
+		template.DefaultPriority = priority
+	}
+
+	if policy, ok := spec["hibernationPolicy"].(map[string]interface{}); ok {
+		template.HibernationPolicy = &HibernationPolicy{}
+		template.HibernationPolicy.ReferenceHourlyCost, _ = policy["referenceHourlyCost"].(string)
+		template.HibernationPolicy.MinIdleTimeout, _ = policy["minIdleTimeout"].(string)
+	}
+
+	if limit, ok := spec["userSessionLimit"].(map[string]interface{}); ok {
+		template.UserSessionLimit = &UserSessionLimit{}
+		switch n := limit["maxConcurrent"].(type) {
+		case int64:
+			template.UserSessionLimit.MaxConcurrent = int32(n)
+		case float64:
+			template.UserSessionLimit.MaxConcurrent = int32(n)
+		}
+		template.UserSessionLimit.WhenFull, _ = limit["whenFull"].(string)
+	}
+
+	if securityContext, ok := spec["securityContext"].(map[string]interface{}); ok {
+		template.Privileged, _ = securityContext["allowPrivileged"].(bool)
+	}
+
+	if args, ok := spec["args"].([]interface{}); ok {
+		template.Args = make([]string, 0, len(args))
+		for _, arg := range args {
+			if argStr, ok := arg.(string); ok {
+				template.Args = append(template.Args, argStr)
+			}
+		}
+	}
+
+	if params, ok := spec["parameters"].([]interface{}); ok {
+		template.Parameters = make([]TemplateParameter, 0, len(params))
+		for _, p := range params {
+			param, ok := p.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			var tp TemplateParameter
+			tp.Name, _ = param["name"].(string)
+			tp.Type, _ = param["type"].(string)
+			tp.Default, _ = param["default"].(string)
+			tp.Required, _ = param["required"].(bool)
+			tp.Description, _ = param["description"].(string)
+			template.Parameters = append(template.Parameters, tp)
+		}
+	}
+
+	if webapp, ok := spec["webapp"].(map[string]interface{}); ok {
+		template.WebApp = &WebAppConfig{}
+		if enabled, ok := webapp["enabled"].(bool); ok {
+			template.WebApp.Enabled = enabled
+		}
+		switch port := webapp["port"].(type) {
+		case int64:
+			template.WebApp.Port = int32(port)
+		case float64:
+			template.WebApp.Port = int32(port)
+		}
+		if path, ok := webapp["path"].(string); ok {
+			template.WebApp.Path = path
+		}
+		if basePath, ok := webapp["basePath"].(string); ok {
+			template.WebApp.BasePath = basePath
+		}
+	}
+
 	return template, nil
 }
 
@@ -1034,6 +1534,22 @@ func (c *Client) GetPods(ctx context.Context, namespace string) (*corev1.PodList
 	return pods, nil
 }
 
+// GetPodMetrics returns current pod usage from metrics-server for pods in a
+// namespace matching labelSelector
+func (c *Client) GetPodMetrics(ctx context.Context, namespace, labelSelector string) (*metricsv1beta1.PodMetricsList, error) {
+	metricsClient, err := metricsclientset.NewForConfig(c.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metrics client: %w", err)
+	}
+
+	podMetrics, err := metricsClient.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{LabelSelector: labelSelector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pod metrics: %w", err)
+	}
+
+	return podMetrics, nil
+}
+
 // GetServices returns services in a namespace
 func (c *Client) GetServices(ctx context.Context, namespace string) (*corev1.ServiceList, error) {
 	services, err := c.clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
@@ -6,6 +6,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic/fake"
@@ -372,6 +373,14 @@ func TestParseSession_WithStatus(t *testing.T) {
 				"phase":   "Running",
 				"podName": "session-pod-123",
 				"url":     "https://session.example.com",
+				"conditions": []interface{}{
+					map[string]interface{}{
+						"type":               "Ready",
+						"status":             "True",
+						"reason":             "PodReady",
+						"lastTransitionTime": "2024-01-01T00:01:00Z",
+					},
+				},
 			},
 		},
 	}
@@ -391,4 +400,8 @@ func TestParseSession_WithStatus(t *testing.T) {
 	assert.Equal(t, "Running", session.Status.Phase)
 	assert.Equal(t, "session-pod-123", session.Status.PodName)
 	assert.Equal(t, "https://session.example.com", session.Status.URL)
+	require.Len(t, session.Status.Conditions, 1)
+	assert.Equal(t, "Ready", session.Status.Conditions[0].Type)
+	assert.Equal(t, metav1.ConditionTrue, session.Status.Conditions[0].Status)
+	assert.Equal(t, "PodReady", session.Status.Conditions[0].Reason)
 }
@@ -0,0 +1,171 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+const (
+	// HomeMountPath is where home archive helper pods mount the home PVC.
+	HomeMountPath = "/config"
+
+	// homeHelperDeadline bounds the lifetime of a helper pod, so one leaked
+	// by a crashed API replica is cleaned up by Kubernetes.
+	homeHelperDeadline = int64(3600)
+
+	// homeHelperStartTimeout bounds how long we wait for a helper to run.
+	homeHelperStartTimeout = 2 * time.Minute
+)
+
+// HomePVCName returns the name of a user's home PVC (created by the
+// controller for sessions with persistentHome).
+func HomePVCName(user string) string {
+	return fmt.Sprintf("home-%s", user)
+}
+
+// GetUserHomePVCs returns a user's home PVCs: the shared home-{user} and
+// the homes of the user's forked sessions.
+func (c *Client) GetUserHomePVCs(ctx context.Context, namespace, user string) ([]corev1.PersistentVolumeClaim, error) {
+	pvcs, err := c.clientset.CoreV1().PersistentVolumeClaims(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("app=streamspace-user-home,user=%s", user),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list home volumes of user %s: %w", user, err)
+	}
+	return pvcs.Items, nil
+}
+
+// StartHomeVolumeHelper starts a short-lived pod mounting a user's home PVC
+// and waits until it is running. Commands are run in it with ExecInPod; the
+// caller must delete it with DeleteHomeVolumeHelper.
+//
+// The helper mounts the PVC read-only unless writable is set. nodeName pins
+// it next to a running session pod, which a ReadWriteOnce PVC requires.
+func (c *Client) StartHomeVolumeHelper(ctx context.Context, namespace, user, image, nodeName string, writable bool) (*corev1.Pod, error) {
+	pvcName := HomePVCName(user)
+	if _, err := c.clientset.CoreV1().PersistentVolumeClaims(namespace).Get(ctx, pvcName, metav1.GetOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to get home volume %s: %w", pvcName, err)
+	}
+
+	deadline := homeHelperDeadline
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("home-archive-%s-", user),
+			Namespace:    namespace,
+			Labels: map[string]string{
+				"app":  "streamspace-home-archive",
+				"user": user,
+			},
+		},
+		Spec: corev1.PodSpec{
+			RestartPolicy:         corev1.RestartPolicyNever,
+			ActiveDeadlineSeconds: &deadline,
+			NodeName:              nodeName,
+			Containers: []corev1.Container{{
+				Name:    "archive",
+				Image:   image,
+				Command: []string{"sleep", fmt.Sprintf("%d", homeHelperDeadline)},
+				VolumeMounts: []corev1.VolumeMount{{
+					Name:      "home",
+					MountPath: HomeMountPath,
+					ReadOnly:  !writable,
+				}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "home",
+				VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+						ClaimName: pvcName,
+						ReadOnly:  !writable,
+					},
+				},
+			}},
+		},
+	}
+
+	created, err := c.clientset.CoreV1().Pods(namespace).Create(ctx, pod, metav1.CreateOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create home archive pod: %w", err)
+	}
+
+	err = wait.PollUntilContextTimeout(ctx, time.Second, homeHelperStartTimeout, true, func(ctx context.Context) (bool, error) {
+		current, err := c.clientset.CoreV1().Pods(namespace).Get(ctx, created.Name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		switch current.Status.Phase {
+		case corev1.PodRunning:
+			return true, nil
+		case corev1.PodFailed, corev1.PodSucceeded:
+			return false, fmt.Errorf("home archive pod %s exited (%s)", created.Name, current.Status.Phase)
+		}
+		return false, nil
+	})
+	if err != nil {
+		c.DeleteHomeVolumeHelper(namespace, created.Name)
+		return nil, fmt.Errorf("home archive pod did not start: %w", err)
+	}
+	return created, nil
+}
+
+// DeleteHomeVolumeHelper deletes a helper pod started by
+// StartHomeVolumeHelper. It is safe to call for a pod that is already gone.
+func (c *Client) DeleteHomeVolumeHelper(namespace, name string) error {
+	// Use a fresh context: cleanup must happen even after the request ends
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	grace := int64(0)
+	err := c.clientset.CoreV1().Pods(namespace).Delete(ctx, name, metav1.DeleteOptions{GracePeriodSeconds: &grace})
+	if err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("failed to delete home archive pod %s: %w", name, err)
+	}
+	return nil
+}
+
+// ExecInPod runs a command in a pod's first container, streaming stdin and
+// stdout (either may be nil). Output on stderr is returned in the error if
+// the command fails.
+func (c *Client) ExecInPod(ctx context.Context, namespace, podName string, command []string, stdin io.Reader, stdout io.Writer) error {
+	req := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(podName).
+		SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Command: command,
+			Stdin:   stdin != nil,
+			Stdout:  stdout != nil,
+			Stderr:  true,
+		}, scheme.ParameterCodec)
+
+	executor, err := remotecommand.NewSPDYExecutor(c.config, http.MethodPost, req.URL())
+	if err != nil {
+		return fmt.Errorf("failed to create exec for pod %s: %w", podName, err)
+	}
+
+	var stderr strings.Builder
+	err = executor.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: &stderr,
+	})
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package k8s
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// parameterRef matches a ${name} parameter reference.
+var parameterRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// ValidateParameters checks parameter values supplied at launch against the
+// template's parameter schema and returns the value of every declared
+// parameter, with defaults filled in.
+//
+// Returns an error for a missing required parameter, a name the template
+// doesn't declare, or a value that doesn't match the parameter's type. The
+// controller applies the same rules to Sessions created without the API.
+func (t *Template) ValidateParameters(values map[string]string) (map[string]string, error) {
+	declared := make(map[string]bool, len(t.Parameters))
+	resolved := make(map[string]string, len(t.Parameters))
+	for _, param := range t.Parameters {
+		declared[param.Name] = true
+		value, ok := values[param.Name]
+		if !ok {
+			if param.Required {
+				return nil, fmt.Errorf("parameter %q is required", param.Name)
+			}
+			resolved[param.Name] = param.Default
+			continue
+		}
+		if err := param.check(value); err != nil {
+			return nil, err
+		}
+		resolved[param.Name] = value
+	}
+
+	for name := range values {
+		if !declared[name] {
+			return nil, fmt.Errorf("template %s has no parameter %q", t.Name, name)
+		}
+	}
+	return resolved, nil
+}
+
+// check reports whether value is valid for the parameter's type.
+func (p TemplateParameter) check(value string) error {
+	switch p.Type {
+	case "", "string":
+	case "number":
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("parameter %q must be a number, got %q", p.Name, value)
+		}
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("parameter %q must be a boolean, got %q", p.Name, value)
+		}
+	default:
+		return fmt.Errorf("parameter %q has unknown type %q", p.Name, p.Type)
+	}
+	return nil
+}
+
+// SubstituteParameters replaces ${name} references to resolved parameters.
+// References to undeclared names are left as is.
+func SubstituteParameters(s string, values map[string]string) string {
+	return parameterRef.ReplaceAllStringFunc(s, func(ref string) string {
+		if value, ok := values[ref[2:len(ref)-1]]; ok {
+			return value
+		}
+		return ref
+	})
+}
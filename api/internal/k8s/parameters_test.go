@@ -0,0 +1,79 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateParameters(t *testing.T) {
+	template := &Template{
+		Name: "vscode",
+		Parameters: []TemplateParameter{
+			{Name: "project", Required: true},
+			{Name: "workers", Type: "number", Default: "2"},
+			{Name: "debug", Type: "boolean", Default: "false"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		values  map[string]string
+		want    map[string]string
+		wantErr string
+	}{
+		{
+			name:   "defaults filled in",
+			values: map[string]string{"project": "alpha"},
+			want:   map[string]string{"project": "alpha", "workers": "2", "debug": "false"},
+		},
+		{
+			name:   "supplied values",
+			values: map[string]string{"project": "alpha", "workers": "8", "debug": "true"},
+			want:   map[string]string{"project": "alpha", "workers": "8", "debug": "true"},
+		},
+		{
+			name:    "missing required",
+			values:  map[string]string{"workers": "8"},
+			wantErr: `parameter "project" is required`,
+		},
+		{
+			name:    "unknown parameter",
+			values:  map[string]string{"project": "alpha", "color": "blue"},
+			wantErr: `template vscode has no parameter "color"`,
+		},
+		{
+			name:    "not a number",
+			values:  map[string]string{"project": "alpha", "workers": "many"},
+			wantErr: `parameter "workers" must be a number`,
+		},
+		{
+			name:    "not a boolean",
+			values:  map[string]string{"project": "alpha", "debug": "maybe"},
+			wantErr: `parameter "debug" must be a boolean`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := template.ValidateParameters(tt.values)
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSubstituteParameters(t *testing.T) {
+	values := map[string]string{"project": "alpha", "debug": "true"}
+
+	assert.Equal(t, "/workspace/alpha", SubstituteParameters("/workspace/${project}", values))
+	assert.Equal(t, "--debug=true --project alpha", SubstituteParameters("--debug=${debug} --project ${project}", values))
+	// Undeclared references and plain $VARS are left for the container
+	assert.Equal(t, "${other} $HOME", SubstituteParameters("${other} $HOME", values))
+}
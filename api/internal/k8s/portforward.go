@@ -0,0 +1,122 @@
+package k8s
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/transport/spdy"
+)
+
+// portForwardProtocolV1Name is the subprotocol used by the kubelet's
+// port-forward endpoint (same value as kubectl port-forward).
+const portForwardProtocolV1Name = "portforward.k8s.io"
+
+// FindSessionPod returns the running pod backing a session.
+//
+// Session pods are labeled with session={name} by the controller. Returns an
+// error if no running pod exists (e.g. the session is hibernated or starting).
+func (c *Client) FindSessionPod(ctx context.Context, namespace, sessionName string) (*corev1.Pod, error) {
+	pods, err := c.clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("session=%s", sessionName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods for session %s: %w", sessionName, err)
+	}
+
+	for i := range pods.Items {
+		pod := &pods.Items[i]
+		if pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil {
+			return pod, nil
+		}
+	}
+
+	return nil, fmt.Errorf("no running pod for session %s", sessionName)
+}
+
+// PortForward opens a TCP stream to a port inside a pod through the
+// Kubernetes port-forward API.
+//
+// This is the same mechanism kubectl port-forward uses: the API server
+// upgrades the connection to SPDY and the kubelet relays the stream to the
+// pod's network namespace, so the port doesn't need to be exposed by a
+// Service. The caller must Close the returned stream.
+func (c *Client) PortForward(ctx context.Context, namespace, podName string, port int32) (io.ReadWriteCloser, error) {
+	transport, upgrader, err := spdy.RoundTripperFor(c.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create port-forward transport: %w", err)
+	}
+
+	req := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Namespace(namespace).
+		Name(podName).
+		SubResource("portforward")
+
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+	streamConn, _, err := dialer.Dial(portForwardProtocolV1Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial port-forward for pod %s: %w", podName, err)
+	}
+
+	// Each forwarded connection needs an error stream and a data stream
+	// sharing the same port and request ID
+	headers := http.Header{}
+	headers.Set(corev1.StreamType, corev1.StreamTypeError)
+	headers.Set(corev1.PortHeader, strconv.Itoa(int(port)))
+	headers.Set(corev1.PortForwardRequestIDHeader, "0")
+
+	errorStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		streamConn.Close()
+		return nil, fmt.Errorf("failed to create port-forward error stream: %w", err)
+	}
+	// We only read from the error stream
+	errorStream.Close()
+
+	headers.Set(corev1.StreamType, corev1.StreamTypeData)
+	dataStream, err := streamConn.CreateStream(headers)
+	if err != nil {
+		streamConn.Close()
+		return nil, fmt.Errorf("failed to create port-forward data stream: %w", err)
+	}
+
+	go func() {
+		message, err := io.ReadAll(errorStream)
+		if err == nil && len(message) > 0 {
+			log.Printf("Port-forward to %s/%s:%d failed: %s", namespace, podName, port, string(message))
+			streamConn.Close()
+		}
+	}()
+
+	stream := &portForwardStream{Stream: dataStream, conn: streamConn}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			stream.Close()
+		case <-streamConn.CloseChan():
+		}
+	}()
+
+	return stream, nil
+}
+
+// portForwardStream is a data stream that closes its SPDY connection when
+// closed.
+type portForwardStream struct {
+	httpstream.Stream
+	conn httpstream.Connection
+}
+
+// Close resets the data stream and tears down the underlying connection.
+func (s *portForwardStream) Close() error {
+	s.Stream.Reset()
+	return s.conn.Close()
+}
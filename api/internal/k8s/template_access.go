@@ -0,0 +1,48 @@
+package k8s
+
+import "strings"
+
+// RestrictedTagPrefix marks a template tag that limits the template to a
+// group: "restricted:finance" means only members of the finance group may
+// see or launch it. A template with several such tags is open to members of
+// any of those groups; one without any is open to everyone.
+//
+// This works at the template layer, alongside the per-application group
+// access of installed applications.
+//
+// Privileged templates (see Template.Privileged) are only open to the
+// groups they are restricted to; without restricted tags only admins may
+// use them.
+const RestrictedTagPrefix = "restricted:"
+
+// RestrictedGroups returns the (lowercased) names of the groups the
+// template is restricted to. Empty means the template is unrestricted.
+func (t *Template) RestrictedGroups() []string {
+	var groups []string
+	for _, tag := range t.Tags {
+		if len(tag) <= len(RestrictedTagPrefix) || !strings.EqualFold(tag[:len(RestrictedTagPrefix)], RestrictedTagPrefix) {
+			continue
+		}
+		if group := strings.ToLower(strings.TrimSpace(tag[len(RestrictedTagPrefix):])); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// AccessibleTo reports whether a member of the named groups may see and
+// launch the template. Group names match case-insensitively.
+func (t *Template) AccessibleTo(groupNames []string) bool {
+	restricted := t.RestrictedGroups()
+	if len(restricted) == 0 {
+		return !t.Privileged
+	}
+	for _, name := range groupNames {
+		for _, group := range restricted {
+			if strings.EqualFold(name, group) {
+				return true
+			}
+		}
+	}
+	return false
+}
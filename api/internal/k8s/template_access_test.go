@@ -0,0 +1,29 @@
+package k8s
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplateRestrictedGroups(t *testing.T) {
+	open := &Template{Name: "firefox", Tags: []string{"browser", "restricted:"}}
+	assert.Empty(t, open.RestrictedGroups())
+	assert.True(t, open.AccessibleTo(nil))
+
+	finance := &Template{Name: "ledger", Tags: []string{"office", "restricted:Finance", "Restricted: audit "}}
+	assert.Equal(t, []string{"finance", "audit"}, finance.RestrictedGroups())
+	assert.True(t, finance.AccessibleTo([]string{"engineering", "FINANCE"}))
+	assert.True(t, finance.AccessibleTo([]string{"audit"}))
+	assert.False(t, finance.AccessibleTo([]string{"engineering"}))
+	assert.False(t, finance.AccessibleTo(nil))
+}
+
+func TestTemplatePrivilegedAccess(t *testing.T) {
+	docker := &Template{Name: "docker-in-docker", Privileged: true}
+	assert.False(t, docker.AccessibleTo([]string{"engineering"}), "unrestricted privileged templates are admin-only")
+
+	docker.Tags = []string{"restricted:platform"}
+	assert.True(t, docker.AccessibleTo([]string{"platform"}))
+	assert.False(t, docker.AccessibleTo([]string{"engineering"}))
+}
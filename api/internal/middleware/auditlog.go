@@ -160,7 +160,7 @@
 //	-- Failed login attempts
 //	SELECT * FROM audit_log
 //	WHERE resource_type = '/api/auth/login'
-//	  AND changes->>'status_code' = '401'
+//	  AND changes->'status_code'->>'new' = '401'
 //	  AND timestamp > NOW() - INTERVAL '1 hour';
 //
 //	-- Resource deletions
@@ -191,6 +191,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/audit"
 	"github.com/streamspace/streamspace/api/internal/db"
 )
 
@@ -505,7 +506,8 @@ func (a *AuditLogger) redactSensitiveData(data map[string]interface{}) map[strin
 //
 //  2. **JSONB column** (for full details):
 //     - changes: Contains method, path, status_code, duration_ms,
-//       request_body, response_body, error, metadata
+//       request_body, response_body, error, metadata, each stored as
+//       {"old": null, "new": value} (see package audit)
 //
 // # Why JSONB for Details?
 //
@@ -556,9 +558,9 @@ func (a *AuditLogger) redactSensitiveData(data map[string]interface{}) map[strin
 //	    action,
 //	    resource_type,
 //	    timestamp,
-//	    changes->>'status_code' as status_code,
-//	    changes->>'duration_ms' as duration_ms,
-//	    changes->>'error' as error
+//	    changes->'status_code'->>'new' as status_code,
+//	    changes->'duration_ms'->>'new' as duration_ms,
+//	    changes->'error'->>'new' as error
 //	FROM audit_log
 //	WHERE user_id = 'user-123'
 //	  AND timestamp > NOW() - INTERVAL '24 hours'
@@ -580,8 +582,9 @@ func (a *AuditLogger) logEvent(event *AuditEvent) error {
 		return nil
 	}
 
-	// Serialize full event details to JSONB
-	details, _ := json.Marshal(map[string]interface{}{
+	// Serialize full event details to JSONB as field-level changes. Request
+	// details have no previous state, so each is recorded as a new value.
+	details, _ := json.Marshal(audit.Values(map[string]interface{}{
 		"method":        event.Method,
 		"path":          event.Path,
 		"status_code":   event.StatusCode,
@@ -590,7 +593,7 @@ func (a *AuditLogger) logEvent(event *AuditEvent) error {
 		"response_body": event.ResponseBody,
 		"error":         event.Error,
 		"metadata":      event.Metadata,
-	})
+	}))
 
 	// Insert into audit_log table
 	query := `
@@ -392,7 +392,9 @@ func EnforceSessionCreation(c *gin.Context, requestedCPU, requestedMemory string
 
 	// Check quotas against user limits
 	// Returns detailed error if any quota is exceeded
-	return quotaEnforcer.CheckSessionCreation(c.Request.Context(), usernameStr, cpu, memory, requestedGPU, currentUsage)
+	// Per-template limits are checked where the template is resolved, and
+	// storage where the user's home volume is
+	return quotaEnforcer.CheckSessionCreation(c.Request.Context(), usernameStr, cpu, memory, requestedGPU, 0, quota.SessionTemplate{}, currentUsage)
 }
 
 // GetUserQuota returns a Gin handler that retrieves user quota information.
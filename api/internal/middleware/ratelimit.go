@@ -9,15 +9,9 @@
 // - Without rate limiting, codes can be brute forced in minutes
 // - With 5 attempts/minute limit, brute force takes ~160 days
 //
-// Current Implementation: In-Memory (Development/Single-Server)
-// - Fast: No network round-trips
-// - Simple: No external dependencies
-// - Limitations: Not distributed (doesn't work across multiple API servers)
-//
-// Production Recommendation: Redis-Backed Rate Limiting
-// - Distributed: Works across multiple API servers
-// - Persistent: Survives API server restarts
-// - Scalable: Handles millions of concurrent rate limit entries
+// Storage is pluggable (see ratelimit_store.go):
+// - In-Memory (default): fast, but per server and lost on restart
+// - Redis (RATE_LIMIT_STORE=redis): shared by all API servers, persistent
 //
 // Usage:
 //   // In handler
@@ -28,13 +22,16 @@
 package middleware
 
 import (
+	"log"
 	"sync"
 	"time"
 )
 
-// RateLimiter implements a simple in-memory sliding window rate limiter.
+// RateLimiter implements a sliding window rate limiter over a
+// RateLimiterStore, with a token bucket mode (CheckLimitTokenBucket) for
+// limits that allow bursts.
 //
-// Thread Safety: Uses sync.RWMutex for concurrent access protection.
+// Thread Safety: Safe for concurrent use; stores synchronize access.
 //
 // Algorithm: Sliding Window
 // - Records timestamp of each attempt
@@ -42,34 +39,62 @@ import (
 // - Counts remaining attempts
 // - Allows if count < maxAttempts
 //
-// Memory Management:
+// Memory Management (in-memory store):
 // - Automatic cleanup runs every 5 minutes
 // - Removes entries older than 10 minutes
 // - Prevents memory leaks from abandoned rate limits
 //
-// For production use with multiple API servers, replace with Redis-backed
-// implementation for distributed rate limiting.
+// Store errors fail open: the request is allowed and the error logged, so
+// a Redis outage doesn't lock every user out.
 type RateLimiter struct {
-	attempts map[string][]time.Time
-	mu       sync.RWMutex
+	store RateLimiterStore
+
+	// now is the clock; tests replace it to move through windows without
+	// sleeping.
+	now func() time.Time
+}
+
+// NewRateLimiter creates a rate limiter over store.
+func NewRateLimiter(store RateLimiterStore) *RateLimiter {
+	return &RateLimiter{store: store, now: time.Now}
+}
+
+// RateLimitStatus describes the current state of a rate limit key.
+//
+// ResetAt is when the oldest attempt in the window expires, i.e. when the
+// next request will be allowed if the key is limited. It is nil when there
+// are no attempts in the window.
+type RateLimitStatus struct {
+	Key         string     `json:"key"`
+	Attempts    int        `json:"attempts"`
+	MaxAttempts int        `json:"maxAttempts"`
+	Window      string     `json:"window"`
+	Limited     bool       `json:"limited"`
+	ResetAt     *time.Time `json:"resetAt,omitempty"`
 }
 
 var (
-	globalRateLimiter = &RateLimiter{
-		attempts: make(map[string][]time.Time),
-	}
-	cleanupOnce sync.Once
+	globalMemoryStore = NewMemoryRateLimiterStore()
+	globalRateLimiter = NewRateLimiter(globalMemoryStore)
+	cleanupOnce       sync.Once
 )
 
 // GetRateLimiter returns the singleton rate limiter instance
 func GetRateLimiter() *RateLimiter {
 	// Start cleanup goroutine once
 	cleanupOnce.Do(func() {
-		go globalRateLimiter.cleanup()
+		go cleanup(globalMemoryStore, globalRateLimiter.now)
 	})
 	return globalRateLimiter
 }
 
+// UseRateLimiterStore switches the singleton rate limiter to store. Call it
+// at startup, before requests are served; attempts recorded so far are not
+// carried over.
+func UseRateLimiterStore(store RateLimiterStore) {
+	globalRateLimiter.store = store
+}
+
 // CheckLimit checks if the rate limit has been exceeded using sliding window algorithm.
 //
 // This method is the core of the rate limiting system. It implements a sliding window
@@ -125,9 +150,9 @@ func GetRateLimiter() *RateLimiter {
 // # Thread Safety
 //
 // This method is thread-safe:
-//   - Uses write lock (rl.mu.Lock()) for exclusive access
 //   - Safe for concurrent calls from multiple goroutines
-//   - Lock held for entire operation (atomic check-and-increment)
+//   - The store checks and records atomically per key (a lock in memory,
+//     a Lua script in Redis)
 //
 // # Performance Characteristics
 //
@@ -175,9 +200,9 @@ func GetRateLimiter() *RateLimiter {
 //   - If all previous attempts are outside window, count=0
 //   - Request is allowed (like fresh start)
 //
-// **Concurrent requests**: First one to acquire lock wins
+// **Concurrent requests**: First one to reach the store wins
 //   - If 2 requests race to be the "Nth" attempt
-//   - Lock ensures only one is recorded as the Nth
+//   - The store's atomic check-and-record ensures only one is recorded as the Nth
 //   - Other is rejected as "N+1th"
 //
 // # Example Usage
@@ -230,112 +255,152 @@ func GetRateLimiter() *RateLimiter {
 //
 // # Known Limitations
 //
-//  1. **In-memory only**: Not distributed across multiple servers
+//  1. **In-memory store is per server**: Not distributed across multiple servers
 //     - Each API server has independent limits
 //     - Attackers can bypass by spreading across servers
-//     - Solution: Use Redis for distributed rate limiting
+//     - Solution: Use the Redis store (RATE_LIMIT_STORE=redis)
 //
-//  2. **Lost on restart**: Rate limit state lost when server restarts
+//  2. **In-memory store is lost on restart**: Rate limit state lost when server restarts
 //     - Attackers could force restart to reset limits
-//     - Solution: Persist to Redis or database
+//     - Solution: Use the Redis store
 //
 //  3. **Memory growth**: Without cleanup, memory usage unbounded
-//     - Solution: Automatic cleanup runs every 5 minutes (implemented)
+//     - Solution: Automatic cleanup runs every 5 minutes (in-memory store);
+//       Redis keys expire one window after the last attempt
 //
 //  4. **No burst allowance**: Sliding window is strict
 //     - Can't "save up" unused capacity for later burst
-//     - Solution: Implement token bucket algorithm instead
+//     - Solution: Use CheckLimitTokenBucket instead
 //
 // See also:
 //   - ResetLimit(): Clear rate limit for a key
 //   - GetAttempts(): Check current attempt count
+//   - Inspect(): Full state of a key (for debugging)
 func (rl *RateLimiter) CheckLimit(key string, maxAttempts int, window time.Duration) bool {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-
-	now := time.Now()
-
-	// Get existing attempts for this key
-	attempts, exists := rl.attempts[key]
-	if !exists {
-		attempts = []time.Time{}
-	}
-
-	// Filter out attempts outside the time window
-	validAttempts := []time.Time{}
-	for _, t := range attempts {
-		if now.Sub(t) < window {
-			validAttempts = append(validAttempts, t)
-		}
+	allowed, err := rl.store.Allow(key, RateLimit{MaxAttempts: maxAttempts, Window: window}, rl.now())
+	if err != nil {
+		log.Printf("Rate limit check failed, allowing request: %v", err)
+		return true
 	}
+	return allowed
+}
 
-	// Check if limit exceeded
-	if len(validAttempts) >= maxAttempts {
-		// Update with filtered attempts (don't record this request)
-		rl.attempts[key] = validAttempts
-		return false
+// CheckLimitTokenBucket checks key against a token bucket of capacity
+// tokens refilled at refillRate tokens per second, and takes a token if
+// there is one.
+//
+// Unlike CheckLimit's sliding window, a key that has been idle builds up
+// capacity again: a client may burst up to capacity requests at once,
+// while its sustained rate stays capped at refillRate. Use it where short
+// bursts are legitimate (API gateway traffic); keep CheckLimit for brute
+// force protection, where unused attempts mustn't be saved up.
+//
+// A key seen for the first time has a full bucket. Buckets are kept apart
+// from CheckLimit's attempts of the same key, don't show up in Inspect or
+// GetAttempts, and are cleared by ResetLimit. The in-memory store's
+// cleanup drops buckets that have refilled; Redis expires them.
+//
+// now is passed in rather than read from the limiter's clock so callers
+// can check a batch of requests against the same instant.
+//
+// Example: bursts of 20, 5 requests/second sustained
+//
+//	if !limiter.CheckLimitTokenBucket(fmt.Sprintf("user:%s:api", userID), 20, 5, time.Now()) {
+//	    c.JSON(429, gin.H{"error": "rate limit exceeded"})
+//	    return
+//	}
+func (rl *RateLimiter) CheckLimitTokenBucket(key string, capacity int, refillRate float64, now time.Time) bool {
+	allowed, err := rl.store.TakeToken(key, TokenBucket{Capacity: capacity, RefillRate: refillRate}, now)
+	if err != nil {
+		log.Printf("Rate limit check failed, allowing request: %v", err)
+		return true
 	}
-
-	// Record this attempt
-	validAttempts = append(validAttempts, now)
-	rl.attempts[key] = validAttempts
-
-	return true
+	return allowed
 }
 
 // ResetLimit clears all attempts for a given key
 func (rl *RateLimiter) ResetLimit(key string) {
-	rl.mu.Lock()
-	defer rl.mu.Unlock()
-	delete(rl.attempts, key)
+	if err := rl.store.Reset(key); err != nil {
+		log.Printf("Failed to reset rate limit: %v", err)
+	}
 }
 
 // GetAttempts returns the number of attempts within the window for a key
 func (rl *RateLimiter) GetAttempts(key string, window time.Duration) int {
-	rl.mu.RLock()
-	defer rl.mu.RUnlock()
+	attempts, err := rl.store.Attempts(key, window, rl.now())
+	if err != nil {
+		log.Printf("Failed to get rate limit attempts: %v", err)
+		return 0
+	}
+	return len(attempts)
+}
 
-	now := time.Now()
-	attempts, exists := rl.attempts[key]
+// GetOldestAttempt returns the time of the oldest attempt within the window
+// for a key, i.e. the one that ages out next. Returns false if there are no
+// attempts in the window.
+func (rl *RateLimiter) GetOldestAttempt(key string, window time.Duration) (time.Time, bool) {
+	attempts, err := rl.store.Attempts(key, window, rl.now())
+	if err != nil {
+		log.Printf("Failed to get rate limit attempts: %v", err)
+		return time.Time{}, false
+	}
+	return oldestAttempt(attempts)
+}
+
+// Inspect returns the current state of a key against the limit it was last
+// checked with. Returns false if the key has never been checked (or was
+// reset or cleaned up since).
+//
+// Used by the admin rate limit endpoint for support and incident triage.
+func (rl *RateLimiter) Inspect(key string) (RateLimitStatus, bool) {
+	limit, exists, err := rl.store.Limit(key)
+	if err != nil {
+		log.Printf("Failed to inspect rate limit: %v", err)
+		return RateLimitStatus{}, false
+	}
 	if !exists {
-		return 0
+		return RateLimitStatus{}, false
+	}
+
+	attempts, err := rl.store.Attempts(key, limit.Window, rl.now())
+	if err != nil {
+		log.Printf("Failed to inspect rate limit: %v", err)
+		return RateLimitStatus{}, false
+	}
+
+	status := RateLimitStatus{
+		Key:         key,
+		Attempts:    len(attempts),
+		MaxAttempts: limit.MaxAttempts,
+		Window:      limit.Window.String(),
+		Limited:     len(attempts) >= limit.MaxAttempts,
 	}
 
-	count := 0
+	if oldest, ok := oldestAttempt(attempts); ok {
+		resetAt := oldest.Add(limit.Window)
+		status.ResetAt = &resetAt
+	}
+
+	return status, true
+}
+
+// oldestAttempt returns the earliest of attempts.
+func oldestAttempt(attempts []time.Time) (time.Time, bool) {
+	var oldest time.Time
 	for _, t := range attempts {
-		if now.Sub(t) < window {
-			count++
+		if oldest.IsZero() || t.Before(oldest) {
+			oldest = t
 		}
 	}
-
-	return count
+	return oldest, !oldest.IsZero()
 }
 
 // cleanup periodically removes old entries to prevent memory leaks
-func (rl *RateLimiter) cleanup() {
+func cleanup(store *MemoryRateLimiterStore, now func() time.Time) {
 	ticker := time.NewTicker(CleanupInterval)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		rl.mu.Lock()
-		now := time.Now()
-
-		for key, attempts := range rl.attempts {
-			// Remove entries older than cleanup threshold
-			validAttempts := []time.Time{}
-			for _, t := range attempts {
-				if now.Sub(t) < CleanupThreshold {
-					validAttempts = append(validAttempts, t)
-				}
-			}
-
-			if len(validAttempts) == 0 {
-				delete(rl.attempts, key)
-			} else {
-				rl.attempts[key] = validAttempts
-			}
-		}
-
-		rl.mu.Unlock()
+		store.Cleanup(CleanupThreshold, now())
 	}
 }
@@ -0,0 +1,75 @@
+// Package middleware provides HTTP middleware for the StreamSpace API.
+// This file implements a generic rate limit middleware that reports the
+// client's budget in response headers.
+//
+// Purpose:
+// Limits enforced inside handlers only tell a client it was limited once
+// it already was. This middleware counts every request against a sliding
+// window limit and sets the standard headers on every response, so clients
+// can slow down before they are rejected:
+//
+//	X-RateLimit-Limit      // Requests allowed per window
+//	X-RateLimit-Remaining  // Requests left in the current window
+//	Retry-After            // On HTTP 429: seconds until the oldest request ages out
+//
+// Usage:
+//
+//	byUser := func(c *gin.Context) string { return "api:" + c.GetString("userID") }
+//	api.Use(middleware.RateLimitMiddleware(byUser, 600, time.Minute))
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitMiddleware returns middleware allowing max requests per window
+// for each key, as returned by key. It sets X-RateLimit-Limit and
+// X-RateLimit-Remaining on every response and rejects requests over the
+// limit with HTTP 429 and Retry-After.
+//
+// Requests for which key returns "" pass through uncounted.
+func RateLimitMiddleware(key func(*gin.Context) string, max int, window time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		k := key(c)
+		if k == "" || max <= 0 {
+			c.Next()
+			return
+		}
+
+		limiter := GetRateLimiter()
+		allowed := limiter.CheckLimit(k, max, window)
+
+		remaining := max - limiter.GetAttempts(k, window)
+		if remaining < 0 {
+			remaining = 0
+		}
+		c.Header("X-RateLimit-Limit", strconv.Itoa(max))
+		c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+
+		if allowed {
+			c.Next()
+			return
+		}
+
+		retryAfter := int(math.Ceil(window.Seconds()))
+		if oldest, ok := limiter.GetOldestAttempt(k, window); ok {
+			retryAfter = int(math.Ceil(oldest.Add(window).Sub(limiter.now()).Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+		}
+
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error":       "Rate limit exceeded",
+			"message":     fmt.Sprintf("At most %d requests per %s, please wait %d seconds", max, window, retryAfter),
+			"retry_after": retryAfter,
+		})
+	}
+}
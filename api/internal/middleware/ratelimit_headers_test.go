@@ -0,0 +1,124 @@
+// Package middleware provides HTTP middleware for the StreamSpace API.
+// This file tests the rate limit middleware and its response headers.
+//
+// Tests validate:
+// - X-RateLimit-Limit and X-RateLimit-Remaining are set on every response
+// - Requests over the limit get 429 with Retry-After
+// - Retry-After counts down to when the oldest request ages out
+// - Requests without a key pass through uncounted
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func rateLimitRouter(key string, max int, window time.Duration) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	keyFunc := func(c *gin.Context) string {
+		return key
+	}
+	router.GET("/", RateLimitMiddleware(keyFunc, max, window), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	return router
+}
+
+func TestRateLimitMiddleware_Headers(t *testing.T) {
+	key := "headers-test"
+	defer GetRateLimiter().ResetLimit(key)
+	router := rateLimitRouter(key, 3, time.Minute)
+
+	for i, wantRemaining := range []string{"2", "1", "0"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i+1, w.Code)
+		}
+		if got := w.Header().Get("X-RateLimit-Limit"); got != "3" {
+			t.Errorf("Request %d: expected X-RateLimit-Limit 3, got %q", i+1, got)
+		}
+		if got := w.Header().Get("X-RateLimit-Remaining"); got != wantRemaining {
+			t.Errorf("Request %d: expected X-RateLimit-Remaining %s, got %q", i+1, wantRemaining, got)
+		}
+		if got := w.Header().Get("Retry-After"); got != "" {
+			t.Errorf("Request %d: expected no Retry-After, got %q", i+1, got)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 over the limit, got %d", w.Code)
+	}
+	if got := w.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("Expected X-RateLimit-Remaining 0, got %q", got)
+	}
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 || retryAfter > 60 {
+		t.Errorf("Expected Retry-After within the window, got %q", w.Header().Get("Retry-After"))
+	}
+}
+
+func TestRateLimitMiddleware_RetryAfterTracksOldestAttempt(t *testing.T) {
+	store := NewMemoryRateLimiterStore()
+	limiter := NewRateLimiter(store)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	previous := globalRateLimiter
+	globalRateLimiter = limiter
+	defer func() { globalRateLimiter = previous }()
+
+	router := rateLimitRouter("retry-after-test", 2, time.Minute)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	now = now.Add(20 * time.Second)
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	now = now.Add(10 * time.Second)
+
+	oldest, ok := limiter.GetOldestAttempt("retry-after-test", time.Minute)
+	if !ok || !oldest.Equal(now.Add(-30*time.Second)) {
+		t.Errorf("Expected the first request as the oldest attempt, got %v (%v)", oldest, ok)
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 over the limit, got %d", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Expected Retry-After 30 (until the first request ages out), got %q", got)
+	}
+
+	// Once the first request ages out, one request is available again
+	now = now.Add(30 * time.Second)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected 200 after the oldest request aged out, got %d", w.Code)
+	}
+	if got := w.Header().Get("X-RateLimit-Remaining"); got != "0" {
+		t.Errorf("Expected X-RateLimit-Remaining 0, got %q", got)
+	}
+}
+
+func TestRateLimitMiddleware_NoKey(t *testing.T) {
+	router := rateLimitRouter("", 1, time.Minute)
+
+	for i := 0; i < 3; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200 without a key, got %d", i+1, w.Code)
+		}
+		if got := w.Header().Get("X-RateLimit-Limit"); got != "" {
+			t.Errorf("Request %d: expected no rate limit headers without a key, got %q", i+1, got)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+// Package middleware provides HTTP middleware for the StreamSpace API.
+// This file implements the Redis backend of the rate limiter.
+//
+// Each key's attempts are a sorted set scored by attempt time (Unix
+// nanoseconds), and the limit it was last checked against a hash next to
+// it. A Lua script trims, counts and records in one step, so API servers
+// sharing the Redis instance share limits and can't both take the last
+// slot. Both keys expire one window after the last attempt.
+//
+// Token buckets are a hash of tokens and last refill time per key, refilled
+// and taken from by another script. They expire once they would have
+// refilled, since a missing bucket is a full one.
+//
+// Attempt times come from the API server's clock, so servers' clocks need
+// to be in sync (NTP) for windows to line up.
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// redisRateLimitTimeout bounds each store call, so a slow Redis doesn't
+// stall requests.
+const redisRateLimitTimeout = 2 * time.Second
+
+// redisAllowScript trims attempts outside the window, records the attempt
+// if fewer than the maximum remain and remembers the limit.
+//
+// KEYS[1]: attempts sorted set, KEYS[2]: limit hash
+// ARGV: now (ns), window (ns), max attempts, member, window (ms)
+var redisAllowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+redis.call('HSET', KEYS[2], 'max', ARGV[3], 'window', ARGV[2])
+redis.call('PEXPIRE', KEYS[2], ARGV[5])
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[3]) then
+  return 0
+end
+redis.call('ZADD', KEYS[1], now, ARGV[4])
+redis.call('PEXPIRE', KEYS[1], ARGV[5])
+return 1
+`)
+
+// redisTakeTokenScript refills a token bucket up to now and takes a token
+// if there is one.
+//
+// KEYS[1]: bucket hash
+// ARGV: now (ns), capacity, refill rate (tokens/s)
+var redisTakeTokenScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local capacity = tonumber(ARGV[2])
+local rate = tonumber(ARGV[3])
+local state = redis.call('HMGET', KEYS[1], 'tokens', 'last')
+local tokens = tonumber(state[1])
+local last = tonumber(state[2])
+if tokens == nil or last == nil then
+  tokens = capacity
+  last = now
+end
+if now > last then
+  if rate > 0 then
+    tokens = tokens + (now - last) / 1e9 * rate
+  end
+  last = now
+end
+tokens = math.min(tokens, capacity)
+local allowed = 0
+if tokens >= 1 then
+  tokens = tokens - 1
+  allowed = 1
+end
+redis.call('HSET', KEYS[1], 'tokens', tostring(tokens), 'last', tostring(last))
+if rate > 0 then
+  redis.call('PEXPIRE', KEYS[1], math.max(1, math.ceil((capacity - tokens) / rate * 1000)))
+end
+return allowed
+`)
+
+// RedisRateLimiterStore keeps attempts in Redis.
+type RedisRateLimiterStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisRateLimiterStore creates a store whose keys start with prefix
+// (e.g. "ratelimit:").
+func NewRedisRateLimiterStore(client *redis.Client, prefix string) *RedisRateLimiterStore {
+	return &RedisRateLimiterStore{client: client, prefix: prefix}
+}
+
+// attemptsKey, limitKey and bucketKey are the Redis keys of a rate limit key.
+func (s *RedisRateLimiterStore) attemptsKey(key string) string { return s.prefix + key }
+func (s *RedisRateLimiterStore) limitKey(key string) string    { return s.prefix + key + ":limit" }
+func (s *RedisRateLimiterStore) bucketKey(key string) string   { return s.prefix + key + ":bucket" }
+
+// Allow implements RateLimiterStore.
+func (s *RedisRateLimiterStore) Allow(key string, limit RateLimit, now time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisRateLimitTimeout)
+	defer cancel()
+
+	// Members must be unique or attempts at the same instant would merge
+	member := fmt.Sprintf("%d-%s", now.UnixNano(), uuid.New().String())
+	ttl := limit.Window.Milliseconds()
+	if ttl < 1 {
+		ttl = 1
+	}
+	allowed, err := redisAllowScript.Run(ctx, s.client,
+		[]string{s.attemptsKey(key), s.limitKey(key)},
+		now.UnixNano(), limit.Window.Nanoseconds(), limit.MaxAttempts, member, ttl,
+	).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to check rate limit %s: %w", key, err)
+	}
+	return allowed == 1, nil
+}
+
+// Attempts implements RateLimiterStore.
+func (s *RedisRateLimiterStore) Attempts(key string, window time.Duration, now time.Time) ([]time.Time, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisRateLimitTimeout)
+	defer cancel()
+
+	scores, err := s.client.ZRangeByScoreWithScores(ctx, s.attemptsKey(key), &redis.ZRangeBy{
+		Min: "(" + strconv.FormatInt(now.Add(-window).UnixNano(), 10),
+		Max: "+inf",
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attempts of rate limit %s: %w", key, err)
+	}
+
+	attempts := make([]time.Time, 0, len(scores))
+	for _, z := range scores {
+		attempts = append(attempts, time.Unix(0, int64(z.Score)))
+	}
+	return attempts, nil
+}
+
+// Limit implements RateLimiterStore.
+func (s *RedisRateLimiterStore) Limit(key string) (RateLimit, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisRateLimitTimeout)
+	defer cancel()
+
+	values, err := s.client.HGetAll(ctx, s.limitKey(key)).Result()
+	if err != nil {
+		return RateLimit{}, false, fmt.Errorf("failed to get rate limit %s: %w", key, err)
+	}
+	if len(values) == 0 {
+		return RateLimit{}, false, nil
+	}
+
+	maxAttempts, err := strconv.Atoi(values["max"])
+	if err != nil {
+		return RateLimit{}, false, fmt.Errorf("invalid max attempts of rate limit %s: %w", key, err)
+	}
+	window, err := strconv.ParseInt(values["window"], 10, 64)
+	if err != nil {
+		return RateLimit{}, false, fmt.Errorf("invalid window of rate limit %s: %w", key, err)
+	}
+	return RateLimit{MaxAttempts: maxAttempts, Window: time.Duration(window)}, true, nil
+}
+
+// TakeToken implements RateLimiterStore.
+func (s *RedisRateLimiterStore) TakeToken(key string, bucket TokenBucket, now time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisRateLimitTimeout)
+	defer cancel()
+
+	allowed, err := redisTakeTokenScript.Run(ctx, s.client, []string{s.bucketKey(key)},
+		now.UnixNano(), bucket.Capacity, bucket.RefillRate,
+	).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to take token of rate limit %s: %w", key, err)
+	}
+	return allowed == 1, nil
+}
+
+// Reset implements RateLimiterStore.
+func (s *RedisRateLimiterStore) Reset(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisRateLimitTimeout)
+	defer cancel()
+
+	if err := s.client.Del(ctx, s.attemptsKey(key), s.limitKey(key), s.bucketKey(key)).Err(); err != nil {
+		return fmt.Errorf("failed to reset rate limit %s: %w", key, err)
+	}
+	return nil
+}
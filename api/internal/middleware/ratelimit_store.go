@@ -0,0 +1,199 @@
+// Package middleware provides HTTP middleware for the StreamSpace API.
+// This file defines the storage backend of the rate limiter.
+//
+// The RateLimiter decides nothing itself: it passes the current time from
+// its clock to a RateLimiterStore, which records attempts (or takes tokens)
+// and answers whether another one fits the limit. Keeping time out of the store makes
+// the sliding window testable without sleeping, and keeping state out of
+// the limiter lets it be shared across API servers:
+//
+//   - MemoryRateLimiterStore: per-process maps (default; what
+//     GetRateLimiter uses unless another store is installed)
+//   - RedisRateLimiterStore: a sorted set per key in Redis, shared by all
+//     API servers (see ratelimit_redis.go)
+package middleware
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit is a limit of MaxAttempts per sliding Window.
+type RateLimit struct {
+	MaxAttempts int
+	Window      time.Duration
+}
+
+// TokenBucket is a limit of Capacity requests at once, refilled at
+// RefillRate requests per second.
+type TokenBucket struct {
+	Capacity   int
+	RefillRate float64
+}
+
+// refill returns the tokens in a bucket that held tokens at last, at now.
+func (b TokenBucket) refill(tokens float64, last, now time.Time) float64 {
+	if elapsed := now.Sub(last).Seconds(); elapsed > 0 && b.RefillRate > 0 {
+		tokens += elapsed * b.RefillRate
+	}
+	return math.Min(tokens, float64(b.Capacity))
+}
+
+// RateLimiterStore records rate limit attempts.
+//
+// Limits are passed per call rather than configured up front, so stores
+// remember the limit a key was last checked against for Inspect.
+type RateLimiterStore interface {
+	// Allow records an attempt of key at now and reports true if fewer
+	// than limit.MaxAttempts attempts fall in the window before now.
+	// Rejected attempts are not recorded. Check and record must be atomic
+	// per key: of two concurrent attempts racing for the last slot, one is
+	// rejected.
+	Allow(key string, limit RateLimit, now time.Time) (bool, error)
+
+	// Attempts returns the times of key's attempts within window before now.
+	Attempts(key string, window time.Duration, now time.Time) ([]time.Time, error)
+
+	// Limit returns the limit key was last checked against, and false if
+	// it was never checked (or was reset or expired since).
+	Limit(key string) (RateLimit, bool, error)
+
+	// TakeToken refills key's token bucket up to now and takes a token,
+	// reporting false if there was none. A key seen for the first time has
+	// a full bucket. Refill and take must be atomic per key. Token buckets
+	// are kept apart from sliding window attempts of the same key.
+	TakeToken(key string, bucket TokenBucket, now time.Time) (bool, error)
+
+	// Reset forgets key's attempts, limit and token bucket.
+	Reset(key string) error
+}
+
+// MemoryRateLimiterStore keeps attempts in memory. It is not shared across
+// API servers and is lost on restart.
+type MemoryRateLimiterStore struct {
+	mu       sync.RWMutex
+	attempts map[string][]time.Time
+	limits   map[string]RateLimit
+	buckets  map[string]*bucketState
+}
+
+// bucketState is a token bucket as of its last take.
+type bucketState struct {
+	bucket TokenBucket
+	tokens float64
+	last   time.Time
+}
+
+// NewMemoryRateLimiterStore creates an empty in-memory store.
+func NewMemoryRateLimiterStore() *MemoryRateLimiterStore {
+	return &MemoryRateLimiterStore{
+		attempts: make(map[string][]time.Time),
+		limits:   make(map[string]RateLimit),
+		buckets:  make(map[string]*bucketState),
+	}
+}
+
+// Allow implements RateLimiterStore.
+func (s *MemoryRateLimiterStore) Allow(key string, limit RateLimit, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Remember the limit so Inspect can report against it
+	s.limits[key] = limit
+
+	// Filter out attempts outside the time window
+	valid := inWindow(s.attempts[key], limit.Window, now)
+
+	// Check if limit exceeded (don't record this request)
+	if len(valid) >= limit.MaxAttempts {
+		s.attempts[key] = valid
+		return false, nil
+	}
+
+	s.attempts[key] = append(valid, now)
+	return true, nil
+}
+
+// Attempts implements RateLimiterStore.
+func (s *MemoryRateLimiterStore) Attempts(key string, window time.Duration, now time.Time) ([]time.Time, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return inWindow(s.attempts[key], window, now), nil
+}
+
+// Limit implements RateLimiterStore.
+func (s *MemoryRateLimiterStore) Limit(key string) (RateLimit, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	limit, ok := s.limits[key]
+	return limit, ok, nil
+}
+
+// TakeToken implements RateLimiterStore.
+func (s *MemoryRateLimiterStore) TakeToken(key string, bucket TokenBucket, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	state, ok := s.buckets[key]
+	if !ok {
+		state = &bucketState{tokens: float64(bucket.Capacity), last: now}
+		s.buckets[key] = state
+	}
+	state.bucket = bucket
+	state.tokens = bucket.refill(state.tokens, state.last, now)
+	if now.After(state.last) {
+		state.last = now
+	}
+
+	if state.tokens < 1 {
+		return false, nil
+	}
+	state.tokens--
+	return true, nil
+}
+
+// Reset implements RateLimiterStore.
+func (s *MemoryRateLimiterStore) Reset(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.attempts, key)
+	delete(s.limits, key)
+	delete(s.buckets, key)
+	return nil
+}
+
+// Cleanup drops attempts older than maxAge, and keys left without any, to
+// bound memory use. Token buckets idle long enough to have refilled are
+// dropped too: a missing bucket is a full one.
+func (s *MemoryRateLimiterStore) Cleanup(maxAge time.Duration, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, state := range s.buckets {
+		if state.bucket.refill(state.tokens, state.last, now) >= float64(state.bucket.Capacity) {
+			delete(s.buckets, key)
+		}
+	}
+
+	for key, attempts := range s.attempts {
+		valid := inWindow(attempts, maxAge, now)
+		if len(valid) == 0 {
+			delete(s.attempts, key)
+			delete(s.limits, key)
+		} else {
+			s.attempts[key] = valid
+		}
+	}
+}
+
+// inWindow returns the attempts less than window before now.
+func inWindow(attempts []time.Time, window time.Duration, now time.Time) []time.Time {
+	valid := []time.Time{}
+	for _, t := range attempts {
+		if now.Sub(t) < window {
+			valid = append(valid, t)
+		}
+	}
+	return valid
+}
@@ -8,17 +8,49 @@
 // - Rate limits reset after the time window expires
 // - Cleanup removes old rate limit entries to prevent memory leaks
 // - GetAttempts returns accurate attempt counts
+// - Sliding window edge cases, on a fake clock instead of sleeping
+// - Token bucket bursts, refill and cleanup
 package middleware
 
 import (
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
 
+// fakeClock is a settable clock for RateLimiter.now.
+type fakeClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{t: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.t = c.t.Add(d)
+}
+
+// newFakeClockRateLimiter returns an in-memory rate limiter on a fake clock.
+func newFakeClockRateLimiter() (*RateLimiter, *fakeClock) {
+	clock := newFakeClock()
+	rl := NewRateLimiter(NewMemoryRateLimiterStore())
+	rl.now = clock.Now
+	return rl, clock
+}
+
 func TestRateLimiter_CheckLimit(t *testing.T) {
-	rl := &RateLimiter{
-		attempts: make(map[string][]time.Time),
-	}
+	rl := NewRateLimiter(NewMemoryRateLimiterStore())
 
 	key := "test-user"
 	maxAttempts := 5
@@ -44,9 +76,7 @@ func TestRateLimiter_CheckLimit(t *testing.T) {
 }
 
 func TestRateLimiter_ResetLimit(t *testing.T) {
-	rl := &RateLimiter{
-		attempts: make(map[string][]time.Time),
-	}
+	rl := NewRateLimiter(NewMemoryRateLimiterStore())
 
 	key := "test-user"
 	maxAttempts := 5
@@ -72,9 +102,7 @@ func TestRateLimiter_ResetLimit(t *testing.T) {
 }
 
 func TestRateLimiter_WindowExpiry(t *testing.T) {
-	rl := &RateLimiter{
-		attempts: make(map[string][]time.Time),
-	}
+	rl, clock := newFakeClockRateLimiter()
 
 	key := "test-user"
 	maxAttempts := 3
@@ -92,11 +120,270 @@ func TestRateLimiter_WindowExpiry(t *testing.T) {
 		t.Error("Should be rate limited")
 	}
 
-	// Wait for window to expire
-	time.Sleep(150 * time.Millisecond)
+	// Let the window expire
+	clock.Advance(150 * time.Millisecond)
 
 	// Should now succeed (old attempts expired)
 	if !rl.CheckLimit(key, maxAttempts, window) {
 		t.Error("Should succeed after window expiry")
 	}
 }
+
+func TestRateLimiter_Inspect(t *testing.T) {
+	rl := NewRateLimiter(NewMemoryRateLimiterStore())
+
+	key := "test-user"
+	maxAttempts := 3
+	window := 1 * time.Minute
+
+	// Unknown key
+	if _, exists := rl.Inspect(key); exists {
+		t.Error("Inspect should report unknown key as not found")
+	}
+
+	before := time.Now()
+	for i := 0; i < maxAttempts; i++ {
+		rl.CheckLimit(key, maxAttempts, window)
+	}
+
+	status, exists := rl.Inspect(key)
+	if !exists {
+		t.Fatal("Inspect should find key after CheckLimit")
+	}
+	if status.Attempts != maxAttempts {
+		t.Errorf("Expected %d attempts, got %d", maxAttempts, status.Attempts)
+	}
+	if status.MaxAttempts != maxAttempts {
+		t.Errorf("Expected max attempts %d, got %d", maxAttempts, status.MaxAttempts)
+	}
+	if status.Window != window.String() {
+		t.Errorf("Expected window %s, got %s", window, status.Window)
+	}
+	if !status.Limited {
+		t.Error("Key should be reported as limited")
+	}
+	if status.ResetAt == nil || status.ResetAt.Before(before.Add(window)) {
+		t.Errorf("ResetAt should be one window after the oldest attempt, got %v", status.ResetAt)
+	}
+
+	// Reset clears the key entirely
+	rl.ResetLimit(key)
+	if _, exists := rl.Inspect(key); exists {
+		t.Error("Inspect should not find key after ResetLimit")
+	}
+}
+
+func TestRateLimiter_ExactlyAtLimit(t *testing.T) {
+	rl, clock := newFakeClockRateLimiter()
+
+	key := "test-user"
+	window := 1 * time.Minute
+
+	// Attempts spread across the window all count
+	for i := 0; i < 3; i++ {
+		if !rl.CheckLimit(key, 3, window) {
+			t.Fatalf("Attempt %d should have succeeded", i+1)
+		}
+		clock.Advance(10 * time.Second)
+	}
+
+	// count == maxAttempts is rejected
+	if rl.CheckLimit(key, 3, window) {
+		t.Error("Attempt at the limit should be rejected")
+	}
+
+	// The oldest attempt leaves the window exactly one window after it was made
+	clock.Advance(window - 30*time.Second - time.Nanosecond)
+	if rl.CheckLimit(key, 3, window) {
+		t.Error("Attempt just before the oldest expires should be rejected")
+	}
+	clock.Advance(time.Nanosecond)
+	if !rl.CheckLimit(key, 3, window) {
+		t.Error("Attempt once the oldest expires should succeed")
+	}
+}
+
+func TestRateLimiter_AllExpired(t *testing.T) {
+	rl, clock := newFakeClockRateLimiter()
+
+	key := "test-user"
+	window := 1 * time.Minute
+
+	for i := 0; i < 5; i++ {
+		rl.CheckLimit(key, 5, window)
+	}
+	clock.Advance(window)
+
+	if got := rl.GetAttempts(key, window); got != 0 {
+		t.Errorf("Expected 0 attempts after the window, got %d", got)
+	}
+	// Like a fresh start: the full limit is available again
+	for i := 0; i < 5; i++ {
+		if !rl.CheckLimit(key, 5, window) {
+			t.Errorf("Attempt %d should have succeeded", i+1)
+		}
+	}
+}
+
+func TestRateLimiter_ConcurrentNth(t *testing.T) {
+	rl, _ := newFakeClockRateLimiter()
+
+	key := "test-user"
+	maxAttempts := 5
+	window := 1 * time.Minute
+
+	// Leave one slot, then race many requests for it at the same instant
+	for i := 0; i < maxAttempts-1; i++ {
+		rl.CheckLimit(key, maxAttempts, window)
+	}
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rl.CheckLimit(key, maxAttempts, window) {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != 1 {
+		t.Errorf("Expected exactly 1 request to take the last slot, got %d", got)
+	}
+	if got := rl.GetAttempts(key, window); got != maxAttempts {
+		t.Errorf("Expected %d attempts, got %d", maxAttempts, got)
+	}
+}
+
+func TestMemoryRateLimiterStore_Cleanup(t *testing.T) {
+	store := NewMemoryRateLimiterStore()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	limit := RateLimit{MaxAttempts: 5, Window: time.Minute}
+
+	store.Allow("old", limit, now)
+	store.Allow("recent", limit, now.Add(9*time.Minute))
+
+	store.Cleanup(CleanupThreshold, now.Add(CleanupThreshold))
+
+	if _, ok, _ := store.Limit("old"); ok {
+		t.Error("Cleanup should drop keys without recent attempts")
+	}
+	if _, ok, _ := store.Limit("recent"); !ok {
+		t.Error("Cleanup should keep keys with recent attempts")
+	}
+}
+
+func TestRateLimiter_TokenBucketBurst(t *testing.T) {
+	rl := NewRateLimiter(NewMemoryRateLimiterStore())
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	key := "test-user"
+	capacity := 5
+	refillRate := 2.0 // tokens per second
+
+	// A fresh key can burst its full capacity at once
+	for i := 0; i < capacity; i++ {
+		if !rl.CheckLimitTokenBucket(key, capacity, refillRate, now) {
+			t.Fatalf("Request %d of the burst should be allowed", i+1)
+		}
+	}
+	if rl.CheckLimitTokenBucket(key, capacity, refillRate, now) {
+		t.Error("Request after the burst should be blocked")
+	}
+
+	// Less than one token refilled
+	if rl.CheckLimitTokenBucket(key, capacity, refillRate, now.Add(400*time.Millisecond)) {
+		t.Error("Request should be blocked until a whole token refilled")
+	}
+
+	// One token refilled after 1/refillRate seconds
+	now = now.Add(500 * time.Millisecond)
+	if !rl.CheckLimitTokenBucket(key, capacity, refillRate, now) {
+		t.Error("Request should be allowed once a token refilled")
+	}
+	if rl.CheckLimitTokenBucket(key, capacity, refillRate, now) {
+		t.Error("Only one token should have refilled")
+	}
+
+	// An idle key refills to capacity, not beyond
+	now = now.Add(time.Hour)
+	for i := 0; i < capacity; i++ {
+		if !rl.CheckLimitTokenBucket(key, capacity, refillRate, now) {
+			t.Fatalf("Request %d of the burst after idling should be allowed", i+1)
+		}
+	}
+	if rl.CheckLimitTokenBucket(key, capacity, refillRate, now) {
+		t.Error("Idle time should not build up more than capacity")
+	}
+}
+
+func TestRateLimiter_TokenBucketSeparateFromWindow(t *testing.T) {
+	rl, clock := newFakeClockRateLimiter()
+	key := "test-user"
+
+	for i := 0; i < 3; i++ {
+		rl.CheckLimit(key, 3, time.Minute)
+	}
+	if !rl.CheckLimitTokenBucket(key, 1, 1, clock.Now()) {
+		t.Error("Sliding window attempts should not use up token bucket tokens")
+	}
+	if rl.GetAttempts(key, time.Minute) != 3 {
+		t.Error("Token bucket takes should not count as sliding window attempts")
+	}
+
+	rl.ResetLimit(key)
+	if !rl.CheckLimitTokenBucket(key, 1, 1, clock.Now()) {
+		t.Error("ResetLimit should refill the token bucket")
+	}
+}
+
+func TestRateLimiter_TokenBucketConcurrent(t *testing.T) {
+	rl := NewRateLimiter(NewMemoryRateLimiterStore())
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	capacity := 10
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if rl.CheckLimitTokenBucket("test-user", capacity, 1, now) {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := allowed.Load(); got != int32(capacity) {
+		t.Errorf("Expected exactly %d requests to get a token, got %d", capacity, got)
+	}
+}
+
+func TestMemoryRateLimiterStore_CleanupTokenBuckets(t *testing.T) {
+	store := NewMemoryRateLimiterStore()
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	bucket := TokenBucket{Capacity: 10, RefillRate: 1}
+
+	store.TakeToken("idle", bucket, now)
+	for i := 0; i < 10; i++ {
+		store.TakeToken("busy", bucket, now.Add(5*time.Second))
+	}
+
+	// "idle" has refilled after 1s, "busy" needs 10s from its last take
+	store.Cleanup(CleanupThreshold, now.Add(10*time.Second))
+
+	store.mu.RLock()
+	_, idle := store.buckets["idle"]
+	_, busy := store.buckets["busy"]
+	store.mu.RUnlock()
+	if idle {
+		t.Error("Cleanup should drop token buckets that have refilled")
+	}
+	if !busy {
+		t.Error("Cleanup should keep token buckets that are still refilling")
+	}
+}
@@ -0,0 +1,160 @@
+// Package middleware provides HTTP middleware for the StreamSpace API.
+// This file implements the per-user rate limit on session create/terminate.
+//
+// Purpose:
+// Quotas cap how many sessions a user runs at once, not how fast they are
+// created. A script that creates and terminates sessions in a loop stays
+// within its quota while every iteration makes a controller schedule a pod,
+// attach a volume and tear both down again. This limit caps that churn:
+// session creates and terminates of a user share one budget per window,
+// separate from any other limit, and requests over it get HTTP 429 with a
+// Retry-After header, before anything is sent to a controller.
+//
+// Configuration:
+//
+//	SESSION_RATE_LIMIT=20            // Creates + terminates per window (default: 20, 0 disables)
+//	SESSION_RATE_LIMIT_WINDOW=1m     // Window length (default: 1m)
+//
+// Usage:
+//
+//	limit := middleware.SessionRateLimitFromEnv()
+//	sessions.POST("", middleware.SessionChurnLimit(limit), h.CreateSession)
+//	sessions.DELETE("/:id", middleware.SessionChurnLimit(limit), h.DeleteSession)
+package middleware
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// DefaultSessionRateLimit is the default number of session creates and
+	// terminates a user may make per window
+	DefaultSessionRateLimit = 20
+
+	// DefaultSessionRateLimitWindow is the default session rate limit window
+	DefaultSessionRateLimitWindow = 1 * time.Minute
+)
+
+// SessionRateLimit is the per-user limit on session creates and terminates.
+type SessionRateLimit struct {
+	// MaxRequests per Window; 0 disables the limit
+	MaxRequests int
+
+	// Window is the sliding window the requests are counted in
+	Window time.Duration
+}
+
+// SessionRateLimitStatus is a user's state against the session rate limit,
+// as reported in their quota.
+type SessionRateLimitStatus struct {
+	MaxRequests int        `json:"maxRequests"`
+	Window      string     `json:"window"`
+	Used        int        `json:"used"`
+	Remaining   int        `json:"remaining"`
+	ResetAt     *time.Time `json:"resetAt,omitempty"`
+}
+
+// SessionRateLimitFromEnv reads SESSION_RATE_LIMIT and
+// SESSION_RATE_LIMIT_WINDOW. Invalid values fall back to the defaults.
+func SessionRateLimitFromEnv() SessionRateLimit {
+	limit := SessionRateLimit{
+		MaxRequests: DefaultSessionRateLimit,
+		Window:      DefaultSessionRateLimitWindow,
+	}
+
+	if v := os.Getenv("SESSION_RATE_LIMIT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			limit.MaxRequests = n
+		} else {
+			log.Printf("Warning: invalid SESSION_RATE_LIMIT %q, using %d", v, DefaultSessionRateLimit)
+		}
+	}
+	if v := os.Getenv("SESSION_RATE_LIMIT_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			limit.Window = d
+		} else {
+			log.Printf("Warning: invalid SESSION_RATE_LIMIT_WINDOW %q, using %s", v, DefaultSessionRateLimitWindow)
+		}
+	}
+
+	return limit
+}
+
+// Enabled reports whether the limit is enforced.
+func (l SessionRateLimit) Enabled() bool {
+	return l.MaxRequests > 0
+}
+
+// sessionRateLimitKey is the limiter key of a user's session churn budget.
+func sessionRateLimitKey(userID string) string {
+	return fmt.Sprintf("session_churn:%s", userID)
+}
+
+// SessionChurnLimit returns middleware counting a request against the
+// user's session rate limit, rejecting it with HTTP 429 and Retry-After
+// when the budget is used up.
+//
+// It must run after authentication; requests without a user ID pass
+// through.
+func SessionChurnLimit(limit SessionRateLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.GetString("userID")
+		if !limit.Enabled() || userID == "" {
+			c.Next()
+			return
+		}
+
+		limiter := GetRateLimiter()
+		key := sessionRateLimitKey(userID)
+		if limiter.CheckLimit(key, limit.MaxRequests, limit.Window) {
+			c.Next()
+			return
+		}
+
+		retryAfter := int(math.Ceil(limit.Window.Seconds()))
+		if status, ok := limiter.Inspect(key); ok && status.ResetAt != nil {
+			retryAfter = int(math.Ceil(time.Until(*status.ResetAt).Seconds()))
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+		}
+
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{
+			"error":       "Session rate limit exceeded",
+			"message":     fmt.Sprintf("At most %d session creates and terminates per %s, please wait %d seconds", limit.MaxRequests, limit.Window, retryAfter),
+			"retry_after": retryAfter,
+		})
+	}
+}
+
+// SessionRateLimitStatusFor returns the user's state against the session
+// rate limit, or nil if the limit is disabled.
+func SessionRateLimitStatusFor(limit SessionRateLimit, userID string) *SessionRateLimitStatus {
+	if !limit.Enabled() {
+		return nil
+	}
+
+	status := &SessionRateLimitStatus{
+		MaxRequests: limit.MaxRequests,
+		Window:      limit.Window.String(),
+		Remaining:   limit.MaxRequests,
+	}
+	if current, ok := GetRateLimiter().Inspect(sessionRateLimitKey(userID)); ok {
+		status.Used = current.Attempts
+		status.ResetAt = current.ResetAt
+		status.Remaining = limit.MaxRequests - current.Attempts
+		if status.Remaining < 0 {
+			status.Remaining = 0
+		}
+	}
+	return status
+}
@@ -0,0 +1,123 @@
+// Package middleware provides HTTP middleware for the StreamSpace API.
+// This file tests the per-user session create/terminate rate limit.
+//
+// Tests validate:
+// - Configuration is read from the environment, with defaults
+// - Creates and terminates share one budget per user
+// - Requests over the limit get 429 with Retry-After
+// - The status reported in the quota reflects the used budget
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func sessionChurnRouter(userID string, limit SessionRateLimit) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	setUser := func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Next()
+	}
+	ok := func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	}
+	router.POST("/sessions", setUser, SessionChurnLimit(limit), ok)
+	router.DELETE("/sessions/:id", setUser, SessionChurnLimit(limit), ok)
+	return router
+}
+
+func TestSessionRateLimitFromEnv(t *testing.T) {
+	t.Setenv("SESSION_RATE_LIMIT", "")
+	t.Setenv("SESSION_RATE_LIMIT_WINDOW", "")
+	limit := SessionRateLimitFromEnv()
+	if limit.MaxRequests != DefaultSessionRateLimit || limit.Window != DefaultSessionRateLimitWindow {
+		t.Errorf("Expected defaults, got %+v", limit)
+	}
+
+	t.Setenv("SESSION_RATE_LIMIT", "5")
+	t.Setenv("SESSION_RATE_LIMIT_WINDOW", "10m")
+	limit = SessionRateLimitFromEnv()
+	if limit.MaxRequests != 5 || limit.Window != 10*time.Minute {
+		t.Errorf("Expected 5 per 10m, got %+v", limit)
+	}
+
+	t.Setenv("SESSION_RATE_LIMIT", "0")
+	if SessionRateLimitFromEnv().Enabled() {
+		t.Error("Expected SESSION_RATE_LIMIT=0 to disable the limit")
+	}
+
+	t.Setenv("SESSION_RATE_LIMIT", "lots")
+	t.Setenv("SESSION_RATE_LIMIT_WINDOW", "-1m")
+	limit = SessionRateLimitFromEnv()
+	if limit.MaxRequests != DefaultSessionRateLimit || limit.Window != DefaultSessionRateLimitWindow {
+		t.Errorf("Expected invalid values to fall back to defaults, got %+v", limit)
+	}
+}
+
+func TestSessionChurnLimit(t *testing.T) {
+	userID := "churn-user"
+	defer GetRateLimiter().ResetLimit(sessionRateLimitKey(userID))
+
+	limit := SessionRateLimit{MaxRequests: 3, Window: time.Minute}
+	router := sessionChurnRouter(userID, limit)
+
+	// Creates and terminates share the budget
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodPost, "/sessions", nil),
+		httptest.NewRequest(http.MethodDelete, "/sessions/s1", nil),
+		httptest.NewRequest(http.MethodPost, "/sessions", nil),
+	}
+	for i, req := range requests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200, got %d", i+1, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/sessions/s2", nil))
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 over the limit, got %d", w.Code)
+	}
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	if err != nil || retryAfter < 1 || retryAfter > 60 {
+		t.Errorf("Expected Retry-After within the window, got %q", w.Header().Get("Retry-After"))
+	}
+
+	status := SessionRateLimitStatusFor(limit, userID)
+	if status == nil || status.Used != 3 || status.Remaining != 0 || status.ResetAt == nil {
+		t.Errorf("Expected the budget to be used up, got %+v", status)
+	}
+
+	// Other users have their own budget
+	w = httptest.NewRecorder()
+	sessionChurnRouter("other-user", limit).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/sessions", nil))
+	GetRateLimiter().ResetLimit(sessionRateLimitKey("other-user"))
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected another user's request to pass, got %d", w.Code)
+	}
+}
+
+func TestSessionChurnLimit_Disabled(t *testing.T) {
+	limit := SessionRateLimit{MaxRequests: 0, Window: time.Minute}
+	router := sessionChurnRouter("unlimited-user", limit)
+
+	for i := 0; i < 5; i++ {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/sessions", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("Request %d: expected 200 with the limit disabled, got %d", i+1, w.Code)
+		}
+	}
+	if SessionRateLimitStatusFor(limit, "unlimited-user") != nil {
+		t.Error("Expected no status with the limit disabled")
+	}
+}
@@ -0,0 +1,183 @@
+// Package middleware provides HTTP middleware for the StreamSpace API.
+// This file implements MFA step-up for sensitive operations.
+//
+// Purpose:
+// A stolen session token normally grants everything the user can do. Step-up
+// requires a FRESH MFA check for destructive operations (terminating a
+// session, transferring ownership, granting "control" on a share), so a
+// stolen token alone can't do the most damaging things.
+//
+// How It Works:
+//  1. VerifyMFA records the time of every successful verification
+//     (users.mfa_verified_at)
+//  2. Flagged routes use RequireMFAStepUp() (or handlers call CheckMFAStepUp
+//     when only some requests are sensitive)
+//  3. If the user has MFA enabled and didn't verify within the last
+//     MFA_STEP_UP_MAX_AGE, the request fails with 401 and code "mfa_required"
+//  4. The client prompts for a code, calls POST /security/mfa/verify (which
+//     is rate limited) and retries the request
+//
+// Users without MFA enabled are not challenged: they have no factor to step
+// up with.
+//
+// Configuration:
+//
+//	MFA_STEP_UP_ENABLED=true   // Opt in (default: disabled)
+//	MFA_STEP_UP_MAX_AGE=5m     // How recent the MFA check must be (default 5m)
+//
+// Usage:
+//
+//	middleware.InitMFAStepUp(database)
+//	sessions.DELETE("/:id", middleware.RequireMFAStepUp(), h.DeleteSession)
+//
+//	// In a handler, for requests that are only sometimes sensitive
+//	if req.PermissionLevel == "control" && !middleware.CheckMFAStepUp(c) {
+//	    return // 401 already written
+//	}
+package middleware
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/db"
+)
+
+const (
+	// DefaultMFAStepUpMaxAge is how recent an MFA check must be by default
+	DefaultMFAStepUpMaxAge = 5 * time.Minute
+
+	// MFARequiredCode is the error code returned when step-up is needed
+	MFARequiredCode = "mfa_required"
+)
+
+// MFAStepUp enforces recent MFA verification for sensitive operations.
+type MFAStepUp struct {
+	db     *sql.DB
+	maxAge time.Duration
+}
+
+var (
+	globalStepUp   *MFAStepUp
+	globalStepUpMu sync.RWMutex
+)
+
+// NewMFAStepUp creates a step-up checker requiring MFA within maxAge.
+func NewMFAStepUp(database *sql.DB, maxAge time.Duration) *MFAStepUp {
+	return &MFAStepUp{db: database, maxAge: maxAge}
+}
+
+// InitMFAStepUp enables step-up enforcement if MFA_STEP_UP_ENABLED=true.
+//
+// When it isn't enabled, RequireMFAStepUp and CheckMFAStepUp let every
+// request through.
+func InitMFAStepUp(database *db.Database) {
+	if !strings.EqualFold(os.Getenv("MFA_STEP_UP_ENABLED"), "true") {
+		return
+	}
+
+	maxAge := DefaultMFAStepUpMaxAge
+	if v := os.Getenv("MFA_STEP_UP_MAX_AGE"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Printf("Invalid MFA_STEP_UP_MAX_AGE %q, using %s", v, maxAge)
+		} else {
+			maxAge = d
+		}
+	}
+
+	SetMFAStepUp(NewMFAStepUp(database.DB(), maxAge))
+	log.Printf("MFA step-up enabled for sensitive operations (max age %s)", maxAge)
+}
+
+// SetMFAStepUp sets (or with nil, clears) the global step-up checker.
+func SetMFAStepUp(stepUp *MFAStepUp) {
+	globalStepUpMu.Lock()
+	defer globalStepUpMu.Unlock()
+	globalStepUp = stepUp
+}
+
+func getMFAStepUp() *MFAStepUp {
+	globalStepUpMu.RLock()
+	defer globalStepUpMu.RUnlock()
+	return globalStepUp
+}
+
+// RecordMFAVerification records a successful MFA verification for step-up.
+func RecordMFAVerification(database *sql.DB, userID string) error {
+	_, err := database.Exec(`UPDATE users SET mfa_verified_at = NOW() WHERE id = $1`, userID)
+	return err
+}
+
+// RequireMFAStepUp returns middleware that requires a recent MFA check.
+func RequireMFAStepUp() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !CheckMFAStepUp(c) {
+			return
+		}
+		c.Next()
+	}
+}
+
+// CheckMFAStepUp reports whether the request may proceed.
+//
+// If it may not, a 401 "mfa_required" response has been written and the
+// request aborted; the caller must return.
+func CheckMFAStepUp(c *gin.Context) bool {
+	stepUp := getMFAStepUp()
+	if stepUp == nil {
+		return true
+	}
+	return stepUp.check(c)
+}
+
+func (s *MFAStepUp) check(c *gin.Context) bool {
+	userID := c.GetString("userID")
+	if userID == "" {
+		// Not authenticated - the auth middleware is responsible for this
+		return true
+	}
+
+	// Compare in the database so clock skew between API and DB doesn't matter
+	var recent, mfaEnabled bool
+	err := s.db.QueryRowContext(c.Request.Context(), `
+		SELECT COALESCE(u.mfa_verified_at > NOW() - make_interval(secs => $2), false),
+		       EXISTS(SELECT 1 FROM mfa_methods m WHERE m.user_id = u.id AND m.enabled = true)
+		FROM users u
+		WHERE u.id = $1
+	`, userID, s.maxAge.Seconds()).Scan(&recent, &mfaEnabled)
+	if err != nil && err != sql.ErrNoRows {
+		// Fail closed: this guards destructive operations
+		log.Printf("MFA step-up check failed for user %s: %v", userID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to verify MFA status",
+			"message": err.Error(),
+		})
+		return false
+	}
+
+	if !mfaEnabled || recent {
+		return true
+	}
+
+	response := gin.H{
+		"error":           "MFA required",
+		"code":            MFARequiredCode,
+		"message":         fmt.Sprintf("This operation requires MFA verification within the last %s", s.maxAge),
+		"max_age_seconds": int(s.maxAge.Seconds()),
+	}
+	// Tell the client up front if it can't verify right now
+	if status, ok := GetRateLimiter().Inspect(fmt.Sprintf("mfa_verify:%s", userID)); ok && status.Limited && status.ResetAt != nil {
+		response["retry_after"] = int(time.Until(*status.ResetAt).Seconds()) + 1
+	}
+
+	c.AbortWithStatusJSON(http.StatusUnauthorized, response)
+	return false
+}
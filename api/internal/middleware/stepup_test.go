@@ -0,0 +1,92 @@
+// Package middleware provides HTTP middleware for the StreamSpace API.
+// This file tests MFA step-up enforcement.
+//
+// Tests validate:
+// - Requests pass when step-up is not enabled
+// - Users with MFA enabled but no recent verification get 401 mfa_required
+// - Recent verification or no enrolled MFA lets the request through
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+)
+
+func stepUpRouter(userID string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.DELETE("/sessions/:id", func(c *gin.Context) {
+		c.Set("userID", userID)
+		c.Next()
+	}, RequireMFAStepUp(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	return router
+}
+
+func TestRequireMFAStepUp(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	tests := []struct {
+		name       string
+		recent     bool
+		mfaEnabled bool
+		wantStatus int
+	}{
+		{"mfa enabled, not verified recently", false, true, http.StatusUnauthorized},
+		{"mfa enabled, verified recently", true, true, http.StatusNoContent},
+		{"mfa not enabled", false, false, http.StatusNoContent},
+	}
+
+	SetMFAStepUp(NewMFAStepUp(db, 5*time.Minute))
+	defer SetMFAStepUp(nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectQuery("SELECT COALESCE").
+				WithArgs("user-1", float64(300)).
+				WillReturnRows(sqlmock.NewRows([]string{"recent", "mfa_enabled"}).AddRow(tt.recent, tt.mfaEnabled))
+
+			w := httptest.NewRecorder()
+			stepUpRouter("user-1").ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/sessions/s1", nil))
+
+			if w.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", w.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusUnauthorized {
+				var body map[string]interface{}
+				if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+					t.Fatalf("invalid body: %v", err)
+				}
+				if body["code"] != MFARequiredCode {
+					t.Errorf("code = %v, want %s", body["code"], MFARequiredCode)
+				}
+			}
+		})
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestRequireMFAStepUp_Disabled(t *testing.T) {
+	SetMFAStepUp(nil)
+
+	w := httptest.NewRecorder()
+	stepUpRouter("user-1").ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/sessions/s1", nil))
+
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d when step-up is disabled", w.Code, http.StatusNoContent)
+	}
+}
@@ -152,6 +152,8 @@ func StructuredLoggerWithConfigFunc(config StructuredLoggerConfig) gin.HandlerFu
 	}
 	if config.SkipHealthCheck {
 		skipMap["/health"] = true
+		skipMap["/healthz"] = true
+		skipMap["/readyz"] = true
 		skipMap["/api/v1/health"] = true
 	}
 
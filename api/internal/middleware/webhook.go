@@ -37,6 +37,15 @@
 // 7. If mismatch: Request is invalid or tampered, reject with 401
 //
 // Signature Format:
+//   X-StreamSpace-Timestamp: <unix-seconds>
+//   X-StreamSpace-Signature: v1=<hex-encoded-hmac-sha256 of "<timestamp>.<body>">
+//   (the scheme of StreamSpace's own webhooks, see package webhooksig)
+//
+//   Requests whose timestamp is more than webhooksig.DefaultTolerance off are
+//   rejected, so a captured request can't be replayed.
+//
+//   Legacy senders may still use the body-only signature without replay
+//   protection:
 //   X-Webhook-Signature: <hex-encoded-hmac-sha256>
 //   Example: "a1b2c3d4e5f67890abcdef1234567890abcdef1234567890abcdef1234567890"
 //
@@ -55,8 +64,7 @@
 //
 //   // Generate signature for testing (sender side)
 //   payload := []byte(`{"event": "push", "repo": "streamspace"}`)
-//   signature := webhookAuth.Sign(payload)
-//   // Send as: curl -H "X-Webhook-Signature: $signature" -d "$payload" /api/webhooks
+//   req.Header = webhookAuth.SignHeaders(payload)
 //
 // Configuration:
 //   secret: Shared secret between sender and receiver (keep confidential!)
@@ -68,10 +76,13 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/webhooksig"
 )
 
 // WebhookAuth validates webhook requests using HMAC-SHA256 signatures
@@ -87,12 +98,14 @@ func NewWebhookAuth(secret string) *WebhookAuth {
 }
 
 // Middleware returns a Gin middleware that validates webhook signatures
-// Expects signature in X-Webhook-Signature header as hex-encoded HMAC-SHA256
+// Expects a timestamped X-StreamSpace-Signature, or the legacy
+// X-Webhook-Signature header as hex-encoded HMAC-SHA256 of the body
 func (w *WebhookAuth) Middleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get signature from header
 		signature := c.GetHeader("X-Webhook-Signature")
-		if signature == "" {
+		signed := c.GetHeader(webhooksig.SignatureHeader) != ""
+		if signature == "" && !signed {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"error": "Missing webhook signature",
 			})
@@ -113,6 +126,22 @@ func (w *WebhookAuth) Middleware() gin.HandlerFunc {
 		// Restore body for downstream handlers
 		c.Request.Body = io.NopCloser(bytes.NewBuffer(body))
 
+		if signed {
+			if err := webhooksig.Verify(string(w.secret), c.Request.Header, body, webhooksig.DefaultTolerance, time.Now()); err != nil {
+				message := "Invalid webhook signature"
+				if errors.Is(err, webhooksig.ErrExpiredTimestamp) {
+					message = "Webhook timestamp too old"
+				}
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"error": message,
+				})
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
 		// Compute HMAC
 		mac := hmac.New(sha256.New, w.secret)
 		mac.Write(body)
@@ -131,7 +160,15 @@ func (w *WebhookAuth) Middleware() gin.HandlerFunc {
 	}
 }
 
-// Sign generates an HMAC-SHA256 signature for the given payload
+// SignHeaders returns the timestamped signature headers for the given payload
+// This is a helper function for testing or generating signatures
+func (w *WebhookAuth) SignHeaders(payload []byte) http.Header {
+	header := http.Header{}
+	webhooksig.SetHeaders(header, string(w.secret), time.Now(), payload)
+	return header
+}
+
+// Sign generates a legacy HMAC-SHA256 signature for the given payload
 // This is a helper function for testing or generating signatures
 func (w *WebhookAuth) Sign(payload []byte) string {
 	mac := hmac.New(sha256.New, w.secret)
@@ -0,0 +1,58 @@
+// Package middleware provides HTTP middleware for the StreamSpace API.
+// This file tests webhook signature authentication.
+//
+// Tests validate:
+// - Timestamped signatures are accepted, and rejected once too old
+// - Legacy body-only signatures are still accepted
+// - Missing or wrong signatures get 401
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/streamspace/streamspace/api/internal/webhooksig"
+)
+
+func TestWebhookAuth_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	auth := NewWebhookAuth("whsec_test")
+	router := gin.New()
+	router.POST("/webhooks/repository/sync", auth.Middleware(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	payload := []byte(`{"repository_id":1}`)
+	stale := http.Header{}
+	webhooksig.SetHeaders(stale, "whsec_test", time.Now().Add(-time.Hour), payload)
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   int
+	}{
+		{"timestamped", auth.SignHeaders(payload), http.StatusNoContent},
+		{"replayed", stale, http.StatusUnauthorized},
+		{"legacy", http.Header{"X-Webhook-Signature": {auth.Sign(payload)}}, http.StatusNoContent},
+		{"wrong secret", NewWebhookAuth("whsec_other").SignHeaders(payload), http.StatusUnauthorized},
+		{"unsigned", http.Header{}, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/webhooks/repository/sync", bytes.NewReader(payload))
+			for key, values := range tt.header {
+				req.Header[key] = values
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Errorf("status = %d, want %d (%s)", w.Code, tt.want, w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+// Package middleware provides HTTP middleware for the StreamSpace API.
+// This file implements the strict origin policy for WebSocket upgrades.
+//
+// Purpose:
+// Every WebSocket upgrader checks the Origin header against an allowlist,
+// but out of the box they are fail-open: with no allowlist configured they
+// fall back to localhost development origins, and handshakes without an
+// Origin header (non-browser clients) are always accepted. A production
+// deployment that forgets to set its origins still accepts them.
+//
+// Strict mode closes that gap:
+//   - Localhost development defaults are disabled
+//   - Only origins on the configured allowlists are accepted (an empty
+//     allowlist rejects every browser connection)
+//   - Handshakes without an Origin header can be rejected too
+//
+// A release build that doesn't enable strict mode logs a warning at
+// startup.
+//
+// The enterprise WebSocket's allowlist is ALLOWED_WEBSOCKET_ORIGINS, a
+// comma-separated list of exact origins and wildcard subdomains
+// ("https://*.preview.example.com"). Deployments that predate it keep
+// working: while it is unset, the numbered ALLOWED_WEBSOCKET_ORIGIN_1..3 are
+// read instead.
+//
+// Configuration:
+//
+//	WEBSOCKET_ENFORCE_ORIGIN=strict     // Enable strict mode (default: off)
+//	WEBSOCKET_REJECT_EMPTY_ORIGIN=true  // Also reject handshakes without Origin (strict mode only)
+//	ALLOWED_WEBSOCKET_ORIGINS=https://app.example.com,https://*.preview.example.com
+//
+// Usage:
+//
+//	policy := middleware.WebSocketOriginPolicyFromEnv()
+//	if origin == "" {
+//	    return policy.AllowEmpty()
+//	}
+//	allowed := append(configured, policy.DevOrigins("http://localhost:3000")...)
+package middleware
+
+import (
+	"log"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// WebSocketOriginPolicy controls how WebSocket upgraders treat origins that
+// aren't explicitly configured.
+type WebSocketOriginPolicy struct {
+	// Strict disables localhost defaults (WEBSOCKET_ENFORCE_ORIGIN=strict).
+	Strict bool
+
+	// RejectEmpty rejects handshakes without an Origin header in strict
+	// mode (WEBSOCKET_REJECT_EMPTY_ORIGIN=true).
+	RejectEmpty bool
+}
+
+// WebSocketOriginPolicyFromEnv reads the policy from the environment.
+func WebSocketOriginPolicyFromEnv() WebSocketOriginPolicy {
+	policy := WebSocketOriginPolicy{
+		Strict: strings.EqualFold(strings.TrimSpace(os.Getenv("WEBSOCKET_ENFORCE_ORIGIN")), "strict"),
+	}
+	if v := os.Getenv("WEBSOCKET_REJECT_EMPTY_ORIGIN"); v != "" {
+		reject, err := strconv.ParseBool(v)
+		if err != nil {
+			log.Printf("Invalid WEBSOCKET_REJECT_EMPTY_ORIGIN %q, accepting handshakes without Origin", v)
+		}
+		policy.RejectEmpty = reject
+	}
+	return policy
+}
+
+// AllowEmpty reports whether handshakes without an Origin header are
+// accepted. Browsers always send one, so these come from non-browser
+// clients that can't be victims of cross-site WebSocket hijacking.
+func (p WebSocketOriginPolicy) AllowEmpty() bool {
+	return !(p.Strict && p.RejectEmpty)
+}
+
+// DevOrigins returns the given localhost development origins, or none in
+// strict mode.
+func (p WebSocketOriginPolicy) DevOrigins(origins ...string) []string {
+	if p.Strict {
+		return nil
+	}
+	return origins
+}
+
+// WarnWebSocketOriginPolicy logs the origin policy at startup, loudly if a
+// release build isn't strict.
+func WarnWebSocketOriginPolicy(release bool) {
+	policy := WebSocketOriginPolicyFromEnv()
+	switch {
+	case policy.Strict && policy.AllowEmpty():
+		log.Println("WebSocket origin policy: strict (handshakes without Origin accepted)")
+	case policy.Strict:
+		log.Println("WebSocket origin policy: strict (handshakes without Origin rejected)")
+	case release:
+		log.Println("WARNING: ==================================================================")
+		log.Println("WARNING: WebSocket origin checks are FAIL-OPEN in this release build:")
+		log.Println("WARNING: localhost origins and unconfigured endpoints are accepted.")
+		log.Println("WARNING: Set WEBSOCKET_ENFORCE_ORIGIN=strict and configure your origins.")
+		log.Println("WARNING: ==================================================================")
+	}
+}
+
+// WebSocketAllowedOriginsFromEnv reads the enterprise WebSocket origin
+// allowlist from ALLOWED_WEBSOCKET_ORIGINS, or from the legacy
+// ALLOWED_WEBSOCKET_ORIGIN_1..3 while it is unset. Blank entries are
+// dropped.
+func WebSocketAllowedOriginsFromEnv() []string {
+	var entries []string
+	if v, ok := os.LookupEnv("ALLOWED_WEBSOCKET_ORIGINS"); ok {
+		entries = strings.Split(v, ",")
+	} else {
+		entries = []string{
+			os.Getenv("ALLOWED_WEBSOCKET_ORIGIN_1"),
+			os.Getenv("ALLOWED_WEBSOCKET_ORIGIN_2"),
+			os.Getenv("ALLOWED_WEBSOCKET_ORIGIN_3"),
+		}
+	}
+
+	origins := []string{}
+	for _, entry := range entries {
+		if entry = strings.TrimSpace(entry); entry != "" {
+			origins = append(origins, entry)
+		}
+	}
+	return origins
+}
+
+// OriginAllowed reports whether origin matches an allowlist entry.
+//
+// Entries are exact origins ("https://app.example.com") or wildcard
+// subdomains ("https://*.preview.example.com"; without a scheme, http and
+// https both match). A wildcard matches any subdomain of its suffix, but
+// not the suffix itself, and not hosts that merely end with the same
+// characters ("evilpreview.example.com"). Ports must match exactly.
+// Comparison is case-insensitive.
+func OriginAllowed(origin string, allowed []string) bool {
+	origin = strings.ToLower(strings.TrimSpace(origin))
+	if origin == "" {
+		return false
+	}
+
+	for _, entry := range allowed {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case strings.Contains(entry, "*"):
+			if wildcardOriginMatches(origin, entry) {
+				return true
+			}
+		case entry == origin:
+			return true
+		}
+	}
+	return false
+}
+
+// wildcardOriginMatches matches a lowercase origin against a lowercase
+// wildcard entry such as "https://*.preview.example.com".
+func wildcardOriginMatches(origin, pattern string) bool {
+	scheme, hostPattern, hasScheme := strings.Cut(pattern, "://")
+	if !hasScheme {
+		scheme, hostPattern = "", pattern
+	}
+	// Only a leading "*." label is supported
+	suffix, ok := strings.CutPrefix(hostPattern, "*.")
+	if !ok || suffix == "" || strings.Contains(suffix, "*") {
+		return false
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" || u.User != nil || (u.Path != "" && u.Path != "/") {
+		return false
+	}
+	if hasScheme && u.Scheme != scheme {
+		return false
+	}
+	if !hasScheme && u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	// The subdomain must be a whole label (or several) in front of ".suffix"
+	subdomain, ok := strings.CutSuffix(u.Host, "."+suffix)
+	return ok && subdomain != "" && !strings.HasPrefix(subdomain, ".") && !strings.HasSuffix(subdomain, ".")
+}
@@ -0,0 +1,143 @@
+// Package middleware provides HTTP middleware for the StreamSpace API.
+// This file tests the strict WebSocket origin policy.
+//
+// Tests validate:
+// - Without strict mode, localhost defaults and empty origins are accepted
+// - Strict mode drops the localhost defaults
+// - Empty origins are only rejected in strict mode, and only when asked to
+// - The allowlist is read from ALLOWED_WEBSOCKET_ORIGINS, falling back to
+//   the numbered variables
+// - Wildcard entries match whole subdomain labels only
+package middleware
+
+import (
+	"os"
+	"testing"
+)
+
+func TestWebSocketOriginPolicy_Default(t *testing.T) {
+	t.Setenv("WEBSOCKET_ENFORCE_ORIGIN", "")
+	t.Setenv("WEBSOCKET_REJECT_EMPTY_ORIGIN", "true")
+
+	policy := WebSocketOriginPolicyFromEnv()
+	if policy.Strict {
+		t.Error("Expected strict mode to be off by default")
+	}
+	if !policy.AllowEmpty() {
+		t.Error("Expected empty origins to be accepted outside strict mode")
+	}
+	if got := policy.DevOrigins("http://localhost:3000"); len(got) != 1 {
+		t.Errorf("Expected the localhost default outside strict mode, got %v", got)
+	}
+}
+
+func TestWebSocketOriginPolicy_Strict(t *testing.T) {
+	t.Setenv("WEBSOCKET_ENFORCE_ORIGIN", "Strict")
+	t.Setenv("WEBSOCKET_REJECT_EMPTY_ORIGIN", "")
+
+	policy := WebSocketOriginPolicyFromEnv()
+	if !policy.Strict {
+		t.Fatal("Expected strict mode")
+	}
+	if got := policy.DevOrigins("http://localhost:3000"); len(got) != 0 {
+		t.Errorf("Expected no localhost defaults in strict mode, got %v", got)
+	}
+	if !policy.AllowEmpty() {
+		t.Error("Expected empty origins to be accepted unless WEBSOCKET_REJECT_EMPTY_ORIGIN is set")
+	}
+
+	t.Setenv("WEBSOCKET_REJECT_EMPTY_ORIGIN", "true")
+	if WebSocketOriginPolicyFromEnv().AllowEmpty() {
+		t.Error("Expected empty origins to be rejected")
+	}
+
+	t.Setenv("WEBSOCKET_REJECT_EMPTY_ORIGIN", "maybe")
+	if !WebSocketOriginPolicyFromEnv().AllowEmpty() {
+		t.Error("Expected an invalid WEBSOCKET_REJECT_EMPTY_ORIGIN to keep the default")
+	}
+}
+
+func TestWebSocketAllowedOriginsFromEnv(t *testing.T) {
+	t.Setenv("ALLOWED_WEBSOCKET_ORIGIN_1", "https://legacy.example.com")
+	t.Setenv("ALLOWED_WEBSOCKET_ORIGIN_2", "")
+	t.Setenv("ALLOWED_WEBSOCKET_ORIGIN_3", " https://admin.example.com ")
+
+	t.Setenv("ALLOWED_WEBSOCKET_ORIGINS", " https://a.example.com, ,https://*.preview.example.com,")
+	got := WebSocketAllowedOriginsFromEnv()
+	if len(got) != 2 || got[0] != "https://a.example.com" || got[1] != "https://*.preview.example.com" {
+		t.Errorf("Expected the comma-separated list, got %v", got)
+	}
+
+	// Set but empty means no configured origins, not the legacy ones
+	t.Setenv("ALLOWED_WEBSOCKET_ORIGINS", "")
+	if got := WebSocketAllowedOriginsFromEnv(); len(got) != 0 {
+		t.Errorf("Expected no origins, got %v", got)
+	}
+
+	os.Unsetenv("ALLOWED_WEBSOCKET_ORIGINS")
+	got = WebSocketAllowedOriginsFromEnv()
+	if len(got) != 2 || got[0] != "https://legacy.example.com" || got[1] != "https://admin.example.com" {
+		t.Errorf("Expected the numbered variables while unset, got %v", got)
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	allowed := []string{
+		"https://app.example.com",
+		"https://*.preview.example.com",
+		"*.dev.example.com",
+		"https://*.ports.example.com:8443",
+	}
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		// Exact entries
+		{"https://app.example.com", true},
+		{"HTTPS://App.Example.com", true},
+		{"http://app.example.com", false},
+		{"https://app.example.com.evil.com", false},
+		{"https://app.example.com:8443", false},
+
+		// Wildcards match subdomains at any depth
+		{"https://pr-42.preview.example.com", true},
+		{"https://a.b.preview.example.com", true},
+		{"https://PR-42.Preview.Example.com", true},
+
+		// ...but not the suffix itself or look-alike hosts
+		{"https://preview.example.com", false},
+		{"https://evilpreview.example.com", false},
+		{"https://.preview.example.com", false},
+		{"https://pr-42.preview.example.com.evil.com", false},
+		{"https://evil.com/.preview.example.com", false},
+		{"https://evil.com?.preview.example.com", false},
+		{"https://x@pr-42.preview.example.com", false},
+
+		// A wildcard with a scheme requires it; without one, http and https match
+		{"http://pr-42.preview.example.com", false},
+		{"http://feature.dev.example.com", true},
+		{"https://feature.dev.example.com", true},
+		{"ftp://feature.dev.example.com", false},
+
+		// Ports must match
+		{"https://pr-42.preview.example.com:8443", false},
+		{"https://pr-42.ports.example.com:8443", true},
+		{"https://pr-42.ports.example.com", false},
+
+		{"", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		if got := OriginAllowed(tt.origin, allowed); got != tt.want {
+			t.Errorf("OriginAllowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+
+	// Malformed wildcards match nothing
+	for _, pattern := range []string{"https://*", "https://*.", "https://a.*.example.com", "https://*example.com", "https://*.*.example.com"} {
+		if OriginAllowed("https://a.b.example.com", []string{pattern}) {
+			t.Errorf("Expected %q to match nothing", pattern)
+		}
+	}
+}
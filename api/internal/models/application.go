@@ -169,6 +169,13 @@ type UpdateGroupAccessRequest struct {
 	AccessLevel string `json:"accessLevel" binding:"required"`
 }
 
+// RemoveGroupsAccessRequest is the request to revoke several groups' access
+// to an application at once.
+type RemoveGroupsAccessRequest struct {
+	// GroupIDs are the groups to remove. Groups without access are skipped.
+	GroupIDs []string `json:"groupIds" binding:"required,min=1"`
+}
+
 // ApplicationListResponse is the response for listing applications.
 type ApplicationListResponse struct {
 	Applications []*InstalledApplication `json:"applications"`
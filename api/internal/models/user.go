@@ -296,7 +296,8 @@ type Group struct {
 // Group quotas work differently from user quotas:
 //   - Limits apply to the sum of all members' usage
 //   - Prevents one group from consuming all platform resources
-//   - Can be combined with individual user quotas (most restrictive wins)
+//   - Can be combined with individual user quotas (most restrictive wins,
+//     unless the quota is in grant mode, see GroupQuotaModeGrant)
 //
 // Example scenario:
 //   - Engineering group has quota: 100 sessions, 200Gi RAM
@@ -331,6 +332,10 @@ type GroupQuota struct {
 	// MaxStorage is the total storage allocation for the entire group.
 	MaxStorage string `json:"maxStorage" db:"max_storage"`
 
+	// QuotaMode is how the limits combine with the member's other limits:
+	// GroupQuotaModeRestrict (default) or GroupQuotaModeGrant.
+	QuotaMode string `json:"quotaMode" db:"quota_mode"`
+
 	// UsedSessions is the sum of all members' active sessions.
 	UsedSessions int `json:"usedSessions" db:"used_sessions"`
 
@@ -350,6 +355,22 @@ type GroupQuota struct {
 	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
 }
 
+// Group quota modes.
+//
+// A member's limits start from their user quota (or the platform defaults).
+// Restrict-mode groups are applied first and can only lower each limit.
+// Grant-mode groups are applied last and can only raise it, to the highest
+// value across the member's grant groups, so a "premium" group lifts a
+// member of a restricted base group. Only MaxSessions, MaxCPU, MaxMemory and
+// MaxStorage follow the mode.
+const (
+	// GroupQuotaModeRestrict lowers members' limits to the group's.
+	GroupQuotaModeRestrict = "restrict"
+
+	// GroupQuotaModeGrant raises members' limits to the group's.
+	GroupQuotaModeGrant = "grant"
+)
+
 // GroupMembership represents a user's membership in a group.
 //
 // Each membership defines:
@@ -499,6 +520,36 @@ type SetQuotaRequest struct {
 	MaxCPU      *string `json:"maxCpu,omitempty"`
 	MaxMemory   *string `json:"maxMemory,omitempty"`
 	MaxStorage  *string `json:"maxStorage,omitempty"`
+	// MaxPriority caps the session priority class: Low, Normal or High
+	MaxPriority *string `json:"maxPriority,omitempty" binding:"omitempty,oneof=Low Normal High"`
+	// TemplateSessionLimits caps concurrent sessions per template or category
+	TemplateSessionLimits *TemplateSessionLimits `json:"templateSessionLimits,omitempty"`
+	// MaxBandwidthPerDay caps the MiB port-forwarded to and from the user's
+	// sessions per day; 0 removes the cap
+	MaxBandwidthPerDay *int64 `json:"maxBandwidthPerDay,omitempty" binding:"omitempty,min=0"`
+	// MaxSessionHoursPerMonth caps the hours the user's sessions may run
+	// per calendar month (UTC); 0 removes the cap
+	MaxSessionHoursPerMonth *int64 `json:"maxSessionHoursPerMonth,omitempty" binding:"omitempty,min=0"`
+	// QuotaMode is restrict or grant (group quotas only, see GroupQuota)
+	QuotaMode *string `json:"quotaMode,omitempty" binding:"omitempty,oneof=restrict grant"`
+}
+
+// TemplateSessionLimits caps how many concurrent sessions of a template, or
+// of any template in a category, a user may run. They apply on top of
+// MaxSessions.
+//
+// Example (at most 1 GPU workstation, up to 5 browsers):
+//
+//	{
+//	  "templates": {"blender-gpu": 1},
+//	  "categories": {"Web Browsers": 5}
+//	}
+type TemplateSessionLimits struct {
+	// Templates maps template names to their session limit.
+	Templates map[string]int `json:"templates,omitempty"`
+
+	// Categories maps template categories to their session limit.
+	Categories map[string]int `json:"categories,omitempty"`
 }
 
 // LoginRequest represents a user login request.
@@ -64,7 +64,10 @@
 //   - Dynamic: Loaded from .so files, can be added without recompile
 package plugins
 
-import "fmt"
+import (
+	"fmt"
+	"sync"
+)
 
 // BasePlugin provides default no-op implementations for the PluginHandler interface.
 //
@@ -159,6 +162,15 @@ func (p *BasePlugin) OnSessionDeleted(ctx *PluginContext, session interface{}) e
 	return nil
 }
 
+// Session Before Hooks - Default no-op implementations
+
+// BeforeSessionCreate is called synchronously before a session is created.
+// Default: no-op. Override to veto (return an error) or adjust the request
+// (return a mutation). See session_hooks.go.
+func (p *BasePlugin) BeforeSessionCreate(ctx *PluginContext, req *SessionCreateRequest) (*SessionCreateMutation, error) {
+	return nil, nil
+}
+
 // User Event Hooks - Default no-op implementations
 
 // OnUserCreated is called when a new user account is created.
@@ -196,27 +208,59 @@ func (p *BasePlugin) OnUserLogout(ctx *PluginContext, user interface{}) error {
 // builtinPlugins stores plugins compiled into the binary.
 //
 // Built-in plugins are registered via init() functions and automatically
-// discovered by the plugin runtime at startup.
-var builtinPlugins = make(map[string]PluginHandler)
+// discovered by the plugin runtime at startup. Registration and lookup may
+// also happen at runtime (dynamic loading, hot-reload, enable/disable), so
+// all access goes through builtinPluginsMu.
+var (
+	builtinPlugins   = make(map[string]PluginHandler)
+	builtinPluginsMu sync.RWMutex
+)
 
 // RegisterBuiltinPlugin registers a plugin as built-in.
 //
-// This should be called from init() functions in plugin packages:
+// This is usually called from init() functions in plugin packages:
 //
 //	func init() {
 //	    plugins.RegisterBuiltinPlugin("slack", &SlackPlugin{})
 //	}
 //
-// Thread Safety: Not thread-safe. Should only be called during init.
+// Thread Safety: Safe to call concurrently, including after startup.
+// Registering an existing name replaces the previous plugin.
 func RegisterBuiltinPlugin(name string, plugin PluginHandler) {
+	builtinPluginsMu.Lock()
 	builtinPlugins[name] = plugin
+	builtinPluginsMu.Unlock()
+
 	fmt.Printf("[Plugin Registry] Registered built-in plugin: %s\n", name)
 }
 
+// UnregisterBuiltinPlugin removes a built-in plugin from the registry.
+//
+// Used by runtime plugin management to disable a built-in without a restart.
+// Returns false if no plugin was registered under the given name.
+//
+// Thread Safety: Safe to call concurrently.
+func UnregisterBuiltinPlugin(name string) bool {
+	builtinPluginsMu.Lock()
+	defer builtinPluginsMu.Unlock()
+
+	if _, exists := builtinPlugins[name]; !exists {
+		return false
+	}
+	delete(builtinPlugins, name)
+	return true
+}
+
 // GetBuiltinPlugin retrieves a built-in plugin by name.
 //
 // Returns nil if plugin not found.
+//
+// Thread Safety: Safe to call concurrently. Lookups take a read lock only,
+// so they don't block each other.
 func GetBuiltinPlugin(name string) PluginHandler {
+	builtinPluginsMu.RLock()
+	defer builtinPluginsMu.RUnlock()
+
 	return builtinPlugins[name]
 }
 
@@ -224,6 +268,9 @@ func GetBuiltinPlugin(name string) PluginHandler {
 //
 // Used by discovery system to enumerate available built-ins.
 func ListBuiltinPlugins() []string {
+	builtinPluginsMu.RLock()
+	defer builtinPluginsMu.RUnlock()
+
 	names := make([]string, 0, len(builtinPlugins))
 	for name := range builtinPlugins {
 		names = append(names, name)
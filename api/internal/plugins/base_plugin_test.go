@@ -0,0 +1,42 @@
+package plugins
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestBuiltinRegistry_Concurrent registers, looks up, lists and unregisters
+// built-in plugins from many goroutines. Run with -race.
+func TestBuiltinRegistry_Concurrent(t *testing.T) {
+	const workers = 16
+	const rounds = 50
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				name := fmt.Sprintf("race-test-%d-%d", w, i%5)
+				RegisterBuiltinPlugin(name, &BasePlugin{Name: name})
+				GetBuiltinPlugin(name)
+				ListBuiltinPlugins()
+				if i%2 == 1 {
+					UnregisterBuiltinPlugin(name)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	for w := 0; w < workers; w++ {
+		for i := 0; i < 5; i++ {
+			name := fmt.Sprintf("race-test-%d-%d", w, i)
+			UnregisterBuiltinPlugin(name)
+			assert.Nil(t, GetBuiltinPlugin(name))
+		}
+	}
+}
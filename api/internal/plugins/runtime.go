@@ -1010,6 +1010,19 @@ func (r *Runtime) EmitEvent(eventType string, data interface{}) {
 	}
 }
 
+// RunBeforeSessionCreate invokes the BeforeSessionCreate hook of every
+// enabled plugin, applying returned mutations to req in place.
+//
+// Unlike EmitEvent this is synchronous: the caller blocks until all hooks
+// have run. A non-nil error is a *PluginVetoError and session creation
+// must be aborted.
+func (r *Runtime) RunBeforeSessionCreate(req *SessionCreateRequest) error {
+	r.pluginsMux.RLock()
+	defer r.pluginsMux.RUnlock()
+
+	return runBeforeSessionCreate(r.plugins, req)
+}
+
 // GetPlugin retrieves a loaded plugin
 func (r *Runtime) GetPlugin(name string) (*LoadedPlugin, error) {
 	r.pluginsMux.RLock()
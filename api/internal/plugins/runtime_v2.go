@@ -906,6 +906,20 @@ func (r *RuntimeV2) EmitEvent(eventType string, data interface{}) {
 	}
 }
 
+// RunBeforeSessionCreate invokes the BeforeSessionCreate hook of every
+// enabled plugin, applying returned mutations to req in place.
+//
+// Hooks run synchronously in plugin name order. A non-nil error is a
+// *PluginVetoError and the caller must abort session creation.
+//
+// Thread Safety: Thread-safe via read lock.
+func (r *RuntimeV2) RunBeforeSessionCreate(req *SessionCreateRequest) error {
+	r.pluginsMux.RLock()
+	defer r.pluginsMux.RUnlock()
+
+	return runBeforeSessionCreate(r.plugins, req)
+}
+
 // GetPlugin retrieves a loaded plugin by name.
 //
 // Returns the LoadedPlugin struct containing:
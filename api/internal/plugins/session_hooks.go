@@ -0,0 +1,181 @@
+// Package plugins - session_hooks.go
+//
+// This file implements "before" hooks for the session lifecycle.
+//
+// The hooks in PluginHandler (OnSessionCreated, OnSessionDeleted, ...) are
+// notifications: they run asynchronously after the fact and their errors are
+// only logged. Before hooks are different - they run synchronously on the
+// request path and can change the outcome:
+//   - Return an error to veto the operation (the user sees the reason)
+//   - Return a mutation to adjust the request before it is applied
+//
+// Hooks are optional. Plugins that embed BasePlugin get a no-op
+// BeforeSessionCreate and only override it when they need to. The runtime
+// detects support with a type assertion, so plugins that implement
+// PluginHandler directly are unaffected.
+//
+// A plugin that panics vetoes the request: a crashed policy plugin must not
+// let through the sessions it exists to stop.
+//
+// Example - enforce a memory ceiling and label every session:
+//
+//	type PolicyPlugin struct {
+//	    plugins.BasePlugin
+//	}
+//
+//	func (p *PolicyPlugin) BeforeSessionCreate(ctx *plugins.PluginContext, req *plugins.SessionCreateRequest) (*plugins.SessionCreateMutation, error) {
+//	    if req.Template == "forbidden-app" {
+//	        return nil, fmt.Errorf("template %s is not allowed", req.Template)
+//	    }
+//	    return &plugins.SessionCreateMutation{
+//	        Memory: "4Gi",
+//	        Labels: map[string]string{"compliance.example.com/reviewed": "true"},
+//	    }, nil
+//	}
+package plugins
+
+import (
+	"fmt"
+	"log"
+	"sort"
+)
+
+// SessionCreateRequest is the view of a session create request that is
+// passed to BeforeSessionCreate hooks.
+//
+// Values are fully resolved (request > template defaults > system defaults)
+// and include mutations applied by plugins that ran earlier. Hooks must
+// treat it as read-only and return a SessionCreateMutation to change it.
+type SessionCreateRequest struct {
+	User               string
+	Template           string
+	Memory             string
+	CPU                string
+	PersistentHome     bool
+	IdleTimeout        string
+	MaxSessionDuration string
+	Tags               []string
+	// Labels are set on the Session resource
+	Labels map[string]string
+}
+
+// SessionCreateMutation describes changes a plugin wants applied to a
+// session create request. Empty fields leave the request unchanged; Tags
+// are appended and Labels are merged, replacing values of existing keys.
+type SessionCreateMutation struct {
+	Memory             string
+	CPU                string
+	IdleTimeout        string
+	MaxSessionDuration string
+	Tags               []string
+	Labels             map[string]string
+}
+
+// apply merges the mutation into the request.
+func (m *SessionCreateMutation) apply(req *SessionCreateRequest) {
+	if m.Memory != "" {
+		req.Memory = m.Memory
+	}
+	if m.CPU != "" {
+		req.CPU = m.CPU
+	}
+	if m.IdleTimeout != "" {
+		req.IdleTimeout = m.IdleTimeout
+	}
+	if m.MaxSessionDuration != "" {
+		req.MaxSessionDuration = m.MaxSessionDuration
+	}
+	req.Tags = append(req.Tags, m.Tags...)
+	if len(m.Labels) > 0 {
+		labels := make(map[string]string, len(req.Labels)+len(m.Labels))
+		for k, v := range req.Labels {
+			labels[k] = v
+		}
+		for k, v := range m.Labels {
+			labels[k] = v
+		}
+		req.Labels = labels
+	}
+}
+
+// SessionCreateInterceptor is implemented by plugins that want to inspect,
+// veto or modify session creation before it happens.
+//
+// BasePlugin provides a no-op implementation, so plugins that embed it
+// satisfy this interface automatically.
+type SessionCreateInterceptor interface {
+	BeforeSessionCreate(ctx *PluginContext, req *SessionCreateRequest) (*SessionCreateMutation, error)
+}
+
+// PluginVetoError is returned when a plugin rejects an operation from a
+// before hook. Reason is the error returned by the plugin.
+type PluginVetoError struct {
+	Plugin string
+	Reason error
+}
+
+// Error implements the error interface.
+func (e *PluginVetoError) Error() string {
+	return fmt.Sprintf("rejected by plugin %s: %v", e.Plugin, e.Reason)
+}
+
+// Unwrap returns the plugin's original error.
+func (e *PluginVetoError) Unwrap() error {
+	return e.Reason
+}
+
+// runBeforeSessionCreate invokes BeforeSessionCreate on every enabled plugin
+// that implements SessionCreateInterceptor.
+//
+// Plugins run sequentially in name order so the outcome is deterministic;
+// each plugin sees the mutations made by those before it. The first veto
+// stops the chain. A plugin that panics vetoes the request, so a crashed
+// policy plugin fails closed.
+//
+// Callers must hold the read lock protecting the plugins map.
+func runBeforeSessionCreate(loaded map[string]*LoadedPlugin, req *SessionCreateRequest) error {
+	names := make([]string, 0, len(loaded))
+	for name := range loaded {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		plugin := loaded[name]
+		if !plugin.Enabled {
+			continue
+		}
+		interceptor, ok := plugin.Handler.(SessionCreateInterceptor)
+		if !ok {
+			continue
+		}
+
+		var pluginCtx *PluginContext
+		if plugin.Instance != nil {
+			pluginCtx = plugin.Instance.Context
+		}
+
+		mutation, err := callBeforeSessionCreate(name, interceptor, pluginCtx, req)
+		if err != nil {
+			return &PluginVetoError{Plugin: name, Reason: err}
+		}
+		if mutation != nil {
+			mutation.apply(req)
+		}
+	}
+
+	return nil
+}
+
+// callBeforeSessionCreate runs a single hook, converting a panic into an
+// error so the request is rejected.
+func callBeforeSessionCreate(name string, interceptor SessionCreateInterceptor, ctx *PluginContext, req *SessionCreateRequest) (mutation *SessionCreateMutation, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("[Plugin Runtime] Plugin %s panicked in BeforeSessionCreate: %v", name, r)
+			mutation, err = nil, fmt.Errorf("plugin failed: %v", r)
+		}
+	}()
+
+	return interceptor.BeforeSessionCreate(ctx, req)
+}
@@ -0,0 +1,104 @@
+package plugins
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// hookPlugin is a plugin whose BeforeSessionCreate is supplied by the test.
+type hookPlugin struct {
+	BasePlugin
+	hook func(req *SessionCreateRequest) (*SessionCreateMutation, error)
+}
+
+func (p *hookPlugin) BeforeSessionCreate(ctx *PluginContext, req *SessionCreateRequest) (*SessionCreateMutation, error) {
+	return p.hook(req)
+}
+
+func loadedHooks(hooks map[string]func(req *SessionCreateRequest) (*SessionCreateMutation, error)) map[string]*LoadedPlugin {
+	loaded := make(map[string]*LoadedPlugin, len(hooks))
+	for name, hook := range hooks {
+		loaded[name] = &LoadedPlugin{Name: name, Enabled: true, Handler: &hookPlugin{hook: hook}}
+	}
+	return loaded
+}
+
+func TestRunBeforeSessionCreate_AppliesMutationsInNameOrder(t *testing.T) {
+	loaded := loadedHooks(map[string]func(req *SessionCreateRequest) (*SessionCreateMutation, error){
+		"a-limits": func(req *SessionCreateRequest) (*SessionCreateMutation, error) {
+			return &SessionCreateMutation{Memory: "4Gi", Tags: []string{"limited"}}, nil
+		},
+		"b-compliance": func(req *SessionCreateRequest) (*SessionCreateMutation, error) {
+			// Sees the mutation of the plugin before it
+			assert.Equal(t, "4Gi", req.Memory)
+			return &SessionCreateMutation{Labels: map[string]string{"compliance.example.com/reviewed": "true"}}, nil
+		},
+	})
+
+	req := &SessionCreateRequest{
+		User:     "alice",
+		Template: "firefox",
+		Memory:   "8Gi",
+		CPU:      "2",
+		Tags:     []string{"dev"},
+		Labels:   map[string]string{"team": "web"},
+	}
+	assert.NoError(t, runBeforeSessionCreate(loaded, req))
+
+	assert.Equal(t, "4Gi", req.Memory)
+	assert.Equal(t, "2", req.CPU)
+	assert.Equal(t, []string{"dev", "limited"}, req.Tags)
+	assert.Equal(t, map[string]string{"team": "web", "compliance.example.com/reviewed": "true"}, req.Labels)
+}
+
+func TestRunBeforeSessionCreate_Veto(t *testing.T) {
+	called := false
+	loaded := loadedHooks(map[string]func(req *SessionCreateRequest) (*SessionCreateMutation, error){
+		"a-policy": func(req *SessionCreateRequest) (*SessionCreateMutation, error) {
+			return nil, errors.New("template firefox is not allowed")
+		},
+		"b-other": func(req *SessionCreateRequest) (*SessionCreateMutation, error) {
+			called = true
+			return nil, nil
+		},
+	})
+
+	err := runBeforeSessionCreate(loaded, &SessionCreateRequest{Template: "firefox"})
+
+	var veto *PluginVetoError
+	assert.True(t, errors.As(err, &veto))
+	assert.Equal(t, "a-policy", veto.Plugin)
+	assert.Contains(t, err.Error(), "template firefox is not allowed")
+	assert.False(t, called, "the first veto stops the chain")
+}
+
+func TestRunBeforeSessionCreate_PanicRejects(t *testing.T) {
+	loaded := loadedHooks(map[string]func(req *SessionCreateRequest) (*SessionCreateMutation, error){
+		"policy": func(req *SessionCreateRequest) (*SessionCreateMutation, error) {
+			panic("nil map")
+		},
+	})
+
+	err := runBeforeSessionCreate(loaded, &SessionCreateRequest{Template: "firefox"})
+
+	var veto *PluginVetoError
+	assert.True(t, errors.As(err, &veto))
+	assert.Equal(t, "policy", veto.Plugin)
+}
+
+func TestRunBeforeSessionCreate_SkipsDisabledPlugins(t *testing.T) {
+	loaded := loadedHooks(map[string]func(req *SessionCreateRequest) (*SessionCreateMutation, error){
+		"policy": func(req *SessionCreateRequest) (*SessionCreateMutation, error) {
+			return nil, errors.New("rejected")
+		},
+	})
+	loaded["policy"].Enabled = false
+	// Plugins without the hook use BasePlugin's no-op
+	loaded["plain"] = &LoadedPlugin{Name: "plain", Enabled: true, Handler: &BasePlugin{}}
+
+	req := &SessionCreateRequest{Memory: "2Gi"}
+	assert.NoError(t, runBeforeSessionCreate(loaded, req))
+	assert.Equal(t, "2Gi", req.Memory)
+}
@@ -0,0 +1,377 @@
+// Package prewarm wakes hibernated sessions shortly before their users
+// usually start them.
+//
+// Regular users start the same template at about the same time every
+// workday, and wait for a cold start every morning. The Predictor learns,
+// per template and weekday, when a user starts working: the first start of
+// the day (a session created, or woken by the user) over the last
+// PREWARM_LOOKBACK. A weekday with starts on at least PREWARM_MIN_DAYS days
+// yields a prediction at the median first-start time, in the user's
+// timezone (ui.timezone preference, else UTC).
+//
+// The Prewarmer wakes the user's hibernated session of the template
+// PREWARM_LEAD before a predicted start. Pre-warming is bounded:
+//   - it is opt-in per user, and templates can be excluded
+//   - at most one pre-warm per prediction and day
+//   - the wake goes through the session quota check and is skipped when
+//     the user is at their limit
+//   - a pre-warm the user doesn't connect to within PREWARM_USE_WINDOW of
+//     the predicted time is a miss; after PREWARM_MAX_MISSES consecutive
+//     misses the template's prediction is suspended until the user saves
+//     their pre-warm settings again
+//
+// Sessions are only woken, never created: users without a hibernated session
+// of the template get the template's warm pool (if any) as usual.
+//
+// Configuration:
+//   - PREWARM_CHECK_INTERVAL: How often predictions are checked (default 1m)
+//   - PREWARM_LEAD: How long before the predicted start to wake (default 10m)
+//   - PREWARM_LOOKBACK: History predictions are learned from (default 28d)
+//   - PREWARM_MIN_DAYS: Starts on a weekday needed for a prediction (default 3)
+//   - PREWARM_USE_WINDOW: Time after the predicted start to connect (default 30m)
+//   - PREWARM_MAX_MISSES: Unused pre-warms before suspending (default 2)
+package prewarm
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/streamspace/streamspace/api/internal/db"
+)
+
+// Pre-warm outcomes (session_prewarms.outcome).
+const (
+	OutcomePending = "pending"
+	OutcomeUsed    = "used"
+	OutcomeUnused  = "unused"
+	OutcomeSkipped = "skipped"
+)
+
+// Config controls predictions and pre-warming.
+type Config struct {
+	CheckInterval time.Duration
+	Lead          time.Duration
+	Lookback      time.Duration
+	MinDays       int
+	UseWindow     time.Duration
+	MaxMisses     int
+}
+
+// ConfigFromEnv reads the pre-warm configuration from the environment.
+func ConfigFromEnv() Config {
+	return Config{
+		CheckInterval: envDuration("PREWARM_CHECK_INTERVAL", time.Minute),
+		Lead:          envDuration("PREWARM_LEAD", 10*time.Minute),
+		Lookback:      envDuration("PREWARM_LOOKBACK", 28*24*time.Hour),
+		MinDays:       envInt("PREWARM_MIN_DAYS", 3),
+		UseWindow:     envDuration("PREWARM_USE_WINDOW", 30*time.Minute),
+		MaxMisses:     envInt("PREWARM_MAX_MISSES", 2),
+	}
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	if len(v) > 1 && v[len(v)-1] == 'd' {
+		if n, err := strconv.Atoi(v[:len(v)-1]); err == nil && n > 0 {
+			return time.Duration(n) * 24 * time.Hour
+		}
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s %q, using %s", name, v, def)
+		return def
+	}
+	return d
+}
+
+func envInt(name string, def int) int {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n <= 0 {
+		log.Printf("Invalid %s %q, using %d", name, v, def)
+		return def
+	}
+	return n
+}
+
+// Start is a user starting a session of a template.
+type Start struct {
+	TemplateName string
+	At           time.Time
+}
+
+// Prediction is when a user usually starts a template on a weekday.
+type Prediction struct {
+	TemplateName string       `json:"templateName"`
+	Weekday      time.Weekday `json:"-"`
+	Day          string       `json:"weekday"`
+	StartTime    string       `json:"startTime"` // HH:MM in Timezone
+	Timezone     string       `json:"timezone"`
+	Days         int          `json:"days"` // Days the prediction is learned from
+
+	// Disabled is set when the user excluded the template
+	Disabled bool `json:"disabled"`
+	// Suspended is set after too many unused pre-warms
+	Suspended bool `json:"suspended"`
+
+	// minute is StartTime as minutes after midnight
+	minute int
+}
+
+// At returns the predicted start on the day of t, in the prediction's
+// timezone.
+func (p Prediction) At(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, p.minute/60, p.minute%60, 0, 0, t.Location())
+}
+
+// Predict learns predictions from starts: for each template and weekday,
+// the median first start of the day, if the template was started on at
+// least minDays such days. Starts are interpreted in loc.
+func Predict(starts []Start, loc *time.Location, minDays int) []Prediction {
+	type dayKey struct {
+		template string
+		date     string
+	}
+	firstOfDay := make(map[dayKey]time.Time)
+	for _, start := range starts {
+		at := start.At.In(loc)
+		key := dayKey{start.TemplateName, at.Format("2006-01-02")}
+		if first, ok := firstOfDay[key]; !ok || at.Before(first) {
+			firstOfDay[key] = at
+		}
+	}
+
+	type slotKey struct {
+		template string
+		weekday  time.Weekday
+	}
+	minutes := make(map[slotKey][]int)
+	for key, at := range firstOfDay {
+		slot := slotKey{key.template, at.Weekday()}
+		minutes[slot] = append(minutes[slot], at.Hour()*60+at.Minute())
+	}
+
+	var predictions []Prediction
+	for slot, mins := range minutes {
+		if len(mins) < minDays {
+			continue
+		}
+		sort.Ints(mins)
+		median := mins[len(mins)/2]
+		if len(mins)%2 == 0 {
+			median = (mins[len(mins)/2-1] + mins[len(mins)/2]) / 2
+		}
+		predictions = append(predictions, Prediction{
+			TemplateName: slot.template,
+			Weekday:      slot.weekday,
+			Day:          slot.weekday.String(),
+			StartTime:    fmt.Sprintf("%02d:%02d", median/60, median%60),
+			Timezone:     loc.String(),
+			Days:         len(mins),
+			minute:       median,
+		})
+	}
+
+	sort.Slice(predictions, func(i, j int) bool {
+		if predictions[i].TemplateName != predictions[j].TemplateName {
+			return predictions[i].TemplateName < predictions[j].TemplateName
+		}
+		return predictions[i].Weekday < predictions[j].Weekday
+	})
+	return predictions
+}
+
+// Settings are a user's pre-warm settings.
+type Settings struct {
+	Enabled           bool     `json:"enabled"`
+	DisabledTemplates []string `json:"disabledTemplates"`
+
+	// updatedAt is when the settings were saved; misses before don't count
+	updatedAt time.Time
+}
+
+// excludes reports whether the user excluded the template.
+func (s *Settings) excludes(templateName string) bool {
+	for _, name := range s.DisabledTemplates {
+		if name == templateName {
+			return true
+		}
+	}
+	return false
+}
+
+// Forecast is a user's settings and predictions.
+type Forecast struct {
+	Settings
+	Predictions []Prediction `json:"predictions"`
+}
+
+// Predictor loads history and settings and predicts start times.
+type Predictor struct {
+	db     *db.Database
+	config Config
+}
+
+// NewPredictor creates a predictor.
+func NewPredictor(database *db.Database, config Config) *Predictor {
+	return &Predictor{db: database, config: config}
+}
+
+// Settings returns the user's settings; pre-warming is off by default.
+func (p *Predictor) Settings(ctx context.Context, userID string) (*Settings, error) {
+	settings := &Settings{DisabledTemplates: []string{}}
+	var disabled string
+	err := p.db.DB().QueryRowContext(ctx, `
+		SELECT enabled, COALESCE(disabled_templates::text, '[]'), updated_at
+		FROM session_prewarm_settings WHERE user_id = $1
+	`, userID).Scan(&settings.Enabled, &disabled, &settings.updatedAt)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(disabled), &settings.DisabledTemplates); err != nil {
+		return nil, fmt.Errorf("invalid disabled templates: %w", err)
+	}
+	return settings, nil
+}
+
+// SetSettings stores the user's settings.
+func (p *Predictor) SetSettings(ctx context.Context, userID string, settings *Settings) error {
+	if settings.DisabledTemplates == nil {
+		settings.DisabledTemplates = []string{}
+	}
+	disabled, err := json.Marshal(settings.DisabledTemplates)
+	if err != nil {
+		return err
+	}
+	_, err = p.db.DB().ExecContext(ctx, `
+		INSERT INTO session_prewarm_settings (user_id, enabled, disabled_templates, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id) DO UPDATE
+		SET enabled = $2, disabled_templates = $3, updated_at = $4
+	`, userID, settings.Enabled, string(disabled), time.Now())
+	return err
+}
+
+// location returns the user's timezone preference, else UTC.
+func (p *Predictor) location(ctx context.Context, userID string) *time.Location {
+	var name sql.NullString
+	err := p.db.DB().QueryRowContext(ctx, `
+		SELECT preferences->'ui'->>'timezone' FROM user_preferences WHERE user_id = $1
+	`, userID).Scan(&name)
+	if err != nil || !name.Valid || name.String == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(name.String)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// starts returns the user's session starts since the given time: sessions
+// they created and sessions they woke (audited state changes to running
+// with the user as actor, so pre-warms don't teach themselves).
+func (p *Predictor) starts(ctx context.Context, userID string, since time.Time) ([]Start, error) {
+	rows, err := p.db.DB().QueryContext(ctx, `
+		SELECT template_name, created_at FROM sessions
+		WHERE user_id = $1 AND created_at >= $2 AND template_name IS NOT NULL
+		UNION ALL
+		SELECT s.template_name, a.timestamp
+		FROM audit_log a JOIN sessions s ON s.id = a.resource_id
+		WHERE a.action = 'session.state_change' AND a.user_id = $1 AND a.timestamp >= $2
+		  AND a.changes->'state'->>'new' = 'running' AND s.template_name IS NOT NULL
+	`, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var starts []Start
+	for rows.Next() {
+		var start Start
+		if err := rows.Scan(&start.TemplateName, &start.At); err != nil {
+			return nil, err
+		}
+		starts = append(starts, start)
+	}
+	return starts, rows.Err()
+}
+
+// misses returns the number of consecutive unused pre-warms of the
+// template since the user last used one or saved their settings.
+func (p *Predictor) misses(ctx context.Context, userID, templateName string, since time.Time) (int, error) {
+	rows, err := p.db.DB().QueryContext(ctx, `
+		SELECT outcome FROM session_prewarms
+		WHERE user_id = $1 AND template_name = $2 AND outcome IN ($3, $4) AND predicted_at > $5
+		ORDER BY predicted_at DESC
+		LIMIT $6
+	`, userID, templateName, OutcomeUsed, OutcomeUnused, since, p.config.MaxMisses)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	misses := 0
+	for rows.Next() {
+		var outcome string
+		if err := rows.Scan(&outcome); err != nil {
+			return 0, err
+		}
+		if outcome != OutcomeUnused {
+			break
+		}
+		misses++
+	}
+	return misses, rows.Err()
+}
+
+// Forecast returns the user's settings and predictions, marking excluded
+// and suspended ones.
+func (p *Predictor) Forecast(ctx context.Context, userID string, now time.Time) (*Forecast, error) {
+	settings, err := p.Settings(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	starts, err := p.starts(ctx, userID, now.Add(-p.config.Lookback))
+	if err != nil {
+		return nil, err
+	}
+	predictions := Predict(starts, p.location(ctx, userID), p.config.MinDays)
+
+	suspended := make(map[string]bool)
+	for i := range predictions {
+		name := predictions[i].TemplateName
+		if _, checked := suspended[name]; !checked {
+			misses, err := p.misses(ctx, userID, name, settings.updatedAt)
+			if err != nil {
+				return nil, err
+			}
+			suspended[name] = misses >= p.config.MaxMisses
+		}
+		predictions[i].Disabled = settings.excludes(name)
+		predictions[i].Suspended = suspended[name]
+	}
+
+	if predictions == nil {
+		predictions = []Prediction{}
+	}
+	return &Forecast{Settings: *settings, Predictions: predictions}, nil
+}
@@ -0,0 +1,189 @@
+package prewarm
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testConfig = Config{
+	CheckInterval: time.Minute,
+	Lead:          10 * time.Minute,
+	Lookback:      28 * 24 * time.Hour,
+	MinDays:       3,
+	UseWindow:     30 * time.Minute,
+	MaxMisses:     2,
+}
+
+func utc(month time.Month, day, hour, minute int) time.Time {
+	return time.Date(2026, month, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestPredict(t *testing.T) {
+	starts := []Start{
+		// Mondays: first starts 08:50, 09:00, 09:20; later starts don't count
+		{TemplateName: "vscode", At: utc(time.September, 21, 8, 50)},
+		{TemplateName: "vscode", At: utc(time.September, 28, 9, 0)},
+		{TemplateName: "vscode", At: utc(time.September, 28, 14, 0)},
+		{TemplateName: "vscode", At: utc(time.October, 5, 9, 20)},
+		// Tuesdays: only two days, below the threshold
+		{TemplateName: "vscode", At: utc(time.September, 22, 9, 0)},
+		{TemplateName: "vscode", At: utc(time.September, 29, 9, 0)},
+	}
+
+	predictions := Predict(starts, time.UTC, 3)
+	require.Len(t, predictions, 1)
+	assert.Equal(t, "vscode", predictions[0].TemplateName)
+	assert.Equal(t, time.Monday, predictions[0].Weekday)
+	assert.Equal(t, "09:00", predictions[0].StartTime)
+	assert.Equal(t, 3, predictions[0].Days)
+	assert.Equal(t, utc(time.October, 12, 9, 0), predictions[0].At(utc(time.October, 12, 3, 0)))
+}
+
+func TestPredict_Timezone(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	// 13:00 UTC is 09:00 in New York (EDT); 03:00 UTC Tuesday is Monday evening there
+	starts := []Start{
+		{TemplateName: "gimp", At: utc(time.September, 21, 13, 0)},
+		{TemplateName: "gimp", At: utc(time.September, 28, 13, 0)},
+		{TemplateName: "gimp", At: utc(time.October, 6, 3, 0)},
+		{TemplateName: "gimp", At: utc(time.October, 12, 13, 30)},
+	}
+
+	predictions := Predict(starts, loc, 3)
+	require.Len(t, predictions, 1)
+	assert.Equal(t, time.Monday, predictions[0].Weekday)
+	assert.Equal(t, "America/New_York", predictions[0].Timezone)
+	// Median of 09:00, 09:00, 09:30 and 23:00 (even count: mean of the middle two)
+	assert.Equal(t, "09:15", predictions[0].StartTime)
+	assert.Equal(t, 4, predictions[0].Days)
+}
+
+// expectForecast expects the queries of a forecast of vscode on Mondays at
+// 09:00 UTC with the given number of recent misses.
+func expectForecast(mock sqlmock.Sqlmock, misses int) {
+	mock.ExpectQuery("SELECT enabled").
+		WithArgs("user1").
+		WillReturnRows(sqlmock.NewRows([]string{"enabled", "disabled_templates", "updated_at"}).
+			AddRow(true, `[]`, utc(time.September, 1, 0, 0)))
+
+	starts := sqlmock.NewRows([]string{"template_name", "created_at"})
+	for _, day := range []int{21, 28} {
+		starts.AddRow("vscode", utc(time.September, day, 9, 0))
+	}
+	starts.AddRow("vscode", utc(time.October, 5, 9, 0))
+	mock.ExpectQuery("SELECT template_name, created_at FROM sessions").WillReturnRows(starts)
+
+	mock.ExpectQuery("SELECT preferences").
+		WithArgs("user1").
+		WillReturnRows(sqlmock.NewRows([]string{"timezone"}))
+
+	outcomes := sqlmock.NewRows([]string{"outcome"})
+	for i := 0; i < misses; i++ {
+		outcomes.AddRow(OutcomeUnused)
+	}
+	mock.ExpectQuery("SELECT outcome FROM session_prewarms").WillReturnRows(outcomes)
+}
+
+func TestForecast_SuspendedAfterMisses(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	predictor := NewPredictor(db.NewDatabaseForTesting(sqlDB), testConfig)
+	expectForecast(mock, 2)
+
+	forecast, err := predictor.Forecast(context.Background(), "user1", utc(time.October, 12, 8, 0))
+	require.NoError(t, err)
+	assert.True(t, forecast.Enabled)
+	require.Len(t, forecast.Predictions, 1)
+	assert.True(t, forecast.Predictions[0].Suspended)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunOnce(t *testing.T) {
+	// Monday 08:55 UTC: five minutes before the predicted start
+	now := utc(time.October, 12, 8, 55)
+	predictedAt := utc(time.October, 12, 9, 0)
+
+	tests := []struct {
+		name    string
+		wakeErr error
+		outcome string
+	}{
+		{name: "woken", outcome: OutcomePending},
+		{name: "quota exceeded", wakeErr: errors.New("quota exceeded"), outcome: OutcomeSkipped},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New()
+			require.NoError(t, err)
+			defer sqlDB.Close()
+
+			database := db.NewDatabaseForTesting(sqlDB)
+			prewarmer := NewPrewarmer(database, NewPredictor(database, testConfig))
+
+			var woken *Candidate
+			prewarmer.SetWaker(func(ctx context.Context, candidate *Candidate) error {
+				woken = candidate
+				return tt.wakeErr
+			})
+
+			mock.ExpectExec("UPDATE session_prewarms").
+				WithArgs(OutcomeUsed, OutcomeUnused, OutcomePending, now.Add(-30*time.Minute)).
+				WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectQuery("SELECT user_id FROM session_prewarm_settings").
+				WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user1"))
+			expectForecast(mock, 0)
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT 1 FROM session_prewarms")).
+				WithArgs("user1", "vscode", predictedAt).
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			mock.ExpectQuery(regexp.QuoteMeta("SELECT 1 FROM sessions")).
+				WithArgs("user1", "vscode").
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+			mock.ExpectQuery("SELECT id, cpu, memory FROM sessions").
+				WithArgs("user1", "vscode").
+				WillReturnRows(sqlmock.NewRows([]string{"id", "cpu", "memory"}).AddRow("user1-vscode-abc", "1000m", "2Gi"))
+			mock.ExpectExec("INSERT INTO session_prewarms").
+				WithArgs("user1", "vscode", "user1-vscode-abc", predictedAt, now, tt.outcome).
+				WillReturnResult(sqlmock.NewResult(1, 1))
+
+			require.NoError(t, prewarmer.RunOnce(context.Background(), now))
+			require.NotNil(t, woken)
+			assert.Equal(t, &Candidate{SessionID: "user1-vscode-abc", UserID: "user1", TemplateName: "vscode", CPU: "1000m", Memory: "2Gi"}, woken)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestRunOnce_OutsideLead(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	database := db.NewDatabaseForTesting(sqlDB)
+	prewarmer := NewPrewarmer(database, NewPredictor(database, testConfig))
+	prewarmer.SetWaker(func(ctx context.Context, candidate *Candidate) error {
+		t.Fatalf("unexpected wake of %s", candidate.SessionID)
+		return nil
+	})
+
+	// 08:30 is before the lead time of the 09:00 prediction
+	mock.ExpectExec("UPDATE session_prewarms").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT user_id FROM session_prewarm_settings").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("user1"))
+	expectForecast(mock, 0)
+
+	require.NoError(t, prewarmer.RunOnce(context.Background(), utc(time.October, 12, 8, 30)))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,220 @@
+package prewarm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/streamspace/streamspace/api/internal/db"
+)
+
+// Candidate is a hibernated session to pre-warm.
+type Candidate struct {
+	SessionID    string
+	UserID       string
+	TemplateName string
+	CPU          string
+	Memory       string
+}
+
+// Waker wakes a session. It returns an error when the session can't be
+// woken, e.g. because the user is at their quota.
+type Waker func(ctx context.Context, candidate *Candidate) error
+
+// Prewarmer wakes sessions ahead of their predicted starts.
+type Prewarmer struct {
+	predictor *Predictor
+	db        *db.Database
+	cfg       Config
+	waker     Waker
+}
+
+// NewPrewarmer creates a prewarmer acting on the predictor's predictions.
+func NewPrewarmer(database *db.Database, predictor *Predictor) *Prewarmer {
+	return &Prewarmer{
+		predictor: predictor,
+		db:        database,
+		cfg:       predictor.config,
+	}
+}
+
+// SetWaker sets the function used to wake sessions. Without one, nothing
+// is pre-warmed.
+func (p *Prewarmer) SetWaker(waker Waker) {
+	p.waker = waker
+}
+
+// Start checks predictions every CheckInterval until ctx is cancelled.
+func (p *Prewarmer) Start(ctx context.Context) {
+	log.Printf("Starting session pre-warmer (interval %s, lead %s, max misses %d)",
+		p.cfg.CheckInterval, p.cfg.Lead, p.cfg.MaxMisses)
+
+	ticker := time.NewTicker(p.cfg.CheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := p.RunOnce(ctx, now); err != nil {
+				log.Printf("Session pre-warm check failed: %v", err)
+			}
+		}
+	}
+}
+
+// RunOnce records the outcome of pre-warms whose use window has passed and
+// pre-warms sessions whose predicted start is within the lead time.
+func (p *Prewarmer) RunOnce(ctx context.Context, now time.Time) error {
+	if err := p.resolve(ctx, now); err != nil {
+		return fmt.Errorf("failed to resolve pre-warm outcomes: %w", err)
+	}
+	if p.waker == nil {
+		return nil
+	}
+
+	users, err := p.enabledUsers(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list pre-warm users: %w", err)
+	}
+	for _, userID := range users {
+		if err := p.prewarmUser(ctx, userID, now); err != nil {
+			log.Printf("Failed to pre-warm sessions of user %s: %v", userID, err)
+		}
+	}
+	return nil
+}
+
+// resolve marks pending pre-warms as used if the session was connected to
+// since it was woken, or unused once the use window has passed.
+func (p *Prewarmer) resolve(ctx context.Context, now time.Time) error {
+	_, err := p.db.DB().ExecContext(ctx, `
+		UPDATE session_prewarms pw
+		SET outcome = CASE
+			WHEN s.last_connection IS NOT NULL AND s.last_connection >= pw.prewarmed_at THEN $1
+			ELSE $2
+		END
+		FROM sessions s
+		WHERE s.id = pw.session_id AND pw.outcome = $3 AND pw.predicted_at <= $4
+	`, OutcomeUsed, OutcomeUnused, OutcomePending, now.Add(-p.cfg.UseWindow))
+	return err
+}
+
+// enabledUsers returns the users who opted in to pre-warming.
+func (p *Prewarmer) enabledUsers(ctx context.Context) ([]string, error) {
+	rows, err := p.db.DB().QueryContext(ctx, `
+		SELECT user_id FROM session_prewarm_settings WHERE enabled = true
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []string
+	for rows.Next() {
+		var userID string
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		users = append(users, userID)
+	}
+	return users, rows.Err()
+}
+
+// prewarmUser pre-warms the user's sessions whose predicted start today is
+// within the lead time.
+func (p *Prewarmer) prewarmUser(ctx context.Context, userID string, now time.Time) error {
+	forecast, err := p.predictor.Forecast(ctx, userID, now)
+	if err != nil {
+		return err
+	}
+
+	for _, prediction := range forecast.Predictions {
+		if prediction.Disabled || prediction.Suspended {
+			continue
+		}
+		loc, err := time.LoadLocation(prediction.Timezone)
+		if err != nil {
+			loc = time.UTC
+		}
+		local := now.In(loc)
+		if local.Weekday() != prediction.Weekday {
+			continue
+		}
+		at := prediction.At(local)
+		if local.Before(at.Add(-p.cfg.Lead)) || !local.Before(at) {
+			continue
+		}
+
+		if err := p.prewarm(ctx, userID, prediction.TemplateName, at, now); err != nil {
+			log.Printf("Failed to pre-warm %s for user %s: %v", prediction.TemplateName, userID, err)
+		}
+	}
+	return nil
+}
+
+// prewarm wakes the user's hibernated session of the template for the
+// start predicted at, once.
+func (p *Prewarmer) prewarm(ctx context.Context, userID, templateName string, at, now time.Time) error {
+	var done bool
+	if err := p.db.DB().QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM session_prewarms
+			WHERE user_id = $1 AND template_name = $2 AND predicted_at = $3
+		)
+	`, userID, templateName, at.UTC()).Scan(&done); err != nil {
+		return err
+	}
+	if done {
+		return nil
+	}
+
+	// The user already started the template themselves
+	var running bool
+	if err := p.db.DB().QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM sessions
+			WHERE user_id = $1 AND template_name = $2 AND state IN ('running', 'pending')
+		)
+	`, userID, templateName).Scan(&running); err != nil {
+		return err
+	}
+	if running {
+		return nil
+	}
+
+	candidate := &Candidate{UserID: userID, TemplateName: templateName}
+	var cpu, memory sql.NullString
+	err := p.db.DB().QueryRowContext(ctx, `
+		SELECT id, cpu, memory FROM sessions
+		WHERE user_id = $1 AND template_name = $2 AND state = 'hibernated'
+		ORDER BY last_connection DESC NULLS LAST
+		LIMIT 1
+	`, userID, templateName).Scan(&candidate.SessionID, &cpu, &memory)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	candidate.CPU = cpu.String
+	candidate.Memory = memory.String
+
+	outcome := OutcomePending
+	if err := p.waker(ctx, candidate); err != nil {
+		log.Printf("Skipped pre-warm of session %s: %v", candidate.SessionID, err)
+		outcome = OutcomeSkipped
+	} else {
+		log.Printf("Pre-warmed session %s of user %s for %s", candidate.SessionID, userID, at.Format(time.RFC3339))
+	}
+
+	_, err = p.db.DB().ExecContext(ctx, `
+		INSERT INTO session_prewarms (user_id, template_name, session_id, predicted_at, prewarmed_at, outcome)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id, template_name, predicted_at) DO NOTHING
+	`, userID, templateName, candidate.SessionID, at.UTC(), now, outcome)
+	return err
+}
@@ -0,0 +1,62 @@
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// GetMaxBandwidthPerDay returns the MiB a user's sessions may transfer
+// through port-forward tunnels per day, or 0 if unlimited.
+//
+// A max_bandwidth_per_day on the user's quota applies as set; group quotas
+// can only lower it (most restrictive wins, as for other limits).
+func (e *Enforcer) GetMaxBandwidthPerDay(ctx context.Context, userID string) (int64, error) {
+	var userCap sql.NullInt64
+	err := e.userDB.DB().QueryRowContext(ctx, `SELECT max_bandwidth_per_day FROM user_quotas WHERE user_id = $1`, userID).Scan(&userCap)
+	if err != nil && err != sql.ErrNoRows {
+		return 0, fmt.Errorf("failed to get user bandwidth quota: %w", err)
+	}
+	maxBandwidth := userCap.Int64
+
+	rows, err := e.userDB.DB().QueryContext(ctx, `
+		SELECT gq.max_bandwidth_per_day
+		FROM group_quotas gq
+		JOIN group_memberships gm ON gm.group_id = gq.group_id
+		WHERE gm.user_id = $1 AND gq.max_bandwidth_per_day > 0
+	`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get group bandwidth quotas: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var groupCap int64
+		if err := rows.Scan(&groupCap); err != nil {
+			return 0, err
+		}
+		if maxBandwidth <= 0 || groupCap < maxBandwidth {
+			maxBandwidth = groupCap
+		}
+	}
+	if maxBandwidth < 0 {
+		maxBandwidth = 0
+	}
+	return maxBandwidth, rows.Err()
+}
+
+// CheckBandwidth validates that a user who transferred usedBytes today may
+// open another port-forward tunnel.
+func (e *Enforcer) CheckBandwidth(ctx context.Context, userID string, usedBytes int64) error {
+	maxBandwidth, err := e.GetMaxBandwidthPerDay(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if maxBandwidth > 0 && usedBytes >= maxBandwidth*1024*1024 {
+		return &QuotaExceededError{
+			Message: fmt.Sprintf("bandwidth quota exceeded: %dMi transferred today, limit is %dMi per day", usedBytes/(1024*1024), maxBandwidth),
+			Limit:   maxBandwidth,
+			Current: usedBytes / (1024 * 1024),
+		}
+	}
+	return nil
+}
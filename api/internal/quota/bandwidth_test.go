@@ -0,0 +1,48 @@
+package quota
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckBandwidth(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	enforcer := NewEnforcer(db.NewUserDB(sqlDB), nil)
+	expectCaps := func(user interface{}, groups ...int64) {
+		mock.ExpectQuery("SELECT max_bandwidth_per_day FROM user_quotas").
+			WithArgs("alice").
+			WillReturnRows(sqlmock.NewRows([]string{"max_bandwidth_per_day"}).AddRow(user))
+		rows := sqlmock.NewRows([]string{"max_bandwidth_per_day"})
+		for _, groupCap := range groups {
+			rows.AddRow(groupCap)
+		}
+		mock.ExpectQuery("SELECT gq.max_bandwidth_per_day").WithArgs("alice").WillReturnRows(rows)
+	}
+
+	// No caps: unlimited
+	expectCaps(nil)
+	assert.NoError(t, enforcer.CheckBandwidth(context.Background(), "alice", 1<<40))
+
+	// The lowest of the user's and groups' caps applies
+	expectCaps(int64(2048), 1024, 4096)
+	maxBandwidth, err := enforcer.GetMaxBandwidthPerDay(context.Background(), "alice")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1024), maxBandwidth)
+
+	// A group cap applies to users without one
+	expectCaps(nil, 1024)
+	assert.NoError(t, enforcer.CheckBandwidth(context.Background(), "alice", 1023*1024*1024))
+	expectCaps(nil, 1024)
+	err = enforcer.CheckBandwidth(context.Background(), "alice", 1024*1024*1024)
+	assert.True(t, IsQuotaExceeded(err))
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,112 @@
+package quota
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Category is a known template category and the resources its sessions get
+// when neither the request nor the template specifies any.
+//
+// Templates store the category's Name. Lookups also accept its slug (the
+// streamspace.io/category label value, e.g. "web-browsers") and aliases, in
+// any case, so "IDE", "ide" and "development" all resolve to Development.
+type Category struct {
+	Name        string   `json:"name"`
+	Slug        string   `json:"slug"`
+	Description string   `json:"description"`
+	Aliases     []string `json:"aliases,omitempty"`
+	CPU         string   `json:"cpu"`
+	Memory      string   `json:"memory"`
+}
+
+// Resource defaults for templates without a known category.
+const (
+	defaultCategoryCPU    = "1000m"  // 1 CPU
+	defaultCategoryMemory = "2048Mi" // 2 GiB
+)
+
+// categories is the registry of known template categories.
+var categories = []Category{
+	{Name: "Web Browsers", Slug: "web-browsers", Description: "Web browsers", Aliases: []string{"browsers", "browser"}, CPU: "1000m", Memory: "2048Mi"},
+	{Name: "Development", Slug: "development", Description: "IDEs, editors and developer tools", Aliases: []string{"ide", "dev"}, CPU: "2000m", Memory: "4096Mi"},
+	{Name: "Design & Graphics", Slug: "design-graphics", Description: "Image editing, illustration and 3D", Aliases: []string{"design", "graphics"}, CPU: "2000m", Memory: "8192Mi"},
+	{Name: "Audio & Video", Slug: "audio-video", Description: "Audio and video editing and playback", Aliases: []string{"media", "video editing"}, CPU: "4000m", Memory: "8192Mi"},
+	{Name: "Gaming", Slug: "gaming", Description: "Games and emulators", Aliases: []string{"emulation", "games"}, CPU: "2000m", Memory: "4096Mi"},
+	{Name: "Productivity", Slug: "productivity", Description: "Office suites and note taking", Aliases: []string{"office"}, CPU: "1000m", Memory: "2048Mi"},
+	{Name: "Communication", Slug: "communication", Description: "Chat, email and video calls", CPU: "1000m", Memory: "2048Mi"},
+	{Name: "File Management", Slug: "file-management", Description: "File managers and transfer tools", Aliases: []string{"files"}, CPU: "1000m", Memory: "2048Mi"},
+	{Name: "Desktop Environments", Slug: "desktop-environments", Description: "Full Linux desktops", Aliases: []string{"desktop", "desktops"}, CPU: "2000m", Memory: "4096Mi"},
+	{Name: "Remote Access", Slug: "remote-access", Description: "Remote desktop and SSH clients", CPU: "1000m", Memory: "2048Mi"},
+	{Name: "AI & Machine Learning", Slug: "ai-machine-learning", Description: "Notebooks and machine learning tools", Aliases: []string{"ai", "machine learning", "ml"}, CPU: "4000m", Memory: "16384Mi"},
+}
+
+// categoryIndex maps the slugified names, slugs and aliases of the
+// registry to their category.
+var categoryIndex = func() map[string]*Category {
+	index := make(map[string]*Category)
+	for i := range categories {
+		category := &categories[i]
+		for _, key := range append([]string{category.Name, category.Slug}, category.Aliases...) {
+			index[categoryKey(key)] = category
+		}
+	}
+	return index
+}()
+
+// categoryKey slugifies a category name for lookups: lowercase, with runs
+// of anything but letters and digits replaced by a single dash.
+func categoryKey(name string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(strings.TrimSpace(name)) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if dash && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			b.WriteRune(r)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+	return b.String()
+}
+
+// ListCategories returns the known template categories sorted by name.
+func ListCategories() []Category {
+	list := make([]Category, len(categories))
+	copy(list, categories)
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
+
+// LookupCategory finds a category by name, slug or alias.
+func LookupCategory(name string) (*Category, bool) {
+	category, ok := categoryIndex[categoryKey(name)]
+	return category, ok
+}
+
+// NormalizeCategory returns the registered name of a template category, so
+// that templates store "Development" whether they were created with "IDE"
+// or "development". An empty category stays empty (uncategorized); unknown
+// categories are an error.
+func NormalizeCategory(name string) (string, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", nil
+	}
+	category, ok := LookupCategory(name)
+	if !ok {
+		return "", fmt.Errorf("unknown template category %q", name)
+	}
+	return category.Name, nil
+}
+
+// GetDefaultResources returns default resource requests based on template category
+func GetDefaultResources(category string) (cpu, memory string) {
+	if c, ok := LookupCategory(category); ok {
+		return c.CPU, c.Memory
+	}
+	return defaultCategoryCPU, defaultCategoryMemory
+}
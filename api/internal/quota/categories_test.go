@@ -0,0 +1,59 @@
+package quota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeCategory(t *testing.T) {
+	for _, name := range []string{"Development", "development", "IDE", "ide", " Dev "} {
+		category, err := NormalizeCategory(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, "Development", category, name)
+	}
+
+	// Names, label slugs and punctuation variants all match
+	for _, name := range []string{"Design & Graphics", "design-graphics", "design graphics", "Graphics"} {
+		category, err := NormalizeCategory(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, "Design & Graphics", category, name)
+	}
+
+	category, err := NormalizeCategory("")
+	require.NoError(t, err)
+	assert.Empty(t, category)
+
+	_, err = NormalizeCategory("developmnet")
+	assert.Error(t, err)
+}
+
+func TestGetDefaultResources(t *testing.T) {
+	cpu, memory := GetDefaultResources("IDE")
+	assert.Equal(t, []string{"2000m", "4096Mi"}, []string{cpu, memory})
+
+	cpu, memory = GetDefaultResources("ai-machine-learning")
+	assert.Equal(t, []string{"4000m", "16384Mi"}, []string{cpu, memory})
+
+	// Unknown categories get the defaults
+	cpu, memory = GetDefaultResources("unknown")
+	assert.Equal(t, []string{defaultCategoryCPU, defaultCategoryMemory}, []string{cpu, memory})
+}
+
+func TestCategoryRegistry(t *testing.T) {
+	// Every name, slug and alias resolves to exactly one category
+	seen := make(map[string]string)
+	for _, category := range ListCategories() {
+		for _, key := range append([]string{category.Name, category.Slug}, category.Aliases...) {
+			if other, ok := seen[categoryKey(key)]; ok {
+				assert.Equal(t, category.Name, other, "%q of %s is also used by %s", key, category.Name, other)
+			}
+			seen[categoryKey(key)] = category.Name
+		}
+		assert.Equal(t, category.Slug, categoryKey(category.Slug), category.Name)
+
+		_, _, err := NewEnforcer(nil, nil).ValidateResourceRequest(category.CPU, category.Memory)
+		assert.NoError(t, err, category.Name)
+	}
+}
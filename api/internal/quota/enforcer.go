@@ -11,12 +11,25 @@
 //   - Total memory: Maximum total RAM across all sessions
 //   - Storage: Maximum persistent storage per user
 //   - GPU: Maximum GPU count per session
+//   - Template sessions: Maximum concurrent sessions per template or category
+//   - Session hours: Maximum hours sessions may run per calendar month
 //
 // Quota hierarchy (most restrictive wins):
 //  1. User-specific quotas (user_quotas table)
 //  2. Group quotas (all groups user belongs to)
 //  3. Platform defaults (defined in code)
 //
+// Group quotas have a mode. For sessions, CPU, memory and storage, a
+// user's limits are computed in this order:
+//  1. Start from the user-specific quota, or the platform defaults
+//  2. Restrict-mode groups (the default) lower each limit to the lowest
+//     across them
+//  3. Grant-mode groups raise each limit to the highest across them; a
+//     grant never lowers a limit
+//
+// So a "premium" grant group lifts a member of a restricted base group,
+// but only for the dimensions it grants more of.
+//
 // Example limits:
 //   - Free tier: 5 sessions, 2 CPU/session, 4 GiB/session, 50 GiB storage
 //   - Pro tier: 20 sessions, 4 CPU/session, 8 GiB/session, 500 GiB storage
@@ -25,14 +38,17 @@
 // Enforcement points:
 //   - Session creation (CheckSessionCreation)
 //   - Resource requests (ValidateResourceRequest)
-//   - Storage allocation (checked separately)
+//   - Storage allocation (CheckSessionCreation, with usage from the user's
+//     home PVCs via CalculateStorageUsage)
+//   - Session hours (CheckSessionHoursBudget, with usage from
+//     UserDB.GetSessionHoursUsed)
 //
 // Example usage:
 //
 //	enforcer := quota.NewEnforcer(userDB, groupDB)
 //
 //	// Check if user can create session
-//	err := enforcer.CheckSessionCreation(ctx, "user1", 1000, 2048, 0, currentUsage)
+//	err := enforcer.CheckSessionCreation(ctx, "user1", 1000, 2048, 0, 0, quota.SessionTemplate{Name: "firefox"}, currentUsage)
 //	if quota.IsQuotaExceeded(err) {
 //	    return errors.New("quota exceeded")
 //	}
@@ -42,13 +58,20 @@ import (
 	"context"
 	"fmt"
 	"strconv"
-	"strings"
 
 	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/models"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 )
 
+// gib is the number of bytes in a GiB.
+const gib = 1024 * 1024 * 1024
+
+// DefaultHomeStorage is the size of the home PVC the controller creates on
+// a user's first persistent-home session (GiB).
+const DefaultHomeStorage int64 = 50
+
 // Limits represents resource quotas for a user or group.
 //
 // Limits can be set at multiple levels:
@@ -57,7 +80,8 @@ import (
 //   - Platform default: Defined in GetUserLimits()
 //
 // When a user belongs to multiple groups, the most restrictive
-// limit is applied for each resource type.
+// limit is applied for each resource type, then raised by any grant-mode
+// groups (see applyGroupQuotas).
 //
 // Units:
 //   - CPU: Millicores (1000m = 1 CPU core)
@@ -104,6 +128,18 @@ type Limits struct {
 	// MaxGPUPerSession is the maximum GPU count per individual session.
 	// Example: 1 (one GPU per session), 0 (no GPU access)
 	MaxGPUPerSession int `json:"max_gpu_per_session"`
+
+	// MaxSessionsPerTemplate caps concurrent sessions of a template.
+	// Example: {"blender-gpu": 1}
+	MaxSessionsPerTemplate map[string]int `json:"max_sessions_per_template,omitempty"`
+
+	// MaxSessionsPerCategory caps concurrent sessions of any template in a
+	// category. Example: {"Web Browsers": 5}
+	MaxSessionsPerCategory map[string]int `json:"max_sessions_per_category,omitempty"`
+
+	// MaxSessionHoursPerMonth is the hours a user's sessions may run per
+	// calendar month (UTC), 0 if unlimited. Example: 160
+	MaxSessionHoursPerMonth int64 `json:"max_session_hours_per_month,omitempty"`
 }
 
 // Usage represents current resource consumption for a user.
@@ -137,12 +173,20 @@ type Usage struct {
 	TotalMemory int64 `json:"total_memory"`
 
 	// TotalStorage is the total persistent storage in use (GiB).
-	// Sum of PVC sizes
+	// Sum of the home PVC sizes, set with CalculateStorageUsage
 	TotalStorage int64 `json:"total_storage"`
 
 	// TotalGPU is the total GPU count across all sessions.
 	// Sum of container.resources.requests["nvidia.com/gpu"]
 	TotalGPU int `json:"total_gpu"`
+
+	// SessionsByTemplate counts active sessions per template.
+	// Calculated from the pods' "template" label
+	SessionsByTemplate map[string]int `json:"sessions_by_template,omitempty"`
+
+	// SessionsByCategory counts active sessions per template category.
+	// Filled by CountCategories
+	SessionsByCategory map[string]int `json:"sessions_by_category,omitempty"`
 }
 
 // Enforcer enforces resource quotas for users and groups.
@@ -168,20 +212,26 @@ type Enforcer struct {
 
 	// groupDB provides access to group quota data.
 	groupDB *db.GroupDB
+
+	// warningThreshold is the usage percentage at which
+	// CheckSessionCreationWithWarnings warns (0 disables warnings).
+	warningThreshold int
 }
 
 // NewEnforcer creates a new quota enforcer instance.
 //
-// The enforcer is stateless and can be shared across goroutines.
+// The enforcer is stateless and can be shared across goroutines. The quota
+// warning threshold is read from QUOTA_WARNING_THRESHOLD (default 80%).
 //
 // Example:
 //
 //	enforcer := NewEnforcer(userDB, groupDB)
-//	err := enforcer.CheckSessionCreation(ctx, username, cpu, memory, gpu, usage)
+//	err := enforcer.CheckSessionCreation(ctx, username, cpu, memory, gpu, storage, template, usage)
 func NewEnforcer(userDB *db.UserDB, groupDB *db.GroupDB) *Enforcer {
 	return &Enforcer{
-		userDB:  userDB,
-		groupDB: groupDB,
+		userDB:           userDB,
+		groupDB:          groupDB,
+		warningThreshold: warningThresholdFromEnv(),
 	}
 }
 
@@ -228,64 +278,103 @@ func (e *Enforcer) GetUserLimits(ctx context.Context, username string) (*Limits,
 		}
 		if user.Quota.MaxStorage != "" {
 			// Parse MaxStorage
-			storage, err := ParseResourceQuantity(user.Quota.MaxStorage, "memory")
+			storage, err := ParseResourceQuantity(user.Quota.MaxStorage, "storage")
 			if err == nil && storage > 0 {
 				limits.MaxStorage = storage
 			}
 		}
 	}
 
-	// Check group limits and apply the most restrictive
-	if len(user.Groups) > 0 {
-		for _, groupName := range user.Groups {
-			group, err := e.groupDB.GetGroupByName(ctx, groupName)
-			if err != nil {
-				continue // Skip groups that don't exist
-			}
-
-			if group.Quota != nil {
-				// Apply most restrictive limits
-				if group.Quota.MaxSessions > 0 && group.Quota.MaxSessions < limits.MaxSessions {
-					limits.MaxSessions = group.Quota.MaxSessions
-				}
-				if group.Quota.MaxCPU != "" {
-					cpu, err := ParseResourceQuantity(group.Quota.MaxCPU, "cpu")
-					if err == nil && cpu > 0 && cpu < limits.MaxCPUPerSession {
-						limits.MaxCPUPerSession = cpu
-					}
-					if err == nil && cpu > 0 && cpu < limits.MaxTotalCPU {
-						limits.MaxTotalCPU = cpu
-					}
-				}
-				if group.Quota.MaxMemory != "" {
-					memory, err := ParseResourceQuantity(group.Quota.MaxMemory, "memory")
-					if err == nil && memory > 0 && memory < limits.MaxMemoryPerSession {
-						limits.MaxMemoryPerSession = memory
-					}
-					if err == nil && memory > 0 && memory < limits.MaxTotalMemory {
-						limits.MaxTotalMemory = memory
-					}
-				}
-				if group.Quota.MaxStorage != "" {
-					storage, err := ParseResourceQuantity(group.Quota.MaxStorage, "memory")
-					if err == nil && storage > 0 && storage < limits.MaxStorage {
-						limits.MaxStorage = storage
-					}
-				}
-			}
+	// Apply group limits: restrict groups first, then grant groups
+	var groupQuotas []*models.GroupQuota
+	for _, groupName := range user.Groups {
+		group, err := e.groupDB.GetGroupByName(ctx, groupName)
+		if err != nil {
+			continue // Skip groups that don't exist
 		}
+		groupQuota, err := e.groupDB.GetGroupQuota(ctx, group.ID)
+		if err != nil {
+			continue // Skip groups without a quota
+		}
+		groupQuotas = append(groupQuotas, groupQuota)
+	}
+	applyGroupQuotas(limits, groupQuotas)
+
+	// Per-template and per-category session limits
+	if err := e.loadTemplateSessionLimits(ctx, user.ID, limits); err != nil {
+		return nil, err
+	}
+
+	// Monthly session-hours budget
+	if err := e.loadSessionHoursBudget(ctx, user.ID, limits); err != nil {
+		return nil, err
 	}
 
 	return limits, nil
 }
 
+// applyGroupQuotas applies the sessions, CPU, memory and storage limits of
+// a user's group quotas to limits. Restrict-mode quotas are applied first
+// and lower each limit to the lowest among them; grant-mode quotas are
+// applied after and raise each limit to the highest among them. Unset or
+// invalid group limits are ignored.
+func applyGroupQuotas(limits *Limits, groupQuotas []*models.GroupQuota) {
+	var grants Limits
+	for _, q := range groupQuotas {
+		cpu, _ := ParseResourceQuantity(q.MaxCPU, "cpu")
+		memory, _ := ParseResourceQuantity(q.MaxMemory, "memory")
+		storage, _ := ParseResourceQuantity(q.MaxStorage, "storage")
+
+		if q.QuotaMode == models.GroupQuotaModeGrant {
+			grants.MaxSessions = max(grants.MaxSessions, q.MaxSessions)
+			grants.MaxTotalCPU = max(grants.MaxTotalCPU, cpu)
+			grants.MaxTotalMemory = max(grants.MaxTotalMemory, memory)
+			grants.MaxStorage = max(grants.MaxStorage, storage)
+			continue
+		}
+
+		if q.MaxSessions > 0 && q.MaxSessions < limits.MaxSessions {
+			limits.MaxSessions = q.MaxSessions
+		}
+		if cpu > 0 {
+			limits.MaxCPUPerSession = min(limits.MaxCPUPerSession, cpu)
+			limits.MaxTotalCPU = min(limits.MaxTotalCPU, cpu)
+		}
+		if memory > 0 {
+			limits.MaxMemoryPerSession = min(limits.MaxMemoryPerSession, memory)
+			limits.MaxTotalMemory = min(limits.MaxTotalMemory, memory)
+		}
+		if storage > 0 && storage < limits.MaxStorage {
+			limits.MaxStorage = storage
+		}
+	}
+
+	// Like user quotas, a group's CPU and memory cap both a single session
+	// and the total
+	limits.MaxSessions = max(limits.MaxSessions, grants.MaxSessions)
+	limits.MaxCPUPerSession = max(limits.MaxCPUPerSession, grants.MaxTotalCPU)
+	limits.MaxTotalCPU = max(limits.MaxTotalCPU, grants.MaxTotalCPU)
+	limits.MaxMemoryPerSession = max(limits.MaxMemoryPerSession, grants.MaxTotalMemory)
+	limits.MaxTotalMemory = max(limits.MaxTotalMemory, grants.MaxTotalMemory)
+	limits.MaxStorage = max(limits.MaxStorage, grants.MaxStorage)
+}
+
 // CheckSessionCreation validates if a user can create a new session with the requested resources
-func (e *Enforcer) CheckSessionCreation(ctx context.Context, username string, requestedCPU, requestedMemory int64, requestedGPU int, currentUsage *Usage) error {
+// from template. requestedStorage is the new persistent storage the session
+// needs (GiB), e.g. a home PVC the user doesn't have yet; sessions reusing
+// existing storage pass 0 and aren't held to the storage quota. A
+// per-template or per-category limit that would be exceeded is reported as
+// a *TemplateLimitExceededError.
+func (e *Enforcer) CheckSessionCreation(ctx context.Context, username string, requestedCPU, requestedMemory int64, requestedGPU int, requestedStorage int64, template SessionTemplate, currentUsage *Usage) error {
 	limits, err := e.GetUserLimits(ctx, username)
 	if err != nil {
 		return fmt.Errorf("failed to get user limits: %w", err)
 	}
+	return checkSessionCreation(limits, requestedCPU, requestedMemory, requestedGPU, requestedStorage, template, currentUsage)
+}
 
+// checkSessionCreation checks a new session's resources against limits.
+func checkSessionCreation(limits *Limits, requestedCPU, requestedMemory int64, requestedGPU int, requestedStorage int64, template SessionTemplate, currentUsage *Usage) error {
 	// Check session count
 	if currentUsage.ActiveSessions >= limits.MaxSessions {
 		return fmt.Errorf("session quota exceeded: %d/%d sessions active", currentUsage.ActiveSessions, limits.MaxSessions)
@@ -318,7 +407,13 @@ func (e *Enforcer) CheckSessionCreation(ctx context.Context, username string, re
 		return fmt.Errorf("GPU quota exceeded: requested %d, limit is %d per session", requestedGPU, limits.MaxGPUPerSession)
 	}
 
-	return nil
+	// Check storage
+	if err := checkStorageLimit(limits, requestedStorage, currentUsage); err != nil {
+		return err
+	}
+
+	// Check per-template and per-category session counts
+	return checkTemplateLimits(limits, template, currentUsage)
 }
 
 // CalculateUsage calculates current resource usage from a list of pods
@@ -332,21 +427,29 @@ func (e *Enforcer) CalculateUsage(pods []corev1.Pod) *Usage {
 		}
 
 		usage.ActiveSessions++
+		if template := pod.Labels["template"]; template != "" {
+			if usage.SessionsByTemplate == nil {
+				usage.SessionsByTemplate = make(map[string]int)
+			}
+			usage.SessionsByTemplate[template]++
+		}
 
-		// Sum up resource requests from all containers
+		// Sum up what quotas charge for all containers: limits, as sessions
+		// may burst to them when their requests are overcommitted, or
+		// requests where no limit is set
 		for _, container := range pod.Spec.Containers {
 			// CPU
-			if cpu := container.Resources.Requests[corev1.ResourceCPU]; !cpu.IsZero() {
+			if cpu := quotaQuantity(container.Resources, corev1.ResourceCPU); !cpu.IsZero() {
 				usage.TotalCPU += cpu.MilliValue()
 			}
 
 			// Memory (convert to MiB)
-			if memory := container.Resources.Requests[corev1.ResourceMemory]; !memory.IsZero() {
+			if memory := quotaQuantity(container.Resources, corev1.ResourceMemory); !memory.IsZero() {
 				usage.TotalMemory += memory.Value() / (1024 * 1024)
 			}
 
 			// GPU (nvidia.com/gpu)
-			if gpu := container.Resources.Requests["nvidia.com/gpu"]; !gpu.IsZero() {
+			if gpu := quotaQuantity(container.Resources, "nvidia.com/gpu"); !gpu.IsZero() {
 				usage.TotalGPU += int(gpu.Value())
 			}
 		}
@@ -355,6 +458,44 @@ func (e *Enforcer) CalculateUsage(pods []corev1.Pod) *Usage {
 	return usage
 }
 
+// checkStorageLimit checks requestedStorage GiB of new storage against the
+// storage quota. Requests for no new storage always pass, so a user over a
+// lowered quota can still use their existing home.
+func checkStorageLimit(limits *Limits, requestedStorage int64, usage *Usage) error {
+	if requestedStorage <= 0 {
+		return nil
+	}
+	totalStorage := usage.TotalStorage + requestedStorage
+	if totalStorage > limits.MaxStorage {
+		return fmt.Errorf("storage quota exceeded: would use %dGi, limit is %dGi", totalStorage, limits.MaxStorage)
+	}
+	return nil
+}
+
+// CalculateStorageUsage returns the total storage requested by PVCs in
+// GiB, rounded up. Pass the user's home PVCs (home-{user} and the homes of
+// forked sessions); PVCs being deleted don't count.
+func (e *Enforcer) CalculateStorageUsage(pvcs []corev1.PersistentVolumeClaim) int64 {
+	var total int64
+	for _, pvc := range pvcs {
+		if pvc.DeletionTimestamp != nil {
+			continue
+		}
+		storage := pvc.Spec.Resources.Requests[corev1.ResourceStorage]
+		total += storage.Value()
+	}
+	return (total + gib - 1) / gib
+}
+
+// quotaQuantity returns the amount of a resource a container is charged
+// for: its limit, or its request if it sets no limit.
+func quotaQuantity(resources corev1.ResourceRequirements, name corev1.ResourceName) resource.Quantity {
+	if limit, ok := resources.Limits[name]; ok && !limit.IsZero() {
+		return limit
+	}
+	return resources.Requests[name]
+}
+
 // ParseResourceQuantity parses a Kubernetes resource quantity string (e.g., "2000m", "4Gi")
 func ParseResourceQuantity(quantity string, resourceType string) (int64, error) {
 	q, err := resource.ParseQuantity(quantity)
@@ -369,6 +510,9 @@ func ParseResourceQuantity(quantity string, resourceType string) (int64, error)
 	case "memory":
 		// Return MiB
 		return q.Value() / (1024 * 1024), nil
+	case "storage":
+		// Return GiB
+		return q.Value() / gib, nil
 	default:
 		return q.Value(), nil
 	}
@@ -383,6 +527,9 @@ func FormatResourceQuantity(value int64, resourceType string) string {
 	case "memory":
 		// Convert MiB to string
 		return fmt.Sprintf("%dMi", value)
+	case "storage":
+		// Convert GiB to string
+		return fmt.Sprintf("%dGi", value)
 	default:
 		return fmt.Sprintf("%d", value)
 	}
@@ -429,28 +576,6 @@ func (e *Enforcer) ValidateResourceRequest(cpuStr, memoryStr string) (cpu, memor
 	return cpu, memory, nil
 }
 
-// GetDefaultResources returns default resource requests based on template category
-func GetDefaultResources(category string) (cpu, memory string) {
-	switch strings.ToLower(category) {
-	case "browsers", "web browsers":
-		return "1000m", "2048Mi" // 1 CPU, 2 GiB
-	case "development", "ide":
-		return "2000m", "4096Mi" // 2 CPUs, 4 GiB
-	case "design", "graphics":
-		return "2000m", "8192Mi" // 2 CPUs, 8 GiB
-	case "gaming", "emulation":
-		return "2000m", "4096Mi" // 2 CPUs, 4 GiB
-	case "productivity", "office":
-		return "1000m", "2048Mi" // 1 CPU, 2 GiB
-	case "media", "video editing":
-		return "4000m", "8192Mi" // 4 CPUs, 8 GiB
-	case "ai", "machine learning":
-		return "4000m", "16384Mi" // 4 CPUs, 16 GiB
-	default:
-		return "1000m", "2048Mi" // 1 CPU, 2 GiB (default)
-	}
-}
-
 // QuotaExceededError represents a quota exceeded error
 type QuotaExceededError struct {
 	Message string
@@ -0,0 +1,117 @@
+package quota
+
+import (
+	"testing"
+
+	"github.com/streamspace/streamspace/api/internal/models"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestCalculateUsage_ChargesLimits(t *testing.T) {
+	pod := func(requests, limits corev1.ResourceList, phase corev1.PodPhase) corev1.Pod {
+		return corev1.Pod{
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{
+				Resources: corev1.ResourceRequirements{Requests: requests, Limits: limits},
+			}}},
+			Status: corev1.PodStatus{Phase: phase},
+		}
+	}
+	list := func(cpu, memory string) corev1.ResourceList {
+		return corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+		}
+	}
+
+	usage := (&Enforcer{}).CalculateUsage([]corev1.Pod{
+		// Overcommitted: charged its limits, not its requests
+		pod(list("500m", "1Gi"), list("2", "4Gi"), corev1.PodRunning),
+		// No limits: charged its requests
+		pod(list("1", "2Gi"), nil, corev1.PodRunning),
+		pod(list("4", "8Gi"), list("4", "8Gi"), corev1.PodPending),
+	})
+
+	assert.Equal(t, 2, usage.ActiveSessions)
+	assert.Equal(t, int64(3000), usage.TotalCPU)
+	assert.Equal(t, int64(6144), usage.TotalMemory)
+}
+
+func TestCalculateStorageUsage(t *testing.T) {
+	pvc := func(size string) corev1.PersistentVolumeClaim {
+		return corev1.PersistentVolumeClaim{
+			Spec: corev1.PersistentVolumeClaimSpec{
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse(size)},
+				},
+			},
+		}
+	}
+
+	enforcer := &Enforcer{}
+	assert.Equal(t, int64(60), enforcer.CalculateStorageUsage([]corev1.PersistentVolumeClaim{pvc("50Gi"), pvc("10Gi")}))
+	assert.Equal(t, int64(51), enforcer.CalculateStorageUsage([]corev1.PersistentVolumeClaim{pvc("50Gi"), pvc("512Mi")}), "partial GiB round up")
+	assert.Zero(t, enforcer.CalculateStorageUsage(nil))
+}
+
+func TestCheckStorageLimit(t *testing.T) {
+	limits := &Limits{MaxStorage: 50}
+
+	assert.NoError(t, checkStorageLimit(limits, 10, &Usage{TotalStorage: 40}), "up to the limit is allowed")
+
+	err := checkStorageLimit(limits, 1, &Usage{TotalStorage: 50})
+	assert.EqualError(t, err, "storage quota exceeded: would use 51Gi, limit is 50Gi")
+
+	assert.NoError(t, checkStorageLimit(limits, 0, &Usage{TotalStorage: 60}), "existing storage is never rejected")
+}
+
+func TestParseResourceQuantity_Storage(t *testing.T) {
+	storage, err := ParseResourceQuantity("100Gi", "storage")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), storage)
+	assert.Equal(t, "100Gi", FormatResourceQuantity(storage, "storage"))
+}
+
+func TestApplyGroupQuotas_GrantAfterRestrict(t *testing.T) {
+	limits := &Limits{
+		MaxSessions:         5,
+		MaxCPUPerSession:    2000,
+		MaxMemoryPerSession: 4096,
+		MaxTotalCPU:         4000,
+		MaxTotalMemory:      8192,
+		MaxStorage:          50,
+	}
+
+	// Listed grant-first: restrict groups still apply before grant groups
+	applyGroupQuotas(limits, []*models.GroupQuota{
+		{GroupID: "premium", QuotaMode: models.GroupQuotaModeGrant, MaxSessions: 2, MaxCPU: "8000m"},
+		{GroupID: "students", QuotaMode: models.GroupQuotaModeRestrict, MaxSessions: 3, MaxCPU: "1000m", MaxMemory: "2Gi"},
+		{GroupID: "premium-gpu", QuotaMode: models.GroupQuotaModeGrant, MaxCPU: "6000m"},
+	})
+
+	// The grant wins for CPU: the highest grant, per session and in total
+	assert.Equal(t, int64(8000), limits.MaxCPUPerSession)
+	assert.Equal(t, int64(8000), limits.MaxTotalCPU)
+	// The restrict wins for sessions: the grant is below it
+	assert.Equal(t, 3, limits.MaxSessions)
+	// No grant for memory or storage
+	assert.Equal(t, int64(2048), limits.MaxMemoryPerSession)
+	assert.Equal(t, int64(2048), limits.MaxTotalMemory)
+	assert.Equal(t, int64(50), limits.MaxStorage)
+}
+
+func TestApplyGroupQuotas_RestrictByDefault(t *testing.T) {
+	limits := &Limits{MaxSessions: 5, MaxCPUPerSession: 2000, MaxTotalCPU: 4000, MaxStorage: 50}
+
+	// Quotas without a mode restrict; the lowest limit wins
+	applyGroupQuotas(limits, []*models.GroupQuota{
+		{GroupID: "a", MaxSessions: 4, MaxCPU: "3000m", MaxStorage: "20Gi"},
+		{GroupID: "b", MaxSessions: 10, MaxCPU: "invalid", MaxStorage: "30Gi"},
+	})
+
+	assert.Equal(t, 4, limits.MaxSessions)
+	assert.Equal(t, int64(2000), limits.MaxCPUPerSession)
+	assert.Equal(t, int64(3000), limits.MaxTotalCPU)
+	assert.Equal(t, int64(20), limits.MaxStorage)
+}
@@ -0,0 +1,89 @@
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Session priority classes, lowest first. The controller maps them to
+// Kubernetes PriorityClasses; High sessions may preempt Low ones.
+const (
+	PriorityLow    = "Low"
+	PriorityNormal = "Normal"
+	PriorityHigh   = "High"
+)
+
+// priorityRank orders priority classes; unknown values rank 0.
+var priorityRank = map[string]int{
+	PriorityLow:    1,
+	PriorityNormal: 2,
+	PriorityHigh:   3,
+}
+
+// ValidPriority reports whether p is a known priority class.
+func ValidPriority(p string) bool {
+	return priorityRank[p] > 0
+}
+
+// GetMaxPriority returns the highest session priority a user may request.
+//
+// Admins and operators default to High, everyone else to Normal. A
+// max_priority on the user's quota replaces the default; group quotas can
+// only lower it (most restrictive wins, as for other limits).
+func (e *Enforcer) GetMaxPriority(ctx context.Context, username string) (string, error) {
+	user, err := e.userDB.GetUserByUsername(ctx, username)
+	if err != nil {
+		return "", fmt.Errorf("failed to get user: %w", err)
+	}
+
+	maxPriority := PriorityNormal
+	if user.Role == "admin" || user.Role == "operator" {
+		maxPriority = PriorityHigh
+	}
+
+	var userCap sql.NullString
+	err = e.userDB.DB().QueryRowContext(ctx, `SELECT max_priority FROM user_quotas WHERE user_id = $1`, user.ID).Scan(&userCap)
+	if err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("failed to get user priority quota: %w", err)
+	}
+	if ValidPriority(userCap.String) {
+		maxPriority = userCap.String
+	}
+
+	rows, err := e.userDB.DB().QueryContext(ctx, `
+		SELECT gq.max_priority
+		FROM group_quotas gq
+		JOIN group_memberships gm ON gm.group_id = gq.group_id
+		WHERE gm.user_id = $1 AND gq.max_priority IS NOT NULL
+	`, user.ID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get group priority quotas: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var groupCap string
+		if err := rows.Scan(&groupCap); err != nil {
+			return "", err
+		}
+		if ValidPriority(groupCap) && priorityRank[groupCap] < priorityRank[maxPriority] {
+			maxPriority = groupCap
+		}
+	}
+	return maxPriority, rows.Err()
+}
+
+// CheckPriority validates that a user may launch a session at priority.
+func (e *Enforcer) CheckPriority(ctx context.Context, username, priority string) error {
+	if !ValidPriority(priority) {
+		return fmt.Errorf("invalid priority %q: must be Low, Normal or High", priority)
+	}
+	maxPriority, err := e.GetMaxPriority(ctx, username)
+	if err != nil {
+		return err
+	}
+	if priorityRank[priority] > priorityRank[maxPriority] {
+		return fmt.Errorf("priority quota exceeded: requested %s, limit is %s", priority, maxPriority)
+	}
+	return nil
+}
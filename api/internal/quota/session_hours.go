@@ -0,0 +1,85 @@
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// SessionHoursPeriodStart returns the start of the session-hours budget
+// period containing now: the first of its calendar month, 00:00 UTC.
+func SessionHoursPeriodStart(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// sessionHoursResetAt returns when the budget period containing now ends
+// and the budget resets.
+func sessionHoursResetAt(now time.Time) time.Time {
+	return SessionHoursPeriodStart(now).AddDate(0, 1, 0)
+}
+
+// loadSessionHoursBudget resolves a user's monthly session-hours budget
+// into limits.
+//
+// A max_session_hours_per_month on the user's quota applies as set; group
+// quotas can only lower it (most restrictive wins, as for other limits).
+func (e *Enforcer) loadSessionHoursBudget(ctx context.Context, userID string, limits *Limits) error {
+	var userBudget sql.NullInt64
+	err := e.userDB.DB().QueryRowContext(ctx, `SELECT max_session_hours_per_month FROM user_quotas WHERE user_id = $1`, userID).Scan(&userBudget)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get user session hours quota: %w", err)
+	}
+	budget := userBudget.Int64
+
+	rows, err := e.userDB.DB().QueryContext(ctx, `
+		SELECT gq.max_session_hours_per_month
+		FROM group_quotas gq
+		JOIN group_memberships gm ON gm.group_id = gq.group_id
+		WHERE gm.user_id = $1 AND gq.max_session_hours_per_month > 0
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get group session hours quotas: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var groupBudget int64
+		if err := rows.Scan(&groupBudget); err != nil {
+			return err
+		}
+		if budget <= 0 || groupBudget < budget {
+			budget = groupBudget
+		}
+	}
+	if budget < 0 {
+		budget = 0
+	}
+	limits.MaxSessionHoursPerMonth = budget
+	return rows.Err()
+}
+
+// CheckSessionHoursBudget validates that a user whose sessions ran
+// currentHoursUsed hours this month (see UserDB.GetSessionHoursUsed and
+// SessionHoursPeriodStart) may start another session.
+func (e *Enforcer) CheckSessionHoursBudget(ctx context.Context, username string, currentHoursUsed int64) error {
+	limits, err := e.GetUserLimits(ctx, username)
+	if err != nil {
+		return fmt.Errorf("failed to get user limits: %w", err)
+	}
+	return checkSessionHoursBudget(limits, currentHoursUsed, time.Now())
+}
+
+// checkSessionHoursBudget rejects new sessions once hoursUsed reaches the
+// monthly budget. The budget resets at the start of the next month.
+func checkSessionHoursBudget(limits *Limits, hoursUsed int64, now time.Time) error {
+	if limits.MaxSessionHoursPerMonth > 0 && hoursUsed >= limits.MaxSessionHoursPerMonth {
+		return &QuotaExceededError{
+			Message: fmt.Sprintf("session hours quota exceeded: %d hours used this month, limit is %d (resets %s)",
+				hoursUsed, limits.MaxSessionHoursPerMonth, sessionHoursResetAt(now).Format(time.RFC3339)),
+			Limit:   limits.MaxSessionHoursPerMonth,
+			Current: hoursUsed,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package quota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionHoursPeriod(t *testing.T) {
+	// The last second of January is still in January's period
+	now := time.Date(2026, 1, 31, 23, 59, 59, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), SessionHoursPeriodStart(now))
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), sessionHoursResetAt(now))
+
+	// Periods are UTC months: this is already February in UTC
+	local := time.Date(2026, 1, 31, 20, 0, 0, 0, time.FixedZone("EST", -5*3600))
+	assert.Equal(t, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), SessionHoursPeriodStart(local))
+
+	// December rolls over into the next year
+	assert.Equal(t, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC), sessionHoursResetAt(time.Date(2026, 12, 15, 0, 0, 0, 0, time.UTC)))
+}
+
+func TestCheckSessionHoursBudget_Edge(t *testing.T) {
+	now := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	limits := &Limits{MaxSessionHoursPerMonth: 100}
+
+	// One hour left: allowed
+	assert.NoError(t, checkSessionHoursBudget(limits, 99, now))
+
+	// Budget exhausted: rejected until April
+	err := checkSessionHoursBudget(limits, 100, now)
+	require.Error(t, err)
+	assert.True(t, IsQuotaExceeded(err))
+	assert.Contains(t, err.Error(), "100 hours used this month, limit is 100")
+	assert.Contains(t, err.Error(), "resets 2026-04-01T00:00:00Z")
+
+	// No budget: unlimited
+	assert.NoError(t, checkSessionHoursBudget(&Limits{}, 10000, now))
+}
+
+func TestLoadSessionHoursBudget(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	enforcer := NewEnforcer(db.NewUserDB(sqlDB), nil)
+	expectBudgets := func(user interface{}, groups ...int64) {
+		mock.ExpectQuery("SELECT max_session_hours_per_month FROM user_quotas").
+			WithArgs("user-1").
+			WillReturnRows(sqlmock.NewRows([]string{"max_session_hours_per_month"}).AddRow(user))
+		rows := sqlmock.NewRows([]string{"max_session_hours_per_month"})
+		for _, groupBudget := range groups {
+			rows.AddRow(groupBudget)
+		}
+		mock.ExpectQuery("SELECT gq.max_session_hours_per_month").WithArgs("user-1").WillReturnRows(rows)
+	}
+
+	// The lowest of the user's and groups' budgets applies
+	expectBudgets(int64(160), 80, 200)
+	limits := &Limits{}
+	require.NoError(t, enforcer.loadSessionHoursBudget(context.Background(), "user-1", limits))
+	assert.Equal(t, int64(80), limits.MaxSessionHoursPerMonth)
+
+	// A group budget applies to users without one
+	expectBudgets(nil, 120)
+	limits = &Limits{}
+	require.NoError(t, enforcer.loadSessionHoursBudget(context.Background(), "user-1", limits))
+	assert.Equal(t, int64(120), limits.MaxSessionHoursPerMonth)
+
+	// No budgets: unlimited
+	expectBudgets(nil)
+	limits = &Limits{}
+	require.NoError(t, enforcer.loadSessionHoursBudget(context.Background(), "user-1", limits))
+	assert.Zero(t, limits.MaxSessionHoursPerMonth)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,138 @@
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/streamspace/streamspace/api/internal/models"
+)
+
+// Scopes of a per-template session limit.
+const (
+	LimitScopeTemplate = "template"
+	LimitScopeCategory = "category"
+)
+
+// SessionTemplate identifies the template a new session is launched from,
+// for per-template and per-category session limits. The zero value skips
+// those limits.
+type SessionTemplate struct {
+	Name     string
+	Category string
+}
+
+// TemplateLimitExceededError reports which per-template or per-category
+// session limit a new session would exceed.
+type TemplateLimitExceededError struct {
+	// Scope is LimitScopeTemplate or LimitScopeCategory
+	Scope string `json:"scope"`
+	// Name is the template or category name
+	Name   string `json:"name"`
+	Limit  int    `json:"limit"`
+	Active int    `json:"active"`
+}
+
+func (e *TemplateLimitExceededError) Error() string {
+	return fmt.Sprintf("%s session quota exceeded: %d/%d sessions of %s %s active",
+		e.Scope, e.Active, e.Limit, e.Scope, e.Name)
+}
+
+// checkTemplateLimits checks a new session of template against the
+// per-template and per-category limits.
+func checkTemplateLimits(limits *Limits, template SessionTemplate, usage *Usage) error {
+	if template.Name != "" {
+		if max, ok := limits.MaxSessionsPerTemplate[template.Name]; ok {
+			if active := usage.SessionsByTemplate[template.Name]; active >= max {
+				return &TemplateLimitExceededError{Scope: LimitScopeTemplate, Name: template.Name, Limit: max, Active: active}
+			}
+		}
+	}
+	if template.Category != "" {
+		if max, ok := limits.MaxSessionsPerCategory[template.Category]; ok {
+			if active := usage.SessionsByCategory[template.Category]; active >= max {
+				return &TemplateLimitExceededError{Scope: LimitScopeCategory, Name: template.Category, Limit: max, Active: active}
+			}
+		}
+	}
+	return nil
+}
+
+// CountCategories fills SessionsByCategory from SessionsByTemplate, using
+// categoryOf to look up each template's category.
+func (u *Usage) CountCategories(categoryOf func(template string) string) {
+	u.SessionsByCategory = make(map[string]int)
+	for template, count := range u.SessionsByTemplate {
+		if category := categoryOf(template); category != "" {
+			u.SessionsByCategory[category] += count
+		}
+	}
+}
+
+// loadTemplateSessionLimits resolves a user's per-template and per-category
+// session limits into limits.
+//
+// Limits on the user's quota apply as set; group quotas can only lower them
+// or add new ones (most restrictive wins, as for other limits).
+func (e *Enforcer) loadTemplateSessionLimits(ctx context.Context, userID string, limits *Limits) error {
+	var userLimits sql.NullString
+	err := e.userDB.DB().QueryRowContext(ctx, `SELECT template_session_limits FROM user_quotas WHERE user_id = $1`, userID).Scan(&userLimits)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to get user template session limits: %w", err)
+	}
+	if err := mergeTemplateSessionLimits(limits, userLimits.String); err != nil {
+		return err
+	}
+
+	rows, err := e.userDB.DB().QueryContext(ctx, `
+		SELECT gq.template_session_limits
+		FROM group_quotas gq
+		JOIN group_memberships gm ON gm.group_id = gq.group_id
+		WHERE gm.user_id = $1 AND gq.template_session_limits IS NOT NULL
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get group template session limits: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var groupLimits string
+		if err := rows.Scan(&groupLimits); err != nil {
+			return err
+		}
+		if err := mergeTemplateSessionLimits(limits, groupLimits); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// mergeTemplateSessionLimits applies stored limits (JSON) to limits,
+// keeping the lower value where both set one. Negative values are ignored.
+func mergeTemplateSessionLimits(limits *Limits, stored string) error {
+	if stored == "" {
+		return nil
+	}
+	var parsed models.TemplateSessionLimits
+	if err := json.Unmarshal([]byte(stored), &parsed); err != nil {
+		return fmt.Errorf("invalid template session limits: %w", err)
+	}
+	limits.MaxSessionsPerTemplate = mergeMinLimits(limits.MaxSessionsPerTemplate, parsed.Templates)
+	limits.MaxSessionsPerCategory = mergeMinLimits(limits.MaxSessionsPerCategory, parsed.Categories)
+	return nil
+}
+
+func mergeMinLimits(dst, src map[string]int) map[string]int {
+	for name, max := range src {
+		if max < 0 {
+			continue
+		}
+		if dst == nil {
+			dst = make(map[string]int)
+		}
+		if current, ok := dst[name]; !ok || max < current {
+			dst[name] = max
+		}
+	}
+	return dst
+}
@@ -0,0 +1,52 @@
+package quota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergeTemplateSessionLimits(t *testing.T) {
+	limits := &Limits{}
+
+	// User quota sets the limits
+	require.NoError(t, mergeTemplateSessionLimits(limits, `{"templates":{"blender-gpu":2},"categories":{"Web Browsers":5}}`))
+	// Groups can only lower them or add new ones
+	require.NoError(t, mergeTemplateSessionLimits(limits, `{"templates":{"blender-gpu":1,"vscode":3},"categories":{"Web Browsers":8}}`))
+
+	assert.Equal(t, map[string]int{"blender-gpu": 1, "vscode": 3}, limits.MaxSessionsPerTemplate)
+	assert.Equal(t, map[string]int{"Web Browsers": 5}, limits.MaxSessionsPerCategory)
+
+	assert.NoError(t, mergeTemplateSessionLimits(limits, ""))
+	assert.Error(t, mergeTemplateSessionLimits(limits, "not json"))
+}
+
+func TestCheckTemplateLimits(t *testing.T) {
+	limits := &Limits{
+		MaxSessionsPerTemplate: map[string]int{"blender-gpu": 1},
+		MaxSessionsPerCategory: map[string]int{"Web Browsers": 2},
+	}
+	usage := &Usage{SessionsByTemplate: map[string]int{"blender-gpu": 1, "firefox": 1, "chromium": 1}}
+	usage.CountCategories(func(name string) string {
+		if name == "firefox" || name == "chromium" {
+			return "Web Browsers"
+		}
+		return "Design"
+	})
+	assert.Equal(t, map[string]int{"Web Browsers": 2, "Design": 1}, usage.SessionsByCategory)
+
+	err := checkTemplateLimits(limits, SessionTemplate{Name: "blender-gpu", Category: "Design"}, usage)
+	var exceeded *TemplateLimitExceededError
+	require.ErrorAs(t, err, &exceeded)
+	assert.Equal(t, &TemplateLimitExceededError{Scope: LimitScopeTemplate, Name: "blender-gpu", Limit: 1, Active: 1}, exceeded)
+	assert.Equal(t, "template session quota exceeded: 1/1 sessions of template blender-gpu active", err.Error())
+
+	err = checkTemplateLimits(limits, SessionTemplate{Name: "firefox", Category: "Web Browsers"}, usage)
+	require.ErrorAs(t, err, &exceeded)
+	assert.Equal(t, LimitScopeCategory, exceeded.Scope)
+	assert.Equal(t, "Web Browsers", exceeded.Name)
+
+	assert.NoError(t, checkTemplateLimits(limits, SessionTemplate{Name: "vscode", Category: "Development"}, usage))
+	assert.NoError(t, checkTemplateLimits(limits, SessionTemplate{}, usage))
+}
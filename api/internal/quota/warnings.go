@@ -0,0 +1,114 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+)
+
+// DefaultWarningThreshold is the usage percentage of a limit at which
+// users are warned, unless QUOTA_WARNING_THRESHOLD overrides it.
+const DefaultWarningThreshold = 80
+
+// Quota dimensions reported in warnings.
+const (
+	WarningSessions = "sessions"
+	WarningCPU      = "cpu"
+	WarningMemory   = "memory"
+	WarningStorage  = "storage"
+)
+
+// QuotaWarning reports a quota dimension whose usage, including the new
+// session, is at or above the warning threshold.
+//
+// Units are those of Limits: millicores for CPU, MiB for memory and GiB
+// for storage.
+type QuotaWarning struct {
+	// Resource is the dimension: "sessions", "cpu", "memory" or "storage".
+	Resource string `json:"resource"`
+
+	// Current is the usage with the new session.
+	Current int64 `json:"current"`
+
+	// Limit is the user's limit.
+	Limit int64 `json:"limit"`
+
+	// Percentage is Current as a percentage of Limit.
+	Percentage float64 `json:"percentage"`
+}
+
+// Message describes the warning for users.
+func (w QuotaWarning) Message() string {
+	return fmt.Sprintf("%s usage is at %.0f%% of your quota (%d of %d)", w.Resource, w.Percentage, w.Current, w.Limit)
+}
+
+// warningThresholdFromEnv reads QUOTA_WARNING_THRESHOLD (a percentage, 0
+// disables warnings).
+func warningThresholdFromEnv() int {
+	env := os.Getenv("QUOTA_WARNING_THRESHOLD")
+	if env == "" {
+		return DefaultWarningThreshold
+	}
+	threshold, err := strconv.Atoi(env)
+	if err != nil || threshold < 0 {
+		log.Printf("Invalid QUOTA_WARNING_THRESHOLD %q, using default %d", env, DefaultWarningThreshold)
+		return DefaultWarningThreshold
+	}
+	return threshold
+}
+
+// SetWarningThreshold sets the usage percentage at which
+// CheckSessionCreationWithWarnings warns; 0 disables warnings.
+func (e *Enforcer) SetWarningThreshold(percent int) {
+	e.warningThreshold = percent
+}
+
+// CheckSessionCreationWithWarnings is CheckSessionCreation that also
+// returns the quota dimensions whose usage with the new session is at or
+// above the warning threshold. Warnings are returned whether or not the
+// session is rejected; they never reject it themselves.
+func (e *Enforcer) CheckSessionCreationWithWarnings(ctx context.Context, username string, requestedCPU, requestedMemory int64, requestedGPU int, requestedStorage int64, template SessionTemplate, currentUsage *Usage) ([]QuotaWarning, error) {
+	limits, err := e.GetUserLimits(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user limits: %w", err)
+	}
+
+	warnings := quotaWarnings(limits, requestedCPU, requestedMemory, requestedStorage, currentUsage, e.warningThreshold)
+	return warnings, checkSessionCreation(limits, requestedCPU, requestedMemory, requestedGPU, requestedStorage, template, currentUsage)
+}
+
+// quotaWarnings returns the dimensions whose usage with a new session is at
+// least threshold percent of the limit. Unlimited dimensions (limit 0) and
+// a threshold of 0 never warn.
+func quotaWarnings(limits *Limits, requestedCPU, requestedMemory, requestedStorage int64, usage *Usage, threshold int) []QuotaWarning {
+	if threshold <= 0 {
+		return nil
+	}
+
+	var warnings []QuotaWarning
+	for _, dim := range []struct {
+		resource       string
+		current, limit int64
+	}{
+		{WarningSessions, int64(usage.ActiveSessions) + 1, int64(limits.MaxSessions)},
+		{WarningCPU, usage.TotalCPU + requestedCPU, limits.MaxTotalCPU},
+		{WarningMemory, usage.TotalMemory + requestedMemory, limits.MaxTotalMemory},
+		{WarningStorage, usage.TotalStorage + requestedStorage, limits.MaxStorage},
+	} {
+		if dim.limit <= 0 {
+			continue
+		}
+		percentage := float64(dim.current) * 100 / float64(dim.limit)
+		if percentage >= float64(threshold) {
+			warnings = append(warnings, QuotaWarning{
+				Resource:   dim.resource,
+				Current:    dim.current,
+				Limit:      dim.limit,
+				Percentage: percentage,
+			})
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,50 @@
+package quota
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQuotaWarnings(t *testing.T) {
+	limits := &Limits{MaxSessions: 5, MaxTotalCPU: 4000, MaxTotalMemory: 8192, MaxStorage: 0}
+	usage := &Usage{ActiveSessions: 3, TotalCPU: 2000, TotalMemory: 2048, TotalStorage: 45}
+
+	// The new session brings sessions to 4/5 (80%) and CPU to 3500m/4000m
+	warnings := quotaWarnings(limits, 1500, 1024, 0, usage, 80)
+	require.Len(t, warnings, 2)
+	assert.Equal(t, QuotaWarning{Resource: WarningSessions, Current: 4, Limit: 5, Percentage: 80}, warnings[0])
+	assert.Equal(t, QuotaWarning{Resource: WarningCPU, Current: 3500, Limit: 4000, Percentage: 87.5}, warnings[1])
+	assert.Equal(t, "cpu usage is at 88% of your quota (3500 of 4000)", warnings[1].Message())
+
+	// Storage without a limit never warns; a threshold of 0 disables warnings
+	assert.Empty(t, quotaWarnings(limits, 1500, 1024, 0, usage, 0))
+	assert.Empty(t, quotaWarnings(limits, 0, 0, 0, usage, 90))
+}
+
+func TestQuotaWarnings_ReportedAlongsideRejection(t *testing.T) {
+	limits := &Limits{MaxSessions: 5, MaxCPUPerSession: 2000, MaxMemoryPerSession: 4096, MaxTotalCPU: 4000, MaxTotalMemory: 8192, MaxStorage: 50}
+	usage := &Usage{ActiveSessions: 2, TotalStorage: 50}
+
+	warnings := quotaWarnings(limits, 1000, 1024, 1, usage, 80)
+	err := checkSessionCreation(limits, 1000, 1024, 0, 1, SessionTemplate{}, usage)
+	assert.EqualError(t, err, "storage quota exceeded: would use 51Gi, limit is 50Gi")
+	require.Len(t, warnings, 1)
+	assert.Equal(t, WarningStorage, warnings[0].Resource)
+	assert.Equal(t, int64(51), warnings[0].Current)
+}
+
+func TestWarningThresholdFromEnv(t *testing.T) {
+	t.Setenv("QUOTA_WARNING_THRESHOLD", "")
+	assert.Equal(t, DefaultWarningThreshold, warningThresholdFromEnv())
+
+	t.Setenv("QUOTA_WARNING_THRESHOLD", "90")
+	assert.Equal(t, 90, warningThresholdFromEnv())
+
+	t.Setenv("QUOTA_WARNING_THRESHOLD", "0")
+	assert.Zero(t, warningThresholdFromEnv())
+
+	t.Setenv("QUOTA_WARNING_THRESHOLD", "most")
+	assert.Equal(t, DefaultWarningThreshold, warningThresholdFromEnv())
+}
@@ -0,0 +1,402 @@
+// Package rightsize recommends CPU and memory for StreamSpace sessions from
+// their observed usage.
+//
+// Users tend to over-provision sessions (request 8Gi, use 1Gi), which wastes
+// cluster capacity that quotas and cost reports then count as consumed. The
+// Sampler periodically records each running session pod's usage from
+// metrics-server into the session_resource_samples table. The Recommender
+// turns a session's samples into suggested requests and limits:
+//
+//   - Requests: p95 usage over the session's lifetime plus headroom
+//   - Limits: peak usage plus headroom (never below the request)
+//   - CPU is rounded up to 50m and memory to 64Mi, with floors of 100m/256Mi
+//
+// A recommendation is only marked confident once the session has at least
+// RIGHTSIZE_MIN_SAMPLES samples; short-lived sessions are reported but never
+// auto-applied.
+//
+// Applying a recommendation updates the Session's spec.resources. Pods are
+// not resized in place: the controller uses the new resources the next time
+// it creates or wakes the session's pod.
+//
+// Configuration:
+//   - RIGHTSIZE_SAMPLE_INTERVAL: How often usage is sampled (default 1m)
+//   - RIGHTSIZE_RETENTION: How long samples are kept (default 30d)
+//   - RIGHTSIZE_MIN_SAMPLES: Samples needed for a confident recommendation (default 60)
+//   - RIGHTSIZE_HEADROOM: Fraction added on top of observed usage (default 0.2)
+//   - RIGHTSIZE_AUTO_APPLY: Apply confident recommendations automatically (default false)
+//
+// Example usage:
+//
+//	recommender := rightsize.NewRecommender(database, rightsize.ConfigFromEnv())
+//	rec, err := recommender.Recommend(ctx, sessionID)
+package rightsize
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/quota"
+)
+
+const (
+	// cpuStepMillis and memoryStepMiB are the rounding granularity of
+	// recommendations
+	cpuStepMillis = 50
+	memoryStepMiB = 64
+
+	// minCPUMillis and minMemoryMiB are the smallest recommended requests;
+	// desktops need some slack to start even if they idle near zero
+	minCPUMillis = 100
+	minMemoryMiB = 256
+
+	bytesPerMiB = 1024 * 1024
+)
+
+// ErrNoSamples is returned when a session has no recorded usage.
+var ErrNoSamples = errors.New("no usage samples recorded for session")
+
+// Config controls sampling and recommendations.
+type Config struct {
+	SampleInterval time.Duration
+	Retention      time.Duration
+	MinSamples     int
+	Headroom       float64
+	AutoApply      bool
+}
+
+// ConfigFromEnv reads the right-sizing configuration from the environment.
+func ConfigFromEnv() Config {
+	cfg := Config{
+		SampleInterval: envDuration("RIGHTSIZE_SAMPLE_INTERVAL", time.Minute),
+		Retention:      envDuration("RIGHTSIZE_RETENTION", 30*24*time.Hour),
+		MinSamples:     60,
+		Headroom:       0.2,
+	}
+	if v := os.Getenv("RIGHTSIZE_MIN_SAMPLES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			cfg.MinSamples = n
+		} else {
+			log.Printf("Invalid RIGHTSIZE_MIN_SAMPLES %q, using %d", v, cfg.MinSamples)
+		}
+	}
+	if v := os.Getenv("RIGHTSIZE_HEADROOM"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= 0 {
+			cfg.Headroom = f
+		} else {
+			log.Printf("Invalid RIGHTSIZE_HEADROOM %q, using %g", v, cfg.Headroom)
+		}
+	}
+	cfg.AutoApply, _ = strconv.ParseBool(os.Getenv("RIGHTSIZE_AUTO_APPLY"))
+	return cfg
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	v := os.Getenv(name)
+	if v == "" {
+		return def
+	}
+	if days, ok := parseDays(v); ok {
+		return days
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		log.Printf("Invalid %s %q, using %s", name, v, def)
+		return def
+	}
+	return d
+}
+
+// parseDays accepts "30d" style durations, which time.ParseDuration doesn't.
+func parseDays(v string) (time.Duration, bool) {
+	if len(v) < 2 || v[len(v)-1] != 'd' {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v[:len(v)-1])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return time.Duration(n) * 24 * time.Hour, true
+}
+
+// Resources is a CPU/memory pair in Kubernetes quantity format.
+type Resources struct {
+	CPU    string `json:"cpu,omitempty"`
+	Memory string `json:"memory,omitempty"`
+}
+
+// Usage is observed CPU and memory usage.
+type Usage struct {
+	CPUMillis int64 `json:"cpuMillis"`
+	MemoryMiB int64 `json:"memoryMiB"`
+}
+
+// Stats summarizes a session's usage samples.
+type Stats struct {
+	Samples   int
+	FirstSeen time.Time
+	LastSeen  time.Time
+	P95       Usage
+	Max       Usage
+}
+
+// Recommendation is the suggested resources for one session.
+type Recommendation struct {
+	SessionID    string    `json:"sessionId"`
+	UserID       string    `json:"userId,omitempty"`
+	TemplateName string    `json:"templateName,omitempty"`
+	Samples      int       `json:"samples"`
+	FirstSeen    time.Time `json:"firstSeen"`
+	LastSeen     time.Time `json:"lastSeen"`
+	P95          Usage     `json:"p95"`
+	Max          Usage     `json:"max"`
+	Current      Resources `json:"current"`
+	Requests     Resources `json:"requests"`
+	Limits       Resources `json:"limits"`
+	// Confident is false until the session has MinSamples samples
+	Confident bool `json:"confident"`
+	// Savings is the current request minus the recommended request
+	// (negative when the session is under-provisioned)
+	Savings Usage `json:"savings"`
+}
+
+// Overprovisioned reports whether the recommendation would free capacity.
+func (r *Recommendation) Overprovisioned() bool {
+	return r.Savings.CPUMillis > 0 || r.Savings.MemoryMiB > 0
+}
+
+// Overview is the cluster-wide over-provisioning summary.
+type Overview struct {
+	Sessions        int              `json:"sessions"`
+	Overprovisioned int              `json:"overprovisioned"`
+	Requested       Usage            `json:"requested"`
+	Recommended     Usage            `json:"recommended"`
+	Savings         Usage            `json:"savings"`
+	Items           []Recommendation `json:"items"`
+}
+
+// Recommender computes recommendations from recorded samples.
+type Recommender struct {
+	db  *db.Database
+	cfg Config
+}
+
+// NewRecommender creates a recommender reading samples from database.
+func NewRecommender(database *db.Database, cfg Config) *Recommender {
+	return &Recommender{db: database, cfg: cfg}
+}
+
+// Config returns the recommender's configuration.
+func (r *Recommender) Config() Config {
+	return r.cfg
+}
+
+const statsColumns = `
+		COUNT(r.session_id), MIN(r.sampled_at), MAX(r.sampled_at),
+		COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY r.cpu_millicores), 0),
+		COALESCE(percentile_cont(0.95) WITHIN GROUP (ORDER BY r.memory_bytes), 0),
+		COALESCE(MAX(r.cpu_millicores), 0), COALESCE(MAX(r.memory_bytes), 0)`
+
+// Recommend computes the recommendation for one session. It returns
+// ErrNoSamples if the session has no recorded usage.
+func (r *Recommender) Recommend(ctx context.Context, sessionID string) (*Recommendation, error) {
+	row := r.db.DB().QueryRowContext(ctx, `
+		SELECT s.id, s.user_id, COALESCE(s.template_name, ''), COALESCE(s.cpu, ''), COALESCE(s.memory, ''),`+statsColumns+`
+		FROM sessions s
+		LEFT JOIN session_resource_samples r ON r.session_id = s.id
+		WHERE s.id = $1
+		GROUP BY s.id, s.user_id, s.template_name, s.cpu, s.memory
+	`, sessionID)
+
+	rec, err := r.scan(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("session %s not found", sessionID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if rec.Samples == 0 {
+		return nil, ErrNoSamples
+	}
+	return rec, nil
+}
+
+// Overview computes recommendations for every active session with samples,
+// most over-provisioned (by memory) first. At most limit items are returned;
+// the totals cover all sessions.
+func (r *Recommender) Overview(ctx context.Context, limit int) (*Overview, error) {
+	rows, err := r.db.DB().QueryContext(ctx, `
+		SELECT s.id, s.user_id, COALESCE(s.template_name, ''), COALESCE(s.cpu, ''), COALESCE(s.memory, ''),`+statsColumns+`
+		FROM sessions s
+		JOIN session_resource_samples r ON r.session_id = s.id
+		WHERE s.state NOT IN ('terminated', 'deleted', 'failed')
+		GROUP BY s.id, s.user_id, s.template_name, s.cpu, s.memory
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query session usage samples: %w", err)
+	}
+	defer rows.Close()
+
+	var recs []Recommendation
+	for rows.Next() {
+		rec, err := r.scan(rows)
+		if err != nil {
+			return nil, err
+		}
+		recs = append(recs, *rec)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return BuildOverview(recs, limit), nil
+}
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (r *Recommender) scan(row scanner) (*Recommendation, error) {
+	var rec Recommendation
+	var cpu, memory string
+	var first, last sql.NullTime
+	var p95CPU, p95Mem float64
+	var maxCPU, maxMem int64
+
+	if err := row.Scan(&rec.SessionID, &rec.UserID, &rec.TemplateName, &cpu, &memory,
+		&rec.Samples, &first, &last, &p95CPU, &p95Mem, &maxCPU, &maxMem); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to scan session usage samples: %w", err)
+	}
+
+	stats := Stats{
+		Samples:   rec.Samples,
+		FirstSeen: first.Time,
+		LastSeen:  last.Time,
+		P95:       Usage{CPUMillis: int64(p95CPU + 0.5), MemoryMiB: int64(p95Mem) / bytesPerMiB},
+		Max:       Usage{CPUMillis: maxCPU, MemoryMiB: maxMem / bytesPerMiB},
+	}
+	computed := Compute(stats, Resources{CPU: cpu, Memory: memory}, r.cfg)
+	computed.SessionID, computed.UserID, computed.TemplateName = rec.SessionID, rec.UserID, rec.TemplateName
+	return computed, nil
+}
+
+// Compute turns usage stats into a recommendation against the session's
+// current requests.
+func Compute(stats Stats, current Resources, cfg Config) *Recommendation {
+	headroom := 1 + cfg.Headroom
+
+	reqCPU := roundUp(int64(float64(stats.P95.CPUMillis)*headroom), cpuStepMillis, minCPUMillis)
+	reqMem := roundUp(int64(float64(stats.P95.MemoryMiB)*headroom), memoryStepMiB, minMemoryMiB)
+	limCPU := roundUp(int64(float64(stats.Max.CPUMillis)*headroom), cpuStepMillis, reqCPU)
+	limMem := roundUp(int64(float64(stats.Max.MemoryMiB)*headroom), memoryStepMiB, reqMem)
+
+	rec := &Recommendation{
+		Samples:   stats.Samples,
+		FirstSeen: stats.FirstSeen,
+		LastSeen:  stats.LastSeen,
+		P95:       stats.P95,
+		Max:       stats.Max,
+		Current:   current,
+		Requests: Resources{
+			CPU:    quota.FormatResourceQuantity(reqCPU, "cpu"),
+			Memory: quota.FormatResourceQuantity(reqMem, "memory"),
+		},
+		Limits: Resources{
+			CPU:    quota.FormatResourceQuantity(limCPU, "cpu"),
+			Memory: quota.FormatResourceQuantity(limMem, "memory"),
+		},
+		Confident: stats.Samples >= cfg.MinSamples,
+	}
+
+	// Savings are only reported for resources the session actually requests
+	if current.CPU != "" {
+		if curCPU, err := quota.ParseResourceQuantity(current.CPU, "cpu"); err == nil {
+			rec.Savings.CPUMillis = curCPU - reqCPU
+		}
+	}
+	if current.Memory != "" {
+		if curMem, err := quota.ParseResourceQuantity(current.Memory, "memory"); err == nil {
+			rec.Savings.MemoryMiB = curMem - reqMem
+		}
+	}
+
+	return rec
+}
+
+// BuildOverview aggregates per-session recommendations. Only confident
+// recommendations count toward the recommended and savings totals.
+func BuildOverview(recs []Recommendation, limit int) *Overview {
+	overview := &Overview{Sessions: len(recs)}
+
+	for _, rec := range recs {
+		requested := parseResources(rec.Current)
+		overview.Requested.CPUMillis += requested.CPUMillis
+		overview.Requested.MemoryMiB += requested.MemoryMiB
+
+		if !rec.Confident {
+			continue
+		}
+		recommended := parseResources(rec.Requests)
+		overview.Recommended.CPUMillis += recommended.CPUMillis
+		overview.Recommended.MemoryMiB += recommended.MemoryMiB
+		if rec.Overprovisioned() {
+			overview.Overprovisioned++
+			overview.Savings.CPUMillis += max(rec.Savings.CPUMillis, 0)
+			overview.Savings.MemoryMiB += max(rec.Savings.MemoryMiB, 0)
+		}
+	}
+
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Savings.MemoryMiB != recs[j].Savings.MemoryMiB {
+			return recs[i].Savings.MemoryMiB > recs[j].Savings.MemoryMiB
+		}
+		if recs[i].Savings.CPUMillis != recs[j].Savings.CPUMillis {
+			return recs[i].Savings.CPUMillis > recs[j].Savings.CPUMillis
+		}
+		return recs[i].SessionID < recs[j].SessionID
+	})
+	if limit > 0 && len(recs) > limit {
+		recs = recs[:limit]
+	}
+	overview.Items = recs
+	if overview.Items == nil {
+		overview.Items = []Recommendation{}
+	}
+
+	return overview
+}
+
+// parseResources converts quantities to millicores and MiB, treating unset or
+// invalid quantities as zero.
+func parseResources(r Resources) Usage {
+	var u Usage
+	if r.CPU != "" {
+		u.CPUMillis, _ = quota.ParseResourceQuantity(r.CPU, "cpu")
+	}
+	if r.Memory != "" {
+		u.MemoryMiB, _ = quota.ParseResourceQuantity(r.Memory, "memory")
+	}
+	return u
+}
+
+// roundUp rounds v up to a multiple of step, and to at least floor.
+func roundUp(v, step, floor int64) int64 {
+	if v%step != 0 {
+		v += step - v%step
+	}
+	if v < floor {
+		return floor
+	}
+	return v
+}
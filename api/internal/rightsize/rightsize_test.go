@@ -0,0 +1,197 @@
+package rightsize
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+var testConfig = Config{SampleInterval: time.Minute, Retention: 24 * time.Hour, MinSamples: 10, Headroom: 0.2}
+
+func TestCompute_Overprovisioned(t *testing.T) {
+	stats := Stats{
+		Samples: 100,
+		P95:     Usage{CPUMillis: 400, MemoryMiB: 900},
+		Max:     Usage{CPUMillis: 1500, MemoryMiB: 1200},
+	}
+
+	rec := Compute(stats, Resources{CPU: "2000m", Memory: "8Gi"}, testConfig)
+
+	// 400m * 1.2 = 480m → 500m; 900Mi * 1.2 = 1080Mi → 1088Mi
+	assert.Equal(t, Resources{CPU: "500m", Memory: "1088Mi"}, rec.Requests)
+	// 1500m * 1.2 = 1800m; 1200Mi * 1.2 = 1440Mi → 1472Mi
+	assert.Equal(t, Resources{CPU: "1800m", Memory: "1472Mi"}, rec.Limits)
+	assert.True(t, rec.Confident)
+	assert.Equal(t, Usage{CPUMillis: 1500, MemoryMiB: 8192 - 1088}, rec.Savings)
+	assert.True(t, rec.Overprovisioned())
+}
+
+func TestCompute_FloorsAndUnderprovisioned(t *testing.T) {
+	stats := Stats{
+		Samples: 3,
+		P95:     Usage{CPUMillis: 5, MemoryMiB: 3000},
+		Max:     Usage{CPUMillis: 10, MemoryMiB: 3000},
+	}
+
+	rec := Compute(stats, Resources{CPU: "1", Memory: "2Gi"}, testConfig)
+
+	assert.Equal(t, "100m", rec.Requests.CPU)
+	assert.Equal(t, "100m", rec.Limits.CPU, "limit is never below the request")
+	assert.Equal(t, "3648Mi", rec.Requests.Memory)
+	assert.Equal(t, int64(2048-3648), rec.Savings.MemoryMiB)
+	assert.False(t, rec.Confident)
+}
+
+func TestCompute_NoCurrentRequests(t *testing.T) {
+	rec := Compute(Stats{Samples: 20, P95: Usage{CPUMillis: 300, MemoryMiB: 500}}, Resources{}, testConfig)
+	assert.Equal(t, Usage{}, rec.Savings)
+	assert.False(t, rec.Overprovisioned())
+}
+
+func TestBuildOverview(t *testing.T) {
+	recs := []Recommendation{
+		{SessionID: "small", Confident: true, Current: Resources{CPU: "1", Memory: "2Gi"},
+			Requests: Resources{CPU: "500m", Memory: "1Gi"}, Savings: Usage{CPUMillis: 500, MemoryMiB: 1024}},
+		{SessionID: "big", Confident: true, Current: Resources{CPU: "4", Memory: "8Gi"},
+			Requests: Resources{CPU: "1", Memory: "1Gi"}, Savings: Usage{CPUMillis: 3000, MemoryMiB: 7168}},
+		{SessionID: "tight", Confident: true, Current: Resources{CPU: "1", Memory: "1Gi"},
+			Requests: Resources{CPU: "1500m", Memory: "1Gi"}, Savings: Usage{CPUMillis: -500}},
+		{SessionID: "new", Confident: false, Current: Resources{CPU: "2", Memory: "4Gi"},
+			Requests: Resources{CPU: "100m", Memory: "256Mi"}, Savings: Usage{CPUMillis: 1900, MemoryMiB: 3840}},
+	}
+
+	overview := BuildOverview(recs, 2)
+
+	assert.Equal(t, 4, overview.Sessions)
+	assert.Equal(t, 2, overview.Overprovisioned)
+	assert.Equal(t, Usage{CPUMillis: 8000, MemoryMiB: 15360}, overview.Requested)
+	// Unconfident sessions are not counted as recommended or saved
+	assert.Equal(t, Usage{CPUMillis: 3000, MemoryMiB: 3072}, overview.Recommended)
+	assert.Equal(t, Usage{CPUMillis: 3500, MemoryMiB: 8192}, overview.Savings)
+
+	require.Len(t, overview.Items, 2)
+	assert.Equal(t, "big", overview.Items[0].SessionID)
+	assert.Equal(t, "new", overview.Items[1].SessionID)
+}
+
+func TestAutoApplicable(t *testing.T) {
+	base := Recommendation{Confident: true, Current: Resources{CPU: "2", Memory: "4Gi"}, Savings: Usage{CPUMillis: 500, MemoryMiB: 1024}}
+	assert.True(t, AutoApplicable(&base))
+
+	notConfident := base
+	notConfident.Confident = false
+	assert.False(t, AutoApplicable(&notConfident))
+
+	grows := base
+	grows.Savings.CPUMillis = -100
+	assert.False(t, AutoApplicable(&grows), "recommendations that grow a session need a user")
+
+	noRequests := base
+	noRequests.Current = Resources{}
+	assert.False(t, AutoApplicable(&noRequests))
+
+	nothingSaved := base
+	nothingSaved.Savings = Usage{}
+	assert.False(t, AutoApplicable(&nothingSaved))
+}
+
+func TestSamplesFromPodMetrics(t *testing.T) {
+	usage := func(cpu, memory string) corev1.ResourceList {
+		return corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+		}
+	}
+	items := []metricsv1beta1.PodMetrics{
+		{
+			ObjectMeta: metav1.ObjectMeta{Name: "user1-firefox-abc", Labels: map[string]string{"session": "user1-firefox"}},
+			Containers: []metricsv1beta1.ContainerMetrics{
+				{Name: "session", Usage: usage("250m", "512Mi")},
+				{Name: "sidecar", Usage: usage("50m", "64Mi")},
+			},
+		},
+		{ObjectMeta: metav1.ObjectMeta{Name: "unlabelled"}, Containers: []metricsv1beta1.ContainerMetrics{{Usage: usage("1", "1Gi")}}},
+	}
+
+	samples := samplesFromPodMetrics(items)
+	require.Len(t, samples, 1)
+	assert.Equal(t, Sample{SessionID: "user1-firefox", CPUMillis: 300, MemoryBytes: 576 << 20}, samples[0])
+}
+
+type fakeSource []Sample
+
+func (f fakeSource) Samples(context.Context) ([]Sample, error) { return f, nil }
+
+func TestSampleOnce(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	database := db.NewDatabaseForTesting(sqlDB)
+
+	now := time.Date(2025, 12, 1, 12, 0, 0, 0, time.UTC)
+	sampler := NewSampler(database, fakeSource{{SessionID: "s1", CPUMillis: 300, MemoryBytes: 1 << 30}}, NewRecommender(database, testConfig))
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO session_resource_samples")).
+		WithArgs("s1", now, int64(300), int64(1<<30)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta("DELETE FROM session_resource_samples WHERE sampled_at < $1")).
+		WithArgs(now.Add(-24 * time.Hour)).
+		WillReturnResult(sqlmock.NewResult(0, 5))
+
+	require.NoError(t, sampler.SampleOnce(context.Background(), now))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecommend(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+	recommender := NewRecommender(db.NewDatabaseForTesting(sqlDB), testConfig)
+
+	columns := []string{"id", "user_id", "template_name", "cpu", "memory", "count", "min", "max", "p95_cpu", "p95_mem", "max_cpu", "max_mem"}
+	first := time.Date(2025, 12, 1, 8, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery("FROM sessions s").WithArgs("s1").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("s1", "alice", "firefox", "2000m", "8Gi", 120, first, first.Add(2*time.Hour), 399.6, float64(900<<20), int64(1500), int64(1200<<20)))
+
+	rec, err := recommender.Recommend(context.Background(), "s1")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", rec.UserID)
+	assert.Equal(t, Usage{CPUMillis: 400, MemoryMiB: 900}, rec.P95)
+	assert.Equal(t, Resources{CPU: "500m", Memory: "1088Mi"}, rec.Requests)
+	assert.True(t, rec.Confident)
+
+	mock.ExpectQuery("FROM sessions s").WithArgs("s2").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("s2", "bob", "firefox", "1", "2Gi", 0, nil, nil, 0.0, 0.0, int64(0), int64(0)))
+
+	_, err = recommender.Recommend(context.Background(), "s2")
+	assert.ErrorIs(t, err, ErrNoSamples)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConfigFromEnv(t *testing.T) {
+	t.Setenv("RIGHTSIZE_SAMPLE_INTERVAL", "30s")
+	t.Setenv("RIGHTSIZE_RETENTION", "7d")
+	t.Setenv("RIGHTSIZE_MIN_SAMPLES", "bogus")
+	t.Setenv("RIGHTSIZE_HEADROOM", "0.5")
+	t.Setenv("RIGHTSIZE_AUTO_APPLY", "true")
+
+	cfg := ConfigFromEnv()
+	assert.Equal(t, 30*time.Second, cfg.SampleInterval)
+	assert.Equal(t, 7*24*time.Hour, cfg.Retention)
+	assert.Equal(t, 60, cfg.MinSamples)
+	assert.Equal(t, 0.5, cfg.Headroom)
+	assert.True(t, cfg.AutoApply)
+}
@@ -0,0 +1,182 @@
+package rightsize
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/streamspace/streamspace/api/internal/db"
+	"github.com/streamspace/streamspace/api/internal/k8s"
+	metricsv1beta1 "k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+const (
+	// sessionPodSelector matches the pods the controller creates for sessions
+	sessionPodSelector = "app=streamspace-session"
+
+	// autoApplyInterval is how often confident recommendations are applied
+	// when RIGHTSIZE_AUTO_APPLY is enabled
+	autoApplyInterval = time.Hour
+)
+
+// Sample is one usage observation of a session pod.
+type Sample struct {
+	SessionID   string
+	CPUMillis   int64
+	MemoryBytes int64
+}
+
+// MetricsSource reports the current usage of running sessions.
+type MetricsSource interface {
+	Samples(ctx context.Context) ([]Sample, error)
+}
+
+// Applier applies a recommendation to a session.
+type Applier func(ctx context.Context, rec *Recommendation) error
+
+// PodMetricsSource reads session pod usage from metrics-server.
+type PodMetricsSource struct {
+	client    *k8s.Client
+	namespace string
+}
+
+// NewPodMetricsSource creates a metrics source for session pods in namespace.
+func NewPodMetricsSource(client *k8s.Client, namespace string) *PodMetricsSource {
+	return &PodMetricsSource{client: client, namespace: namespace}
+}
+
+// Samples returns the current usage of every session pod.
+func (p *PodMetricsSource) Samples(ctx context.Context) ([]Sample, error) {
+	list, err := p.client.GetPodMetrics(ctx, p.namespace, sessionPodSelector)
+	if err != nil {
+		return nil, err
+	}
+	return samplesFromPodMetrics(list.Items), nil
+}
+
+// samplesFromPodMetrics sums container usage per session pod. Pods without
+// a session label are skipped.
+func samplesFromPodMetrics(items []metricsv1beta1.PodMetrics) []Sample {
+	samples := make([]Sample, 0, len(items))
+	for _, pod := range items {
+		sessionID := pod.Labels["session"]
+		if sessionID == "" {
+			continue
+		}
+		sample := Sample{SessionID: sessionID}
+		for _, container := range pod.Containers {
+			sample.CPUMillis += container.Usage.Cpu().MilliValue()
+			sample.MemoryBytes += container.Usage.Memory().Value()
+		}
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
+// Sampler records session usage samples and prunes expired ones.
+type Sampler struct {
+	db          *db.Database
+	source      MetricsSource
+	recommender *Recommender
+	cfg         Config
+	applier     Applier
+}
+
+// NewSampler creates a sampler that records samples from source.
+func NewSampler(database *db.Database, source MetricsSource, recommender *Recommender) *Sampler {
+	return &Sampler{
+		db:          database,
+		source:      source,
+		recommender: recommender,
+		cfg:         recommender.Config(),
+	}
+}
+
+// SetApplier sets the function used to auto-apply recommendations. It is
+// only called when RIGHTSIZE_AUTO_APPLY is enabled.
+func (s *Sampler) SetApplier(applier Applier) {
+	s.applier = applier
+}
+
+// Start samples usage every SampleInterval until ctx is cancelled.
+func (s *Sampler) Start(ctx context.Context) {
+	log.Printf("Starting session usage sampler (interval %s, retention %s, auto-apply %t)",
+		s.cfg.SampleInterval, s.cfg.Retention, s.cfg.AutoApply)
+
+	ticker := time.NewTicker(s.cfg.SampleInterval)
+	defer ticker.Stop()
+	lastApply := time.Now()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.SampleOnce(ctx, now); err != nil {
+				log.Printf("Session usage sampling failed: %v", err)
+			}
+			if s.cfg.AutoApply && s.applier != nil && now.Sub(lastApply) >= autoApplyInterval {
+				lastApply = now
+				s.autoApply(ctx)
+			}
+		}
+	}
+}
+
+// SampleOnce records one sample per running session and deletes samples
+// older than the retention period.
+func (s *Sampler) SampleOnce(ctx context.Context, now time.Time) error {
+	samples, err := s.source.Samples(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, sample := range samples {
+		if _, err := s.db.DB().ExecContext(ctx, `
+			INSERT INTO session_resource_samples (session_id, sampled_at, cpu_millicores, memory_bytes)
+			VALUES ($1, $2, $3, $4)
+		`, sample.SessionID, now, sample.CPUMillis, sample.MemoryBytes); err != nil {
+			return fmt.Errorf("failed to record usage sample for session %s: %w", sample.SessionID, err)
+		}
+	}
+
+	if _, err := s.db.DB().ExecContext(ctx,
+		`DELETE FROM session_resource_samples WHERE sampled_at < $1`, now.Add(-s.cfg.Retention)); err != nil {
+		return fmt.Errorf("failed to prune usage samples: %w", err)
+	}
+
+	return nil
+}
+
+// autoApply applies confident recommendations that only shrink a session.
+// Recommendations that would grow a session are left for the user or an
+// admin to apply, since they may run into quotas.
+func (s *Sampler) autoApply(ctx context.Context) {
+	overview, err := s.recommender.Overview(ctx, 0)
+	if err != nil {
+		log.Printf("Right-sizing auto-apply failed: %v", err)
+		return
+	}
+
+	for i := range overview.Items {
+		rec := &overview.Items[i]
+		if !AutoApplicable(rec) {
+			continue
+		}
+		if err := s.applier(ctx, rec); err != nil {
+			log.Printf("Failed to auto-apply right-sizing to session %s: %v", rec.SessionID, err)
+			continue
+		}
+		log.Printf("Auto-applied right-sizing to session %s: requests %s/%s (was %s/%s)",
+			rec.SessionID, rec.Requests.CPU, rec.Requests.Memory, rec.Current.CPU, rec.Current.Memory)
+	}
+}
+
+// AutoApplicable reports whether a recommendation may be applied without
+// a user asking for it: it must be confident, replace explicit requests,
+// free capacity and grow nothing.
+func AutoApplicable(rec *Recommendation) bool {
+	return rec.Confident && rec.Current.CPU != "" && rec.Current.Memory != "" && rec.Overprovisioned() &&
+		rec.Savings.CPUMillis >= 0 && rec.Savings.MemoryMiB >= 0
+}
@@ -166,6 +166,7 @@ type TemplateManifest struct {
 			Port        int    `yaml:"port"`
 			Path        string `yaml:"path,omitempty"`
 			HealthCheck string `yaml:"healthCheck,omitempty"`
+			BasePath    string `yaml:"basePath,omitempty"`
 		} `yaml:"webapp,omitempty"`
 		Capabilities []string `yaml:"capabilities,omitempty"`
 		Tags         []string `yaml:"tags,omitempty"`
@@ -42,6 +42,7 @@ package tracker
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"log"
 	"sync"
@@ -52,6 +53,10 @@ import (
 	"github.com/streamspace/streamspace/api/internal/k8s"
 )
 
+// ErrSessionHibernating is returned for connections to a session that is
+// draining its connections before it hibernates.
+var ErrSessionHibernating = errors.New("session is hibernating")
+
 // ConnectionTracker manages active connections and implements auto-hibernation.
 //
 // Thread safety:
@@ -99,6 +104,17 @@ type ConnectionTracker struct {
 
 	// stopCh signals the background checker to stop.
 	stopCh chan struct{}
+
+	// listener is told about connections as they come and go (optional).
+	listener ConnectionListener
+}
+
+// ConnectionListener is notified when connections are added and removed,
+// including stale connections dropped by the checker. The WebSocket
+// notifier implements it to count each session's viewers.
+type ConnectionListener interface {
+	NotifySessionConnected(sessionID, userID, connectionID string)
+	NotifySessionDisconnected(sessionID, userID, connectionID string)
 }
 
 // Connection represents an active user connection to a session.
@@ -181,6 +197,14 @@ func NewConnectionTracker(database *db.Database, k8sClient *k8s.Client, publishe
 	}
 }
 
+// SetListener sets the listener notified of connection changes. Set it
+// before Start so connections restored from the database are reported too.
+func (ct *ConnectionTracker) SetListener(listener ConnectionListener) {
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.listener = listener
+}
+
 // Start begins the connection tracking loop.
 //
 // This method:
@@ -240,8 +264,7 @@ func (ct *ConnectionTracker) loadActiveConnections() error {
 	defer rows.Close()
 
 	ct.mu.Lock()
-	defer ct.mu.Unlock()
-
+	var loaded []*Connection
 	for rows.Next() {
 		var conn Connection
 		if err := rows.Scan(&conn.ID, &conn.SessionID, &conn.UserID, &conn.ClientIP, &conn.UserAgent, &conn.ConnectedAt, &conn.LastHeartbeat); err != nil {
@@ -250,9 +273,18 @@ func (ct *ConnectionTracker) loadActiveConnections() error {
 		}
 
 		ct.connections[conn.ID] = &conn
+		loaded = append(loaded, &conn)
+	}
+	listener := ct.listener
+	ct.mu.Unlock()
+
+	if listener != nil {
+		for _, conn := range loaded {
+			listener.NotifySessionConnected(conn.SessionID, conn.UserID, conn.ID)
+		}
 	}
 
-	log.Printf("Loaded %d active connections from database", len(ct.connections))
+	log.Printf("Loaded %d active connections from database", len(loaded))
 	return nil
 }
 
@@ -329,8 +361,17 @@ func (ct *ConnectionTracker) checkConnections() {
 //	}
 //	err := tracker.AddConnection(ctx, conn)
 func (ct *ConnectionTracker) AddConnection(ctx context.Context, conn *Connection) error {
+	// A hibernating session is draining its connections; don't add more
+	var state sql.NullString
+	if err := ct.db.DB().QueryRowContext(ctx, `
+		SELECT state FROM sessions WHERE id = $1
+	`, conn.SessionID).Scan(&state); err == nil && state.String == "hibernating" {
+		return ErrSessionHibernating
+	}
+
 	ct.mu.Lock()
 	ct.connections[conn.ID] = conn
+	listener := ct.listener
 	ct.mu.Unlock()
 
 	// Insert into database
@@ -343,17 +384,26 @@ func (ct *ConnectionTracker) AddConnection(ctx context.Context, conn *Connection
 	}
 
 	// Update session last_connection timestamp
-	_, err = ct.db.DB().ExecContext(ctx, `
+	var count int
+	var namespace sql.NullString
+	err = ct.db.DB().QueryRowContext(ctx, `
 		UPDATE sessions
 		SET last_connection = $1, active_connections = active_connections + 1
 		WHERE id = $2
-	`, time.Now(), conn.SessionID)
+		RETURNING active_connections, namespace
+	`, time.Now(), conn.SessionID).Scan(&count, &namespace)
 	if err != nil {
 		log.Printf("Failed to update session last_connection: %v", err)
+	} else {
+		ct.reportConnectionCount(ctx, conn.SessionID, namespace.String, count)
+	}
+
+	if listener != nil {
+		listener.NotifySessionConnected(conn.SessionID, conn.UserID, conn.ID)
 	}
 
 	// Auto-start session if hibernated
-	go ct.autoStartSession(ctx, conn.SessionID)
+	go ct.autoStartSession(ctx, conn.SessionID, conn.UserID)
 
 	log.Printf("Connection added: %s (session: %s, user: %s)", conn.ID, conn.SessionID, conn.UserID)
 	return nil
@@ -397,12 +447,17 @@ func (ct *ConnectionTracker) removeConnection(ctx context.Context, connectionID
 	if exists {
 		delete(ct.connections, connectionID)
 	}
+	listener := ct.listener
 	ct.mu.Unlock()
 
 	if !exists {
 		return nil // Already removed
 	}
 
+	if listener != nil {
+		listener.NotifySessionDisconnected(conn.SessionID, conn.UserID, connectionID)
+	}
+
 	// Delete from database
 	_, err := ct.db.DB().ExecContext(ctx, `
 		DELETE FROM connections WHERE id = $1
@@ -412,14 +467,19 @@ func (ct *ConnectionTracker) removeConnection(ctx context.Context, connectionID
 	}
 
 	// Update session active connections count
-	_, err = ct.db.DB().ExecContext(ctx, `
+	var count int
+	var namespace sql.NullString
+	err = ct.db.DB().QueryRowContext(ctx, `
 		UPDATE sessions
 		SET active_connections = GREATEST(0, active_connections - 1),
 		    last_disconnect = $1
 		WHERE id = $2
-	`, time.Now(), conn.SessionID)
+		RETURNING active_connections, namespace
+	`, time.Now(), conn.SessionID).Scan(&count, &namespace)
 	if err != nil {
 		log.Printf("Failed to update session active_connections: %v", err)
+	} else {
+		ct.reportConnectionCount(ctx, conn.SessionID, namespace.String, count)
 	}
 
 	log.Printf("Connection removed: %s (session: %s)", connectionID, conn.SessionID)
@@ -467,8 +527,9 @@ func (ct *ConnectionTracker) GetConnection(connectionID string) *Connection {
 	return conn
 }
 
-// autoStartSession automatically starts a hibernated session
-func (ct *ConnectionTracker) autoStartSession(ctx context.Context, sessionID string) {
+// autoStartSession automatically starts a hibernated session the user
+// connected to. The wake is audited as the user's.
+func (ct *ConnectionTracker) autoStartSession(ctx context.Context, sessionID, userID string) {
 	// Get session from K8s
 	// Namespace is typically streamspace, but we should query from DB
 	namespace := ct.getSessionNamespace(ctx, sessionID)
@@ -489,6 +550,10 @@ func (ct *ConnectionTracker) autoStartSession(ctx context.Context, sessionID str
 
 	log.Printf("Auto-starting hibernated session: %s", sessionID)
 
+	if err := events.RequestSessionState(ctx, ct.db.DB(), sessionID, "running", userID); err != nil {
+		log.Printf("Failed to record wake request of session %s: %v", sessionID, err)
+	}
+
 	// Update session state to running
 	_, err = ct.k8sClient.UpdateSessionState(ctx, namespace, sessionID, "running")
 	if err != nil {
@@ -498,9 +563,10 @@ func (ct *ConnectionTracker) autoStartSession(ctx context.Context, sessionID str
 
 	// Publish wake event for controllers
 	event := &events.SessionWakeEvent{
-		SessionID: sessionID,
-		UserID:    session.User,
-		Platform:  ct.platform,
+		SessionID:        sessionID,
+		UserID:           session.User,
+		Platform:         ct.platform,
+		TargetController: session.PinToAgent,
 	}
 	if err := ct.publisher.PublishSessionWake(ctx, event); err != nil {
 		log.Printf("Warning: Failed to publish session wake event: %v", err)
@@ -548,6 +614,10 @@ func (ct *ConnectionTracker) autoHibernateSession(ctx context.Context, sessionID
 
 	log.Printf("Auto-hibernating idle session: %s", sessionID)
 
+	if err := events.RequestSessionState(ctx, ct.db.DB(), sessionID, "hibernated", events.ActorIdleReconciler); err != nil {
+		log.Printf("Failed to record idle hibernation of session %s: %v", sessionID, err)
+	}
+
 	// Update session state to hibernated
 	_, err = ct.k8sClient.UpdateSessionState(ctx, namespace, sessionID, "hibernated")
 	if err != nil {
@@ -557,9 +627,10 @@ func (ct *ConnectionTracker) autoHibernateSession(ctx context.Context, sessionID
 
 	// Publish hibernate event for controllers
 	event := &events.SessionHibernateEvent{
-		SessionID: sessionID,
-		UserID:    session.User,
-		Platform:  ct.platform,
+		SessionID:        sessionID,
+		UserID:           session.User,
+		Platform:         ct.platform,
+		TargetController: session.PinToAgent,
 	}
 	if err := ct.publisher.PublishSessionHibernate(ctx, event); err != nil {
 		log.Printf("Warning: Failed to publish session hibernate event: %v", err)
@@ -579,6 +650,21 @@ func (ct *ConnectionTracker) updateSessionConnectionCount(ctx context.Context, s
 	return err
 }
 
+// reportConnectionCount records a session's open connections on its Session
+// resource, where the controller reads them to drain a hibernating session
+// (Kubernetes only).
+func (ct *ConnectionTracker) reportConnectionCount(ctx context.Context, sessionID, namespace string, count int) {
+	if ct.k8sClient == nil || ct.platform != events.PlatformKubernetes {
+		return
+	}
+	if namespace == "" {
+		namespace = "streamspace" // Default fallback
+	}
+	if err := ct.k8sClient.SetSessionActiveConnections(ctx, namespace, sessionID, count); err != nil {
+		log.Printf("Failed to report connections of session %s: %v", sessionID, err)
+	}
+}
+
 // getSessionNamespace gets the namespace for a session from database
 func (ct *ConnectionTracker) getSessionNamespace(ctx context.Context, sessionID string) string {
 	var namespace string
@@ -0,0 +1,109 @@
+// Package webhooksig implements the signing scheme of StreamSpace webhooks.
+//
+// Every webhook delivery carries:
+//
+//	X-StreamSpace-Timestamp: 1760000000            (Unix seconds of the attempt)
+//	X-StreamSpace-Signature: v1=<hex HMAC-SHA256>  (over "<timestamp>.<body>")
+//	X-StreamSpace-Event-ID:  <uuid>                (same for every delivery of an event)
+//	X-StreamSpace-Delivery:  <id>                  (same for every retry of a delivery)
+//
+// The HMAC key is the webhook's secret. Signing the timestamp together with
+// the body means a captured request can't be replayed later with a fresh
+// timestamp; receivers reject timestamps outside a tolerance (Verify uses
+// DefaultTolerance) and dedupe retries by event ID, which is also the "id"
+// field of the body.
+//
+// Receivers in other languages verify the same way:
+//
+//	expected = "v1=" + hex(hmac_sha256(secret, timestamp + "." + body))
+//	constant_time_equal(expected, signature) and abs(now - timestamp) <= 300
+//
+// The "v1=" prefix versions the scheme; a header may carry several
+// comma-separated signatures (e.g. while a secret is rotated), any of which
+// may match.
+package webhooksig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Webhook delivery headers.
+const (
+	SignatureHeader = "X-StreamSpace-Signature"
+	TimestampHeader = "X-StreamSpace-Timestamp"
+	EventIDHeader   = "X-StreamSpace-Event-ID"
+	DeliveryHeader  = "X-StreamSpace-Delivery"
+)
+
+// signatureVersion prefixes signatures of the current scheme.
+const signatureVersion = "v1="
+
+// DefaultTolerance is how far a delivery's timestamp may be from the
+// receiver's clock.
+const DefaultTolerance = 5 * time.Minute
+
+// Verification errors.
+var (
+	ErrMissingSignature = errors.New("missing webhook signature or timestamp")
+	ErrInvalidTimestamp = errors.New("invalid webhook timestamp")
+	ErrExpiredTimestamp = errors.New("webhook timestamp outside tolerance")
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+)
+
+// Sign returns the signature header value of body sent at timestamp.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	return signatureVersion + hex.EncodeToString(mac(secret, strconv.FormatInt(timestamp.Unix(), 10), body))
+}
+
+// SetHeaders signs body and sets the timestamp and signature headers.
+func SetHeaders(header http.Header, secret string, now time.Time, body []byte) {
+	header.Set(TimestampHeader, strconv.FormatInt(now.Unix(), 10))
+	header.Set(SignatureHeader, Sign(secret, now, body))
+}
+
+// Verify checks the signature and timestamp headers of a delivery. It
+// rejects deliveries whose timestamp is more than tolerance away from now,
+// so a captured delivery can't be replayed later.
+func Verify(secret string, header http.Header, body []byte, tolerance time.Duration, now time.Time) error {
+	signatures, timestamp := header.Get(SignatureHeader), header.Get(TimestampHeader)
+	if signatures == "" || timestamp == "" {
+		return ErrMissingSignature
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	if age := now.Sub(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return ErrExpiredTimestamp
+	}
+
+	expected := mac(secret, timestamp, body)
+	for _, signature := range strings.Split(signatures, ",") {
+		signature = strings.TrimSpace(signature)
+		if !strings.HasPrefix(signature, signatureVersion) {
+			continue
+		}
+		got, err := hex.DecodeString(strings.TrimPrefix(signature, signatureVersion))
+		if err == nil && hmac.Equal(got, expected) {
+			return nil
+		}
+	}
+	return ErrInvalidSignature
+}
+
+// mac computes the HMAC-SHA256 of "<timestamp>.<body>".
+func mac(secret, timestamp string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(secret))
+	h.Write([]byte(timestamp))
+	h.Write([]byte("."))
+	h.Write(body)
+	return h.Sum(nil)
+}
@@ -0,0 +1,49 @@
+package webhooksig
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerify(t *testing.T) {
+	sentAt := time.Unix(1760000000, 0)
+	body := []byte(`{"id":"evt-1","event":"session.ready"}`)
+
+	header := http.Header{}
+	SetHeaders(header, "whsec_test", sentAt, body)
+	assert.Equal(t, "1760000000", header.Get(TimestampHeader))
+
+	assert.NoError(t, Verify("whsec_test", header, body, DefaultTolerance, sentAt.Add(time.Minute)))
+
+	// Tampered body, wrong secret
+	assert.ErrorIs(t, Verify("whsec_test", header, []byte(`{"id":"evt-1","event":"session.failed"}`), DefaultTolerance, sentAt), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("whsec_other", header, body, DefaultTolerance, sentAt), ErrInvalidSignature)
+
+	// Replayed too late, or with a fresh timestamp but the old signature
+	assert.ErrorIs(t, Verify("whsec_test", header, body, DefaultTolerance, sentAt.Add(10*time.Minute)), ErrExpiredTimestamp)
+	replayed := header.Clone()
+	replayed.Set(TimestampHeader, "1760000600")
+	assert.ErrorIs(t, Verify("whsec_test", replayed, body, DefaultTolerance, sentAt.Add(10*time.Minute)), ErrInvalidSignature)
+
+	assert.ErrorIs(t, Verify("whsec_test", http.Header{}, body, DefaultTolerance, sentAt), ErrMissingSignature)
+	garbled := header.Clone()
+	garbled.Set(TimestampHeader, "yesterday")
+	assert.ErrorIs(t, Verify("whsec_test", garbled, body, DefaultTolerance, sentAt), ErrInvalidTimestamp)
+}
+
+func TestVerify_MultipleSignatures(t *testing.T) {
+	sentAt := time.Unix(1760000000, 0)
+	body := []byte(`{}`)
+
+	// During a secret rotation a delivery is signed with both secrets
+	header := http.Header{}
+	header.Set(TimestampHeader, "1760000000")
+	header.Set(SignatureHeader, Sign("whsec_old", sentAt, body)+", "+Sign("whsec_new", sentAt, body))
+
+	assert.NoError(t, Verify("whsec_old", header, body, DefaultTolerance, sentAt))
+	assert.NoError(t, Verify("whsec_new", header, body, DefaultTolerance, sentAt))
+	assert.ErrorIs(t, Verify("whsec_other", header, body, DefaultTolerance, sentAt), ErrInvalidSignature)
+}
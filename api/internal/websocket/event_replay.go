@@ -0,0 +1,232 @@
+// Package websocket - event_replay.go
+//
+// This file keeps recent session events so reconnecting clients can catch
+// up on what they missed.
+//
+// Events are only delivered to connected clients, so a mobile client that
+// drops for a few seconds silently loses every event sent meanwhile - a
+// session.state.changed it never sees leaves its UI wrong until the next
+// full refresh. Every event with a user (except heartbeats) now gets a
+// sequence number ("seq", increasing across all events) and is kept in a
+// per-user ring buffer of the last N events. A client reconnecting with
+//
+//	/api/v1/ws/sessions?since=<last seq it saw>
+//
+// first receives the user's retained events after that sequence, in order,
+// then live events. Replay covers user subscriptions only. An event sent
+// while the connection is being set up may arrive twice, so clients should
+// ignore events whose seq they have already seen. Events older than the
+// buffer or the TTL are gone; a client that was offline longer should
+// reload its state.
+//
+// Environment:
+//   - SESSION_EVENT_REPLAY_SIZE: events kept per user (default 100, 0
+//     disables replay)
+//   - SESSION_EVENT_REPLAY_TTL: how long events are kept (default 1h)
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// defaultEventReplaySize is the default SESSION_EVENT_REPLAY_SIZE.
+	defaultEventReplaySize = 100
+
+	// defaultEventReplayTTL is the default SESSION_EVENT_REPLAY_TTL.
+	defaultEventReplayTTL = time.Hour
+
+	// eventReplaySweepInterval is how often users whose events all expired
+	// are forgotten.
+	eventReplaySweepInterval = time.Minute
+)
+
+// loggedEvent is an event kept for replay.
+type loggedEvent struct {
+	event      SessionEvent
+	recordedAt time.Time
+}
+
+// eventLog keeps the most recent events per user.
+type eventLog struct {
+	mu sync.Mutex
+
+	// size is the ring buffer size per user (0 disables the log).
+	size int
+
+	// ttl is how long events are kept.
+	ttl time.Duration
+
+	// seq is the sequence number of the last recorded event.
+	seq uint64
+
+	// users maps userID -> events, oldest first.
+	users map[string][]loggedEvent
+
+	// lastSweep is when expired users were last forgotten.
+	lastSweep time.Time
+
+	// now is the clock; tests replace it.
+	now func() time.Time
+}
+
+// newEventLog creates an event log keeping size events per user for ttl.
+func newEventLog(size int, ttl time.Duration) *eventLog {
+	return &eventLog{
+		size:  size,
+		ttl:   ttl,
+		users: make(map[string][]loggedEvent),
+		now:   time.Now,
+	}
+}
+
+// eventLogFromEnv creates an event log configured from
+// SESSION_EVENT_REPLAY_SIZE and SESSION_EVENT_REPLAY_TTL.
+func eventLogFromEnv() *eventLog {
+	size := defaultEventReplaySize
+	if v := os.Getenv("SESSION_EVENT_REPLAY_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			size = n
+		} else {
+			log.Printf("Invalid SESSION_EVENT_REPLAY_SIZE %q, using %d", v, size)
+		}
+	}
+	ttl := defaultEventReplayTTL
+	if v := os.Getenv("SESSION_EVENT_REPLAY_TTL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			ttl = d
+		} else {
+			log.Printf("Invalid SESSION_EVENT_REPLAY_TTL %q, using %s", v, ttl)
+		}
+	}
+	return newEventLog(size, ttl)
+}
+
+// record assigns the event the next sequence number and keeps it for its
+// user. Events without a user, heartbeats (superseded by the next one) and
+// all events with the log disabled are returned unchanged.
+func (l *eventLog) record(event SessionEvent) SessionEvent {
+	if l == nil || l.size <= 0 || event.UserID == "" || event.Type == EventSessionHeartbeat {
+		return event
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.seq++
+	event.Seq = l.seq
+
+	now := l.now()
+	events := append(l.live(l.users[event.UserID]), loggedEvent{event: event, recordedAt: now})
+	if len(events) > l.size {
+		events = append([]loggedEvent(nil), events[len(events)-l.size:]...)
+	}
+	l.users[event.UserID] = events
+
+	if now.Sub(l.lastSweep) >= eventReplaySweepInterval {
+		l.lastSweep = now
+		for userID, events := range l.users {
+			if len(l.live(events)) == 0 {
+				delete(l.users, userID)
+			}
+		}
+	}
+	return event
+}
+
+// since returns the user's retained events with a sequence number after
+// seq, oldest first.
+func (l *eventLog) since(userID string, seq uint64) []SessionEvent {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var events []SessionEvent
+	for _, logged := range l.live(l.users[userID]) {
+		if logged.event.Seq > seq {
+			events = append(events, logged.event)
+		}
+	}
+	return events
+}
+
+// live drops the expired events from the front of events. Caller must
+// hold mu.
+func (l *eventLog) live(events []loggedEvent) []loggedEvent {
+	cutoff := l.now().Add(-l.ttl)
+	for len(events) > 0 && events[0].recordedAt.Before(cutoff) {
+		events = events[1:]
+	}
+	return events
+}
+
+// GetEventsSince returns the user's retained events with a sequence number
+// after seq, oldest first. Clients pass the last seq they saw to catch up
+// after a reconnect.
+func (n *Notifier) GetEventsSince(userID string, seq uint64) []SessionEvent {
+	return n.events.since(userID, seq)
+}
+
+// ParseReplaySince parses the ?since=<seq> query parameter of a WebSocket
+// upgrade. It returns nil if the client didn't ask for a replay.
+func ParseReplaySince(value string) *uint64 {
+	if value == "" {
+		return nil
+	}
+	seq, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		log.Printf("Ignoring invalid WebSocket replay sequence %q", value)
+		return nil
+	}
+	return &seq
+}
+
+// serveWithReplay serves a sessions client, first replaying the user's
+// events after since (nil replays nothing).
+func (m *Manager) serveWithReplay(conn *websocket.Conn, clientID, userID string, since *uint64) {
+	if since == nil || userID == "" {
+		m.sessionsHub.ServeClient(conn, clientID)
+		return
+	}
+
+	replayed := m.notifier.GetEventsSince(userID, *since)
+	m.sessionsHub.ServeClient(conn, clientID, encodeEvents(replayed)...)
+
+	// Events recorded while the client was registering weren't in the
+	// replay and may have missed it live as well; send them now (clients
+	// drop the ones they already got by seq)
+	last := *since
+	if len(replayed) > 0 {
+		last = replayed[len(replayed)-1].Seq
+	}
+	for _, data := range encodeEvents(m.notifier.GetEventsSince(userID, last)) {
+		m.sessionsHub.SendTo(func(client *Client) bool { return client.id == clientID }, data)
+	}
+	if len(replayed) > 0 {
+		log.Printf("Replayed %d events for user %s to client %s", len(replayed), userID, clientID)
+	}
+}
+
+// encodeEvents marshals events for a client, skipping any that fail.
+func encodeEvents(events []SessionEvent) [][]byte {
+	encoded := make([][]byte, 0, len(events))
+	for _, event := range events {
+		data, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("Failed to marshal replayed session event: %v", err)
+			continue
+		}
+		encoded = append(encoded, data)
+	}
+	return encoded
+}
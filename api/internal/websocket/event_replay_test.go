@@ -0,0 +1,115 @@
+package websocket
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventLog_RingBufferPerUser(t *testing.T) {
+	l := newEventLog(3, time.Hour)
+
+	for i := 0; i < 5; i++ {
+		l.record(SessionEvent{Type: EventSessionStateChange, SessionID: "alice-firefox", UserID: "alice"})
+	}
+	bob := l.record(SessionEvent{Type: EventSessionCreated, SessionID: "bob-vscode", UserID: "bob"})
+	assert.Equal(t, uint64(6), bob.Seq, "sequence numbers increase across users")
+
+	// Only the last 3 of alice's events are kept
+	events := l.since("alice", 0)
+	require.Len(t, events, 3)
+	assert.Equal(t, []uint64{3, 4, 5}, []uint64{events[0].Seq, events[1].Seq, events[2].Seq})
+
+	events = l.since("alice", 4)
+	require.Len(t, events, 1)
+	assert.Equal(t, uint64(5), events[0].Seq)
+	assert.Empty(t, l.since("alice", 5))
+	assert.Empty(t, l.since("carol", 0))
+}
+
+func TestEventLog_SkipsUnroutableAndHeartbeats(t *testing.T) {
+	l := newEventLog(10, time.Hour)
+
+	assert.Zero(t, l.record(SessionEvent{Type: EventSessionCreated, SessionID: "s1"}).Seq)
+	assert.Zero(t, l.record(SessionEvent{Type: EventSessionHeartbeat, SessionID: "s1", UserID: "alice"}).Seq)
+	assert.Empty(t, l.since("alice", 0))
+
+	disabled := newEventLog(0, time.Hour)
+	assert.Zero(t, disabled.record(SessionEvent{Type: EventSessionCreated, UserID: "alice"}).Seq)
+	assert.Empty(t, disabled.since("alice", 0))
+}
+
+func TestEventLog_ExpiresOldEvents(t *testing.T) {
+	l := newEventLog(10, time.Hour)
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	l.now = func() time.Time { return now }
+
+	l.record(SessionEvent{Type: EventSessionCreated, UserID: "alice"})
+	l.record(SessionEvent{Type: EventSessionCreated, UserID: "bob"})
+	now = now.Add(45 * time.Minute)
+	l.record(SessionEvent{Type: EventSessionStateChange, UserID: "alice"})
+
+	now = now.Add(30 * time.Minute)
+	events := l.since("alice", 0)
+	require.Len(t, events, 1)
+	assert.Equal(t, uint64(3), events[0].Seq)
+	assert.Empty(t, l.since("bob", 0))
+
+	// The next sweep forgets users whose events all expired
+	l.record(SessionEvent{Type: EventSessionCreated, UserID: "carol"})
+	assert.NotContains(t, l.users, "bob")
+	assert.Contains(t, l.users, "alice")
+}
+
+func TestNotifier_GetEventsSince(t *testing.T) {
+	n := testNotifier(10)
+
+	// Events are kept even when nobody is subscribed
+	n.NotifySessionStateChange("alice-firefox", "alice", "pending", "running")
+	n.NotifySessionDeleted("alice-firefox", "alice")
+
+	events := n.GetEventsSince("alice", 0)
+	require.Len(t, events, 2)
+	assert.Equal(t, EventSessionStateChange, events[0].Type)
+	assert.Equal(t, EventSessionDeleted, events[1].Type)
+
+	events = n.GetEventsSince("alice", events[0].Seq)
+	require.Len(t, events, 1)
+	assert.Equal(t, EventSessionDeleted, events[0].Type)
+}
+
+func TestParseReplaySince(t *testing.T) {
+	assert.Nil(t, ParseReplaySince(""))
+	assert.Nil(t, ParseReplaySince("latest"))
+	assert.Nil(t, ParseReplaySince("-1"))
+
+	since := ParseReplaySince("42")
+	require.NotNil(t, since)
+	assert.Equal(t, uint64(42), *since)
+
+	since = ParseReplaySince("0")
+	require.NotNil(t, since)
+	assert.Zero(t, *since)
+}
+
+func TestEventLogFromEnv(t *testing.T) {
+	t.Setenv("SESSION_EVENT_REPLAY_SIZE", "")
+	t.Setenv("SESSION_EVENT_REPLAY_TTL", "")
+	l := eventLogFromEnv()
+	assert.Equal(t, defaultEventReplaySize, l.size)
+	assert.Equal(t, defaultEventReplayTTL, l.ttl)
+
+	t.Setenv("SESSION_EVENT_REPLAY_SIZE", "0")
+	t.Setenv("SESSION_EVENT_REPLAY_TTL", "10m")
+	l = eventLogFromEnv()
+	assert.Zero(t, l.size)
+	assert.Equal(t, 10*time.Minute, l.ttl)
+
+	t.Setenv("SESSION_EVENT_REPLAY_SIZE", "lots")
+	t.Setenv("SESSION_EVENT_REPLAY_TTL", "-1h")
+	l = eventLogFromEnv()
+	assert.Equal(t, defaultEventReplaySize, l.size)
+	assert.Equal(t, defaultEventReplayTTL, l.ttl)
+}
@@ -52,9 +52,11 @@
 //
 //	// Handle WebSocket connections
 //	router.GET("/ws/sessions", func(c *gin.Context) {
-//	    userID := c.Query("user_id")
+//	    userID, role := c.GetString("userID"), c.GetString("userRole")
 //	    conn, _ := upgrader.Upgrade(c.Writer, c.Request, nil)
-//	    manager.HandleSessionsWebSocket(conn, userID, "")
+//	    viewer := websocket.Viewer{UserID: userID, Role: role}
+//	    since := websocket.ParseReplaySince(c.Query("since"))
+//	    manager.HandleSessionsWebSocket(conn, viewer, userID, "", since)
 //	})
 //
 //	// Shutdown cleanly
@@ -96,6 +98,9 @@ func NewManager(database *db.Database, k8sClient *k8s.Client) *Manager {
 	}
 	// Initialize notifier with reference to manager
 	m.notifier = NewNotifier(m)
+	if database != nil {
+		m.notifier.SetAuthorizer(&dbSubscriptionAuthorizer{db: database})
+	}
 	return m
 }
 
@@ -105,6 +110,9 @@ func (m *Manager) Start() {
 	go m.metricsHub.Run()
 	go m.broadcastSessionUpdates()
 	go m.broadcastMetrics()
+	if interval := sessionHeartbeatIntervalFromEnv(); interval > 0 {
+		go m.sessionHeartbeats(interval)
+	}
 }
 
 // GetNotifier returns the notifier for event-driven notifications
@@ -112,25 +120,49 @@ func (m *Manager) GetNotifier() *Notifier {
 	return m.notifier
 }
 
+// Stats returns delivery counters for each hub, keyed by hub name.
+// Subscription counts are reported separately by GetNotifier().Stats().
+func (m *Manager) Stats() map[string]HubStats {
+	return map[string]HubStats{
+		"sessions": m.sessionsHub.Stats(),
+		"metrics":  m.metricsHub.Stats(),
+	}
+}
+
 // HandleSessionsWebSocket handles WebSocket connections for session updates
 // Supports subscribing to user-specific or session-specific events via query params:
 // - ?user_id=<userID> - Subscribe to all events for a specific user
 // - ?session_id=<sessionID> - Subscribe to events for a specific session
-func (m *Manager) HandleSessionsWebSocket(conn *websocket.Conn, userID, sessionID string) {
+//
+// Subscriptions are authorized for viewer; if one is rejected the error is
+// sent to the client and the connection is closed. Every client also gets
+// the events sent to its viewer's role (see Notifier.NotifyRole).
+//
+// With since set (see ParseReplaySince), the user's events after that
+// sequence are replayed before live events (see event_replay.go).
+func (m *Manager) HandleSessionsWebSocket(conn *websocket.Conn, viewer Viewer, userID, sessionID string, since *uint64) {
 	clientID := uuid.New().String()
 
+	// Cleanup subscription on disconnect
+	defer m.notifier.UnsubscribeClient(clientID)
+	m.notifier.SubscribeRole(clientID, viewer.Role)
+
 	// Subscribe to user or session events if specified
+	var err error
 	if userID != "" {
-		m.notifier.SubscribeUser(clientID, userID)
+		err = m.notifier.SubscribeUser(clientID, viewer, userID)
 	}
-	if sessionID != "" {
-		m.notifier.SubscribeSession(clientID, sessionID)
+	if err == nil && sessionID != "" {
+		err = m.notifier.SubscribeSession(context.Background(), clientID, viewer, sessionID)
+	}
+	if err != nil {
+		log.Printf("Rejected WebSocket subscription for user %s (user=%q session=%q): %v", viewer.UserID, userID, sessionID, err)
+		conn.WriteJSON(map[string]interface{}{"error": err.Error()})
+		conn.Close()
+		return
 	}
 
-	// Cleanup subscription on disconnect
-	defer m.notifier.UnsubscribeClient(clientID)
-
-	m.sessionsHub.ServeClient(conn, clientID)
+	m.serveWithReplay(conn, clientID, userID, since)
 }
 
 // CloseAll closes all WebSocket connections and subscriptions
@@ -142,24 +174,12 @@ func (m *Manager) CloseAll() {
 		m.notifier.CloseAll()
 	}
 
-	// Close session hub clients
+	// Close hub clients
 	if m.sessionsHub != nil {
-		m.sessionsHub.mu.Lock()
-		for client := range m.sessionsHub.clients {
-			close(client.send)
-		}
-		m.sessionsHub.clients = make(map[*Client]bool)
-		m.sessionsHub.mu.Unlock()
+		m.sessionsHub.CloseAll()
 	}
-
-	// Close metrics hub clients
 	if m.metricsHub != nil {
-		m.metricsHub.mu.Lock()
-		for client := range m.metricsHub.clients {
-			close(client.send)
-		}
-		m.metricsHub.clients = make(map[*Client]bool)
-		m.metricsHub.mu.Unlock()
+		m.metricsHub.CloseAll()
 	}
 
 	log.Println("All WebSocket connections closed")
@@ -0,0 +1,95 @@
+// Package websocket - heartbeats.go
+//
+// This file emits periodic session.heartbeat events.
+//
+// Event-driven notifications only fire when something changes, so a UI
+// watching a quiet session can't tell a healthy stream from a stalled
+// feed. On every tick, each running session with at least one subscriber
+// (of the session or of its owner) gets a heartbeat carrying its current
+// connection counts and resource usage. Sessions nobody is subscribed to
+// and hibernated or terminated sessions get none, so an idle deployment
+// sends nothing.
+//
+// Environment:
+//   - SESSION_HEARTBEAT_INTERVAL: time between heartbeats (default 15s,
+//     0 disables them)
+package websocket
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/streamspace/streamspace/api/internal/k8s"
+)
+
+// defaultSessionHeartbeatInterval is the default SESSION_HEARTBEAT_INTERVAL.
+const defaultSessionHeartbeatInterval = 15 * time.Second
+
+// sessionHeartbeatIntervalFromEnv reads SESSION_HEARTBEAT_INTERVAL.
+func sessionHeartbeatIntervalFromEnv() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("SESSION_HEARTBEAT_INTERVAL")); err == nil && d >= 0 {
+		return d
+	}
+	return defaultSessionHeartbeatInterval
+}
+
+// sessionHeartbeats sends heartbeats every interval until the process exits.
+func (m *Manager) sessionHeartbeats(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if m.sessionsHub.ClientCount() == 0 {
+			continue // No clients, skip
+		}
+
+		sessions, err := m.k8sClient.ListSessions(context.Background(), "streamspace")
+		if err != nil {
+			log.Printf("Failed to fetch sessions for heartbeats: %v", err)
+			continue
+		}
+
+		for _, event := range m.notifier.heartbeatEvents(sessions, time.Now()) {
+			m.notifier.NotifySessionEvent(event)
+		}
+	}
+}
+
+// heartbeatEvents returns the heartbeats due for sessions: one per running
+// session that has subscribers.
+func (n *Notifier) heartbeatEvents(sessions []*k8s.Session, now time.Time) []SessionEvent {
+	events := []SessionEvent{}
+	for _, session := range sessions {
+		if session.State != "running" || !n.hasSubscribers(session.Name, session.User) {
+			continue
+		}
+
+		viewers := n.viewers.get(session.Name)
+		events = append(events, SessionEvent{
+			Type:      EventSessionHeartbeat,
+			SessionID: session.Name,
+			UserID:    session.User,
+			Timestamp: now,
+			Data: map[string]interface{}{
+				"activeConnections": viewers.Connections,
+				"viewers":           viewers.Viewers,
+				"resourceUsage": map[string]string{
+					"cpu":    session.Status.ResourceUsage.CPU,
+					"memory": session.Status.ResourceUsage.Memory,
+				},
+			},
+		})
+	}
+	return events
+}
+
+// hasSubscribers reports whether any client is subscribed to the session
+// or to its owner.
+func (n *Notifier) hasSubscribers(sessionID, userID string) bool {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	return len(n.sessionSubscriptions[sessionID]) > 0 ||
+		(userID != "" && len(n.userSubscriptions[userID]) > 0)
+}
@@ -0,0 +1,62 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/streamspace/streamspace/api/internal/k8s"
+)
+
+func TestHeartbeatEvents_OnlyRunningSessionsWithSubscribers(t *testing.T) {
+	n := testNotifier(10)
+	ctx := context.Background()
+	alice := Viewer{UserID: "alice", Role: "user"}
+	require.NoError(t, n.SubscribeSession(ctx, "c1", alice, "alice-firefox"))
+	require.NoError(t, n.SubscribeUser("c2", Viewer{UserID: "root", Role: "admin"}, "carol"))
+	n.viewers.connect("alice-firefox", "alice", "conn1")
+	n.viewers.connect("alice-firefox", "alice", "conn2")
+
+	running := &k8s.Session{Name: "alice-firefox", User: "alice", State: "running"}
+	running.Status.ResourceUsage.CPU = "250m"
+	running.Status.ResourceUsage.Memory = "1Gi"
+	sessions := []*k8s.Session{
+		running,
+		{Name: "carol-vscode", User: "carol", State: "running"},  // owner subscribed
+		{Name: "carol-gimp", User: "carol", State: "hibernated"}, // not running
+		{Name: "bob-private", User: "bob", State: "running"},     // no subscribers
+	}
+
+	now := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	events := n.heartbeatEvents(sessions, now)
+	require.Len(t, events, 2)
+
+	assert.Equal(t, EventSessionHeartbeat, events[0].Type)
+	assert.Equal(t, "alice-firefox", events[0].SessionID)
+	assert.Equal(t, now, events[0].Timestamp)
+	assert.Equal(t, 2, events[0].Data["activeConnections"])
+	assert.Equal(t, 1, events[0].Data["viewers"])
+	assert.Equal(t, map[string]string{"cpu": "250m", "memory": "1Gi"}, events[0].Data["resourceUsage"])
+
+	assert.Equal(t, "carol-vscode", events[1].SessionID)
+	assert.Equal(t, 0, events[1].Data["activeConnections"])
+
+	// Once the last subscriber leaves, heartbeats stop
+	n.UnsubscribeClient("c1")
+	n.UnsubscribeClient("c2")
+	assert.Empty(t, n.heartbeatEvents(sessions, now))
+}
+
+func TestSessionHeartbeatIntervalFromEnv(t *testing.T) {
+	t.Setenv("SESSION_HEARTBEAT_INTERVAL", "")
+	assert.Equal(t, defaultSessionHeartbeatInterval, sessionHeartbeatIntervalFromEnv())
+
+	t.Setenv("SESSION_HEARTBEAT_INTERVAL", "30s")
+	assert.Equal(t, 30*time.Second, sessionHeartbeatIntervalFromEnv())
+
+	t.Setenv("SESSION_HEARTBEAT_INTERVAL", "0")
+	assert.Equal(t, time.Duration(0), sessionHeartbeatIntervalFromEnv())
+}
@@ -25,7 +25,7 @@
 // Concurrency:
 //   - Hub.Run() runs in goroutine, handles all channel operations
 //   - Each Client has readPump and writePump goroutines
-//   - Thread-safe with sync.RWMutex for shared state
+//   - Registration and fan-out are shared with the enterprise hub (see internal/wshub)
 //
 // Example usage:
 //
@@ -41,57 +41,33 @@ package websocket
 
 import (
 	"log"
-	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
+	"github.com/streamspace/streamspace/api/internal/wshub"
 )
 
 // Hub maintains active WebSocket connections and implements message broadcasting.
 //
-// The Hub pattern:
-//   - Centralizes connection management
-//   - Provides thread-safe registration/unregistration
-//   - Broadcasts messages to all clients efficiently
-//   - Handles slow/disconnected clients gracefully
-//
-// Channel-based design:
-//   - register: New clients connect
-//   - unregister: Clients disconnect
-//   - broadcast: Messages to send to all clients
-//   - All operations go through channels to avoid race conditions
+// Registration, broadcast and slow-client eviction come from the shared
+// wshub.Hub (also used by the enterprise hub in internal/handlers); Hub adds
+// the pumps that move pre-encoded JSON to the browser and delivery stats.
 //
 // Hub lifecycle:
 //  1. Create with NewHub()
 //  2. Start with go hub.Run()
 //  3. Clients connect via ServeClient()
-//  4. Send messages via Broadcast()
+//  4. Send messages via Broadcast() or SendTo()
 //  5. Clients disconnect automatically on connection close
 //
 // Thread safety:
-//   - All client map access protected by sync.RWMutex
-//   - Channel operations are inherently thread-safe
-//   - Safe to call Broadcast() from multiple goroutines
+//   - Safe to call Broadcast(), SendTo() and ClientCount() from multiple goroutines
 type Hub struct {
-	// clients is the set of registered clients.
-	// Map key: *Client, Map value: bool (always true, used as a set)
-	clients map[*Client]bool
-
-	// broadcast is the channel for messages to send to all clients.
-	// Buffer size: 256 messages
-	broadcast chan []byte
-
-	// register is the channel for new client registration requests.
-	// Unbuffered channel (synchronous registration)
-	register chan *Client
+	*wshub.Hub[*Client, []byte]
 
-	// unregister is the channel for client disconnection requests.
-	// Unbuffered channel (synchronous unregistration)
-	unregister chan *Client
-
-	// mu protects concurrent access to clients map.
-	// Used when checking client count or iterating clients.
-	mu sync.RWMutex
+	// stats holds cumulative delivery counters across all clients.
+	// Updated atomically by each client's writePump (see stats.go).
+	stats connStats
 }
 
 // Client represents an individual WebSocket connection.
@@ -139,76 +115,28 @@ type Client struct {
 	// id uniquely identifies this client.
 	// Format: "{userID}-{sessionID}" or UUID
 	id string
+
+	// stats holds this connection's delivery counters.
+	stats connStats
+
+	// wire counts bytes written to the network, if the connection was
+	// upgraded through CountWireBytes. Nil otherwise.
+	wire *wireCountingConn
 }
 
 // NewHub creates a new WebSocket hub
 func NewHub() *Hub {
-	return &Hub{
-		broadcast:  make(chan []byte, 256),
-		register:   make(chan *Client),
-		unregister: make(chan *Client),
-		clients:    make(map[*Client]bool),
-	}
-}
-
-// Run starts the hub's main loop
-func (h *Hub) Run() {
-	for {
-		select {
-		case client := <-h.register:
-			h.mu.Lock()
-			h.clients[client] = true
-			h.mu.Unlock()
-			log.Printf("WebSocket client registered: %s (total: %d)", client.id, len(h.clients))
-
-		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
-				log.Printf("WebSocket client unregistered: %s (total: %d)", client.id, len(h.clients))
-			}
-			h.mu.Unlock()
-
-		case message := <-h.broadcast:
-			// BUG FIX: Collect clients to close first, then modify map with write lock
-			// Using RLock while iterating, but need write lock to modify map
-			h.mu.RLock()
-			clientsToClose := make([]*Client, 0)
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-					// Successfully sent
-				default:
-					// Client's send buffer is full, mark for closing
-					clientsToClose = append(clientsToClose, client)
-				}
-			}
-			h.mu.RUnlock()
-
-			// Now close and remove blocked clients with write lock
-			if len(clientsToClose) > 0 {
-				h.mu.Lock()
-				for _, client := range clientsToClose {
-					close(client.send)
-					delete(h.clients, client)
-				}
-				h.mu.Unlock()
-			}
-		}
-	}
+	return &Hub{Hub: wshub.New[*Client, []byte]("Sessions", 256)}
 }
 
-// Broadcast sends a message to all connected clients
-func (h *Hub) Broadcast(message []byte) {
-	h.broadcast <- message
+// HubID implements wshub.Client.
+func (c *Client) HubID() string {
+	return c.id
 }
 
-// ClientCount returns the number of connected clients
-func (h *Hub) ClientCount() int {
-	h.mu.RLock()
-	defer h.mu.RUnlock()
-	return len(h.clients)
+// Outbox implements wshub.Client.
+func (c *Client) Outbox() chan []byte {
+	return c.send
 }
 
 // writePump pumps messages from the hub to the websocket connection
@@ -230,10 +158,15 @@ func (c *Client) writePump() {
 				return
 			}
 
-			w, err := c.conn.NextWriter(websocket.TextMessage)
+			nw, err := c.conn.NextWriter(websocket.TextMessage)
 			if err != nil {
 				return
 			}
+			var wireBefore uint64
+			if c.wire != nil {
+				wireBefore = c.wire.written.Load()
+			}
+			w := &countingWriter{w: nw}
 			w.Write(message)
 
 			// Add queued messages to the current websocket message
@@ -243,10 +176,16 @@ func (c *Client) writePump() {
 				w.Write(<-c.send)
 			}
 
-			if err := w.Close(); err != nil {
+			if err := nw.Close(); err != nil {
 				return
 			}
 
+			var wire uint64
+			if c.wire != nil {
+				wire = c.wire.written.Load() - wireBefore
+			}
+			c.recordFrame(n+1, w.n, wire)
+
 		case <-ticker.C:
 			// Send ping to keep connection alive
 			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
@@ -260,7 +199,7 @@ func (c *Client) writePump() {
 // readPump pumps messages from the websocket connection to the hub
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		c.hub.Unregister(c)
 		c.conn.Close()
 	}()
 
@@ -289,16 +228,30 @@ func (c *Client) readPump() {
 	}
 }
 
-// ServeClient handles a new WebSocket connection
-func (h *Hub) ServeClient(conn *websocket.Conn, clientID string) {
+// ServeClient handles a new WebSocket connection. The backlog messages
+// (e.g. replayed events) are sent before any message broadcast after the
+// client registers; any beyond the send buffer are dropped.
+func (h *Hub) ServeClient(conn *websocket.Conn, clientID string, backlog ...[]byte) {
 	client := &Client{
 		hub:  h,
 		conn: conn,
 		send: make(chan []byte, 256),
 		id:   clientID,
 	}
+	if wire, ok := conn.NetConn().(*wireCountingConn); ok {
+		client.wire = wire
+	}
+
+	// Nothing else sends to the client before it registers
+	for i, message := range backlog {
+		if len(client.send) == cap(client.send) {
+			log.Printf("Dropped %d backlog messages for client %s (buffer full)", len(backlog)-i, clientID)
+			break
+		}
+		client.send <- message
+	}
 
-	client.hub.register <- client
+	client.hub.Register(client)
 
 	// Start pumps in separate goroutines
 	go client.writePump()
@@ -1,6 +1,7 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 	"sync"
@@ -39,6 +40,11 @@ const (
 	// Data: oldState, newState (running→hibernated, etc.)
 	EventSessionStateChange EventType = "session.state.changed"
 
+	// EventSessionAdminTerminated is emitted when an admin terminates a
+	// user's session.
+	// Data: reason
+	EventSessionAdminTerminated EventType = "session.admin_terminated"
+
 	// Session activity events - connection and usage tracking
 
 	// EventSessionConnected is emitted when a user connects to a session.
@@ -49,8 +55,9 @@ const (
 	// Data: connectionId, duration
 	EventSessionDisconnected EventType = "session.disconnected"
 
-	// EventSessionHeartbeat is emitted on periodic heartbeat (optional).
-	// Data: timestamp, active connections count
+	// EventSessionHeartbeat is emitted periodically for running sessions
+	// with subscribers (see heartbeats.go).
+	// Data: activeConnections, viewers, resourceUsage (cpu, memory)
 	EventSessionHeartbeat EventType = "session.heartbeat"
 
 	// EventSessionIdle is emitted when session becomes idle.
@@ -128,6 +135,10 @@ type SessionEvent struct {
 	// Data contains event-specific payload (optional).
 	// Structure depends on event type.
 	Data map[string]interface{} `json:"data,omitempty"`
+
+	// Seq orders the events kept for replay (see event_replay.go).
+	// Clients reconnect with the last one they saw.
+	Seq uint64 `json:"seq,omitempty"`
 }
 
 // Notifier handles event subscriptions and targeted real-time notifications.
@@ -135,14 +146,18 @@ type SessionEvent struct {
 // The Notifier implements a pub/sub pattern:
 //   - Clients subscribe to user events (all sessions for a user)
 //   - Clients subscribe to session events (specific session)
-//   - Backend emits events via NotifySessionEvent()
+//   - Clients subscribe to their role's events (system-wide events)
+//   - Backend emits events via NotifySessionEvent() or NotifyRole()
 //   - Notifier routes events to subscribed clients
 //   - Hub delivers messages over WebSocket
 //
 // Subscription model:
 //   - User subscriptions: Get all events for a user's sessions
 //   - Session subscriptions: Get events for a specific session
-//   - Clients can have both types of subscriptions simultaneously
+//   - Role subscriptions: Get events for everyone with a role, e.g.
+//     compliance and system events for admins
+//   - Clients can have all types of subscriptions simultaneously
+//   - Subscriptions are authorized and capped per client (see subscriptions.go)
 //
 // Thread safety:
 //   - All map access protected by sync.RWMutex
@@ -152,8 +167,8 @@ type SessionEvent struct {
 //
 //	notifier := NewNotifier(manager)
 //
-//	// Client subscribes to user events
-//	notifier.SubscribeUser(clientID, userID)
+//	// Client subscribes to its own user's events
+//	err := notifier.SubscribeUser(clientID, viewer, viewer.UserID)
 //
 //	// Backend emits event
 //	notifier.NotifySessionCreated(sessionID, userID, data)
@@ -176,10 +191,39 @@ type Notifier struct {
 	// Clients in this map receive events only for that specific session.
 	sessionSubscriptions map[string]map[string]bool
 
-	// clientUsers maps client IDs to their associated userID.
-	// clientID -> userID
-	// Used for cleanup when client disconnects.
-	clientUsers map[string]string
+	// clientUsers maps client IDs to the users they are subscribed to.
+	// clientID -> set of userIDs
+	// Used for limits and cleanup when client disconnects.
+	clientUsers map[string]map[string]bool
+
+	// clientSessions maps client IDs to the sessions they are subscribed to.
+	// clientID -> set of sessionIDs
+	clientSessions map[string]map[string]bool
+
+	// roleSubscriptions maps roles to set of subscribed client IDs.
+	// role -> set of client IDs
+	// Clients in this map receive events sent to the role via NotifyRole.
+	roleSubscriptions map[string]map[string]bool
+
+	// clientRoles maps client IDs to the roles they are subscribed to.
+	// clientID -> set of roles
+	clientRoles map[string]map[string]bool
+
+	// maxPerClient caps user + session subscriptions per client.
+	maxPerClient int
+
+	// authorizer decides which sessions non-privileged viewers may observe.
+	authorizer SubscriptionAuthorizer
+
+	// viewers counts the users connected to each session (see viewers.go).
+	viewers *viewerRegistry
+
+	// events keeps recent events per user for replay (see event_replay.go).
+	events *eventLog
+
+	// Rejected subscription attempts (reported by Stats).
+	rejectedLimit     uint64
+	rejectedForbidden uint64
 }
 
 // NewNotifier creates a new event notifier
@@ -188,15 +232,43 @@ func NewNotifier(manager *Manager) *Notifier {
 		manager:              manager,
 		userSubscriptions:    make(map[string]map[string]bool),
 		sessionSubscriptions: make(map[string]map[string]bool),
-		clientUsers:          make(map[string]string),
+		clientUsers:          make(map[string]map[string]bool),
+		clientSessions:       make(map[string]map[string]bool),
+		roleSubscriptions:    make(map[string]map[string]bool),
+		clientRoles:          make(map[string]map[string]bool),
+		maxPerClient:         maxSubscriptionsPerClientFromEnv(),
+		viewers:              newViewerRegistry(),
+		events:               eventLogFromEnv(),
 	}
 }
 
-// SubscribeUser subscribes a client to receive events for a specific user
-func (n *Notifier) SubscribeUser(clientID, userID string) {
+// SetAuthorizer sets the authorizer used for session subscriptions.
+// Without one, only admins and operators may subscribe to sessions.
+func (n *Notifier) SetAuthorizer(authorizer SubscriptionAuthorizer) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.authorizer = authorizer
+}
+
+// SubscribeUser subscribes a client to receive events for a specific user.
+//
+// Viewers may subscribe to their own user; admins and operators to any user.
+// Returns ErrSubscriptionForbidden or ErrSubscriptionLimit on rejection.
+func (n *Notifier) SubscribeUser(clientID string, viewer Viewer, userID string) error {
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
+	if userID != viewer.UserID && !viewer.privileged() {
+		n.rejectedForbidden++
+		return ErrSubscriptionForbidden
+	}
+	if n.clientUsers[clientID][userID] {
+		return nil
+	}
+	if err := n.checkLimit(clientID); err != nil {
+		return err
+	}
+
 	// Add to user subscriptions
 	if _, exists := n.userSubscriptions[userID]; !exists {
 		n.userSubscriptions[userID] = make(map[string]bool)
@@ -204,22 +276,102 @@ func (n *Notifier) SubscribeUser(clientID, userID string) {
 	n.userSubscriptions[userID][clientID] = true
 
 	// Track client to user mapping
-	n.clientUsers[clientID] = userID
+	if _, exists := n.clientUsers[clientID]; !exists {
+		n.clientUsers[clientID] = make(map[string]bool)
+	}
+	n.clientUsers[clientID][userID] = true
 
 	log.Printf("Client %s subscribed to user %s events", clientID, userID)
+	return nil
 }
 
-// SubscribeSession subscribes a client to receive events for a specific session
-func (n *Notifier) SubscribeSession(clientID, sessionID string) {
+// SubscribeSession subscribes a client to receive events for a specific session.
+//
+// Viewers may subscribe to sessions they own or that are shared with them;
+// admins and operators to any session. Returns ErrSubscriptionForbidden or
+// ErrSubscriptionLimit on rejection.
+func (n *Notifier) SubscribeSession(ctx context.Context, clientID string, viewer Viewer, sessionID string) error {
+	if !viewer.privileged() {
+		n.mu.RLock()
+		authorizer := n.authorizer
+		n.mu.RUnlock()
+
+		// Checked outside the lock so a slow query doesn't block notifications
+		allowed := false
+		if authorizer != nil {
+			ok, err := authorizer.CanObserveSession(ctx, viewer.UserID, sessionID)
+			if err != nil {
+				log.Printf("Failed to authorize session subscription for user %s: %v", viewer.UserID, err)
+			}
+			allowed = ok && err == nil
+		}
+		if !allowed {
+			n.mu.Lock()
+			n.rejectedForbidden++
+			n.mu.Unlock()
+			return ErrSubscriptionForbidden
+		}
+	}
+
 	n.mu.Lock()
 	defer n.mu.Unlock()
 
+	if n.clientSessions[clientID][sessionID] {
+		return nil
+	}
+	if err := n.checkLimit(clientID); err != nil {
+		return err
+	}
+
 	if _, exists := n.sessionSubscriptions[sessionID]; !exists {
 		n.sessionSubscriptions[sessionID] = make(map[string]bool)
 	}
 	n.sessionSubscriptions[sessionID][clientID] = true
 
+	if _, exists := n.clientSessions[clientID]; !exists {
+		n.clientSessions[clientID] = make(map[string]bool)
+	}
+	n.clientSessions[clientID][sessionID] = true
+
 	log.Printf("Client %s subscribed to session %s events", clientID, sessionID)
+	return nil
+}
+
+// SubscribeRole subscribes a client to events sent to everyone with a role.
+//
+// The role is not checked: callers pass the connecting viewer's own role,
+// never one requested by the client. Role subscriptions don't count
+// against the per-client limit, since a client has only one role.
+func (n *Notifier) SubscribeRole(clientID, role string) {
+	if role == "" {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if _, exists := n.roleSubscriptions[role]; !exists {
+		n.roleSubscriptions[role] = make(map[string]bool)
+	}
+	n.roleSubscriptions[role][clientID] = true
+
+	if _, exists := n.clientRoles[clientID]; !exists {
+		n.clientRoles[clientID] = make(map[string]bool)
+	}
+	n.clientRoles[clientID][role] = true
+
+	log.Printf("Client %s subscribed to role %s events", clientID, role)
+}
+
+// checkLimit returns ErrSubscriptionLimit if the client can't take another
+// subscription. Caller must hold mu.
+func (n *Notifier) checkLimit(clientID string) error {
+	if len(n.clientUsers[clientID])+len(n.clientSessions[clientID]) >= n.maxPerClient {
+		n.rejectedLimit++
+		log.Printf("Client %s reached the subscription limit (%d)", clientID, n.maxPerClient)
+		return ErrSubscriptionLimit
+	}
+	return nil
 }
 
 // UnsubscribeClient removes all subscriptions for a client
@@ -228,31 +380,75 @@ func (n *Notifier) UnsubscribeClient(clientID string) {
 	defer n.mu.Unlock()
 
 	// Remove from user subscriptions
-	if userID, exists := n.clientUsers[clientID]; exists {
+	for userID := range n.clientUsers[clientID] {
 		if clients, exists := n.userSubscriptions[userID]; exists {
 			delete(clients, clientID)
 			if len(clients) == 0 {
 				delete(n.userSubscriptions, userID)
 			}
 		}
-		delete(n.clientUsers, clientID)
 	}
+	delete(n.clientUsers, clientID)
 
 	// Remove from session subscriptions
-	for sessionID, clients := range n.sessionSubscriptions {
-		if clients[clientID] {
+	for sessionID := range n.clientSessions[clientID] {
+		if clients, exists := n.sessionSubscriptions[sessionID]; exists {
 			delete(clients, clientID)
 			if len(clients) == 0 {
 				delete(n.sessionSubscriptions, sessionID)
 			}
 		}
 	}
+	delete(n.clientSessions, clientID)
+
+	// Remove from role subscriptions
+	for role := range n.clientRoles[clientID] {
+		if clients, exists := n.roleSubscriptions[role]; exists {
+			delete(clients, clientID)
+			if len(clients) == 0 {
+				delete(n.roleSubscriptions, role)
+			}
+		}
+	}
+	delete(n.clientRoles, clientID)
 
 	log.Printf("Client %s unsubscribed from all events", clientID)
 }
 
-// NotifySessionEvent sends a session event to subscribed clients
+// Stats returns current subscription counts and rejected attempts.
+func (n *Notifier) Stats() SubscriptionStats {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	stats := SubscriptionStats{
+		MaxPerClient:      n.maxPerClient,
+		RejectedLimit:     n.rejectedLimit,
+		RejectedForbidden: n.rejectedForbidden,
+	}
+	clients := make(map[string]bool)
+	for clientID, users := range n.clientUsers {
+		clients[clientID] = true
+		stats.UserSubscriptions += len(users)
+	}
+	for clientID, sessions := range n.clientSessions {
+		clients[clientID] = true
+		stats.SessionSubscriptions += len(sessions)
+	}
+	for clientID, roles := range n.clientRoles {
+		clients[clientID] = true
+		stats.RoleSubscriptions += len(roles)
+	}
+	stats.Clients = len(clients)
+	stats.TotalSubscriptions = stats.UserSubscriptions + stats.SessionSubscriptions + stats.RoleSubscriptions
+	return stats
+}
+
+// NotifySessionEvent sends a session event to subscribed clients. Events
+// with a user are kept for replay to clients that reconnect, whether or not
+// anyone is subscribed right now.
 func (n *Notifier) NotifySessionEvent(event SessionEvent) {
+	event = n.events.record(event)
+
 	n.mu.RLock()
 	targetClients := make(map[string]bool)
 
@@ -286,23 +482,43 @@ func (n *Notifier) NotifySessionEvent(event SessionEvent) {
 	}
 
 	// Send to target clients
-	n.manager.sessionsHub.mu.RLock()
-	sentCount := 0
-	for client := range n.manager.sessionsHub.clients {
-		if targetClients[client.id] {
-			select {
-			case client.send <- data:
-				sentCount++
-			default:
-				log.Printf("Failed to send event to client %s (buffer full)", client.id)
-			}
-		}
-	}
-	n.manager.sessionsHub.mu.RUnlock()
+	sentCount := n.manager.sessionsHub.SendTo(func(client *Client) bool {
+		return targetClients[client.id]
+	}, data)
 
 	log.Printf("Event %s for session %s sent to %d clients", event.Type, event.SessionID, sentCount)
 }
 
+// NotifyRole sends an event to every client subscribed to the role,
+// regardless of the event's user or session. Use it for system-wide events
+// only some roles may see, e.g. NotifyRole("admin", event) for compliance
+// violations.
+func (n *Notifier) NotifyRole(role string, event SessionEvent) {
+	n.mu.RLock()
+	targetClients := make(map[string]bool, len(n.roleSubscriptions[role]))
+	for clientID := range n.roleSubscriptions[role] {
+		targetClients[clientID] = true
+	}
+	n.mu.RUnlock()
+
+	// No subscribers, skip
+	if len(targetClients) == 0 {
+		return
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Failed to marshal role event: %v", err)
+		return
+	}
+
+	sentCount := n.manager.sessionsHub.SendTo(func(client *Client) bool {
+		return targetClients[client.id]
+	}, data)
+
+	log.Printf("Event %s for role %s sent to %d clients", event.Type, role, sentCount)
+}
+
 // NotifySessionCreated notifies clients when a session is created
 func (n *Notifier) NotifySessionCreated(sessionID, userID string, data map[string]interface{}) {
 	event := SessionEvent{
@@ -353,7 +569,23 @@ func (n *Notifier) NotifySessionStateChange(sessionID, userID, oldState, newStat
 	n.NotifySessionEvent(event)
 }
 
-// NotifySessionConnected notifies clients when someone connects to a session
+// NotifySessionAdminTerminated tells a session's owner that an admin
+// terminated it, and why.
+func (n *Notifier) NotifySessionAdminTerminated(sessionID, userID, reason string) {
+	event := SessionEvent{
+		Type:      EventSessionAdminTerminated,
+		SessionID: sessionID,
+		UserID:    userID,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"reason": reason,
+		},
+	}
+	n.NotifySessionEvent(event)
+}
+
+// NotifySessionConnected notifies clients when someone connects to a session,
+// and the session's subscribers if it gained a viewer
 func (n *Notifier) NotifySessionConnected(sessionID, userID string, connectionID string) {
 	event := SessionEvent{
 		Type:      EventSessionConnected,
@@ -365,9 +597,14 @@ func (n *Notifier) NotifySessionConnected(sessionID, userID string, connectionID
 		},
 	}
 	n.NotifySessionEvent(event)
+
+	if viewers, changed := n.viewers.connect(sessionID, userID, connectionID); changed {
+		n.notifySessionViewers(viewers)
+	}
 }
 
-// NotifySessionDisconnected notifies clients when someone disconnects from a session
+// NotifySessionDisconnected notifies clients when someone disconnects from a
+// session, and the session's subscribers if it lost a viewer
 func (n *Notifier) NotifySessionDisconnected(sessionID, userID string, connectionID string) {
 	event := SessionEvent{
 		Type:      EventSessionDisconnected,
@@ -379,6 +616,10 @@ func (n *Notifier) NotifySessionDisconnected(sessionID, userID string, connectio
 		},
 	}
 	n.NotifySessionEvent(event)
+
+	if viewers, changed := n.viewers.disconnect(sessionID, connectionID); changed {
+		n.notifySessionViewers(viewers)
+	}
 }
 
 // NotifySessionIdle notifies clients when a session becomes idle
@@ -487,7 +728,10 @@ func (n *Notifier) CloseAll() {
 	// Clear all subscriptions
 	n.userSubscriptions = make(map[string]map[string]bool)
 	n.sessionSubscriptions = make(map[string]map[string]bool)
-	n.clientUsers = make(map[string]string)
+	n.clientUsers = make(map[string]map[string]bool)
+	n.clientSessions = make(map[string]map[string]bool)
+	n.roleSubscriptions = make(map[string]map[string]bool)
+	n.clientRoles = make(map[string]map[string]bool)
 
 	log.Println("All subscriptions closed")
 }
@@ -0,0 +1,152 @@
+package websocket
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAuthorizer allows the sessions listed per user
+type fakeAuthorizer map[string][]string
+
+func (f fakeAuthorizer) CanObserveSession(_ context.Context, userID, sessionID string) (bool, error) {
+	for _, id := range f[userID] {
+		if id == sessionID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func testNotifier(maxPerClient int) *Notifier {
+	n := NewNotifier(nil)
+	n.maxPerClient = maxPerClient
+	n.SetAuthorizer(fakeAuthorizer{"alice": {"alice-firefox", "bob-shared"}})
+	return n
+}
+
+func TestSubscribeUser_OnlyOwnUnlessPrivileged(t *testing.T) {
+	n := testNotifier(10)
+	alice := Viewer{UserID: "alice", Role: "user"}
+
+	assert.NoError(t, n.SubscribeUser("c1", alice, "alice"))
+	assert.ErrorIs(t, n.SubscribeUser("c1", alice, "bob"), ErrSubscriptionForbidden)
+	assert.NoError(t, n.SubscribeUser("c2", Viewer{UserID: "root", Role: "admin"}, "bob"))
+
+	stats := n.Stats()
+	assert.Equal(t, 2, stats.UserSubscriptions)
+	assert.Equal(t, uint64(1), stats.RejectedForbidden)
+}
+
+func TestSubscribeSession_OwnedOrShared(t *testing.T) {
+	n := testNotifier(10)
+	ctx := context.Background()
+	alice := Viewer{UserID: "alice", Role: "user"}
+
+	assert.NoError(t, n.SubscribeSession(ctx, "c1", alice, "alice-firefox"))
+	assert.NoError(t, n.SubscribeSession(ctx, "c1", alice, "bob-shared"))
+	assert.ErrorIs(t, n.SubscribeSession(ctx, "c1", alice, "bob-private"), ErrSubscriptionForbidden)
+	assert.NoError(t, n.SubscribeSession(ctx, "c2", Viewer{UserID: "ops", Role: "operator"}, "bob-private"))
+
+	// Without an authorizer only privileged viewers may subscribe
+	n.SetAuthorizer(nil)
+	assert.ErrorIs(t, n.SubscribeSession(ctx, "c3", alice, "alice-firefox"), ErrSubscriptionForbidden)
+}
+
+func TestSubscribe_PerClientLimit(t *testing.T) {
+	n := testNotifier(2)
+	ctx := context.Background()
+	admin := Viewer{UserID: "root", Role: "admin"}
+
+	require.NoError(t, n.SubscribeUser("c1", admin, "alice"))
+	require.NoError(t, n.SubscribeSession(ctx, "c1", admin, "s1"))
+
+	// Re-subscribing is a no-op, not a new subscription
+	assert.NoError(t, n.SubscribeSession(ctx, "c1", admin, "s1"))
+	assert.ErrorIs(t, n.SubscribeSession(ctx, "c1", admin, "s2"), ErrSubscriptionLimit)
+	assert.ErrorIs(t, n.SubscribeUser("c1", admin, "bob"), ErrSubscriptionLimit)
+
+	// Other clients have their own allowance
+	assert.NoError(t, n.SubscribeSession(ctx, "c2", admin, "s2"))
+
+	stats := n.Stats()
+	assert.Equal(t, 2, stats.Clients)
+	assert.Equal(t, 3, stats.TotalSubscriptions)
+	assert.Equal(t, uint64(2), stats.RejectedLimit)
+}
+
+func TestUnsubscribeClient_RemovesAllSubscriptions(t *testing.T) {
+	n := testNotifier(10)
+	ctx := context.Background()
+	admin := Viewer{UserID: "root", Role: "admin"}
+
+	require.NoError(t, n.SubscribeUser("c1", admin, "alice"))
+	require.NoError(t, n.SubscribeUser("c1", admin, "bob"))
+	require.NoError(t, n.SubscribeSession(ctx, "c1", admin, "s1"))
+	require.NoError(t, n.SubscribeSession(ctx, "c2", admin, "s1"))
+
+	n.UnsubscribeClient("c1")
+
+	assert.Empty(t, n.userSubscriptions)
+	assert.Equal(t, map[string]bool{"c2": true}, n.sessionSubscriptions["s1"])
+	assert.Equal(t, 1, n.Stats().TotalSubscriptions)
+}
+
+func TestSubscribeRole_CleanedUpWithClient(t *testing.T) {
+	n := testNotifier(1)
+	admin := Viewer{UserID: "root", Role: "admin"}
+
+	// Role subscriptions don't use up the per-client allowance
+	n.SubscribeRole("c1", "admin")
+	require.NoError(t, n.SubscribeUser("c1", admin, "alice"))
+	n.SubscribeRole("c2", "admin")
+	n.SubscribeRole("c3", "user")
+	n.SubscribeRole("c4", "")
+
+	stats := n.Stats()
+	assert.Equal(t, 3, stats.RoleSubscriptions)
+	assert.Equal(t, 4, stats.TotalSubscriptions)
+	assert.Equal(t, 3, stats.Clients)
+
+	n.UnsubscribeClient("c1")
+	n.UnsubscribeClient("c3")
+	assert.Equal(t, map[string]map[string]bool{"admin": {"c2": true}}, n.roleSubscriptions)
+	assert.NotContains(t, n.clientRoles, "c1")
+}
+
+func TestNotifyRole_OnlyReachesRole(t *testing.T) {
+	m := &Manager{sessionsHub: NewHub()}
+	m.notifier = NewNotifier(m)
+	go m.sessionsHub.Run()
+	defer m.sessionsHub.Stop()
+
+	clients := map[string]*Client{}
+	for _, id := range []string{"admin-1", "admin-2", "user-1"} {
+		clients[id] = &Client{hub: m.sessionsHub, id: id, send: make(chan []byte, 4)}
+		require.True(t, m.sessionsHub.Register(clients[id]))
+	}
+	require.Eventually(t, func() bool { return m.sessionsHub.ClientCount() == 3 }, time.Second, 5*time.Millisecond)
+	m.notifier.SubscribeRole("admin-1", "admin")
+	m.notifier.SubscribeRole("admin-2", "admin")
+	m.notifier.SubscribeRole("user-1", "user")
+	// Admins' own user subscriptions don't matter for role events
+	require.NoError(t, m.notifier.SubscribeUser("user-1", Viewer{UserID: "alice", Role: "user"}, "alice"))
+
+	m.notifier.NotifyRole("admin", SessionEvent{Type: "compliance.violation", UserID: "alice"})
+
+	for _, id := range []string{"admin-1", "admin-2"} {
+		select {
+		case data := <-clients[id].send:
+			assert.Contains(t, string(data), "compliance.violation")
+		default:
+			t.Errorf("%s did not receive the role event", id)
+		}
+	}
+	assert.Empty(t, clients["user-1"].send, "other roles don't receive it")
+
+	// Roles nobody holds are a no-op
+	m.notifier.NotifyRole("operator", SessionEvent{Type: "compliance.violation"})
+}
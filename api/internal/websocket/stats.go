@@ -0,0 +1,180 @@
+// Package websocket - stats.go
+//
+// This file implements connection-level delivery metrics for the Hub.
+//
+// Batching in writePump and permessage-deflate compression are both meant to
+// reduce bandwidth. These counters make it possible to verify that in
+// production instead of assuming it:
+//   - MessagesSent vs FramesSent shows how much batching actually happens
+//   - BytesUncompressed vs BytesCompressed shows what compression saves
+//
+// All counters are lock-free atomics updated from writePump, so recording
+// them adds no contention to the send path.
+//
+// Measuring bytes after compression:
+//
+// gorilla/websocket compresses inside the frame writer and doesn't report
+// the result, so post-compression bytes are counted at the network layer.
+// Wrap the response writer with CountWireBytes before upgrading:
+//
+//	conn, err := upgrader.Upgrade(websocket.CountWireBytes(c.Writer), c.Request, nil)
+//
+// Connections upgraded without the wrapper report BytesCompressed equal to
+// BytesUncompressed.
+package websocket
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync/atomic"
+)
+
+// connStats holds the delivery counters for one connection (or, on the Hub,
+// cumulative totals across all connections it has served).
+type connStats struct {
+	messagesSent      atomic.Uint64
+	framesSent        atomic.Uint64
+	messagesBatched   atomic.Uint64
+	bytesUncompressed atomic.Uint64
+	bytesCompressed   atomic.Uint64
+}
+
+// ConnectionStats is a point-in-time snapshot of delivery counters.
+//
+// MessagesBatched counts messages that were appended to a frame that already
+// carried another message. BytesUncompressed is the payload handed to the
+// frame writer; BytesCompressed is what went out on the network for those
+// frames, including frame headers.
+type ConnectionStats struct {
+	MessagesSent      uint64 `json:"messagesSent"`
+	FramesSent        uint64 `json:"framesSent"`
+	MessagesBatched   uint64 `json:"messagesBatched"`
+	BytesUncompressed uint64 `json:"bytesUncompressed"`
+	BytesCompressed   uint64 `json:"bytesCompressed"`
+}
+
+// HubStats aggregates delivery counters for a Hub.
+//
+// Totals are cumulative since the hub was created and include clients that
+// have since disconnected.
+type HubStats struct {
+	ActiveClients int             `json:"activeClients"`
+	Totals        ConnectionStats `json:"totals"`
+
+	// MessagesPerFrame is the average batching factor (1.0 = no batching).
+	MessagesPerFrame float64 `json:"messagesPerFrame"`
+
+	// CompressionRatio is compressed/uncompressed bytes (1.0 = no savings).
+	CompressionRatio float64 `json:"compressionRatio"`
+}
+
+// snapshot returns the current counter values.
+func (s *connStats) snapshot() ConnectionStats {
+	return ConnectionStats{
+		MessagesSent:      s.messagesSent.Load(),
+		FramesSent:        s.framesSent.Load(),
+		MessagesBatched:   s.messagesBatched.Load(),
+		BytesUncompressed: s.bytesUncompressed.Load(),
+		BytesCompressed:   s.bytesCompressed.Load(),
+	}
+}
+
+// Stats returns a snapshot of this client's delivery counters.
+func (c *Client) Stats() ConnectionStats {
+	return c.stats.snapshot()
+}
+
+// Stats returns aggregated delivery counters for the hub.
+func (h *Hub) Stats() HubStats {
+	totals := h.stats.snapshot()
+
+	stats := HubStats{
+		ActiveClients:    h.ClientCount(),
+		Totals:           totals,
+		MessagesPerFrame: 1,
+		CompressionRatio: 1,
+	}
+	if totals.FramesSent > 0 {
+		stats.MessagesPerFrame = float64(totals.MessagesSent) / float64(totals.FramesSent)
+	}
+	if totals.BytesUncompressed > 0 {
+		stats.CompressionRatio = float64(totals.BytesCompressed) / float64(totals.BytesUncompressed)
+	}
+	return stats
+}
+
+// recordFrame records one frame written by writePump.
+//
+// messages is the number of messages in the frame, payload the bytes passed
+// to the frame writer, and wire the bytes written to the network (0 if the
+// connection isn't wrapped with CountWireBytes).
+func (c *Client) recordFrame(messages int, payload, wire uint64) {
+	if wire == 0 {
+		wire = payload
+	}
+	for _, s := range []*connStats{&c.stats, &c.hub.stats} {
+		s.framesSent.Add(1)
+		s.messagesSent.Add(uint64(messages))
+		if messages > 1 {
+			s.messagesBatched.Add(uint64(messages - 1))
+		}
+		s.bytesUncompressed.Add(payload)
+		s.bytesCompressed.Add(wire)
+	}
+}
+
+// countingWriter counts bytes written through it.
+type countingWriter struct {
+	w io.Writer
+	n uint64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += uint64(n)
+	return n, err
+}
+
+// wireCountingConn wraps a hijacked net.Conn and counts bytes written to it.
+type wireCountingConn struct {
+	net.Conn
+	written atomic.Uint64
+}
+
+func (c *wireCountingConn) Write(p []byte) (int, error) {
+	n, err := c.Conn.Write(p)
+	c.written.Add(uint64(n))
+	return n, err
+}
+
+// wireCountingResponseWriter hands out a wireCountingConn when hijacked.
+type wireCountingResponseWriter struct {
+	http.ResponseWriter
+}
+
+// Hijack implements http.Hijacker so the upgrader receives the counting conn.
+func (w *wireCountingResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+	conn, brw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	return &wireCountingConn{Conn: conn}, brw, nil
+}
+
+// Unwrap exposes the underlying writer to http.ResponseController.
+func (w *wireCountingResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// CountWireBytes wraps w so that a WebSocket upgraded from it reports
+// post-compression byte counts in ConnectionStats.
+func CountWireBytes(w http.ResponseWriter) http.ResponseWriter {
+	return &wireCountingResponseWriter{ResponseWriter: w}
+}
@@ -0,0 +1,97 @@
+// Package websocket - subscriptions.go
+//
+// This file implements limits and authorization for Notifier subscriptions.
+//
+// Without them a client could subscribe to any user or session, receiving
+// events it has no business seeing, and could subscribe to thousands of them
+// and grow the subscription maps without bound. Every subscription now:
+//   - Is checked against the subscribing viewer: users may observe their own
+//     events and sessions shared with them; admins and operators may observe
+//     anything
+//   - Counts against a per-client cap (WEBSOCKET_MAX_SUBSCRIPTIONS_PER_CLIENT,
+//     default 100)
+//
+// Rejected subscriptions are counted in SubscriptionStats alongside the
+// current subscription totals.
+package websocket
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+
+	"github.com/streamspace/streamspace/api/internal/db"
+)
+
+// defaultMaxSubscriptionsPerClient is used when
+// WEBSOCKET_MAX_SUBSCRIPTIONS_PER_CLIENT is unset or invalid.
+const defaultMaxSubscriptionsPerClient = 100
+
+var (
+	// ErrSubscriptionLimit is returned when a client already holds the
+	// maximum number of subscriptions.
+	ErrSubscriptionLimit = errors.New("subscription limit reached")
+
+	// ErrSubscriptionForbidden is returned when the viewer is not allowed to
+	// observe the requested user or session.
+	ErrSubscriptionForbidden = errors.New("not allowed to subscribe")
+)
+
+// Viewer identifies the authenticated user behind a WebSocket client.
+type Viewer struct {
+	UserID string
+	Role   string
+}
+
+// privileged reports whether the viewer may observe any user or session.
+func (v Viewer) privileged() bool {
+	return v.Role == "admin" || v.Role == "operator"
+}
+
+// SubscriptionAuthorizer decides which sessions a non-privileged viewer may
+// observe. Users may always observe their own user events.
+type SubscriptionAuthorizer interface {
+	CanObserveSession(ctx context.Context, userID, sessionID string) (bool, error)
+}
+
+// dbSubscriptionAuthorizer allows session owners and users the session is
+// shared with.
+type dbSubscriptionAuthorizer struct {
+	db *db.Database
+}
+
+// CanObserveSession reports whether the user owns the session or has an
+// unrevoked share for it.
+func (a *dbSubscriptionAuthorizer) CanObserveSession(ctx context.Context, userID, sessionID string) (bool, error) {
+	var allowed bool
+	err := a.db.DB().QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM sessions WHERE id = $1 AND user_id = $2
+		) OR EXISTS(
+			SELECT 1 FROM session_shares
+			WHERE session_id = $1 AND shared_with_user_id = $2 AND revoked_at IS NULL
+		)
+	`, sessionID, userID).Scan(&allowed)
+	return allowed, err
+}
+
+// SubscriptionStats reports current subscriptions and rejected attempts.
+type SubscriptionStats struct {
+	Clients              int    `json:"clients"`
+	UserSubscriptions    int    `json:"userSubscriptions"`
+	SessionSubscriptions int    `json:"sessionSubscriptions"`
+	RoleSubscriptions    int    `json:"roleSubscriptions"`
+	TotalSubscriptions   int    `json:"totalSubscriptions"`
+	MaxPerClient         int    `json:"maxPerClient"`
+	RejectedLimit        uint64 `json:"rejectedLimit"`
+	RejectedForbidden    uint64 `json:"rejectedForbidden"`
+}
+
+// maxSubscriptionsPerClientFromEnv reads WEBSOCKET_MAX_SUBSCRIPTIONS_PER_CLIENT.
+func maxSubscriptionsPerClientFromEnv() int {
+	if v, err := strconv.Atoi(os.Getenv("WEBSOCKET_MAX_SUBSCRIPTIONS_PER_CLIENT")); err == nil && v > 0 {
+		return v
+	}
+	return defaultMaxSubscriptionsPerClient
+}
@@ -0,0 +1,143 @@
+// Package websocket - viewers.go
+//
+// This file counts the people connected to each session's stream.
+//
+// Collaboration records list who may join a session, not who is watching.
+// The viewer registry is fed by NotifySessionConnected/Disconnected (the
+// connection tracker reports every connection, including stale ones it
+// drops) and counts distinct users: a user with the session open in two
+// tabs is one viewer. Whenever the count or the set of viewers changes, a
+// session.viewers event is sent to the session's subscribers.
+package websocket
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// EventSessionViewers is emitted when the users connected to a session change.
+// Data: viewers (count), users, connections
+const EventSessionViewers EventType = "session.viewers"
+
+// SessionViewers is the live audience of a session.
+type SessionViewers struct {
+	SessionID string `json:"sessionId"`
+
+	// Viewers is the number of distinct users connected.
+	Viewers int `json:"viewers"`
+
+	// Users are the connected users, sorted.
+	Users []string `json:"users"`
+
+	// Connections counts every open connection, including a user's
+	// duplicates.
+	Connections int `json:"connections"`
+}
+
+// viewerRegistry tracks open connections per session.
+type viewerRegistry struct {
+	mu sync.Mutex
+
+	// sessions maps sessionID -> connectionID -> userID.
+	sessions map[string]map[string]string
+}
+
+func newViewerRegistry() *viewerRegistry {
+	return &viewerRegistry{sessions: make(map[string]map[string]string)}
+}
+
+// connect records a connection. It reports whether the session's viewers
+// changed, which a user's additional connection doesn't.
+func (r *viewerRegistry) connect(sessionID, userID, connectionID string) (SessionViewers, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conns, ok := r.sessions[sessionID]
+	if !ok {
+		conns = make(map[string]string)
+		r.sessions[sessionID] = conns
+	}
+	if _, dup := conns[connectionID]; dup {
+		return r.snapshot(sessionID), false
+	}
+
+	changed := !hasUser(conns, userID)
+	conns[connectionID] = userID
+	return r.snapshot(sessionID), changed
+}
+
+// disconnect forgets a connection. It reports whether the session's viewers
+// changed, which they don't while the user has another connection open.
+func (r *viewerRegistry) disconnect(sessionID, connectionID string) (SessionViewers, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	conns := r.sessions[sessionID]
+	userID, ok := conns[connectionID]
+	if !ok {
+		return r.snapshot(sessionID), false
+	}
+	delete(conns, connectionID)
+	if len(conns) == 0 {
+		delete(r.sessions, sessionID)
+	}
+	return r.snapshot(sessionID), !hasUser(conns, userID)
+}
+
+// get returns a session's current viewers.
+func (r *viewerRegistry) get(sessionID string) SessionViewers {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.snapshot(sessionID)
+}
+
+// snapshot builds a session's viewers. Caller must hold mu.
+func (r *viewerRegistry) snapshot(sessionID string) SessionViewers {
+	conns := r.sessions[sessionID]
+	seen := make(map[string]bool, len(conns))
+	users := []string{}
+	for _, userID := range conns {
+		if !seen[userID] {
+			seen[userID] = true
+			users = append(users, userID)
+		}
+	}
+	sort.Strings(users)
+
+	return SessionViewers{
+		SessionID:   sessionID,
+		Viewers:     len(users),
+		Users:       users,
+		Connections: len(conns),
+	}
+}
+
+// hasUser reports whether any of conns belongs to userID.
+func hasUser(conns map[string]string, userID string) bool {
+	for _, u := range conns {
+		if u == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// SessionViewers returns the users currently connected to a session.
+func (n *Notifier) SessionViewers(sessionID string) SessionViewers {
+	return n.viewers.get(sessionID)
+}
+
+// notifySessionViewers tells a session's subscribers who is connected.
+func (n *Notifier) notifySessionViewers(viewers SessionViewers) {
+	n.NotifySessionEvent(SessionEvent{
+		Type:      EventSessionViewers,
+		SessionID: viewers.SessionID,
+		Timestamp: time.Now(),
+		Data: map[string]interface{}{
+			"viewers":     viewers.Viewers,
+			"users":       viewers.Users,
+			"connections": viewers.Connections,
+		},
+	})
+}
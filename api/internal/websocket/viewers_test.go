@@ -0,0 +1,65 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestViewerRegistry_CountsDistinctUsers(t *testing.T) {
+	r := newViewerRegistry()
+
+	viewers, changed := r.connect("s1", "alice", "c1")
+	assert.True(t, changed)
+	assert.Equal(t, 1, viewers.Viewers)
+
+	// A second tab of the same user is another connection, not another viewer
+	viewers, changed = r.connect("s1", "alice", "c2")
+	assert.False(t, changed)
+	assert.Equal(t, 1, viewers.Viewers)
+	assert.Equal(t, 2, viewers.Connections)
+
+	viewers, changed = r.connect("s1", "bob", "c3")
+	assert.True(t, changed)
+	assert.Equal(t, []string{"alice", "bob"}, viewers.Users)
+
+	// Reported twice (tracker reload), counted once
+	_, changed = r.connect("s1", "bob", "c3")
+	assert.False(t, changed)
+	assert.Equal(t, 3, r.get("s1").Connections)
+}
+
+func TestViewerRegistry_Disconnect(t *testing.T) {
+	r := newViewerRegistry()
+	r.connect("s1", "alice", "c1")
+	r.connect("s1", "alice", "c2")
+	r.connect("s1", "bob", "c3")
+
+	viewers, changed := r.disconnect("s1", "c1")
+	assert.False(t, changed, "alice is still connected through c2")
+	assert.Equal(t, 2, viewers.Viewers)
+
+	viewers, changed = r.disconnect("s1", "c2")
+	assert.True(t, changed)
+	assert.Equal(t, []string{"bob"}, viewers.Users)
+
+	_, changed = r.disconnect("s1", "c2")
+	assert.False(t, changed, "unknown connection")
+
+	viewers, changed = r.disconnect("s1", "c3")
+	assert.True(t, changed)
+	assert.Equal(t, SessionViewers{SessionID: "s1", Users: []string{}}, viewers)
+	assert.Empty(t, r.sessions)
+}
+
+func TestNotifier_SessionViewers(t *testing.T) {
+	n := NewNotifier(nil)
+	n.NotifySessionConnected("s1", "alice", "c1")
+	n.NotifySessionConnected("s1", "alice", "c2")
+	n.NotifySessionConnected("s2", "bob", "c3")
+	n.NotifySessionDisconnected("s1", "alice", "c1")
+
+	assert.Equal(t, 1, n.SessionViewers("s1").Viewers)
+	assert.Equal(t, 1, n.SessionViewers("s2").Viewers)
+	assert.Equal(t, 0, n.SessionViewers("s3").Viewers)
+}
@@ -0,0 +1,344 @@
+// Package wshub provides the connection registry and fan-out shared by
+// StreamSpace's WebSocket hubs.
+//
+// Two hubs used to carry their own copies of this logic: the enterprise hub
+// in internal/handlers (typed WebSocketMessage values per user) and the
+// session/metrics hubs in internal/websocket (pre-encoded JSON). The copies
+// had drifted apart - only one capped evictions per broadcast, and they
+// locked differently - so a fix in one didn't reach the other. Hub is
+// parameterized over the client and message types so both are built on the
+// same registration, broadcast and eviction code.
+//
+// Concurrency:
+//   - Run() owns registration, unregistration and broadcasts
+//   - The client map is guarded by an RWMutex, so SendTo and ClientCount
+//     may be called from any goroutine
+//   - Sends never block: a client whose buffer is full is skipped by
+//     SendTo and evicted by the next broadcast
+//   - With a Priority classifier, a full buffer first drops queued
+//     lower-priority messages to make room (see priority.go)
+//   - With an Accepts filter, clients only get the messages they want
+//   - Stop ends Run; afterwards registrations are refused and broadcasts
+//     dropped, while SendTo still reaches connected clients until CloseAll
+//
+// Example usage:
+//
+//	hub := wshub.New[*Client, []byte]("sessions", 256)
+//	go hub.Run()
+//
+//	hub.Register(client)
+//	hub.Broadcast(data)
+//	hub.SendTo(func(c *Client) bool { return c.userID == "alice" }, data)
+package wshub
+
+import (
+	"log"
+	"sync"
+)
+
+// Client is implemented by connections registered with a Hub.
+//
+// Clients are compared by identity, so use pointer types.
+type Client[M any] interface {
+	comparable
+
+	// HubID uniquely identifies the client within its hub.
+	HubID() string
+
+	// Outbox is the client's buffered send channel. The hub closes it when
+	// the client is unregistered or evicted.
+	Outbox() chan M
+}
+
+// Hub tracks connected clients and delivers messages to them.
+type Hub[C Client[M], M any] struct {
+	// name identifies the hub in log messages.
+	name string
+
+	clients    map[string]C
+	register   chan registration[C]
+	unregister chan C
+	broadcast  chan M
+	mu         sync.RWMutex
+
+	// done is closed by Stop to end Run.
+	done     chan struct{}
+	stopOnce sync.Once
+
+	// MaxEvictionsPerCycle caps how many slow clients one broadcast removes
+	// (0 = unlimited). Bounds the write-lock hold time during mass disconnects.
+	MaxEvictionsPerCycle int
+
+	// pendingEvictions holds slow clients left over from the previous cycle.
+	// Only accessed from Run().
+	pendingEvictions []C
+
+	// Priority classifies messages for clients whose buffer is full (see
+	// priority.go). Nil treats every message alike: a full buffer skips the
+	// message, or evicts the client on broadcast.
+	Priority func(M) Priority
+
+	// Accepts reports whether a client wants a message, e.g. because it
+	// subscribed to its type. Broadcasts and SendTo skip clients it rejects.
+	// Nil delivers every message to every client.
+	Accepts func(C, M) bool
+
+	// makeRoomMu serializes dropping queued messages for higher-priority ones.
+	makeRoomMu sync.Mutex
+}
+
+// registration asks Run to add a client. Run answers on added, which is
+// buffered so it never blocks.
+type registration[C any] struct {
+	client C
+	added  chan bool
+}
+
+// New creates a hub. broadcastBuffer sizes the broadcast channel; Broadcast
+// blocks once it is full.
+func New[C Client[M], M any](name string, broadcastBuffer int) *Hub[C, M] {
+	return &Hub[C, M]{
+		name:       name,
+		clients:    make(map[string]C),
+		register:   make(chan registration[C]),
+		unregister: make(chan C),
+		broadcast:  make(chan M, broadcastBuffer),
+		done:       make(chan struct{}),
+	}
+}
+
+// Run processes registrations, unregistrations and broadcasts until Stop
+// is called. Start it once in its own goroutine.
+func (h *Hub[C, M]) Run() {
+	for {
+		select {
+		case <-h.done:
+			log.Printf("%s WebSocket hub stopped", h.name)
+			return
+
+		case req := <-h.register:
+			// Checking done under the write lock means a client is either
+			// refused or in the map before a SendTo that follows Stop
+			h.mu.Lock()
+			added := !h.stopped()
+			if added {
+				h.clients[req.client.HubID()] = req.client
+			}
+			total := len(h.clients)
+			h.mu.Unlock()
+			req.added <- added
+			if added {
+				log.Printf("%s WebSocket client registered: %s (total: %d)", h.name, req.client.HubID(), total)
+			}
+
+		case client := <-h.unregister:
+			h.mu.Lock()
+			// Only the registered instance is removed: a client evicted
+			// earlier (or replaced under the same ID) must not close the
+			// current one's channel.
+			if h.remove(client) {
+				log.Printf("%s WebSocket client unregistered: %s (total: %d)", h.name, client.HubID(), len(h.clients))
+			}
+			h.mu.Unlock()
+
+		case message := <-h.broadcast:
+			// Deliver under the read lock, then evict slow clients under the
+			// write lock. Holding the write lock while sending would block
+			// SendTo and ClientCount for the whole fan-out.
+			var slow []C
+			priority := h.priorityOf(message)
+			h.mu.RLock()
+			for _, client := range h.clients {
+				if !h.accepts(client, message) {
+					continue
+				}
+				if !h.deliver(client, message, priority) {
+					// Buffer full of messages at least as important: the
+					// client is too slow or already gone
+					slow = append(slow, client)
+				}
+			}
+			h.mu.RUnlock()
+
+			h.evictClients(slow)
+		}
+	}
+}
+
+// remove deletes client from the map and closes its outbox if it is the
+// registered instance. Caller must hold the write lock.
+func (h *Hub[C, M]) remove(client C) bool {
+	if existing, ok := h.clients[client.HubID()]; !ok || existing != client {
+		return false
+	}
+	delete(h.clients, client.HubID())
+	close(client.Outbox())
+	return true
+}
+
+// evictClients removes slow clients found during a broadcast.
+//
+// At most MaxEvictionsPerCycle clients are removed per call so a broadcast
+// storm that overflows thousands of buffers at once can't hold the write
+// lock for long. The rest are carried over and removed first on the next
+// cycle.
+//
+// Must only be called from Run().
+func (h *Hub[C, M]) evictClients(found []C) {
+	candidates := h.pendingEvictions
+	if len(found) > 0 {
+		// Skip clients already waiting from a previous cycle
+		pending := make(map[C]bool, len(candidates))
+		for _, client := range candidates {
+			pending[client] = true
+		}
+		for _, client := range found {
+			if !pending[client] {
+				candidates = append(candidates, client)
+			}
+		}
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	batch := candidates
+	h.pendingEvictions = nil
+	if h.MaxEvictionsPerCycle > 0 && len(candidates) > h.MaxEvictionsPerCycle {
+		batch = candidates[:h.MaxEvictionsPerCycle]
+		h.pendingEvictions = append([]C(nil), candidates[h.MaxEvictionsPerCycle:]...)
+	}
+
+	h.mu.Lock()
+	for _, client := range batch {
+		// May already be gone via Unregister
+		if h.remove(client) {
+			log.Printf("%s WebSocket client removed (buffer full): %s", h.name, client.HubID())
+		}
+	}
+	h.mu.Unlock()
+
+	if len(h.pendingEvictions) > 0 {
+		log.Printf("%s WebSocket eviction cap reached: %d slow clients deferred to next cycle", h.name, len(h.pendingEvictions))
+	}
+}
+
+// accepts reports whether the client wants the message (always, without an
+// Accepts filter).
+func (h *Hub[C, M]) accepts(client C, message M) bool {
+	return h.Accepts == nil || h.Accepts(client, message)
+}
+
+// Register adds a client. It blocks until Run has added it to the map, so
+// SendTo reaches the client as soon as Register returns, and reports
+// whether the client was added; once the hub is stopped it is refused, and
+// the caller should close the connection itself.
+func (h *Hub[C, M]) Register(client C) bool {
+	req := registration[C]{client: client, added: make(chan bool, 1)}
+	select {
+	case h.register <- req:
+		return <-req.added
+	case <-h.done:
+		return false
+	}
+}
+
+// Unregister removes a client and closes its outbox. It blocks until Run
+// processes the request; unknown or already removed clients are ignored,
+// as is every client once the hub is stopped.
+func (h *Hub[C, M]) Unregister(client C) {
+	select {
+	case h.unregister <- client:
+	case <-h.done:
+	}
+}
+
+// Broadcast queues a message for every connected client. Once the hub is
+// stopped, messages are dropped.
+func (h *Hub[C, M]) Broadcast(message M) {
+	select {
+	case <-h.done:
+		return
+	default:
+	}
+	select {
+	case h.broadcast <- message:
+	case <-h.done:
+	}
+}
+
+// Stop ends Run. Connected clients stay registered and can still be sent
+// to with SendTo; call CloseAll to disconnect them. Stopping twice is a
+// no-op.
+func (h *Hub[C, M]) Stop() {
+	h.stopOnce.Do(func() { close(h.done) })
+}
+
+// stopped reports whether Stop was called.
+func (h *Hub[C, M]) stopped() bool {
+	select {
+	case <-h.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// Queued returns the number of messages waiting in client outboxes.
+func (h *Hub[C, M]) Queued() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	queued := 0
+	for _, client := range h.clients {
+		queued += len(client.Outbox())
+	}
+	return queued
+}
+
+// SendTo delivers a message to every client match accepts and returns how
+// many received it. Clients with a full buffer are skipped, not evicted;
+// the next broadcast removes them.
+func (h *Hub[C, M]) SendTo(match func(C) bool, message M) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	sent := 0
+	priority := h.priorityOf(message)
+	for _, client := range h.clients {
+		if !match(client) || !h.accepts(client, message) {
+			continue
+		}
+		if h.deliver(client, message, priority) {
+			sent++
+		} else {
+			log.Printf("Failed to send to %s WebSocket client %s (buffer full)", h.name, client.HubID())
+		}
+	}
+	return sent
+}
+
+// Client returns the registered client with the given ID.
+func (h *Hub[C, M]) Client(id string) (C, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	client, ok := h.clients[id]
+	return client, ok
+}
+
+// ClientCount returns the number of connected clients.
+func (h *Hub[C, M]) ClientCount() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.clients)
+}
+
+// CloseAll closes every client's outbox and forgets all clients. Their
+// write pumps send a close frame and exit.
+func (h *Hub[C, M]) CloseAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, client := range h.clients {
+		close(client.Outbox())
+	}
+	h.clients = make(map[string]C)
+}
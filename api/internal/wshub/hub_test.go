@@ -0,0 +1,275 @@
+package wshub
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testClient struct {
+	id   string
+	user string
+	send chan string
+}
+
+func (c *testClient) HubID() string       { return c.id }
+func (c *testClient) Outbox() chan string { return c.send }
+
+func newTestClient(id, user string, buffer int) *testClient {
+	return &testClient{id: id, user: user, send: make(chan string, buffer)}
+}
+
+// waitForClients waits for Run to process queued registrations.
+func waitForClients(t *testing.T, hub *Hub[*testClient, string], n int) {
+	t.Helper()
+	assert.Eventually(t, func() bool { return hub.ClientCount() == n }, time.Second, 5*time.Millisecond)
+}
+
+func TestHub_RegisterBroadcastUnregister(t *testing.T) {
+	hub := New[*testClient, string]("test", 16)
+	go hub.Run()
+
+	a := newTestClient("a", "alice", 4)
+	b := newTestClient("b", "bob", 4)
+	hub.Register(a)
+	hub.Register(b)
+	waitForClients(t, hub, 2)
+
+	hub.Broadcast("hello")
+	for _, client := range []*testClient{a, b} {
+		select {
+		case msg := <-client.send:
+			assert.Equal(t, "hello", msg)
+		case <-time.After(time.Second):
+			t.Fatalf("client %s did not receive broadcast", client.id)
+		}
+	}
+
+	hub.Unregister(a)
+	waitForClients(t, hub, 1)
+	_, open := <-a.send
+	assert.False(t, open, "outbox closed on unregister")
+
+	// Unregistering again is a no-op
+	hub.Unregister(a)
+	hub.Unregister(a)
+	assert.Equal(t, 1, hub.ClientCount())
+}
+
+func TestHub_RegisterIsSynchronous(t *testing.T) {
+	hub := New[*testClient, string]("test", 16)
+	go hub.Run()
+	defer hub.Stop()
+
+	// A client can be sent to as soon as Register returns
+	for i := 0; i < 100; i++ {
+		client := newTestClient(fmt.Sprintf("c%d", i), "alice", 1)
+		require.True(t, hub.Register(client))
+		match := func(c *testClient) bool { return c == client }
+		require.Equal(t, 1, hub.SendTo(match, "welcome"), "client %s missing after Register", client.id)
+	}
+}
+
+func TestHub_RegisterDuringStop(t *testing.T) {
+	hub := New[*testClient, string]("test", 16)
+	go hub.Run()
+
+	var wg sync.WaitGroup
+	var added atomic.Int32
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if hub.Register(newTestClient(fmt.Sprintf("c%d", i), "alice", 1)) {
+				added.Add(1)
+			}
+		}(i)
+	}
+
+	// Every client Register accepted is reachable after Stop; the rest are
+	// refused
+	hub.Stop()
+	sent := hub.SendTo(func(*testClient) bool { return true }, "bye")
+	wg.Wait()
+	assert.Equal(t, int(added.Load()), sent)
+	assert.Equal(t, sent, hub.ClientCount())
+}
+
+func TestHub_UnregisterStaleInstance(t *testing.T) {
+	hub := New[*testClient, string]("test", 16)
+	go hub.Run()
+
+	old := newTestClient("same-id", "alice", 1)
+	current := newTestClient("same-id", "alice", 1)
+	hub.Register(old)
+	hub.Register(current)
+	assert.Eventually(t, func() bool {
+		got, _ := hub.Client("same-id")
+		return got == current
+	}, time.Second, 5*time.Millisecond)
+
+	// The replaced instance must not remove or close the current one
+	hub.Unregister(old)
+	hub.Unregister(old) // returns once the first request has been processed
+	got, ok := hub.Client("same-id")
+	require.True(t, ok)
+	assert.Same(t, current, got)
+}
+
+func TestHub_SendTo(t *testing.T) {
+	hub := New[*testClient, string]("test", 16)
+	go hub.Run()
+
+	a1 := newTestClient("a1", "alice", 1)
+	a2 := newTestClient("a2", "alice", 1)
+	b := newTestClient("b", "bob", 1)
+	for _, client := range []*testClient{a1, a2, b} {
+		hub.Register(client)
+	}
+	waitForClients(t, hub, 3)
+
+	toAlice := func(c *testClient) bool { return c.user == "alice" }
+	assert.Equal(t, 2, hub.SendTo(toAlice, "hi"))
+	assert.Len(t, b.send, 0)
+
+	// Full buffers are skipped without blocking or evicting
+	assert.Equal(t, 0, hub.SendTo(toAlice, "again"))
+	assert.Equal(t, 3, hub.ClientCount())
+}
+
+func TestHub_Accepts(t *testing.T) {
+	hub := New[*testClient, string]("test", 16)
+	// Bob only wants alerts
+	hub.Accepts = func(c *testClient, msg string) bool {
+		return c.user != "bob" || msg == "alert"
+	}
+	go hub.Run()
+
+	a := newTestClient("a", "alice", 4)
+	b := newTestClient("b", "bob", 1)
+	hub.Register(a)
+	hub.Register(b)
+	waitForClients(t, hub, 2)
+
+	// Rejected messages neither reach the client nor fill its buffer
+	hub.Broadcast("news")
+	hub.Broadcast("news")
+	hub.Broadcast("alert")
+	for _, want := range []string{"news", "news", "alert"} {
+		select {
+		case msg := <-a.send:
+			assert.Equal(t, want, msg)
+		case <-time.After(time.Second):
+			t.Fatalf("alice did not receive %q", want)
+		}
+	}
+	select {
+	case msg := <-b.send:
+		assert.Equal(t, "alert", msg)
+	case <-time.After(time.Second):
+		t.Fatal("bob did not receive the alert")
+	}
+	assert.Equal(t, 2, hub.ClientCount(), "filtered client is not evicted")
+
+	everyone := func(*testClient) bool { return true }
+	assert.Equal(t, 1, hub.SendTo(everyone, "news"))
+	assert.Len(t, b.send, 0)
+}
+
+func TestHub_EvictClientsCap(t *testing.T) {
+	hub := New[*testClient, string]("test", 16)
+	hub.MaxEvictionsPerCycle = 2
+
+	var slow []*testClient
+	for i := 0; i < 5; i++ {
+		client := newTestClient(fmt.Sprintf("client-%d", i), "u", 1)
+		hub.clients[client.id] = client
+		slow = append(slow, client)
+	}
+
+	// First cycle removes only the cap, the rest is deferred
+	hub.evictClients(slow)
+	assert.Len(t, hub.clients, 3)
+	assert.Len(t, hub.pendingEvictions, 3)
+
+	// Re-detected clients are not queued twice
+	hub.evictClients(slow[2:])
+	assert.Len(t, hub.clients, 1)
+	assert.Len(t, hub.pendingEvictions, 1)
+
+	// Deferred clients are removed on a later cycle even without new findings
+	hub.evictClients(nil)
+	assert.Empty(t, hub.clients)
+	assert.Empty(t, hub.pendingEvictions)
+
+	// Unlimited when the cap is 0
+	hub.MaxEvictionsPerCycle = 0
+	var all []*testClient
+	for i := 0; i < 5; i++ {
+		client := newTestClient(fmt.Sprintf("other-%d", i), "u", 1)
+		hub.clients[client.id] = client
+		all = append(all, client)
+	}
+	hub.evictClients(all)
+	assert.Empty(t, hub.clients)
+}
+
+func TestHub_CloseAll(t *testing.T) {
+	hub := New[*testClient, string]("test", 16)
+	go hub.Run()
+
+	a := newTestClient("a", "alice", 1)
+	hub.Register(a)
+	waitForClients(t, hub, 1)
+	hub.CloseAll()
+
+	assert.Equal(t, 0, hub.ClientCount())
+	_, open := <-a.send
+	assert.False(t, open)
+
+	// A late unregister from the client's read pump is harmless
+	hub.Unregister(a)
+}
+
+func TestHub_Stop(t *testing.T) {
+	hub := New[*testClient, string]("test", 1)
+	stopped := make(chan struct{})
+	go func() {
+		hub.Run()
+		close(stopped)
+	}()
+
+	a := newTestClient("a", "alice", 4)
+	require.True(t, hub.Register(a))
+	waitForClients(t, hub, 1)
+
+	hub.Stop()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after Stop")
+	}
+	hub.Stop()
+
+	// Nothing blocks on the stopped loop
+	assert.False(t, hub.Register(newTestClient("b", "bob", 1)), "registrations are refused")
+	hub.Broadcast("dropped")
+	hub.Broadcast("dropped")
+	hub.Unregister(a)
+
+	// Connected clients can still be reached until they are closed
+	assert.Equal(t, 1, hub.ClientCount())
+	assert.Equal(t, 1, hub.SendTo(func(*testClient) bool { return true }, "bye"))
+	assert.Equal(t, 1, hub.Queued())
+	assert.Equal(t, "bye", <-a.send)
+	assert.Equal(t, 0, hub.Queued())
+
+	hub.CloseAll()
+	_, open := <-a.send
+	assert.False(t, open)
+}
@@ -0,0 +1,154 @@
+package wshub
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Priority ranks messages for delivery to a client whose buffer is full.
+//
+// A message that doesn't fit makes room by dropping the oldest queued
+// message of the lowest priority below its own. Only when every queued
+// message is at least as important does the send fail (and a broadcast
+// evict the client), so a lagging client loses node.health updates long
+// before it loses a security alert.
+type Priority int
+
+// Message priorities, lowest first.
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+	PriorityCritical
+)
+
+// String returns the priority's name as used in configuration.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	case PriorityCritical:
+		return "critical"
+	default:
+		return fmt.Sprintf("Priority(%d)", int(p))
+	}
+}
+
+// ParsePriority parses a priority name (low, normal, high or critical).
+func ParsePriority(name string) (Priority, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "low":
+		return PriorityLow, nil
+	case "normal":
+		return PriorityNormal, nil
+	case "high":
+		return PriorityHigh, nil
+	case "critical":
+		return PriorityCritical, nil
+	default:
+		return PriorityNormal, fmt.Errorf("unknown priority %q (use low, normal, high or critical)", name)
+	}
+}
+
+// ParsePriorities parses per-type priorities written as
+// "type=priority,type=priority", e.g. "security.alert=critical,node.health=low".
+func ParsePriorities(spec string) (map[string]Priority, error) {
+	priorities := make(map[string]Priority)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		msgType, name, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(msgType) == "" {
+			return nil, fmt.Errorf("invalid priority %q (use type=priority)", entry)
+		}
+		priority, err := ParsePriority(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", strings.TrimSpace(msgType), err)
+		}
+		priorities[strings.TrimSpace(msgType)] = priority
+	}
+	return priorities, nil
+}
+
+// priorityOf returns the message's priority (Normal without a classifier).
+func (h *Hub[C, M]) priorityOf(message M) Priority {
+	if h.Priority == nil {
+		return PriorityNormal
+	}
+	return h.Priority(message)
+}
+
+// deliver queues a message for a client without blocking. If the client's
+// buffer is full, lower-priority messages are dropped to make room. It
+// reports whether the message was queued.
+//
+// Callers must hold the read lock, so the outbox can't be closed meanwhile.
+func (h *Hub[C, M]) deliver(client C, message M, priority Priority) bool {
+	select {
+	case client.Outbox() <- message:
+		return true
+	default:
+	}
+	if h.Priority == nil || priority == PriorityLow {
+		return false
+	}
+
+	// One make-room at a time: concurrent drains of the same outbox would
+	// hand each other's messages back in the wrong order
+	h.makeRoomMu.Lock()
+	defer h.makeRoomMu.Unlock()
+
+	outbox := client.Outbox()
+	queued := make([]M, 0, cap(outbox))
+drain:
+	for len(queued) < cap(outbox) {
+		select {
+		case m := <-outbox:
+			queued = append(queued, m)
+		default:
+			break drain
+		}
+	}
+
+	// Drop the oldest message of the lowest priority below this one
+	victim := -1
+	lowest := priority
+	for i, m := range queued {
+		if p := h.priorityOf(m); p < lowest {
+			victim, lowest = i, p
+		}
+	}
+	if victim >= 0 {
+		queued = append(queued[:victim], queued[victim+1:]...)
+	}
+
+	// Put the queue back in order, followed by the message. The write pump
+	// may have freed room meanwhile, so the message can fit even without a
+	// victim; messages that no longer fit because concurrent senders took
+	// the space are dropped.
+	queued = append(queued, message)
+	delivered := false
+	dropped := 0
+	for i, m := range queued {
+		select {
+		case outbox <- m:
+			delivered = i == len(queued)-1
+		default:
+			dropped++
+		}
+	}
+	if victim >= 0 {
+		log.Printf("%s WebSocket client %s lagging: dropped a %s message for a %s one", h.name, client.HubID(), lowest, priority)
+	}
+	if dropped > 1 || (dropped == 1 && delivered) {
+		log.Printf("%s WebSocket client %s: %d queued messages no longer fit and were dropped", h.name, client.HubID(), dropped)
+	}
+	return delivered
+}
@@ -0,0 +1,114 @@
+package wshub
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// prefixPriority classifies test messages by their "priority:" prefix.
+func prefixPriority(message string) Priority {
+	name, _, _ := strings.Cut(message, ":")
+	priority, err := ParsePriority(name)
+	if err != nil {
+		return PriorityNormal
+	}
+	return priority
+}
+
+func drain(client *testClient) []string {
+	var messages []string
+	for len(client.send) > 0 {
+		messages = append(messages, <-client.send)
+	}
+	return messages
+}
+
+func TestHub_PriorityMakesRoom(t *testing.T) {
+	hub := New[*testClient, string]("test", 16)
+	hub.Priority = prefixPriority
+	go hub.Run()
+
+	a := newTestClient("a", "alice", 3)
+	hub.Register(a)
+	waitForClients(t, hub, 1)
+	all := func(*testClient) bool { return true }
+
+	a.send <- "normal:1"
+	a.send <- "low:2"
+	a.send <- "low:3"
+
+	// The oldest low-priority message makes room; order is kept
+	assert.Equal(t, 1, hub.SendTo(all, "critical:alert"))
+	assert.Equal(t, []string{"normal:1", "low:3", "critical:alert"}, drain(a))
+
+	// Nothing below the message's own priority: it isn't delivered
+	a.send <- "high:1"
+	a.send <- "critical:2"
+	a.send <- "high:3"
+	assert.Equal(t, 0, hub.SendTo(all, "high:4"))
+	assert.Equal(t, 0, hub.SendTo(all, "low:5"))
+	assert.Equal(t, []string{"high:1", "critical:2", "high:3"}, drain(a))
+}
+
+func TestHub_PriorityBroadcastKeepsLaggingClient(t *testing.T) {
+	hub := New[*testClient, string]("test", 16)
+	hub.Priority = prefixPriority
+	go hub.Run()
+
+	a := newTestClient("a", "alice", 2)
+	hub.Register(a)
+	waitForClients(t, hub, 1)
+
+	a.send <- "low:health-1"
+	a.send <- "low:health-2"
+
+	// A critical broadcast replaces a low message instead of evicting
+	hub.Broadcast("critical:alert")
+	require.Eventually(t, func() bool {
+		return len(a.send) == 2
+	}, time.Second, 5*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, 1, hub.ClientCount())
+	assert.Equal(t, []string{"low:health-2", "critical:alert"}, drain(a))
+
+	// A low broadcast to a full client still evicts it
+	a.send <- "normal:1"
+	a.send <- "normal:2"
+	hub.Broadcast("low:health-3")
+	waitForClients(t, hub, 0)
+}
+
+func TestHub_NoPriorityClassifier(t *testing.T) {
+	hub := New[*testClient, string]("test", 16)
+	go hub.Run()
+
+	a := newTestClient("a", "alice", 1)
+	hub.Register(a)
+	waitForClients(t, hub, 1)
+
+	// Without a classifier nothing is dropped to make room
+	a.send <- "low:1"
+	assert.Equal(t, 0, hub.SendTo(func(*testClient) bool { return true }, "critical:2"))
+	assert.Equal(t, []string{"low:1"}, drain(a))
+}
+
+func TestParsePriorities(t *testing.T) {
+	priorities, err := ParsePriorities(" security.alert=critical, node.health=LOW,,session.terminated = high ")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Priority{
+		"security.alert":     PriorityCritical,
+		"node.health":        PriorityLow,
+		"session.terminated": PriorityHigh,
+	}, priorities)
+
+	_, err = ParsePriorities("security.alert")
+	assert.Error(t, err)
+	_, err = ParsePriorities("security.alert=urgent")
+	assert.ErrorContains(t, err, "security.alert")
+
+	assert.Equal(t, "critical", PriorityCritical.String())
+}
@@ -39,14 +39,18 @@ func main() {
 	var controllerID string
 	var dockerHost string
 	var networkName string
+	var forwardBindIP string
+	var idCollision string
 
 	// Parse command-line flags
 	flag.StringVar(&natsURL, "nats-url", getEnv("NATS_URL", "nats://localhost:4222"), "NATS server URL")
 	flag.StringVar(&natsUser, "nats-user", getEnv("NATS_USER", ""), "NATS username")
 	flag.StringVar(&natsPassword, "nats-password", getEnv("NATS_PASSWORD", ""), "NATS password")
 	flag.StringVar(&controllerID, "controller-id", getEnv("CONTROLLER_ID", "streamspace-docker-controller-1"), "Unique controller ID")
+	flag.StringVar(&idCollision, "controller-id-collision", getEnv("CONTROLLER_ID_COLLISION", events.IDCollisionFail), "What to do if the controller ID is already in use: fail or suffix")
 	flag.StringVar(&dockerHost, "docker-host", getEnv("DOCKER_HOST", "unix:///var/run/docker.sock"), "Docker host")
 	flag.StringVar(&networkName, "network", getEnv("DOCKER_NETWORK", "streamspace"), "Docker network name")
+	flag.StringVar(&forwardBindIP, "forward-bind-ip", getEnv("FORWARD_BIND_IP", "127.0.0.1"), "Host IP to publish forwardable session ports on")
 	flag.Parse()
 
 	log.Printf("StreamSpace Docker Controller starting...")
@@ -55,7 +59,7 @@ func main() {
 	log.Printf("Docker Host: %s", dockerHost)
 
 	// Initialize Docker client
-	dockerClient, err := docker.NewClient(dockerHost, networkName)
+	dockerClient, err := docker.NewClient(dockerHost, networkName, forwardBindIP)
 	if err != nil {
 		log.Fatalf("Failed to create Docker client: %v", err)
 	}
@@ -73,6 +77,17 @@ func main() {
 	}
 	defer subscriber.Close()
 
+	// Refuse to run with the ID of a live controller: heartbeats and
+	// targeted commands would be attributed to the wrong instance
+	if idCollision != events.IDCollisionFail && idCollision != events.IDCollisionSuffix {
+		log.Fatalf("Invalid --controller-id-collision %q: must be %s or %s", idCollision, events.IDCollisionFail, events.IDCollisionSuffix)
+	}
+	controllerID, err = subscriber.ClaimControllerID(idCollision)
+	if err != nil {
+		log.Fatalf("Cannot start: %v", err)
+	}
+	log.Printf("Registered controller ID: %s", controllerID)
+
 	// Start subscriber in background
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strconv"
 	"strings"
 
 	"github.com/docker/docker/api/types"
@@ -19,12 +20,17 @@ import (
 
 // Client wraps the Docker API client for StreamSpace operations.
 type Client struct {
-	docker      *client.Client
-	networkName string
+	docker        *client.Client
+	networkName   string
+	forwardBindIP string
 }
 
 // NewClient creates a new Docker client.
-func NewClient(host, networkName string) (*Client, error) {
+//
+// forwardBindIP is the host IP forwardable session ports are published on.
+// Use loopback when the API runs on the same host so the ports are only
+// reachable through the API's authenticated port-forward endpoint.
+func NewClient(host, networkName, forwardBindIP string) (*Client, error) {
 	opts := []client.Opt{
 		client.FromEnv,
 		client.WithAPIVersionNegotiation(),
@@ -46,9 +52,14 @@ func NewClient(host, networkName string) (*Client, error) {
 		return nil, fmt.Errorf("failed to connect to Docker: %w", err)
 	}
 
+	if forwardBindIP == "" {
+		forwardBindIP = "127.0.0.1"
+	}
+
 	return &Client{
-		docker:      cli,
-		networkName: networkName,
+		docker:        cli,
+		networkName:   networkName,
+		forwardBindIP: forwardBindIP,
 	}, nil
 }
 
@@ -69,6 +80,7 @@ type SessionConfig struct {
 	PersistentHome bool
 	HomeVolume     string
 	Env            map[string]string
+	ForwardPorts   []int // Extra ports published for the API's port-forward endpoint
 }
 
 // CreateSession creates a new session container.
@@ -97,6 +109,19 @@ func (c *Client) CreateSession(ctx context.Context, config SessionConfig) (strin
 		}
 	}
 
+	// Forwardable ports are reached through the authenticated API tunnel,
+	// so they're published on forwardBindIP (loopback by default)
+	for _, p := range config.ForwardPorts {
+		port := nat.Port(fmt.Sprintf("%d/tcp", p))
+		if _, exists := portBindings[port]; exists {
+			continue
+		}
+		exposedPorts[port] = struct{}{}
+		portBindings[port] = []nat.PortBinding{
+			{HostIP: c.forwardBindIP, HostPort: ""}, // Auto-assign host port
+		}
+	}
+
 	// Configure mounts
 	var mounts []mount.Mount
 	if config.PersistentHome && config.HomeVolume != "" {
@@ -241,6 +266,34 @@ func (c *Client) GetSessionURL(ctx context.Context, sessionID string, vncPort in
 	return "", fmt.Errorf("VNC port not exposed")
 }
 
+// GetForwardedPorts returns the host ports published for a session's
+// container ports, keyed by container port. The VNC port is included.
+//
+// Host ports are auto-assigned, so they change whenever the container
+// restarts and must be re-read after every start.
+func (c *Client) GetForwardedPorts(ctx context.Context, sessionID string) (map[string]int, error) {
+	containerName := fmt.Sprintf("ss-%s", sessionID)
+
+	info, err := c.docker.ContainerInspect(ctx, containerName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect container: %w", err)
+	}
+
+	ports := make(map[string]int)
+	for port, bindings := range info.NetworkSettings.Ports {
+		if port.Proto() != "tcp" || len(bindings) == 0 {
+			continue
+		}
+		hostPort, err := strconv.Atoi(bindings[0].HostPort)
+		if err != nil {
+			continue
+		}
+		ports[port.Port()] = hostPort
+	}
+
+	return ports, nil
+}
+
 // EnsureUserVolume creates a volume for user's persistent home if it doesn't exist.
 func (c *Client) EnsureUserVolume(ctx context.Context, userID string) (string, error) {
 	volumeName := fmt.Sprintf("streamspace-home-%s", userID)
@@ -0,0 +1,140 @@
+package events
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+// SubjectControllerIdentity is the prefix of the subject each controller
+// answers on while it runs (streamspace.controller.identity.<id>), so a
+// starting controller can tell whether its ID is already taken.
+const SubjectControllerIdentity = "streamspace.controller.identity"
+
+// Policies for a controller ID that is already in use.
+const (
+	// IDCollisionFail refuses to start (default).
+	IDCollisionFail = "fail"
+	// IDCollisionSuffix appends a unique token to the ID.
+	IDCollisionSuffix = "suffix"
+)
+
+// identityProbeTimeout bounds how long a live controller has to answer.
+const identityProbeTimeout = 2 * time.Second
+
+// ControllerIdentity is a live controller's answer to an identity probe.
+type ControllerIdentity struct {
+	ControllerID string    `json:"controller_id"`
+	Instance     string    `json:"instance"`
+	Hostname     string    `json:"hostname,omitempty"`
+	StartedAt    time.Time `json:"started_at"`
+}
+
+// ControllerIDInUseError is returned by ClaimControllerID when another live
+// controller already uses the ID.
+type ControllerIDInUseError struct {
+	ControllerID string
+	Owner        ControllerIdentity
+}
+
+func (e *ControllerIDInUseError) Error() string {
+	owner := e.Owner.Instance
+	if e.Owner.Hostname != "" {
+		owner = fmt.Sprintf("%s on %s", owner, e.Owner.Hostname)
+	}
+	return fmt.Sprintf("controller ID %q is already in use by a running controller (instance %s, started %s); "+
+		"set a unique CONTROLLER_ID or use --controller-id-collision=suffix",
+		e.ControllerID, owner, e.Owner.StartedAt.Format(time.RFC3339))
+}
+
+// ClaimControllerID makes sure no other running controller uses this
+// controller's ID and then answers identity probes for it.
+//
+// Heartbeats and targeted commands are keyed by controller ID, so two
+// controllers sharing one would silently corrupt the API's view of both.
+// With IDCollisionFail a taken ID is a *ControllerIDInUseError; with
+// IDCollisionSuffix a unique token is appended instead. It must be called
+// before Start, and returns the ID the controller runs as.
+func (s *Subscriber) ClaimControllerID(policy string) (string, error) {
+	if err := validateControllerID(s.controllerID); err != nil {
+		return "", err
+	}
+
+	for attempt := 0; ; attempt++ {
+		owner, inUse, err := s.probeControllerID(s.controllerID)
+		if err != nil {
+			return "", err
+		}
+		if !inUse {
+			break
+		}
+		if policy != IDCollisionSuffix || attempt >= 2 {
+			return "", &ControllerIDInUseError{ControllerID: s.controllerID, Owner: owner}
+		}
+		suffixed := fmt.Sprintf("%s-%s", s.controllerID, uuid.New().String()[:8])
+		log.Printf("Controller ID %s is in use, running as %s", s.controllerID, suffixed)
+		s.controllerID = suffixed
+	}
+
+	identity := ControllerIdentity{
+		ControllerID: s.controllerID,
+		Instance:     s.instance,
+		StartedAt:    time.Now(),
+	}
+	identity.Hostname, _ = os.Hostname()
+	reply, err := json.Marshal(identity)
+	if err != nil {
+		return "", err
+	}
+
+	subject := fmt.Sprintf("%s.%s", SubjectControllerIdentity, s.controllerID)
+	if _, err := s.conn.Subscribe(subject, func(msg *nats.Msg) {
+		if err := msg.Respond(reply); err != nil {
+			log.Printf("Failed to answer identity probe: %v", err)
+		}
+	}); err != nil {
+		return "", fmt.Errorf("failed to subscribe to %s: %w", subject, err)
+	}
+	// Make sure the subscription is registered before we report the ID claimed
+	if err := s.conn.Flush(); err != nil {
+		return "", fmt.Errorf("failed to register controller ID: %w", err)
+	}
+	return s.controllerID, nil
+}
+
+// probeControllerID asks whether a running controller answers for id.
+func (s *Subscriber) probeControllerID(id string) (ControllerIdentity, bool, error) {
+	var owner ControllerIdentity
+	subject := fmt.Sprintf("%s.%s", SubjectControllerIdentity, id)
+
+	msg, err := s.conn.Request(subject, nil, identityProbeTimeout)
+	if errors.Is(err, nats.ErrNoResponders) || errors.Is(err, nats.ErrTimeout) {
+		return owner, false, nil
+	}
+	if err != nil {
+		return owner, false, fmt.Errorf("failed to check controller ID %q: %w", id, err)
+	}
+	if err := json.Unmarshal(msg.Data, &owner); err != nil {
+		owner = ControllerIdentity{ControllerID: id, Instance: "unknown"}
+	}
+	return owner, owner.Instance != s.instance, nil
+}
+
+// validateControllerID rejects IDs that can't be used as a NATS subject
+// token.
+func validateControllerID(id string) error {
+	if id == "" {
+		return fmt.Errorf("controller ID must not be empty")
+	}
+	if strings.ContainsAny(id, ".*> \t") {
+		return fmt.Errorf("invalid controller ID %q: must not contain '.', '*', '>' or whitespace", id)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Result cache defaults.
+const (
+	defaultResultCacheSize = 1000
+	defaultResultCacheTTL  = 15 * time.Minute
+)
+
+// resultCache remembers the final status of commands this controller
+// completed, keyed by the command's event ID.
+//
+// The API resends a command whose result it didn't see (timeout, reconnect),
+// so the same command can arrive twice. Re-sending the cached status instead
+// of running the handler again makes delivery safely at-least-once: a
+// session's container is not created twice because its first status update
+// never arrived.
+//
+// Only successful commands are cached; a failed command is executed again
+// when it is resent. Entries expire after ttl (the window in which the API
+// may still resend) and the oldest entries are dropped beyond size.
+type resultCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]cachedResult
+	order   []string // event IDs, oldest first
+}
+
+type cachedResult struct {
+	status  SessionStatusEvent
+	expires time.Time
+}
+
+// newResultCache creates a cache holding up to size results for ttl.
+// Zero values use the defaults.
+func newResultCache(size int, ttl time.Duration) *resultCache {
+	if size <= 0 {
+		size = defaultResultCacheSize
+	}
+	if ttl <= 0 {
+		ttl = defaultResultCacheTTL
+	}
+	return &resultCache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cachedResult),
+	}
+}
+
+// get returns the status a completed command ended with.
+func (c *resultCache) get(eventID string) (SessionStatusEvent, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result, ok := c.entries[eventID]
+	if !ok || c.now().After(result.expires) {
+		return SessionStatusEvent{}, false
+	}
+	return result.status, true
+}
+
+// put records the status a command completed with.
+func (c *resultCache) put(eventID string, status SessionStatusEvent) {
+	if eventID == "" {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.prune(now)
+
+	if _, exists := c.entries[eventID]; exists {
+		return
+	}
+	c.order = append(c.order, eventID)
+	c.entries[eventID] = cachedResult{status: status, expires: now.Add(c.ttl)}
+}
+
+// prune drops expired entries and the oldest entries beyond size, leaving
+// room for one more. Entries expire in insertion order, so only the front of
+// order needs checking. Caller must hold mu.
+func (c *resultCache) prune(now time.Time) {
+	for len(c.order) > 0 {
+		oldest := c.order[0]
+		if len(c.order) < c.size && !now.After(c.entries[oldest].expires) {
+			return
+		}
+		delete(c.entries, oldest)
+		c.order = c.order[1:]
+	}
+}
@@ -0,0 +1,205 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a controllable time source for resultCache.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestResultCache(size int, ttl time.Duration) (*resultCache, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	cache := newResultCache(size, ttl)
+	cache.now = clock.now
+	return cache, clock
+}
+
+func TestResultCacheReplaysCompletedCommands(t *testing.T) {
+	cache, _ := newTestResultCache(10, time.Minute)
+
+	if _, ok := cache.get("evt-1"); ok {
+		t.Fatal("expected no result before the command completed")
+	}
+
+	cache.put("evt-1", SessionStatusEvent{SessionID: "alice-firefox", Status: "running", URL: "http://localhost:3000"})
+	status, ok := cache.get("evt-1")
+	if !ok {
+		t.Fatal("expected the completed command to be cached")
+	}
+	if status.SessionID != "alice-firefox" || status.Status != "running" || status.URL != "http://localhost:3000" {
+		t.Errorf("unexpected cached status: %+v", status)
+	}
+}
+
+func TestResultCacheSkipsAnonymousCommands(t *testing.T) {
+	cache, _ := newTestResultCache(10, time.Minute)
+
+	cache.put("", SessionStatusEvent{Status: "running"})
+
+	if _, ok := cache.get(""); ok {
+		t.Error("commands without an ID can't be deduplicated")
+	}
+	if len(cache.order) != 0 {
+		t.Errorf("expected no entries, got %d", len(cache.order))
+	}
+}
+
+func TestResultCacheKeepsFirstResult(t *testing.T) {
+	cache, _ := newTestResultCache(10, time.Minute)
+
+	cache.put("evt-1", SessionStatusEvent{Status: "running"})
+	cache.put("evt-1", SessionStatusEvent{Status: "failed"})
+
+	if status, _ := cache.get("evt-1"); status.Status != "running" {
+		t.Errorf("expected the first result to be kept, got %+v", status)
+	}
+	if len(cache.order) != 1 {
+		t.Errorf("expected one entry, got %d", len(cache.order))
+	}
+}
+
+func TestResultCacheExpiresEntries(t *testing.T) {
+	cache, clock := newTestResultCache(10, time.Minute)
+	cache.put("evt-1", SessionStatusEvent{Status: "running"})
+
+	clock.t = clock.t.Add(time.Minute)
+	if _, ok := cache.get("evt-1"); !ok {
+		t.Error("expected the entry to live for the full ttl")
+	}
+
+	clock.t = clock.t.Add(time.Second)
+	if _, ok := cache.get("evt-1"); ok {
+		t.Error("expected the entry to expire after ttl")
+	}
+
+	// Expired entries are dropped on the next put
+	cache.put("evt-2", SessionStatusEvent{Status: "running"})
+	if _, exists := cache.entries["evt-1"]; exists {
+		t.Error("expected the expired entry to be pruned")
+	}
+}
+
+func TestResultCacheEvictsOldestBeyondSize(t *testing.T) {
+	cache, clock := newTestResultCache(3, time.Hour)
+	for i := 1; i <= 4; i++ {
+		cache.put(fmt.Sprintf("evt-%d", i), SessionStatusEvent{Status: "running"})
+		clock.t = clock.t.Add(time.Second)
+	}
+
+	if _, ok := cache.get("evt-1"); ok {
+		t.Error("expected the oldest entry to be evicted")
+	}
+	for i := 2; i <= 4; i++ {
+		if _, ok := cache.get(fmt.Sprintf("evt-%d", i)); !ok {
+			t.Errorf("expected evt-%d to be cached", i)
+		}
+	}
+	if len(cache.entries) != 3 || len(cache.order) != 3 {
+		t.Errorf("expected 3 entries, got %d (order %d)", len(cache.entries), len(cache.order))
+	}
+}
+
+func TestResultCacheDefaults(t *testing.T) {
+	cache := newResultCache(0, 0)
+	if cache.size != defaultResultCacheSize || cache.ttl != defaultResultCacheTTL {
+		t.Errorf("expected defaults, got size=%d ttl=%s", cache.size, cache.ttl)
+	}
+}
+
+func TestResultCacheConcurrentAccess(t *testing.T) {
+	cache := newResultCache(50, time.Minute)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				id := fmt.Sprintf("evt-%d-%d", w, i)
+				cache.put(id, SessionStatusEvent{Status: "running"})
+				cache.get(id)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if len(cache.entries) > 50 || len(cache.entries) != len(cache.order) {
+		t.Errorf("cache out of bounds: %d entries, %d ordered", len(cache.entries), len(cache.order))
+	}
+}
+
+func TestHandleMessageResendsCompletedCommands(t *testing.T) {
+	var sent []SessionStatusEvent
+	s := &Subscriber{
+		controllerID: "docker-1",
+		results:      newResultCache(10, time.Minute),
+		publish: func(subject string, data []byte) error {
+			if subject != "streamspace.session.status" {
+				t.Errorf("status published to %s", subject)
+			}
+			var status SessionStatusEvent
+			if err := json.Unmarshal(data, &status); err != nil {
+				t.Fatalf("invalid status event: %v", err)
+			}
+			sent = append(sent, status)
+			return nil
+		},
+	}
+
+	runs := 0
+	handler := func(data []byte) (*SessionStatusEvent, error) {
+		runs++
+		return s.publishStatusWithURL("alice-firefox", "running", "Session created", "http://localhost:3000"), nil
+	}
+	command := []byte(`{"event_id": "evt-1", "session_id": "alice-firefox"}`)
+
+	s.handleMessage("streamspace.session.create.docker", handler, command)
+	s.handleMessage("streamspace.session.create.docker", handler, command)
+
+	if runs != 1 {
+		t.Fatalf("expected the handler to run once, ran %d times", runs)
+	}
+	if len(sent) != 2 {
+		t.Fatalf("expected the status to be sent twice, sent %d", len(sent))
+	}
+	first, resent := sent[0], sent[1]
+	if resent.SessionID != first.SessionID || resent.Status != first.Status || resent.URL != first.URL || resent.ControllerID != "docker-1" {
+		t.Errorf("expected the cached status to be re-sent, got %+v after %+v", resent, first)
+	}
+	if resent.EventID == first.EventID {
+		t.Error("expected the re-sent status to be a new event")
+	}
+
+	// A different command runs its handler
+	s.handleMessage("streamspace.session.create.docker", handler, []byte(`{"event_id": "evt-2"}`))
+	if runs != 2 {
+		t.Errorf("expected a new command to run the handler, ran %d times", runs)
+	}
+}
+
+func TestHandleMessageRunsFailedCommandsAgain(t *testing.T) {
+	s := &Subscriber{
+		results: newResultCache(10, time.Minute),
+		publish: func(string, []byte) error { return nil },
+	}
+
+	runs := 0
+	handler := func(data []byte) (*SessionStatusEvent, error) {
+		runs++
+		return nil, fmt.Errorf("docker unavailable")
+	}
+	command := []byte(`{"event_id": "evt-1"}`)
+
+	s.handleMessage("streamspace.session.wake.docker", handler, command)
+	s.handleMessage("streamspace.session.wake.docker", handler, command)
+
+	if runs != 2 {
+		t.Errorf("expected a failed command to run again when resent, ran %d times", runs)
+	}
+}
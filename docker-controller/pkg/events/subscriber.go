@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -25,8 +26,20 @@ type Subscriber struct {
 	conn         *nats.Conn
 	docker       *docker.Client
 	controllerID string
+	// instance identifies this process, to tell it apart from another
+	// controller claiming the same ID
+	instance string
+	// results replays the status of commands that are resent
+	results *resultCache
+	// migrations maps renamed subjects to their old and new names
+	migrations *SubjectMigrations
+	// publish sends an event to the names of subject (replaced in tests)
+	publish func(subject string, data []byte) error
 }
 
+// commandHandler runs a command and returns the status it completed with.
+type commandHandler func(data []byte) (*SessionStatusEvent, error)
+
 // NewSubscriber creates a new NATS event subscriber.
 func NewSubscriber(cfg Config, dockerClient *docker.Client, controllerID string) (*Subscriber, error) {
 	if cfg.URL == "" {
@@ -49,17 +62,24 @@ func NewSubscriber(cfg Config, dockerClient *docker.Client, controllerID string)
 		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
 	}
 
+	migrations := subjectMigrationsFromEnv()
 	return &Subscriber{
 		conn:         conn,
 		docker:       dockerClient,
 		controllerID: controllerID,
+		instance:     uuid.New().String(),
+		results:      newResultCache(0, 0),
+		migrations:   migrations,
+		publish: func(subject string, data []byte) error {
+			return migrations.Publish(conn, subject, data)
+		},
 	}, nil
 }
 
 // Start starts the subscriber and begins processing events.
 func (s *Subscriber) Start(ctx context.Context) error {
 	// Subscribe to Docker-specific events
-	subjects := map[string]func(data []byte) error{
+	subjects := map[string]commandHandler{
 		"streamspace.session.create.docker":    s.handleSessionCreate,
 		"streamspace.session.delete.docker":    s.handleSessionDelete,
 		"streamspace.session.hibernate.docker": s.handleSessionHibernate,
@@ -67,16 +87,24 @@ func (s *Subscriber) Start(ctx context.Context) error {
 	}
 
 	for subject, handler := range subjects {
-		h := handler // Capture for closure
-		_, err := s.conn.Subscribe(subject, func(msg *nats.Msg) {
-			if err := h(msg.Data); err != nil {
-				log.Printf("Error handling event %s: %v", subject, err)
-			}
+		subject, h := subject, handler // Capture for closure
+		_, err := s.migrations.Subscribe(s.conn, subject, "", func(msg *nats.Msg) {
+			s.handleMessage(subject, h, msg.Data)
 		})
 		if err != nil {
 			return fmt.Errorf("failed to subscribe to %s: %w", subject, err)
 		}
-		log.Printf("Subscribed to NATS subject: %s", subject)
+		log.Printf("Subscribed to NATS subject: %s", strings.Join(s.migrations.SubscribeSubjects(subject), ", "))
+
+		// Commands of sessions pinned to this controller arrive on its
+		// own subject
+		controllerSubject := subject + "." + s.controllerID
+		_, err = s.migrations.Subscribe(s.conn, controllerSubject, "", func(msg *nats.Msg) {
+			s.handleMessage(subject, h, msg.Data)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", controllerSubject, err)
+		}
 	}
 
 	// Block until context is cancelled
@@ -91,15 +119,61 @@ func (s *Subscriber) Close() {
 	}
 }
 
+// handleMessage runs the handler of a command received on subject.
+//
+// A command that already completed (it was resent because its status
+// update was lost) re-sends the status it completed with instead of being
+// executed again.
+func (s *Subscriber) handleMessage(subject string, handler commandHandler, data []byte) {
+	var envelope struct {
+		EventID string `json:"event_id"`
+	}
+	_ = json.Unmarshal(data, &envelope)
+
+	if status, done := s.results.get(envelope.EventID); done {
+		log.Printf("Command %s (%s) already completed, re-sending its status", envelope.EventID, subject)
+		status.EventID = uuid.New().String()
+		status.Timestamp = time.Now()
+		s.sendStatus(status)
+		return
+	}
+
+	status, err := handler(data)
+	if err != nil {
+		log.Printf("Error handling event %s: %v", subject, err)
+		return
+	}
+	if status != nil {
+		s.results.put(envelope.EventID, *status)
+	}
+}
+
 // handleSessionCreate handles session creation events.
-func (s *Subscriber) handleSessionCreate(data []byte) error {
+func (s *Subscriber) handleSessionCreate(data []byte) (*SessionStatusEvent, error) {
 	var event SessionCreateEvent
 	if err := json.Unmarshal(data, &event); err != nil {
-		return fmt.Errorf("failed to unmarshal: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
 	}
 
 	log.Printf("Creating Docker session: %s for user %s", event.SessionID, event.UserID)
 
+	// The API shouldn't route Kubernetes-only templates here, but fail
+	// clearly rather than trying to run them if it does
+	if event.TemplateConfig != nil && len(event.TemplateConfig.SupportedBackends) > 0 {
+		supported := false
+		for _, backend := range event.TemplateConfig.SupportedBackends {
+			if backend == "docker" {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			err := fmt.Errorf("template %s does not support the docker backend", event.TemplateID)
+			s.publishStatus(event.SessionID, "failed", err.Error())
+			return nil, err
+		}
+	}
+
 	// Ensure user volume exists for persistent home
 	var homeVolume string
 	if event.PersistentHome {
@@ -107,7 +181,7 @@ func (s *Subscriber) handleSessionCreate(data []byte) error {
 		homeVolume, err = s.docker.EnsureUserVolume(context.Background(), event.UserID)
 		if err != nil {
 			s.publishStatus(event.SessionID, "failed", fmt.Sprintf("Failed to create home volume: %v", err))
-			return err
+			return nil, err
 		}
 	}
 
@@ -152,100 +226,127 @@ func (s *Subscriber) handleSessionCreate(data []byte) error {
 		HomeVolume:     homeVolume,
 		Env:            env,
 	}
+	if event.TemplateConfig != nil {
+		config.ForwardPorts = event.TemplateConfig.ForwardPorts
+	}
 
 	_, err := s.docker.CreateSession(context.Background(), config)
 	if err != nil {
 		s.publishStatus(event.SessionID, "failed", fmt.Sprintf("Failed to create container: %v", err))
-		return err
+		return nil, err
 	}
 
 	// Get URL
 	url, _ := s.docker.GetSessionURL(context.Background(), event.SessionID, vncPort)
+	ports, _ := s.docker.GetForwardedPorts(context.Background(), event.SessionID)
 
-	s.publishStatusWithURL(event.SessionID, "running", "Session created", url)
-	return nil
+	return s.publishStatusWithPorts(event.SessionID, "running", "Session created", url, ports), nil
 }
 
 // handleSessionDelete handles session deletion events.
-func (s *Subscriber) handleSessionDelete(data []byte) error {
+func (s *Subscriber) handleSessionDelete(data []byte) (*SessionStatusEvent, error) {
 	var event SessionDeleteEvent
 	if err := json.Unmarshal(data, &event); err != nil {
-		return fmt.Errorf("failed to unmarshal: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
 	}
 
 	log.Printf("Deleting Docker session: %s", event.SessionID)
 
-	if err := s.docker.RemoveSession(context.Background(), event.SessionID, event.Force); err != nil {
-		return err
+	// A cancelled launch may already have started its container
+	if err := s.docker.RemoveSession(context.Background(), event.SessionID, event.Force || event.Cancel); err != nil {
+		return nil, err
 	}
 
-	s.publishStatus(event.SessionID, "deleted", "Session deleted")
-	return nil
+	if event.Cancel {
+		status := SessionStatusEvent{
+			EventID:      uuid.New().String(),
+			Timestamp:    time.Now(),
+			SessionID:    event.SessionID,
+			Status:       "deleted",
+			Phase:        "Cancelled",
+			Message:      "Session creation cancelled",
+			ControllerID: s.controllerID,
+		}
+		s.sendStatus(status)
+		return &status, nil
+	}
+	return s.publishStatus(event.SessionID, "deleted", "Session deleted"), nil
 }
 
 // handleSessionHibernate handles session hibernation events.
-func (s *Subscriber) handleSessionHibernate(data []byte) error {
+func (s *Subscriber) handleSessionHibernate(data []byte) (*SessionStatusEvent, error) {
 	var event SessionHibernateEvent
 	if err := json.Unmarshal(data, &event); err != nil {
-		return fmt.Errorf("failed to unmarshal: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
 	}
 
 	log.Printf("Hibernating Docker session: %s", event.SessionID)
 
 	if err := s.docker.StopSession(context.Background(), event.SessionID); err != nil {
 		s.publishStatus(event.SessionID, "failed", fmt.Sprintf("Failed to hibernate: %v", err))
-		return err
+		return nil, err
 	}
 
-	s.publishStatus(event.SessionID, "hibernated", "Session hibernated")
-	return nil
+	return s.publishStatus(event.SessionID, "hibernated", "Session hibernated"), nil
 }
 
 // handleSessionWake handles session wake events.
-func (s *Subscriber) handleSessionWake(data []byte) error {
+func (s *Subscriber) handleSessionWake(data []byte) (*SessionStatusEvent, error) {
 	var event SessionWakeEvent
 	if err := json.Unmarshal(data, &event); err != nil {
-		return fmt.Errorf("failed to unmarshal: %w", err)
+		return nil, fmt.Errorf("failed to unmarshal: %w", err)
 	}
 
 	log.Printf("Waking Docker session: %s", event.SessionID)
 
 	if err := s.docker.StartSession(context.Background(), event.SessionID); err != nil {
 		s.publishStatus(event.SessionID, "failed", fmt.Sprintf("Failed to wake: %v", err))
-		return err
+		return nil, err
 	}
 
 	// Get URL
 	url, _ := s.docker.GetSessionURL(context.Background(), event.SessionID, 3000)
+	ports, _ := s.docker.GetForwardedPorts(context.Background(), event.SessionID)
 
-	s.publishStatusWithURL(event.SessionID, "running", "Session woken", url)
-	return nil
+	return s.publishStatusWithPorts(event.SessionID, "running", "Session woken", url, ports), nil
 }
 
 // publishStatus publishes a session status update.
-func (s *Subscriber) publishStatus(sessionID, status, message string) {
-	s.publishStatusWithURL(sessionID, status, message, "")
+func (s *Subscriber) publishStatus(sessionID, status, message string) *SessionStatusEvent {
+	return s.publishStatusWithURL(sessionID, status, message, "")
 }
 
 // publishStatusWithURL publishes a session status update with URL.
-func (s *Subscriber) publishStatusWithURL(sessionID, status, message, url string) {
+func (s *Subscriber) publishStatusWithURL(sessionID, status, message, url string) *SessionStatusEvent {
+	return s.publishStatusWithPorts(sessionID, status, message, url, nil)
+}
+
+// publishStatusWithPorts publishes a session status update with URL and the
+// host ports published for the container, and returns the published event.
+func (s *Subscriber) publishStatusWithPorts(sessionID, status, message, url string, ports map[string]int) *SessionStatusEvent {
 	event := SessionStatusEvent{
-		EventID:      uuid.New().String(),
-		Timestamp:    time.Now(),
-		SessionID:    sessionID,
-		Status:       status,
-		Message:      message,
-		URL:          url,
-		ControllerID: s.controllerID,
+		EventID:        uuid.New().String(),
+		Timestamp:      time.Now(),
+		SessionID:      sessionID,
+		Status:         status,
+		Message:        message,
+		URL:            url,
+		ControllerID:   s.controllerID,
+		ForwardedPorts: ports,
 	}
+	s.sendStatus(event)
+	return &event
+}
 
+// sendStatus publishes a status event.
+func (s *Subscriber) sendStatus(event SessionStatusEvent) {
 	data, err := json.Marshal(event)
 	if err != nil {
 		log.Printf("Failed to marshal status event: %v", err)
 		return
 	}
 
-	if err := s.conn.Publish("streamspace.session.status", data); err != nil {
+	if err := s.publish("streamspace.session.status", data); err != nil {
 		log.Printf("Failed to publish status: %v", err)
 	}
 }
@@ -25,6 +25,10 @@ type TemplateConfig struct {
 	VNCPort     int               `json:"vnc_port"`
 	DisplayName string            `json:"display_name,omitempty"`
 	Env         map[string]string `json:"env,omitempty"`
+	// ForwardPorts are extra container ports to publish for port-forwarding
+	ForwardPorts []int `json:"forward_ports,omitempty"`
+	// SupportedBackends are the platforms the template can run on (empty = all)
+	SupportedBackends []string `json:"supported_backends,omitempty"`
 }
 
 // SessionDeleteEvent is received when a session should be deleted.
@@ -35,6 +39,9 @@ type SessionDeleteEvent struct {
 	UserID    string    `json:"user_id"`
 	Platform  string    `json:"platform"`
 	Force     bool      `json:"force"`
+
+	// Cancel marks the delete of a session that is still starting.
+	Cancel bool `json:"cancel,omitempty"`
 }
 
 // SessionHibernateEvent is received when a session should be hibernated.
@@ -65,6 +72,8 @@ type SessionStatusEvent struct {
 	URL          string    `json:"url,omitempty"`
 	Message      string    `json:"message,omitempty"`
 	ControllerID string    `json:"controller_id"`
+	// ForwardedPorts maps container port to published host port
+	ForwardedPorts map[string]int `json:"forwarded_ports,omitempty"`
 }
 
 // ResourceSpec defines resource requirements.
@@ -48,6 +48,7 @@ type SessionSpec struct {
 	// Required: Yes
 	// Example: "alice", "bob@example.com"
 	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
 	User string `json:"user"`
 
 	// Template specifies the name of the Template resource to use for this session.
@@ -62,6 +63,7 @@ type SessionSpec struct {
 	// Required: Yes
 	// Example: "firefox-browser", "vscode-dev"
 	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MinLength=1
 	Template string `json:"template"`
 
 	// State defines the desired lifecycle state of the session.
@@ -116,6 +118,22 @@ type SessionSpec struct {
 	// +optional
 	PersistentHome bool `json:"persistentHome,omitempty"`
 
+	// ForkFrom names a session (of the same user) whose home volume is
+	// forked for this session.
+	//
+	// Instead of the shared "home-{user}" PVC, the session gets its own
+	// "home-{session}" PVC holding a copy of the source's home as it was at
+	// fork time. The copy is restored from a CSI VolumeSnapshot when the
+	// source's storage class supports snapshots, otherwise a Job copies the
+	// files. The forked volume is owned by the session and deleted with it.
+	//
+	// Only used when PersistentHome is enabled.
+	//
+	// Example: "alice-vscode-8f2c1a9e"
+	// Optional: Yes
+	// +optional
+	ForkFrom string `json:"forkFrom,omitempty"`
+
 	// IdleTimeout specifies the duration of inactivity before auto-hibernation.
 	//
 	// Format: Duration string (e.g., "30m", "1h", "2h30m")
@@ -128,6 +146,7 @@ type SessionSpec struct {
 	// Example: "30m", "1h", "2h30m"
 	// Optional: Yes
 	// +optional
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
 	IdleTimeout string `json:"idleTimeout,omitempty"`
 
 	// MaxSessionDuration specifies the maximum lifetime of a session.
@@ -142,8 +161,26 @@ type SessionSpec struct {
 	// Example: "8h", "24h"
 	// Optional: Yes
 	// +optional
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
 	MaxSessionDuration string `json:"maxSessionDuration,omitempty"`
 
+	// StartupTimeout is how long the session may take to become ready.
+	//
+	// Format: Duration string (e.g., "10m")
+	//
+	// A launch that isn't ready in time (image can't be pulled, pod can't be
+	// scheduled, container keeps crashing) is marked Failed with a diagnosis
+	// of what it was stuck on, and its pod is removed. Counted from when the
+	// session's Deployment is created, so time spent queued for a launch
+	// slot doesn't count.
+	//
+	// Example: "10m"
+	// Optional: Yes (default: the template's startupTimeout, then
+	// SESSION_STARTUP_TIMEOUT)
+	// +optional
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	StartupTimeout string `json:"startupTimeout,omitempty"`
+
 	// Tags are user-defined labels for organizing and filtering sessions.
 	//
 	// Tags can be used to:
@@ -155,8 +192,61 @@ type SessionSpec struct {
 	// Optional: Yes
 	// +optional
 	Tags []string `json:"tags,omitempty"`
+
+	// Parameters supplies values for the template's parameters, substituted
+	// into its Env values and Args. Validated against the template's
+	// parameter schema when the session is created.
+	//
+	// Example: {"project": "alpha", "debug": "true"}
+	// Optional: Yes
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+
+	// Priority is the session's scheduling priority class. The controller
+	// maps it to a Kubernetes PriorityClass, so under contention High
+	// (interactive) sessions preempt Low (batch) ones.
+	//
+	// Defaults to the template's DefaultPriority, then Normal. The API caps
+	// the priority a user may request by their quota.
+	//
+	// Example: "Low"
+	// Optional: Yes
+	// +optional
+	// +kubebuilder:validation:Enum=Low;Normal;High
+	Priority string `json:"priority,omitempty"`
+
+	// PinToNode keeps the session's pod on one node across restarts and
+	// wakes, so an operator debugging it finds its logs and local state in
+	// one place. The controller sets strict node affinity (replacing the
+	// template's session affinity) and reports the node's health in the
+	// Pinned condition; a pinned node that can't take the pod leaves the
+	// session Pending rather than moving it.
+	//
+	// A debugging aid for operators, not placement policy: use the
+	// template's node selection for that.
+	//
+	// Example: "worker-3"
+	// Optional: Yes
+	// +optional
+	PinToNode string `json:"pinToNode,omitempty"`
+
+	// PinToAgent routes all of the session's commands (hibernate, wake,
+	// delete) to one controller instance instead of any controller of the
+	// platform. The value is the controller's ID (CONTROLLER_ID).
+	//
+	// Example: "streamspace-docker-controller-2"
+	// Optional: Yes
+	// +optional
+	PinToAgent string `json:"pinToAgent,omitempty"`
 }
 
+// Session priority classes for SessionSpec.Priority.
+const (
+	SessionPriorityLow    = "Low"
+	SessionPriorityNormal = "Normal"
+	SessionPriorityHigh   = "High"
+)
+
 // SessionStatus defines the observed state of a Session.
 //
 // The status is managed entirely by the controller and should not be modified by users.
@@ -182,7 +272,12 @@ type SessionStatus struct {
 	// Possible values:
 	//   - "Pending": Resources are being created
 	//   - "Running": Pod is running and ready
+	//   - "Hibernating": Session waits for its connections to close before
+	//     it is scaled to zero (see SESSION_HIBERNATION_GRACE)
 	//   - "Hibernated": Session is scaled to zero (sleeping)
+	//   - "CrashLooping": Session container keeps crashing (see CrashLoop)
+	//   - "Evicted": Session pod was evicted or preempted and is being
+	//     rescheduled (see Eviction)
 	//   - "Failed": Session encountered an error
 	//   - "Terminated": Session is being deleted
 	//
@@ -192,6 +287,13 @@ type SessionStatus struct {
 	// +optional
 	Phase string `json:"phase,omitempty"`
 
+	// ObservedGeneration is the Session generation the phase reflects. It
+	// trails metadata.generation while the controller applies a change.
+	//
+	// Optional: Yes (computed by controller)
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
 	// PodName is the name of the Kubernetes Pod running this session.
 	//
 	// This can be used to:
@@ -233,6 +335,14 @@ type SessionStatus struct {
 	// +optional
 	LastActivity *metav1.Time `json:"lastActivity,omitempty"`
 
+	// ReadyAt is when the session first became ready. ReadyAt minus the
+	// creation timestamp is the session's launch latency (also exported as
+	// streamspace_session_time_to_ready_seconds).
+	//
+	// Optional: Yes (computed by controller)
+	// +optional
+	ReadyAt *metav1.Time `json:"readyAt,omitempty"`
+
 	// ResourceUsage tracks the current CPU and memory consumption of the session pod.
 	//
 	// Values are fetched from Kubernetes metrics API and updated periodically.
@@ -264,6 +374,213 @@ type SessionStatus struct {
 	// Optional: Yes (managed by controller)
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// CrashLoop tracks restarts of the session container.
+	//
+	// When the container restarts too often within the detection window the
+	// phase becomes "CrashLooping" and the last termination reason and
+	// message explain why.
+	//
+	// Optional: Yes (computed by controller)
+	// +optional
+	CrashLoop *CrashLoopStatus `json:"crashLoop,omitempty"`
+
+	// Eviction records the last involuntary disruption of the session pod
+	// (node-pressure eviction, preemption, drain).
+	//
+	// The "Evicted" condition is True until a replacement pod is ready.
+	//
+	// Optional: Yes (computed by controller)
+	// +optional
+	Eviction *EvictionStatus `json:"eviction,omitempty"`
+
+	// Priority is the effective priority class the session was scheduled
+	// with (its own, or the template default).
+	// +optional
+	Priority string `json:"priority,omitempty"`
+
+	// EffectiveResources are the requests and limits the session container
+	// runs with. Requests are lower than the spec's when the template's
+	// category is overcommitted; limits still cap bursts.
+	//
+	// Optional: Yes (computed by controller)
+	// +optional
+	EffectiveResources *corev1.ResourceRequirements `json:"effectiveResources,omitempty"`
+
+	// Hibernation explains the last automatic hibernation: whether the
+	// standard idle timeout applied or a shorter, cost-scaled one.
+	//
+	// Optional: Yes (computed by controller)
+	// +optional
+	Hibernation *HibernationStatus `json:"hibernation,omitempty"`
+
+	// StreamingFeatures are the streaming features the session container
+	// was started with, so clients only offer what works.
+	//
+	// Optional: Yes (computed by controller)
+	// +optional
+	StreamingFeatures *StreamingFeatures `json:"streamingFeatures,omitempty"`
+}
+
+// StreamingFeatures are the effective streaming features of a session: the
+// template's spec.vnc settings after security policy.
+//
+// Example:
+//
+//	streamingFeatures:
+//	  clipboard: false
+//	  audio: true
+//	  fileTransfer: false
+//	  maxResolution: "1920x1080"
+//	  restricted: true
+type StreamingFeatures struct {
+	// Clipboard is true if clipboard sync is enabled.
+	Clipboard bool `json:"clipboard"`
+
+	// Audio is true if audio is streamed.
+	Audio bool `json:"audio"`
+
+	// FileTransfer is true if file upload and download are enabled.
+	FileTransfer bool `json:"fileTransfer"`
+
+	// MaxResolution is the resolution cap (WIDTHxHEIGHT), if any.
+	// +optional
+	MaxResolution string `json:"maxResolution,omitempty"`
+
+	// Restricted is true when security policy disabled clipboard sync and
+	// file transfer because the template is untrusted.
+	// +optional
+	Restricted bool `json:"restricted,omitempty"`
+}
+
+// Hibernation reasons (HibernationStatus.Reason).
+const (
+	// HibernationReasonIdle means the session exceeded its idle timeout.
+	HibernationReasonIdle = "IdleTimeout"
+
+	// HibernationReasonCost means the session exceeded an idle timeout
+	// shortened because of its hourly cost (Template.Spec.HibernationPolicy).
+	HibernationReasonCost = "CostIdleTimeout"
+)
+
+// HomeCreatedByAnnotation is set on a user's home PVC to the name of the
+// session that created it. If that session's creation is cancelled, the PVC
+// is deleted again unless another session has started using it.
+const HomeCreatedByAnnotation = "stream.space/created-by-session"
+
+// StateActorAnnotation is set on a Session whose spec.state the controller
+// changed on its own, to who changed it (e.g. "reconciler:idle"). It is
+// reported with the resulting status so the API can audit the change, and
+// removed when the state is changed through the API.
+const StateActorAnnotation = "stream.space/state-actor"
+
+// StateCommandTimeAnnotation is set on a Session to when the state command
+// (hibernate or wake) that last set spec.state was issued, in RFC 3339
+// format. Commands issued earlier are stale and ignored, so a session ends
+// in the state of the latest command even if commands arrive out of order.
+const StateCommandTimeAnnotation = "stream.space/state-command-time"
+
+// ActiveConnectionsAnnotation is set by the API to the number of open
+// connections of a Session. The controller waits for it to drop to zero
+// before scaling down a hibernating session.
+const ActiveConnectionsAnnotation = "stream.space/active-connections"
+
+// HibernationStatus records why the controller hibernated a session.
+//
+// Example:
+//
+//	hibernation:
+//	  reason: "CostIdleTimeout"
+//	  idleTimeout: "7m30s"
+//	  hourlyCost: "4.00"
+//	  message: "Idle for 7m31s; idle timeout 30m0s shortened to 7m30s for hourly cost 4.00 (reference 1.00)"
+//	  time: "2025-01-15T14:25:00Z"
+type HibernationStatus struct {
+	// Reason is IdleTimeout or CostIdleTimeout.
+	Reason string `json:"reason"`
+
+	// IdleTimeout is the effective idle timeout that was exceeded.
+	IdleTimeout string `json:"idleTimeout"`
+
+	// HourlyCost is the session's estimated hourly cost, when cost scaling
+	// was evaluated.
+	// +optional
+	HourlyCost string `json:"hourlyCost,omitempty"`
+
+	// Message is a human-readable explanation.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Time is when the session was hibernated.
+	Time metav1.Time `json:"time"`
+}
+
+// EvictionStatus describes an involuntary disruption of a session pod.
+//
+// Example:
+//
+//	eviction:
+//	  podName: "ss-alice-firefox-7d9f8-abcde"
+//	  reason: "Evicted"
+//	  message: "The node was low on resource: memory."
+//	  time: "2025-01-15T14:25:00Z"
+//	  dataLost: true
+type EvictionStatus struct {
+	// PodName is the pod that was disrupted.
+	PodName string `json:"podName"`
+
+	// Reason is the disruption reason (e.g. "Evicted", "PreemptionByScheduler",
+	// "EvictionByEvictionAPI", "DeletionByTaintManager").
+	Reason string `json:"reason"`
+
+	// Message is the human-readable explanation from Kubernetes.
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// Time is when the controller observed the disruption.
+	Time metav1.Time `json:"time"`
+
+	// DataLost is true when the session had no persistent home, so anything
+	// stored in the pod was lost.
+	// +optional
+	DataLost bool `json:"dataLost,omitempty"`
+}
+
+// CrashLoopStatus records restarts of a session container.
+//
+// Kubernetes only reports a cumulative restart count, so the controller
+// remembers the count at the start of the current detection window and
+// compares against it.
+//
+// Example:
+//
+//	crashLoop:
+//	  restartCount: 7
+//	  windowStart: "2025-01-15T14:25:00Z"
+//	  windowStartRestartCount: 2
+//	  lastTerminationReason: "OOMKilled"
+//	  lastExitCode: 137
+type CrashLoopStatus struct {
+	// RestartCount is the total number of container restarts of the pod.
+	RestartCount int32 `json:"restartCount"`
+
+	// WindowStart is when the current detection window started.
+	WindowStart metav1.Time `json:"windowStart"`
+
+	// WindowStartRestartCount is RestartCount at WindowStart.
+	WindowStartRestartCount int32 `json:"windowStartRestartCount"`
+
+	// LastTerminationReason is why the container last exited (e.g. "Error", "OOMKilled").
+	// +optional
+	LastTerminationReason string `json:"lastTerminationReason,omitempty"`
+
+	// LastTerminationMessage is the container's termination message, if any.
+	// +optional
+	LastTerminationMessage string `json:"lastTerminationMessage,omitempty"`
+
+	// LastExitCode is the exit code of the last termination.
+	// +optional
+	LastExitCode int32 `json:"lastExitCode,omitempty"`
 }
 
 // ResourceUsage tracks current resource consumption for a session.
@@ -0,0 +1,74 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// durationPattern is the schema pattern of IdleTimeout, MaxSessionDuration
+// and StartupTimeout. time.ParseDuration is more lenient (".5h", "1.h"), so
+// durations must match both to be accepted by the API server.
+var durationPattern = regexp.MustCompile(`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`)
+
+// Valid values of SessionSpec.State.
+var validSessionStates = []string{"running", "hibernated", "terminated"}
+
+// Validate checks the fields the CRD schema can only partly validate, so
+// callers creating Sessions can reject a bad spec with a clear error before
+// it is persisted, rather than a reconciler tripping over it later.
+//
+// Durations must parse with time.ParseDuration: "30" (no unit) is rejected
+// instead of silently disabling hibernation.
+func (s *SessionSpec) Validate() error {
+	var problems []string
+	if strings.TrimSpace(s.User) == "" {
+		problems = append(problems, "user is required")
+	}
+	if strings.TrimSpace(s.Template) == "" {
+		problems = append(problems, "template is required")
+	}
+	if !validSessionState(s.State) {
+		problems = append(problems, fmt.Sprintf("state %q must be one of %s", s.State, strings.Join(validSessionStates, ", ")))
+	}
+	if err := validateDuration(s.IdleTimeout); err != nil {
+		problems = append(problems, fmt.Sprintf("idleTimeout: %v", err))
+	}
+	if err := validateDuration(s.MaxSessionDuration); err != nil {
+		problems = append(problems, fmt.Sprintf("maxSessionDuration: %v", err))
+	}
+	if err := validateDuration(s.StartupTimeout); err != nil {
+		problems = append(problems, fmt.Sprintf("startupTimeout: %v", err))
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("invalid session spec: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+func validSessionState(state string) bool {
+	for _, valid := range validSessionStates {
+		if state == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// validateDuration accepts an empty string (unset) or a positive duration
+// with units ("30m", "1h30m", "1.5h").
+func validateDuration(value string) error {
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil || !durationPattern.MatchString(value) {
+		return fmt.Errorf("%q is not a duration (use units, e.g. \"30m\" or \"2h\")", value)
+	}
+	if d <= 0 {
+		return fmt.Errorf("%q must be positive", value)
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package v1alpha1
 
 import (
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -40,6 +41,10 @@ import (
 //	    port: 3000
 //	  capabilities: ["Network", "Audio", "Clipboard"]
 //	  tags: ["browser", "web", "privacy"]
+//	  defaultIdleTimeout: "30m"
+//	  defaultMaxSessionDuration: "8h"
+//	  homeVolumeAccessMode: "ReadWriteMany"
+//	  sessionAffinity: "None"
 type TemplateSpec struct {
 	// DisplayName is the human-readable name shown in the UI.
 	//
@@ -125,6 +130,15 @@ type TemplateSpec struct {
 	// +optional
 	DefaultResources corev1.ResourceRequirements `json:"defaultResources,omitempty"`
 
+	// DisableOvercommit runs sessions with their full requests even when
+	// the controller overcommits the template's category
+	// (SESSION_OVERCOMMIT_RATIOS). Set it for workloads that can't tolerate
+	// CPU throttling or memory pressure from busy neighbours.
+	//
+	// Optional: Yes (default: false)
+	// +optional
+	DisableOvercommit bool `json:"disableOvercommit,omitempty"`
+
 	// Ports define the container ports that should be exposed.
 	//
 	// Common ports:
@@ -208,6 +222,18 @@ type TemplateSpec struct {
 	// +optional
 	VNC VNCConfig `json:"vnc,omitempty"`
 
+	// WebApp configures native web applications served over HTTP.
+	//
+	// Example:
+	//   webapp:
+	//     enabled: true
+	//     port: 8080
+	//     basePath: /
+	//
+	// Optional: Yes
+	// +optional
+	WebApp *WebAppConfig `json:"webapp,omitempty"`
+
 	// Capabilities describe special features this application supports.
 	//
 	// Standard capabilities:
@@ -238,8 +264,476 @@ type TemplateSpec struct {
 	// Optional: Yes
 	// +optional
 	Tags []string `json:"tags,omitempty"`
+
+	// DefaultIdleTimeout is the idle timeout applied to sessions that don't set one.
+	//
+	// Format: Duration string (e.g., "30m", "1h")
+	//
+	// The SessionReconciler copies this into Session.Spec.IdleTimeout when the
+	// session leaves it empty, so admins can enforce auto-hibernation policy at
+	// the template level. A value set on the session always wins.
+	//
+	// Example: "30m"
+	// Optional: Yes
+	// +optional
+	DefaultIdleTimeout string `json:"defaultIdleTimeout,omitempty"`
+
+	// DefaultMaxSessionDuration is the maximum lifetime applied to sessions that
+	// don't set one.
+	//
+	// Format: Duration string (e.g., "8h", "24h")
+	//
+	// Copied into Session.Spec.MaxSessionDuration when the session leaves it
+	// empty. A value set on the session always wins.
+	//
+	// Example: "8h"
+	// Optional: Yes
+	// +optional
+	DefaultMaxSessionDuration string `json:"defaultMaxSessionDuration,omitempty"`
+
+	// HomeVolumeAccessMode is the access mode used when the user's home PVC
+	// (home-{user}) is first created by a session of this template.
+	//
+	// Valid values:
+	//   - "ReadWriteMany": Default. Sessions can run on any node (needs NFS,
+	//     CephFS or similar)
+	//   - "ReadWriteOnce": For storage classes without RWX support. The volume
+	//     can only be mounted on one node, so the user's sessions must be
+	//     co-located (see SessionAffinity)
+	//
+	// The PVC is shared by all of a user's sessions and its access mode can't
+	// change after creation, so the first template to create it wins.
+	//
+	// Example: "ReadWriteOnce"
+	// Optional: Yes
+	// +optional
+	// +kubebuilder:validation:Enum=ReadWriteMany;ReadWriteOnce
+	HomeVolumeAccessMode corev1.PersistentVolumeAccessMode `json:"homeVolumeAccessMode,omitempty"`
+
+	// SessionAffinity controls whether a new session's pod is scheduled next
+	// to the same user's other running sessions.
+	//
+	// Valid values:
+	//   - "None": Default with ReadWriteMany home volumes. Schedule anywhere
+	//   - "Preferred": Co-locate with the user's sessions when possible
+	//   - "Required": Only schedule on a node already running one of the
+	//     user's sessions (any node if there are none)
+	//
+	// When HomeVolumeAccessMode is ReadWriteOnce and persistent home is
+	// enabled, the controller always uses "Required": a second session on
+	// another node could never mount the volume and would stay Pending.
+	//
+	// Example: "Preferred"
+	// Optional: Yes
+	// +optional
+	// +kubebuilder:validation:Enum=None;Preferred;Required
+	SessionAffinity string `json:"sessionAffinity,omitempty"`
+
+	// DeploymentStrategy is the update strategy of the session Deployment,
+	// used whenever a spec change replaces the session pod.
+	//
+	// Valid values:
+	//   - "Recreate": Stop the old pod before starting the new one. Default
+	//     when persistent home is enabled with a ReadWriteOnce home volume:
+	//     the old pod must release the volume before the new one can mount
+	//     it, otherwise the rollout can get stuck
+	//   - "RollingUpdate": Start the new pod first. Default otherwise
+	//
+	// Example: "Recreate"
+	// Optional: Yes
+	// +optional
+	// +kubebuilder:validation:Enum=Recreate;RollingUpdate
+	DeploymentStrategy appsv1.DeploymentStrategyType `json:"deploymentStrategy,omitempty"`
+
+	// ForwardablePorts lists the container ports users may tunnel to through
+	// the API's port-forward endpoint (/api/v1/sessions/{id}/forward/{port}).
+	//
+	// Use this for non-browser services inside the session such as SSH,
+	// language servers or debuggers. Ports not listed here are never
+	// forwarded, even for the session owner.
+	//
+	// Example: [22, 5432]
+	//
+	// Optional: Yes (default: no ports are forwardable)
+	// +optional
+	ForwardablePorts []int32 `json:"forwardablePorts,omitempty"`
+
+	// SupportedBackends lists the platforms this template can run on.
+	//
+	// Some templates only work on one backend (GPUs, privileged containers,
+	// multiple PVCs are Kubernetes-only). The API refuses to launch a
+	// template on a platform that isn't listed instead of dispatching it to
+	// a controller that would fail on create.
+	//
+	// Valid values: "kubernetes", "docker", "hyperv", "vcenter"
+	//
+	// Optional: Yes (default: all backends)
+	// +optional
+	SupportedBackends []string `json:"supportedBackends,omitempty"`
+
+	// WarmPoolSize is the number of ready, unassigned pods to keep for this
+	// template.
+	//
+	// Launching a session from a warm pod skips the image pull and container
+	// start: the session controller claims a ready pod, relabels it for the
+	// session and hands it to the user. The pool is refilled in the
+	// background. When the pool is empty, sessions are created normally.
+	//
+	// Warm pods run the template's defaults only. Sessions that need their
+	// own resources or a persistent home directory (volumes can't be added to
+	// a running pod) always get a freshly created pod.
+	//
+	// Example: 3
+	//
+	// Optional: Yes (default: 0, no warm pool)
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	WarmPoolSize int32 `json:"warmPoolSize,omitempty"`
+
+	// PrePull pins the template's image on every node.
+	//
+	// When true, the controller runs a DaemonSet that pulls BaseImage onto
+	// each node ahead of time so session pods find it in the local cache.
+	// Setting it back to false removes the DaemonSet. Coverage is reported in
+	// status.prePullReadyNodes / status.prePullDesiredNodes.
+	//
+	// The image must contain /bin/sh (the pull step runs "true" in it).
+	//
+	// Optional: Yes (default: false)
+	// +optional
+	PrePull bool `json:"prePull,omitempty"`
+
+	// MaxConcurrentLaunches limits how many sessions of this template may be
+	// starting (created but not yet ready) at the same time.
+	//
+	// Launches beyond the limit are queued with the session's LaunchQueued
+	// condition set, so releasing a new image to many users doesn't make
+	// every node pull it at once. Warm pool claims and templates whose image
+	// is pre-pulled on every node are not limited.
+	//
+	// Example: 10
+	//
+	// Optional: Yes (default: SESSION_MAX_CONCURRENT_LAUNCHES, unlimited if unset)
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxConcurrentLaunches int32 `json:"maxConcurrentLaunches,omitempty"`
+
+	// StartupTimeout is how long this template's sessions may take to become
+	// ready before they are marked Failed. Sessions can set their own.
+	//
+	// Raise it for templates with very large images or slow first starts.
+	//
+	// Example: "20m"
+	// Optional: Yes (default: SESSION_STARTUP_TIMEOUT)
+	// +optional
+	// +kubebuilder:validation:Pattern=`^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+	StartupTimeout string `json:"startupTimeout,omitempty"`
+
+	// ImagePullSecrets are Secrets used to pull BaseImage from a private
+	// registry, for session pods and the pre-pull DaemonSet alike.
+	//
+	// Example:
+	//   imagePullSecrets:
+	//     - name: registry-credentials
+	//
+	// Optional: Yes
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+
+	// NetworkEgress declares which outbound traffic sessions of this
+	// template need. Only enforced when the controller generates
+	// NetworkPolicies (SESSION_NETWORK_POLICIES=true).
+	//
+	// Valid values:
+	//   - "None": DNS only (default, most restrictive)
+	//   - "Internal": DNS and pods/services inside the cluster
+	//   - "Internet": DNS and public internet, but not private address
+	//     ranges, the cluster, or cloud metadata endpoints
+	//
+	// Inbound traffic is always limited to the ingress controller and the
+	// StreamSpace API proxy; sessions can never reach each other.
+	//
+	// Example: "Internet"
+	// Optional: Yes
+	// +optional
+	// +kubebuilder:validation:Enum=None;Internal;Internet
+	NetworkEgress string `json:"networkEgress,omitempty"`
+
+	// Args are passed to the container's entrypoint. They may reference
+	// template parameters with ${name}.
+	//
+	// Example:
+	//   args: ["--project", "${project}"]
+	//
+	// Optional: Yes
+	// +optional
+	Args []string `json:"args,omitempty"`
+
+	// Parameters declares the values a session may supply at launch. The
+	// controller substitutes ${name} in Env values and Args with the
+	// session's value, or the parameter's default when none was supplied.
+	//
+	// Example:
+	//   parameters:
+	//     - name: project
+	//       type: string
+	//       required: true
+	//     - name: debug
+	//       type: boolean
+	//       default: "false"
+	//
+	// Optional: Yes
+	// +optional
+	Parameters []TemplateParameter `json:"parameters,omitempty"`
+
+	// DefaultPriority is the priority class of sessions that don't request
+	// one (see SessionSpec.Priority). Batch-style templates can default to
+	// Low so interactive sessions win under contention.
+	//
+	// Example: "Low"
+	// Optional: Yes
+	// +optional
+	// +kubebuilder:validation:Enum=Low;Normal;High
+	DefaultPriority string `json:"defaultPriority,omitempty"`
+
+	// HibernationPolicy makes sessions of expensive templates (large GPU
+	// sessions) hibernate sooner when idle, overriding the controller-wide
+	// policy.
+	//
+	// Optional: Yes
+	// +optional
+	HibernationPolicy *HibernationPolicy `json:"hibernationPolicy,omitempty"`
+
+	// UserSessionLimit caps how many sessions of this template each user
+	// may run at once, e.g. one for a licensed application with per-seat
+	// limits. The API enforces it when sessions are created.
+	//
+	// Example (one session per user, further launches wait their turn):
+	//   userSessionLimit:
+	//     maxConcurrent: 1
+	//     whenFull: Queue
+	//
+	// Optional: Yes (default: no limit besides the user's quota)
+	// +optional
+	UserSessionLimit *UserSessionLimit `json:"userSessionLimit,omitempty"`
+
+	// SecurityContext adjusts the security context session pods run with.
+	//
+	// Hardened sessions (Hardened, or SESSION_SECURITY_DEFAULTS on the
+	// controller) run as a non-root user (1000), with all capabilities
+	// dropped, no privilege escalation and the RuntimeDefault seccomp
+	// profile. Fields set here override those defaults one by one.
+	//
+	// Example (hardened, read-only root filesystem, /tmp stays writable):
+	//   securityContext:
+	//     hardened: true
+	//     container:
+	//       readOnlyRootFilesystem: true
+	//
+	// Example (image that must start as root):
+	//   securityContext:
+	//     allowPrivileged: true
+	//     pod:
+	//       runAsNonRoot: false
+	//       runAsUser: 0
+	//
+	// Optional: Yes (hardened only with SESSION_SECURITY_DEFAULTS)
+	// +optional
+	SecurityContext *TemplateSecurityContext `json:"securityContext,omitempty"`
+
+	// CompanionResources are ConfigMaps and Secrets the application needs at
+	// runtime (default settings, a license file, ...). The controller creates
+	// them next to each session and cleans them up with it, so templates ship
+	// their dependencies instead of needing manual setup per app.
+	//
+	// Data values may reference template parameters and ${session}, ${user},
+	// ${template} and ${namespace}.
+	//
+	// Example:
+	//   companionResources:
+	//     - name: settings
+	//       kind: ConfigMap
+	//       mountPath: /config/.app
+	//       data:
+	//         settings.json: '{"owner": "${user}", "project": "${project}"}'
+	//
+	// Optional: Yes
+	// +optional
+	CompanionResources []CompanionResource `json:"companionResources,omitempty"`
+}
+
+// CompanionResource is a ConfigMap or Secret created for the sessions of a
+// template.
+//
+// Session-scoped resources are named {session}-{name} and owned by their
+// session. User-scoped resources are named {user}-{template}-{name}, shared
+// by the user's sessions of the template and owned by all of them, so they
+// are deleted with the user's last session. Their data can only reference
+// ${user}, ${template} and ${namespace}, which are the same for every
+// session sharing them.
+type CompanionResource struct {
+	// Name identifies the resource within the template.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:MaxLength=40
+	// +kubebuilder:validation:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Name string `json:"name"`
+
+	// Kind of the resource: "ConfigMap" or "Secret".
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Enum=ConfigMap;Secret
+	Kind string `json:"kind"`
+
+	// Scope is "Session" (default, one per session) or "User" (one per user
+	// and template).
+	// +optional
+	// +kubebuilder:validation:Enum=Session;User
+	Scope string `json:"scope,omitempty"`
+
+	// Data holds the resource's keys. Values are templated, see
+	// TemplateSpec.CompanionResources.
+	// +optional
+	Data map[string]string `json:"data,omitempty"`
+
+	// MountPath mounts the resource's keys as files in the session container.
+	// +optional
+	MountPath string `json:"mountPath,omitempty"`
+
+	// EnvFrom exposes the resource's keys as environment variables of the
+	// session container.
+	// +optional
+	EnvFrom bool `json:"envFrom,omitempty"`
+}
+
+// TemplateSecurityContext overrides the security context of session pods.
+//
+// Settings that weaken isolation - running as root, privileged mode,
+// privilege escalation, added capabilities or an unconfined seccomp
+// profile - are rejected unless AllowPrivileged is set, and the controller
+// can refuse such templates altogether.
+type TemplateSecurityContext struct {
+	// Pod overrides fields of the default pod security context.
+	// +optional
+	Pod *corev1.PodSecurityContext `json:"pod,omitempty"`
+
+	// Container overrides fields of the default container security context.
+	// +optional
+	Container *corev1.SecurityContext `json:"container,omitempty"`
+
+	// AllowPrivileged opts the template in to settings that weaken isolation.
+	// +optional
+	AllowPrivileged bool `json:"allowPrivileged,omitempty"`
+
+	// Hardened runs the template's sessions with the hardened defaults
+	// (non-root, all capabilities dropped, RuntimeDefault seccomp) even when
+	// the controller doesn't apply them to every template
+	// (SESSION_SECURITY_DEFAULTS). Set it once the image runs as non-root.
+	// +optional
+	Hardened bool `json:"hardened,omitempty"`
+
+	// Untrusted marks the template as running untrusted content (e.g. a
+	// browser for arbitrary sites). Its sessions get neither clipboard sync
+	// nor file transfer, whatever spec.vnc enables.
+	// +optional
+	Untrusted bool `json:"untrusted,omitempty"`
+}
+
+// HibernationPolicy scales a session's idle timeout with its hourly cost.
+//
+// A session costing more than ReferenceHourlyCost gets its idle timeout
+// shortened in proportion: twice the reference cost, half the timeout. The
+// timeout never drops below MinIdleTimeout, and cheaper sessions keep their
+// full timeout. The cost is estimated from the session's resources and the
+// COST_PRICE_* unit prices also used for cost reports.
+//
+// Example (sessions over $1/hour hibernate sooner, but not before 5 minutes):
+//
+//	hibernationPolicy:
+//	  referenceHourlyCost: "1.00"
+//	  minIdleTimeout: "5m"
+type HibernationPolicy struct {
+	// ReferenceHourlyCost is the hourly cost up to which the idle timeout
+	// applies unchanged. "0" disables cost scaling for the template.
+	// +kubebuilder:validation:Pattern=`^[0-9]+(\.[0-9]+)?$`
+	ReferenceHourlyCost string `json:"referenceHourlyCost"`
+
+	// MinIdleTimeout is the shortest idle timeout cost scaling can produce
+	// (default: the controller's HIBERNATION_MIN_IDLE_TIMEOUT, 5m).
+	// +optional
+	MinIdleTimeout string `json:"minIdleTimeout,omitempty"`
+}
+
+// UserSessionLimit caps the concurrent sessions of a template per user.
+//
+// Sessions count against the limit from launch until they terminate, fail
+// or are deleted; hibernated sessions still hold their slot.
+type UserSessionLimit struct {
+	// MaxConcurrent is how many sessions of the template a user may have.
+	// +kubebuilder:validation:Minimum=1
+	MaxConcurrent int32 `json:"maxConcurrent"`
+
+	// WhenFull decides what happens to a launch beyond MaxConcurrent:
+	// Reject (default) refuses it, Queue holds it and starts it once one of
+	// the user's sessions of the template ends.
+	// +optional
+	// +kubebuilder:validation:Enum=Reject;Queue
+	WhenFull string `json:"whenFull,omitempty"`
+}
+
+// TemplateParameter declares a value supplied when a session is launched.
+type TemplateParameter struct {
+	// Name is referenced as ${name} in Env values and Args.
+	// +kubebuilder:validation:Required
+	// +kubebuilder:validation:Pattern=`^[A-Za-z_][A-Za-z0-9_]*$`
+	Name string `json:"name"`
+
+	// Type of the value: "string" (default), "number" or "boolean".
+	// +optional
+	// +kubebuilder:validation:Enum=string;number;boolean
+	Type string `json:"type,omitempty"`
+
+	// Default is used when the session doesn't supply a value.
+	// +optional
+	Default string `json:"default,omitempty"`
+
+	// Required parameters must be supplied when the session is created.
+	// +optional
+	Required bool `json:"required,omitempty"`
+
+	// Description is shown to users at launch.
+	// +optional
+	Description string `json:"description,omitempty"`
 }
 
+// Session affinity modes for TemplateSpec.SessionAffinity.
+const (
+	SessionAffinityNone      = "None"
+	SessionAffinityPreferred = "Preferred"
+	SessionAffinityRequired  = "Required"
+)
+
+// Egress modes for TemplateSpec.NetworkEgress.
+const (
+	NetworkEgressNone     = "None"
+	NetworkEgressInternal = "Internal"
+	NetworkEgressInternet = "Internet"
+)
+
+// Companion resource kinds and scopes for CompanionResource.
+const (
+	CompanionKindConfigMap = "ConfigMap"
+	CompanionKindSecret    = "Secret"
+
+	CompanionScopeSession = "Session"
+	CompanionScopeUser    = "User"
+)
+
+// Parameter types for TemplateParameter.Type.
+const (
+	ParameterTypeString  = "string"
+	ParameterTypeNumber  = "number"
+	ParameterTypeBoolean = "boolean"
+)
+
 // VNCConfig defines generic VNC settings (VNC-agnostic, NOT Kasm-specific!).
 //
 // CRITICAL: StreamSpace is migrating to 100% open source VNC stack.
@@ -317,6 +811,64 @@ type VNCConfig struct {
 	// Default: false (rely on ingress TLS termination)
 	// +optional
 	Encryption bool `json:"encryption,omitempty"`
+
+	// Clipboard enables clipboard sync between the user's machine and the
+	// session. Untrusted templates never get it.
+	//
+	// Default: true
+	// +optional
+	Clipboard *bool `json:"clipboard,omitempty"`
+
+	// Audio enables audio streaming from the session.
+	//
+	// Default: true if Capabilities lists "Audio"
+	// +optional
+	Audio *bool `json:"audio,omitempty"`
+
+	// FileTransfer enables file upload and download. Untrusted templates
+	// never get it.
+	//
+	// Default: true
+	// +optional
+	FileTransfer *bool `json:"fileTransfer,omitempty"`
+
+	// MaxResolution caps the desktop resolution as WIDTHxHEIGHT (e.g.
+	// "1920x1080"). Clients scale larger displays down to it.
+	//
+	// Default: no cap
+	// +kubebuilder:validation:Pattern=`^[1-9][0-9]*x[1-9][0-9]*$`
+	// +optional
+	MaxResolution string `json:"maxResolution,omitempty"`
+}
+
+// WebAppConfig defines configuration for native web applications.
+type WebAppConfig struct {
+	// Enabled marks the template as a web application.
+	Enabled bool `json:"enabled"`
+
+	// Port is the HTTP port the application listens on.
+	// +optional
+	Port int `json:"port,omitempty"`
+
+	// Path is the application's entry path (e.g. "/login").
+	// +optional
+	Path string `json:"path,omitempty"`
+
+	// HealthCheck is the path used to check if the application is ready.
+	// +optional
+	HealthCheck string `json:"healthCheck,omitempty"`
+
+	// BasePath is the path prefix the application serves from.
+	//
+	// With path-based session routing (INGRESS_ROUTING_MODE=path) sessions
+	// are reached at {domain}/sessions/{name}/ and the ingress rewrites that
+	// prefix to BasePath, so an application that only works at the root of
+	// a host keeps working. The external prefix is passed to the container
+	// in STREAMSPACE_BASE_PATH for applications that build absolute URLs.
+	//
+	// Default: "/"
+	// +optional
+	BasePath string `json:"basePath,omitempty"`
 }
 
 // TemplateStatus defines the observed state of a Template.
@@ -362,6 +914,15 @@ type TemplateStatus struct {
 	// +optional
 	Message string `json:"message,omitempty"`
 
+	// LastValidatedAt is when the template was last fully validated,
+	// including its references (image, image pull secrets). Templates are
+	// revalidated periodically, so a template whose image disappeared from
+	// the registry turns invalid before a launch fails.
+	//
+	// Optional: Yes (computed by controller)
+	// +optional
+	LastValidatedAt *metav1.Time `json:"lastValidatedAt,omitempty"`
+
 	// Conditions represent detailed validation and operational status.
 	//
 	// Standard condition types:
@@ -379,6 +940,24 @@ type TemplateStatus struct {
 	// Optional: Yes (managed by controller)
 	// +optional
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// WarmPoolReady is the number of warm pods ready to be claimed.
+	//
+	// Optional: Yes (managed by controller)
+	// +optional
+	WarmPoolReady int32 `json:"warmPoolReady,omitempty"`
+
+	// PrePullReadyNodes is the number of nodes that have pulled the image.
+	//
+	// Optional: Yes (managed by controller)
+	// +optional
+	PrePullReadyNodes int32 `json:"prePullReadyNodes,omitempty"`
+
+	// PrePullDesiredNodes is the number of nodes the image is pulled onto.
+	//
+	// Optional: Yes (managed by controller)
+	// +optional
+	PrePullDesiredNodes int32 `json:"prePullDesiredNodes,omitempty"`
 }
 
 // Template is the Schema for the templates API.
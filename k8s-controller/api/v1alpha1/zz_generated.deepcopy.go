@@ -127,6 +127,91 @@ func (in *ApplicationInstallStatus) DeepCopy() *ApplicationInstallStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CompanionResource) DeepCopyInto(out *CompanionResource) {
+	*out = *in
+	if in.Data != nil {
+		in, out := &in.Data, &out.Data
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CompanionResource.
+func (in *CompanionResource) DeepCopy() *CompanionResource {
+	if in == nil {
+		return nil
+	}
+	out := new(CompanionResource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CrashLoopStatus) DeepCopyInto(out *CrashLoopStatus) {
+	*out = *in
+	in.WindowStart.DeepCopyInto(&out.WindowStart)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CrashLoopStatus.
+func (in *CrashLoopStatus) DeepCopy() *CrashLoopStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CrashLoopStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EvictionStatus) DeepCopyInto(out *EvictionStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EvictionStatus.
+func (in *EvictionStatus) DeepCopy() *EvictionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EvictionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HibernationPolicy) DeepCopyInto(out *HibernationPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HibernationPolicy.
+func (in *HibernationPolicy) DeepCopy() *HibernationPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(HibernationPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HibernationStatus) DeepCopyInto(out *HibernationStatus) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HibernationStatus.
+func (in *HibernationStatus) DeepCopy() *HibernationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(HibernationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *ResourceUsage) DeepCopyInto(out *ResourceUsage) {
 	*out = *in
@@ -205,6 +290,18 @@ func (in *SessionList) DeepCopyObject() runtime.Object {
 func (in *SessionSpec) DeepCopyInto(out *SessionSpec) {
 	*out = *in
 	in.Resources.DeepCopyInto(&out.Resources)
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SessionSpec.
@@ -224,6 +321,10 @@ func (in *SessionStatus) DeepCopyInto(out *SessionStatus) {
 		in, out := &in.LastActivity, &out.LastActivity
 		*out = (*in).DeepCopy()
 	}
+	if in.ReadyAt != nil {
+		in, out := &in.ReadyAt, &out.ReadyAt
+		*out = (*in).DeepCopy()
+	}
 	if in.ResourceUsage != nil {
 		in, out := &in.ResourceUsage, &out.ResourceUsage
 		*out = new(ResourceUsage)
@@ -236,6 +337,31 @@ func (in *SessionStatus) DeepCopyInto(out *SessionStatus) {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.CrashLoop != nil {
+		in, out := &in.CrashLoop, &out.CrashLoop
+		*out = new(CrashLoopStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Eviction != nil {
+		in, out := &in.Eviction, &out.Eviction
+		*out = new(EvictionStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.EffectiveResources != nil {
+		in, out := &in.EffectiveResources, &out.EffectiveResources
+		*out = new(corev1.ResourceRequirements)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Hibernation != nil {
+		in, out := &in.Hibernation, &out.Hibernation
+		*out = new(HibernationStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.StreamingFeatures != nil {
+		in, out := &in.StreamingFeatures, &out.StreamingFeatures
+		*out = new(StreamingFeatures)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SessionStatus.
@@ -248,13 +374,28 @@ func (in *SessionStatus) DeepCopy() *SessionStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *StreamingFeatures) DeepCopyInto(out *StreamingFeatures) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new StreamingFeatures.
+func (in *StreamingFeatures) DeepCopy() *StreamingFeatures {
+	if in == nil {
+		return nil
+	}
+	out := new(StreamingFeatures)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Template) DeepCopyInto(out *Template) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Template.
@@ -311,7 +452,12 @@ func (in *TemplateList) DeepCopyObject() runtime.Object {
 func (in *TemplateSpec) DeepCopyInto(out *TemplateSpec) {
 	*out = *in
 	in.DefaultResources.DeepCopyInto(&out.DefaultResources)
-	out.VNC = in.VNC
+	in.VNC.DeepCopyInto(&out.VNC)
+	if in.WebApp != nil {
+		in, out := &in.WebApp, &out.WebApp
+		*out = new(WebAppConfig)
+		**out = **in
+	}
 	if in.Env != nil {
 		in, out := &in.Env, &out.Env
 		*out = make([]corev1.EnvVar, len(*in))
@@ -324,6 +470,93 @@ func (in *TemplateSpec) DeepCopyInto(out *TemplateSpec) {
 		*out = make([]string, len(*in))
 		copy(*out, *in)
 	}
+	if in.ForwardablePorts != nil {
+		in, out := &in.ForwardablePorts, &out.ForwardablePorts
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	if in.SupportedBackends != nil {
+		in, out := &in.SupportedBackends, &out.SupportedBackends
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImagePullSecrets != nil {
+		in, out := &in.ImagePullSecrets, &out.ImagePullSecrets
+		*out = make([]corev1.LocalObjectReference, len(*in))
+		copy(*out, *in)
+	}
+	if in.Args != nil {
+		in, out := &in.Args, &out.Args
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Parameters != nil {
+		in, out := &in.Parameters, &out.Parameters
+		*out = make([]TemplateParameter, len(*in))
+		copy(*out, *in)
+	}
+	if in.HibernationPolicy != nil {
+		in, out := &in.HibernationPolicy, &out.HibernationPolicy
+		*out = new(HibernationPolicy)
+		**out = **in
+	}
+	if in.UserSessionLimit != nil {
+		in, out := &in.UserSessionLimit, &out.UserSessionLimit
+		*out = new(UserSessionLimit)
+		**out = **in
+	}
+	if in.SecurityContext != nil {
+		in, out := &in.SecurityContext, &out.SecurityContext
+		*out = new(TemplateSecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CompanionResources != nil {
+		in, out := &in.CompanionResources, &out.CompanionResources
+		*out = make([]CompanionResource, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateSecurityContext) DeepCopyInto(out *TemplateSecurityContext) {
+	*out = *in
+	if in.Pod != nil {
+		in, out := &in.Pod, &out.Pod
+		*out = new(corev1.PodSecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Container != nil {
+		in, out := &in.Container, &out.Container
+		*out = new(corev1.SecurityContext)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateSecurityContext.
+func (in *TemplateSecurityContext) DeepCopy() *TemplateSecurityContext {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateSecurityContext)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateParameter) DeepCopyInto(out *TemplateParameter) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateParameter.
+func (in *TemplateParameter) DeepCopy() *TemplateParameter {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateParameter)
+	in.DeepCopyInto(out)
+	return out
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateSpec.
@@ -339,6 +572,17 @@ func (in *TemplateSpec) DeepCopy() *TemplateSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *TemplateStatus) DeepCopyInto(out *TemplateStatus) {
 	*out = *in
+	if in.LastValidatedAt != nil {
+		in, out := &in.LastValidatedAt, &out.LastValidatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateStatus.
@@ -351,9 +595,39 @@ func (in *TemplateStatus) DeepCopy() *TemplateStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *UserSessionLimit) DeepCopyInto(out *UserSessionLimit) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new UserSessionLimit.
+func (in *UserSessionLimit) DeepCopy() *UserSessionLimit {
+	if in == nil {
+		return nil
+	}
+	out := new(UserSessionLimit)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *VNCConfig) DeepCopyInto(out *VNCConfig) {
 	*out = *in
+	if in.Clipboard != nil {
+		in, out := &in.Clipboard, &out.Clipboard
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Audio != nil {
+		in, out := &in.Audio, &out.Audio
+		*out = new(bool)
+		**out = **in
+	}
+	if in.FileTransfer != nil {
+		in, out := &in.FileTransfer, &out.FileTransfer
+		*out = new(bool)
+		**out = **in
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VNCConfig.
@@ -365,3 +639,18 @@ func (in *VNCConfig) DeepCopy() *VNCConfig {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *WebAppConfig) DeepCopyInto(out *WebAppConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new WebAppConfig.
+func (in *WebAppConfig) DeepCopy() *WebAppConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(WebAppConfig)
+	in.DeepCopyInto(out)
+	return out
+}
@@ -102,6 +102,7 @@ func main() {
 	var natsPassword string
 	var namespace string
 	var controllerID string
+	var eventResultTTL time.Duration
 
 	// Parse command-line flags
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
@@ -114,6 +115,8 @@ func main() {
 	flag.StringVar(&natsPassword, "nats-password", getEnv("NATS_PASSWORD", ""), "NATS password")
 	flag.StringVar(&namespace, "namespace", getEnv("NAMESPACE", "streamspace"), "Kubernetes namespace")
 	flag.StringVar(&controllerID, "controller-id", getEnv("CONTROLLER_ID", "streamspace-kubernetes-controller-1"), "Unique controller ID")
+	flag.DurationVar(&eventResultTTL, "event-result-ttl", getEnvDuration("EVENT_RESULT_TTL", 15*time.Minute),
+		"How long completed NATS event results are kept to answer redelivered events without re-executing them")
 
 	// Setup logging options (can be configured via flags like --zap-log-level=debug)
 	opts := zap.Options{
@@ -176,6 +179,8 @@ func main() {
 		Scheme:       mgr.GetScheme(),
 		NATSConn:     sessionNATSConn,
 		ControllerID: controllerID,
+		Recorder:     mgr.GetEventRecorderFor("session-controller"),
+		APIReader:    mgr.GetAPIReader(),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Session")
 		os.Exit(1)
@@ -184,13 +189,44 @@ func main() {
 	// Register TemplateReconciler
 	// Validates and manages Template resources:
 	//   - Ensures template specifications are valid
-	//   - Tracks template usage and popularity
-	//   - Handles template versioning
+	//   - Periodically revalidates the image and image pull secrets
+	//   - Reports templates turning invalid to the API (shares the
+	//     session reconciler's NATS connection)
+	var imageChecker controllers.ImageChecker
+	if controllers.TemplateImageCheckEnabled() {
+		imageChecker = controllers.NewRegistryImageChecker()
+	}
 	if err = (&controllers.TemplateReconciler{
+		Client:             mgr.GetClient(),
+		Scheme:             mgr.GetScheme(),
+		ImageChecker:       imageChecker,
+		NATSConn:           sessionNATSConn,
+		Recorder:           mgr.GetEventRecorderFor("template-controller"),
+		RevalidateInterval: controllers.TemplateRevalidationIntervalFromEnv(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "Template")
+		os.Exit(1)
+	}
+
+	// Register WarmPoolReconciler
+	// Keeps ready pods for templates with spec.warmPoolSize so launches
+	// can claim a running pod instead of starting one from scratch
+	if err = (&controllers.WarmPoolReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 	}).SetupWithManager(mgr); err != nil {
-		setupLog.Error(err, "unable to create controller", "controller", "Template")
+		setupLog.Error(err, "unable to create controller", "controller", "WarmPool")
+		os.Exit(1)
+	}
+
+	// Register PrePullReconciler
+	// Runs a DaemonSet per template with spec.prePull so the template
+	// image is cached on every node before sessions need it
+	if err = (&controllers.PrePullReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PrePull")
 		os.Exit(1)
 	}
 
@@ -201,8 +237,9 @@ func main() {
 	//   - Wakes sessions on user activity
 	//   - Updates Session status and metrics
 	if err = (&controllers.HibernationReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("hibernation-controller"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Hibernation")
 		os.Exit(1)
@@ -271,9 +308,10 @@ func main() {
 	// Initialize NATS event subscriber for platform-agnostic event handling
 	setupLog.Info("initializing NATS event subscriber", "url", natsURL)
 	subscriber, err := events.NewSubscriber(events.Config{
-		URL:      natsURL,
-		User:     natsUser,
-		Password: natsPassword,
+		URL:            natsURL,
+		User:           natsUser,
+		Password:       natsPassword,
+		ResultCacheTTL: eventResultTTL,
 	}, mgr.GetClient(), namespace, controllerID)
 
 	if err != nil {
@@ -309,3 +347,13 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvDuration gets a duration environment variable with a default fallback
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,176 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+// defaultCapacityRefresh is how long node capacity is cached.
+const defaultCapacityRefresh = time.Minute
+
+// Pre-admission capacity check.
+//
+// A session asking for more than any single node can allocate (say 256Gi on
+// a cluster whose biggest node has 128Gi) would otherwise leave its pod
+// Pending forever with a FailedScheduling event nobody reads. Before
+// creating the Deployment, the requested resources are compared with each
+// schedulable node's allocatable resources; if none can hold the whole
+// request the session fails with "no node can satisfy this request".
+//
+// This only rules out requests that can never fit. Whether a node has room
+// right now is still up to the scheduler.
+//
+// Environment:
+//   - SESSION_CAPACITY_CHECK: set to "false" to disable the check, e.g. when
+//     a cluster autoscaler can add larger nodes on demand (default true)
+//   - SESSION_CAPACITY_REFRESH: how long node capacity is cached (default 1m)
+
+// capacityCheckEnabled reports whether SESSION_CAPACITY_CHECK allows the check.
+func capacityCheckEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("SESSION_CAPACITY_CHECK"))
+	return err != nil || enabled
+}
+
+// capacityRefreshInterval reads SESSION_CAPACITY_REFRESH.
+func capacityRefreshInterval() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("SESSION_CAPACITY_REFRESH")); err == nil && d > 0 {
+		return d
+	}
+	return defaultCapacityRefresh
+}
+
+// nodeCapacityCache holds the allocatable resources of schedulable nodes.
+// The zero value is ready to use.
+type nodeCapacityCache struct {
+	mu      sync.Mutex
+	nodes   []corev1.ResourceList
+	fetched time.Time
+}
+
+// allocatable returns the cached node capacity, listing nodes again once it
+// is older than maxAge.
+func (c *nodeCapacityCache) allocatable(ctx context.Context, r *SessionReconciler, maxAge time.Duration) ([]corev1.ResourceList, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.fetched.IsZero() && time.Since(c.fetched) < maxAge {
+		return c.nodes, nil
+	}
+
+	var nodes corev1.NodeList
+	if err := r.List(ctx, &nodes); err != nil {
+		return nil, err
+	}
+	c.nodes = c.nodes[:0]
+	for _, node := range nodes.Items {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		c.nodes = append(c.nodes, node.Status.Allocatable)
+	}
+	c.fetched = time.Now()
+	return c.nodes, nil
+}
+
+// checkCapacity returns an error if no schedulable node can hold the
+// session's resource requests. Failing to list nodes doesn't block the
+// launch; the scheduler remains the final word.
+func (r *SessionReconciler) checkCapacity(ctx context.Context, session *streamv1alpha1.Session, template *streamv1alpha1.Template) error {
+	if !capacityCheckEnabled() {
+		return nil
+	}
+
+	nodes, err := r.capacity.allocatable(ctx, r, capacityRefreshInterval())
+	if err != nil {
+		return nil
+	}
+	return fitsAnyNode(effectiveRequests(sessionResources(session, template)), nodes)
+}
+
+// sessionResources returns the resources the session container runs with:
+// the session's own, or the template defaults, with requests lowered for
+// overcommitted categories (see createDeployment).
+func sessionResources(session *streamv1alpha1.Session, template *streamv1alpha1.Template) corev1.ResourceRequirements {
+	resources := template.Spec.DefaultResources
+	if len(session.Spec.Resources.Requests) > 0 || len(session.Spec.Resources.Limits) > 0 {
+		resources = session.Spec.Resources
+	}
+	return overcommitPolicyFromEnv().apply(template, resources)
+}
+
+// effectiveRequests returns what the scheduler will reserve: requests, with
+// limits standing in for requests that aren't set (as the API server
+// defaults them).
+func effectiveRequests(resources corev1.ResourceRequirements) corev1.ResourceList {
+	requests := corev1.ResourceList{}
+	for name, quantity := range resources.Limits {
+		requests[name] = quantity
+	}
+	for name, quantity := range resources.Requests {
+		requests[name] = quantity
+	}
+	for name, quantity := range requests {
+		if quantity.Sign() <= 0 {
+			delete(requests, name)
+		}
+	}
+	return requests
+}
+
+// fitsAnyNode returns nil if a single node can allocate all of requests.
+// With no nodes known there is nothing to compare against, so it passes.
+func fitsAnyNode(requests corev1.ResourceList, nodes []corev1.ResourceList) error {
+	if len(requests) == 0 || len(nodes) == 0 {
+		return nil
+	}
+
+	largest := corev1.ResourceList{}
+	for _, allocatable := range nodes {
+		fits := true
+		for name, want := range requests {
+			have, ok := allocatable[name]
+			if ok && have.Cmp(largest[name]) > 0 {
+				largest[name] = have
+			}
+			if !ok || have.Cmp(want) < 0 {
+				fits = false
+			}
+		}
+		if fits {
+			return nil
+		}
+	}
+
+	names := make([]string, 0, len(requests))
+	for name := range requests {
+		names = append(names, string(name))
+	}
+	sort.Strings(names)
+
+	var exceeded []string
+	for _, name := range names {
+		want := requests[corev1.ResourceName(name)]
+		have, ok := largest[corev1.ResourceName(name)]
+		switch {
+		case !ok:
+			exceeded = append(exceeded, fmt.Sprintf("%s %s (no node has any)", name, want.String()))
+		case have.Cmp(want) < 0:
+			exceeded = append(exceeded, fmt.Sprintf("%s %s (largest node has %s)", name, want.String(), have.String()))
+		}
+	}
+	if len(exceeded) == 0 {
+		// Every resource fits somewhere, just not all on the same node
+		return fmt.Errorf("no node can satisfy this request: no single node has all of %s", strings.Join(names, ", "))
+	}
+	return fmt.Errorf("no node can satisfy this request: %s", strings.Join(exceeded, ", "))
+}
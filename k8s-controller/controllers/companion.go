@@ -0,0 +1,233 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+// userScopedCompanionRefs are the only references a user-scoped companion
+// resource may use: everything else differs between the sessions sharing it.
+var userScopedCompanionRefs = map[string]bool{"user": true, "template": true, "namespace": true}
+
+// validateCompanionResources checks a template's companion resources.
+func validateCompanionResources(resources []streamv1alpha1.CompanionResource) error {
+	seen := make(map[string]bool, len(resources))
+	for _, res := range resources {
+		if errs := validation.IsDNS1123Label(res.Name); len(errs) > 0 || len(res.Name) > 40 {
+			return fmt.Errorf("companion resource name %q must be a lowercase DNS label of at most 40 characters", res.Name)
+		}
+		if seen[res.Name] {
+			return fmt.Errorf("companion resource %q is declared more than once", res.Name)
+		}
+		seen[res.Name] = true
+
+		switch res.Kind {
+		case streamv1alpha1.CompanionKindConfigMap, streamv1alpha1.CompanionKindSecret:
+		default:
+			return fmt.Errorf("companion resource %q has unknown kind %q (use ConfigMap or Secret)", res.Name, res.Kind)
+		}
+		switch res.Scope {
+		case "", streamv1alpha1.CompanionScopeSession, streamv1alpha1.CompanionScopeUser:
+		default:
+			return fmt.Errorf("companion resource %q has unknown scope %q (use Session or User)", res.Name, res.Scope)
+		}
+		if res.MountPath != "" && !path.IsAbs(res.MountPath) {
+			return fmt.Errorf("companion resource %q mountPath must be absolute, got %q", res.Name, res.MountPath)
+		}
+
+		for key, value := range res.Data {
+			if errs := validation.IsConfigMapKey(key); len(errs) > 0 {
+				return fmt.Errorf("companion resource %q has invalid key %q: %s", res.Name, key, errs[0])
+			}
+			if res.Scope != streamv1alpha1.CompanionScopeUser {
+				continue
+			}
+			for _, ref := range parameterRef.FindAllStringSubmatch(value, -1) {
+				if !userScopedCompanionRefs[ref[1]] {
+					return fmt.Errorf("user-scoped companion resource %q can't reference ${%s}: it is shared by the user's sessions", res.Name, ref[1])
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// companionResourceName returns the name of a companion resource's object
+// for a session.
+func companionResourceName(session *streamv1alpha1.Session, res streamv1alpha1.CompanionResource) string {
+	if res.Scope == streamv1alpha1.CompanionScopeUser {
+		return fmt.Sprintf("%s-%s-%s", session.Spec.User, session.Spec.Template, res.Name)
+	}
+	return fmt.Sprintf("%s-%s", session.Name, res.Name)
+}
+
+// companionValues returns the values substituted into a companion
+// resource's data. The built-in names win over parameters of the same name.
+func companionValues(session *streamv1alpha1.Session, template *streamv1alpha1.Template, res streamv1alpha1.CompanionResource) map[string]string {
+	values := map[string]string{
+		"user":      session.Spec.User,
+		"template":  template.Name,
+		"namespace": session.Namespace,
+	}
+	if res.Scope == streamv1alpha1.CompanionScopeUser {
+		return values
+	}
+
+	// Already validated by Reconcile, so an error can't occur here
+	params, _ := resolveParameters(template, session)
+	for name, value := range params {
+		if _, builtin := values[name]; !builtin {
+			values[name] = value
+		}
+	}
+	values["session"] = session.Name
+	return values
+}
+
+// companionObject builds the ConfigMap or Secret of a companion resource.
+//
+// Session-scoped objects are controlled by the session. User-scoped ones
+// get a plain owner reference from every session using them, so garbage
+// collection removes them with the last one.
+func companionObject(session *streamv1alpha1.Session, template *streamv1alpha1.Template, res streamv1alpha1.CompanionResource) client.Object {
+	meta := metav1.ObjectMeta{
+		Name:      companionResourceName(session, res),
+		Namespace: session.Namespace,
+		Labels: map[string]string{
+			"app":       "streamspace-companion",
+			"user":      session.Spec.User,
+			"template":  session.Spec.Template,
+			"companion": res.Name,
+		},
+	}
+	owner := *metav1.NewControllerRef(session, streamv1alpha1.GroupVersion.WithKind("Session"))
+	if res.Scope == streamv1alpha1.CompanionScopeUser {
+		owner.Controller = nil
+		owner.BlockOwnerDeletion = nil
+	} else {
+		meta.Labels["session"] = session.Name
+	}
+	meta.OwnerReferences = []metav1.OwnerReference{owner}
+
+	values := companionValues(session, template, res)
+	if res.Kind == streamv1alpha1.CompanionKindSecret {
+		data := make(map[string][]byte, len(res.Data))
+		for key, value := range res.Data {
+			data[key] = []byte(substituteParameters(value, values))
+		}
+		return &corev1.Secret{ObjectMeta: meta, Type: corev1.SecretTypeOpaque, Data: data}
+	}
+
+	data := make(map[string]string, len(res.Data))
+	for key, value := range res.Data {
+		data[key] = substituteParameters(value, values)
+	}
+	return &corev1.ConfigMap{ObjectMeta: meta, Data: data}
+}
+
+// ensureCompanionResources creates or updates a session's companion
+// resources. It runs before the session pod is created, which mounts them.
+func (r *SessionReconciler) ensureCompanionResources(ctx context.Context, session *streamv1alpha1.Session, template *streamv1alpha1.Template) error {
+	log := log.FromContext(ctx)
+
+	for _, res := range template.Spec.CompanionResources {
+		desired := companionObject(session, template, res)
+
+		var existing client.Object = &corev1.ConfigMap{}
+		if res.Kind == streamv1alpha1.CompanionKindSecret {
+			existing = &corev1.Secret{}
+		}
+		err := r.Get(ctx, types.NamespacedName{Name: desired.GetName(), Namespace: desired.GetNamespace()}, existing)
+		if errors.IsNotFound(err) {
+			if err := r.Create(ctx, desired); err != nil {
+				return fmt.Errorf("failed to create %s %s: %w", res.Kind, desired.GetName(), err)
+			}
+			log.Info("Created companion resource", "kind", res.Kind, "name", desired.GetName())
+			continue
+		} else if err != nil {
+			return err
+		}
+
+		if syncCompanionObject(existing, desired, session) {
+			if err := r.Update(ctx, existing); err != nil {
+				return fmt.Errorf("failed to update %s %s: %w", res.Kind, existing.GetName(), err)
+			}
+			log.Info("Updated companion resource", "kind", res.Kind, "name", existing.GetName())
+		}
+	}
+	return nil
+}
+
+// syncCompanionObject brings an existing companion object's data in line
+// with the template and adds the session to its owners. It reports whether
+// existing changed.
+func syncCompanionObject(existing, desired client.Object, session *streamv1alpha1.Session) bool {
+	changed := false
+	switch live := existing.(type) {
+	case *corev1.ConfigMap:
+		if want := desired.(*corev1.ConfigMap).Data; !equality.Semantic.DeepEqual(live.Data, want) {
+			live.Data = want
+			changed = true
+		}
+	case *corev1.Secret:
+		if want := desired.(*corev1.Secret).Data; !equality.Semantic.DeepEqual(live.Data, want) {
+			live.Data = want
+			changed = true
+		}
+	}
+
+	for _, ref := range existing.GetOwnerReferences() {
+		if ref.UID == session.UID {
+			return changed
+		}
+	}
+	existing.SetOwnerReferences(append(existing.GetOwnerReferences(), desired.GetOwnerReferences()...))
+	return true
+}
+
+// applyCompanionResources mounts a template's companion resources into the
+// session container or exposes them as environment variables.
+func applyCompanionResources(session *streamv1alpha1.Session, template *streamv1alpha1.Template, podSpec *corev1.PodSpec, container *corev1.Container) {
+	for _, res := range template.Spec.CompanionResources {
+		name := companionResourceName(session, res)
+		secret := res.Kind == streamv1alpha1.CompanionKindSecret
+
+		if res.MountPath != "" {
+			volume := corev1.Volume{Name: "companion-" + res.Name}
+			if secret {
+				volume.Secret = &corev1.SecretVolumeSource{SecretName: name}
+			} else {
+				volume.ConfigMap = &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: name},
+				}
+			}
+			podSpec.Volumes = append(podSpec.Volumes, volume)
+			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+				Name:      volume.Name,
+				MountPath: res.MountPath,
+				ReadOnly:  true,
+			})
+		}
+
+		if res.EnvFrom {
+			ref := corev1.LocalObjectReference{Name: name}
+			if secret {
+				container.EnvFrom = append(container.EnvFrom, corev1.EnvFromSource{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: ref}})
+			} else {
+				container.EnvFrom = append(container.EnvFrom, corev1.EnvFromSource{ConfigMapRef: &corev1.ConfigMapEnvSource{LocalObjectReference: ref}})
+			}
+		}
+	}
+}
@@ -0,0 +1,245 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+	"github.com/streamspace/streamspace/pkg/metrics"
+)
+
+// Crash loop detection defaults.
+const (
+	defaultCrashLoopRestarts = 3
+	defaultCrashLoopWindow   = 10 * time.Minute
+
+	// crashLoopRecheckInterval is how often a crash-looping session is
+	// re-examined for recovery
+	crashLoopRecheckInterval = 30 * time.Second
+)
+
+// crashLoopConfig controls crash loop detection.
+//
+// Environment:
+//   - SESSION_CRASHLOOP_RESTARTS: restarts within the window that mark a
+//     session as CrashLooping (default 3)
+//   - SESSION_CRASHLOOP_WINDOW: detection window (default 10m)
+//   - SESSION_CRASHLOOP_HIBERNATE_AFTER: restarts within the window after
+//     which the session is hibernated to stop the thrash (default 0, never)
+type crashLoopConfig struct {
+	Restarts       int32
+	Window         time.Duration
+	HibernateAfter int32
+}
+
+// crashLoopConfigFromEnv reads crash loop detection settings.
+func crashLoopConfigFromEnv() crashLoopConfig {
+	cfg := crashLoopConfig{
+		Restarts: defaultCrashLoopRestarts,
+		Window:   defaultCrashLoopWindow,
+	}
+	if v, err := strconv.Atoi(os.Getenv("SESSION_CRASHLOOP_RESTARTS")); err == nil && v > 0 {
+		cfg.Restarts = int32(v)
+	}
+	if d, err := time.ParseDuration(os.Getenv("SESSION_CRASHLOOP_WINDOW")); err == nil && d > 0 {
+		cfg.Window = d
+	}
+	if v, err := strconv.Atoi(os.Getenv("SESSION_CRASHLOOP_HIBERNATE_AFTER")); err == nil && v > 0 {
+		cfg.HibernateAfter = int32(v)
+	}
+	return cfg
+}
+
+// sessionPod returns the newest live pod running a session, if any.
+//
+// Both Deployment pods and claimed warm pods carry the session label.
+func (r *SessionReconciler) sessionPod(ctx context.Context, session *streamv1alpha1.Session) (*corev1.Pod, error) {
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, client.InNamespace(session.Namespace), client.MatchingLabels{
+		"app":     "streamspace-session",
+		"session": session.Name,
+	}); err != nil {
+		return nil, err
+	}
+
+	var newest *corev1.Pod
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if newest == nil || pod.CreationTimestamp.After(newest.CreationTimestamp.Time) {
+			newest = pod
+		}
+	}
+	return newest, nil
+}
+
+// sessionContainerStatus returns the status of the session container.
+func sessionContainerStatus(pod *corev1.Pod) *corev1.ContainerStatus {
+	for i := range pod.Status.ContainerStatuses {
+		if pod.Status.ContainerStatuses[i].Name == "session" {
+			return &pod.Status.ContainerStatuses[i]
+		}
+	}
+	if len(pod.Status.ContainerStatuses) > 0 {
+		return &pod.Status.ContainerStatuses[0]
+	}
+	return nil
+}
+
+// updateCrashLoopStatus records the session container's restarts in
+// session.Status.CrashLoop and reports whether it is crash looping.
+//
+// A session is crash looping when its container isn't ready and restarted
+// at least cfg.Restarts times in the current window. The window only rolls
+// over while the session isn't crash looping, so a looping session stays
+// CrashLooping until its container becomes ready again.
+//
+// Returns the number of restarts in the current window.
+func updateCrashLoopStatus(session *streamv1alpha1.Session, pod *corev1.Pod, cfg crashLoopConfig, now time.Time) (int32, bool) {
+	cs := sessionContainerStatus(pod)
+	if cs == nil {
+		return 0, false
+	}
+
+	status := session.Status.CrashLoop
+	if status == nil || cs.RestartCount < status.RestartCount {
+		// First observation, or the pod was replaced and its count reset
+		status = &streamv1alpha1.CrashLoopStatus{WindowStart: metav1.NewTime(now)}
+	}
+	status.RestartCount = cs.RestartCount
+
+	if last := cs.LastTerminationState.Terminated; last != nil {
+		status.LastTerminationReason = last.Reason
+		status.LastTerminationMessage = last.Message
+		status.LastExitCode = last.ExitCode
+	}
+
+	windowRestarts := status.RestartCount - status.WindowStartRestartCount
+	looping := !cs.Ready && windowRestarts >= cfg.Restarts
+
+	if !looping && now.Sub(status.WindowStart.Time) > cfg.Window {
+		status.WindowStart = metav1.NewTime(now)
+		status.WindowStartRestartCount = status.RestartCount
+		windowRestarts = 0
+	}
+
+	session.Status.CrashLoop = status
+	return windowRestarts, looping
+}
+
+// crashLoopMessage describes a crash loop for users and operators.
+func crashLoopMessage(status *streamv1alpha1.CrashLoopStatus, restarts int32, window time.Duration) string {
+	msg := fmt.Sprintf("Session container restarted %d times in %s", restarts, window)
+	if status.LastTerminationReason != "" {
+		msg += fmt.Sprintf(": %s (exit code %d)", status.LastTerminationReason, status.LastExitCode)
+	}
+	if status.LastTerminationMessage != "" {
+		msg += ": " + status.LastTerminationMessage
+	}
+	return msg
+}
+
+// detectCrashLoop checks a running session for a crash-looping container.
+//
+// If the container is crash looping the session phase is set to
+// CrashLooping, the API is notified and, if SESSION_CRASHLOOP_HIBERNATE_AFTER
+// is reached, the session is hibernated. handled is true in that case and
+// the caller should return result.
+//
+// Otherwise the restart bookkeeping is left on session.Status for the
+// caller's status update.
+func (r *SessionReconciler) detectCrashLoop(ctx context.Context, session *streamv1alpha1.Session) (handled bool, result ctrl.Result, err error) {
+	log := log.FromContext(ctx)
+	cfg := crashLoopConfigFromEnv()
+
+	pod, err := r.sessionPod(ctx, session)
+	if err != nil || pod == nil {
+		return false, ctrl.Result{}, err
+	}
+
+	var prevRestarts int32
+	if session.Status.CrashLoop != nil {
+		prevRestarts = session.Status.CrashLoop.RestartCount
+	}
+
+	restarts, looping := updateCrashLoopStatus(session, pod, cfg, time.Now())
+	if !looping {
+		if session.Status.Phase == "CrashLooping" {
+			log.Info("Session container recovered from crash loop", "session", session.Name)
+			meta.SetStatusCondition(&session.Status.Conditions, metav1.Condition{
+				Type:               readyCondition,
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: session.Generation,
+				Reason:             "ContainerRecovered",
+				Message:            "Session container is running again",
+			})
+		}
+		return false, ctrl.Result{}, nil
+	}
+
+	message := crashLoopMessage(session.Status.CrashLoop, restarts, cfg.Window)
+	wasLooping := session.Status.Phase == "CrashLooping"
+
+	session.Status.Phase = "CrashLooping"
+	session.Status.PodName = pod.Name
+	r.setCondition(ctx, session, readyCondition, metav1.ConditionFalse, "CrashLoopBackOff", message)
+
+	// Publish on the transition and whenever the restart count moves, so the
+	// API (and the user) see the latest termination reason
+	if !wasLooping || session.Status.CrashLoop.RestartCount != prevRestarts {
+		r.publishSessionStatus(session.Name, "crashlooping", "CrashLooping", session.Status.URL, pod.Name, message)
+	}
+	if !wasLooping {
+		log.Info("Session container is crash looping", "session", session.Name, "pod", pod.Name, "restarts", restarts)
+		metrics.RecordSessionState("crashlooping", session.Namespace, 1)
+	}
+
+	if cfg.HibernateAfter > 0 && restarts >= cfg.HibernateAfter {
+		err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+			fresh := &streamv1alpha1.Session{}
+			if err := r.Get(ctx, types.NamespacedName{Name: session.Name, Namespace: session.Namespace}, fresh); err != nil {
+				return err
+			}
+			fresh.Spec.State = "hibernated"
+			setStateActor(fresh, "controller:"+r.ControllerID)
+			return r.Update(ctx, fresh)
+		})
+		if err != nil {
+			log.Error(err, "Failed to hibernate crash-looping session")
+			return true, ctrl.Result{}, err
+		}
+		metrics.RecordHibernation(session.Namespace, "crashloop")
+		log.Info("Hibernated crash-looping session", "session", session.Name, "restarts", restarts)
+		// The state change triggers handleHibernated
+		return true, ctrl.Result{}, nil
+	}
+
+	return true, ctrl.Result{RequeueAfter: crashLoopRecheckInterval}, nil
+}
+
+// sessionForPod maps a session pod to its Session so container restarts
+// trigger a reconcile.
+func sessionForPod(_ context.Context, obj client.Object) []reconcile.Request {
+	name := obj.GetLabels()["session"]
+	if name == "" {
+		return nil
+	}
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Name: name, Namespace: obj.GetNamespace()}},
+	}
+}
@@ -0,0 +1,186 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+const (
+	// appliedSpecAnnotation records the session container spec the
+	// controller applied to a Deployment
+	appliedSpecAnnotation = "stream.space/applied-spec"
+
+	// driftedCondition is True while a session's Deployment differs from
+	// what the controller applied and the drift policy is "report"
+	driftedCondition = "Drifted"
+)
+
+// Drift policies (SESSION_DRIFT_POLICY).
+const (
+	driftPolicyCorrect = "correct"
+	driftPolicyReport  = "report"
+)
+
+// driftPolicyFromEnv returns how out-of-band Deployment edits are handled:
+// "correct" (default) restores what the controller applied, "report" leaves
+// them and sets the Drifted condition.
+func driftPolicyFromEnv() string {
+	if strings.EqualFold(os.Getenv("SESSION_DRIFT_POLICY"), driftPolicyReport) {
+		return driftPolicyReport
+	}
+	return driftPolicyCorrect
+}
+
+// appliedSpec is the part of a session Deployment checked for drift.
+//
+// It is recorded from the Deployment as first seen running rather than
+// recomputed from the template, so neither a template update (which existing
+// sessions deliberately don't pick up) nor API server defaulting is mistaken
+// for drift.
+type appliedSpec struct {
+	Image     string                      `json:"image"`
+	Args      []string                    `json:"args,omitempty"`
+	Env       []corev1.EnvVar             `json:"env,omitempty"`
+	Resources corev1.ResourceRequirements `json:"resources,omitempty"`
+}
+
+// sessionContainerSpec returns the drift-checked fields of a Deployment's
+// session container.
+func sessionContainerSpec(deployment *appsv1.Deployment) appliedSpec {
+	containers := deployment.Spec.Template.Spec.Containers
+	if len(containers) == 0 {
+		return appliedSpec{}
+	}
+	c := containers[0]
+	return appliedSpec{Image: c.Image, Args: c.Args, Env: c.Env, Resources: c.Resources}
+}
+
+// recordAppliedSpec stores the Deployment's current container spec as the
+// one the controller applied.
+func recordAppliedSpec(deployment *appsv1.Deployment) {
+	data, err := json.Marshal(sessionContainerSpec(deployment))
+	if err != nil {
+		return
+	}
+	if deployment.Annotations == nil {
+		deployment.Annotations = map[string]string{}
+	}
+	deployment.Annotations[appliedSpecAnnotation] = string(data)
+}
+
+// applySessionResources copies the Session's resource override into a
+// hibernated Deployment before it wakes, so resources changed while the
+// session was running (e.g. an applied right-sizing recommendation) take
+// effect on the next start. It reports whether the Deployment changed.
+//
+// Running pods are not resized in place; the new spec is recorded as the
+// drift baseline so it isn't reverted.
+func applySessionResources(session *streamv1alpha1.Session, template *streamv1alpha1.Template, deployment *appsv1.Deployment) bool {
+	if len(session.Spec.Resources.Requests) == 0 && len(session.Spec.Resources.Limits) == 0 {
+		return false
+	}
+	desired := overcommitPolicyFromEnv().apply(template, session.Spec.Resources)
+	containers := deployment.Spec.Template.Spec.Containers
+	if len(containers) == 0 || equality.Semantic.DeepEqual(containers[0].Resources, desired) {
+		return false
+	}
+	containers[0].Resources = *desired.DeepCopy()
+	recordAppliedSpec(deployment)
+	return true
+}
+
+// driftedFields lists what differs between a live Deployment and the spec
+// the controller applied. Running sessions always have one replica.
+func driftedFields(deployment *appsv1.Deployment, applied appliedSpec) []string {
+	var fields []string
+	if deployment.Spec.Replicas != nil && *deployment.Spec.Replicas != 1 {
+		fields = append(fields, "replicas")
+	}
+	live := sessionContainerSpec(deployment)
+	if live.Image != applied.Image {
+		fields = append(fields, "image")
+	}
+	if !equality.Semantic.DeepEqual(live.Args, applied.Args) {
+		fields = append(fields, "args")
+	}
+	if !equality.Semantic.DeepEqual(live.Env, applied.Env) {
+		fields = append(fields, "env")
+	}
+	if !equality.Semantic.DeepEqual(live.Resources, applied.Resources) {
+		fields = append(fields, "resources")
+	}
+	return fields
+}
+
+// reconcileDrift detects out-of-band edits to a running session's
+// Deployment (kubectl scale, image patches, ...).
+//
+// Depending on SESSION_DRIFT_POLICY the edits are reverted or reported with
+// the Drifted condition; either way an event is recorded on the Session so
+// operators notice the tampering. The first check of a Deployment records
+// its spec as the baseline.
+func (r *SessionReconciler) reconcileDrift(ctx context.Context, session *streamv1alpha1.Session, deployment *appsv1.Deployment) error {
+	log := log.FromContext(ctx)
+
+	recorded, ok := deployment.Annotations[appliedSpecAnnotation]
+	var applied appliedSpec
+	if !ok || json.Unmarshal([]byte(recorded), &applied) != nil {
+		recordAppliedSpec(deployment)
+		return r.Update(ctx, deployment)
+	}
+
+	fields := driftedFields(deployment, applied)
+	if len(fields) == 0 {
+		if meta.IsStatusConditionTrue(session.Status.Conditions, driftedCondition) {
+			r.setCondition(ctx, session, driftedCondition, metav1.ConditionFalse, "InSync", "Deployment matches the session spec")
+		}
+		return nil
+	}
+
+	message := fmt.Sprintf("Deployment %s was modified outside StreamSpace: %s", deployment.Name, strings.Join(fields, ", "))
+	if driftPolicyFromEnv() == driftPolicyReport {
+		if !meta.IsStatusConditionTrue(session.Status.Conditions, driftedCondition) {
+			log.Info("Session Deployment drifted", "deployment", deployment.Name, "fields", fields)
+			r.recordEvent(session, corev1.EventTypeWarning, "DriftDetected", message)
+			r.setCondition(ctx, session, driftedCondition, metav1.ConditionTrue, "DriftDetected", message)
+		}
+		return nil
+	}
+
+	deployment.Spec.Replicas = int32Ptr(1)
+	container := &deployment.Spec.Template.Spec.Containers[0]
+	container.Image = applied.Image
+	container.Args = applied.Args
+	container.Env = applied.Env
+	container.Resources = applied.Resources
+	if err := r.Update(ctx, deployment); err != nil {
+		return err
+	}
+
+	log.Info("Corrected session Deployment drift", "deployment", deployment.Name, "fields", fields)
+	r.recordEvent(session, corev1.EventTypeWarning, "DriftCorrected", message+"; changes were reverted")
+	if meta.IsStatusConditionTrue(session.Status.Conditions, driftedCondition) {
+		r.setCondition(ctx, session, driftedCondition, metav1.ConditionFalse, "DriftCorrected", message+"; changes were reverted")
+	}
+	return nil
+}
+
+// recordEvent records a Kubernetes event on a session, if an event
+// recorder is configured.
+func (r *SessionReconciler) recordEvent(session *streamv1alpha1.Session, eventType, reason, message string) {
+	if r.Recorder != nil {
+		r.Recorder.Event(session, eventType, reason, message)
+	}
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+const (
+	// evictedCondition is True while an evicted session is being rescheduled
+	evictedCondition = "Evicted"
+
+	// evictionRecheckInterval is how often a rescheduling session is checked
+	// for a ready replacement pod
+	evictionRecheckInterval = 5 * time.Second
+)
+
+// podDisruption reports whether a pod was involuntarily disrupted and why.
+//
+// Kubernetes reports disruptions in two ways:
+//   - Node-pressure eviction by the kubelet leaves the pod Failed with
+//     reason "Evicted"
+//   - Eviction API (drain), scheduler preemption and taint-based eviction
+//     delete the pod and set its DisruptionTarget condition
+func podDisruption(pod *corev1.Pod) (reason, message string, disrupted bool) {
+	if pod.Status.Phase == corev1.PodFailed && pod.Status.Reason == "Evicted" {
+		return "Evicted", pod.Status.Message, true
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.DisruptionTarget && cond.Status == corev1.ConditionTrue {
+			if cond.Reason == "" {
+				return "Evicted", cond.Message, true
+			}
+			return cond.Reason, cond.Message, true
+		}
+	}
+	return "", "", false
+}
+
+// evictionMessage describes an eviction for the session owner.
+func evictionMessage(eviction *streamv1alpha1.EvictionStatus) string {
+	msg := fmt.Sprintf("Session pod was disrupted (%s)", eviction.Reason)
+	if eviction.Message != "" {
+		msg += ": " + eviction.Message
+	}
+	msg += ". Rescheduling the session."
+	if eviction.DataLost {
+		msg += " The session had no persistent home, so data stored in it was lost."
+	}
+	return msg
+}
+
+// detectEviction records an involuntary disruption of the session pod.
+//
+// It runs before the pod is ensured so the disruption is reported even when
+// the replacement is created in the same reconcile. Each disrupted pod is
+// reported once: the Evicted condition is set, the API is notified and
+// Failed pods (which Kubernetes leaves behind) are deleted so the
+// Deployment's replacement, or a new warm pod / Deployment, takes over.
+func (r *SessionReconciler) detectEviction(ctx context.Context, session *streamv1alpha1.Session) error {
+	log := log.FromContext(ctx)
+
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, client.InNamespace(session.Namespace), client.MatchingLabels{
+		"app":     "streamspace-session",
+		"session": session.Name,
+	}); err != nil {
+		return err
+	}
+
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		reason, message, disrupted := podDisruption(pod)
+		if !disrupted {
+			continue
+		}
+
+		if session.Status.Eviction == nil || session.Status.Eviction.PodName != pod.Name {
+			session.Status.Eviction = &streamv1alpha1.EvictionStatus{
+				PodName:  pod.Name,
+				Reason:   reason,
+				Message:  message,
+				Time:     metav1.Now(),
+				DataLost: !session.Spec.PersistentHome,
+			}
+			userMessage := evictionMessage(session.Status.Eviction)
+
+			log.Info("Session pod was disrupted, rescheduling", "session", session.Name, "pod", pod.Name, "reason", reason)
+			session.Status.Phase = "Evicted"
+			session.Status.PodName = ""
+			r.setCondition(ctx, session, evictedCondition, metav1.ConditionTrue, reason, userMessage)
+			r.publishSessionStatus(session.Name, "evicted", "Evicted", session.Status.URL, pod.Name, userMessage)
+		}
+
+		if pod.Status.Phase == corev1.PodFailed && pod.DeletionTimestamp == nil {
+			if err := r.Delete(ctx, pod); err != nil && !errors.IsNotFound(err) {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// awaitRescheduledPod keeps an evicted session in the Evicted phase until
+// its replacement pod is ready.
+//
+// handled is true while the session is still waiting; the caller should
+// return result.
+func (r *SessionReconciler) awaitRescheduledPod(ctx context.Context, session *streamv1alpha1.Session) (handled bool, result ctrl.Result, err error) {
+	if !meta.IsStatusConditionTrue(session.Status.Conditions, evictedCondition) {
+		return false, ctrl.Result{}, nil
+	}
+
+	pod, err := r.sessionPod(ctx, session)
+	if err != nil {
+		return true, ctrl.Result{}, err
+	}
+
+	if pod != nil && isPodReady(pod) {
+		log.FromContext(ctx).Info("Evicted session rescheduled", "session", session.Name, "pod", pod.Name)
+		meta.SetStatusCondition(&session.Status.Conditions, metav1.Condition{
+			Type:               evictedCondition,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: session.Generation,
+			Reason:             "Rescheduled",
+			Message:            fmt.Sprintf("Session was rescheduled to pod %s", pod.Name),
+		})
+		return false, ctrl.Result{}, nil
+	}
+
+	if session.Status.Phase != "Evicted" {
+		session.Status.Phase = "Evicted"
+		if err := r.Status().Update(ctx, session); err != nil {
+			return true, ctrl.Result{}, err
+		}
+	}
+	return true, ctrl.Result{RequeueAfter: evictionRecheckInterval}, nil
+}
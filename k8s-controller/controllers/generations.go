@@ -0,0 +1,56 @@
+package controllers
+
+import (
+	"sync"
+	"time"
+)
+
+// Stale reconciles.
+//
+// The reconciler reads Sessions from the informer cache, which lags behind
+// writes: a state flipped by a NATS command, or a spec the reconciler just
+// updated itself, may not be in the cache when the next reconcile runs.
+// Acting on the older spec scales the session to a state the user already
+// left, and the newer spec's watch event scales it back, so replicas flap
+// when users toggle quickly. Each reconcile records the Session generation
+// it acted on, and a reconcile that reads an older generation is skipped
+// until the cache catches up.
+//
+// status.observedGeneration reports the generation the session's resources
+// last converged to, so clients can tell whether their latest change has
+// been applied.
+
+// staleSessionRequeue is when a skipped stale reconcile is retried, in case
+// the newer generation's watch event was already handled.
+const staleSessionRequeue = time.Second
+
+// generationTracker records the latest Session generation reconciled per
+// session. The zero value is ready to use.
+type generationTracker struct {
+	mu   sync.Mutex
+	seen map[string]int64 // session → generation
+}
+
+// observe records that session is being reconciled at generation. It
+// reports false, recording nothing, if a newer generation was already
+// reconciled.
+func (t *generationTracker) observe(session string, generation int64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.seen == nil {
+		t.seen = map[string]int64{}
+	}
+	if generation < t.seen[session] {
+		return false
+	}
+	t.seen[session] = generation
+	return true
+}
+
+// forget drops a deleted session.
+func (t *generationTracker) forget(session string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.seen, session)
+}
@@ -78,10 +78,31 @@
 // - CheckInterval: How often to check sessions (default: 1 minute)
 // - DefaultIdleTime: Fallback if Session.Spec.IdleTimeout not set (default: 30 minutes)
 //
+// COST-AWARE HIBERNATION:
+//
+// Expensive sessions (large GPU templates) can hibernate sooner than cheap
+// ones. With a hibernation policy the idle timeout shrinks in proportion to
+// how far the session's estimated hourly cost exceeds a reference cost:
+//
+//   effective timeout = idleTimeout × referenceHourlyCost / hourlyCost
+//
+// never below the policy's minimum idle timeout. The policy comes from
+// Template.Spec.HibernationPolicy, else the controller-wide
+// HIBERNATION_COST_REFERENCE_HOURLY and HIBERNATION_MIN_IDLE_TIMEOUT (unset:
+// no cost scaling). Hourly cost uses the session's resources and the
+// COST_PRICE_CPU_HOUR, COST_PRICE_GB_HOUR and COST_PRICE_GPU_HOUR prices.
+//
+// Session.Status.Hibernation records whether the standard (IdleTimeout) or
+// the cost-scaled (CostIdleTimeout) timeout applied, so users can see why
+// their session hibernated, and the stream.space/state-actor annotation
+// ("reconciler:idle") lets the API audit the hibernation as the idle
+// reconciler's rather than a user's.
+//
 // METRICS:
 //
 // The controller exports Prometheus metrics:
 // - session_hibernations_total{reason="idle"}: Auto-hibernations triggered
+// - session_hibernations_total{reason="idle-cost"}: ... with a cost-scaled timeout
 // - session_idle_duration_seconds: How long sessions were idle before hibernation
 //
 // These metrics help:
@@ -139,8 +160,10 @@ import (
 	"context"
 	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -150,6 +173,19 @@ import (
 	"github.com/streamspace/streamspace/pkg/metrics"
 )
 
+// idleHibernationActor is the state actor of idle hibernations, as audited
+// by the API.
+const idleHibernationActor = "reconciler:idle"
+
+// setStateActor records who changed the session's spec.state on the
+// controller's own initiative (see StateActorAnnotation).
+func setStateActor(session *streamv1alpha1.Session, actor string) {
+	if session.Annotations == nil {
+		session.Annotations = map[string]string{}
+	}
+	session.Annotations[streamv1alpha1.StateActorAnnotation] = actor
+}
+
 // HibernationReconciler handles automatic hibernation of idle sessions.
 //
 // This controller monitors running sessions and automatically hibernates them
@@ -161,6 +197,7 @@ import (
 // - Scheme: Runtime scheme for type information
 // - CheckInterval: How often to check sessions for idle timeout (default: 1 minute)
 // - DefaultIdleTime: Fallback idle timeout if Session doesn't specify (default: 30 minutes)
+// - Recorder: Records a Kubernetes event on each hibernated Session
 //
 // RECONCILIATION FREQUENCY:
 //
@@ -185,6 +222,7 @@ type HibernationReconciler struct {
 	Scheme *runtime.Scheme   // Type information for objects
 	CheckInterval time.Duration  // How often to check for idle sessions
 	DefaultIdleTime time.Duration  // Default idle timeout if not specified
+	Recorder record.EventRecorder  // Records hibernation events on Sessions (optional)
 }
 
 // Reconcile checks sessions for idle timeout and triggers auto-hibernation.
@@ -328,6 +366,16 @@ func (r *HibernationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 		idleTimeout = r.DefaultIdleTime // Fallback to default (30 minutes)
 	}
 
+	// Expensive sessions may hibernate sooner (Template.Spec.HibernationPolicy).
+	// A missing template only means its policy can't be applied.
+	var template *streamv1alpha1.Template
+	var tmpl streamv1alpha1.Template
+	if err := r.Get(ctx, client.ObjectKey{Namespace: session.Namespace, Name: session.Spec.Template}, &tmpl); err == nil {
+		template = &tmpl
+	}
+	hibernation := newIdleHibernation(&session, template, idleTimeout)
+	idleTimeout = hibernation.effectiveTimeout
+
 	// Check if LastActivity timestamp exists and is set
 	if session.Status.LastActivity != nil {
 		// Calculate how long the session has been idle
@@ -340,6 +388,7 @@ func (r *HibernationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 				"session", session.Name,
 				"idleDuration", idleDuration,
 				"idleTimeout", idleTimeout,
+				"costScaled", hibernation.costScaled,
 			)
 
 			// BUG FIX: Use retry.RetryOnConflict to handle race conditions
@@ -356,6 +405,7 @@ func (r *HibernationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 				// Update state to hibernated
 				// This triggers SessionReconciler to scale Deployment to 0
 				freshSession.Spec.State = "hibernated"
+				setStateActor(freshSession, idleHibernationActor)
 				return r.Update(ctx, freshSession)
 			})
 
@@ -364,9 +414,28 @@ func (r *HibernationReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 				return ctrl.Result{}, err
 			}
 
+			// Tell the user why: the standard or a cost-scaled idle timeout
+			status := hibernation.status(idleDuration, time.Now())
+			err = retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				freshSession := &streamv1alpha1.Session{}
+				if err := r.Get(ctx, client.ObjectKeyFromObject(&session), freshSession); err != nil {
+					return err
+				}
+				freshSession.Status.Hibernation = status
+				return r.Status().Update(ctx, freshSession)
+			})
+			if err != nil {
+				// The session is hibernated either way; only the explanation is lost
+				log.Error(err, "Failed to record hibernation reason")
+			}
+			if r.Recorder != nil {
+				r.Recorder.Event(&session, corev1.EventTypeNormal, status.Reason, status.Message)
+			}
+
 			// Record hibernation metrics for cost analysis
-			// Label "idle" distinguishes auto-hibernation from manual
-			metrics.RecordHibernation(session.Namespace, "idle")
+			// Label "idle" distinguishes auto-hibernation from manual,
+			// "idle-cost" marks cost-scaled idle timeouts
+			metrics.RecordHibernation(session.Namespace, hibernation.metricReason())
 			metrics.ObserveIdleDuration(session.Namespace, idleDuration.Seconds())
 
 			log.Info("Session hibernated due to idle timeout", "session", session.Name)
@@ -217,4 +217,86 @@ var _ = Describe("Hibernation Controller", func() {
 			Expect(k8sClient.Delete(ctx, session)).To(Succeed())
 		})
 	})
+
+	Context("When a template has a cost-aware hibernation policy", func() {
+		It("Should hibernate expensive sessions sooner and record why", func() {
+			ctx := context.Background()
+
+			// 4 CPU, 16Gi and 2 GPUs cost about 2.04/hour at default prices,
+			// so a 40s idle timeout shrinks to about 10s
+			template := &streamv1alpha1.Template{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "gpu-hibernate-template",
+					Namespace: "default",
+				},
+				Spec: streamv1alpha1.TemplateSpec{
+					DisplayName: "GPU Hibernate Test Template",
+					BaseImage:   "lscr.io/linuxserver/blender:latest",
+					DefaultResources: corev1.ResourceRequirements{
+						Requests: corev1.ResourceList{
+							corev1.ResourceMemory: resource.MustParse("16Gi"),
+							corev1.ResourceCPU:    resource.MustParse("4"),
+						},
+						Limits: corev1.ResourceList{
+							"nvidia.com/gpu": resource.MustParse("2"),
+						},
+					},
+					VNC: streamv1alpha1.VNCConfig{
+						Enabled: true,
+						Port:    3000,
+					},
+					HibernationPolicy: &streamv1alpha1.HibernationPolicy{
+						ReferenceHourlyCost: "0.50",
+						MinIdleTimeout:      "1s",
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, template)).To(Succeed())
+
+			session := &streamv1alpha1.Session{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "gpu-hibernate-session",
+					Namespace: "default",
+				},
+				Spec: streamv1alpha1.SessionSpec{
+					User:        "gpuuser",
+					Template:    "gpu-hibernate-template",
+					State:       "running",
+					IdleTimeout: "40s",
+				},
+			}
+			Expect(k8sClient.Create(ctx, session)).To(Succeed())
+
+			// Idle for 15 seconds: under the idle timeout, over the scaled one
+			createdSession := &streamv1alpha1.Session{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{
+					Name:      "gpu-hibernate-session",
+					Namespace: "default",
+				}, createdSession)
+			}, timeout, interval).Should(Succeed())
+
+			pastTime := metav1.NewTime(time.Now().Add(-15 * time.Second))
+			createdSession.Status.LastActivity = &pastTime
+			Expect(k8sClient.Status().Update(ctx, createdSession)).To(Succeed())
+
+			Eventually(func() string {
+				err := k8sClient.Get(ctx, types.NamespacedName{
+					Name:      "gpu-hibernate-session",
+					Namespace: "default",
+				}, createdSession)
+				if err != nil || createdSession.Status.Hibernation == nil {
+					return ""
+				}
+				return createdSession.Status.Hibernation.Reason
+			}, timeout, interval).Should(Equal(streamv1alpha1.HibernationReasonCost))
+
+			Expect(createdSession.Spec.State).To(Equal("hibernated"))
+			Expect(createdSession.Status.Hibernation.HourlyCost).To(Equal("2.04"))
+
+			// Cleanup
+			Expect(k8sClient.Delete(ctx, session)).To(Succeed())
+			Expect(k8sClient.Delete(ctx, template)).To(Succeed())
+		})
+	})
 })
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+// gpuResourceName is the extended resource GPU sessions request.
+const gpuResourceName corev1.ResourceName = "nvidia.com/gpu"
+
+// defaultMinIdleTimeout is the shortest cost-scaled idle timeout when
+// neither the template nor HIBERNATION_MIN_IDLE_TIMEOUT sets one.
+const defaultMinIdleTimeout = 5 * time.Minute
+
+// costPrices are the unit prices used to estimate a session's hourly cost.
+// They use the same COST_PRICE_* variables and defaults as the API's cost
+// reports, so "expensive" means the same thing in both places.
+type costPrices struct {
+	cpuHour      float64
+	memoryGBHour float64
+	gpuHour      float64
+}
+
+func costPricesFromEnv() costPrices {
+	return costPrices{
+		cpuHour:      envFloat("COST_PRICE_CPU_HOUR", 0.04),
+		memoryGBHour: envFloat("COST_PRICE_GB_HOUR", 0.005),
+		gpuHour:      envFloat("COST_PRICE_GPU_HOUR", 0.90),
+	}
+}
+
+// costPolicy is the resolved cost-aware hibernation policy of a session.
+type costPolicy struct {
+	referenceHourlyCost float64
+	minIdleTimeout      time.Duration
+}
+
+// hibernationCostPolicy returns the cost-aware hibernation policy for a
+// template's sessions: the template's own, else the controller-wide one
+// (HIBERNATION_COST_REFERENCE_HOURLY, HIBERNATION_MIN_IDLE_TIMEOUT). It
+// returns false when cost scaling is off.
+func hibernationCostPolicy(template *streamv1alpha1.Template) (costPolicy, bool) {
+	reference := os.Getenv("HIBERNATION_COST_REFERENCE_HOURLY")
+	minIdle := os.Getenv("HIBERNATION_MIN_IDLE_TIMEOUT")
+	if template != nil && template.Spec.HibernationPolicy != nil {
+		reference = template.Spec.HibernationPolicy.ReferenceHourlyCost
+		if template.Spec.HibernationPolicy.MinIdleTimeout != "" {
+			minIdle = template.Spec.HibernationPolicy.MinIdleTimeout
+		}
+	}
+
+	policy := costPolicy{minIdleTimeout: defaultMinIdleTimeout}
+	if reference == "" {
+		return policy, false
+	}
+	value, err := strconv.ParseFloat(reference, 64)
+	if err != nil || value <= 0 {
+		return policy, false
+	}
+	policy.referenceHourlyCost = value
+
+	if minIdle != "" {
+		if d, err := time.ParseDuration(minIdle); err == nil && d > 0 {
+			policy.minIdleTimeout = d
+		}
+	}
+	return policy, true
+}
+
+// sessionHourlyCost estimates what a session costs per hour from its
+// resources. Like quotas and cost reports it uses the requested amounts,
+// falling back to limits for resources without a request.
+func sessionHourlyCost(resources corev1.ResourceRequirements, prices costPrices) float64 {
+	amount := func(name corev1.ResourceName) float64 {
+		if q, ok := resources.Requests[name]; ok {
+			return q.AsApproximateFloat64()
+		}
+		if q, ok := resources.Limits[name]; ok {
+			return q.AsApproximateFloat64()
+		}
+		return 0
+	}
+
+	cpu := amount(corev1.ResourceCPU)
+	memoryGB := amount(corev1.ResourceMemory) / (1 << 30)
+	gpus := amount(gpuResourceName)
+	return cpu*prices.cpuHour + memoryGB*prices.memoryGBHour + gpus*prices.gpuHour
+}
+
+// costScaledIdleTimeout shortens an idle timeout in proportion to how far
+// the hourly cost exceeds the policy's reference, but not below its
+// minimum (or below the original timeout, if that is shorter already). It
+// reports whether the timeout was shortened.
+func costScaledIdleTimeout(idleTimeout time.Duration, hourlyCost float64, policy costPolicy) (time.Duration, bool) {
+	if hourlyCost <= policy.referenceHourlyCost {
+		return idleTimeout, false
+	}
+	scaled := time.Duration(float64(idleTimeout) * policy.referenceHourlyCost / hourlyCost)
+	if scaled < policy.minIdleTimeout {
+		scaled = policy.minIdleTimeout
+	}
+	if scaled >= idleTimeout {
+		return idleTimeout, false
+	}
+	return scaled.Round(time.Second), true
+}
+
+// idleHibernation decides a session's effective idle timeout and how to
+// explain a hibernation caused by it.
+type idleHibernation struct {
+	baseTimeout      time.Duration
+	effectiveTimeout time.Duration
+	hourlyCost       float64
+	policy           costPolicy
+	costEvaluated    bool
+	costScaled       bool
+}
+
+// newIdleHibernation applies the template's cost-aware hibernation policy
+// to a session's idle timeout. template may be nil if it was not found.
+func newIdleHibernation(session *streamv1alpha1.Session, template *streamv1alpha1.Template, idleTimeout time.Duration) idleHibernation {
+	h := idleHibernation{baseTimeout: idleTimeout, effectiveTimeout: idleTimeout}
+
+	policy, ok := hibernationCostPolicy(template)
+	if !ok {
+		return h
+	}
+
+	resources := session.Spec.Resources
+	if len(resources.Requests) == 0 && len(resources.Limits) == 0 && template != nil {
+		resources = template.Spec.DefaultResources
+	}
+
+	h.policy = policy
+	h.costEvaluated = true
+	h.hourlyCost = sessionHourlyCost(resources, costPricesFromEnv())
+	h.effectiveTimeout, h.costScaled = costScaledIdleTimeout(idleTimeout, h.hourlyCost, policy)
+	return h
+}
+
+// status explains a hibernation after idleDuration for the session status.
+func (h idleHibernation) status(idleDuration time.Duration, now time.Time) *streamv1alpha1.HibernationStatus {
+	status := &streamv1alpha1.HibernationStatus{
+		Reason:      streamv1alpha1.HibernationReasonIdle,
+		IdleTimeout: h.effectiveTimeout.String(),
+		Message:     fmt.Sprintf("Idle for %s, exceeding the idle timeout of %s", idleDuration.Round(time.Second), h.effectiveTimeout),
+	}
+	if h.costEvaluated {
+		status.HourlyCost = strconv.FormatFloat(h.hourlyCost, 'f', 2, 64)
+	}
+	if h.costScaled {
+		status.Reason = streamv1alpha1.HibernationReasonCost
+		status.Message = fmt.Sprintf("Idle for %s; idle timeout %s shortened to %s for hourly cost %.2f (reference %.2f)",
+			idleDuration.Round(time.Second), h.baseTimeout, h.effectiveTimeout, h.hourlyCost, h.policy.referenceHourlyCost)
+	}
+	status.Time.Time = now
+	return status
+}
+
+// metricReason is the hibernation metric label: "idle" as before, or
+// "idle-cost" for cost-scaled timeouts.
+func (h idleHibernation) metricReason() string {
+	if h.costScaled {
+		return "idle-cost"
+	}
+	return "idle"
+}
+
+// envFloat reads a non-negative float from the environment, falling back
+// to def if unset or invalid.
+func envFloat(key string, def float64) float64 {
+	if f, err := strconv.ParseFloat(os.Getenv(key), 64); err == nil && f >= 0 {
+		return f
+	}
+	return def
+}
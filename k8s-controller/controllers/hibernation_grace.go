@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+// Hibernation grace.
+//
+// Scaling a session to zero cuts off whoever is still connected and gives
+// the app no chance to save. With SESSION_HIBERNATION_GRACE set, a session
+// asked to hibernate first enters the "Hibernating" phase: the API stops
+// accepting new connections and tells the user, and the Deployment is only
+// scaled down once the session's connections have closed or the grace has
+// passed, whichever comes first.
+//
+// The API reports the number of open connections of a hibernating session
+// in the ActiveConnectionsAnnotation. Without it the session is treated as
+// having none.
+//
+// Environment:
+//   - SESSION_HIBERNATION_GRACE: longest wait for connections to drain
+//     (default 0: scale down immediately)
+const (
+	// drainingCondition is True while a hibernating session drains
+	drainingCondition = "Draining"
+
+	// drainRecheckInterval is how often a draining session is checked for
+	// closed connections
+	drainRecheckInterval = 5 * time.Second
+)
+
+// hibernationGraceFromEnv reads the hibernation grace.
+func hibernationGraceFromEnv() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("SESSION_HIBERNATION_GRACE")); err == nil && d > 0 {
+		return d
+	}
+	return 0
+}
+
+// activeConnections returns the open connections the API reported for a
+// session.
+func activeConnections(session *streamv1alpha1.Session) int {
+	n, err := strconv.Atoi(session.Annotations[streamv1alpha1.ActiveConnectionsAnnotation])
+	if err != nil || n < 0 {
+		return 0
+	}
+	return n
+}
+
+// drainRemaining returns how long a session that started draining at since
+// still waits for its connections at now, or 0 if it can scale down.
+func drainRemaining(session *streamv1alpha1.Session, since time.Time, grace time.Duration, now time.Time) time.Duration {
+	if activeConnections(session) == 0 {
+		return 0
+	}
+	if remaining := since.Add(grace).Sub(now); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// drainBeforeHibernation holds off the scale-down of a session that still
+// has a pod until its connections drained or the grace passed. It returns
+// true once the session may scale down; otherwise the reconcile should end
+// with the returned result.
+func (r *SessionReconciler) drainBeforeHibernation(ctx context.Context, session *streamv1alpha1.Session) (bool, ctrl.Result, error) {
+	grace := hibernationGraceFromEnv()
+	if grace == 0 {
+		return true, ctrl.Result{}, nil
+	}
+
+	draining := meta.FindStatusCondition(session.Status.Conditions, drainingCondition)
+	if draining == nil || draining.Status != metav1.ConditionTrue {
+		pod, err := r.sessionPod(ctx, session)
+		if err != nil {
+			return false, ctrl.Result{}, err
+		}
+		if pod == nil {
+			// Nothing running to drain
+			return true, ctrl.Result{}, nil
+		}
+
+		message := fmt.Sprintf("Session hibernates when its connections close, at most in %s", grace)
+		session.Status.Phase = "Hibernating"
+		r.setCondition(ctx, session, drainingCondition, metav1.ConditionTrue, "HibernationGrace", message)
+		r.recordEvent(session, corev1.EventTypeNormal, "Hibernating", message)
+		r.publishSessionEvent(SessionStatusEvent{
+			SessionID: session.Name,
+			Status:    "hibernating",
+			Phase:     "Hibernating",
+			Message:   message,
+			Actor:     session.Annotations[streamv1alpha1.StateActorAnnotation],
+		})
+		log.FromContext(ctx).Info("Draining session before hibernation", "session", session.Name, "grace", grace)
+		return false, ctrl.Result{RequeueAfter: drainRecheckInterval}, nil
+	}
+
+	if remaining := drainRemaining(session, draining.LastTransitionTime.Time, grace, time.Now()); remaining > 0 {
+		if remaining > drainRecheckInterval {
+			remaining = drainRecheckInterval
+		}
+		return false, ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	reason, message := "Drained", "Connections closed"
+	if activeConnections(session) > 0 {
+		reason, message = "GraceExpired", fmt.Sprintf("%d connections still open after %s", activeConnections(session), grace)
+	}
+	r.setCondition(ctx, session, drainingCondition, metav1.ConditionFalse, reason, message)
+	return true, ctrl.Result{}, nil
+}
+
+// cancelDrain ends the drain of a session woken before it hibernated.
+func (r *SessionReconciler) cancelDrain(ctx context.Context, session *streamv1alpha1.Session) {
+	if meta.IsStatusConditionTrue(session.Status.Conditions, drainingCondition) {
+		r.setCondition(ctx, session, drainingCondition, metav1.ConditionFalse, "HibernationCancelled", "Session was woken before it hibernated")
+	}
+}
@@ -0,0 +1,398 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+// Home volume forks.
+//
+// A session with spec.forkFrom starts with a copy of the source session's
+// home as it was at fork time, in its own PVC (home-{session}). The copy is
+// restored from a CSI VolumeSnapshot when the source's storage class has a
+// VolumeSnapshotClass for its driver; otherwise a Job copies the files into
+// a new, empty PVC. The session's pod isn't created until the copy is done.
+//
+// Forking a running source isn't blocked, but files written during the fork
+// may be copied half-written: the session gets a ForkWhileRunning warning
+// and should hibernate the source first for an exact copy.
+
+const (
+	// forkHomeCondition reports the state of the session's home fork
+	forkHomeCondition = "HomeForked"
+
+	// forkMethodAnnotation records how a forked home PVC was populated
+	forkMethodAnnotation = "stream.space/fork-method"
+	forkMethodSnapshot   = "snapshot"
+	forkMethodCopy       = "copy"
+
+	// forkCopyImageEnv overrides the image of the copy Job
+	forkCopyImageEnv     = "FORK_COPY_IMAGE"
+	defaultForkCopyImage = "busybox:1.36"
+
+	// forkPollInterval is how often a session waits for its fork
+	forkPollInterval = 5 * time.Second
+
+	// defaultSnapshotClassAnnotation marks the default VolumeSnapshotClass
+	defaultSnapshotClassAnnotation = "snapshot.storage.kubernetes.io/is-default-class"
+)
+
+var (
+	volumeSnapshotGVK          = schema.GroupVersionKind{Group: "snapshot.storage.k8s.io", Version: "v1", Kind: "VolumeSnapshot"}
+	volumeSnapshotClassListGVK = schema.GroupVersionKind{Group: "snapshot.storage.k8s.io", Version: "v1", Kind: "VolumeSnapshotClassList"}
+)
+
+// sessionDeploymentName returns the name of the session's Deployment (and
+// the prefix of its Service and Ingress). A fork runs next to its source,
+// which usually has the same user and template, so it is named after the
+// session instead.
+func sessionDeploymentName(session *streamv1alpha1.Session) string {
+	if session.Spec.ForkFrom != "" {
+		return fmt.Sprintf("ss-%s", session.Name)
+	}
+	return fmt.Sprintf("ss-%s-%s", session.Spec.User, session.Spec.Template)
+}
+
+// homePVCName returns the PVC mounted as the session's home: the user's
+// shared home, or the session's own one for forks.
+func homePVCName(session *streamv1alpha1.Session) string {
+	if session.Spec.ForkFrom != "" {
+		return fmt.Sprintf("home-%s", session.Name)
+	}
+	return fmt.Sprintf("home-%s", session.Spec.User)
+}
+
+// forkCopyJobName returns the name of the Job copying a fork's home.
+func forkCopyJobName(session *streamv1alpha1.Session) string {
+	return fmt.Sprintf("%s-home-fork", session.Name)
+}
+
+// ensureForkedHome creates the session's forked home volume and reports
+// whether it holds the source's files yet.
+func (r *SessionReconciler) ensureForkedHome(ctx context.Context, session *streamv1alpha1.Session) (bool, error) {
+	log := log.FromContext(ctx)
+
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, types.NamespacedName{Name: homePVCName(session), Namespace: session.Namespace}, pvc)
+	if err == nil {
+		if pvc.Annotations[forkMethodAnnotation] == forkMethodCopy {
+			return r.forkCopyDone(ctx, session, pvc)
+		}
+		// A PVC restored from a snapshot is populated when it's provisioned
+		return true, nil
+	}
+	if !errors.IsNotFound(err) {
+		return false, err
+	}
+
+	source := &streamv1alpha1.Session{}
+	if err := r.Get(ctx, types.NamespacedName{Name: session.Spec.ForkFrom, Namespace: session.Namespace}, source); err != nil {
+		if errors.IsNotFound(err) {
+			r.setCondition(ctx, session, forkHomeCondition, metav1.ConditionFalse, "ForkSourceNotFound",
+				fmt.Sprintf("Session %s to fork the home of does not exist", session.Spec.ForkFrom))
+			return false, nil
+		}
+		return false, err
+	}
+	if source.Spec.User != session.Spec.User {
+		r.setCondition(ctx, session, forkHomeCondition, metav1.ConditionFalse, "ForkSourceForbidden",
+			fmt.Sprintf("Session %s belongs to another user", source.Name))
+		return false, nil
+	}
+
+	sourcePVC := &corev1.PersistentVolumeClaim{}
+	if err := r.Get(ctx, types.NamespacedName{Name: homePVCName(source), Namespace: session.Namespace}, sourcePVC); err != nil {
+		if errors.IsNotFound(err) {
+			r.setCondition(ctx, session, forkHomeCondition, metav1.ConditionFalse, "ForkSourceHasNoHome",
+				fmt.Sprintf("Session %s has no home volume to fork", source.Name))
+			return false, nil
+		}
+		return false, err
+	}
+
+	if source.Spec.State == "running" {
+		r.recordEvent(session, corev1.EventTypeWarning, "ForkWhileRunning",
+			fmt.Sprintf("Forking the home of running session %s: files written during the fork may be inconsistent. Hibernate it first for an exact copy.", source.Name))
+	}
+
+	snapshotClass, err := r.snapshotClassFor(ctx, sourcePVC)
+	if err != nil {
+		return false, err
+	}
+
+	if snapshotClass != "" {
+		if err := r.forkFromSnapshot(ctx, session, sourcePVC, snapshotClass); err != nil {
+			return false, err
+		}
+		log.Info("Forked home volume from snapshot", "source", sourcePVC.Name, "snapshotClass", snapshotClass)
+		r.setCondition(ctx, session, forkHomeCondition, metav1.ConditionTrue, "Snapshotted",
+			fmt.Sprintf("Home restored from a snapshot of %s", sourcePVC.Name))
+		return true, nil
+	}
+
+	fork := forkedHomePVC(session, sourcePVC, forkMethodCopy)
+	if err := r.Create(ctx, fork); err != nil && !errors.IsAlreadyExists(err) {
+		return false, fmt.Errorf("failed to create forked home PVC: %w", err)
+	}
+	log.Info("Copying home volume for fork (snapshots not supported)", "source", sourcePVC.Name)
+	return r.forkCopyDone(ctx, session, fork)
+}
+
+// snapshotClassFor returns the VolumeSnapshotClass that can snapshot the
+// PVC, or "" if its storage class doesn't support snapshots (including
+// clusters without the snapshot CRDs).
+func (r *SessionReconciler) snapshotClassFor(ctx context.Context, pvc *corev1.PersistentVolumeClaim) (string, error) {
+	if pvc.Spec.StorageClassName == nil || *pvc.Spec.StorageClassName == "" {
+		return "", nil
+	}
+	storageClass := &storagev1.StorageClass{}
+	if err := r.Get(ctx, types.NamespacedName{Name: *pvc.Spec.StorageClassName}, storageClass); err != nil {
+		if errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	classes := &unstructured.UnstructuredList{}
+	classes.SetGroupVersionKind(volumeSnapshotClassListGVK)
+	if err := r.List(ctx, classes); err != nil {
+		if meta.IsNoMatchError(err) || errors.IsNotFound(err) {
+			return "", nil
+		}
+		return "", err
+	}
+
+	match := ""
+	for _, class := range classes.Items {
+		driver, _, _ := unstructured.NestedString(class.Object, "driver")
+		if driver != storageClass.Provisioner {
+			continue
+		}
+		if class.GetAnnotations()[defaultSnapshotClassAnnotation] == "true" {
+			return class.GetName(), nil
+		}
+		if match == "" {
+			match = class.GetName()
+		}
+	}
+	return match, nil
+}
+
+// forkFromSnapshot snapshots the source PVC and creates the session's home
+// PVC from the snapshot.
+func (r *SessionReconciler) forkFromSnapshot(ctx context.Context, session *streamv1alpha1.Session, sourcePVC *corev1.PersistentVolumeClaim, snapshotClass string) error {
+	snapshotName := fmt.Sprintf("%s-home-fork", session.Name)
+
+	snapshot := &unstructured.Unstructured{}
+	snapshot.SetGroupVersionKind(volumeSnapshotGVK)
+	snapshot.SetName(snapshotName)
+	snapshot.SetNamespace(session.Namespace)
+	snapshot.SetLabels(map[string]string{
+		"app":     "streamspace-home-fork",
+		"user":    session.Spec.User,
+		"session": session.Name,
+	})
+	snapshot.SetOwnerReferences([]metav1.OwnerReference{
+		*metav1.NewControllerRef(session, streamv1alpha1.GroupVersion.WithKind("Session")),
+	})
+	snapshot.Object["spec"] = map[string]interface{}{
+		"volumeSnapshotClassName": snapshotClass,
+		"source": map[string]interface{}{
+			"persistentVolumeClaimName": sourcePVC.Name,
+		},
+	}
+	if err := r.Create(ctx, snapshot); err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create VolumeSnapshot: %w", err)
+	}
+
+	fork := forkedHomePVC(session, sourcePVC, forkMethodSnapshot)
+	apiGroup := volumeSnapshotGVK.Group
+	fork.Spec.DataSource = &corev1.TypedLocalObjectReference{
+		APIGroup: &apiGroup,
+		Kind:     volumeSnapshotGVK.Kind,
+		Name:     snapshotName,
+	}
+	if err := r.Create(ctx, fork); err != nil && !errors.IsAlreadyExists(err) {
+		return fmt.Errorf("failed to create forked home PVC: %w", err)
+	}
+	return nil
+}
+
+// forkCopyDone runs the Job copying the source's home into the fork and
+// reports whether it has finished.
+func (r *SessionReconciler) forkCopyDone(ctx context.Context, session *streamv1alpha1.Session, fork *corev1.PersistentVolumeClaim) (bool, error) {
+	job := &batchv1.Job{}
+	err := r.Get(ctx, types.NamespacedName{Name: forkCopyJobName(session), Namespace: session.Namespace}, job)
+	if errors.IsNotFound(err) {
+		source := &streamv1alpha1.Session{}
+		if err := r.Get(ctx, types.NamespacedName{Name: session.Spec.ForkFrom, Namespace: session.Namespace}, source); err != nil {
+			if errors.IsNotFound(err) {
+				r.setCondition(ctx, session, forkHomeCondition, metav1.ConditionFalse, "ForkSourceNotFound",
+					fmt.Sprintf("Session %s to fork the home of does not exist", session.Spec.ForkFrom))
+				return false, nil
+			}
+			return false, err
+		}
+		sourceClaim := homePVCName(source)
+
+		// A ReadWriteOnce source can only be mounted on the node using it
+		nodeName, err := r.nodeMountingClaim(ctx, session.Namespace, sourceClaim)
+		if err != nil {
+			return false, err
+		}
+		if err := r.Create(ctx, forkCopyJob(session, sourceClaim, fork.Name, nodeName)); err != nil && !errors.IsAlreadyExists(err) {
+			return false, fmt.Errorf("failed to create home copy Job: %w", err)
+		}
+		r.setCondition(ctx, session, forkHomeCondition, metav1.ConditionFalse, "Copying",
+			fmt.Sprintf("Copying the home of %s (its storage doesn't support snapshots)", source.Name))
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	if job.Status.Succeeded > 0 {
+		if !meta.IsStatusConditionTrue(session.Status.Conditions, forkHomeCondition) {
+			r.setCondition(ctx, session, forkHomeCondition, metav1.ConditionTrue, "Copied",
+				fmt.Sprintf("Home copied from session %s", session.Spec.ForkFrom))
+		}
+		return true, nil
+	}
+	for _, cond := range job.Status.Conditions {
+		if cond.Type == batchv1.JobFailed && cond.Status == corev1.ConditionTrue {
+			if cond := meta.FindStatusCondition(session.Status.Conditions, forkHomeCondition); cond == nil || cond.Reason != "CopyFailed" {
+				r.recordEvent(session, corev1.EventTypeWarning, "ForkFailed",
+					fmt.Sprintf("Copying the home of %s failed; delete Job %s to retry", session.Spec.ForkFrom, job.Name))
+				r.setCondition(ctx, session, forkHomeCondition, metav1.ConditionFalse, "CopyFailed",
+					fmt.Sprintf("Job %s failed to copy the home of %s", job.Name, session.Spec.ForkFrom))
+			}
+			return false, nil
+		}
+	}
+	return false, nil
+}
+
+// nodeMountingClaim returns the node of a running pod that mounts the
+// claim, or "" if none does.
+func (r *SessionReconciler) nodeMountingClaim(ctx context.Context, namespace, claimName string) (string, error) {
+	pods := &corev1.PodList{}
+	if err := r.List(ctx, pods, client.InNamespace(namespace)); err != nil {
+		return "", err
+	}
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == "" || pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil && volume.PersistentVolumeClaim.ClaimName == claimName {
+				return pod.Spec.NodeName, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// forkedHomePVC builds the session's own home PVC, sized and classed like
+// the source's. Unlike the shared user home it is owned by the session.
+func forkedHomePVC(session *streamv1alpha1.Session, source *corev1.PersistentVolumeClaim, method string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      homePVCName(session),
+			Namespace: session.Namespace,
+			Labels: map[string]string{
+				"app":     "streamspace-user-home",
+				"user":    session.Spec.User,
+				"session": session.Name,
+			},
+			Annotations: map[string]string{
+				forkMethodAnnotation: method,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(session, streamv1alpha1.GroupVersion.WithKind("Session")),
+			},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      source.Spec.AccessModes,
+			StorageClassName: source.Spec.StorageClassName,
+			VolumeMode:       source.Spec.VolumeMode,
+			Resources: corev1.VolumeResourceRequirements{
+				Requests: corev1.ResourceList{
+					corev1.ResourceStorage: source.Spec.Resources.Requests[corev1.ResourceStorage],
+				},
+			},
+		},
+	}
+}
+
+// forkCopyJob builds the Job that copies the source home into the fork.
+func forkCopyJob(session *streamv1alpha1.Session, sourceClaim, targetClaim, nodeName string) *batchv1.Job {
+	image := os.Getenv(forkCopyImageEnv)
+	if image == "" {
+		image = defaultForkCopyImage
+	}
+	labels := map[string]string{
+		"app":     "streamspace-home-fork",
+		"user":    session.Spec.User,
+		"session": session.Name,
+	}
+	backoffLimit := int32(2)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      forkCopyJobName(session),
+			Namespace: session.Namespace,
+			Labels:    labels,
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(session, streamv1alpha1.GroupVersion.WithKind("Session")),
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					NodeName:      nodeName,
+					Containers: []corev1.Container{{
+						Name:    "copy",
+						Image:   image,
+						Command: []string{"sh", "-c", "cp -a /source/. /target/"},
+						VolumeMounts: []corev1.VolumeMount{
+							{Name: "source", MountPath: "/source", ReadOnly: true},
+							{Name: "target", MountPath: "/target"},
+						},
+					}},
+					Volumes: []corev1.Volume{
+						{
+							Name: "source",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: sourceClaim, ReadOnly: true},
+							},
+						},
+						{
+							Name: "target",
+							VolumeSource: corev1.VolumeSource{
+								PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: targetClaim},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,312 @@
+package controllers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ErrImageNotFound is returned by an ImageChecker when the registry reports
+// that the image doesn't exist.
+var ErrImageNotFound = errors.New("image not found in registry")
+
+// ImageChecker checks that a template's image still exists.
+//
+// CheckImage returns nil if the image exists, an error wrapping
+// ErrImageNotFound if the registry says it doesn't, and any other error if
+// that couldn't be determined (registry unreachable, access denied). Only
+// ErrImageNotFound makes a template invalid: nodes may have credentials or
+// network access the controller lacks.
+type ImageChecker interface {
+	CheckImage(ctx context.Context, image string, pullSecrets []corev1.Secret) error
+}
+
+// RegistryImageChecker asks the image's registry for its manifest using the
+// Docker Registry HTTP API v2, with credentials from the template's image
+// pull secrets if they have any for the registry.
+type RegistryImageChecker struct {
+	Client *http.Client
+}
+
+// NewRegistryImageChecker creates a RegistryImageChecker with a short
+// request timeout.
+func NewRegistryImageChecker() *RegistryImageChecker {
+	return &RegistryImageChecker{Client: &http.Client{Timeout: 15 * time.Second}}
+}
+
+// manifestMediaTypes are the manifest formats accepted, so registries that
+// only serve image indexes (multi-arch images) don't answer 404.
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// CheckImage implements ImageChecker.
+func (c *RegistryImageChecker) CheckImage(ctx context.Context, image string, pullSecrets []corev1.Secret) error {
+	ref, err := parseImageReference(image)
+	if err != nil {
+		return err
+	}
+	username, password := registryCredentials(pullSecrets, ref.Registry)
+	manifestURL := fmt.Sprintf("https://%s/v2/%s/manifests/%s", ref.host(), ref.Repository, ref.Reference)
+
+	resp, err := c.headManifest(ctx, manifestURL, "")
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		authorization, err := c.authorize(ctx, resp.Header.Get("WWW-Authenticate"), ref, username, password)
+		if err != nil {
+			return err
+		}
+		if resp, err = c.headManifest(ctx, manifestURL, authorization); err != nil {
+			return err
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusNotFound:
+		return fmt.Errorf("%w: %s", ErrImageNotFound, image)
+	default:
+		return fmt.Errorf("registry %s returned %s for %s", ref.Registry, resp.Status, image)
+	}
+}
+
+// headManifest requests a manifest's headers.
+func (c *RegistryImageChecker) headManifest(ctx context.Context, manifestURL, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, manifestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
+
+// authorize answers a registry's authentication challenge: basic auth with
+// the pull secret's credentials, or a bearer token from the registry's
+// token service (anonymous if there are no credentials, as for public
+// images on Docker Hub).
+func (c *RegistryImageChecker) authorize(ctx context.Context, challenge string, ref imageReference, username, password string) (string, error) {
+	scheme, params := parseAuthChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if username == "" {
+			return "", fmt.Errorf("registry %s requires credentials", ref.Registry)
+		}
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("registry %s requested unsupported authentication %q", ref.Registry, scheme)
+	}
+
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil || tokenURL.Host == "" {
+		return "", fmt.Errorf("registry %s sent an invalid token realm %q", ref.Registry, params["realm"])
+	}
+	scope := params["scope"]
+	if scope == "" {
+		scope = fmt.Sprintf("repository:%s:pull", ref.Repository)
+	}
+	query := tokenURL.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", scope)
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if username != "" {
+		req.SetBasicAuth(username, password)
+	}
+	resp, err := c.client().Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("token service of registry %s returned %s", ref.Registry, resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("invalid token from registry %s: %w", ref.Registry, err)
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	return "Bearer " + token.Token, nil
+}
+
+func (c *RegistryImageChecker) client() *http.Client {
+	if c.Client != nil {
+		return c.Client
+	}
+	return http.DefaultClient
+}
+
+// imageReference is a parsed image name.
+type imageReference struct {
+	Registry   string // e.g. docker.io, lscr.io, localhost:5000
+	Repository string // e.g. library/nginx
+	Reference  string // tag or digest
+}
+
+// parseImageReference parses an image name the way container runtimes do:
+// a first path component with a dot or port (or "localhost") is the
+// registry, otherwise the image is on Docker Hub; the tag defaults to
+// latest.
+func parseImageReference(image string) (imageReference, error) {
+	ref := imageReference{Registry: "docker.io"}
+	name := image
+
+	if i := strings.Index(name, "@"); i >= 0 {
+		ref.Reference = name[i+1:]
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		if ref.Reference == "" {
+			ref.Reference = name[i+1:]
+		}
+		name = name[:i]
+	}
+	if ref.Reference == "" {
+		ref.Reference = "latest"
+	}
+
+	if i := strings.Index(name, "/"); i >= 0 {
+		if first := name[:i]; strings.ContainsAny(first, ".:") || first == "localhost" {
+			ref.Registry = first
+			name = name[i+1:]
+		}
+	}
+	if ref.Registry == "docker.io" && !strings.Contains(name, "/") {
+		name = "library/" + name
+	}
+
+	if name == "" || strings.HasSuffix(name, "/") {
+		return imageReference{}, fmt.Errorf("invalid image reference %q", image)
+	}
+	ref.Repository = name
+	return ref, nil
+}
+
+// host is the registry's API host.
+func (r imageReference) host() string {
+	if r.Registry == "docker.io" {
+		return "registry-1.docker.io"
+	}
+	return r.Registry
+}
+
+// dockerConfigAuth is a registry entry of a docker config pull secret.
+type dockerConfigAuth struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Auth     string `json:"auth"`
+}
+
+// registryCredentials returns the credentials for registry from image pull
+// secrets (kubernetes.io/dockerconfigjson or kubernetes.io/dockercfg).
+func registryCredentials(secrets []corev1.Secret, registry string) (string, string) {
+	for _, secret := range secrets {
+		var auths map[string]dockerConfigAuth
+		if data, ok := secret.Data[corev1.DockerConfigJsonKey]; ok {
+			var config struct {
+				Auths map[string]dockerConfigAuth `json:"auths"`
+			}
+			if err := json.Unmarshal(data, &config); err != nil {
+				continue
+			}
+			auths = config.Auths
+		} else if data, ok := secret.Data[corev1.DockerConfigKey]; ok {
+			if err := json.Unmarshal(data, &auths); err != nil {
+				continue
+			}
+		}
+
+		for server, auth := range auths {
+			if registryHost(server) != registryHost(registry) {
+				continue
+			}
+			if auth.Username == "" && auth.Auth != "" {
+				decoded, err := base64.StdEncoding.DecodeString(auth.Auth)
+				if err != nil {
+					continue
+				}
+				auth.Username, auth.Password, _ = strings.Cut(string(decoded), ":")
+			}
+			return auth.Username, auth.Password
+		}
+	}
+	return "", ""
+}
+
+// registryHost normalizes a docker config server key, which may be a URL
+// such as https://index.docker.io/v1/.
+func registryHost(server string) string {
+	server = strings.TrimPrefix(strings.TrimPrefix(server, "https://"), "http://")
+	if i := strings.Index(server, "/"); i >= 0 {
+		server = server[:i]
+	}
+	switch server {
+	case "index.docker.io", "registry-1.docker.io":
+		return "docker.io"
+	}
+	return server
+}
+
+// parseAuthChallenge splits a WWW-Authenticate header into its scheme and
+// parameters, e.g. Bearer realm="https://auth.docker.io/token",service="...".
+// Quoted values may contain commas (scope="repository:x:pull,push").
+func parseAuthChallenge(header string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(header), " ")
+	params := make(map[string]string)
+
+	for rest = strings.TrimSpace(rest); rest != ""; rest = strings.TrimSpace(rest) {
+		key, value, ok := strings.Cut(rest, "=")
+		if !ok {
+			break
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		if strings.HasPrefix(value, `"`) {
+			end := strings.Index(value[1:], `"`)
+			if end < 0 {
+				params[key] = value[1:]
+				break
+			}
+			params[key] = value[1 : end+1]
+			rest = value[end+2:]
+		} else {
+			value, rest, _ = strings.Cut(value, ",")
+			params[key] = strings.TrimSpace(value)
+		}
+		rest = strings.TrimPrefix(strings.TrimSpace(rest), ",")
+	}
+	return scheme, params
+}
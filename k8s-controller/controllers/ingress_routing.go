@@ -0,0 +1,129 @@
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	networkingv1 "k8s.io/api/networking/v1"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+// Session ingress routing modes (INGRESS_ROUTING_MODE).
+const (
+	// ingressRoutingSubdomain exposes sessions at https://{name}.{domain}.
+	// Requires wildcard DNS and a wildcard certificate.
+	ingressRoutingSubdomain = "subdomain"
+
+	// ingressRoutingPath exposes sessions at https://{domain}/sessions/{name}/
+	// so StreamSpace can run behind a single hostname and certificate.
+	ingressRoutingPath = "path"
+
+	// sessionPathPrefix is the URL prefix of path-routed sessions
+	sessionPathPrefix = "/sessions/"
+)
+
+// ingressRouting controls how session Ingresses are generated.
+//
+// Environment:
+//   - INGRESS_DOMAIN: base domain (default streamspace.local)
+//   - INGRESS_CLASS: ingress class (default traefik)
+//   - INGRESS_ROUTING_MODE: "subdomain" (default) or "path"
+//   - INGRESS_PATH_MIDDLEWARE: Traefik middleware reference that strips
+//     /sessions/{name} in path mode (e.g. "streamspace-streamspace-session-prefix@kubernetescrd",
+//     created by the Helm chart)
+type ingressRouting struct {
+	Mode           string
+	Domain         string
+	Class          string
+	PathMiddleware string
+}
+
+// ingressRoutingFromEnv reads session ingress settings.
+func ingressRoutingFromEnv() ingressRouting {
+	cfg := ingressRouting{
+		Mode:           ingressRoutingSubdomain,
+		Domain:         os.Getenv("INGRESS_DOMAIN"),
+		Class:          os.Getenv("INGRESS_CLASS"),
+		PathMiddleware: os.Getenv("INGRESS_PATH_MIDDLEWARE"),
+	}
+	if cfg.Domain == "" {
+		cfg.Domain = "streamspace.local" // Default for development
+	}
+	if cfg.Class == "" {
+		cfg.Class = "traefik"
+	}
+	if strings.EqualFold(os.Getenv("INGRESS_ROUTING_MODE"), ingressRoutingPath) {
+		cfg.Mode = ingressRoutingPath
+	}
+	return cfg
+}
+
+// host returns the hostname a session is served on.
+func (cfg ingressRouting) host(session *streamv1alpha1.Session) string {
+	if cfg.Mode == ingressRoutingPath {
+		return cfg.Domain
+	}
+	return fmt.Sprintf("%s.%s", session.Name, cfg.Domain)
+}
+
+// basePath returns the external path a session is served under, with a
+// trailing slash ("/" in subdomain mode).
+func (cfg ingressRouting) basePath(session *streamv1alpha1.Session) string {
+	if cfg.Mode == ingressRoutingPath {
+		return sessionPathPrefix + session.Name + "/"
+	}
+	return "/"
+}
+
+// sessionURL returns the URL users open to reach a session.
+func (cfg ingressRouting) sessionURL(session *streamv1alpha1.Session) string {
+	if cfg.Mode == ingressRoutingPath {
+		return fmt.Sprintf("https://%s%s", cfg.Domain, cfg.basePath(session))
+	}
+	return fmt.Sprintf("https://%s", cfg.host(session))
+}
+
+// templateBasePath returns the path prefix a template's application serves
+// from, normalized to start and end with "/".
+func templateBasePath(template *streamv1alpha1.Template) string {
+	if template.Spec.WebApp == nil || template.Spec.WebApp.BasePath == "" {
+		return "/"
+	}
+	basePath := "/" + strings.Trim(template.Spec.WebApp.BasePath, "/")
+	if basePath != "/" {
+		basePath += "/"
+	}
+	return basePath
+}
+
+// httpPath returns the Ingress path for a session and the annotations that
+// rewrite it to what the application expects.
+//
+// In subdomain mode the whole host belongs to the session, so "/" is routed
+// as is. In path mode /sessions/{name}/... is rewritten to the template's
+// webapp.basePath:
+//
+//   - NGINX: a regex path with nginx.ingress.kubernetes.io/rewrite-target
+//   - Traefik: a prefix path with the INGRESS_PATH_MIDDLEWARE strip-prefix
+//     middleware (only a "/" base path can be expressed with the shared
+//     middleware; others need a custom one)
+func (cfg ingressRouting) httpPath(session *streamv1alpha1.Session, template *streamv1alpha1.Template) (string, networkingv1.PathType, map[string]string) {
+	annotations := map[string]string{}
+	if cfg.Mode != ingressRoutingPath {
+		return "/", networkingv1.PathTypePrefix, annotations
+	}
+
+	prefix := strings.TrimSuffix(cfg.basePath(session), "/")
+	if strings.Contains(cfg.Class, "nginx") {
+		annotations["nginx.ingress.kubernetes.io/use-regex"] = "true"
+		annotations["nginx.ingress.kubernetes.io/rewrite-target"] = templateBasePath(template) + "$2"
+		return prefix + "(/|$)(.*)", networkingv1.PathTypeImplementationSpecific, annotations
+	}
+
+	if cfg.PathMiddleware != "" {
+		annotations["traefik.ingress.kubernetes.io/router.middlewares"] = cfg.PathMiddleware
+	}
+	return prefix, networkingv1.PathTypePrefix, annotations
+}
@@ -0,0 +1,223 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+// Per-template launch throttle.
+//
+// When a freshly published template is launched by many users at once,
+// every node pulls its image at the same moment and the registry and network
+// saturate, making every one of those launches slow. With a launch limit,
+// at most N sessions of a template may be starting (Deployment created,
+// session not yet ready) at a time; further launches wait in a FIFO queue
+// with the LaunchQueued condition set and phase Pending, and start as
+// earlier launches become ready.
+//
+// The limit comes from the template's spec.maxConcurrentLaunches, falling
+// back to SESSION_MAX_CONCURRENT_LAUNCHES. Launches that don't pull an image
+// are never throttled:
+//   - Sessions that claim a warm pod (the pod is already running)
+//   - Templates with spec.prePull whose image is cached on every node
+//   - Waking a hibernated session (its Deployment already exists)
+//
+// A launch stops counting once the session is ready, hibernated, terminated
+// or deleted, or after SESSION_LAUNCH_TIMEOUT so pods stuck in ImagePullBackOff
+// can't hold a slot forever.
+//
+// The in-flight set is kept in memory by the (leader) controller. After a
+// restart launches already in progress are not counted, so up to N extra
+// launches may start once.
+//
+// Environment:
+//   - SESSION_MAX_CONCURRENT_LAUNCHES: default per-template limit (default 0, unlimited)
+//   - SESSION_LAUNCH_TIMEOUT: how long a launch may hold a slot (default 5m)
+
+const (
+	// launchQueuedCondition is True while a session waits for a launch slot
+	launchQueuedCondition = "LaunchQueued"
+
+	// launchQueueRetry is how often a queued session checks for a free slot
+	launchQueueRetry = 5 * time.Second
+
+	// launchWaiterExpiry drops queued sessions that stopped checking in
+	// (deleted, or their state changed)
+	launchWaiterExpiry = 6 * launchQueueRetry
+
+	defaultLaunchTimeout = 5 * time.Minute
+)
+
+// defaultLaunchLimit reads SESSION_MAX_CONCURRENT_LAUNCHES.
+func defaultLaunchLimit() int {
+	if v, err := strconv.Atoi(os.Getenv("SESSION_MAX_CONCURRENT_LAUNCHES")); err == nil && v > 0 {
+		return v
+	}
+	return 0
+}
+
+// launchTimeout reads SESSION_LAUNCH_TIMEOUT.
+func launchTimeout() time.Duration {
+	if d, err := time.ParseDuration(os.Getenv("SESSION_LAUNCH_TIMEOUT")); err == nil && d > 0 {
+		return d
+	}
+	return defaultLaunchTimeout
+}
+
+// templateLaunchLimit returns the number of concurrent launches allowed for
+// a template, or 0 when launches are not throttled.
+func templateLaunchLimit(template *streamv1alpha1.Template) int {
+	if template.Spec.MaxConcurrentLaunches > 0 {
+		return int(template.Spec.MaxConcurrentLaunches)
+	}
+	return defaultLaunchLimit()
+}
+
+// imagePrePulled reports whether the template's image is pre-pulled onto
+// every node, so launching it causes no registry traffic.
+func imagePrePulled(template *streamv1alpha1.Template) bool {
+	return template.Spec.PrePull &&
+		template.Status.PrePullDesiredNodes > 0 &&
+		template.Status.PrePullReadyNodes >= template.Status.PrePullDesiredNodes
+}
+
+// launchWaiter is a session queued for a launch slot.
+type launchWaiter struct {
+	session  string
+	lastSeen time.Time
+}
+
+// launchTracker tracks in-flight and queued launches per template.
+// The zero value is ready to use.
+type launchTracker struct {
+	mu       sync.Mutex
+	inFlight map[string]map[string]time.Time // template → session → admitted at
+	waiting  map[string][]launchWaiter       // template → FIFO queue
+}
+
+// admit decides whether session may start launching template now, given at
+// most limit launches in flight. When it must wait, admit returns its
+// 1-based position in the queue and the number of launches in flight.
+func (t *launchTracker) admit(template, session string, limit int, now time.Time, timeout time.Duration) (admitted bool, position, inFlight int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.inFlight == nil {
+		t.inFlight = map[string]map[string]time.Time{}
+		t.waiting = map[string][]launchWaiter{}
+	}
+	launches := t.inFlight[template]
+	if launches == nil {
+		launches = map[string]time.Time{}
+		t.inFlight[template] = launches
+	}
+
+	for s, at := range launches {
+		if now.Sub(at) >= timeout {
+			delete(launches, s)
+		}
+	}
+	if _, ok := launches[session]; ok {
+		return true, 0, len(launches)
+	}
+
+	// Refresh the queue, dropping sessions that stopped checking in
+	index := -1
+	queue := t.waiting[template][:0]
+	for _, w := range t.waiting[template] {
+		if w.session == session {
+			w.lastSeen = now
+			index = len(queue)
+		} else if now.Sub(w.lastSeen) >= launchWaiterExpiry {
+			continue
+		}
+		queue = append(queue, w)
+	}
+	if index < 0 {
+		index = len(queue)
+		queue = append(queue, launchWaiter{session: session, lastSeen: now})
+	}
+
+	if index < limit-len(launches) {
+		queue = append(queue[:index], queue[index+1:]...)
+		launches[session] = now
+		t.waiting[template] = queue
+		return true, 0, len(launches)
+	}
+
+	t.waiting[template] = queue
+	free := limit - len(launches)
+	if free < 0 {
+		free = 0
+	}
+	return false, index - free + 1, len(launches)
+}
+
+// release frees the launch slot or queue place held by session.
+func (t *launchTracker) release(session string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, launches := range t.inFlight {
+		delete(launches, session)
+	}
+	for template, queue := range t.waiting {
+		for i, w := range queue {
+			if w.session == session {
+				t.waiting[template] = append(queue[:i], queue[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
+// throttleLaunch queues a new launch while too many launches of the same
+// template are in flight. It returns true if the session must wait, along
+// with the result to requeue it with.
+func (r *SessionReconciler) throttleLaunch(ctx context.Context, session *streamv1alpha1.Session, template *streamv1alpha1.Template) (bool, ctrl.Result) {
+	limit := templateLaunchLimit(template)
+	if limit <= 0 || imagePrePulled(template) {
+		return false, ctrl.Result{}
+	}
+
+	templateKey := types.NamespacedName{Namespace: template.Namespace, Name: template.Name}.String()
+	sessionKey := types.NamespacedName{Namespace: session.Namespace, Name: session.Name}.String()
+	admitted, position, inFlight := r.launches.admit(templateKey, sessionKey, limit, time.Now(), launchTimeout())
+	queued := meta.IsStatusConditionTrue(session.Status.Conditions, launchQueuedCondition)
+
+	if admitted {
+		if queued {
+			r.setCondition(ctx, session, launchQueuedCondition, metav1.ConditionFalse, "Launched",
+				"Launch slot acquired")
+		}
+		return false, ctrl.Result{}
+	}
+
+	message := fmt.Sprintf("Waiting for a launch slot: %d launches of template %s in progress (limit %d), position %d in queue",
+		inFlight, template.Name, limit, position)
+	if !queued {
+		log.FromContext(ctx).Info("Session launch queued", "template", template.Name, "inFlight", inFlight, "position", position)
+		r.recordEvent(session, corev1.EventTypeNormal, "LaunchQueued", message)
+		r.publishSessionStatus(session.Name, "pending", "Pending", "", "", message)
+	}
+
+	// Only write the status when the queue position changed
+	if condition := meta.FindStatusCondition(session.Status.Conditions, launchQueuedCondition); !queued || condition.Message != message {
+		session.Status.Phase = "Pending"
+		r.setCondition(ctx, session, launchQueuedCondition, metav1.ConditionTrue, "TemplateLaunchLimit", message)
+	}
+	return true, ctrl.Result{RequeueAfter: launchQueueRetry}
+}
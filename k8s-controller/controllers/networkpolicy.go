@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+// privateIPRanges are excluded from "Internet" egress so a session can't
+// reach the cluster, the node network or cloud metadata endpoints through
+// their IPs.
+var privateIPRanges = []string{
+	"10.0.0.0/8",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"100.64.0.0/10",  // Carrier-grade NAT (often used for pod networks)
+	"169.254.0.0/16", // Link-local, including 169.254.169.254 metadata
+}
+
+// networkPoliciesEnabled reports whether session NetworkPolicies are generated.
+//
+// Policies are opt-in (SESSION_NETWORK_POLICIES=true) because they require a
+// CNI that enforces them (Calico, Cilium, ...) and lock sessions down to
+// what their template declares.
+func networkPoliciesEnabled() bool {
+	return strings.EqualFold(os.Getenv("SESSION_NETWORK_POLICIES"), "true")
+}
+
+// ensureNetworkPolicy creates or updates the NetworkPolicy isolating a session.
+//
+// It runs before the session pod is created so the pod never runs without
+// its policy.
+func (r *SessionReconciler) ensureNetworkPolicy(ctx context.Context, session *streamv1alpha1.Session, template *streamv1alpha1.Template) error {
+	log := log.FromContext(ctx)
+
+	desired := r.createNetworkPolicy(session, template)
+	existing := &networkingv1.NetworkPolicy{}
+	err := r.Get(ctx, types.NamespacedName{Name: desired.Name, Namespace: desired.Namespace}, existing)
+
+	if errors.IsNotFound(err) {
+		if err := r.Create(ctx, desired); err != nil {
+			return fmt.Errorf("failed to create NetworkPolicy: %w", err)
+		}
+		log.Info("Created NetworkPolicy", "name", desired.Name, "egress", networkEgressMode(template))
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	// Template egress may have changed since the policy was created
+	if existing.Annotations["stream.space/network-egress"] != desired.Annotations["stream.space/network-egress"] {
+		existing.Annotations = desired.Annotations
+		existing.Spec = desired.Spec
+		if err := r.Update(ctx, existing); err != nil {
+			return fmt.Errorf("failed to update NetworkPolicy: %w", err)
+		}
+		log.Info("Updated NetworkPolicy", "name", existing.Name, "egress", networkEgressMode(template))
+	}
+	return nil
+}
+
+// networkEgressMode returns the template's egress mode with the default applied.
+func networkEgressMode(template *streamv1alpha1.Template) string {
+	if template.Spec.NetworkEgress == "" {
+		return streamv1alpha1.NetworkEgressNone
+	}
+	return template.Spec.NetworkEgress
+}
+
+// createNetworkPolicy constructs the NetworkPolicy for a session pod.
+//
+// INGRESS:
+//
+// Only the ingress controller's namespace (INGRESS_CONTROLLER_NAMESPACE,
+// default kube-system) and the StreamSpace API pods (which proxy VNC) may
+// connect. In particular other session pods can't.
+//
+// EGRESS (Template.Spec.NetworkEgress):
+//
+//   - None: DNS only
+//   - Internal: DNS and any pod in the cluster
+//   - Internet: DNS and public IPs (private ranges excluded)
+//
+// NAMING CONVENTION:
+//
+//   - NetworkPolicy name: {session}-netpol
+//   - Example: "alice-firefox-1a2b3c4d-netpol"
+//
+// The policy is keyed on the session, not on user and template, so two
+// sessions of the same template get separate policies.
+//
+// OWNER REFERENCE:
+//
+// NetworkPolicy has owner reference to Session for automatic cleanup.
+func (r *SessionReconciler) createNetworkPolicy(session *streamv1alpha1.Session, template *streamv1alpha1.Template) *networkingv1.NetworkPolicy {
+	name := fmt.Sprintf("%s-netpol", session.Name)
+	egressMode := networkEgressMode(template)
+
+	ingressNamespace := os.Getenv("INGRESS_CONTROLLER_NAMESPACE")
+	if ingressNamespace == "" {
+		ingressNamespace = "kube-system" // Traefik on k3s
+	}
+
+	ingress := []networkingv1.NetworkPolicyIngressRule{
+		{
+			From: []networkingv1.NetworkPolicyPeer{
+				{
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"kubernetes.io/metadata.name": ingressNamespace},
+					},
+				},
+				{
+					// StreamSpace API (Helm chart labels)
+					PodSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"app.kubernetes.io/component": "api"},
+					},
+				},
+				{
+					// StreamSpace API (plain manifests)
+					PodSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"component": "api"},
+					},
+				},
+			},
+		},
+	}
+
+	udp := corev1.ProtocolUDP
+	tcp := corev1.ProtocolTCP
+	dnsPort := intstr.FromInt32(53)
+	egress := []networkingv1.NetworkPolicyEgressRule{
+		{
+			// DNS is always needed for name resolution
+			Ports: []networkingv1.NetworkPolicyPort{
+				{Protocol: &udp, Port: &dnsPort},
+				{Protocol: &tcp, Port: &dnsPort},
+			},
+		},
+	}
+
+	switch egressMode {
+	case streamv1alpha1.NetworkEgressInternal:
+		egress = append(egress, networkingv1.NetworkPolicyEgressRule{
+			To: []networkingv1.NetworkPolicyPeer{
+				{NamespaceSelector: &metav1.LabelSelector{}}, // All pods in all namespaces
+			},
+		})
+	case streamv1alpha1.NetworkEgressInternet:
+		egress = append(egress, networkingv1.NetworkPolicyEgressRule{
+			To: []networkingv1.NetworkPolicyPeer{
+				{
+					IPBlock: &networkingv1.IPBlock{
+						CIDR:   "0.0.0.0/0",
+						Except: privateIPRanges,
+					},
+				},
+			},
+		})
+	}
+
+	return &networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: session.Namespace,
+			Labels: map[string]string{
+				"app":      "streamspace-session",
+				"user":     session.Spec.User,
+				"template": session.Spec.Template,
+				"session":  session.Name,
+			},
+			Annotations: map[string]string{
+				"stream.space/network-egress": egressMode,
+			},
+			OwnerReferences: []metav1.OwnerReference{
+				*metav1.NewControllerRef(session, streamv1alpha1.GroupVersion.WithKind("Session")),
+			},
+		},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{
+				MatchLabels: map[string]string{"session": session.Name},
+			},
+			PolicyTypes: []networkingv1.PolicyType{
+				networkingv1.PolicyTypeIngress,
+				networkingv1.PolicyTypeEgress,
+			},
+			Ingress: ingress,
+			Egress:  egress,
+		},
+	}
+}
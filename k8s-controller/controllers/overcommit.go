@@ -0,0 +1,163 @@
+package controllers
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+// Resource overcommit.
+//
+// Interactive desktop sessions sit idle most of the time, so reserving their
+// full size on a node wastes capacity. With an overcommit ratio for a
+// template category the session's size becomes its limit and its request
+// (what the scheduler packs nodes by) is the size divided by the ratio:
+// a 2 CPU / 4Gi browser session with ratio 4 requests 500m / 1Gi but may
+// still burst to 2 CPU / 4Gi.
+//
+// The size is the limit if one is set, otherwise the request. A request
+// already lower than size/ratio is kept. Only CPU and memory are
+// overcommitted; extended resources such as GPUs must request their limit.
+// Quotas are charged on limits, so overcommit packs more sessions per node
+// without letting a user run more than their quota.
+//
+// Memory overcommit lets busy sessions be OOM-killed or evicted when a node
+// runs out; set SESSION_OVERCOMMIT_MEMORY=false to overcommit CPU only, and
+// spec.disableOvercommit on templates whose workloads can't tolerate either.
+//
+// The effective requests and limits are reported in
+// status.effectiveResources.
+//
+// Environment:
+//   - SESSION_OVERCOMMIT_RATIOS: comma-separated category=ratio pairs, with
+//     "default" for other categories, e.g. "default=2,Web Browsers=4"
+//     (default none: requests are left as specified)
+//   - SESSION_OVERCOMMIT_MEMORY: overcommit memory as well as CPU (default true)
+
+// overcommitDefaultKey is the ratio for categories without their own.
+const overcommitDefaultKey = "default"
+
+// overcommitPolicy is the parsed overcommit configuration.
+type overcommitPolicy struct {
+	ratios map[string]float64 // by overcommitCategoryKey
+	memory bool
+}
+
+// overcommitPolicyFromEnv reads the overcommit policy. Malformed entries and
+// ratios below 1 are ignored.
+func overcommitPolicyFromEnv() overcommitPolicy {
+	policy := overcommitPolicy{ratios: map[string]float64{}, memory: true}
+	if v, err := strconv.ParseBool(os.Getenv("SESSION_OVERCOMMIT_MEMORY")); err == nil {
+		policy.memory = v
+	}
+	for _, entry := range strings.Split(os.Getenv("SESSION_OVERCOMMIT_RATIOS"), ",") {
+		i := strings.LastIndex(entry, "=")
+		if i < 0 {
+			continue
+		}
+		ratio, err := strconv.ParseFloat(strings.TrimSpace(entry[i+1:]), 64)
+		if err != nil || ratio < 1 || math.IsInf(ratio, 0) {
+			continue
+		}
+		policy.ratios[overcommitCategoryKey(entry[:i])] = ratio
+	}
+	return policy
+}
+
+// overcommitCategoryKey normalizes a category name, so "Web Browsers" and
+// "web-browsers" match.
+func overcommitCategoryKey(category string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(category) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// ratio returns the overcommit ratio for a template's sessions, 1 if they
+// aren't overcommitted.
+func (p overcommitPolicy) ratio(template *streamv1alpha1.Template) float64 {
+	if template != nil && template.Spec.DisableOvercommit {
+		return 1
+	}
+	if template != nil && template.Spec.Category != "" {
+		if ratio, ok := p.ratios[overcommitCategoryKey(template.Spec.Category)]; ok {
+			return ratio
+		}
+	}
+	if ratio, ok := p.ratios[overcommitDefaultKey]; ok {
+		return ratio
+	}
+	return 1
+}
+
+// apply returns the resources a session container runs with.
+func (p overcommitPolicy) apply(template *streamv1alpha1.Template, resources corev1.ResourceRequirements) corev1.ResourceRequirements {
+	ratio := p.ratio(template)
+	if ratio <= 1 {
+		return resources
+	}
+
+	out := *resources.DeepCopy()
+	names := []corev1.ResourceName{corev1.ResourceCPU}
+	if p.memory {
+		names = append(names, corev1.ResourceMemory)
+	}
+	for _, name := range names {
+		size, ok := out.Limits[name]
+		if !ok {
+			size, ok = out.Requests[name]
+		}
+		if !ok || size.IsZero() {
+			continue
+		}
+
+		request := overcommittedRequest(name, size, ratio)
+		if current, ok := out.Requests[name]; ok && current.Cmp(request) < 0 {
+			request = current
+		}
+		if out.Limits == nil {
+			out.Limits = corev1.ResourceList{}
+		}
+		if out.Requests == nil {
+			out.Requests = corev1.ResourceList{}
+		}
+		out.Limits[name] = size
+		out.Requests[name] = request
+	}
+	return out
+}
+
+// overcommittedRequest divides size by ratio, rounding CPU up to the
+// millicore and memory up to the MiB.
+func overcommittedRequest(name corev1.ResourceName, size resource.Quantity, ratio float64) resource.Quantity {
+	if name == corev1.ResourceCPU {
+		milli := int64(math.Ceil(float64(size.MilliValue()) / ratio))
+		return *resource.NewMilliQuantity(milli, resource.DecimalSI)
+	}
+	const mi = 1024 * 1024
+	mib := int64(math.Ceil(float64(size.Value()) / ratio / mi))
+	return *resource.NewQuantity(mib*mi, resource.BinarySI)
+}
+
+// effectiveResources returns the resources of a Deployment's session
+// container, or nil if it sets none.
+func effectiveResources(containers []corev1.Container) *corev1.ResourceRequirements {
+	if len(containers) == 0 {
+		return nil
+	}
+	resources := containers[0].Resources
+	if len(resources.Requests) == 0 && len(resources.Limits) == 0 {
+		return nil
+	}
+	return resources.DeepCopy()
+}
@@ -0,0 +1,128 @@
+package controllers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// parameterRef matches a ${name} reference in template Env values and Args.
+var parameterRef = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+// parameterName is the valid form of a TemplateParameter name.
+var parameterName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// validateParameterSchema checks a template's parameter declarations.
+func validateParameterSchema(params []streamv1alpha1.TemplateParameter) error {
+	seen := make(map[string]bool, len(params))
+	for _, param := range params {
+		if !parameterName.MatchString(param.Name) {
+			return fmt.Errorf("parameter name %q must be a letter or underscore followed by letters, digits or underscores", param.Name)
+		}
+		if seen[param.Name] {
+			return fmt.Errorf("parameter %q is declared more than once", param.Name)
+		}
+		seen[param.Name] = true
+
+		switch param.Type {
+		case "", streamv1alpha1.ParameterTypeString, streamv1alpha1.ParameterTypeNumber, streamv1alpha1.ParameterTypeBoolean:
+		default:
+			return fmt.Errorf("parameter %q has unknown type %q", param.Name, param.Type)
+		}
+		if param.Default != "" {
+			if err := checkParameterType(param, param.Default); err != nil {
+				return fmt.Errorf("invalid default: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// checkParameterType reports whether value is valid for the parameter's type.
+func checkParameterType(param streamv1alpha1.TemplateParameter, value string) error {
+	switch param.Type {
+	case "", streamv1alpha1.ParameterTypeString:
+		return nil
+	case streamv1alpha1.ParameterTypeNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return fmt.Errorf("parameter %q must be a number, got %q", param.Name, value)
+		}
+	case streamv1alpha1.ParameterTypeBoolean:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("parameter %q must be a boolean, got %q", param.Name, value)
+		}
+	default:
+		return fmt.Errorf("parameter %q has unknown type %q", param.Name, param.Type)
+	}
+	return nil
+}
+
+// resolveParameters validates a session's parameter values against its
+// template's schema and returns the value of every declared parameter,
+// falling back to defaults.
+func resolveParameters(template *streamv1alpha1.Template, session *streamv1alpha1.Session) (map[string]string, error) {
+	declared := make(map[string]bool, len(template.Spec.Parameters))
+	values := make(map[string]string, len(template.Spec.Parameters))
+	for _, param := range template.Spec.Parameters {
+		declared[param.Name] = true
+		value, ok := session.Spec.Parameters[param.Name]
+		if !ok {
+			if param.Required {
+				return nil, fmt.Errorf("parameter %q is required", param.Name)
+			}
+			values[param.Name] = param.Default
+			continue
+		}
+		if err := checkParameterType(param, value); err != nil {
+			return nil, err
+		}
+		values[param.Name] = value
+	}
+
+	for name := range session.Spec.Parameters {
+		if !declared[name] {
+			return nil, fmt.Errorf("template %s has no parameter %q", template.Name, name)
+		}
+	}
+	return values, nil
+}
+
+// substituteParameters replaces ${name} references to declared parameters.
+// Anything else, including references to undeclared names, is left as is.
+func substituteParameters(s string, values map[string]string) string {
+	return parameterRef.ReplaceAllStringFunc(s, func(ref string) string {
+		if value, ok := values[ref[2:len(ref)-1]]; ok {
+			return value
+		}
+		return ref
+	})
+}
+
+// parameterizedEnv returns the template's env vars with parameters
+// substituted into their values.
+func parameterizedEnv(env []corev1.EnvVar, values map[string]string) []corev1.EnvVar {
+	if len(values) == 0 {
+		return env
+	}
+	out := make([]corev1.EnvVar, len(env))
+	for i, e := range env {
+		out[i] = e
+		out[i].Value = substituteParameters(e.Value, values)
+	}
+	return out
+}
+
+// parameterizedArgs returns the template's args with parameters substituted.
+func parameterizedArgs(args []string, values map[string]string) []string {
+	if len(args) == 0 {
+		return nil
+	}
+	out := make([]string, len(args))
+	for i, arg := range args {
+		out[i] = substituteParameters(arg, values)
+	}
+	return out
+}
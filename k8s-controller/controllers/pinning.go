@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+// Node pinning.
+//
+// An operator debugging a flaky session sets spec.pinToNode to keep its pod
+// on one node across restarts and wakes. The pod gets strict node affinity
+// to that node instead of the template's session affinity, and isn't
+// launched in a warm pod (which may be anywhere). Pinning a running
+// session, or changing or removing the pin, restarts its pod.
+//
+// The Pinned condition reports whether the node can take the pod: False
+// with reason NodeNotFound, NodeUnschedulable (cordoned) or NodeNotReady
+// while it can't, in which case the pod stays Pending rather than moving.
+//
+// spec.pinToAgent is handled by the API, which routes the session's
+// commands to that controller's own subject.
+
+// pinnedCondition reports the health of a session's pinned node
+const pinnedCondition = "Pinned"
+
+// pinnedNodeAffinity returns the affinity that keeps a pod on node.
+func pinnedNodeAffinity(node string) *corev1.Affinity {
+	return &corev1.Affinity{
+		NodeAffinity: &corev1.NodeAffinity{
+			RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{
+				NodeSelectorTerms: []corev1.NodeSelectorTerm{{
+					MatchFields: []corev1.NodeSelectorRequirement{{
+						Key:      "metadata.name",
+						Operator: corev1.NodeSelectorOpIn,
+						Values:   []string{node},
+					}},
+				}},
+			},
+		},
+	}
+}
+
+// sessionAffinity returns the affinity of a session's pod: pinned to its
+// node if it has one, otherwise the template's session affinity.
+func sessionAffinity(session *streamv1alpha1.Session, template *streamv1alpha1.Template, homeRWO bool) *corev1.Affinity {
+	if session.Spec.PinToNode != "" {
+		return pinnedNodeAffinity(session.Spec.PinToNode)
+	}
+	return userSessionAffinity(session, template, homeRWO)
+}
+
+// applyNodePin updates an existing Deployment's affinity to the session's
+// pin. It reports whether the Deployment changed; the caller updates it.
+//
+// Unpinned Deployments are left alone, so template affinity changes still
+// only apply to new sessions.
+func applyNodePin(session *streamv1alpha1.Session, template *streamv1alpha1.Template, homeRWO bool, deployment *appsv1.Deployment) bool {
+	current := deployment.Spec.Template.Spec.Affinity
+	if session.Spec.PinToNode == "" && (current == nil || current.NodeAffinity == nil) {
+		return false
+	}
+	affinity := sessionAffinity(session, template, homeRWO)
+	if equality.Semantic.DeepEqual(current, affinity) {
+		return false
+	}
+	deployment.Spec.Template.Spec.Affinity = affinity
+	return true
+}
+
+// updatePinnedCondition sets the Pinned condition from the health of the
+// session's pinned node, or removes it if the session isn't pinned. The
+// caller persists the status.
+func (r *SessionReconciler) updatePinnedCondition(ctx context.Context, session *streamv1alpha1.Session) error {
+	if session.Spec.PinToNode == "" {
+		meta.RemoveStatusCondition(&session.Status.Conditions, pinnedCondition)
+		return nil
+	}
+
+	status, reason, message := metav1.ConditionTrue, "NodeAvailable",
+		fmt.Sprintf("Session is pinned to node %s", session.Spec.PinToNode)
+
+	node := &corev1.Node{}
+	err := r.Get(ctx, types.NamespacedName{Name: session.Spec.PinToNode}, node)
+	switch {
+	case errors.IsNotFound(err):
+		status, reason = metav1.ConditionFalse, "NodeNotFound"
+		message = fmt.Sprintf("Pinned node %s does not exist", session.Spec.PinToNode)
+	case err != nil:
+		return err
+	case node.Spec.Unschedulable:
+		status, reason = metav1.ConditionFalse, "NodeUnschedulable"
+		message = fmt.Sprintf("Pinned node %s is cordoned; the session can't start until it is uncordoned or unpinned", node.Name)
+	case !nodeReady(node):
+		status, reason = metav1.ConditionFalse, "NodeNotReady"
+		message = fmt.Sprintf("Pinned node %s is not ready; the session can't start until it recovers or is unpinned", node.Name)
+	}
+
+	if previous := meta.FindStatusCondition(session.Status.Conditions, pinnedCondition); status == metav1.ConditionFalse &&
+		(previous == nil || previous.Reason != reason) {
+		log.FromContext(ctx).Info("Pinned node can't run session", "node", session.Spec.PinToNode, "reason", reason)
+		r.recordEvent(session, corev1.EventTypeWarning, "PinnedNodeUnavailable", message)
+	}
+	meta.SetStatusCondition(&session.Status.Conditions, metav1.Condition{
+		Type:               pinnedCondition,
+		Status:             status,
+		ObservedGeneration: session.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+	return nil
+}
+
+// nodeReady reports whether a node's kubelet reports it Ready.
+func nodeReady(node *corev1.Node) bool {
+	for _, cond := range node.Status.Conditions {
+		if cond.Type == corev1.NodeReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,220 @@
+// Package controllers implements Kubernetes controllers for StreamSpace.
+//
+// PRE-PULL CONTROLLER
+//
+// The PrePullReconciler pre-pulls template images onto every node so that
+// session pods don't wait for a registry download on first launch.
+//
+// HOW IT WORKS:
+//
+// For each Template with spec.prePull=true the controller manages a
+// DaemonSet named ss-prepull-{template}. Each DaemonSet pod:
+//  1. Runs the template's BaseImage as an init container ("true"), which
+//     forces the kubelet to pull the image onto the node
+//  2. Then idles in a tiny pause container so the image stays referenced
+//     and isn't garbage collected by the kubelet
+//
+// A DaemonSet pod becomes Ready once its init container has finished, so the
+// DaemonSet's numberReady is the number of nodes that have the image cached.
+// The controller copies it to status.prePullReadyNodes (and the number of
+// nodes it should be on to status.prePullDesiredNodes).
+//
+// Image pull secrets from spec.imagePullSecrets are used for the pull.
+//
+// UNPINNING:
+//
+// Setting prePull back to false deletes the DaemonSet. Images already pulled
+// stay on nodes until the kubelet's image GC removes them.
+//
+// This complements the warm pool: the warm pool removes container start time
+// for the most popular templates, pre-pull removes image download time for
+// every launch on every node.
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+// defaultPrePullPauseImage keeps pre-pull pods alive after the pull.
+// Override with PREPULL_PAUSE_IMAGE (e.g. for air-gapped registries).
+const defaultPrePullPauseImage = "registry.k8s.io/pause:3.9"
+
+// PrePullReconciler manages image pre-pull DaemonSets for Templates.
+type PrePullReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=stream.streamspace.io,resources=templates,verbs=get;list;watch
+//+kubebuilder:rbac:groups=stream.streamspace.io,resources=templates/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile creates, updates or removes a Template's pre-pull DaemonSet.
+func (r *PrePullReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	template := &streamv1alpha1.Template{}
+	if err := r.Get(ctx, req.NamespacedName, template); err != nil {
+		// The DaemonSet is owned by the template and garbage collected with it
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	name := prePullDaemonSetName(template.Name)
+	existing := &appsv1.DaemonSet{}
+	err := r.Get(ctx, types.NamespacedName{Name: name, Namespace: template.Namespace}, existing)
+	if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	found := err == nil
+
+	var ready, desired int32
+
+	if !template.Spec.PrePull || !template.Status.Valid {
+		// Unpinned (or invalid) - remove the DaemonSet
+		if found {
+			if err := r.Delete(ctx, existing); err != nil && !errors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+			log.Info("Deleted pre-pull DaemonSet", "name", name, "template", template.Name)
+		}
+	} else {
+		daemonSet := newPrePullDaemonSet(template)
+		if err := ctrl.SetControllerReference(template, daemonSet, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+
+		if !found {
+			if err := r.Create(ctx, daemonSet); err != nil {
+				log.Error(err, "Failed to create pre-pull DaemonSet", "template", template.Name)
+				return ctrl.Result{}, err
+			}
+			log.Info("Created pre-pull DaemonSet", "name", name, "template", template.Name)
+		} else {
+			// Image or pull secrets changed - roll the DaemonSet. Compare only
+			// the fields we own; the API server defaults the rest.
+			if prePullImage(existing) != template.Spec.BaseImage ||
+				!reflect.DeepEqual(existing.Spec.Template.Spec.ImagePullSecrets, daemonSet.Spec.Template.Spec.ImagePullSecrets) {
+				existing.Spec.Template = daemonSet.Spec.Template
+				if err := r.Update(ctx, existing); err != nil {
+					return ctrl.Result{}, err
+				}
+				log.Info("Updated pre-pull DaemonSet", "name", name, "template", template.Name)
+			}
+			ready = existing.Status.NumberReady
+			desired = existing.Status.DesiredNumberScheduled
+		}
+	}
+
+	if template.Status.PrePullReadyNodes != ready || template.Status.PrePullDesiredNodes != desired {
+		template.Status.PrePullReadyNodes = ready
+		template.Status.PrePullDesiredNodes = desired
+		if err := r.Status().Update(ctx, template); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// prePullDaemonSetName returns the DaemonSet name for a template.
+func prePullDaemonSetName(templateName string) string {
+	return fmt.Sprintf("ss-prepull-%s", templateName)
+}
+
+// prePullImage returns the image a pre-pull DaemonSet pulls.
+func prePullImage(daemonSet *appsv1.DaemonSet) string {
+	if len(daemonSet.Spec.Template.Spec.InitContainers) == 0 {
+		return ""
+	}
+	return daemonSet.Spec.Template.Spec.InitContainers[0].Image
+}
+
+// newPrePullDaemonSet builds the DaemonSet that pulls a template's image.
+func newPrePullDaemonSet(template *streamv1alpha1.Template) *appsv1.DaemonSet {
+	labels := map[string]string{
+		"app":      "streamspace-prepull",
+		"template": template.Name,
+	}
+
+	pauseImage := os.Getenv("PREPULL_PAUSE_IMAGE")
+	if pauseImage == "" {
+		pauseImage = defaultPrePullPauseImage
+	}
+
+	// Pre-pull pods do nothing - keep their footprint negligible
+	minimal := corev1.ResourceRequirements{
+		Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("1m"),
+			corev1.ResourceMemory: resource.MustParse("8Mi"),
+		},
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("50m"),
+			corev1.ResourceMemory: resource.MustParse("32Mi"),
+		},
+	}
+
+	return &appsv1.DaemonSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      prePullDaemonSetName(template.Name),
+			Namespace: template.Namespace,
+			Labels:    labels,
+		},
+		Spec: appsv1.DaemonSetSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: labels},
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: labels},
+				Spec: corev1.PodSpec{
+					InitContainers: []corev1.Container{
+						{
+							Name:            "pull",
+							Image:           template.Spec.BaseImage,
+							ImagePullPolicy: corev1.PullIfNotPresent,
+							Command:         []string{"/bin/sh", "-c", "true"},
+							Resources:       minimal,
+						},
+					},
+					Containers: []corev1.Container{
+						{
+							Name:      "pause",
+							Image:     pauseImage,
+							Resources: minimal,
+						},
+					},
+					ImagePullSecrets: template.Spec.ImagePullSecrets,
+					// Pull onto every node, including tainted ones sessions may use
+					Tolerations: []corev1.Toleration{
+						{Operator: corev1.TolerationOpExists},
+					},
+				},
+			},
+		},
+	}
+}
+
+// SetupWithManager registers the PrePullReconciler with the controller manager.
+//
+// Owning DaemonSets means rollout progress (numberReady) is reflected in the
+// template status as nodes finish pulling.
+func (r *PrePullReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&streamv1alpha1.Template{}).
+		Owns(&appsv1.DaemonSet{}).
+		Named("prepull"). // Unique name to distinguish from TemplateReconciler
+		Complete(r)
+}
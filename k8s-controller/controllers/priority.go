@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"os"
+	"strings"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+// sessionPriority returns a session's effective priority class: its own,
+// else the template default, else Normal.
+func sessionPriority(session *streamv1alpha1.Session, template *streamv1alpha1.Template) string {
+	if session.Spec.Priority != "" {
+		return session.Spec.Priority
+	}
+	if template.Spec.DefaultPriority != "" {
+		return template.Spec.DefaultPriority
+	}
+	return streamv1alpha1.SessionPriorityNormal
+}
+
+// priorityClassName returns the Kubernetes PriorityClass for a session
+// priority, configured with SESSION_PRIORITY_CLASS_LOW, _NORMAL and _HIGH.
+//
+// The classes are cluster-scoped and installed by the Helm chart
+// (controller.config.sessionPriorityClasses). An unset variable leaves the
+// pod at the cluster's default priority.
+func priorityClassName(priority string) string {
+	return os.Getenv("SESSION_PRIORITY_CLASS_" + strings.ToUpper(priority))
+}
@@ -0,0 +1,63 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+const (
+	// readyCondition is True once the session pod passes its readiness
+	// checks and the session URL is serving
+	readyCondition = "Ready"
+
+	// readinessRecheckInterval is how often a running session whose pod
+	// isn't ready yet is checked again
+	readinessRecheckInterval = 5 * time.Second
+)
+
+// updateReadyCondition sets the Ready condition from the session pod's
+// readiness and reports whether the pod is ready.
+//
+// The session URL exists as soon as the Ingress does, but the app behind it
+// may still be starting. The API only hands out the URL once Ready is True,
+// so users don't land on a broken first load. The caller persists the
+// condition with its status update.
+func (r *SessionReconciler) updateReadyCondition(ctx context.Context, session *streamv1alpha1.Session) (bool, error) {
+	pod, err := r.sessionPod(ctx, session)
+	if err != nil {
+		return false, err
+	}
+
+	condition := metav1.Condition{
+		Type:               readyCondition,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: session.Generation,
+		Reason:             "PodNotReady",
+		Message:            "Waiting for the session pod to become ready",
+	}
+	ready := pod != nil && isPodReady(pod)
+	if ready {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "PodReady"
+		condition.Message = "Session is running and accepting connections"
+	}
+
+	// Remember when the session first became ready (see startup_metrics.go).
+	// Sessions that were ready before readyAt existed get the time their
+	// Ready condition was set.
+	if ready && session.Status.ReadyAt == nil {
+		readyAt := metav1.Now()
+		if previous := meta.FindStatusCondition(session.Status.Conditions, readyCondition); previous != nil && previous.Status == metav1.ConditionTrue {
+			readyAt = previous.LastTransitionTime
+		}
+		session.Status.ReadyAt = &readyAt
+	}
+
+	meta.SetStatusCondition(&session.Status.Conditions, condition)
+	return ready, nil
+}
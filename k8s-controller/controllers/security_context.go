@@ -0,0 +1,275 @@
+package controllers
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+// defaultSessionUID is the user and group session pods run as by default.
+const defaultSessionUID = 1000
+
+// Session pod security context.
+//
+// Session pods run untrusted, user-driven workloads side by side. Hardened
+// sessions run with:
+//   - pod: non-root (UID/GID/fsGroup 1000), RuntimeDefault seccomp profile
+//   - container: no privilege escalation, all capabilities dropped
+//
+// Most shipped images (linuxserver.io) start as root through s6 and drop
+// privileges themselves, so the hardened defaults are opt-in while templates
+// migrate: a template opts in with spec.securityContext.hardened, and
+// SESSION_SECURITY_DEFAULTS applies them to every template once all images
+// run as non-root.
+//
+// A template's spec.securityContext overrides these field by field. A
+// read-only root filesystem is opt-in, since most desktop images write
+// outside their home directory; when it is enabled /tmp gets an emptyDir.
+//
+// Overrides that weaken isolation (see privilegedSettings) need
+// spec.securityContext.allowPrivileged, otherwise the template fails
+// validation and its sessions don't start.
+//
+// Environment:
+//   - SESSION_ALLOW_PRIVILEGED_TEMPLATES: set to "false" to also reject
+//     templates that opt in with allowPrivileged (default true)
+//   - SESSION_SECURITY_DEFAULTS: set to "true" to apply the hardened
+//     defaults to every template (default false). Template overrides
+//     still apply.
+
+// privilegedTemplatesAllowed reports whether SESSION_ALLOW_PRIVILEGED_TEMPLATES
+// lets templates opt in to privileged settings.
+func privilegedTemplatesAllowed() bool {
+	allowed, err := strconv.ParseBool(os.Getenv("SESSION_ALLOW_PRIVILEGED_TEMPLATES"))
+	return err != nil || allowed
+}
+
+// securityDefaultsEnabled reports whether SESSION_SECURITY_DEFAULTS applies
+// the hardened defaults to every template.
+func securityDefaultsEnabled() bool {
+	enabled, _ := strconv.ParseBool(os.Getenv("SESSION_SECURITY_DEFAULTS"))
+	return enabled
+}
+
+// sessionHardened reports whether sessions of template run with the hardened
+// defaults.
+func sessionHardened(template *streamv1alpha1.Template) bool {
+	if sc := template.Spec.SecurityContext; sc != nil && sc.Hardened {
+		return true
+	}
+	return securityDefaultsEnabled()
+}
+
+// sessionSecurityContext returns the pod and container security contexts of
+// a session of template.
+func sessionSecurityContext(template *streamv1alpha1.Template) (*corev1.PodSecurityContext, *corev1.SecurityContext) {
+	pod := &corev1.PodSecurityContext{}
+	container := &corev1.SecurityContext{}
+	if sessionHardened(template) {
+		uid := int64(defaultSessionUID)
+		pod = &corev1.PodSecurityContext{
+			RunAsNonRoot:   boolPtr(true),
+			RunAsUser:      &uid,
+			RunAsGroup:     &uid,
+			FSGroup:        &uid,
+			SeccompProfile: &corev1.SeccompProfile{Type: corev1.SeccompProfileTypeRuntimeDefault},
+		}
+		container = &corev1.SecurityContext{
+			AllowPrivilegeEscalation: boolPtr(false),
+			Capabilities:             &corev1.Capabilities{Drop: []corev1.Capability{"ALL"}},
+		}
+	}
+
+	if sc := template.Spec.SecurityContext; sc != nil {
+		if sc.Pod != nil {
+			mergePodSecurityContext(pod, sc.Pod)
+		}
+		if sc.Container != nil {
+			mergeContainerSecurityContext(container, sc.Container)
+		}
+	}
+	return pod, container
+}
+
+// mergePodSecurityContext copies the fields set in override onto base.
+func mergePodSecurityContext(base, override *corev1.PodSecurityContext) {
+	override = override.DeepCopy()
+	if override.SELinuxOptions != nil {
+		base.SELinuxOptions = override.SELinuxOptions
+	}
+	if override.WindowsOptions != nil {
+		base.WindowsOptions = override.WindowsOptions
+	}
+	if override.RunAsUser != nil {
+		base.RunAsUser = override.RunAsUser
+	}
+	if override.RunAsGroup != nil {
+		base.RunAsGroup = override.RunAsGroup
+	}
+	if override.RunAsNonRoot != nil {
+		base.RunAsNonRoot = override.RunAsNonRoot
+	}
+	if override.SupplementalGroups != nil {
+		base.SupplementalGroups = override.SupplementalGroups
+	}
+	if override.SupplementalGroupsPolicy != nil {
+		base.SupplementalGroupsPolicy = override.SupplementalGroupsPolicy
+	}
+	if override.FSGroup != nil {
+		base.FSGroup = override.FSGroup
+	}
+	if override.FSGroupChangePolicy != nil {
+		base.FSGroupChangePolicy = override.FSGroupChangePolicy
+	}
+	if override.Sysctls != nil {
+		base.Sysctls = override.Sysctls
+	}
+	if override.SeccompProfile != nil {
+		base.SeccompProfile = override.SeccompProfile
+	}
+	if override.AppArmorProfile != nil {
+		base.AppArmorProfile = override.AppArmorProfile
+	}
+	if override.SELinuxChangePolicy != nil {
+		base.SELinuxChangePolicy = override.SELinuxChangePolicy
+	}
+}
+
+// mergeContainerSecurityContext copies the fields set in override onto base.
+func mergeContainerSecurityContext(base, override *corev1.SecurityContext) {
+	override = override.DeepCopy()
+	if override.Capabilities != nil {
+		// Add and Drop merge separately, so adding a capability keeps the
+		// default drop of ALL
+		if base.Capabilities == nil {
+			base.Capabilities = &corev1.Capabilities{}
+		}
+		if override.Capabilities.Add != nil {
+			base.Capabilities.Add = override.Capabilities.Add
+		}
+		if override.Capabilities.Drop != nil {
+			base.Capabilities.Drop = override.Capabilities.Drop
+		}
+	}
+	if override.Privileged != nil {
+		base.Privileged = override.Privileged
+	}
+	if override.SELinuxOptions != nil {
+		base.SELinuxOptions = override.SELinuxOptions
+	}
+	if override.WindowsOptions != nil {
+		base.WindowsOptions = override.WindowsOptions
+	}
+	if override.RunAsUser != nil {
+		base.RunAsUser = override.RunAsUser
+	}
+	if override.RunAsGroup != nil {
+		base.RunAsGroup = override.RunAsGroup
+	}
+	if override.RunAsNonRoot != nil {
+		base.RunAsNonRoot = override.RunAsNonRoot
+	}
+	if override.ReadOnlyRootFilesystem != nil {
+		base.ReadOnlyRootFilesystem = override.ReadOnlyRootFilesystem
+	}
+	if override.AllowPrivilegeEscalation != nil {
+		base.AllowPrivilegeEscalation = override.AllowPrivilegeEscalation
+	}
+	if override.ProcMount != nil {
+		base.ProcMount = override.ProcMount
+	}
+	if override.SeccompProfile != nil {
+		base.SeccompProfile = override.SeccompProfile
+	}
+	if override.AppArmorProfile != nil {
+		base.AppArmorProfile = override.AppArmorProfile
+	}
+}
+
+// privilegedSettings lists the settings of a template's security context
+// that weaken isolation.
+func privilegedSettings(sc *streamv1alpha1.TemplateSecurityContext) []string {
+	if sc == nil {
+		return nil
+	}
+
+	var found []string
+	if pod := sc.Pod; pod != nil {
+		if pod.RunAsNonRoot != nil && !*pod.RunAsNonRoot {
+			found = append(found, "pod.runAsNonRoot=false")
+		}
+		if pod.RunAsUser != nil && *pod.RunAsUser == 0 {
+			found = append(found, "pod.runAsUser=0")
+		}
+		if pod.SeccompProfile != nil && pod.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+			found = append(found, "pod.seccompProfile=Unconfined")
+		}
+		if len(pod.Sysctls) > 0 {
+			found = append(found, "pod.sysctls")
+		}
+	}
+	if container := sc.Container; container != nil {
+		if container.Privileged != nil && *container.Privileged {
+			found = append(found, "container.privileged=true")
+		}
+		if container.AllowPrivilegeEscalation != nil && *container.AllowPrivilegeEscalation {
+			found = append(found, "container.allowPrivilegeEscalation=true")
+		}
+		if container.Capabilities != nil && len(container.Capabilities.Add) > 0 {
+			found = append(found, "container.capabilities.add")
+		}
+		if container.Capabilities != nil && container.Capabilities.Drop != nil && !dropsAllCapabilities(container.Capabilities.Drop) {
+			// Replaces the default drop of ALL, e.g. capabilities: {drop: []}
+			found = append(found, "container.capabilities.drop without ALL")
+		}
+		if container.RunAsNonRoot != nil && !*container.RunAsNonRoot {
+			found = append(found, "container.runAsNonRoot=false")
+		}
+		if container.RunAsUser != nil && *container.RunAsUser == 0 {
+			found = append(found, "container.runAsUser=0")
+		}
+		if container.SeccompProfile != nil && container.SeccompProfile.Type == corev1.SeccompProfileTypeUnconfined {
+			found = append(found, "container.seccompProfile=Unconfined")
+		}
+		if container.ProcMount != nil && *container.ProcMount == corev1.UnmaskedProcMount {
+			found = append(found, "container.procMount=Unmasked")
+		}
+	}
+	return found
+}
+
+// dropsAllCapabilities reports whether drop contains ALL.
+func dropsAllCapabilities(drop []corev1.Capability) bool {
+	for _, c := range drop {
+		if strings.EqualFold(string(c), "ALL") {
+			return true
+		}
+	}
+	return false
+}
+
+// validateSecurityContext rejects privileged settings the template hasn't
+// opted in to, or that the controller doesn't allow.
+func validateSecurityContext(sc *streamv1alpha1.TemplateSecurityContext, allowPrivileged bool) error {
+	settings := privilegedSettings(sc)
+	if len(settings) == 0 {
+		return nil
+	}
+	if !sc.AllowPrivileged {
+		return fmt.Errorf("securityContext sets %s, which requires allowPrivileged: true", strings.Join(settings, ", "))
+	}
+	if !allowPrivileged {
+		return fmt.Errorf("securityContext sets %s, but privileged templates are disabled (SESSION_ALLOW_PRIVILEGED_TEMPLATES=false)", strings.Join(settings, ", "))
+	}
+	return nil
+}
+
+// boolPtr returns a pointer to b.
+func boolPtr(b bool) *bool {
+	return &b
+}
@@ -83,7 +83,7 @@
 //
 // 4. PersistentVolumeClaim (home-{user}):
 //    - Shared across all sessions for same user
-//    - ReadWriteMany (NFS backed)
+//    - ReadWriteMany (NFS backed) unless the template asks for ReadWriteOnce
 //    - Persists data even when sessions are terminated
 //    - No owner reference (survives session deletion)
 //
@@ -159,7 +159,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"os"
 	"time"
 
 	"github.com/google/uuid"
@@ -173,8 +172,10 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
@@ -223,19 +224,34 @@ import (
 // - Kubernetes optimistic concurrency prevents conflicts
 // - Status updates use separate client with retry
 type SessionReconciler struct {
-	client.Client                // Kubernetes API client
-	Scheme       *runtime.Scheme // Type information for objects
-	NATSConn     *nats.Conn      // NATS connection for publishing status events
-	ControllerID string          // Unique identifier for this controller instance
+	client.Client                     // Kubernetes API client
+	Scheme       *runtime.Scheme      // Type information for objects
+	NATSConn     *nats.Conn           // NATS connection for publishing status events
+	ControllerID string               // Unique identifier for this controller instance
+	Recorder     record.EventRecorder // Records Kubernetes events on Sessions (optional)
+
+	// APIReader reads uncached objects from the API server: the pod events
+	// a startup timeout diagnosis quotes (optional, see startup_timeout.go)
+	APIReader client.Reader
+
+	// capacity caches node allocatable resources (see capacity.go)
+	capacity nodeCapacityCache
+
+	// launches tracks in-flight launches per template (see launch_throttle.go)
+	launches launchTracker
+
+	// generations tracks the Session generations reconciled (see generations.go)
+	generations generationTracker
 }
 
 // setCondition sets or updates a condition on the Session's status.
 //
 // Standard condition types for Sessions:
-//   - "Ready": Session is running and accepting connections
+//   - "Ready": Session pod is ready and the URL is serving (see readiness.go)
 //   - "TemplateResolved": Template was found and validated
 //   - "PVCBound": Persistent volume is bound and mounted
 //   - "DeploymentReady": Deployment is created and running
+//   - "Drifted": Deployment was edited out-of-band (see drift.go)
 //
 // Parameters:
 //   - ctx: Context for API calls
@@ -269,6 +285,14 @@ func (r *SessionReconciler) setCondition(ctx context.Context, session *streamv1a
 	}
 }
 
+// specInvalidReported reports whether the SpecValid condition already
+// records the given validation error, so the warning event and status
+// update are only issued when the problem first appears or changes.
+func specInvalidReported(conditions []metav1.Condition, message string) bool {
+	cond := meta.FindStatusCondition(conditions, "SpecValid")
+	return cond != nil && cond.Status == metav1.ConditionFalse && cond.Message == message
+}
+
 // SessionStatusEvent represents a session status update published to NATS.
 // This struct matches the event type expected by the API backend.
 type SessionStatusEvent struct {
@@ -281,26 +305,37 @@ type SessionStatusEvent struct {
 	PodName      string    `json:"pod_name,omitempty"`
 	Message      string    `json:"message,omitempty"`
 	ControllerID string    `json:"controller_id"`
+	// Ready reports whether a Running session's pod is ready (unset for
+	// other phases)
+	Ready *bool `json:"ready,omitempty"`
+	// Actor is who made the controller change the state on its own (see
+	// StateActorAnnotation)
+	Actor string `json:"actor,omitempty"`
 }
 
 // publishSessionStatus publishes a session status update to NATS so the API can update its database.
 // This is critical for the UI to show the correct session state and enable the Connect button.
 func (r *SessionReconciler) publishSessionStatus(sessionID, status, phase, url, podName, message string) {
+	r.publishSessionEvent(SessionStatusEvent{
+		SessionID: sessionID,
+		Status:    status,
+		Phase:     phase,
+		URL:       url,
+		PodName:   podName,
+		Message:   message,
+	})
+}
+
+// publishSessionEvent publishes a session status event, filling in the
+// event ID, timestamp and controller ID.
+func (r *SessionReconciler) publishSessionEvent(event SessionStatusEvent) {
 	if r.NATSConn == nil {
 		return // NATS not configured, skip publishing
 	}
 
-	event := SessionStatusEvent{
-		EventID:      uuid.New().String(),
-		Timestamp:    time.Now(),
-		SessionID:    sessionID,
-		Status:       status,
-		Phase:        phase,
-		URL:          url,
-		PodName:      podName,
-		Message:      message,
-		ControllerID: r.ControllerID,
-	}
+	event.EventID = uuid.New().String()
+	event.Timestamp = time.Now()
+	event.ControllerID = r.ControllerID
 
 	data, err := json.Marshal(event)
 	if err != nil {
@@ -319,9 +354,19 @@ func (r *SessionReconciler) publishSessionStatus(sessionID, status, phase, url,
 //+kubebuilder:rbac:groups=stream.streamspace.io,resources=sessions/finalizers,verbs=update
 //+kubebuilder:rbac:groups=stream.streamspace.io,resources=templates,verbs=get;list;watch
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=services,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=events,verbs=get;list;create;patch
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshots,verbs=get;list;watch;create;delete
+//+kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshotclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=storage.k8s.io,resources=storageclasses,verbs=get;list;watch
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;delete
 
 // Reconcile is the main reconciliation loop for Session resources.
 //
@@ -381,16 +426,26 @@ func (r *SessionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			// Owner references will automatically delete owned resources (Deployment, Service, Ingress)
 			// No action needed, just log and return
 			log.Info("Session resource not found. Ignoring since object must be deleted")
+			r.launches.release(req.NamespacedName.String())
+			r.generations.forget(req.NamespacedName.String())
 			return ctrl.Result{}, nil
 		}
 		// Other error (API server down, network issue, etc.) - retry
 		log.Error(err, "Failed to get Session")
 		metrics.RecordReconciliation(req.Namespace, "error")
+		metrics.RecordReconcileError(req.Namespace, reconcileErrorReason(err))
 		return ctrl.Result{}, err
 	}
 
 	log.Info("Reconciling Session", "name", session.Name, "state", session.Spec.State)
 
+	// A cached Session older than one already reconciled would scale the
+	// session back to a state it has left (see generations.go)
+	if !r.generations.observe(req.NamespacedName.String(), session.Generation) {
+		log.Info("Skipping stale Session", "generation", session.Generation)
+		return ctrl.Result{RequeueAfter: staleSessionRequeue}, nil
+	}
+
 	// Update metrics for this session - track by user and template for capacity planning
 	// These metrics help answer: "How many sessions does user X have?" and "How popular is template Y?"
 	metrics.RecordSessionByUser(session.Spec.User, session.Namespace, 1)
@@ -402,15 +457,49 @@ func (r *SessionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	if err != nil {
 		log.Error(err, "Failed to get Template")
 		metrics.RecordReconciliation(req.Namespace, "error")
+		metrics.RecordReconcileError(req.Namespace, reconcileErrorReason(err))
 		// Set condition to indicate template was not found
 		r.setCondition(ctx, &session, "TemplateResolved", metav1.ConditionFalse, "TemplateNotFound",
 			fmt.Sprintf("Template '%s' not found in namespace '%s'", session.Spec.Template, session.Namespace))
 		return ctrl.Result{}, err
 	}
 
+	// Fill template-level lifecycle defaults into the session spec when the
+	// session leaves them empty. Persisting them lets the HibernationReconciler
+	// (which only reads the session) enforce the template's policy.
+	if applyTemplateDefaults(&session, template) {
+		if err := r.Update(ctx, &session); err != nil {
+			log.Error(err, "Failed to apply template defaults to Session")
+			metrics.RecordReconciliation(req.Namespace, "error")
+			metrics.RecordReconcileError(req.Namespace, reconcileErrorReason(err))
+			return ctrl.Result{}, err
+		}
+		log.Info("Applied template defaults to Session",
+			"idleTimeout", session.Spec.IdleTimeout,
+			"maxSessionDuration", session.Spec.MaxSessionDuration)
+	}
+
+	// Sessions created before the CRD schema validated durations may still
+	// carry bad values. Surface them rather than failing: the hibernation
+	// controller falls back to its defaults for unparseable durations.
+	// Reported once per distinct problem, not on every reconcile.
+	if err := session.Spec.Validate(); err != nil {
+		if !specInvalidReported(session.Status.Conditions, err.Error()) {
+			log.Info("Session spec is invalid", "error", err.Error())
+			r.recordEvent(&session, corev1.EventTypeWarning, "InvalidSpec", err.Error())
+			r.setCondition(ctx, &session, "SpecValid", metav1.ConditionFalse, "InvalidSpec", err.Error())
+		}
+	} else if meta.IsStatusConditionFalse(session.Status.Conditions, "SpecValid") {
+		r.setCondition(ctx, &session, "SpecValid", metav1.ConditionTrue, "Valid", "Session spec is valid")
+	}
+
 	// Route to state-specific handler based on desired state
 	// Each handler is responsible for making actual state match desired state
 	var result ctrl.Result
+	if session.Spec.State != "running" {
+		// A hibernated or terminated session no longer holds a launch slot
+		r.launches.release(req.NamespacedName.String())
+	}
 	switch session.Spec.State {
 	case "running":
 		// Create/update resources, scale up Deployment to 1 replica
@@ -422,17 +511,21 @@ func (r *SessionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		// Delete all resources except PVC (user data persists)
 		result, err = r.handleTerminated(ctx, &session)
 	default:
-		// Unknown state - this shouldn't happen due to CRD validation
-		// But handle gracefully just in case
+		// Unknown state - this shouldn't happen due to CRD validation, and
+		// Validate above has already reported it on the Session. Nothing to
+		// do until the spec is fixed, which triggers a new reconcile.
 		log.Info("Unknown state", "state", session.Spec.State)
-		// TODO: Add webhook validation to reject invalid states
 		return ctrl.Result{}, nil
 	}
 
+	// Handlers may have updated the spec (template defaults)
+	r.generations.observe(req.NamespacedName.String(), session.Generation)
+
 	// Record reconciliation result in Prometheus metrics
 	// This helps track error rates and success rates over time
 	if err != nil {
 		metrics.RecordReconciliation(req.Namespace, "error")
+		metrics.RecordReconcileError(req.Namespace, reconcileErrorReason(err))
 	} else {
 		metrics.RecordReconciliation(req.Namespace, "success")
 	}
@@ -469,9 +562,9 @@ func (r *SessionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 //
 // RESOURCE NAMING:
 //
-// - Deployment: ss-{user}-{template} (e.g., "ss-alice-firefox")
+// - Deployment: ss-{user}-{template} (e.g., "ss-alice-firefox"), ss-{session} for forks
 // - Service: {deployment}-svc (e.g., "ss-alice-firefox-svc")
-// - PVC: home-{user} (e.g., "home-alice")
+// - PVC: home-{user} (e.g., "home-alice"), home-{session} for forks (see home_fork.go)
 // - Ingress: {deployment} (e.g., "ss-alice-firefox")
 //
 // ERROR HANDLING:
@@ -489,10 +582,12 @@ func (r *SessionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 // TODO:
 //   - Add resource quota checking before creating Deployment
 //   - Implement admission webhooks for real-time validation
-//   - Add pod security policies (non-root, dropped capabilities)
 func (r *SessionReconciler) handleRunning(ctx context.Context, session *streamv1alpha1.Session, template *streamv1alpha1.Template) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
+	// Woken while still draining: keep the connections that are left
+	r.cancelDrain(ctx, session)
+
 	// BUG FIX: Validate template before creating session resources
 	// Previously controller would create deployment even with invalid templates
 	if !template.Status.Valid {
@@ -527,29 +622,134 @@ func (r *SessionReconciler) handleRunning(ctx context.Context, session *streamv1
 		return ctrl.Result{}, err
 	}
 
+	// Parameter values must match the template's schema. The API checks them
+	// at launch, but Sessions can also be applied directly.
+	if _, err := resolveParameters(template, session); err != nil {
+		log.Error(err, "Invalid session parameters")
+		session.Status.Phase = "Failed"
+		r.setCondition(ctx, session, readyCondition, metav1.ConditionFalse, "InvalidParameters", err.Error())
+		return ctrl.Result{}, nil
+	}
+
+	// A launch that timed out stays Failed until the spec changes (see
+	// startup_timeout.go)
+	if startupFailed(session) {
+		return ctrl.Result{}, r.cleanUpFailedStartup(ctx, session)
+	}
+
 	// Generate consistent names for all resources
 	// Using predictable naming makes debugging easier and avoids resource sprawl
-	deploymentName := fmt.Sprintf("ss-%s-%s", session.Spec.User, session.Spec.Template)
+	deploymentName := sessionDeploymentName(session)
 	serviceName := fmt.Sprintf("%s-svc", deploymentName)
 
-	// --- STEP 1: Ensure Deployment exists and is running ---
+	// --- STEP 0: Isolate the session with a NetworkPolicy (if enabled) ---
+
+	// Created before the pod so it never runs unrestricted
+	if networkPoliciesEnabled() {
+		if err := r.ensureNetworkPolicy(ctx, session, template); err != nil {
+			log.Error(err, "Failed to ensure NetworkPolicy")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// --- STEP 0b: Fork the source session's home volume (if forking) ---
+
+	// The pod must not start before the fork holds the source's files
+	if session.Spec.PersistentHome && session.Spec.ForkFrom != "" {
+		ready, err := r.ensureForkedHome(ctx, session)
+		if err != nil {
+			log.Error(err, "Failed to fork home volume", "source", session.Spec.ForkFrom)
+			return ctrl.Result{}, err
+		}
+		if !ready {
+			return ctrl.Result{RequeueAfter: forkPollInterval}, nil
+		}
+	}
+
+	// --- STEP 0c: Create the template's companion ConfigMaps and Secrets ---
+
+	// The pod mounts them, so they must exist before it starts
+	if len(template.Spec.CompanionResources) > 0 {
+		if err := r.ensureCompanionResources(ctx, session, template); err != nil {
+			log.Error(err, "Failed to ensure companion resources")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// --- STEP 1: Ensure Deployment (or claimed warm pod) exists and is running ---
+
+	// A ReadWriteOnce home pins the session to the node using it, whichever
+	// template created the volume
+	homeRWO, err := r.sessionHomeReadWriteOnce(ctx, session, template)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Report an evicted or preempted pod before it is replaced, so the user
+	// learns why their session went away
+	if err := r.detectEviction(ctx, session); err != nil {
+		log.Error(err, "Failed to check session pod for eviction")
+		return ctrl.Result{}, err
+	}
+
+	// Sessions launched from the template's warm pool run in a claimed pod
+	// instead of a Deployment (see warmpool_controller.go)
+	podName := deploymentName
+	warmPod, err := r.findClaimedWarmPod(ctx, session)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
 
 	// Check if deployment already exists
 	deployment := &appsv1.Deployment{}
-	err := r.Get(ctx, types.NamespacedName{Name: deploymentName, Namespace: session.Namespace}, deployment)
+	if warmPod == nil {
+		err = r.Get(ctx, types.NamespacedName{Name: deploymentName, Namespace: session.Namespace}, deployment)
+	}
 
-	if errors.IsNotFound(err) {
-		// Deployment doesn't exist - create a new one
-		// This happens when a session is first created or after termination
-		deployment = r.createDeployment(session, template)
-		if err := r.Create(ctx, deployment); err != nil {
-			log.Error(err, "Failed to create Deployment")
-			// Set condition to indicate deployment creation failed
-			r.setCondition(ctx, session, "DeploymentReady", metav1.ConditionFalse, "DeploymentCreationFailed",
-				fmt.Sprintf("Failed to create deployment: %v", err))
-			return ctrl.Result{}, err
+	if warmPod != nil {
+		// Running in a claimed warm pod, no Deployment needed
+		podName = warmPod.Name
+	} else if errors.IsNotFound(err) {
+		// New launch - try the warm pool before creating from scratch
+		warmPod, err = r.claimWarmPod(ctx, session, template)
+		if err != nil {
+			log.Error(err, "Failed to claim warm pod, creating Deployment")
+			warmPod = nil
+		}
+
+		if warmPod != nil {
+			log.Info("Claimed warm pod", "pod", warmPod.Name)
+			podName = warmPod.Name
+		} else {
+			// Fail fast on requests no node could ever schedule, instead of
+			// leaving the pod Pending forever (see capacity.go)
+			if err := r.checkCapacity(ctx, session, template); err != nil {
+				log.Info("Session cannot be scheduled on any node", "reason", err.Error())
+				session.Status.Phase = "Failed"
+				r.recordEvent(session, corev1.EventTypeWarning, "Unschedulable", err.Error())
+				r.setCondition(ctx, session, readyCondition, metav1.ConditionFalse, "Unschedulable", err.Error())
+				// Checked again once the node cache refreshes, in case larger nodes joined
+				return ctrl.Result{RequeueAfter: capacityRefreshInterval()}, nil
+			}
+
+			// Hold the launch back while too many sessions of this template
+			// are still pulling and starting (see launch_throttle.go)
+			if queued, result := r.throttleLaunch(ctx, session, template); queued {
+				return result, nil
+			}
+
+			// Deployment doesn't exist - create a new one
+			// This happens when a session is first created or after termination
+			deployment = r.createDeployment(session, template, homeRWO)
+			if err := r.Create(ctx, deployment); err != nil {
+				log.Error(err, "Failed to create Deployment")
+				// Set condition to indicate deployment creation failed
+				r.setCondition(ctx, session, "DeploymentReady", metav1.ConditionFalse, "DeploymentCreationFailed",
+					fmt.Sprintf("Failed to create deployment: %v", err))
+				return ctrl.Result{}, err
+			}
+			log.Info("Created Deployment", "name", deploymentName)
 		}
-		log.Info("Created Deployment", "name", deploymentName)
 	} else if err != nil {
 		// API error (not 404) - could be transient, retry
 		return ctrl.Result{}, err
@@ -559,15 +759,33 @@ func (r *SessionReconciler) handleRunning(ctx context.Context, session *streamv1
 		if deployment.Spec.Replicas == nil || *deployment.Spec.Replicas == 0 {
 			// Session was hibernated, wake it up by scaling to 1 replica
 			deployment.Spec.Replicas = int32Ptr(1)
+			resized := applySessionResources(session, template, deployment)
+			applyNodePin(session, template, homeRWO, deployment)
 			if err := r.Update(ctx, deployment); err != nil {
 				log.Error(err, "Failed to scale up Deployment")
 				return ctrl.Result{}, err
 			}
 			log.Info("Scaled up Deployment (waking from hibernation)", "name", deploymentName)
+			if resized {
+				r.recordEvent(session, corev1.EventTypeNormal, "ResourcesUpdated",
+					fmt.Sprintf("Session woke with updated resources (requests cpu=%s, memory=%s)",
+						session.Spec.Resources.Requests.Cpu(), session.Spec.Resources.Requests.Memory()))
+			}
 			// Record wake event in metrics for cost analysis
 			metrics.RecordWake(session.Namespace)
+		} else if applyNodePin(session, template, homeRWO, deployment) {
+			// Pinned, re-pinned or unpinned while running: restart the pod
+			// where it now belongs
+			if err := r.Update(ctx, deployment); err != nil {
+				log.Error(err, "Failed to update Deployment node pin")
+				return ctrl.Result{}, err
+			}
+			log.Info("Updated Deployment node pin", "name", deploymentName, "node", session.Spec.PinToNode)
+		} else if err := r.reconcileDrift(ctx, session, deployment); err != nil {
+			// Deployment already running - make sure nobody edited it behind our back
+			log.Error(err, "Failed to reconcile Deployment drift")
+			return ctrl.Result{}, err
 		}
-		// else: Deployment already running with 1 replica, nothing to do
 	}
 
 	// --- STEP 2: Ensure Service exists for pod networking ---
@@ -592,7 +810,8 @@ func (r *SessionReconciler) handleRunning(ctx context.Context, session *streamv1
 
 	// PVC is shared across all sessions for the same user
 	// It persists even when sessions are deleted, allowing data to survive
-	if session.Spec.PersistentHome {
+	// (forks have their own PVC, created in STEP 0b)
+	if session.Spec.PersistentHome && session.Spec.ForkFrom == "" {
 		pvcName := fmt.Sprintf("home-%s", session.Spec.User)
 		pvc := &corev1.PersistentVolumeClaim{}
 		err = r.Get(ctx, types.NamespacedName{Name: pvcName, Namespace: session.Namespace}, pvc)
@@ -600,7 +819,7 @@ func (r *SessionReconciler) handleRunning(ctx context.Context, session *streamv1
 		if errors.IsNotFound(err) {
 			// PVC doesn't exist - create one for this user
 			// This is the first session for this user, or PVC was manually deleted
-			pvc = r.createUserPVC(session)
+			pvc = r.createUserPVC(session, template)
 			if err := r.Create(ctx, pvc); err != nil {
 				log.Error(err, "Failed to create PVC")
 				// PVC creation failure is serious - pod won't start without it
@@ -635,20 +854,60 @@ func (r *SessionReconciler) handleRunning(ctx context.Context, session *streamv1
 	}
 	// else: Ingress already exists, no action needed
 
-	// --- STEP 5: Update Session status to reflect running state ---
+	// --- STEP 5: Time out a stuck launch, wait for a rescheduled pod and detect a crash-looping container ---
+
+	// A first launch that isn't ready in time fails with a diagnosis instead
+	// of staying Pending forever (see startup_timeout.go)
+	if warmPod == nil {
+		if handled, result, err := r.checkStartupTimeout(ctx, session, template, deployment); handled || err != nil {
+			return result, err
+		}
+	}
+
+	// An evicted session stays Evicted until its replacement pod is ready
+	if handled, result, err := r.awaitRescheduledPod(ctx, session); handled || err != nil {
+		return result, err
+	}
 
-	// Get ingress domain from environment (configured at deployment time)
-	// This determines the URL format: https://{session}.{domain}
-	ingressDomain := os.Getenv("INGRESS_DOMAIN")
-	if ingressDomain == "" {
-		ingressDomain = "streamspace.local" // Default for development
+	// A crashing container keeps being restarted by Kubernetes; surface it
+	// as CrashLooping instead of reporting the session as Running
+	if handled, result, err := r.detectCrashLoop(ctx, session); handled || err != nil {
+		return result, err
 	}
 
+	// --- STEP 6: Update Session status to reflect running state ---
+
+	// Ingress settings are configured at deployment time and determine the
+	// URL format: https://{session}.{domain} or https://{domain}/sessions/{session}/
+	routing := ingressRoutingFromEnv()
+
 	// Update status fields to reflect current state
 	// Status updates are separate from spec updates to avoid conflicts
 	session.Status.Phase = "Running"
-	session.Status.PodName = deploymentName // For debugging (kubectl logs, exec)
-	session.Status.URL = fmt.Sprintf("https://%s.%s", session.Name, ingressDomain)
+	session.Status.ObservedGeneration = session.Generation
+	session.Status.PodName = podName // For debugging (kubectl logs, exec)
+	session.Status.URL = routing.sessionURL(session)
+	session.Status.Priority = sessionPriority(session, template)
+	session.Status.StreamingFeatures = streamingFeatures(template)
+	if warmPod != nil {
+		session.Status.EffectiveResources = effectiveResources(warmPod.Spec.Containers)
+	} else {
+		session.Status.EffectiveResources = effectiveResources(deployment.Spec.Template.Spec.Containers)
+	}
+
+	// A pinned session can only start once its node can take the pod
+	if err := r.updatePinnedCondition(ctx, session); err != nil {
+		log.Error(err, "Failed to check pinned node", "node", session.Spec.PinToNode)
+	}
+
+	// The URL exists before the app behind it is serving; the API only
+	// hands it out once the Ready condition is True
+	launching := session.Status.ReadyAt == nil && !meta.IsStatusConditionTrue(session.Status.Conditions, readyCondition)
+	ready, err := r.updateReadyCondition(ctx, session)
+	if err != nil {
+		log.Error(err, "Failed to check session pod readiness")
+	}
+
 	if err := r.Status().Update(ctx, session); err != nil {
 		log.Error(err, "Failed to update Session status")
 		// Status update failures are not critical - don't fail reconciliation
@@ -656,9 +915,31 @@ func (r *SessionReconciler) handleRunning(ctx context.Context, session *streamv1
 		return ctrl.Result{}, err
 	}
 
+	// Launch latency is recorded once readyAt is persisted, so a failed
+	// status update doesn't count the same launch twice
+	if ready && launching {
+		r.recordStartup(ctx, session, warmPod != nil)
+	}
+	if ready {
+		r.launches.release(types.NamespacedName{Namespace: session.Namespace, Name: session.Name}.String())
+	}
+
 	// Publish status to NATS so the API can update its database
-	// This enables the Connect button in the UI
-	r.publishSessionStatus(session.Name, "running", "Running", session.Status.URL, session.Status.PodName, "Session is running")
+	// This enables the Connect button in the UI. Readiness lets the API fire
+	// session.ready webhooks only once the URL is serving.
+	message := "Session is running"
+	if !ready {
+		message = "Session is starting"
+	}
+	r.publishSessionEvent(SessionStatusEvent{
+		SessionID: session.Name,
+		Status:    "running",
+		Phase:     "Running",
+		URL:       session.Status.URL,
+		PodName:   session.Status.PodName,
+		Message:   message,
+		Ready:     &ready,
+	})
 
 	// Record session state in Prometheus for monitoring
 	metrics.RecordSessionState("running", session.Namespace, 1)
@@ -669,8 +950,13 @@ func (r *SessionReconciler) handleRunning(ctx context.Context, session *streamv1
 		"user", session.Spec.User,
 		"template", session.Spec.Template,
 		"url", session.Status.URL,
+		"ready", ready,
 	)
 
+	// Warm pods aren't owned by the Session, so poll until the pod is ready
+	if !ready {
+		return ctrl.Result{RequeueAfter: readinessRecheckInterval}, nil
+	}
 	return ctrl.Result{}, nil
 }
 
@@ -728,6 +1014,12 @@ func (r *SessionReconciler) handleRunning(ctx context.Context, session *streamv1
 //   - Manual: User explicitly hibernated
 //   - Auto-idle: HibernationReconciler triggered
 //
+// DRAINING:
+//
+// With SESSION_HIBERNATION_GRACE set, the session is "Hibernating" until its
+// connections close or the grace passes, and only then scaled down (see
+// hibernation_grace.go).
+//
 // TODO:
 //   - Add pre-hibernation webhook to allow cleanup scripts
 //   - Optionally delete pod immediately instead of waiting for scale-down
@@ -735,7 +1027,12 @@ func (r *SessionReconciler) handleRunning(ctx context.Context, session *streamv1
 func (r *SessionReconciler) handleHibernated(ctx context.Context, session *streamv1alpha1.Session) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
-	deploymentName := fmt.Sprintf("ss-%s-%s", session.Spec.User, session.Spec.Template)
+	// Let connected users and the app finish before the pod goes away
+	if done, result, err := r.drainBeforeHibernation(ctx, session); !done || err != nil {
+		return result, err
+	}
+
+	deploymentName := sessionDeploymentName(session)
 
 	// Scale deployment to 0 replicas to stop the pod
 	deployment := &appsv1.Deployment{}
@@ -755,15 +1052,28 @@ func (r *SessionReconciler) handleHibernated(ctx context.Context, session *strea
 	}
 	// else: Deployment already at 0 replicas or doesn't exist (idempotent)
 
+	// Warm-launched sessions have no Deployment to scale - delete the pod
+	if err := r.releaseWarmPod(ctx, session); err != nil {
+		log.Error(err, "Failed to delete warm pod")
+		return ctrl.Result{}, err
+	}
+
 	// Update Session status to reflect hibernated state
 	session.Status.Phase = "Hibernated"
+	session.Status.ObservedGeneration = session.Generation
 	if err := r.Status().Update(ctx, session); err != nil {
 		log.Error(err, "Failed to update Session status")
 		return ctrl.Result{}, err
 	}
 
 	// Publish status to NATS so the API can update its database
-	r.publishSessionStatus(session.Name, "hibernated", "Hibernated", "", "", "Session is hibernated")
+	r.publishSessionEvent(SessionStatusEvent{
+		SessionID: session.Name,
+		Status:    "hibernated",
+		Phase:     "Hibernated",
+		Message:   "Session is hibernated",
+		Actor:     session.Annotations[streamv1alpha1.StateActorAnnotation],
+	})
 
 	// Record session state in Prometheus for dashboards
 	metrics.RecordSessionState("hibernated", session.Namespace, 1)
@@ -838,7 +1148,7 @@ func (r *SessionReconciler) handleHibernated(ctx context.Context, session *strea
 func (r *SessionReconciler) handleTerminated(ctx context.Context, session *streamv1alpha1.Session) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
 
-	deploymentName := fmt.Sprintf("ss-%s-%s", session.Spec.User, session.Spec.Template)
+	deploymentName := sessionDeploymentName(session)
 
 	// Delete deployment explicitly (Service/Ingress will be garbage collected via ownerReferences)
 	deployment := &appsv1.Deployment{}
@@ -854,8 +1164,14 @@ func (r *SessionReconciler) handleTerminated(ctx context.Context, session *strea
 	}
 	// else: Deployment already deleted or never existed (idempotent)
 
+	if err := r.releaseWarmPod(ctx, session); err != nil {
+		log.Error(err, "Failed to delete warm pod")
+		return ctrl.Result{}, err
+	}
+
 	// Update Session status to reflect terminated state
 	session.Status.Phase = "Terminated"
+	session.Status.ObservedGeneration = session.Generation
 	if err := r.Status().Update(ctx, session); err != nil {
 		log.Error(err, "Failed to update Session status")
 		return ctrl.Result{}, err
@@ -922,6 +1238,8 @@ func (r *SessionReconciler) handleTerminated(ctx context.Context, session *strea
 //   2. Template.Spec.DefaultResources (template default)
 //   3. No limits (Kubernetes defaults)
 //
+// Requests are then lowered for overcommitted categories (see overcommit.go).
+//
 // SECURITY:
 //
 // TODO: Add security enhancements:
@@ -936,8 +1254,8 @@ func (r *SessionReconciler) handleTerminated(ctx context.Context, session *strea
 //   - Ensures Deployment is deleted when Session is deleted
 //   - Prevents orphaned resources
 //   - Enables kubectl tree view
-func (r *SessionReconciler) createDeployment(session *streamv1alpha1.Session, template *streamv1alpha1.Template) *appsv1.Deployment {
-	name := fmt.Sprintf("ss-%s-%s", session.Spec.User, session.Spec.Template)
+func (r *SessionReconciler) createDeployment(session *streamv1alpha1.Session, template *streamv1alpha1.Template, homeRWO bool) *appsv1.Deployment {
+	name := sessionDeploymentName(session)
 
 	// Build standard labels for resource identification and filtering
 	labels := map[string]string{
@@ -964,6 +1282,11 @@ func (r *SessionReconciler) createDeployment(session *streamv1alpha1.Session, te
 		vncPort = int32(template.Spec.VNC.Port)
 	}
 
+	// Substitute the session's parameters into env values and args
+	// (already validated by Reconcile, so an error can't occur here)
+	params, _ := resolveParameters(template, session)
+	env := parameterizedEnv(template.Spec.Env, params)
+
 	// Build container specification
 	// This defines what runs inside the pod
 	container := corev1.Container{
@@ -976,9 +1299,23 @@ func (r *SessionReconciler) createDeployment(session *streamv1alpha1.Session, te
 				Protocol:      corev1.ProtocolTCP,
 			},
 		},
-		Env: template.Spec.Env, // Environment variables from template
+		Env:  env,                                             // Environment variables from template
+		Args: parameterizedArgs(template.Spec.Args, params), // Entrypoint args from template
 	}
 
+	// Apps that build absolute URLs need to know the external path they're
+	// served under when sessions are routed by path
+	if routing := ingressRoutingFromEnv(); routing.Mode == ingressRoutingPath {
+		container.Env = append(append([]corev1.EnvVar{}, env...), corev1.EnvVar{
+			Name:  "STREAMSPACE_BASE_PATH",
+			Value: routing.basePath(session),
+		})
+	}
+
+	// Clipboard, audio and file transfer settings, after security policy
+	// (see streaming_features.go)
+	container.Env = append(append([]corev1.EnvVar{}, container.Env...), streamingFeatureEnv(streamingFeatures(template))...)
+
 	// Apply resource limits/requests in priority order
 	// Session-specific resources override template defaults
 	if len(session.Spec.Resources.Requests) > 0 || len(session.Spec.Resources.Limits) > 0 {
@@ -990,15 +1327,36 @@ func (r *SessionReconciler) createDeployment(session *streamv1alpha1.Session, te
 	}
 	// else: No limits specified, use Kubernetes defaults (unrestricted)
 
+	// Request less than the limit for overcommitted categories (see overcommit.go)
+	container.Resources = overcommitPolicyFromEnv().apply(template, container.Resources)
+
+	// Run hardened unless the template relaxes it (see security_context.go)
+	podSecurity, containerSecurity := sessionSecurityContext(template)
+	container.SecurityContext = containerSecurity
+
 	// Build pod specification
 	podSpec := corev1.PodSpec{
-		Containers: []corev1.Container{container},
+		Containers:       []corev1.Container{container},
+		ImagePullSecrets: template.Spec.ImagePullSecrets, // Private registry credentials
+		SecurityContext:  podSecurity,
+	}
+
+	// Apps still need a writable /tmp on a read-only root filesystem
+	if containerSecurity.ReadOnlyRootFilesystem != nil && *containerSecurity.ReadOnlyRootFilesystem {
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      "tmp",
+			MountPath: "/tmp",
+		})
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name:         "tmp",
+			VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+		})
 	}
 
 	// Add persistent volume if user requested persistent home directory
 	// This allows user data to survive session termination
 	if session.Spec.PersistentHome {
-		pvcName := fmt.Sprintf("home-%s", session.Spec.User)
+		pvcName := homePVCName(session)
 
 		// Add volume mount to container (mount PVC at /config)
 		// LinuxServer.io images use /config as the persistent directory
@@ -1008,21 +1366,31 @@ func (r *SessionReconciler) createDeployment(session *streamv1alpha1.Session, te
 		})
 
 		// Add volume definition to pod spec (reference to PVC)
-		podSpec.Volumes = []corev1.Volume{
-			{
-				Name: "user-home",
-				VolumeSource: corev1.VolumeSource{
-					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
-						ClaimName: pvcName, // References existing or to-be-created PVC
-					},
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: "user-home",
+			VolumeSource: corev1.VolumeSource{
+				PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+					ClaimName: pvcName, // References existing or to-be-created PVC
 				},
 			},
-		}
+		})
 	}
 
+	// Mount the template's companion ConfigMaps and Secrets
+	applyCompanionResources(session, template, &podSpec, &container)
+
 	// Update pod spec with modified container (container was modified after initial podSpec creation)
 	podSpec.Containers[0] = container
 
+	// Co-locate with the user's other sessions if the template asks for it
+	// (or its home volume is ReadWriteOnce and can't follow us elsewhere),
+	// unless an operator pinned the session to a node (see pinning.go)
+	podSpec.Affinity = sessionAffinity(session, template, homeRWO)
+
+	// Map the session's priority to its PriorityClass, so the scheduler can
+	// preempt lower-priority sessions when the cluster is full
+	podSpec.PriorityClassName = priorityClassName(sessionPriority(session, template))
+
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      name,
@@ -1034,6 +1402,7 @@ func (r *SessionReconciler) createDeployment(session *streamv1alpha1.Session, te
 		},
 		Spec: appsv1.DeploymentSpec{
 			Replicas: int32Ptr(1),
+			Strategy: sessionDeploymentStrategy(session, template, homeRWO),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: labels,
 			},
@@ -1082,7 +1451,7 @@ func (r *SessionReconciler) createDeployment(session *streamv1alpha1.Session, te
 //
 // Service has owner reference to Session for automatic cleanup.
 func (r *SessionReconciler) createService(session *streamv1alpha1.Session, template *streamv1alpha1.Template) *corev1.Service {
-	deploymentName := fmt.Sprintf("ss-%s-%s", session.Spec.User, session.Spec.Template)
+	deploymentName := sessionDeploymentName(session)
 	serviceName := fmt.Sprintf("%s-svc", deploymentName)
 	labels := map[string]string{
 		"app":      "streamspace-session",
@@ -1130,13 +1499,130 @@ func (r *SessionReconciler) createService(session *streamv1alpha1.Session, templ
 	return service
 }
 
+// sessionDeploymentStrategy returns the update strategy of a session's
+// Deployment.
+//
+// Deployments default to RollingUpdate, which starts the replacement pod
+// before stopping the old one. With a ReadWriteOnce home volume the new pod
+// may be unable to mount it until the old pod releases it, so the rollout
+// gets stuck: those sessions default to Recreate. Whether the home is
+// ReadWriteOnce (homeRWO) comes from the live PVC, see
+// sessionHomeReadWriteOnce. Templates can choose either explicitly.
+func sessionDeploymentStrategy(session *streamv1alpha1.Session, template *streamv1alpha1.Template, homeRWO bool) appsv1.DeploymentStrategy {
+	strategy := template.Spec.DeploymentStrategy
+	if strategy == "" {
+		strategy = appsv1.RollingUpdateDeploymentStrategyType
+		if session.Spec.PersistentHome && homeRWO {
+			strategy = appsv1.RecreateDeploymentStrategyType
+		}
+	}
+	return appsv1.DeploymentStrategy{Type: strategy}
+}
+
+// userSessionAffinity builds the pod affinity that places a session next to
+// the same user's other running sessions.
+//
+// AFFINITY MODES (Template.Spec.SessionAffinity):
+//
+//   - None (default): No affinity, returns nil
+//   - Preferred: Soft affinity - the scheduler tries the user's node first
+//   - Required: Hard affinity - only nodes already running a session of this
+//     user. If the user has no running sessions, Kubernetes lets the pod
+//     schedule anywhere because it matches its own affinity term.
+//
+// READWRITEONCE HOME VOLUMES:
+//
+// With the default ReadWriteMany home PVC every session can mount it from
+// any node, so affinity is only an optimisation. A ReadWriteOnce PVC can
+// only be attached to one node: a second session scheduled elsewhere would
+// stay Pending forever. When the session's home is ReadWriteOnce (homeRWO,
+// see sessionHomeReadWriteOnce) Required affinity is forced whenever
+// persistent home is enabled.
+func userSessionAffinity(session *streamv1alpha1.Session, template *streamv1alpha1.Template, homeRWO bool) *corev1.Affinity {
+	mode := template.Spec.SessionAffinity
+	if session.Spec.PersistentHome && homeRWO {
+		mode = streamv1alpha1.SessionAffinityRequired
+	}
+
+	term := corev1.PodAffinityTerm{
+		LabelSelector: &metav1.LabelSelector{
+			MatchLabels: map[string]string{
+				"app":  "streamspace-session",
+				"user": session.Spec.User,
+			},
+		},
+		TopologyKey: "kubernetes.io/hostname",
+	}
+
+	switch mode {
+	case streamv1alpha1.SessionAffinityRequired:
+		return &corev1.Affinity{
+			PodAffinity: &corev1.PodAffinity{
+				RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{term},
+			},
+		}
+	case streamv1alpha1.SessionAffinityPreferred:
+		return &corev1.Affinity{
+			PodAffinity: &corev1.PodAffinity{
+				PreferredDuringSchedulingIgnoredDuringExecution: []corev1.WeightedPodAffinityTerm{
+					{Weight: 100, PodAffinityTerm: term},
+				},
+			},
+		}
+	default:
+		return nil
+	}
+}
+
+// sessionHomeReadWriteOnce reports whether the session's home volume can
+// only be attached to one node.
+//
+// The user's home is shared by all their sessions and keeps the access mode
+// of the template that created it, so the live PVC decides, not the
+// session's template. Before the PVC exists the template decides, since
+// this session will create it (see createUserPVC).
+func (r *SessionReconciler) sessionHomeReadWriteOnce(ctx context.Context, session *streamv1alpha1.Session, template *streamv1alpha1.Template) (bool, error) {
+	if !session.Spec.PersistentHome {
+		return false, nil
+	}
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := r.Get(ctx, types.NamespacedName{Name: homePVCName(session), Namespace: session.Namespace}, pvc)
+	if errors.IsNotFound(err) {
+		return homeReadWriteOnce(template, nil), nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get home volume: %w", err)
+	}
+	return homeReadWriteOnce(template, pvc), nil
+}
+
+// homeReadWriteOnce reports whether a home volume is ReadWriteOnce: from
+// the access modes of pvc, or, if it doesn't exist yet (nil), the mode the
+// template would create it with.
+func homeReadWriteOnce(template *streamv1alpha1.Template, pvc *corev1.PersistentVolumeClaim) bool {
+	modes := []corev1.PersistentVolumeAccessMode{template.Spec.HomeVolumeAccessMode}
+	if pvc != nil {
+		modes = pvc.Spec.AccessModes
+	}
+	rwo := false
+	for _, mode := range modes {
+		switch mode {
+		case corev1.ReadWriteMany:
+			return false
+		case corev1.ReadWriteOnce, corev1.ReadWriteOncePod:
+			rwo = true
+		}
+	}
+	return rwo
+}
+
 // createUserPVC constructs a PersistentVolumeClaim for user's home directory.
 //
 // PVC DESIGN:
 //
 //   - Shared across all sessions for the same user
 //   - Persists even when sessions are deleted
-//   - ReadWriteMany access mode (requires NFS or similar)
+//   - ReadWriteMany access mode by default (requires NFS or similar)
 //   - No owner reference (intentionally survives session deletion)
 //
 // NAMING CONVENTION:
@@ -1146,11 +1632,17 @@ func (r *SessionReconciler) createService(session *streamv1alpha1.Session, templ
 //
 // ACCESS MODE:
 //
-// ReadWriteMany is required because:
+// ReadWriteMany is the default because:
 //   - User might have multiple concurrent sessions
 //   - Each session mounts the same PVC
 //   - Requires distributed filesystem (NFS, CephFS, GlusterFS)
 //
+// Templates can set homeVolumeAccessMode: ReadWriteOnce for storage without
+// RWX support. The user's sessions are then pinned to one node (see
+// userSessionAffinity), whatever their template. Only the template that
+// creates the PVC decides its access mode; existing PVCs are never changed
+// (see sessionHomeReadWriteOnce).
+//
 // CAPACITY:
 //
 //   - Default: 50Gi per user
@@ -1171,7 +1663,7 @@ func (r *SessionReconciler) createService(session *streamv1alpha1.Session, templ
 //   - Per-user storage quotas
 //   - Encryption at rest
 //   - Access auditing
-func (r *SessionReconciler) createUserPVC(session *streamv1alpha1.Session) *corev1.PersistentVolumeClaim {
+func (r *SessionReconciler) createUserPVC(session *streamv1alpha1.Session, template *streamv1alpha1.Template) *corev1.PersistentVolumeClaim {
 	pvcName := fmt.Sprintf("home-%s", session.Spec.User)
 	labels := map[string]string{
 		"app":  "streamspace-user-home",
@@ -1181,16 +1673,25 @@ func (r *SessionReconciler) createUserPVC(session *streamv1alpha1.Session) *core
 	// Default home directory size
 	storageSize := "50Gi"
 
+	accessMode := corev1.ReadWriteMany // NFS support
+	if template.Spec.HomeVolumeAccessMode != "" {
+		accessMode = template.Spec.HomeVolumeAccessMode
+	}
+
 	pvc := &corev1.PersistentVolumeClaim{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      pvcName,
 			Namespace: session.Namespace,
 			Labels:    labels,
+			// Lets a cancelled launch remove the PVC it just created
+			Annotations: map[string]string{
+				streamv1alpha1.HomeCreatedByAnnotation: session.Name,
+			},
 			// Note: No owner reference - PVC persists across sessions
 		},
 		Spec: corev1.PersistentVolumeClaimSpec{
 			AccessModes: []corev1.PersistentVolumeAccessMode{
-				corev1.ReadWriteMany, // NFS support
+				accessMode,
 			},
 			Resources: corev1.VolumeResourceRequirements{
 				Requests: corev1.ResourceList{
@@ -1210,6 +1711,7 @@ func (r *SessionReconciler) createUserPVC(session *streamv1alpha1.Session) *core
 // Exposes the session to users via HTTPS URL:
 //   - Hostname: {session-name}.{ingress-domain}
 //   - Example: https://alice-firefox.streamspace.local
+//   - Or, with INGRESS_ROUTING_MODE=path: https://streamspace.local/sessions/alice-firefox/
 //   - Routes traffic to Service → Pod
 //
 // INGRESS CONTROLLER:
@@ -1224,12 +1726,15 @@ func (r *SessionReconciler) createUserPVC(session *streamv1alpha1.Session) *core
 //   - Hostname: {session-name}.{ingress-domain}
 //   - Session name: User-provided (must be DNS-safe)
 //   - Ingress domain: Configured via INGRESS_DOMAIN env var
+//   - Path mode: {ingress-domain}/sessions/{session-name}/, rewritten to
+//     the template's webapp.basePath (see ingressRouting.httpPath)
 //
 // TLS/HTTPS:
 //
 // TLS is handled by the ingress controller:
 //   - Cert-manager can auto-provision Let's Encrypt certificates
 //   - Or use wildcard certificate for *.{ingress-domain}
+//   - Path mode only needs a certificate for {ingress-domain}
 //   - TODO: Add TLS configuration section
 //
 // NETWORKING FLOW:
@@ -1251,7 +1756,7 @@ func (r *SessionReconciler) createUserPVC(session *streamv1alpha1.Session) *core
 //   - Add rate limiting annotations
 //   - Support custom domains per user
 func (r *SessionReconciler) createIngress(session *streamv1alpha1.Session, template *streamv1alpha1.Template, serviceName string) *networkingv1.Ingress {
-	deploymentName := fmt.Sprintf("ss-%s-%s", session.Spec.User, session.Spec.Template)
+	deploymentName := sessionDeploymentName(session)
 	labels := map[string]string{
 		"app":      "streamspace-session",
 		"user":     session.Spec.User,
@@ -1268,15 +1773,8 @@ func (r *SessionReconciler) createIngress(session *streamv1alpha1.Session, templ
 	}
 
 	// Get ingress configuration from environment
-	ingressDomain := os.Getenv("INGRESS_DOMAIN")
-	if ingressDomain == "" {
-		ingressDomain = "streamspace.local"
-	}
-
-	ingressClass := os.Getenv("INGRESS_CLASS")
-	if ingressClass == "" {
-		ingressClass = "traefik"
-	}
+	routing := ingressRoutingFromEnv()
+	ingressClass := routing.Class
 
 	// Determine VNC port
 	vncPort := int32(5900)
@@ -1284,20 +1782,17 @@ func (r *SessionReconciler) createIngress(session *streamv1alpha1.Session, templ
 		vncPort = int32(template.Spec.VNC.Port)
 	}
 
-	// Build hostname
-	hostname := fmt.Sprintf("%s.%s", session.Name, ingressDomain)
-
-	// Path type
-	pathTypePrefix := networkingv1.PathTypePrefix
+	// Build hostname and path (subdomain or path-based routing)
+	hostname := routing.host(session)
+	path, pathType, annotations := routing.httpPath(session, template)
+	annotations["kubernetes.io/ingress.class"] = ingressClass
 
 	ingress := &networkingv1.Ingress{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      deploymentName,
 			Namespace: session.Namespace,
 			Labels:    labels,
-			Annotations: map[string]string{
-				"kubernetes.io/ingress.class": ingressClass,
-			},
+			Annotations: annotations,
 			OwnerReferences: []metav1.OwnerReference{
 				*metav1.NewControllerRef(session, streamv1alpha1.GroupVersion.WithKind("Session")),
 			},
@@ -1311,8 +1806,8 @@ func (r *SessionReconciler) createIngress(session *streamv1alpha1.Session, templ
 						HTTP: &networkingv1.HTTPIngressRuleValue{
 							Paths: []networkingv1.HTTPIngressPath{
 								{
-									Path:     "/",
-									PathType: &pathTypePrefix,
+									Path:     path,
+									PathType: &pathType,
 									Backend: networkingv1.IngressBackend{
 										Service: &networkingv1.IngressServiceBackend{
 											Name: serviceName,
@@ -1365,6 +1860,27 @@ func (r *SessionReconciler) getTemplate(ctx context.Context, templateName, names
 	return template, nil
 }
 
+// applyTemplateDefaults copies the template's default IdleTimeout and
+// MaxSessionDuration into the session spec where the session left them empty.
+//
+// Session-level values always take precedence over template defaults.
+// Returns true if the session spec was modified and needs to be persisted.
+func applyTemplateDefaults(session *streamv1alpha1.Session, template *streamv1alpha1.Template) bool {
+	changed := false
+
+	if session.Spec.IdleTimeout == "" && template.Spec.DefaultIdleTimeout != "" {
+		session.Spec.IdleTimeout = template.Spec.DefaultIdleTimeout
+		changed = true
+	}
+
+	if session.Spec.MaxSessionDuration == "" && template.Spec.DefaultMaxSessionDuration != "" {
+		session.Spec.MaxSessionDuration = template.Spec.DefaultMaxSessionDuration
+		changed = true
+	}
+
+	return changed
+}
+
 // SetupWithManager registers the SessionReconciler with the controller manager.
 //
 // This function configures:
@@ -1414,6 +1930,10 @@ func (r *SessionReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&appsv1.Deployment{}).
 		Owns(&corev1.Service{}).
 		Owns(&networkingv1.Ingress{}).
+		Owns(&networkingv1.NetworkPolicy{}).
+		// Session pods (Deployment pods and claimed warm pods) carry the
+		// session label; watching them catches container restarts
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(sessionForPod)).
 		Complete(r)
 }
 
@@ -2,15 +2,21 @@ package controllers
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
 
 	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
 )
@@ -239,4 +245,1136 @@ var _ = Describe("Session Controller State Transitions", func() {
 			return -1
 		}, time.Second*5, time.Millisecond*100).Should(Equal(int32(1)))
 	})
+
+	It("Should converge to the last state after rapid flips", func() {
+		ctx := context.Background()
+		key := types.NamespacedName{Name: "test-session", Namespace: "default"}
+
+		// Flip faster than the controller reconciles
+		session := &streamv1alpha1.Session{}
+		for _, state := range []string{"hibernated", "running", "hibernated", "running", "hibernated"} {
+			Expect(retry.RetryOnConflict(retry.DefaultRetry, func() error {
+				if err := k8sClient.Get(ctx, key, session); err != nil {
+					return err
+				}
+				session.Spec.State = state
+				return k8sClient.Update(ctx, session)
+			})).To(Succeed())
+		}
+		final := session.Generation
+
+		deployment := &appsv1.Deployment{}
+		Eventually(func() int32 {
+			_ = k8sClient.Get(ctx, types.NamespacedName{
+				Name:      "ss-testuser-test-template",
+				Namespace: "default",
+			}, deployment)
+			if deployment.Spec.Replicas != nil {
+				return *deployment.Spec.Replicas
+			}
+			return -1
+		}, time.Second*5, time.Millisecond*100).Should(Equal(int32(0)))
+
+		Eventually(func() int64 {
+			_ = k8sClient.Get(ctx, key, session)
+			return session.Status.ObservedGeneration
+		}, time.Second*5, time.Millisecond*100).Should(Equal(final))
+		Expect(session.Status.Phase).To(Equal("Hibernated"))
+		Consistently(func() int32 {
+			_ = k8sClient.Get(ctx, types.NamespacedName{
+				Name:      "ss-testuser-test-template",
+				Namespace: "default",
+			}, deployment)
+			return *deployment.Spec.Replicas
+		}, time.Second, time.Millisecond*100).Should(Equal(int32(0)))
+	})
+
+	It("Should skip reconciles of generations older than one already reconciled", func() {
+		var generations generationTracker
+		Expect(generations.observe("default/alice-firefox", 3)).To(BeTrue())
+		Expect(generations.observe("default/alice-firefox", 2)).To(BeFalse())
+		Expect(generations.observe("default/alice-firefox", 3)).To(BeTrue())
+		Expect(generations.observe("default/bob-firefox", 1)).To(BeTrue())
+
+		generations.forget("default/alice-firefox")
+		Expect(generations.observe("default/alice-firefox", 1)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Session Drift Detection", func() {
+	const (
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	It("Should revert out-of-band edits to the session Deployment", func() {
+		ctx := context.Background()
+
+		template := &streamv1alpha1.Template{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "drift-template",
+				Namespace: "default",
+			},
+			Spec: streamv1alpha1.TemplateSpec{
+				DisplayName: "Drift Template",
+				BaseImage:   "lscr.io/linuxserver/firefox:latest",
+			},
+		}
+		Expect(k8sClient.Create(ctx, template)).To(Succeed())
+
+		session := &streamv1alpha1.Session{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "drift-session",
+				Namespace: "default",
+			},
+			Spec: streamv1alpha1.SessionSpec{
+				User:     "driftuser",
+				Template: "drift-template",
+				State:    "running",
+			},
+		}
+		Expect(k8sClient.Create(ctx, session)).To(Succeed())
+
+		// Wait for the controller to record the applied spec
+		deploymentKey := types.NamespacedName{Name: "ss-driftuser-drift-template", Namespace: "default"}
+		deployment := &appsv1.Deployment{}
+		Eventually(func() bool {
+			if err := k8sClient.Get(ctx, deploymentKey, deployment); err != nil {
+				return false
+			}
+			_, ok := deployment.Annotations[appliedSpecAnnotation]
+			return ok
+		}, timeout, interval).Should(BeTrue())
+
+		// Tamper with the Deployment as kubectl would
+		deployment.Spec.Replicas = int32Ptr(3)
+		deployment.Spec.Template.Spec.Containers[0].Image = "example.com/tampered:latest"
+		Expect(k8sClient.Update(ctx, deployment)).To(Succeed())
+
+		Eventually(func() string {
+			_ = k8sClient.Get(ctx, deploymentKey, deployment)
+			return deployment.Spec.Template.Spec.Containers[0].Image
+		}, timeout, interval).Should(Equal("lscr.io/linuxserver/firefox:latest"))
+		Expect(deployment.Spec.Replicas).To(Equal(int32Ptr(1)))
+	})
+})
+
+var _ = Describe("Session Template Defaults", func() {
+	template := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{
+		DefaultIdleTimeout:        "30m",
+		DefaultMaxSessionDuration: "8h",
+	}}
+
+	It("Should fill in timeouts the session leaves empty", func() {
+		session := &streamv1alpha1.Session{}
+		Expect(applyTemplateDefaults(session, template)).To(BeTrue())
+		Expect(session.Spec.IdleTimeout).To(Equal("30m"))
+		Expect(session.Spec.MaxSessionDuration).To(Equal("8h"))
+
+		// Already applied
+		Expect(applyTemplateDefaults(session, template)).To(BeFalse())
+	})
+
+	It("Should keep timeouts set on the session", func() {
+		session := &streamv1alpha1.Session{Spec: streamv1alpha1.SessionSpec{IdleTimeout: "2h"}}
+		Expect(applyTemplateDefaults(session, template)).To(BeTrue())
+		Expect(session.Spec.IdleTimeout).To(Equal("2h"))
+		Expect(session.Spec.MaxSessionDuration).To(Equal("8h"))
+	})
+
+	It("Should leave sessions alone when the template has no defaults", func() {
+		session := &streamv1alpha1.Session{}
+		Expect(applyTemplateDefaults(session, &streamv1alpha1.Template{})).To(BeFalse())
+		Expect(session.Spec.IdleTimeout).To(BeEmpty())
+		Expect(session.Spec.MaxSessionDuration).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Session Resources On Wake", func() {
+	resources := func(cpu, memory string) corev1.ResourceRequirements {
+		return corev1.ResourceRequirements{Requests: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse(cpu),
+			corev1.ResourceMemory: resource.MustParse(memory),
+		}}
+	}
+	hibernated := func(current corev1.ResourceRequirements) *appsv1.Deployment {
+		return &appsv1.Deployment{Spec: appsv1.DeploymentSpec{
+			Replicas: int32Ptr(0),
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "session", Resources: current}},
+			}},
+		}}
+	}
+
+	It("Should apply resources changed while the session was hibernated", func() {
+		session := &streamv1alpha1.Session{Spec: streamv1alpha1.SessionSpec{Resources: resources("500m", "1088Mi")}}
+		deployment := hibernated(resources("2", "8Gi"))
+
+		Expect(applySessionResources(session, nil, deployment)).To(BeTrue())
+		Expect(deployment.Spec.Template.Spec.Containers[0].Resources).To(Equal(session.Spec.Resources))
+		// The new resources are the drift baseline, not drift
+		Expect(deployment.Annotations).To(HaveKey(appliedSpecAnnotation))
+	})
+
+	It("Should leave the Deployment alone without an override or change", func() {
+		deployment := hibernated(resources("2", "8Gi"))
+		Expect(applySessionResources(&streamv1alpha1.Session{}, nil, deployment)).To(BeFalse())
+
+		session := &streamv1alpha1.Session{Spec: streamv1alpha1.SessionSpec{Resources: resources("2", "8Gi")}}
+		Expect(applySessionResources(session, nil, deployment)).To(BeFalse())
+		Expect(deployment.Annotations).NotTo(HaveKey(appliedSpecAnnotation))
+	})
+})
+
+var _ = Describe("Session Launch Throttle", func() {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	It("Should queue launches beyond the limit in arrival order", func() {
+		var tracker launchTracker
+
+		admitted, _, _ := tracker.admit("ns/firefox", "ns/a", 2, now, time.Minute)
+		Expect(admitted).To(BeTrue())
+		admitted, _, _ = tracker.admit("ns/firefox", "ns/b", 2, now, time.Minute)
+		Expect(admitted).To(BeTrue())
+
+		admitted, position, inFlight := tracker.admit("ns/firefox", "ns/c", 2, now, time.Minute)
+		Expect(admitted).To(BeFalse())
+		Expect(position).To(Equal(1))
+		Expect(inFlight).To(Equal(2))
+		_, position, _ = tracker.admit("ns/firefox", "ns/d", 2, now, time.Minute)
+		Expect(position).To(Equal(2))
+
+		// Other templates are limited separately
+		admitted, _, _ = tracker.admit("ns/chrome", "ns/e", 2, now, time.Minute)
+		Expect(admitted).To(BeTrue())
+
+		// A ready session frees its slot for the head of the queue only
+		tracker.release("ns/a")
+		admitted, _, _ = tracker.admit("ns/firefox", "ns/d", 2, now, time.Minute)
+		Expect(admitted).To(BeFalse())
+		admitted, _, _ = tracker.admit("ns/firefox", "ns/c", 2, now, time.Minute)
+		Expect(admitted).To(BeTrue())
+	})
+
+	It("Should free slots of launches that take too long", func() {
+		var tracker launchTracker
+		tracker.admit("ns/firefox", "ns/stuck", 1, now, time.Minute)
+
+		admitted, _, _ := tracker.admit("ns/firefox", "ns/next", 1, now.Add(30*time.Second), time.Minute)
+		Expect(admitted).To(BeFalse())
+		admitted, _, _ = tracker.admit("ns/firefox", "ns/next", 1, now.Add(time.Minute), time.Minute)
+		Expect(admitted).To(BeTrue())
+	})
+
+	It("Should drop queued sessions that stop checking in", func() {
+		var tracker launchTracker
+		tracker.admit("ns/firefox", "ns/a", 1, now, time.Hour)
+		tracker.admit("ns/firefox", "ns/gone", 1, now, time.Hour)
+
+		_, position, _ := tracker.admit("ns/firefox", "ns/b", 1, now.Add(launchWaiterExpiry), time.Hour)
+		Expect(position).To(Equal(1))
+	})
+
+	It("Should not throttle templates whose image is pre-pulled everywhere", func() {
+		template := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{PrePull: true, MaxConcurrentLaunches: 5}}
+		Expect(templateLaunchLimit(template)).To(Equal(5))
+		Expect(imagePrePulled(template)).To(BeFalse())
+
+		template.Status.PrePullDesiredNodes = 3
+		template.Status.PrePullReadyNodes = 2
+		Expect(imagePrePulled(template)).To(BeFalse())
+		template.Status.PrePullReadyNodes = 3
+		Expect(imagePrePulled(template)).To(BeTrue())
+	})
+})
+
+var _ = Describe("Session Capacity Check", func() {
+	nodes := []corev1.ResourceList{
+		{corev1.ResourceMemory: resource.MustParse("128Gi"), corev1.ResourceCPU: resource.MustParse("16")},
+		{corev1.ResourceMemory: resource.MustParse("32Gi"), corev1.ResourceCPU: resource.MustParse("64"), gpuResourceName: resource.MustParse("2")},
+	}
+
+	It("Should accept requests a single node can hold", func() {
+		requests := effectiveRequests(corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("64Gi")},
+		})
+		Expect(fitsAnyNode(requests, nodes)).To(Succeed())
+	})
+
+	It("Should reject requests larger than the largest node", func() {
+		requests := corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Gi")}
+		err := fitsAnyNode(requests, nodes)
+		Expect(err).To(MatchError(ContainSubstring("no node can satisfy this request")))
+		Expect(err).To(MatchError(ContainSubstring("largest node has 128Gi")))
+	})
+
+	It("Should reject requests that only fit across different nodes", func() {
+		requests := corev1.ResourceList{
+			corev1.ResourceMemory: resource.MustParse("64Gi"),
+			gpuResourceName:       resource.MustParse("1"),
+		}
+		Expect(fitsAnyNode(requests, nodes)).To(MatchError(ContainSubstring("no single node has all of")))
+	})
+
+	It("Should reject GPU requests on clusters without GPUs", func() {
+		requests := corev1.ResourceList{gpuResourceName: resource.MustParse("1")}
+		Expect(fitsAnyNode(requests, nodes[:1])).To(MatchError(ContainSubstring("no node has any")))
+	})
+
+	It("Should not block when node capacity is unknown", func() {
+		requests := corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("256Gi")}
+		Expect(fitsAnyNode(requests, nil)).To(Succeed())
+	})
+})
+
+var _ = Describe("Session Security Context", func() {
+	session := &streamv1alpha1.Session{
+		ObjectMeta: metav1.ObjectMeta{Name: "secure-session", Namespace: "default"},
+		Spec:       streamv1alpha1.SessionSpec{User: "alice", Template: "firefox", State: "running"},
+	}
+
+	It("Should not harden session pods unless enabled", func() {
+		template := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{BaseImage: "lscr.io/linuxserver/firefox:latest"}}
+		pod := (&SessionReconciler{}).createDeployment(session, template, false).Spec.Template.Spec
+
+		Expect(pod.SecurityContext.RunAsNonRoot).To(BeNil())
+		Expect(pod.Containers[0].SecurityContext.Capabilities).To(BeNil())
+	})
+
+	It("Should run session pods hardened when the template opts in", func() {
+		template := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{
+			BaseImage:       "firefox:latest",
+			SecurityContext: &streamv1alpha1.TemplateSecurityContext{Hardened: true},
+		}}
+		pod := (&SessionReconciler{}).createDeployment(session, template, false).Spec.Template.Spec
+
+		Expect(pod.SecurityContext.RunAsNonRoot).To(Equal(boolPtr(true)))
+		Expect(pod.SecurityContext.SeccompProfile.Type).To(Equal(corev1.SeccompProfileTypeRuntimeDefault))
+		container := pod.Containers[0].SecurityContext
+		Expect(container.AllowPrivilegeEscalation).To(Equal(boolPtr(false)))
+		Expect(container.Capabilities.Drop).To(ConsistOf(corev1.Capability("ALL")))
+		Expect(pod.Volumes).To(BeEmpty())
+	})
+
+	It("Should harden every template with SESSION_SECURITY_DEFAULTS", func() {
+		GinkgoT().Setenv("SESSION_SECURITY_DEFAULTS", "true")
+		template := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{BaseImage: "firefox:latest"}}
+		pod := (&SessionReconciler{}).createDeployment(session, template, false).Spec.Template.Spec
+
+		Expect(pod.SecurityContext.RunAsNonRoot).To(Equal(boolPtr(true)))
+	})
+
+	It("Should apply template overrides and keep /tmp writable", func() {
+		template := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{
+			BaseImage: "firefox:latest",
+			SecurityContext: &streamv1alpha1.TemplateSecurityContext{
+				Hardened: true,
+				Container: &corev1.SecurityContext{
+					ReadOnlyRootFilesystem: boolPtr(true),
+					Capabilities:           &corev1.Capabilities{Add: []corev1.Capability{"NET_BIND_SERVICE"}},
+				},
+			},
+		}}
+		pod := (&SessionReconciler{}).createDeployment(session, template, false).Spec.Template.Spec
+
+		container := pod.Containers[0]
+		Expect(container.SecurityContext.ReadOnlyRootFilesystem).To(Equal(boolPtr(true)))
+		Expect(container.SecurityContext.Capabilities.Add).To(ConsistOf(corev1.Capability("NET_BIND_SERVICE")))
+		Expect(container.SecurityContext.Capabilities.Drop).To(ConsistOf(corev1.Capability("ALL")))
+		Expect(container.VolumeMounts).To(ContainElement(corev1.VolumeMount{Name: "tmp", MountPath: "/tmp"}))
+	})
+
+	It("Should require an opt-in for privileged settings", func() {
+		root := int64(0)
+		sc := &streamv1alpha1.TemplateSecurityContext{
+			Pod:       &corev1.PodSecurityContext{RunAsNonRoot: boolPtr(false), RunAsUser: &root},
+			Container: &corev1.SecurityContext{Capabilities: &corev1.Capabilities{Add: []corev1.Capability{"SYS_ADMIN"}}},
+		}
+		Expect(validateSecurityContext(sc, true)).To(MatchError(ContainSubstring("requires allowPrivileged")))
+
+		sc.AllowPrivileged = true
+		Expect(validateSecurityContext(sc, true)).To(Succeed())
+		Expect(validateSecurityContext(sc, false)).To(MatchError(ContainSubstring("privileged templates are disabled")))
+
+		hardened := &streamv1alpha1.TemplateSecurityContext{
+			Container: &corev1.SecurityContext{ReadOnlyRootFilesystem: boolPtr(true)},
+		}
+		Expect(validateSecurityContext(hardened, false)).To(Succeed())
+	})
+
+	It("Should treat dropping fewer capabilities than ALL as privileged", func() {
+		sc := &streamv1alpha1.TemplateSecurityContext{
+			Container: &corev1.SecurityContext{Capabilities: &corev1.Capabilities{Drop: []corev1.Capability{}}},
+		}
+		Expect(privilegedSettings(sc)).To(ConsistOf("container.capabilities.drop without ALL"))
+
+		sc.Container.Capabilities.Drop = []corev1.Capability{"NET_RAW"}
+		Expect(validateSecurityContext(sc, true)).To(MatchError(ContainSubstring("requires allowPrivileged")))
+
+		sc.Container.Capabilities.Drop = []corev1.Capability{"ALL"}
+		Expect(privilegedSettings(sc)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Session Startup Metrics", func() {
+	created := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) metav1.Time {
+		return metav1.NewTime(created.Add(time.Duration(seconds) * time.Second))
+	}
+
+	It("Should split the launch into stages", func() {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{CreationTimestamp: at(2)},
+			Status: corev1.PodStatus{
+				Conditions: []corev1.PodCondition{
+					{Type: corev1.PodScheduled, Status: corev1.ConditionTrue, LastTransitionTime: at(5)},
+					{Type: corev1.PodReady, Status: corev1.ConditionTrue, LastTransitionTime: at(50)},
+				},
+				ContainerStatuses: []corev1.ContainerStatus{{
+					Name:  "session",
+					State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{StartedAt: at(45)}},
+				}},
+			},
+		}
+
+		Expect(startupStages(created, pod, created.Add(time.Minute))).To(Equal([]startupStage{
+			{name: "controller", duration: 2 * time.Second},
+			{name: "scheduling", duration: 3 * time.Second},
+			{name: "image_pull", duration: 40 * time.Second},
+			{name: "readiness", duration: 5 * time.Second},
+		}))
+	})
+
+	It("Should leave out stages without both timestamps", func() {
+		pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{CreationTimestamp: at(2)}}
+		Expect(startupStages(created, pod, created.Add(30*time.Second))).To(Equal([]startupStage{
+			{name: "controller", duration: 2 * time.Second},
+		}))
+	})
+
+	It("Should classify reconcile errors by API reason", func() {
+		conflict := apierrors.NewConflict(schema.GroupResource{Resource: "sessions"}, "s", fmt.Errorf("modified"))
+		Expect(reconcileErrorReason(conflict)).To(Equal("Conflict"))
+		Expect(reconcileErrorReason(context.DeadlineExceeded)).To(Equal("Timeout"))
+		Expect(reconcileErrorReason(fmt.Errorf("boom"))).To(Equal("Unknown"))
+	})
+})
+
+var _ = Describe("Session Warm Pool", func() {
+	readyPod := func(name, node string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: node, Containers: []corev1.Container{{Name: "session"}}},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+			},
+		}
+	}
+	names := func(pods []*corev1.Pod) []string {
+		var result []string
+		for _, pod := range pods {
+			result = append(result, pod.Name)
+		}
+		return result
+	}
+	template := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{WarmPoolSize: 2}}
+
+	It("Should serve persistent-home sessions from the pool", func() {
+		session := &streamv1alpha1.Session{Spec: streamv1alpha1.SessionSpec{User: "alice", PersistentHome: true}}
+		Expect(warmPoolEligible(session, template)).To(BeTrue())
+
+		session.Spec.Parameters = map[string]string{"url": "https://example.com"}
+		Expect(warmPoolEligible(session, template)).To(BeFalse())
+	})
+
+	It("Should only pick pods on the user's node with required affinity", func() {
+		pending := readyPod("pending", "node-a")
+		pending.Status.Phase = corev1.PodPending
+		pods := []corev1.Pod{readyPod("a", "node-a"), readyPod("b", "node-b"), pending}
+		session := &streamv1alpha1.Session{Spec: streamv1alpha1.SessionSpec{User: "alice"}}
+		required := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{
+			SessionAffinity: streamv1alpha1.SessionAffinityRequired,
+		}}
+		affinity := userSessionAffinity(session, required, false)
+
+		Expect(names(warmPodCandidates(pods, affinity, map[string]bool{"node-b": true}))).To(Equal([]string{"b"}))
+		Expect(warmPodCandidates(pods, affinity, map[string]bool{"node-c": true})).To(BeEmpty())
+
+		// A user without running sessions can start anywhere
+		Expect(names(warmPodCandidates(pods, affinity, map[string]bool{}))).To(Equal([]string{"a", "b"}))
+	})
+
+	It("Should try pods on the user's node first with preferred affinity", func() {
+		pods := []corev1.Pod{readyPod("a", "node-a"), readyPod("b", "node-b")}
+		session := &streamv1alpha1.Session{Spec: streamv1alpha1.SessionSpec{User: "alice"}}
+		preferred := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{
+			SessionAffinity: streamv1alpha1.SessionAffinityPreferred,
+		}}
+		affinity := userSessionAffinity(session, preferred, false)
+
+		Expect(names(warmPodCandidates(pods, affinity, map[string]bool{"node-b": true}))).To(Equal([]string{"b", "a"}))
+		Expect(names(warmPodCandidates(pods, nil, nil))).To(Equal([]string{"a", "b"}))
+	})
+
+	It("Should require affinity based on the home PVC's actual access mode", func() {
+		pvcWith := func(modes ...corev1.PersistentVolumeAccessMode) *corev1.PersistentVolumeClaim {
+			return &corev1.PersistentVolumeClaim{Spec: corev1.PersistentVolumeClaimSpec{AccessModes: modes}}
+		}
+		rwx := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{HomeVolumeAccessMode: corev1.ReadWriteMany}}
+		rwo := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{HomeVolumeAccessMode: corev1.ReadWriteOnce}}
+
+		// An existing PVC wins over whatever the template asks for now
+		Expect(homeReadWriteOnce(rwx, pvcWith(corev1.ReadWriteOnce))).To(BeTrue())
+		Expect(homeReadWriteOnce(template, pvcWith(corev1.ReadWriteOncePod))).To(BeTrue())
+		Expect(homeReadWriteOnce(rwo, pvcWith(corev1.ReadWriteMany))).To(BeFalse())
+		Expect(homeReadWriteOnce(rwo, pvcWith(corev1.ReadWriteOnce, corev1.ReadWriteMany))).To(BeFalse())
+
+		// Without a PVC yet, the template decides what will be created
+		Expect(homeReadWriteOnce(rwo, nil)).To(BeTrue())
+		Expect(homeReadWriteOnce(rwx, nil)).To(BeFalse())
+		Expect(homeReadWriteOnce(template, nil)).To(BeFalse())
+
+		session := &streamv1alpha1.Session{Spec: streamv1alpha1.SessionSpec{User: "alice", PersistentHome: true}}
+		affinity := userSessionAffinity(session, rwx, homeReadWriteOnce(rwx, pvcWith(corev1.ReadWriteOnce)))
+		Expect(affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution).To(HaveLen(1))
+		Expect(userSessionAffinity(session, rwo, homeReadWriteOnce(rwo, pvcWith(corev1.ReadWriteMany)))).To(BeNil())
+	})
+
+	It("Should mount the user's home in a copy on the same node", func() {
+		session := &streamv1alpha1.Session{
+			ObjectMeta: metav1.ObjectMeta{Name: "alice-firefox", Namespace: "default"},
+			Spec:       streamv1alpha1.SessionSpec{User: "alice", PersistentHome: true},
+		}
+		claimed := readyPod("ss-pool-firefox-abc", "node-a")
+		claimed.Labels = map[string]string{"session": "alice-firefox", warmPoolLabel: warmPoolClaimed}
+
+		pod := homeBoundPod(session, &claimed)
+		Expect(pod.Spec.NodeName).To(Equal("node-a"))
+		Expect(pod.Labels).To(HaveKeyWithValue(warmPoolLabel, warmPoolClaimed))
+		Expect(pod.Labels).To(HaveKeyWithValue("session", "alice-firefox"))
+		Expect(pod.Spec.Volumes).To(ContainElement(HaveField("VolumeSource.PersistentVolumeClaim.ClaimName", "home-alice")))
+		Expect(pod.Spec.Containers[0].VolumeMounts).To(ContainElement(HaveField("MountPath", "/config")))
+
+		// The claimed pod is left untouched
+		Expect(claimed.Spec.Volumes).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Session Deployment Strategy", func() {
+	session := &streamv1alpha1.Session{
+		ObjectMeta: metav1.ObjectMeta{Name: "alice-vscode", Namespace: "default"},
+		Spec:       streamv1alpha1.SessionSpec{User: "alice", Template: "vscode", PersistentHome: true},
+	}
+	templateWith := func(mode corev1.PersistentVolumeAccessMode) *streamv1alpha1.Template {
+		return &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{
+			BaseImage:            "vscode:latest",
+			HomeVolumeAccessMode: mode,
+		}}
+	}
+
+	It("Should match the strategy to the home volume's access mode", func() {
+		r := &SessionReconciler{}
+		for mode, want := range map[corev1.PersistentVolumeAccessMode]appsv1.DeploymentStrategyType{
+			"":                   appsv1.RollingUpdateDeploymentStrategyType,
+			corev1.ReadWriteMany: appsv1.RollingUpdateDeploymentStrategyType,
+			corev1.ReadWriteOnce: appsv1.RecreateDeploymentStrategyType,
+		} {
+			template := templateWith(mode)
+			pvc := r.createUserPVC(session, template)
+			deployment := r.createDeployment(session, template, homeReadWriteOnce(template, pvc))
+
+			Expect(deployment.Spec.Strategy.Type).To(Equal(want), "access mode %q", pvc.Spec.AccessModes[0])
+			Expect(deployment.Spec.Strategy.Type == appsv1.RecreateDeploymentStrategyType).
+				To(Equal(pvc.Spec.AccessModes[0] == corev1.ReadWriteOnce))
+		}
+	})
+
+	It("Should roll sessions without a persistent home", func() {
+		ephemeral := session.DeepCopy()
+		ephemeral.Spec.PersistentHome = false
+
+		strategy := sessionDeploymentStrategy(ephemeral, templateWith(corev1.ReadWriteOnce), true)
+		Expect(strategy.Type).To(Equal(appsv1.RollingUpdateDeploymentStrategyType))
+	})
+
+	It("Should follow the existing home PVC rather than the template", func() {
+		r := &SessionReconciler{}
+		pvc := r.createUserPVC(session, templateWith(corev1.ReadWriteOnce))
+		template := templateWith(corev1.ReadWriteMany)
+		strategy := sessionDeploymentStrategy(session, template, homeReadWriteOnce(template, pvc))
+		Expect(strategy.Type).To(Equal(appsv1.RecreateDeploymentStrategyType))
+
+		pvc = r.createUserPVC(session, templateWith(corev1.ReadWriteMany))
+		template = templateWith(corev1.ReadWriteOnce)
+		strategy = sessionDeploymentStrategy(session, template, homeReadWriteOnce(template, pvc))
+		Expect(strategy.Type).To(Equal(appsv1.RollingUpdateDeploymentStrategyType))
+	})
+
+	It("Should use the template's strategy when set", func() {
+		template := templateWith(corev1.ReadWriteOnce)
+		template.Spec.DeploymentStrategy = appsv1.RollingUpdateDeploymentStrategyType
+		Expect(sessionDeploymentStrategy(session, template, true).Type).To(Equal(appsv1.RollingUpdateDeploymentStrategyType))
+
+		template = templateWith(corev1.ReadWriteMany)
+		template.Spec.DeploymentStrategy = appsv1.RecreateDeploymentStrategyType
+		Expect(sessionDeploymentStrategy(session, template, false).Type).To(Equal(appsv1.RecreateDeploymentStrategyType))
+	})
+})
+
+var _ = Describe("Session Network Policy", func() {
+	r := &SessionReconciler{}
+	newSession := func(name string) *streamv1alpha1.Session {
+		return &streamv1alpha1.Session{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec:       streamv1alpha1.SessionSpec{User: "alice", Template: "firefox"},
+		}
+	}
+	egressTo := func(policy *networkingv1.NetworkPolicy) []networkingv1.NetworkPolicyPeer {
+		var peers []networkingv1.NetworkPolicyPeer
+		for _, rule := range policy.Spec.Egress {
+			peers = append(peers, rule.To...)
+		}
+		return peers
+	}
+
+	It("Should give each session of a template its own policy", func() {
+		template := &streamv1alpha1.Template{}
+		first := r.createNetworkPolicy(newSession("alice-firefox-1"), template)
+		second := r.createNetworkPolicy(newSession("alice-firefox-2"), template)
+
+		Expect(first.Name).To(Equal("alice-firefox-1-netpol"))
+		Expect(second.Name).NotTo(Equal(first.Name))
+		Expect(first.Spec.PodSelector.MatchLabels).To(Equal(map[string]string{"session": "alice-firefox-1"}))
+	})
+
+	It("Should only allow DNS egress by default", func() {
+		policy := r.createNetworkPolicy(newSession("alice-firefox-1"), &streamv1alpha1.Template{})
+
+		Expect(policy.Annotations).To(HaveKeyWithValue("stream.space/network-egress", streamv1alpha1.NetworkEgressNone))
+		Expect(policy.Spec.PolicyTypes).To(ConsistOf(networkingv1.PolicyTypeIngress, networkingv1.PolicyTypeEgress))
+		Expect(policy.Spec.Egress).To(HaveLen(1))
+		Expect(policy.Spec.Egress[0].Ports).To(HaveLen(2))
+		Expect(egressTo(policy)).To(BeEmpty())
+	})
+
+	It("Should allow cluster egress for Internal templates", func() {
+		template := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{
+			NetworkEgress: streamv1alpha1.NetworkEgressInternal,
+		}}
+		policy := r.createNetworkPolicy(newSession("alice-firefox-1"), template)
+
+		Expect(egressTo(policy)).To(ConsistOf(HaveField("NamespaceSelector", Not(BeNil()))))
+	})
+
+	It("Should exclude private ranges from Internet egress", func() {
+		template := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{
+			NetworkEgress: streamv1alpha1.NetworkEgressInternet,
+		}}
+		peers := egressTo(r.createNetworkPolicy(newSession("alice-firefox-1"), template))
+
+		Expect(peers).To(HaveLen(1))
+		Expect(peers[0].IPBlock.CIDR).To(Equal("0.0.0.0/0"))
+		Expect(peers[0].IPBlock.Except).To(ContainElement("169.254.0.0/16"))
+	})
+
+	It("Should only admit the ingress controller and the API", func() {
+		GinkgoT().Setenv("INGRESS_CONTROLLER_NAMESPACE", "ingress-nginx")
+
+		policy := r.createNetworkPolicy(newSession("alice-firefox-1"), &streamv1alpha1.Template{})
+		Expect(policy.Spec.Ingress).To(HaveLen(1))
+		from := policy.Spec.Ingress[0].From
+		Expect(from[0].NamespaceSelector.MatchLabels).To(HaveKeyWithValue("kubernetes.io/metadata.name", "ingress-nginx"))
+		for _, peer := range from[1:] {
+			Expect(peer.PodSelector).NotTo(BeNil())
+			Expect(peer.NamespaceSelector).To(BeNil())
+		}
+	})
+})
+
+var _ = Describe("Session Home Fork", func() {
+	newSession := func(name, forkFrom string) *streamv1alpha1.Session {
+		return &streamv1alpha1.Session{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Spec: streamv1alpha1.SessionSpec{
+				User:           "alice",
+				Template:       "vscode",
+				PersistentHome: true,
+				ForkFrom:       forkFrom,
+			},
+		}
+	}
+
+	It("Should give a fork its own home and Deployment", func() {
+		source := newSession("alice-vscode-1", "")
+		fork := newSession("alice-vscode-2", "alice-vscode-1")
+
+		Expect(homePVCName(source)).To(Equal("home-alice"))
+		Expect(sessionDeploymentName(source)).To(Equal("ss-alice-vscode"))
+		Expect(homePVCName(fork)).To(Equal("home-alice-vscode-2"))
+		Expect(sessionDeploymentName(fork)).To(Equal("ss-alice-vscode-2"))
+	})
+
+	It("Should size the forked home like the source and tie it to the fork", func() {
+		storageClass := "csi-rbd"
+		sourcePVC := &corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: "home-alice", Namespace: "default"},
+			Spec: corev1.PersistentVolumeClaimSpec{
+				AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+				StorageClassName: &storageClass,
+				Resources: corev1.VolumeResourceRequirements{
+					Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("20Gi")},
+				},
+			},
+		}
+
+		pvc := forkedHomePVC(newSession("alice-vscode-2", "alice-vscode-1"), sourcePVC, forkMethodCopy)
+		Expect(pvc.Name).To(Equal("home-alice-vscode-2"))
+		Expect(pvc.Annotations).To(HaveKeyWithValue(forkMethodAnnotation, forkMethodCopy))
+		Expect(pvc.OwnerReferences).To(ConsistOf(HaveField("Name", "alice-vscode-2")))
+		Expect(pvc.Spec.AccessModes).To(ConsistOf(corev1.ReadWriteOnce))
+		Expect(*pvc.Spec.StorageClassName).To(Equal("csi-rbd"))
+		Expect(pvc.Spec.Resources.Requests.Storage().String()).To(Equal("20Gi"))
+	})
+
+	It("Should copy the source home read-only on the node using it", func() {
+		GinkgoT().Setenv(forkCopyImageEnv, "registry.local/busybox:1.36")
+
+		job := forkCopyJob(newSession("alice-vscode-2", "alice-vscode-1"), "home-alice", "home-alice-vscode-2", "node-a")
+		Expect(job.Name).To(Equal("alice-vscode-2-home-fork"))
+
+		podSpec := job.Spec.Template.Spec
+		Expect(podSpec.NodeName).To(Equal("node-a"))
+		Expect(podSpec.RestartPolicy).To(Equal(corev1.RestartPolicyNever))
+		Expect(podSpec.Containers[0].Image).To(Equal("registry.local/busybox:1.36"))
+		Expect(podSpec.Volumes[0].PersistentVolumeClaim.ClaimName).To(Equal("home-alice"))
+		Expect(podSpec.Volumes[0].PersistentVolumeClaim.ReadOnly).To(BeTrue())
+		Expect(podSpec.Volumes[1].PersistentVolumeClaim.ClaimName).To(Equal("home-alice-vscode-2"))
+	})
+})
+
+var _ = Describe("Template Companion Resources", func() {
+	template := &streamv1alpha1.Template{
+		ObjectMeta: metav1.ObjectMeta{Name: "vscode", Namespace: "default"},
+		Spec: streamv1alpha1.TemplateSpec{
+			Parameters: []streamv1alpha1.TemplateParameter{{Name: "project", Default: "demo"}},
+			CompanionResources: []streamv1alpha1.CompanionResource{
+				{
+					Name:      "settings",
+					Kind:      streamv1alpha1.CompanionKindConfigMap,
+					MountPath: "/config/.vscode",
+					Data:      map[string]string{"settings.json": `{"owner": "${user}", "project": "${project}", "session": "${session}"}`},
+				},
+				{
+					Name:    "license",
+					Kind:    streamv1alpha1.CompanionKindSecret,
+					Scope:   streamv1alpha1.CompanionScopeUser,
+					EnvFrom: true,
+					Data:    map[string]string{"LICENSE_USER": "${user}"},
+				},
+			},
+		},
+	}
+	newSession := func(name string, uid types.UID) *streamv1alpha1.Session {
+		return &streamv1alpha1.Session{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", UID: uid},
+			Spec: streamv1alpha1.SessionSpec{
+				User:       "alice",
+				Template:   "vscode",
+				Parameters: map[string]string{"project": "streamspace"},
+			},
+		}
+	}
+
+	It("Should template a session-scoped resource and tie it to the session", func() {
+		obj := companionObject(newSession("alice-vscode-1", "uid-1"), template, template.Spec.CompanionResources[0])
+
+		configMap, ok := obj.(*corev1.ConfigMap)
+		Expect(ok).To(BeTrue())
+		Expect(configMap.Name).To(Equal("alice-vscode-1-settings"))
+		Expect(configMap.Data).To(HaveKeyWithValue("settings.json",
+			`{"owner": "alice", "project": "streamspace", "session": "alice-vscode-1"}`))
+		Expect(configMap.OwnerReferences).To(ConsistOf(HaveField("Controller", HaveValue(BeTrue()))))
+	})
+
+	It("Should share a user-scoped resource between the user's sessions", func() {
+		res := template.Spec.CompanionResources[1]
+		obj := companionObject(newSession("alice-vscode-1", "uid-1"), template, res)
+
+		secret, ok := obj.(*corev1.Secret)
+		Expect(ok).To(BeTrue())
+		Expect(secret.Name).To(Equal("alice-vscode-license"))
+		Expect(secret.Data).To(HaveKeyWithValue("LICENSE_USER", []byte("alice")))
+		Expect(secret.OwnerReferences).To(ConsistOf(HaveField("Controller", BeNil())))
+
+		// A second session adds itself to the owners, so the Secret outlives the first
+		second := newSession("alice-vscode-2", "uid-2")
+		Expect(syncCompanionObject(secret, companionObject(second, template, res), second)).To(BeTrue())
+		Expect(secret.OwnerReferences).To(ConsistOf(HaveField("UID", types.UID("uid-1")), HaveField("UID", types.UID("uid-2"))))
+		Expect(syncCompanionObject(secret, companionObject(second, template, res), second)).To(BeFalse())
+	})
+
+	It("Should mount and expose companion resources in the session container", func() {
+		podSpec := &corev1.PodSpec{}
+		container := &corev1.Container{}
+		applyCompanionResources(newSession("alice-vscode-1", "uid-1"), template, podSpec, container)
+
+		Expect(podSpec.Volumes).To(HaveLen(1))
+		Expect(podSpec.Volumes[0].ConfigMap.Name).To(Equal("alice-vscode-1-settings"))
+		Expect(container.VolumeMounts).To(ConsistOf(HaveField("MountPath", "/config/.vscode")))
+		Expect(container.EnvFrom).To(HaveLen(1))
+		Expect(container.EnvFrom[0].SecretRef.Name).To(Equal("alice-vscode-license"))
+	})
+
+	It("Should reject invalid companion resources", func() {
+		Expect(validateCompanionResources(template.Spec.CompanionResources)).To(Succeed())
+
+		invalid := [][]streamv1alpha1.CompanionResource{
+			{{Name: "Settings", Kind: streamv1alpha1.CompanionKindConfigMap}},
+			{{Name: "settings", Kind: "PersistentVolumeClaim"}},
+			{{Name: "settings", Kind: streamv1alpha1.CompanionKindConfigMap, MountPath: "config"}},
+			{{Name: "settings", Kind: streamv1alpha1.CompanionKindConfigMap, Data: map[string]string{"bad/key": "x"}}},
+			{{Name: "settings", Kind: streamv1alpha1.CompanionKindConfigMap}, {Name: "settings", Kind: streamv1alpha1.CompanionKindSecret}},
+			{{Name: "token", Kind: streamv1alpha1.CompanionKindSecret, Scope: streamv1alpha1.CompanionScopeUser,
+				Data: map[string]string{"TOKEN": "${session}"}}},
+		}
+		for _, resources := range invalid {
+			Expect(validateCompanionResources(resources)).NotTo(Succeed(), "%+v", resources)
+		}
+	})
+})
+
+var _ = Describe("Session Spec Validation", func() {
+	It("Should accept the durations the CRD schema accepts", func() {
+		spec := streamv1alpha1.SessionSpec{User: "alice", Template: "firefox", State: "running"}
+		for _, d := range []string{"30m", "1h30m", "1.5h", "90s"} {
+			spec.IdleTimeout = d
+			Expect(spec.Validate()).To(Succeed(), d)
+		}
+		for _, d := range []string{"30", ".5h", "1.h", "0s"} {
+			spec.IdleTimeout = d
+			Expect(spec.Validate()).To(MatchError(ContainSubstring("idleTimeout")), d)
+		}
+	})
+
+	It("Should only report an invalid spec when the problem changes", func() {
+		Expect(specInvalidReported(nil, "bad idleTimeout")).To(BeFalse())
+
+		conditions := []metav1.Condition{{Type: "SpecValid", Status: metav1.ConditionFalse, Message: "bad idleTimeout"}}
+		Expect(specInvalidReported(conditions, "bad idleTimeout")).To(BeTrue())
+		Expect(specInvalidReported(conditions, "bad maxSessionDuration")).To(BeFalse())
+
+		conditions[0].Status = metav1.ConditionTrue
+		Expect(specInvalidReported(conditions, "bad idleTimeout")).To(BeFalse())
+	})
+})
+
+var _ = Describe("Session Hibernation Grace", func() {
+	It("Should wait for open connections until the grace passes", func() {
+		since := time.Date(2026, time.October, 12, 9, 0, 0, 0, time.UTC)
+		session := &streamv1alpha1.Session{}
+
+		// No connections reported: scale down right away
+		Expect(activeConnections(session)).To(Equal(0))
+		Expect(drainRemaining(session, since, 5*time.Minute, since.Add(time.Minute))).To(BeZero())
+
+		session.Annotations = map[string]string{streamv1alpha1.ActiveConnectionsAnnotation: "2"}
+		Expect(activeConnections(session)).To(Equal(2))
+		Expect(drainRemaining(session, since, 5*time.Minute, since.Add(time.Minute))).To(Equal(4 * time.Minute))
+		Expect(drainRemaining(session, since, 5*time.Minute, since.Add(6*time.Minute))).To(BeZero())
+
+		session.Annotations[streamv1alpha1.ActiveConnectionsAnnotation] = "0"
+		Expect(drainRemaining(session, since, 5*time.Minute, since.Add(time.Minute))).To(BeZero())
+	})
+})
+
+var _ = Describe("Session Resource Overcommit", func() {
+	size := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("2"),
+			corev1.ResourceMemory: resource.MustParse("4Gi"),
+			"nvidia.com/gpu":      resource.MustParse("1"),
+		},
+	}
+	template := func(category string, disabled bool) *streamv1alpha1.Template {
+		return &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{Category: category, DisableOvercommit: disabled}}
+	}
+
+	It("Should lower requests by the category's ratio and keep limits", func() {
+		GinkgoT().Setenv("SESSION_OVERCOMMIT_RATIOS", "default=2, Web Browsers=4, bogus, Development=0.5")
+		GinkgoT().Setenv("SESSION_OVERCOMMIT_MEMORY", "")
+		policy := overcommitPolicyFromEnv()
+
+		browser := policy.apply(template("web-browsers", false), size)
+		Expect(browser.Limits).To(Equal(size.Limits))
+		Expect(browser.Requests.Cpu().String()).To(Equal("500m"))
+		Expect(browser.Requests.Memory().String()).To(Equal("1Gi"))
+		// Extended resources must request their limit
+		Expect(browser.Requests).NotTo(HaveKey(corev1.ResourceName("nvidia.com/gpu")))
+
+		// Invalid ratios are ignored, so the default applies
+		development := policy.apply(template("Development", false), size)
+		Expect(development.Requests.Cpu().String()).To(Equal("1"))
+
+		// The size is the request if no limit is set
+		requestOnly := corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("1")}}
+		resources := policy.apply(template("", false), requestOnly)
+		Expect(resources.Limits.Cpu().String()).To(Equal("1"))
+		Expect(resources.Requests.Cpu().String()).To(Equal("500m"))
+
+		// Requests already below size/ratio are kept
+		lowRequest := *size.DeepCopy()
+		lowRequest.Requests = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("100m")}
+		kept := policy.apply(template("Web Browsers", false), lowRequest)
+		Expect(kept.Requests.Cpu().String()).To(Equal("100m"))
+	})
+
+	It("Should leave requests alone when disabled", func() {
+		GinkgoT().Setenv("SESSION_OVERCOMMIT_RATIOS", "default=4")
+		GinkgoT().Setenv("SESSION_OVERCOMMIT_MEMORY", "false")
+		policy := overcommitPolicyFromEnv()
+
+		Expect(policy.apply(template("Gaming", true), size)).To(Equal(size))
+
+		cpuOnly := policy.apply(template("Gaming", false), size)
+		Expect(cpuOnly.Requests.Cpu().String()).To(Equal("500m"))
+		Expect(cpuOnly.Requests).NotTo(HaveKey(corev1.ResourceMemory))
+
+		GinkgoT().Setenv("SESSION_OVERCOMMIT_RATIOS", "")
+		Expect(overcommitPolicyFromEnv().apply(template("Gaming", false), size)).To(Equal(size))
+	})
+
+	It("Should report the effective resources and size the capacity check with them", func() {
+		GinkgoT().Setenv("SESSION_OVERCOMMIT_RATIOS", "default=4")
+		session := &streamv1alpha1.Session{ObjectMeta: metav1.ObjectMeta{Name: "alice-firefox", Namespace: "streamspace"}}
+		tmpl := template("Web Browsers", false)
+		tmpl.Spec.BaseImage = "lscr.io/linuxserver/firefox:latest"
+		tmpl.Spec.DefaultResources = size
+
+		deployment := (&SessionReconciler{}).createDeployment(session, tmpl, false)
+		effective := effectiveResources(deployment.Spec.Template.Spec.Containers)
+		Expect(effective).NotTo(BeNil())
+		Expect(effective.Requests.Cpu().String()).To(Equal("500m"))
+		Expect(effective.Limits.Cpu().String()).To(Equal("2"))
+		reported := sessionResources(session, tmpl)
+		Expect(reported.Requests.Cpu().String()).To(Equal("500m"))
+
+		Expect(effectiveResources([]corev1.Container{{Name: "session"}})).To(BeNil())
+	})
+})
+
+var _ = Describe("Session Streaming Features", func() {
+	session := &streamv1alpha1.Session{
+		ObjectMeta: metav1.ObjectMeta{Name: "stream-session", Namespace: "default"},
+		Spec:       streamv1alpha1.SessionSpec{User: "alice", Template: "firefox", State: "running"},
+	}
+	env := func(template *streamv1alpha1.Template) map[string]string {
+		values := map[string]string{}
+		for _, e := range (&SessionReconciler{}).createDeployment(session, template, false).Spec.Template.Spec.Containers[0].Env {
+			values[e.Name] = e.Value
+		}
+		return values
+	}
+
+	It("Should pass the template's features to the session container", func() {
+		template := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{
+			BaseImage:    "firefox:latest",
+			Capabilities: []string{"Audio"},
+			VNC:          streamv1alpha1.VNCConfig{FileTransfer: boolPtr(false), MaxResolution: "1920x1080"},
+		}}
+
+		Expect(env(template)).To(And(
+			HaveKeyWithValue("STREAMSPACE_CLIPBOARD", "true"),
+			HaveKeyWithValue("STREAMSPACE_AUDIO", "true"),
+			HaveKeyWithValue("STREAMSPACE_FILE_TRANSFER", "false"),
+			HaveKeyWithValue("STREAMSPACE_MAX_RESOLUTION", "1920x1080"),
+		))
+		Expect(streamingFeatures(template).Restricted).To(BeFalse())
+	})
+
+	It("Should disable clipboard and file transfer for untrusted templates", func() {
+		template := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{
+			BaseImage:       "firefox:latest",
+			VNC:             streamv1alpha1.VNCConfig{Clipboard: boolPtr(true), Audio: boolPtr(true)},
+			SecurityContext: &streamv1alpha1.TemplateSecurityContext{Untrusted: true},
+		}}
+
+		Expect(streamingFeatures(template)).To(Equal(&streamv1alpha1.StreamingFeatures{Audio: true, Restricted: true}))
+		Expect(env(template)).To(And(
+			HaveKeyWithValue("STREAMSPACE_CLIPBOARD", "false"),
+			HaveKeyWithValue("STREAMSPACE_FILE_TRANSFER", "false"),
+			Not(HaveKey("STREAMSPACE_MAX_RESOLUTION")),
+		))
+	})
+
+	It("Should treat catalog templates as untrusted with SESSION_UNTRUSTED_CATALOG_TEMPLATES", func() {
+		template := &streamv1alpha1.Template{
+			ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{catalogIDLabel: "42"}},
+			Spec:       streamv1alpha1.TemplateSpec{BaseImage: "firefox:latest"},
+		}
+		Expect(streamingFeatures(template).Clipboard).To(BeTrue())
+
+		GinkgoT().Setenv("SESSION_UNTRUSTED_CATALOG_TEMPLATES", "true")
+		Expect(streamingFeatures(template).Clipboard).To(BeFalse())
+	})
+})
+
+var _ = Describe("Session Node Pinning", func() {
+	template := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{BaseImage: "firefox:latest"}}
+	pinnedSession := func(node string) *streamv1alpha1.Session {
+		return &streamv1alpha1.Session{
+			ObjectMeta: metav1.ObjectMeta{Name: "pinned-session", Namespace: "default"},
+			Spec:       streamv1alpha1.SessionSpec{User: "alice", Template: "firefox", State: "running", PinToNode: node},
+		}
+	}
+
+	It("Should keep a pinned session's pod on its node", func() {
+		deployment := (&SessionReconciler{}).createDeployment(pinnedSession("worker-3"), template, false)
+		Expect(deployment.Spec.Template.Spec.Affinity).To(Equal(pinnedNodeAffinity("worker-3")))
+	})
+
+	It("Should re-pin and unpin existing Deployments but leave unpinned ones alone", func() {
+		deployment := (&SessionReconciler{}).createDeployment(pinnedSession(""), template, false)
+		Expect(applyNodePin(pinnedSession(""), template, false, deployment)).To(BeFalse())
+
+		Expect(applyNodePin(pinnedSession("worker-3"), template, false, deployment)).To(BeTrue())
+		Expect(deployment.Spec.Template.Spec.Affinity).To(Equal(pinnedNodeAffinity("worker-3")))
+		Expect(applyNodePin(pinnedSession("worker-3"), template, false, deployment)).To(BeFalse())
+
+		Expect(applyNodePin(pinnedSession(""), template, false, deployment)).To(BeTrue())
+		Expect(deployment.Spec.Template.Spec.Affinity).To(Equal(userSessionAffinity(pinnedSession(""), template, false)))
+	})
+
+	It("Should not launch pinned sessions in warm pods", func() {
+		pooled := &streamv1alpha1.Template{Spec: streamv1alpha1.TemplateSpec{BaseImage: "firefox:latest", WarmPoolSize: 2}}
+		Expect(warmPoolEligible(pinnedSession(""), pooled)).To(BeTrue())
+		Expect(warmPoolEligible(pinnedSession("worker-3"), pooled)).To(BeFalse())
+	})
+
+	It("Should report pinned nodes that can't take the pod", func() {
+		r := &SessionReconciler{Client: k8sClient}
+		reason := func(session *streamv1alpha1.Session) string {
+			Expect(r.updatePinnedCondition(ctx, session)).To(Succeed())
+			condition := meta.FindStatusCondition(session.Status.Conditions, pinnedCondition)
+			if condition == nil {
+				return ""
+			}
+			return condition.Reason
+		}
+
+		session := pinnedSession("pin-test-node")
+		Expect(reason(session)).To(Equal("NodeNotFound"))
+
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "pin-test-node"},
+			Spec:       corev1.NodeSpec{Unschedulable: true},
+		}
+		Expect(k8sClient.Create(ctx, node)).To(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, node)).To(Succeed()) }()
+		Expect(reason(session)).To(Equal("NodeUnschedulable"))
+
+		node.Spec.Unschedulable = false
+		Expect(k8sClient.Update(ctx, node)).To(Succeed())
+		Expect(reason(session)).To(Equal("NodeNotReady"))
+
+		node.Status.Conditions = []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}}
+		Expect(k8sClient.Status().Update(ctx, node)).To(Succeed())
+		Expect(reason(session)).To(Equal("NodeAvailable"))
+		Expect(meta.IsStatusConditionTrue(session.Status.Conditions, pinnedCondition)).To(BeTrue())
+
+		session.Spec.PinToNode = ""
+		Expect(reason(session)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Session Startup Timeout", func() {
+	waitingPod := func(status corev1.ContainerStatus) *corev1.Pod {
+		status.Name = "session"
+		return &corev1.Pod{Status: corev1.PodStatus{
+			Phase:             corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{status},
+		}}
+	}
+
+	It("Should resolve the timeout from session, template, then environment", func() {
+		session := &streamv1alpha1.Session{}
+		template := &streamv1alpha1.Template{}
+		GinkgoT().Setenv("SESSION_STARTUP_TIMEOUT", "")
+		Expect(startupTimeout(session, template)).To(Equal(defaultStartupTimeout))
+
+		GinkgoT().Setenv("SESSION_STARTUP_TIMEOUT", "0")
+		Expect(startupTimeout(session, template)).To(BeZero())
+
+		template.Spec.StartupTimeout = "20m"
+		Expect(startupTimeout(session, template)).To(Equal(20 * time.Minute))
+		session.Spec.StartupTimeout = "5m"
+		Expect(startupTimeout(session, template)).To(Equal(5 * time.Minute))
+	})
+
+	It("Should explain an image that can't be pulled", func() {
+		pod := waitingPod(corev1.ContainerStatus{
+			Image: "registry.example.com/app:v2",
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+				Reason:  "ImagePullBackOff",
+				Message: `Back-off pulling image "registry.example.com/app:v2"`,
+			}},
+		})
+		Expect(startupDiagnosis(&appsv1.Deployment{}, pod, nil)).To(Equal(
+			`container session: image registry.example.com/app:v2 could not be pulled (ImagePullBackOff): Back-off pulling image "registry.example.com/app:v2"`))
+	})
+
+	It("Should explain a crashing container with its last exit", func() {
+		pod := waitingPod(corev1.ContainerStatus{
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}},
+			LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+				Reason: "Error", ExitCode: 1,
+			}},
+		})
+		Expect(startupDiagnosis(&appsv1.Deployment{}, pod, nil)).To(Equal(
+			"container session keeps crashing (CrashLoopBackOff), last exit: Error (exit code 1)"))
+	})
+
+	It("Should explain an unschedulable pod and quote the latest warning", func() {
+		pod := &corev1.Pod{Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			Conditions: []corev1.PodCondition{{
+				Type:    corev1.PodScheduled,
+				Status:  corev1.ConditionFalse,
+				Message: "0/3 nodes are available: 3 Insufficient memory.",
+			}},
+		}}
+		Expect(startupDiagnosis(&appsv1.Deployment{}, pod, nil)).To(Equal(
+			"pod could not be scheduled: 0/3 nodes are available: 3 Insufficient memory."))
+
+		pod.Status.Conditions = nil
+		pod.Status.InitContainerStatuses = []corev1.ContainerStatus{{
+			Name:  "home-init",
+			State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 2}},
+		}}
+		events := []corev1.Event{{Reason: "FailedMount", Message: "MountVolume.SetUp failed for volume \"home\""}}
+		Expect(startupDiagnosis(&appsv1.Deployment{}, pod, events)).To(Equal(
+			`init container home-init failed: Error (exit code 2) (last warning: FailedMount: MountVolume.SetUp failed for volume "home")`))
+	})
+
+	It("Should explain a Deployment that couldn't create its pod", func() {
+		deployment := &appsv1.Deployment{Status: appsv1.DeploymentStatus{Conditions: []appsv1.DeploymentCondition{{
+			Type:    appsv1.DeploymentReplicaFailure,
+			Status:  corev1.ConditionTrue,
+			Message: `pods "s-abc" is forbidden: exceeded quota: compute`,
+		}}}}
+		Expect(startupDiagnosis(deployment, nil, nil)).To(Equal(
+			`no pod could be created: pods "s-abc" is forbidden: exceeded quota: compute`))
+	})
+
+	It("Should keep a timed-out session failed until its spec changes", func() {
+		session := &streamv1alpha1.Session{ObjectMeta: metav1.ObjectMeta{Generation: 2}}
+		session.Status.Phase = "Failed"
+		meta.SetStatusCondition(&session.Status.Conditions, metav1.Condition{
+			Type: readyCondition, Status: metav1.ConditionFalse, Reason: startupTimeoutReason, ObservedGeneration: 2,
+		})
+		Expect(startupFailed(session)).To(BeTrue())
+
+		session.Generation = 3
+		Expect(startupFailed(session)).To(BeFalse())
+	})
 })
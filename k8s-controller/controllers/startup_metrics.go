@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+	"github.com/streamspace/streamspace/pkg/metrics"
+)
+
+// Launch latency metrics.
+//
+// When a session first becomes ready, the time since it was created is
+// recorded in streamspace_session_time_to_ready_seconds, and split into
+// stages in streamspace_session_startup_stage_seconds so slow launches can
+// be pinned on the right component:
+//   - controller: session created -> pod created (controller and the
+//     Deployment/ReplicaSet controllers)
+//   - scheduling: pod created -> pod scheduled
+//   - image_pull: pod scheduled -> container started (image pull and
+//     container creation)
+//   - readiness: container started -> pod ready (app start-up)
+//
+// Sessions served from the warm pool skip the stages: their pod was started
+// before the session existed.
+
+// startupStage is one measured step of a session launch.
+type startupStage struct {
+	name     string
+	duration time.Duration
+}
+
+// recordStartup records the launch latency of a session that just became
+// ready for the first time.
+func (r *SessionReconciler) recordStartup(ctx context.Context, session *streamv1alpha1.Session, warmPool bool) {
+	readyAt := time.Now()
+	if session.Status.ReadyAt != nil {
+		readyAt = session.Status.ReadyAt.Time
+	}
+	metrics.ObserveTimeToReady(session.Namespace, session.Spec.Template, warmPool,
+		readyAt.Sub(session.CreationTimestamp.Time).Seconds())
+	if warmPool {
+		return
+	}
+
+	pod, err := r.sessionPod(ctx, session)
+	if err != nil || pod == nil {
+		log.FromContext(ctx).V(1).Info("Session pod not found, startup stages not recorded", "error", err)
+		return
+	}
+	for _, stage := range startupStages(session.CreationTimestamp.Time, pod, readyAt) {
+		metrics.ObserveStartupStage(session.Namespace, stage.name, stage.duration.Seconds())
+	}
+}
+
+// startupStages splits the launch of a session created at created into
+// stages, using the timestamps Kubernetes recorded on its pod. Stages whose
+// timestamps are missing or out of order are left out.
+func startupStages(created time.Time, pod *corev1.Pod, readyAt time.Time) []startupStage {
+	var scheduled, started time.Time
+	for _, condition := range pod.Status.Conditions {
+		switch condition.Type {
+		case corev1.PodScheduled:
+			if condition.Status == corev1.ConditionTrue {
+				scheduled = condition.LastTransitionTime.Time
+			}
+		case corev1.PodReady:
+			if condition.Status == corev1.ConditionTrue {
+				readyAt = condition.LastTransitionTime.Time
+			}
+		}
+	}
+	if status := sessionContainerStatus(pod); status != nil && status.State.Running != nil {
+		started = status.State.Running.StartedAt.Time
+	}
+
+	points := []struct {
+		name string
+		at   time.Time
+	}{
+		{"controller", pod.CreationTimestamp.Time},
+		{"scheduling", scheduled},
+		{"image_pull", started},
+		{"readiness", readyAt},
+	}
+
+	var stages []startupStage
+	previous := created
+	for _, point := range points {
+		if point.at.IsZero() {
+			// Unknown boundary: neither stage around it can be measured
+			previous = time.Time{}
+			continue
+		}
+		if !previous.IsZero() && !point.at.Before(previous) {
+			stages = append(stages, startupStage{name: point.name, duration: point.at.Sub(previous)})
+		}
+		previous = point.at
+	}
+	return stages
+}
+
+// reconcileErrorReason classifies a reconcile error for
+// streamspace_session_reconcile_errors_total: the API status reason
+// ("Conflict", "NotFound", "Forbidden", ...) or "Unknown".
+func reconcileErrorReason(err error) string {
+	if reason := apierrors.ReasonForError(err); reason != metav1.StatusReasonUnknown {
+		return string(reason)
+	}
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return string(metav1.StatusReasonTimeout)
+	case errors.Is(err, context.Canceled):
+		return "Canceled"
+	}
+	return "Unknown"
+}
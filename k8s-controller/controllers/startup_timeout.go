@@ -0,0 +1,276 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+	"github.com/streamspace/streamspace/pkg/metrics"
+)
+
+// Session startup timeout.
+//
+// A session whose pod never becomes ready (image can't be pulled, no node
+// has room, an init container or the app keeps failing) would otherwise sit
+// in Pending or CrashLooping until someone notices. Once a launch takes
+// longer than its startup timeout, the session is marked Failed with a
+// diagnosis built from the pod's conditions, container states and latest
+// warning event, the API is told (it notifies the owner), and the
+// Deployment is deleted so the pod stops holding resources.
+//
+// The clock starts when the Deployment is created, so time queued for a
+// launch slot doesn't count. Only first launches are timed: a session that
+// was ready before is covered by crash loop and eviction detection, and a
+// claimed warm pod was ready before the session existed.
+//
+// A timed-out session stays Failed until its spec changes (e.g. it is
+// hibernated and woken, or its startupTimeout is raised), which launches it
+// again.
+//
+// The timeout comes from the session's spec.startupTimeout, then the
+// template's, then the environment.
+//
+// Environment:
+//   - SESSION_STARTUP_TIMEOUT: default startup timeout (default 15m, 0
+//     disables it)
+
+const (
+	// defaultStartupTimeout is the default SESSION_STARTUP_TIMEOUT
+	defaultStartupTimeout = 15 * time.Minute
+
+	// startupTimeoutReason is the Ready condition reason of a session whose
+	// launch timed out
+	startupTimeoutReason = "StartupTimeout"
+)
+
+// startupTimeout returns the startup timeout of a session, or 0 if its
+// launch isn't timed.
+func startupTimeout(session *streamv1alpha1.Session, template *streamv1alpha1.Template) time.Duration {
+	for _, value := range []string{session.Spec.StartupTimeout, template.Spec.StartupTimeout} {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	if d, err := time.ParseDuration(os.Getenv("SESSION_STARTUP_TIMEOUT")); err == nil && d >= 0 {
+		return d
+	}
+	return defaultStartupTimeout
+}
+
+// startupFailed reports whether the session's current generation timed
+// out launching.
+func startupFailed(session *streamv1alpha1.Session) bool {
+	cond := meta.FindStatusCondition(session.Status.Conditions, readyCondition)
+	return session.Status.Phase == "Failed" && cond != nil &&
+		cond.Reason == startupTimeoutReason && cond.ObservedGeneration == session.Generation
+}
+
+// cleanUpFailedStartup deletes the Deployment of a session whose launch
+// timed out, if it still exists.
+func (r *SessionReconciler) cleanUpFailedStartup(ctx context.Context, session *streamv1alpha1.Session) error {
+	deployment := &appsv1.Deployment{}
+	err := r.Get(ctx, types.NamespacedName{Name: sessionDeploymentName(session), Namespace: session.Namespace}, deployment)
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := r.Delete(ctx, deployment); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	log.FromContext(ctx).Info("Deleted Deployment of timed-out launch", "name", deployment.Name)
+	return nil
+}
+
+// checkStartupTimeout fails a first launch that has been starting for
+// longer than its startup timeout.
+//
+// handled is true if the session was marked Failed; the caller should
+// return result.
+func (r *SessionReconciler) checkStartupTimeout(ctx context.Context, session *streamv1alpha1.Session, template *streamv1alpha1.Template, deployment *appsv1.Deployment) (handled bool, result ctrl.Result, err error) {
+	if session.Status.ReadyAt != nil || meta.IsStatusConditionTrue(session.Status.Conditions, readyCondition) {
+		return false, ctrl.Result{}, nil
+	}
+	timeout := startupTimeout(session, template)
+	if timeout <= 0 || deployment.CreationTimestamp.IsZero() || time.Since(deployment.CreationTimestamp.Time) < timeout {
+		// Not ready yet: the caller requeues until it is
+		return false, ctrl.Result{}, nil
+	}
+
+	pod, err := r.sessionPod(ctx, session)
+	if err != nil {
+		return false, ctrl.Result{}, err
+	}
+	if pod != nil && isPodReady(pod) {
+		// Became ready just now; the caller records it
+		return false, ctrl.Result{}, nil
+	}
+
+	var events []corev1.Event
+	if pod != nil {
+		events = r.podWarningEvents(ctx, pod)
+	}
+	message := fmt.Sprintf("Session did not become ready within %s: %s", timeout, startupDiagnosis(deployment, pod, events))
+	log.FromContext(ctx).Info("Session startup timed out", "session", session.Name, "timeout", timeout.String(), "diagnosis", message)
+
+	// Persist Failed before deleting anything, so a failed cleanup is
+	// retried rather than the launch restarted
+	session.Status.Phase = "Failed"
+	session.Status.ObservedGeneration = session.Generation
+	session.Status.PodName = ""
+	meta.SetStatusCondition(&session.Status.Conditions, metav1.Condition{
+		Type:               readyCondition,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: session.Generation,
+		Reason:             startupTimeoutReason,
+		Message:            message,
+	})
+	if err := r.Status().Update(ctx, session); err != nil {
+		return true, ctrl.Result{}, err
+	}
+
+	r.recordEvent(session, corev1.EventTypeWarning, startupTimeoutReason, message)
+	r.publishSessionStatus(session.Name, "failed", "Failed", "", "", message)
+	metrics.RecordSessionState("failed", session.Namespace, 1)
+	r.launches.release(types.NamespacedName{Namespace: session.Namespace, Name: session.Name}.String())
+
+	return true, ctrl.Result{}, r.cleanUpFailedStartup(ctx, session)
+}
+
+// podWarningEvents returns the pod's warning events, newest first. Events
+// are read from the API server (the cache doesn't hold them); without an
+// APIReader, or on error, there are none.
+func (r *SessionReconciler) podWarningEvents(ctx context.Context, pod *corev1.Pod) []corev1.Event {
+	if r.APIReader == nil {
+		return nil
+	}
+	list := &corev1.EventList{}
+	if err := r.APIReader.List(ctx, list, client.InNamespace(pod.Namespace), client.MatchingFields{
+		"involvedObject.kind": "Pod",
+		"involvedObject.name": pod.Name,
+	}); err != nil {
+		log.FromContext(ctx).V(1).Info("Failed to list pod events", "pod", pod.Name, "error", err.Error())
+		return nil
+	}
+
+	var warnings []corev1.Event
+	for _, event := range list.Items {
+		if event.Type == corev1.EventTypeWarning {
+			warnings = append(warnings, event)
+		}
+	}
+	sort.Slice(warnings, func(i, j int) bool {
+		return eventTime(warnings[i]).After(eventTime(warnings[j]))
+	})
+	return warnings
+}
+
+// eventTime returns when an event last occurred.
+func eventTime(event corev1.Event) time.Time {
+	switch {
+	case !event.LastTimestamp.IsZero():
+		return event.LastTimestamp.Time
+	case !event.EventTime.IsZero():
+		return event.EventTime.Time
+	}
+	return event.CreationTimestamp.Time
+}
+
+// startupDiagnosis explains in plain words why a session's pod isn't ready:
+// the first problem found in its scheduling, init containers and session
+// container, followed by the latest warning event (newest first in events).
+func startupDiagnosis(deployment *appsv1.Deployment, pod *corev1.Pod, events []corev1.Event) string {
+	if pod == nil {
+		for _, cond := range deployment.Status.Conditions {
+			if cond.Type == appsv1.DeploymentReplicaFailure && cond.Status == corev1.ConditionTrue {
+				return "no pod could be created: " + cond.Message
+			}
+		}
+		return "no pod was created"
+	}
+
+	diagnosis := podDiagnosis(pod)
+	if len(events) > 0 && !strings.Contains(diagnosis, events[0].Message) {
+		diagnosis += fmt.Sprintf(" (last warning: %s: %s)", events[0].Reason, events[0].Message)
+	}
+	return diagnosis
+}
+
+// podDiagnosis describes the first problem found with a pod that isn't
+// ready.
+func podDiagnosis(pod *corev1.Pod) string {
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse {
+			return "pod could not be scheduled: " + cond.Message
+		}
+	}
+
+	for i := range pod.Status.InitContainerStatuses {
+		status := &pod.Status.InitContainerStatuses[i]
+		if terminated := status.State.Terminated; terminated != nil && terminated.ExitCode != 0 {
+			return fmt.Sprintf("init container %s failed: %s", status.Name, terminationSummary(terminated))
+		}
+		if status.State.Waiting != nil && status.State.Waiting.Reason != "PodInitializing" {
+			return "init " + containerWaitingDiagnosis(status)
+		}
+	}
+
+	status := sessionContainerStatus(pod)
+	switch {
+	case status == nil:
+		return fmt.Sprintf("pod is %s", pod.Status.Phase)
+	case status.State.Waiting != nil:
+		return containerWaitingDiagnosis(status)
+	case status.State.Running != nil:
+		return fmt.Sprintf("container %s is running but never passed its readiness check", status.Name)
+	case status.State.Terminated != nil:
+		return fmt.Sprintf("container %s exited: %s", status.Name, terminationSummary(status.State.Terminated))
+	}
+	return fmt.Sprintf("pod is %s", pod.Status.Phase)
+}
+
+// containerWaitingDiagnosis describes why a container is waiting.
+func containerWaitingDiagnosis(status *corev1.ContainerStatus) string {
+	waiting := status.State.Waiting
+	var diagnosis string
+	switch waiting.Reason {
+	case "ErrImagePull", "ImagePullBackOff", "InvalidImageName":
+		diagnosis = fmt.Sprintf("container %s: image %s could not be pulled (%s)", status.Name, status.Image, waiting.Reason)
+	case "CrashLoopBackOff":
+		diagnosis = fmt.Sprintf("container %s keeps crashing (CrashLoopBackOff)", status.Name)
+		if last := status.LastTerminationState.Terminated; last != nil {
+			diagnosis += ", last exit: " + terminationSummary(last)
+		}
+		return diagnosis
+	default:
+		diagnosis = fmt.Sprintf("container %s is waiting (%s)", status.Name, waiting.Reason)
+	}
+	if waiting.Message != "" {
+		diagnosis += ": " + waiting.Message
+	}
+	return diagnosis
+}
+
+// terminationSummary describes how a container terminated.
+func terminationSummary(terminated *corev1.ContainerStateTerminated) string {
+	summary := fmt.Sprintf("%s (exit code %d)", terminated.Reason, terminated.ExitCode)
+	if terminated.Message != "" {
+		summary += ": " + terminated.Message
+	}
+	return summary
+}
@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"os"
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+// catalogIDLabel is set on templates installed from the catalog.
+const catalogIDLabel = "stream.space/catalog-id"
+
+// Streaming features.
+//
+// A template's spec.vnc turns clipboard sync, audio and file transfer on or
+// off and can cap the desktop resolution. The session container gets the
+// effective settings as environment variables, which images that support
+// them read at startup, and the session's status.streamingFeatures reports
+// them so clients only offer what works.
+//
+// Untrusted templates (spec.securityContext.untrusted) never get clipboard
+// sync or file transfer, whatever spec.vnc enables, so data can't move
+// between the user's machine and untrusted content.
+//
+// Container environment:
+//   - STREAMSPACE_CLIPBOARD, STREAMSPACE_AUDIO, STREAMSPACE_FILE_TRANSFER:
+//     "true" or "false"
+//   - STREAMSPACE_MAX_RESOLUTION: WIDTHxHEIGHT, set only when capped
+//
+// Environment:
+//   - SESSION_UNTRUSTED_CATALOG_TEMPLATES: set to "true" to also treat
+//     templates installed from the catalog as untrusted (default false)
+
+// untrustedCatalogTemplates reports whether SESSION_UNTRUSTED_CATALOG_TEMPLATES
+// treats templates installed from the catalog as untrusted.
+func untrustedCatalogTemplates() bool {
+	untrusted, _ := strconv.ParseBool(os.Getenv("SESSION_UNTRUSTED_CATALOG_TEMPLATES"))
+	return untrusted
+}
+
+// templateUntrusted reports whether security policy restricts data transfer
+// in sessions of template.
+func templateUntrusted(template *streamv1alpha1.Template) bool {
+	if sc := template.Spec.SecurityContext; sc != nil && sc.Untrusted {
+		return true
+	}
+	_, fromCatalog := template.Labels[catalogIDLabel]
+	return fromCatalog && untrustedCatalogTemplates()
+}
+
+// streamingFeatures returns the effective streaming features of sessions of
+// template.
+func streamingFeatures(template *streamv1alpha1.Template) *streamv1alpha1.StreamingFeatures {
+	vnc := template.Spec.VNC
+	features := &streamv1alpha1.StreamingFeatures{
+		Clipboard:     vnc.Clipboard == nil || *vnc.Clipboard,
+		Audio:         hasCapability(template, "Audio"),
+		FileTransfer:  vnc.FileTransfer == nil || *vnc.FileTransfer,
+		MaxResolution: vnc.MaxResolution,
+	}
+	if vnc.Audio != nil {
+		features.Audio = *vnc.Audio
+	}
+	if templateUntrusted(template) {
+		features.Restricted = true
+		features.Clipboard = false
+		features.FileTransfer = false
+	}
+	return features
+}
+
+// hasCapability reports whether template lists capability.
+func hasCapability(template *streamv1alpha1.Template, capability string) bool {
+	for _, c := range template.Spec.Capabilities {
+		if c == capability {
+			return true
+		}
+	}
+	return false
+}
+
+// streamingFeatureEnv returns the container environment variables for
+// features.
+func streamingFeatureEnv(features *streamv1alpha1.StreamingFeatures) []corev1.EnvVar {
+	env := []corev1.EnvVar{
+		{Name: "STREAMSPACE_CLIPBOARD", Value: strconv.FormatBool(features.Clipboard)},
+		{Name: "STREAMSPACE_AUDIO", Value: strconv.FormatBool(features.Audio)},
+		{Name: "STREAMSPACE_FILE_TRANSFER", Value: strconv.FormatBool(features.FileTransfer)},
+	}
+	if features.MaxResolution != "" {
+		env = append(env, corev1.EnvVar{Name: "STREAMSPACE_MAX_RESOLUTION", Value: features.MaxResolution})
+	}
+	return env
+}
@@ -151,12 +151,22 @@ package controllers
 
 import (
 	"context"
+	"fmt"
+	"time"
 
+	"github.com/nats-io/nats.go"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
 
 	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
 	"github.com/streamspace/streamspace/pkg/metrics"
@@ -171,10 +181,16 @@ import (
 //
 // - Client: Kubernetes client for reading/writing Templates
 // - Scheme: Runtime scheme for type information
+// - ImageChecker: Checks the base image still exists (optional)
+// - NATSConn: Publishes template validity changes to the API (optional)
+// - Recorder: Records validity changes as events on Templates (optional)
+// - RevalidateInterval: How often templates are revalidated (0 = only on changes)
 //
 // RBAC PERMISSIONS (defined by kubebuilder markers below):
 //
 // Templates: get, list, watch, create, update, patch, delete, update status
+// Secrets: get (image pull secrets must exist)
+// Events: create (validity changes)
 //
 // WHY THESE PERMISSIONS:
 //
@@ -187,7 +203,7 @@ import (
 // Controller reconciles when:
 // - New Template created
 // - Template spec updated (baseImage changed, VNC config changed)
-// - Periodic resync (default: 10 hours)
+// - Periodic revalidation (RevalidateInterval, see template_revalidation.go)
 //
 // Note: Templates are typically created once and rarely updated,
 // so reconciliation frequency is low.
@@ -199,18 +215,24 @@ import (
 // - Immediate status update
 // - Easy to understand and debug
 //
-// Asynchronous validation (not implemented):
-// - Could validate image existence
-// - Could test container startup
-// - Adds complexity, delayed feedback
+// References (image pull secrets, the base image in its registry) are
+// checked on every validation, which also runs periodically because they
+// can disappear after the template was accepted.
 type TemplateReconciler struct {
 	client.Client  // Kubernetes API client
 	Scheme *runtime.Scheme  // Type information for objects
+
+	ImageChecker       ImageChecker         // Checks the base image exists (optional)
+	NATSConn           *nats.Conn           // Publishes validity changes (optional)
+	Recorder           record.EventRecorder // Records validity changes on Templates (optional)
+	RevalidateInterval time.Duration        // Periodic revalidation (0 = only on changes)
 }
 
 //+kubebuilder:rbac:groups=stream.streamspace.io,resources=templates,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=stream.streamspace.io,resources=templates/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=stream.streamspace.io,resources=templates/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
 
 // Reconcile is the main reconciliation loop for Template resources.
 //
@@ -224,8 +246,10 @@ type TemplateReconciler struct {
 // 3. Apply default values to spec (e.g., VNC port defaults to 5900)
 // 4. Persist defaults back to API server
 // 5. Validate template fields (baseImage, displayName, VNC config)
-// 6. Update status with validation results
-// 7. Record metrics for monitoring
+// 6. Check references (image pull secrets, base image in its registry)
+// 7. Update status with validation results and lastValidatedAt
+// 8. Record metrics for monitoring
+// 9. Requeue after RevalidateInterval to catch references that disappear
 //
 // DEFAULT VALUE HANDLING:
 //
@@ -269,9 +293,13 @@ type TemplateReconciler struct {
 //   - Valid templates: "Template is valid and ready to use"
 //   - Invalid templates: Error message explaining what's wrong
 //
+// A valid template turning invalid (or back) is reported with an event and
+// on NATS, see reportValidityChange.
+//
 // BUG FIX: Invalid templates return nil instead of error after status update.
 // Previously, returning error caused retry loops even after status was updated.
-// The status already indicates the problem, no need to requeue.
+// The status already indicates the problem; it is only revalidated on the
+// regular interval.
 //
 // FUTURE ENHANCEMENTS:
 //
@@ -332,14 +360,38 @@ func (r *TemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		}
 	}
 
-	// Validate template configuration
+	// Revalidate periodically: the image or secrets may disappear later
+	result := ctrl.Result{RequeueAfter: r.RevalidateInterval}
+	wasValid := template.Status.Valid
+	wasInvalid := meta.IsStatusConditionFalse(template.Status.Conditions, validatedCondition)
+	now := metav1.Now()
+	template.Status.LastValidatedAt = &now
+
+	// Validate template configuration, then what it refers to
 	// Validation is now read-only (doesn't mutate the template)
 	// All mutations happen above in the defaults section
-	if err := r.validateTemplate(&template); err != nil {
+	err := r.validateTemplate(&template)
+	if err == nil {
+		err = r.validateReferences(ctx, &template)
+		if err != nil && !isTemplateInvalid(err) {
+			// Not a verdict on the template (e.g. API server error)
+			log.Error(err, "Failed to check Template references")
+			return ctrl.Result{}, err
+		}
+	}
+	if err != nil {
 		// Validation failed - mark template as invalid
-		log.Error(err, "Template validation failed")
+		reason := invalidReason(err)
+		log.Error(err, "Template validation failed", "reason", reason)
 		template.Status.Valid = false
 		template.Status.Message = err.Error()
+		meta.SetStatusCondition(&template.Status.Conditions, metav1.Condition{
+			Type:               validatedCondition,
+			Status:             metav1.ConditionFalse,
+			ObservedGeneration: template.Generation,
+			Reason:             reason,
+			Message:            err.Error(),
+		})
 		metrics.RecordTemplateValidation(req.Namespace, "invalid")
 
 		// Update status to reflect validation failure
@@ -348,16 +400,26 @@ func (r *TemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 			log.Error(updateErr, "Failed to update Template status")
 			return ctrl.Result{}, updateErr
 		}
+		if wasValid {
+			r.reportValidityChange(ctx, &template, reason)
+		}
 
 		// BUG FIX: Return nil instead of err after successful status update
 		// Returning err here causes retry loop even though status was updated correctly
 		// The status.Valid=false already indicates the problem to users
-		return ctrl.Result{}, nil
+		return result, nil
 	}
 
 	// Validation passed - mark template as valid
 	template.Status.Valid = true
 	template.Status.Message = "Template is valid and ready to use"
+	meta.SetStatusCondition(&template.Status.Conditions, metav1.Condition{
+		Type:               validatedCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: template.Generation,
+		Reason:             templateReasonValid,
+		Message:            template.Status.Message,
+	})
 	metrics.RecordTemplateValidation(req.Namespace, "valid")
 
 	// Update status to reflect successful validation
@@ -366,8 +428,13 @@ func (r *TemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 		return ctrl.Result{}, err
 	}
 
+	// A template that had failed validation recovered; a new one is just valid
+	if wasInvalid {
+		r.reportValidityChange(ctx, &template, templateReasonValid)
+	}
+
 	log.Info("Template reconciliation complete", "name", template.Name, "valid", template.Status.Valid)
-	return ctrl.Result{}, nil
+	return result, nil
 }
 
 // validateTemplate performs validation on template fields.
@@ -385,6 +452,14 @@ func (r *TemplateReconciler) Reconcile(ctx context.Context, req ctrl.Request) (c
 //    - Port must be set (should be set by defaults in Reconcile())
 //    - Port must be in range 1024-65535
 //
+// 3. Session defaults (if set):
+//    - defaultIdleTimeout and defaultMaxSessionDuration must parse as durations
+//
+// 4. Scheduling (if set):
+//    - homeVolumeAccessMode must be ReadWriteMany or ReadWriteOnce
+//    - sessionAffinity must be None, Preferred or Required
+//    - deploymentStrategy must be Recreate or RollingUpdate
+//
 // PORT RANGE RATIONALE:
 //
 // - Ports < 1024 are privileged (require root)
@@ -442,6 +517,82 @@ func (r *TemplateReconciler) validateTemplate(template *streamv1alpha1.Template)
 		}
 	}
 
+	// Session lifecycle defaults must be valid durations, otherwise every
+	// session inheriting them would silently lose hibernation/termination
+	if template.Spec.DefaultIdleTimeout != "" {
+		if _, err := time.ParseDuration(template.Spec.DefaultIdleTimeout); err != nil {
+			return errors.NewBadRequest(fmt.Sprintf("defaultIdleTimeout is not a valid duration: %q", template.Spec.DefaultIdleTimeout))
+		}
+	}
+	if template.Spec.DefaultMaxSessionDuration != "" {
+		if _, err := time.ParseDuration(template.Spec.DefaultMaxSessionDuration); err != nil {
+			return errors.NewBadRequest(fmt.Sprintf("defaultMaxSessionDuration is not a valid duration: %q", template.Spec.DefaultMaxSessionDuration))
+		}
+	}
+
+	if err := validateParameterSchema(template.Spec.Parameters); err != nil {
+		return errors.NewBadRequest(err.Error())
+	}
+
+	if err := validateCompanionResources(template.Spec.CompanionResources); err != nil {
+		return errors.NewBadRequest(err.Error())
+	}
+
+	// Scheduling options (CRD enum validation may not be installed everywhere)
+	switch template.Spec.HomeVolumeAccessMode {
+	case "", corev1.ReadWriteMany, corev1.ReadWriteOnce:
+	default:
+		return errors.NewBadRequest(fmt.Sprintf("homeVolumeAccessMode must be ReadWriteMany or ReadWriteOnce, got %q", template.Spec.HomeVolumeAccessMode))
+	}
+	switch template.Spec.SessionAffinity {
+	case "", streamv1alpha1.SessionAffinityNone, streamv1alpha1.SessionAffinityPreferred, streamv1alpha1.SessionAffinityRequired:
+	default:
+		return errors.NewBadRequest(fmt.Sprintf("sessionAffinity must be None, Preferred or Required, got %q", template.Spec.SessionAffinity))
+	}
+	switch template.Spec.DeploymentStrategy {
+	case "", appsv1.RecreateDeploymentStrategyType, appsv1.RollingUpdateDeploymentStrategyType:
+	default:
+		return errors.NewBadRequest(fmt.Sprintf("deploymentStrategy must be Recreate or RollingUpdate, got %q", template.Spec.DeploymentStrategy))
+	}
+	switch template.Spec.DefaultPriority {
+	case "", streamv1alpha1.SessionPriorityLow, streamv1alpha1.SessionPriorityNormal, streamv1alpha1.SessionPriorityHigh:
+	default:
+		return errors.NewBadRequest(fmt.Sprintf("defaultPriority must be Low, Normal or High, got %q", template.Spec.DefaultPriority))
+	}
+
+	for _, port := range template.Spec.ForwardablePorts {
+		if port < 1 || port > 65535 {
+			return errors.NewBadRequest(fmt.Sprintf("forwardablePorts contains invalid port %d", port))
+		}
+	}
+
+	switch template.Spec.NetworkEgress {
+	case "", streamv1alpha1.NetworkEgressNone, streamv1alpha1.NetworkEgressInternal, streamv1alpha1.NetworkEgressInternet:
+	default:
+		return errors.NewBadRequest(fmt.Sprintf("networkEgress must be None, Internal or Internet, got %q", template.Spec.NetworkEgress))
+	}
+
+	if template.Spec.WarmPoolSize < 0 {
+		return errors.NewBadRequest(fmt.Sprintf("warmPoolSize must not be negative, got %d", template.Spec.WarmPoolSize))
+	}
+
+	if template.Spec.MaxConcurrentLaunches < 0 {
+		return errors.NewBadRequest(fmt.Sprintf("maxConcurrentLaunches must not be negative, got %d", template.Spec.MaxConcurrentLaunches))
+	}
+
+	for _, backend := range template.Spec.SupportedBackends {
+		switch backend {
+		case "kubernetes", "docker", "hyperv", "vcenter":
+		default:
+			return errors.NewBadRequest(fmt.Sprintf("supportedBackends contains unknown backend %q", backend))
+		}
+	}
+
+	// Root, privileged mode, added capabilities etc. need an explicit opt-in
+	if err := validateSecurityContext(template.Spec.SecurityContext, privilegedTemplatesAllowed()); err != nil {
+		return errors.NewBadRequest(err.Error())
+	}
+
 	return nil
 }
 
@@ -464,6 +615,7 @@ func (r *TemplateReconciler) validateTemplate(template *streamv1alpha1.Template)
 //   - Template spec updated
 //   - Template deleted
 //   - Periodic resync (default: 10 hours)
+//   - Every RevalidateInterval (RequeueAfter)
 //
 // OWNERSHIP:
 //
@@ -472,14 +624,14 @@ func (r *TemplateReconciler) validateTemplate(template *streamv1alpha1.Template)
 //   - Sessions reference Templates but don't have owner references
 //   - Deleting a Template doesn't delete Sessions (intentional)
 //
-// FUTURE ENHANCEMENTS:
+// EVENT FILTERING:
 //
-// TODO: Add event filtering predicates:
-//   - Only reconcile on spec changes (ignore status updates)
-//   - Filter out metadata-only changes
-//   - Reduce unnecessary reconciliation loops
+// Only spec changes (generation bumps) trigger a reconcile. Status updates
+// are ignored: every validation stamps status.lastValidatedAt, so reacting
+// to it would revalidate (and query the registry) in a loop. Periodic
+// revalidation comes from RequeueAfter instead.
 func (r *TemplateReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&streamv1alpha1.Template{}).
+		For(&streamv1alpha1.Template{}, builder.WithPredicates(predicate.GenerationChangedPredicate{})).
 		Complete(r)
 }
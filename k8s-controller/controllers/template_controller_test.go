@@ -2,11 +2,18 @@ package controllers
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 
@@ -183,3 +190,140 @@ var _ = Describe("Template Controller", func() {
 		})
 	})
 })
+
+var _ = Describe("Template Revalidation", func() {
+	const (
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	It("Should record when a template was validated", func() {
+		ctx := context.Background()
+
+		template := &streamv1alpha1.Template{
+			ObjectMeta: metav1.ObjectMeta{Name: "validated-template", Namespace: "default"},
+			Spec: streamv1alpha1.TemplateSpec{
+				DisplayName: "Validated Template",
+				BaseImage:   "lscr.io/linuxserver/firefox:latest",
+			},
+		}
+		Expect(k8sClient.Create(ctx, template)).To(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, template)).To(Succeed()) }()
+
+		created := &streamv1alpha1.Template{}
+		Eventually(func() *metav1.Time {
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: "validated-template", Namespace: "default"}, created); err != nil {
+				return nil
+			}
+			return created.Status.LastValidatedAt
+		}, timeout, interval).ShouldNot(BeNil())
+
+		Expect(created.Status.Valid).To(BeTrue())
+		condition := meta.FindStatusCondition(created.Status.Conditions, validatedCondition)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Reason).To(Equal(templateReasonValid))
+	})
+
+	It("Should mark a template whose image pull secret is missing invalid", func() {
+		ctx := context.Background()
+
+		template := &streamv1alpha1.Template{
+			ObjectMeta: metav1.ObjectMeta{Name: "missing-secret-template", Namespace: "default"},
+			Spec: streamv1alpha1.TemplateSpec{
+				DisplayName:      "Private Template",
+				BaseImage:        "registry.example.com/apps/private:1.0",
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "deleted-pull-secret"}},
+			},
+		}
+		Expect(k8sClient.Create(ctx, template)).To(Succeed())
+		defer func() { Expect(k8sClient.Delete(ctx, template)).To(Succeed()) }()
+
+		created := &streamv1alpha1.Template{}
+		Eventually(func() string {
+			if err := k8sClient.Get(ctx, types.NamespacedName{Name: "missing-secret-template", Namespace: "default"}, created); err != nil {
+				return ""
+			}
+			if condition := meta.FindStatusCondition(created.Status.Conditions, validatedCondition); condition != nil {
+				return condition.Reason
+			}
+			return ""
+		}, timeout, interval).Should(Equal(templateReasonSecretMissing))
+
+		Expect(created.Status.Valid).To(BeFalse())
+		Expect(created.Status.Message).To(ContainSubstring(`"deleted-pull-secret" not found`))
+		Expect(created.Status.LastValidatedAt).NotTo(BeNil())
+	})
+
+	It("Should read the revalidation interval from the environment", func() {
+		GinkgoT().Setenv("TEMPLATE_REVALIDATION_INTERVAL", "")
+		Expect(TemplateRevalidationIntervalFromEnv()).To(Equal(defaultTemplateRevalidationInterval))
+		GinkgoT().Setenv("TEMPLATE_REVALIDATION_INTERVAL", "30m")
+		Expect(TemplateRevalidationIntervalFromEnv()).To(Equal(30 * time.Minute))
+		GinkgoT().Setenv("TEMPLATE_REVALIDATION_INTERVAL", "0")
+		Expect(TemplateRevalidationIntervalFromEnv()).To(BeZero())
+	})
+})
+
+var _ = Describe("Template Image Check", func() {
+	It("Should parse image references like container runtimes", func() {
+		ref, err := parseImageReference("nginx")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ref).To(Equal(imageReference{Registry: "docker.io", Repository: "library/nginx", Reference: "latest"}))
+		Expect(ref.host()).To(Equal("registry-1.docker.io"))
+
+		ref, err = parseImageReference("lscr.io/linuxserver/firefox:1.2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ref).To(Equal(imageReference{Registry: "lscr.io", Repository: "linuxserver/firefox", Reference: "1.2"}))
+
+		ref, err = parseImageReference("localhost:5000/team/app:v1@sha256:abc")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ref).To(Equal(imageReference{Registry: "localhost:5000", Repository: "team/app", Reference: "sha256:abc"}))
+
+		_, err = parseImageReference("registry.example.com/")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("Should find images in a registry with token authentication", func() {
+		var server *httptest.Server
+		server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/token" {
+				user, pass, _ := r.BasicAuth()
+				if user != "robot" || pass != "s3cret" || r.URL.Query().Get("scope") != "repository:apps/firefox:pull,push" {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				w.Write([]byte(`{"token": "t0k"}`))
+				return
+			}
+			if r.Header.Get("Authorization") != "Bearer t0k" {
+				w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="test",scope="repository:apps/firefox:pull,push"`, server.URL))
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.URL.Path == "/v2/apps/firefox/manifests/1.0" {
+				w.WriteHeader(http.StatusOK)
+				return
+			}
+			w.WriteHeader(http.StatusNotFound)
+		}))
+		defer server.Close()
+
+		host := strings.TrimPrefix(server.URL, "https://")
+		pullSecret := corev1.Secret{Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(fmt.Sprintf(`{"auths": {"https://%s/v1/": {"auth": "%s"}}}`,
+				host, base64.StdEncoding.EncodeToString([]byte("robot:s3cret")))),
+		}}
+		checker := &RegistryImageChecker{Client: server.Client()}
+		ctx := context.Background()
+
+		Expect(checker.CheckImage(ctx, host+"/apps/firefox:1.0", []corev1.Secret{pullSecret})).To(Succeed())
+		Expect(checker.CheckImage(ctx, host+"/apps/firefox:2.0", []corev1.Secret{pullSecret})).To(MatchError(ErrImageNotFound))
+	})
+
+	It("Should not report an image missing when the registry is unreachable", func() {
+		checker := &RegistryImageChecker{Client: &http.Client{Timeout: time.Second}}
+		err := checker.CheckImage(context.Background(), "127.0.0.1:1/apps/firefox:1.0", nil)
+		Expect(err).To(HaveOccurred())
+		Expect(errors.Is(err, ErrImageNotFound)).To(BeFalse())
+	})
+})
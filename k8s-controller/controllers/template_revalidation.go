@@ -0,0 +1,190 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+// Template revalidation.
+//
+// A template is validated when it is created or changed, but what it refers
+// to can disappear later: the base image is deleted from the registry, an
+// image pull secret is removed. Templates are therefore revalidated
+// periodically, and every validation also checks the references:
+//   - each spec.imagePullSecrets Secret exists
+//   - the base image exists in its registry (if an ImageChecker is set)
+//
+// status.lastValidatedAt records the last validation. A template that turns
+// invalid gets valid=false, the reason in status.message and the Validated
+// condition, a Warning event, and a template status event on NATS, which
+// the API turns into a notification for admins. A template whose reference
+// comes back is marked valid again at the next revalidation.
+//
+// Environment:
+//   - TEMPLATE_REVALIDATION_INTERVAL: how often templates are revalidated
+//     (default 6h; 0 only validates on changes)
+//   - TEMPLATE_IMAGE_CHECK: "false" skips the registry check, e.g. when the
+//     controller can't reach the registries nodes pull from
+
+// defaultTemplateRevalidationInterval is how often templates are revalidated.
+const defaultTemplateRevalidationInterval = 6 * time.Hour
+
+// validatedCondition is the template condition reporting validation.
+const validatedCondition = "Validated"
+
+// Validated condition reasons.
+const (
+	templateReasonValid         = "Valid"
+	templateReasonSpecInvalid   = "SpecInvalid"
+	templateReasonSecretMissing = "ImagePullSecretMissing"
+	templateReasonImageNotFound = "ImageNotFound"
+)
+
+// templateStatusSubject is where template validity changes are published.
+const templateStatusSubject = "streamspace.template.status"
+
+// TemplateRevalidationIntervalFromEnv reads TEMPLATE_REVALIDATION_INTERVAL.
+func TemplateRevalidationIntervalFromEnv() time.Duration {
+	v := os.Getenv("TEMPLATE_REVALIDATION_INTERVAL")
+	if v == "" {
+		return defaultTemplateRevalidationInterval
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return defaultTemplateRevalidationInterval
+	}
+	return d
+}
+
+// TemplateImageCheckEnabled reads TEMPLATE_IMAGE_CHECK (default true).
+func TemplateImageCheckEnabled() bool {
+	enabled, err := strconv.ParseBool(os.Getenv("TEMPLATE_IMAGE_CHECK"))
+	return err != nil || enabled
+}
+
+// templateInvalidError is a failed validation and its condition reason.
+type templateInvalidError struct {
+	reason string
+	err    error
+}
+
+func (e *templateInvalidError) Error() string { return e.err.Error() }
+
+func (e *templateInvalidError) Unwrap() error { return e.err }
+
+// invalidReason returns the Validated condition reason of a validation error.
+func invalidReason(err error) string {
+	var invalid *templateInvalidError
+	if errors.As(err, &invalid) {
+		return invalid.reason
+	}
+	return templateReasonSpecInvalid
+}
+
+// isTemplateInvalid reports whether a reference check failed because of the
+// template rather than e.g. an API server error.
+func isTemplateInvalid(err error) bool {
+	var invalid *templateInvalidError
+	return errors.As(err, &invalid)
+}
+
+// validateReferences checks that what the template refers to still exists.
+//
+// An image check that can't reach a verdict is logged and ignored; only a
+// registry answering "not found" makes the template invalid.
+func (r *TemplateReconciler) validateReferences(ctx context.Context, template *streamv1alpha1.Template) error {
+	log := log.FromContext(ctx)
+
+	pullSecrets := make([]corev1.Secret, 0, len(template.Spec.ImagePullSecrets))
+	for _, ref := range template.Spec.ImagePullSecrets {
+		secret := corev1.Secret{}
+		err := r.Get(ctx, types.NamespacedName{Name: ref.Name, Namespace: template.Namespace}, &secret)
+		if apierrors.IsNotFound(err) {
+			return &templateInvalidError{
+				reason: templateReasonSecretMissing,
+				err:    fmt.Errorf("image pull secret %q not found", ref.Name),
+			}
+		}
+		if err != nil {
+			return err
+		}
+		pullSecrets = append(pullSecrets, secret)
+	}
+
+	if r.ImageChecker == nil {
+		return nil
+	}
+	err := r.ImageChecker.CheckImage(ctx, template.Spec.BaseImage, pullSecrets)
+	if errors.Is(err, ErrImageNotFound) {
+		return &templateInvalidError{
+			reason: templateReasonImageNotFound,
+			err:    fmt.Errorf("base image %s no longer exists in its registry", template.Spec.BaseImage),
+		}
+	}
+	if err != nil {
+		log.Info("Could not check template image, keeping validation result", "template", template.Name, "image", template.Spec.BaseImage, "error", err.Error())
+	}
+	return nil
+}
+
+// TemplateStatusEvent is published to NATS when a template's validity
+// changes, so the API can notify admins.
+type TemplateStatusEvent struct {
+	EventID         string    `json:"event_id"`
+	Timestamp       time.Time `json:"timestamp"`
+	TemplateName    string    `json:"template_name"`
+	Namespace       string    `json:"namespace"`
+	Valid           bool      `json:"valid"`
+	Reason          string    `json:"reason"`
+	Message         string    `json:"message"`
+	LastValidatedAt time.Time `json:"last_validated_at"`
+}
+
+// reportValidityChange announces a template turning invalid or valid again.
+func (r *TemplateReconciler) reportValidityChange(ctx context.Context, template *streamv1alpha1.Template, reason string) {
+	log := log.FromContext(ctx)
+
+	if r.Recorder != nil {
+		eventType := corev1.EventTypeNormal
+		if !template.Status.Valid {
+			eventType = corev1.EventTypeWarning
+		}
+		r.Recorder.Event(template, eventType, reason, template.Status.Message)
+	}
+
+	if r.NATSConn == nil {
+		return
+	}
+	event := TemplateStatusEvent{
+		EventID:      uuid.New().String(),
+		Timestamp:    time.Now(),
+		TemplateName: template.Name,
+		Namespace:    template.Namespace,
+		Valid:        template.Status.Valid,
+		Reason:       reason,
+		Message:      template.Status.Message,
+	}
+	if template.Status.LastValidatedAt != nil {
+		event.LastValidatedAt = template.Status.LastValidatedAt.Time
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	if err := r.NATSConn.Publish(templateStatusSubject, data); err != nil {
+		log.Error(err, "Failed to publish template status", "template", template.Name)
+	}
+}
@@ -0,0 +1,458 @@
+// Package controllers implements Kubernetes controllers for StreamSpace.
+//
+// WARM POOL CONTROLLER
+//
+// The WarmPoolReconciler keeps a pool of ready, unassigned session pods for
+// templates that set spec.warmPoolSize, so popular templates launch in about
+// a second instead of waiting for an image pull and container start.
+//
+// POOL LIFECYCLE:
+//
+//  1. WarmPoolReconciler creates warmPoolSize pods from the template
+//     (labels: app=streamspace-warm-pool, stream.space/warm-pool=ready)
+//  2. SessionReconciler launches a session by claiming a ready pod: it
+//     relabels the pod for the session (app/user/template/session), marks
+//     it stream.space/warm-pool=claimed and moves ownership to the Session
+//  3. The session's Service selects the claimed pod through those labels
+//  4. WarmPoolReconciler notices the pool shrank and creates a replacement
+//
+// Claims use the pod's resourceVersion, so two sessions racing for the same
+// pod can't both win: the loser gets a conflict and tries the next pod.
+//
+// AFFINITY:
+//
+// Warm pods are already scheduled, so the template's session affinity is
+// applied when picking one: with Required affinity (or a ReadWriteOnce home)
+// only pods on a node running another session of the user qualify, with
+// Preferred affinity those pods are tried first.
+//
+// PERSISTENT HOME:
+//
+// A running pod's volumes can't be changed. A persistent-home session claims
+// a warm pod like any other, then replaces it with a copy on the same node
+// that mounts the user's home PVC. The image is already on that node, so
+// only the container start remains.
+//
+// FALLBACK:
+//
+// If the pool has no suitable pod (or the session needs its own resources,
+// parameters or priority), the SessionReconciler creates a Deployment as
+// usual.
+//
+// OWNERSHIP:
+//
+//   - Unclaimed pods are owned by the Template (deleted with it)
+//   - Claimed pods are owned by the Session (deleted with it)
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+)
+
+const (
+	// warmPoolLabel marks pods created by the warm pool and their state
+	warmPoolLabel = "stream.space/warm-pool"
+
+	// Values of warmPoolLabel
+	warmPoolReady   = "ready"
+	warmPoolClaimed = "claimed"
+
+	// warmPoolApp is the app label of unclaimed warm pods. It differs from
+	// session pods so Services and affinity rules ignore the pool.
+	warmPoolApp = "streamspace-warm-pool"
+)
+
+// WarmPoolReconciler maintains the warm pod pool of each Template.
+type WarmPoolReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// RefillInterval is how often pools are checked even without events
+	RefillInterval time.Duration
+}
+
+//+kubebuilder:rbac:groups=stream.streamspace.io,resources=templates,verbs=get;list;watch
+//+kubebuilder:rbac:groups=stream.streamspace.io,resources=templates/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile brings a Template's warm pool to its configured size.
+func (r *WarmPoolReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	template := &streamv1alpha1.Template{}
+	if err := r.Get(ctx, req.NamespacedName, template); err != nil {
+		// Pool pods are owned by the template and garbage collected with it
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, client.InNamespace(template.Namespace), client.MatchingLabels{
+		"template":    template.Name,
+		warmPoolLabel: warmPoolReady,
+	}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// Never serve from an invalid template
+	desired := int(template.Spec.WarmPoolSize)
+	if !template.Status.Valid {
+		desired = 0
+	}
+
+	var pool []*corev1.Pod
+	var ready int32
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		// Crashed warm pods are useless - replace them
+		if pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded {
+			if err := r.Delete(ctx, pod); err != nil && !errors.IsNotFound(err) {
+				return ctrl.Result{}, err
+			}
+			log.Info("Deleted failed warm pod", "pod", pod.Name, "template", template.Name)
+			continue
+		}
+		if isPodReady(pod) {
+			ready++
+		}
+		pool = append(pool, pod)
+	}
+
+	// Shrink: drop pods that aren't ready first so ready capacity survives
+	for len(pool) > desired {
+		victim := len(pool) - 1
+		for i, pod := range pool {
+			if !isPodReady(pod) {
+				victim = i
+				break
+			}
+		}
+		pod := pool[victim]
+		if err := r.Delete(ctx, pod); err != nil && !errors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		if isPodReady(pod) {
+			ready--
+		}
+		pool = append(pool[:victim], pool[victim+1:]...)
+		log.Info("Deleted surplus warm pod", "pod", pod.Name, "template", template.Name)
+	}
+
+	// Grow: refill the pool
+	for i := len(pool); i < desired; i++ {
+		pod := newWarmPod(template)
+		if err := ctrl.SetControllerReference(template, pod, r.Scheme); err != nil {
+			return ctrl.Result{}, err
+		}
+		if err := r.Create(ctx, pod); err != nil {
+			log.Error(err, "Failed to create warm pod", "template", template.Name)
+			return ctrl.Result{}, err
+		}
+		log.Info("Created warm pod", "pod", pod.Name, "template", template.Name)
+	}
+
+	if template.Status.WarmPoolReady != ready {
+		template.Status.WarmPoolReady = ready
+		if err := r.Status().Update(ctx, template); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if desired == 0 {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{RequeueAfter: r.RefillInterval}, nil
+}
+
+// newWarmPod builds an unclaimed pod that runs a template with its defaults.
+//
+// The pod spec comes from the same builder as session Deployments so a
+// claimed pod is indistinguishable from a freshly created session.
+func newWarmPod(template *streamv1alpha1.Template) *corev1.Pod {
+	placeholder := &streamv1alpha1.Session{
+		ObjectMeta: metav1.ObjectMeta{Namespace: template.Namespace},
+		Spec:       streamv1alpha1.SessionSpec{Template: template.Name},
+	}
+	podSpec := (&SessionReconciler{}).createDeployment(placeholder, template, false).Spec.Template.Spec
+
+	// Affinity is per user: claimWarmPod only picks pods on nodes that
+	// satisfy it (see warmPodCandidates)
+	podSpec.Affinity = nil
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName: fmt.Sprintf("ss-pool-%s-", template.Name),
+			Namespace:    template.Namespace,
+			Labels: map[string]string{
+				"app":         warmPoolApp,
+				"template":    template.Name,
+				warmPoolLabel: warmPoolReady,
+			},
+		},
+		Spec: podSpec,
+	}
+}
+
+// warmPoolEligible reports whether a session can run in a warm pod.
+//
+// Warm pods are started with template defaults, and a running pod's
+// resources, environment and priority can't be changed, so sessions that
+// customise any of them need a fresh pod, as do templates with companion
+// resources (they are per session or user). A persistent home is bound when
+// the pod is claimed (see bindHomeVolume).
+func warmPoolEligible(session *streamv1alpha1.Session, template *streamv1alpha1.Template) bool {
+	if template.Spec.WarmPoolSize <= 0 || len(session.Spec.Parameters) > 0 || len(template.Spec.CompanionResources) > 0 {
+		return false
+	}
+	// Warm pods can be on any node (see pinning.go)
+	if session.Spec.PinToNode != "" {
+		return false
+	}
+	if sessionPriority(session, template) != sessionPriority(&streamv1alpha1.Session{}, template) {
+		return false
+	}
+	return len(session.Spec.Resources.Requests) == 0 && len(session.Spec.Resources.Limits) == 0
+}
+
+// claimWarmPod assigns a ready warm pod to a session.
+//
+// Returns nil (and no error) if the session isn't eligible or the pool has
+// no ready pod; the caller then creates a Deployment.
+func (r *SessionReconciler) claimWarmPod(ctx context.Context, session *streamv1alpha1.Session, template *streamv1alpha1.Template) (*corev1.Pod, error) {
+	if !warmPoolEligible(session, template) {
+		return nil, nil
+	}
+
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, client.InNamespace(session.Namespace), client.MatchingLabels{
+		"template":    template.Name,
+		warmPoolLabel: warmPoolReady,
+	}); err != nil {
+		return nil, err
+	}
+
+	homeRWO, err := r.sessionHomeReadWriteOnce(ctx, session, template)
+	if err != nil {
+		return nil, err
+	}
+	affinity := userSessionAffinity(session, template, homeRWO)
+	var userNodes map[string]bool
+	if affinity != nil {
+		sessionPods := &corev1.PodList{}
+		if err := r.List(ctx, sessionPods, client.InNamespace(session.Namespace), client.MatchingLabels{
+			"app":  "streamspace-session",
+			"user": session.Spec.User,
+		}); err != nil {
+			return nil, err
+		}
+		userNodes = map[string]bool{}
+		for _, pod := range sessionPods.Items {
+			if pod.Spec.NodeName != "" && pod.DeletionTimestamp == nil {
+				userNodes[pod.Spec.NodeName] = true
+			}
+		}
+	}
+
+	for _, pod := range warmPodCandidates(podList.Items, affinity, userNodes) {
+		pod.Labels["app"] = "streamspace-session"
+		pod.Labels["user"] = session.Spec.User
+		pod.Labels["session"] = session.Name
+		pod.Labels[warmPoolLabel] = warmPoolClaimed
+		for _, tag := range session.Spec.Tags {
+			if tag != "" {
+				pod.Labels[fmt.Sprintf("tag.stream.space/%s", tag)] = "true"
+			}
+		}
+		pod.OwnerReferences = []metav1.OwnerReference{
+			*metav1.NewControllerRef(session, streamv1alpha1.GroupVersion.WithKind("Session")),
+		}
+
+		// Update carries the resourceVersion we listed, so only one session
+		// can claim this pod
+		if err := r.Update(ctx, pod); err != nil {
+			if errors.IsConflict(err) || errors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+
+		if session.Spec.PersistentHome {
+			return r.bindHomeVolume(ctx, session, pod)
+		}
+		return pod, nil
+	}
+
+	return nil, nil
+}
+
+// warmPodCandidates returns the ready warm pods a session may claim, in the
+// order to try them.
+//
+// userNodes holds the nodes running the user's other sessions. With
+// Required affinity only pods on those nodes qualify, unless the user has
+// no running session (the pod would match its own affinity term). With
+// Preferred affinity those pods come first.
+func warmPodCandidates(pods []corev1.Pod, affinity *corev1.Affinity, userNodes map[string]bool) []*corev1.Pod {
+	required := affinity != nil && affinity.PodAffinity != nil &&
+		len(affinity.PodAffinity.RequiredDuringSchedulingIgnoredDuringExecution) > 0
+
+	var preferred, others []*corev1.Pod
+	for i := range pods {
+		pod := &pods[i]
+		if pod.DeletionTimestamp != nil || !isPodReady(pod) {
+			continue
+		}
+		switch {
+		case userNodes[pod.Spec.NodeName]:
+			preferred = append(preferred, pod)
+		case required && len(userNodes) > 0:
+			// Not on the user's node
+		default:
+			others = append(others, pod)
+		}
+	}
+	return append(preferred, others...)
+}
+
+// bindHomeVolume replaces a claimed warm pod with a copy that mounts the
+// session's persistent home.
+//
+// The copy is pinned to the warm pod's node, where the image is already
+// pulled, and keeps the claimed labels and Session ownership so
+// findClaimedWarmPod and releaseWarmPod treat it like the original.
+func (r *SessionReconciler) bindHomeVolume(ctx context.Context, session *streamv1alpha1.Session, claimed *corev1.Pod) (*corev1.Pod, error) {
+	pod := homeBoundPod(session, claimed)
+	if err := r.Create(ctx, pod); err != nil {
+		return nil, err
+	}
+	if err := r.Delete(ctx, claimed); err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	log.FromContext(ctx).Info("Bound home volume to warm pod", "pod", pod.Name, "replaced", claimed.Name, "node", pod.Spec.NodeName)
+	return pod, nil
+}
+
+// homeBoundPod builds the copy of a claimed warm pod that mounts the
+// session's home PVC at /config, the same mount createDeployment adds.
+func homeBoundPod(session *streamv1alpha1.Session, claimed *corev1.Pod) *corev1.Pod {
+	// The copy keeps the warm pod's nodeName
+	spec := *claimed.Spec.DeepCopy()
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: "user-home",
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: homePVCName(session),
+			},
+		},
+	})
+	spec.Containers[0].VolumeMounts = append(spec.Containers[0].VolumeMounts, corev1.VolumeMount{
+		Name:      "user-home",
+		MountPath: "/config",
+	})
+
+	labels := make(map[string]string, len(claimed.Labels))
+	for k, v := range claimed.Labels {
+		labels[k] = v
+	}
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			GenerateName:    fmt.Sprintf("ss-%s-", session.Name),
+			Namespace:       claimed.Namespace,
+			Labels:          labels,
+			Annotations:     claimed.Annotations,
+			OwnerReferences: claimed.OwnerReferences,
+		},
+		Spec: spec,
+	}
+}
+
+// findClaimedWarmPod returns the warm pod a session is running in, if any.
+//
+// A claimed pod that has failed is deleted so the session falls back to a
+// Deployment instead of pointing at a dead pod.
+func (r *SessionReconciler) findClaimedWarmPod(ctx context.Context, session *streamv1alpha1.Session) (*corev1.Pod, error) {
+	podList := &corev1.PodList{}
+	if err := r.List(ctx, podList, client.InNamespace(session.Namespace), client.MatchingLabels{
+		"session":     session.Name,
+		warmPoolLabel: warmPoolClaimed,
+	}); err != nil {
+		return nil, err
+	}
+
+	for i := range podList.Items {
+		pod := &podList.Items[i]
+		if pod.DeletionTimestamp != nil {
+			continue
+		}
+		if pod.Status.Phase == corev1.PodFailed || pod.Status.Phase == corev1.PodSucceeded {
+			if err := r.Delete(ctx, pod); err != nil && !errors.IsNotFound(err) {
+				return nil, err
+			}
+			continue
+		}
+		return pod, nil
+	}
+
+	return nil, nil
+}
+
+// releaseWarmPod deletes the warm pod a session is running in, if any.
+//
+// Warm pods can't be scaled to zero like a Deployment, so hibernating or
+// terminating a warm-launched session deletes its pod. Waking it creates a
+// Deployment (or claims another warm pod).
+func (r *SessionReconciler) releaseWarmPod(ctx context.Context, session *streamv1alpha1.Session) error {
+	pod, err := r.findClaimedWarmPod(ctx, session)
+	if err != nil || pod == nil {
+		return err
+	}
+	if err := r.Delete(ctx, pod); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	log.FromContext(ctx).Info("Deleted warm pod", "pod", pod.Name, "session", session.Name)
+	return nil
+}
+
+// isPodReady reports whether a pod is running and passing readiness checks.
+func isPodReady(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodReady {
+			return cond.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// SetupWithManager registers the WarmPoolReconciler with the controller manager.
+//
+// Owning pods means a claim (which moves ownership away from the Template)
+// or a crashed warm pod immediately triggers a refill.
+func (r *WarmPoolReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	if r.RefillInterval == 0 {
+		r.RefillInterval = 30 * time.Second
+	}
+
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&streamv1alpha1.Template{}).
+		Owns(&corev1.Pod{}).
+		Named("warmpool"). // Unique name to distinguish from TemplateReconciler
+		Complete(r)
+}
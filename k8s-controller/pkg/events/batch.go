@@ -0,0 +1,105 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+)
+
+// handleSessionBatchEvent registers the batch subject, so it is subscribed
+// to and advertised as the session.batch action. handleMessage calls
+// handleSessionBatch instead to collect the per-command results.
+func (s *Subscriber) handleSessionBatchEvent(ctx context.Context, data []byte) error {
+	_, err := s.handleSessionBatch(ctx, data)
+	return err
+}
+
+// handleSessionBatch runs the commands of a SessionBatchEvent in order and
+// returns the result of each. A failed command doesn't stop the rest.
+//
+// Every command is recorded in the result cache under its own event ID,
+// as if it had arrived individually: the API may resend a command on its
+// own when the batch ack is lost, and then gets the recorded result
+// instead of running it again.
+func (s *Subscriber) handleSessionBatch(ctx context.Context, data []byte) ([]CommandResult, error) {
+	var event SessionBatchEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal SessionBatchEvent: %w", err)
+	}
+
+	log.Printf("Handling session batch event %s: %d commands", event.EventID, len(event.Commands))
+
+	results := make([]CommandResult, 0, len(event.Commands))
+	for _, command := range event.Commands {
+		results = append(results, s.runSessionCommand(ctx, event.Platform, command))
+	}
+	return results, nil
+}
+
+// runSessionCommand runs one command of a batch, or replays its result if
+// it already completed.
+func (s *Subscriber) runSessionCommand(ctx context.Context, platform string, command SessionCommand) CommandResult {
+	ack, done := s.results.get(command.EventID)
+	if !done {
+		err := s.dispatchSessionCommand(ctx, platform, command)
+		if err != nil {
+			log.Printf("Error handling batched %s of session %s: %v", command.Action, command.SessionID, err)
+		}
+		ack = s.newAck(command.EventID, err)
+		s.results.put(ack)
+	}
+
+	return CommandResult{
+		EventID:   command.EventID,
+		SessionID: command.SessionID,
+		Success:   ack.Success,
+		Error:     ack.Error,
+	}
+}
+
+// dispatchSessionCommand runs a command with the handler of its individual
+// event.
+func (s *Subscriber) dispatchSessionCommand(ctx context.Context, platform string, command SessionCommand) error {
+	var handler EventHandler
+	var event interface{}
+	switch command.Action {
+	case "session.hibernate":
+		handler = s.handleSessionHibernate
+		event = SessionHibernateEvent{
+			EventID:   command.EventID,
+			Timestamp: time.Now(),
+			SessionID: command.SessionID,
+			UserID:    command.UserID,
+			Platform:  platform,
+		}
+	case "session.wake":
+		handler = s.handleSessionWake
+		event = SessionWakeEvent{
+			EventID:   command.EventID,
+			Timestamp: time.Now(),
+			SessionID: command.SessionID,
+			UserID:    command.UserID,
+			Platform:  platform,
+		}
+	case "session.delete":
+		handler = s.handleSessionDelete
+		event = SessionDeleteEvent{
+			EventID:   command.EventID,
+			Timestamp: time.Now(),
+			SessionID: command.SessionID,
+			UserID:    command.UserID,
+			Platform:  platform,
+			Force:     command.Force,
+		}
+	default:
+		return fmt.Errorf("unsupported batch command %q", command.Action)
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return handler(ctx, data)
+}
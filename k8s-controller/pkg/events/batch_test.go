@@ -0,0 +1,47 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestHandleSessionBatchReportsEachCommand(t *testing.T) {
+	s := &Subscriber{controllerID: "ctrl-a", results: newResultCache(10, 0)}
+
+	// The first command already completed individually
+	s.results.put(EventAck{EventID: "evt-1", ControllerID: "ctrl-a", Success: true})
+
+	data, err := json.Marshal(SessionBatchEvent{
+		EventID:  "batch-1",
+		Platform: PlatformKubernetes,
+		Commands: []SessionCommand{
+			{EventID: "evt-1", Action: "session.hibernate", SessionID: "alice-firefox"},
+			{EventID: "evt-2", Action: "session.snapshot", SessionID: "bob-firefox"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := s.handleSessionBatch(context.Background(), data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected a result per command, got %d", len(results))
+	}
+
+	if !results[0].Success || results[0].SessionID != "alice-firefox" {
+		t.Errorf("expected the completed command to be replayed, got %+v", results[0])
+	}
+	if results[1].Success || !strings.Contains(results[1].Error, "unsupported") {
+		t.Errorf("expected the unknown command to fail, got %+v", results[1])
+	}
+
+	// Failed commands are run again when resent
+	if _, ok := s.results.get("evt-2"); ok {
+		t.Error("expected the failed command not to be cached")
+	}
+}
@@ -0,0 +1,127 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// cancelledSessions remembers sessions whose creation was cancelled.
+//
+// Creates are requests the API retries, while the cancel is a plain event,
+// so a cancel can overtake its create. A create for a remembered session is
+// dropped instead of starting the session the user already abandoned.
+// Entries expire after ttl, the window in which the API may still retry.
+type cancelledSessions struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]time.Time // session ID -> expiry
+}
+
+// newCancelledSessions creates a set remembering sessions for ttl.
+func newCancelledSessions(ttl time.Duration) *cancelledSessions {
+	if ttl <= 0 {
+		ttl = defaultResultCacheTTL
+	}
+	return &cancelledSessions{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]time.Time),
+	}
+}
+
+// add remembers a cancelled session.
+func (c *cancelledSessions) add(sessionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for id, expires := range c.entries {
+		if now.After(expires) {
+			delete(c.entries, id)
+		}
+	}
+	c.entries[sessionID] = now.Add(c.ttl)
+}
+
+// has reports whether a session's creation was cancelled.
+func (c *cancelledSessions) has(sessionID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires, ok := c.entries[sessionID]
+	return ok && !c.now().After(expires)
+}
+
+// cancelSession aborts a session that is still starting.
+//
+// Deleting the Session removes everything it owns (Deployment, Service,
+// Ingress, companion resources, a forked home). The user's shared home PVC
+// has no owner, so it is deleted separately if this launch created it.
+func (s *Subscriber) cancelSession(ctx context.Context, event SessionDeleteEvent) error {
+	s.cancelled.add(event.SessionID)
+
+	session := &streamv1alpha1.Session{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      event.SessionID,
+			Namespace: s.namespace,
+		},
+	}
+	if err := s.client.Delete(ctx, session); err != nil {
+		if !errors.IsNotFound(err) {
+			return fmt.Errorf("failed to delete session: %w", err)
+		}
+		log.Printf("Session %s cancelled before it was created", event.SessionID)
+	}
+
+	if err := s.deleteCancelledHome(ctx, event.SessionID, event.UserID); err != nil {
+		// The session is gone either way; an orphaned PVC only costs storage
+		log.Printf("Failed to clean up home of cancelled session %s: %v", event.SessionID, err)
+	}
+
+	s.publishSessionStatus(event.SessionID, "deleted", "Cancelled", "Session creation cancelled")
+	log.Printf("Session %s cancelled successfully", event.SessionID)
+	return nil
+}
+
+// deleteCancelledHome deletes the user's home PVC if the cancelled session
+// created it and no other session of the user mounts it.
+func (s *Subscriber) deleteCancelledHome(ctx context.Context, sessionID, user string) error {
+	pvc := &corev1.PersistentVolumeClaim{}
+	err := s.client.Get(ctx, types.NamespacedName{Name: fmt.Sprintf("home-%s", user), Namespace: s.namespace}, pvc)
+	if errors.IsNotFound(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if pvc.Annotations[streamv1alpha1.HomeCreatedByAnnotation] != sessionID {
+		return nil
+	}
+
+	sessions := &streamv1alpha1.SessionList{}
+	if err := s.client.List(ctx, sessions, client.InNamespace(s.namespace)); err != nil {
+		return err
+	}
+	for _, other := range sessions.Items {
+		if other.Name != sessionID && other.Spec.User == user && other.Spec.PersistentHome && other.Spec.ForkFrom == "" {
+			log.Printf("Keeping home %s created by cancelled session %s: session %s uses it", pvc.Name, sessionID, other.Name)
+			return nil
+		}
+	}
+
+	if err := s.client.Delete(ctx, pvc); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	log.Printf("Deleted home %s created by cancelled session %s", pvc.Name, sessionID)
+	return nil
+}
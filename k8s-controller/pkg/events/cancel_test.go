@@ -0,0 +1,35 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCancelledSessionsRememberCancels(t *testing.T) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	cancelled := newCancelledSessions(time.Minute)
+	cancelled.now = clock.now
+
+	if cancelled.has("alice-firefox") {
+		t.Fatal("expected no cancel before one was recorded")
+	}
+
+	cancelled.add("alice-firefox")
+	if !cancelled.has("alice-firefox") {
+		t.Error("expected the cancelled session to be remembered")
+	}
+	if cancelled.has("bob-firefox") {
+		t.Error("expected other sessions not to be cancelled")
+	}
+
+	clock.t = clock.t.Add(2 * time.Minute)
+	if cancelled.has("alice-firefox") {
+		t.Error("expected the cancel to expire after the ttl")
+	}
+
+	// Expired cancels are dropped when new ones are recorded
+	cancelled.add("bob-firefox")
+	if _, ok := cancelled.entries["alice-firefox"]; ok {
+		t.Error("expected the expired cancel to be pruned")
+	}
+}
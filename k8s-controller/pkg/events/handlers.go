@@ -12,7 +12,6 @@ import (
 
 	"github.com/google/uuid"
 	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
-	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
@@ -53,22 +52,37 @@ func (s *Subscriber) handleSessionCreate(ctx context.Context, data []byte) error
 
 	log.Printf("Handling session create event: %s for user %s", event.SessionID, event.UserID)
 
+	if s.cancelled.has(event.SessionID) {
+		log.Printf("Session %s was cancelled, not creating it", event.SessionID)
+		return nil
+	}
+
+	// Plugin labels first, so they can't replace the ones the controller owns
+	labels := make(map[string]string, len(event.Labels)+2)
+	for k, v := range event.Labels {
+		labels[k] = v
+	}
+	labels["streamspace.io/user"] = event.UserID
+	labels["streamspace.io/template"] = event.TemplateID
+
 	// Create Session CRD
 	session := &streamv1alpha1.Session{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      event.SessionID,
 			Namespace: s.namespace,
-			Labels: map[string]string{
-				"streamspace.io/user":     event.UserID,
-				"streamspace.io/template": event.TemplateID,
-			},
+			Labels:    labels,
 		},
 		Spec: streamv1alpha1.SessionSpec{
-			User:           event.UserID,
-			Template:       event.TemplateID,
-			State:          "running",
-			PersistentHome: event.PersistentHome,
-			IdleTimeout:    event.IdleTimeout,
+			User:               event.UserID,
+			Template:           event.TemplateID,
+			State:              "running",
+			PersistentHome:     event.PersistentHome,
+			ForkFrom:           event.ForkFrom,
+			IdleTimeout:        event.IdleTimeout,
+			MaxSessionDuration: event.MaxSessionDuration,
+			Tags:               event.Tags,
+			Parameters:         event.Parameters,
+			Priority:           event.Priority,
 			Resources: corev1.ResourceRequirements{
 				Requests: corev1.ResourceList{
 					corev1.ResourceMemory: resource.MustParse(event.Resources.Memory),
@@ -82,6 +96,13 @@ func (s *Subscriber) handleSessionCreate(ctx context.Context, data []byte) error
 		},
 	}
 
+	// Reject a bad spec here with a clear message instead of letting the
+	// API server (or a later reconcile) fail on it.
+	if err := session.Spec.Validate(); err != nil {
+		s.publishSessionStatus(event.SessionID, "failed", "", err.Error())
+		return fmt.Errorf("session %s: %w", event.SessionID, err)
+	}
+
 	if err := s.client.Create(ctx, session); err != nil {
 		if errors.IsAlreadyExists(err) {
 			log.Printf("Session %s already exists", event.SessionID)
@@ -104,6 +125,11 @@ func (s *Subscriber) handleSessionDelete(ctx context.Context, data []byte) error
 
 	log.Printf("Handling session delete event: %s", event.SessionID)
 
+	// A launch the user abandoned also takes the home it just created
+	if event.Cancel {
+		return s.cancelSession(ctx, event)
+	}
+
 	// Delete Session CRD
 	session := &streamv1alpha1.Session{
 		ObjectMeta: metav1.ObjectMeta{
@@ -133,37 +159,14 @@ func (s *Subscriber) handleSessionHibernate(ctx context.Context, data []byte) er
 
 	log.Printf("Handling session hibernate event: %s", event.SessionID)
 
-	// Get the session
-	session := &streamv1alpha1.Session{}
-	if err := s.client.Get(ctx, types.NamespacedName{
-		Name:      event.SessionID,
-		Namespace: s.namespace,
-	}, session); err != nil {
-		return fmt.Errorf("failed to get session: %w", err)
-	}
-
-	// Update state to hibernated
-	session.Spec.State = "hibernated"
-	if err := s.client.Update(ctx, session); err != nil {
+	// The session reconciler scales the deployment down once the state is set
+	applied, err := s.applySessionState(ctx, event.SessionID, "hibernated", event.Timestamp)
+	if err != nil {
 		return fmt.Errorf("failed to update session state: %w", err)
 	}
-
-	// Scale deployment to 0
-	deploymentName := fmt.Sprintf("ss-%s", event.SessionID)
-	deployment := &appsv1.Deployment{}
-	if err := s.client.Get(ctx, types.NamespacedName{
-		Name:      deploymentName,
-		Namespace: s.namespace,
-	}, deployment); err != nil {
-		if !errors.IsNotFound(err) {
-			return fmt.Errorf("failed to get deployment: %w", err)
-		}
-	} else {
-		replicas := int32(0)
-		deployment.Spec.Replicas = &replicas
-		if err := s.client.Update(ctx, deployment); err != nil {
-			return fmt.Errorf("failed to scale deployment to 0: %w", err)
-		}
+	if !applied {
+		log.Printf("Ignoring stale hibernate of session %s issued at %s", event.SessionID, event.Timestamp.Format(time.RFC3339Nano))
+		return nil
 	}
 
 	s.publishSessionStatus(event.SessionID, "hibernated", "Hibernated", "Session hibernated")
@@ -180,37 +183,14 @@ func (s *Subscriber) handleSessionWake(ctx context.Context, data []byte) error {
 
 	log.Printf("Handling session wake event: %s", event.SessionID)
 
-	// Get the session
-	session := &streamv1alpha1.Session{}
-	if err := s.client.Get(ctx, types.NamespacedName{
-		Name:      event.SessionID,
-		Namespace: s.namespace,
-	}, session); err != nil {
-		return fmt.Errorf("failed to get session: %w", err)
-	}
-
-	// Update state to running
-	session.Spec.State = "running"
-	if err := s.client.Update(ctx, session); err != nil {
+	// The session reconciler scales the deployment up once the state is set
+	applied, err := s.applySessionState(ctx, event.SessionID, "running", event.Timestamp)
+	if err != nil {
 		return fmt.Errorf("failed to update session state: %w", err)
 	}
-
-	// Scale deployment to 1
-	deploymentName := fmt.Sprintf("ss-%s", event.SessionID)
-	deployment := &appsv1.Deployment{}
-	if err := s.client.Get(ctx, types.NamespacedName{
-		Name:      deploymentName,
-		Namespace: s.namespace,
-	}, deployment); err != nil {
-		if !errors.IsNotFound(err) {
-			return fmt.Errorf("failed to get deployment: %w", err)
-		}
-	} else {
-		replicas := int32(1)
-		deployment.Spec.Replicas = &replicas
-		if err := s.client.Update(ctx, deployment); err != nil {
-			return fmt.Errorf("failed to scale deployment to 1: %w", err)
-		}
+	if !applied {
+		log.Printf("Ignoring stale wake of session %s issued at %s", event.SessionID, event.Timestamp.Format(time.RFC3339Nano))
+		return nil
 	}
 
 	s.publishSessionStatus(event.SessionID, "running", "Running", "Session woken")
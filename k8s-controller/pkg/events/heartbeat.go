@@ -0,0 +1,100 @@
+package events
+
+import (
+	"context"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// heartbeatInterval is how often the controller reports its capabilities.
+// The API treats a controller as gone after 90s without a heartbeat.
+const heartbeatInterval = 30 * time.Second
+
+// gpuResourceName is the extended resource GPU device plugins advertise.
+const gpuResourceName corev1.ResourceName = "nvidia.com/gpu"
+
+// runHeartbeat publishes a heartbeat now and every heartbeatInterval until
+// ctx is cancelled.
+func (s *Subscriber) runHeartbeat(ctx context.Context) {
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.publishHeartbeat(ctx); err != nil {
+			log.Printf("Failed to publish controller heartbeat: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishHeartbeat reports the controller's health and capabilities.
+func (s *Subscriber) publishHeartbeat(ctx context.Context) error {
+	event := ControllerHeartbeatEvent{
+		ControllerID: s.controllerID,
+		Platform:     s.platform,
+		Timestamp:    time.Now(),
+		Status:       "healthy",
+		Capabilities: s.capabilities(ctx),
+	}
+	return s.publishStatus(SubjectControllerHeartbeat, event)
+}
+
+// capabilities describes what this controller can run: the event actions it
+// handles, and from the cluster's nodes whether GPUs are available and the
+// largest session a node can hold.
+func (s *Subscriber) capabilities(ctx context.Context) ControllerCapabilities {
+	caps := ControllerCapabilities{Backend: s.platform}
+	for subject := range s.handlers {
+		caps.Actions = append(caps.Actions, strings.TrimPrefix(subject, "streamspace."))
+	}
+	sort.Strings(caps.Actions)
+
+	var nodes corev1.NodeList
+	if err := s.client.List(ctx, &nodes); err != nil {
+		// Unknown limits don't restrict dispatch
+		log.Printf("Failed to list nodes for capability report: %v", err)
+		return caps
+	}
+	caps.GPU, caps.MaxResources = nodeCapacity(nodes.Items)
+	return caps
+}
+
+// nodeCapacity returns whether any schedulable node has allocatable GPUs
+// and the largest allocatable memory and CPU of a single node.
+func nodeCapacity(nodes []corev1.Node) (bool, ResourceSpec) {
+	var gpu bool
+	var maxMemory, maxCPU resource.Quantity
+	for _, node := range nodes {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		allocatable := node.Status.Allocatable
+		if q, ok := allocatable[gpuResourceName]; ok && q.Sign() > 0 {
+			gpu = true
+		}
+		if q, ok := allocatable[corev1.ResourceMemory]; ok && q.Cmp(maxMemory) > 0 {
+			maxMemory = q
+		}
+		if q, ok := allocatable[corev1.ResourceCPU]; ok && q.Cmp(maxCPU) > 0 {
+			maxCPU = q
+		}
+	}
+
+	var spec ResourceSpec
+	if !maxMemory.IsZero() {
+		spec.Memory = maxMemory.String()
+	}
+	if !maxCPU.IsZero() {
+		spec.CPU = maxCPU.String()
+	}
+	return gpu, spec
+}
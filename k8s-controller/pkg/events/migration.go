@@ -0,0 +1,251 @@
+package events
+
+import (
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+)
+
+// Subject migration - must match the API events package (see its
+// migration.go for how a rename is rolled out).
+//
+// While a subject is migrating the controller subscribes to both its old
+// and new name, handles a message published to both once, logs which name
+// delivered each message, and publishes status events to the names
+// NATS_SUBJECT_MIGRATION_PUBLISH selects.
+//
+// Environment:
+//   - NATS_SUBJECT_MIGRATIONS: comma-separated old=new subject pairs
+//     (default none)
+//   - NATS_SUBJECT_MIGRATION_PUBLISH: both, old or new (default both)
+
+// MigrationIDHeader identifies the copies of a message published to both
+// names of a migrating subject.
+const MigrationIDHeader = "Streamspace-Migration-Id"
+
+// Publish modes of NATS_SUBJECT_MIGRATION_PUBLISH.
+const (
+	MigrationPublishBoth = "both"
+	MigrationPublishOld  = "old"
+	MigrationPublishNew  = "new"
+)
+
+// migrationDedupWindow is how long message IDs are remembered to drop the
+// second copy of a message published to both names.
+const migrationDedupWindow = 2 * time.Minute
+
+// SubjectMigration is an old=new subject pair.
+type SubjectMigration struct {
+	Old string `json:"old"`
+	New string `json:"new"`
+}
+
+// SubjectMigrations maps subjects to their migration pairs. The zero value
+// and nil have no pairs, so every subject is used as is.
+type SubjectMigrations struct {
+	pairs   []SubjectMigration
+	publish string
+
+	mu     sync.Mutex
+	seen   map[string]time.Time
+	pruned time.Time
+	now    func() time.Time
+}
+
+// NewSubjectMigrations creates migrations for the given pairs. publish is
+// one of the MigrationPublish modes; anything else publishes to both.
+func NewSubjectMigrations(pairs []SubjectMigration, publish string) *SubjectMigrations {
+	if publish != MigrationPublishOld && publish != MigrationPublishNew {
+		publish = MigrationPublishBoth
+	}
+	return &SubjectMigrations{
+		pairs:   pairs,
+		publish: publish,
+		seen:    make(map[string]time.Time),
+		now:     time.Now,
+	}
+}
+
+// subjectMigrationsFromEnv reads the migration pairs. Malformed pairs are
+// logged and ignored.
+func subjectMigrationsFromEnv() *SubjectMigrations {
+	var pairs []SubjectMigration
+	for _, entry := range strings.Split(os.Getenv("NATS_SUBJECT_MIGRATIONS"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		from, to, ok := strings.Cut(entry, "=")
+		from, to = strings.TrimSpace(from), strings.TrimSpace(to)
+		if !ok || from == "" || to == "" || from == to {
+			log.Printf("Ignoring invalid NATS subject migration %q (expected old=new)", entry)
+			continue
+		}
+		pairs = append(pairs, SubjectMigration{Old: from, New: to})
+	}
+
+	return NewSubjectMigrations(pairs, strings.ToLower(strings.TrimSpace(os.Getenv("NATS_SUBJECT_MIGRATION_PUBLISH"))))
+}
+
+// Pairs returns the configured migration pairs.
+func (m *SubjectMigrations) Pairs() []SubjectMigration {
+	if m == nil {
+		return nil
+	}
+	return m.pairs
+}
+
+// names returns the old and new name of subject if a pair covers it.
+func (m *SubjectMigrations) names(subject string) (oldName, newName string, ok bool) {
+	if m == nil {
+		return "", "", false
+	}
+	for _, pair := range m.pairs {
+		if rest, found := cutSubjectPrefix(subject, pair.Old); found {
+			return subject, pair.New + rest, true
+		}
+		if rest, found := cutSubjectPrefix(subject, pair.New); found {
+			return pair.Old + rest, subject, true
+		}
+	}
+	return "", "", false
+}
+
+// cutSubjectPrefix returns what follows prefix in subject if subject is
+// prefix or a subject below it.
+func cutSubjectPrefix(subject, prefix string) (string, bool) {
+	if subject == prefix {
+		return "", true
+	}
+	if strings.HasPrefix(subject, prefix+".") {
+		return subject[len(prefix):], true
+	}
+	return "", false
+}
+
+// SubscribeSubjects returns the names to subscribe to for subject.
+func (m *SubjectMigrations) SubscribeSubjects(subject string) []string {
+	oldName, newName, ok := m.names(subject)
+	if !ok {
+		return []string{subject}
+	}
+	return []string{oldName, newName}
+}
+
+// PublishSubjects returns the names to publish subject's events to.
+func (m *SubjectMigrations) PublishSubjects(subject string) []string {
+	oldName, newName, ok := m.names(subject)
+	if !ok {
+		return []string{subject}
+	}
+	switch m.publish {
+	case MigrationPublishOld:
+		return []string{oldName}
+	case MigrationPublishNew:
+		return []string{newName}
+	default:
+		return []string{oldName, newName}
+	}
+}
+
+// RequestSubject returns the name to send a request for subject to.
+func (m *SubjectMigrations) RequestSubject(subject string) string {
+	oldName, newName, ok := m.names(subject)
+	if !ok {
+		return subject
+	}
+	if m.publish == MigrationPublishNew {
+		return newName
+	}
+	return oldName
+}
+
+// Publish publishes data to the names of subject. Copies sent to both
+// names share a MigrationIDHeader.
+func (m *SubjectMigrations) Publish(conn *nats.Conn, subject string, data []byte) error {
+	subjects := m.PublishSubjects(subject)
+	if len(subjects) == 1 {
+		return conn.Publish(subjects[0], data)
+	}
+
+	id := uuid.New().String()
+	for _, name := range subjects {
+		msg := nats.NewMsg(name)
+		msg.Header.Set(MigrationIDHeader, id)
+		msg.Data = data
+		if err := conn.PublishMsg(msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Subscribe subscribes handler to the names of subject, in queue group
+// queue if it isn't empty. While subject is migrating, each message is
+// logged with the name that delivered it and the second copy of a message
+// published to both names is dropped.
+func (m *SubjectMigrations) Subscribe(conn *nats.Conn, subject, queue string, handler nats.MsgHandler) ([]*nats.Subscription, error) {
+	subjects := m.SubscribeSubjects(subject)
+	if len(subjects) > 1 {
+		handler = m.wrap(subjects[0], subjects[1], handler)
+	}
+
+	var subs []*nats.Subscription
+	for _, name := range subjects {
+		var sub *nats.Subscription
+		var err error
+		if queue != "" {
+			sub, err = conn.QueueSubscribe(name, queue, handler)
+		} else {
+			sub, err = conn.Subscribe(name, handler)
+		}
+		if err != nil {
+			for _, s := range subs {
+				s.Unsubscribe()
+			}
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, nil
+}
+
+// wrap logs and deduplicates the messages of a migrating subject.
+func (m *SubjectMigrations) wrap(oldName, newName string, handler nats.MsgHandler) nats.MsgHandler {
+	return func(msg *nats.Msg) {
+		id := msg.Header.Get(MigrationIDHeader)
+		if id != "" && m.duplicate(id) {
+			log.Printf("Dropped duplicate of message %s delivered by %s (migrating %s to %s)", id, msg.Subject, oldName, newName)
+			return
+		}
+		log.Printf("Message delivered by %s (migrating %s to %s)", msg.Subject, oldName, newName)
+		handler(msg)
+	}
+}
+
+// duplicate records a message ID and reports whether it was seen within
+// migrationDedupWindow.
+func (m *SubjectMigrations) duplicate(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if seen, ok := m.seen[id]; ok && now.Sub(seen) < migrationDedupWindow {
+		return true
+	}
+	if now.Sub(m.pruned) >= migrationDedupWindow {
+		for key, seen := range m.seen {
+			if now.Sub(seen) >= migrationDedupWindow {
+				delete(m.seen, key)
+			}
+		}
+		m.pruned = now
+	}
+	m.seen[id] = now
+	return false
+}
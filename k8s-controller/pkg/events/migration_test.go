@@ -0,0 +1,39 @@
+package events
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nats-io/nats.go"
+)
+
+func TestSubjectMigrationsCoverPlatformSubjects(t *testing.T) {
+	m := NewSubjectMigrations([]SubjectMigration{{Old: SubjectSessionCreate, New: "streamspace.v2.session.create"}}, MigrationPublishOld)
+
+	platform := SubjectSessionCreate + "." + PlatformKubernetes
+	want := []string{platform, "streamspace.v2.session.create.kubernetes"}
+	if got := m.SubscribeSubjects(platform); !reflect.DeepEqual(got, want) {
+		t.Fatalf("SubscribeSubjects(%s) = %v, want %v", platform, got, want)
+	}
+	if got := m.PublishSubjects(want[1]); !reflect.DeepEqual(got, []string{platform}) {
+		t.Fatalf("PublishSubjects(%s) = %v, want the old name", want[1], got)
+	}
+	if got := m.SubscribeSubjects(SubjectSessionStatus); !reflect.DeepEqual(got, []string{SubjectSessionStatus}) {
+		t.Fatalf("SubscribeSubjects(%s) = %v, want it unchanged", SubjectSessionStatus, got)
+	}
+}
+
+func TestSubjectMigrationsHandleDualPublishedMessageOnce(t *testing.T) {
+	m := NewSubjectMigrations([]SubjectMigration{{Old: SubjectSessionCreate, New: "streamspace.v2.session.create"}}, MigrationPublishBoth)
+
+	handled := 0
+	handler := m.wrap(SubjectSessionCreate, "streamspace.v2.session.create", func(*nats.Msg) { handled++ })
+	for _, subject := range []string{SubjectSessionCreate, "streamspace.v2.session.create"} {
+		msg := nats.NewMsg(subject)
+		msg.Header.Set(MigrationIDHeader, "evt-1")
+		handler(msg)
+	}
+	if handled != 1 {
+		t.Fatalf("handled %d copies of a dual-published message, want 1", handled)
+	}
+}
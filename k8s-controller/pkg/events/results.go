@@ -0,0 +1,100 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Result cache defaults.
+const (
+	defaultResultCacheSize = 1000
+	defaultResultCacheTTL  = 15 * time.Minute
+)
+
+// resultCache remembers the acks of events this controller completed.
+//
+// The API retries a request whose ack was lost (timeout, reconnect), so the
+// same event can arrive twice. Replaying the cached ack instead of running
+// the handler again makes delivery safely at-least-once: a session is not
+// created twice because its first ack never arrived.
+//
+// Only successful results are cached; a failed event is executed again when
+// it is resent. Entries expire after ttl (the window in which the API may
+// still retry) and the oldest entries are dropped beyond size. The cache is
+// per controller process, so a retry picked up by another controller in the
+// queue group still runs the (idempotent) handler.
+type resultCache struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]cachedResult
+	order   []string // event IDs, oldest first
+}
+
+type cachedResult struct {
+	ack     EventAck
+	expires time.Time
+}
+
+// newResultCache creates a cache holding up to size results for ttl.
+// Zero values use the defaults.
+func newResultCache(size int, ttl time.Duration) *resultCache {
+	if size <= 0 {
+		size = defaultResultCacheSize
+	}
+	if ttl <= 0 {
+		ttl = defaultResultCacheTTL
+	}
+	return &resultCache{
+		size:    size,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cachedResult),
+	}
+}
+
+// get returns the cached ack for a completed event.
+func (c *resultCache) get(eventID string) (EventAck, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result, ok := c.entries[eventID]
+	if !ok || c.now().After(result.expires) {
+		return EventAck{}, false
+	}
+	return result.ack, true
+}
+
+// put records a successfully completed event.
+func (c *resultCache) put(ack EventAck) {
+	if ack.EventID == "" || !ack.Success {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.prune(now)
+
+	if _, exists := c.entries[ack.EventID]; exists {
+		return
+	}
+	c.order = append(c.order, ack.EventID)
+	c.entries[ack.EventID] = cachedResult{ack: ack, expires: now.Add(c.ttl)}
+}
+
+// prune drops expired entries and the oldest entries beyond size, leaving
+// room for one more. Entries expire in insertion order, so only the front of
+// order needs checking. Caller must hold mu.
+func (c *resultCache) prune(now time.Time) {
+	for len(c.order) > 0 {
+		oldest := c.order[0]
+		if len(c.order) < c.size && !now.After(c.entries[oldest].expires) {
+			return
+		}
+		delete(c.entries, oldest)
+		c.order = c.order[1:]
+	}
+}
@@ -0,0 +1,135 @@
+package events
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a controllable time source for resultCache.
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time { return c.t }
+
+func newTestResultCache(size int, ttl time.Duration) (*resultCache, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)}
+	cache := newResultCache(size, ttl)
+	cache.now = clock.now
+	return cache, clock
+}
+
+func TestResultCacheReplaysCompletedEvents(t *testing.T) {
+	cache, _ := newTestResultCache(10, time.Minute)
+
+	if _, ok := cache.get("evt-1"); ok {
+		t.Fatal("expected no result before the event completed")
+	}
+
+	cache.put(EventAck{EventID: "evt-1", ControllerID: "ctrl-a", Success: true})
+	ack, ok := cache.get("evt-1")
+	if !ok {
+		t.Fatal("expected the completed event to be cached")
+	}
+	if ack.ControllerID != "ctrl-a" || !ack.Success {
+		t.Errorf("unexpected cached ack: %+v", ack)
+	}
+}
+
+func TestResultCacheSkipsFailuresAndAnonymousEvents(t *testing.T) {
+	cache, _ := newTestResultCache(10, time.Minute)
+
+	cache.put(EventAck{EventID: "evt-failed", Success: false, Error: "boom"})
+	cache.put(EventAck{EventID: "", Success: true})
+
+	if _, ok := cache.get("evt-failed"); ok {
+		t.Error("failed events must run again when resent")
+	}
+	if _, ok := cache.get(""); ok {
+		t.Error("events without an ID can't be deduplicated")
+	}
+}
+
+func TestResultCacheKeepsFirstResult(t *testing.T) {
+	cache, _ := newTestResultCache(10, time.Minute)
+
+	cache.put(EventAck{EventID: "evt-1", ControllerID: "ctrl-a", Success: true})
+	cache.put(EventAck{EventID: "evt-1", ControllerID: "ctrl-b", Success: true})
+
+	if ack, _ := cache.get("evt-1"); ack.ControllerID != "ctrl-a" {
+		t.Errorf("expected the first result to be kept, got %+v", ack)
+	}
+	if len(cache.order) != 1 {
+		t.Errorf("expected one entry, got %d", len(cache.order))
+	}
+}
+
+func TestResultCacheExpiresEntries(t *testing.T) {
+	cache, clock := newTestResultCache(10, time.Minute)
+	cache.put(EventAck{EventID: "evt-1", Success: true})
+
+	clock.t = clock.t.Add(time.Minute)
+	if _, ok := cache.get("evt-1"); !ok {
+		t.Error("expected the entry to live for the full ttl")
+	}
+
+	clock.t = clock.t.Add(time.Second)
+	if _, ok := cache.get("evt-1"); ok {
+		t.Error("expected the entry to expire after ttl")
+	}
+
+	// Expired entries are dropped on the next put
+	cache.put(EventAck{EventID: "evt-2", Success: true})
+	if _, exists := cache.entries["evt-1"]; exists {
+		t.Error("expected the expired entry to be pruned")
+	}
+}
+
+func TestResultCacheEvictsOldestBeyondSize(t *testing.T) {
+	cache, clock := newTestResultCache(3, time.Hour)
+	for i := 1; i <= 4; i++ {
+		cache.put(EventAck{EventID: fmt.Sprintf("evt-%d", i), Success: true})
+		clock.t = clock.t.Add(time.Second)
+	}
+
+	if _, ok := cache.get("evt-1"); ok {
+		t.Error("expected the oldest entry to be evicted")
+	}
+	for i := 2; i <= 4; i++ {
+		if _, ok := cache.get(fmt.Sprintf("evt-%d", i)); !ok {
+			t.Errorf("expected evt-%d to be cached", i)
+		}
+	}
+	if len(cache.entries) != 3 || len(cache.order) != 3 {
+		t.Errorf("expected 3 entries, got %d (order %d)", len(cache.entries), len(cache.order))
+	}
+}
+
+func TestResultCacheDefaults(t *testing.T) {
+	cache := newResultCache(0, 0)
+	if cache.size != defaultResultCacheSize || cache.ttl != defaultResultCacheTTL {
+		t.Errorf("expected defaults, got size=%d ttl=%s", cache.size, cache.ttl)
+	}
+}
+
+func TestResultCacheConcurrentAccess(t *testing.T) {
+	cache := newResultCache(50, time.Minute)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				id := fmt.Sprintf("evt-%d-%d", w, i)
+				cache.put(EventAck{EventID: id, Success: true})
+				cache.get(id)
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if len(cache.entries) > 50 || len(cache.entries) != len(cache.order) {
+		t.Errorf("cache out of bounds: %d entries, %d ordered", len(cache.entries), len(cache.order))
+	}
+}
@@ -0,0 +1,66 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+)
+
+// Session state commands.
+//
+// Hibernate and wake commands arrive on different subjects and are handled
+// on different goroutines, so when a user toggles a session quickly a wake
+// can be handled before the hibernate sent ahead of it. A command that sets
+// spec.state records when it was issued in StateCommandTimeAnnotation, and
+// commands issued before that are stale and ignored: the session ends in
+// the state of the latest command whatever order the commands arrive in.
+// The session reconciler then converges the session's resources to
+// spec.state; the handlers don't scale them themselves.
+
+// applySessionState sets the state of a session to that of a command issued
+// at issued. It reports false if a later command already set the state.
+func (s *Subscriber) applySessionState(ctx context.Context, sessionID, state string, issued time.Time) (bool, error) {
+	if issued.IsZero() {
+		issued = time.Now()
+	}
+
+	applied := false
+	// The client reads from the cache, which may not have the other
+	// command's update yet; conflicts are retried with a fresh read
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		session := &streamv1alpha1.Session{}
+		if err := s.client.Get(ctx, types.NamespacedName{
+			Name:      sessionID,
+			Namespace: s.namespace,
+		}, session); err != nil {
+			return err
+		}
+		if staleStateCommand(session, issued) {
+			applied = false
+			return nil
+		}
+
+		session.Spec.State = state
+		delete(session.Annotations, streamv1alpha1.StateActorAnnotation)
+		if session.Annotations == nil {
+			session.Annotations = map[string]string{}
+		}
+		session.Annotations[streamv1alpha1.StateCommandTimeAnnotation] = issued.UTC().Format(time.RFC3339Nano)
+		if err := s.client.Update(ctx, session); err != nil {
+			return err
+		}
+		applied = true
+		return nil
+	})
+	return applied, err
+}
+
+// staleStateCommand reports whether a state command issued at issued is
+// older than the one that last set the session's state.
+func staleStateCommand(session *streamv1alpha1.Session, issued time.Time) bool {
+	last, err := time.Parse(time.RFC3339Nano, session.Annotations[streamv1alpha1.StateCommandTimeAnnotation])
+	return err == nil && issued.Before(last)
+}
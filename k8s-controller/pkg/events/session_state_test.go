@@ -0,0 +1,89 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	streamv1alpha1 "github.com/streamspace/streamspace/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestRapidStateFlipsConvergeToLatestCommand(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := streamv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	session := &streamv1alpha1.Session{
+		ObjectMeta: metav1.ObjectMeta{Name: "alice-firefox", Namespace: "streamspace"},
+		Spec:       streamv1alpha1.SessionSpec{User: "alice", Template: "firefox", State: "running"},
+	}
+	s := &Subscriber{
+		client:    fake.NewClientBuilder().WithScheme(scheme).WithObjects(session).Build(),
+		namespace: "streamspace",
+	}
+
+	// running → hibernated → running → hibernated → running, delivered out
+	// of order: commands on different subjects race each other
+	start := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	type command struct {
+		state  string
+		issued time.Time
+	}
+	flips := []command{
+		{"running", start.Add(4 * time.Millisecond)},
+		{"hibernated", start.Add(1 * time.Millisecond)},
+		{"hibernated", start.Add(3 * time.Millisecond)},
+		{"running", start.Add(2 * time.Millisecond)},
+	}
+	for _, c := range flips {
+		var data []byte
+		var err error
+		if c.state == "running" {
+			data, err = json.Marshal(SessionWakeEvent{SessionID: "alice-firefox", Timestamp: c.issued})
+		} else {
+			data, err = json.Marshal(SessionHibernateEvent{SessionID: "alice-firefox", Timestamp: c.issued})
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		handler := s.handleSessionWake
+		if c.state == "hibernated" {
+			handler = s.handleSessionHibernate
+		}
+		if err := handler(context.Background(), data); err != nil {
+			t.Fatalf("%s issued at %s: %v", c.state, c.issued, err)
+		}
+	}
+
+	got := &streamv1alpha1.Session{}
+	if err := s.client.Get(context.Background(), types.NamespacedName{Name: "alice-firefox", Namespace: "streamspace"}, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Spec.State != "running" {
+		t.Errorf("state = %s, want running (the latest command)", got.Spec.State)
+	}
+	if want := flips[0].issued.Format(time.RFC3339Nano); got.Annotations[streamv1alpha1.StateCommandTimeAnnotation] != want {
+		t.Errorf("command time = %s, want %s", got.Annotations[streamv1alpha1.StateCommandTimeAnnotation], want)
+	}
+}
+
+func TestStaleStateCommand(t *testing.T) {
+	issued := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	session := &streamv1alpha1.Session{}
+	if staleStateCommand(session, issued) {
+		t.Error("expected a command to apply to a session no command has set")
+	}
+
+	session.Annotations = map[string]string{streamv1alpha1.StateCommandTimeAnnotation: issued.Format(time.RFC3339Nano)}
+	if !staleStateCommand(session, issued.Add(-time.Millisecond)) {
+		t.Error("expected an earlier command to be stale")
+	}
+	if staleStateCommand(session, issued) {
+		t.Error("expected a redelivered command to apply again")
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"strings"
 	"time"
 
 	"github.com/nats-io/nats.go"
@@ -23,6 +24,10 @@ type Config struct {
 	URL      string
 	User     string
 	Password string
+
+	// ResultCacheTTL is how long completed event results are kept to answer
+	// redelivered events without re-executing them (default 15m).
+	ResultCacheTTL time.Duration
 }
 
 // Subscriber subscribes to NATS events and handles them.
@@ -34,6 +39,9 @@ type Subscriber struct {
 	controllerID string
 	platform     string
 	handlers     map[string]EventHandler
+	results      *resultCache
+	cancelled    *cancelledSessions
+	migrations   *SubjectMigrations
 }
 
 // EventHandler is a function that handles a specific event type.
@@ -97,6 +105,9 @@ func NewSubscriber(cfg Config, k8sClient client.Client, namespace, controllerID
 		controllerID: controllerID,
 		platform:     PlatformKubernetes,
 		handlers:     make(map[string]EventHandler),
+		results:      newResultCache(0, cfg.ResultCacheTTL),
+		cancelled:    newCancelledSessions(cfg.ResultCacheTTL),
+		migrations:   subjectMigrationsFromEnv(),
 	}
 
 	// Register default handlers
@@ -112,6 +123,7 @@ func (s *Subscriber) registerHandlers() {
 	s.handlers[SubjectSessionDelete] = s.handleSessionDelete
 	s.handlers[SubjectSessionHibernate] = s.handleSessionHibernate
 	s.handlers[SubjectSessionWake] = s.handleSessionWake
+	s.handlers[SubjectSessionBatch] = s.handleSessionBatchEvent
 
 	// Application events
 	s.handlers[SubjectAppInstall] = s.handleAppInstall
@@ -135,30 +147,29 @@ func (s *Subscriber) Start(ctx context.Context) error {
 	queueGroup := fmt.Sprintf("streamspace-%s-controllers", s.platform)
 
 	for subject := range s.handlers {
+		subject := subject
+		callback := func(msg *nats.Msg) { s.handleMessage(ctx, subject, msg) }
+
 		// Subscribe to platform-specific subject with queue group
+		// (and its other name while the subject is migrating)
 		platformSubject := fmt.Sprintf("%s.%s", subject, s.platform)
-
-		_, err := s.conn.QueueSubscribe(platformSubject, queueGroup, func(msg *nats.Msg) {
-			// Extract base subject from the platform-specific subject
-			baseSubject := subject
-
-			handler, ok := s.handlers[baseSubject]
-			if !ok {
-				log.Printf("No handler for subject: %s", baseSubject)
-				return
-			}
-
-			if err := handler(ctx, msg.Data); err != nil {
-				log.Printf("Error handling event %s: %v", baseSubject, err)
-			}
-		})
-		if err != nil {
+		if _, err := s.migrations.Subscribe(s.conn, platformSubject, queueGroup, callback); err != nil {
 			return fmt.Errorf("failed to subscribe to %s: %w", platformSubject, err)
 		}
-
-		log.Printf("Subscribed to NATS subject: %s (queue: %s)", platformSubject, queueGroup)
+		log.Printf("Subscribed to NATS subject: %s (queue: %s)",
+			strings.Join(s.migrations.SubscribeSubjects(platformSubject), ", "), queueGroup)
+
+		// Events the API routed to this controller because of its
+		// capabilities arrive on its own subject
+		controllerSubject := fmt.Sprintf("%s.%s", platformSubject, s.controllerID)
+		if _, err := s.migrations.Subscribe(s.conn, controllerSubject, "", callback); err != nil {
+			return fmt.Errorf("failed to subscribe to %s: %w", controllerSubject, err)
+		}
 	}
 
+	// Report health and capabilities so the API can route sessions
+	go s.runHeartbeat(ctx)
+
 	// Request sync from API to get all installed applications
 	if err := s.requestSync(); err != nil {
 		log.Printf("Warning: failed to request sync from API: %v", err)
@@ -172,6 +183,51 @@ func (s *Subscriber) Start(ctx context.Context) error {
 	return nil
 }
 
+// handleMessage runs the handler of an event received on subject.
+func (s *Subscriber) handleMessage(ctx context.Context, baseSubject string, msg *nats.Msg) {
+	handler, ok := s.handlers[baseSubject]
+	if !ok {
+		log.Printf("No handler for subject: %s", baseSubject)
+		return
+	}
+
+	var envelope struct {
+		EventID string `json:"event_id"`
+	}
+	_ = json.Unmarshal(msg.Data, &envelope)
+
+	// A redelivered event (its ack was lost) gets the original
+	// result instead of being executed again
+	if ack, done := s.results.get(envelope.EventID); done {
+		log.Printf("Event %s (%s) already completed, replaying result", envelope.EventID, baseSubject)
+		if msg.Reply != "" {
+			s.respond(msg, ack)
+		}
+		return
+	}
+
+	// A batch reports the result of each of its commands
+	var results []CommandResult
+	var err error
+	if baseSubject == SubjectSessionBatch {
+		results, err = s.handleSessionBatch(ctx, msg.Data)
+	} else {
+		err = handler(ctx, msg.Data)
+	}
+	if err != nil {
+		log.Printf("Error handling event %s: %v", baseSubject, err)
+	}
+
+	ack := s.newAck(envelope.EventID, err)
+	ack.Results = results
+	s.results.put(ack)
+
+	// Requests (e.g. session create with ack) wait for a reply
+	if msg.Reply != "" {
+		s.respond(msg, ack)
+	}
+}
+
 // requestSync publishes a sync request to the API to get all installed applications.
 func (s *Subscriber) requestSync() error {
 	event := ControllerSyncRequestEvent{
@@ -187,7 +243,31 @@ func (s *Subscriber) requestSync() error {
 	}
 
 	// Publish to generic subject (not platform-specific) so API receives it
-	return s.conn.Publish(SubjectControllerSyncRequest, data)
+	return s.migrations.Publish(s.conn, SubjectControllerSyncRequest, data)
+}
+
+// newAck builds the result of handling an event.
+func (s *Subscriber) newAck(eventID string, handlerErr error) EventAck {
+	ack := EventAck{
+		EventID:      eventID,
+		ControllerID: s.controllerID,
+		Success:      handlerErr == nil,
+	}
+	if handlerErr != nil {
+		ack.Error = handlerErr.Error()
+	}
+	return ack
+}
+
+// respond replies to a request with whether it was handled.
+func (s *Subscriber) respond(msg *nats.Msg, ack EventAck) {
+	data, err := json.Marshal(ack)
+	if err != nil {
+		return
+	}
+	if err := msg.Respond(data); err != nil {
+		log.Printf("Failed to acknowledge event %s: %v", ack.EventID, err)
+	}
 }
 
 // Close closes the NATS connection.
@@ -203,5 +283,5 @@ func (s *Subscriber) publishStatus(subject string, event interface{}) error {
 	if err != nil {
 		return err
 	}
-	return s.conn.Publish(subject, data)
+	return s.migrations.Publish(s.conn, subject, data)
 }
@@ -12,6 +12,7 @@ const (
 	SubjectSessionHibernate = "streamspace.session.hibernate"
 	SubjectSessionWake      = "streamspace.session.wake"
 	SubjectSessionStatus    = "streamspace.session.status"
+	SubjectSessionBatch     = "streamspace.session.batch"
 
 	SubjectAppInstall   = "streamspace.app.install"
 	SubjectAppUninstall = "streamspace.app.uninstall"
@@ -48,6 +49,18 @@ type SessionCreateEvent struct {
 	PersistentHome bool              `json:"persistent_home"`
 	IdleTimeout    string            `json:"idle_timeout"`
 	Metadata       map[string]string `json:"metadata,omitempty"`
+	// Parameters are the values for the template's parameters
+	Parameters map[string]string `json:"parameters,omitempty"`
+	// Priority is the session's priority class (empty = template default)
+	Priority string `json:"priority,omitempty"`
+	// MaxSessionDuration is the session's maximum lifetime (empty = template default)
+	MaxSessionDuration string `json:"max_session_duration,omitempty"`
+	// Tags are the session's tags
+	Tags []string `json:"tags,omitempty"`
+	// Labels are extra labels for the Session resource
+	Labels map[string]string `json:"labels,omitempty"`
+	// ForkFrom is the session whose home volume is forked for this one
+	ForkFrom string `json:"fork_from,omitempty"`
 }
 
 // SessionDeleteEvent is received when a session should be deleted.
@@ -58,6 +71,10 @@ type SessionDeleteEvent struct {
 	UserID    string    `json:"user_id"`
 	Platform  string    `json:"platform"`
 	Force     bool      `json:"force"`
+
+	// Cancel marks the delete of a session that is still starting: its
+	// create is aborted and a home PVC it just created is removed.
+	Cancel bool `json:"cancel,omitempty"`
 }
 
 // SessionHibernateEvent is received when a session should be hibernated.
@@ -78,6 +95,33 @@ type SessionWakeEvent struct {
 	Platform  string    `json:"platform"`
 }
 
+// SessionBatchEvent is received with several session commands to run in
+// one go; each is acknowledged in EventAck.Results.
+type SessionBatchEvent struct {
+	EventID   string           `json:"event_id"`
+	Timestamp time.Time        `json:"timestamp"`
+	Platform  string           `json:"platform"`
+	Commands  []SessionCommand `json:"commands"`
+}
+
+// SessionCommand is one command of a SessionBatchEvent. Its EventID is the
+// one it would have as an individual event.
+type SessionCommand struct {
+	EventID   string `json:"event_id"`
+	Action    string `json:"action"` // session.hibernate, session.wake or session.delete
+	SessionID string `json:"session_id"`
+	UserID    string `json:"user_id"`
+	Force     bool   `json:"force,omitempty"`
+}
+
+// CommandResult is the result of one command of a batch.
+type CommandResult struct {
+	EventID   string `json:"event_id"`
+	SessionID string `json:"session_id"`
+	Success   bool   `json:"success"`
+	Error     string `json:"error,omitempty"`
+}
+
 // SessionStatusEvent is published when session status changes.
 type SessionStatusEvent struct {
 	EventID       string        `json:"event_id"`
@@ -92,6 +136,17 @@ type SessionStatusEvent struct {
 	ControllerID  string        `json:"controller_id"`
 }
 
+// EventAck is the reply to an event received as a request (the sender
+// waits for a controller to acknowledge it).
+type EventAck struct {
+	EventID      string `json:"event_id"`
+	ControllerID string `json:"controller_id"`
+	Success      bool   `json:"success"`
+	Error        string `json:"error,omitempty"`
+	// Results are the per-command results of a SessionBatchEvent
+	Results []CommandResult `json:"results,omitempty"`
+}
+
 // AppInstallEvent is received when an application should be installed.
 type AppInstallEvent struct {
 	EventID           string    `json:"event_id"`
@@ -191,3 +246,28 @@ type ControllerSyncRequestEvent struct {
 	ControllerID string    `json:"controller_id"`
 	Platform     string    `json:"platform"`
 }
+
+// ControllerCapabilities is what the controller reports it can do, so the
+// API only dispatches sessions it can actually run.
+type ControllerCapabilities struct {
+	// Backend is the platform implementation
+	Backend string `json:"backend,omitempty"`
+	// Actions are the event actions handled, e.g. "session.create"
+	Actions []string `json:"actions,omitempty"`
+	// GPU is true if any node has allocatable GPUs
+	GPU bool `json:"gpu"`
+	// MaxResources is the largest session a single node can place
+	MaxResources ResourceSpec `json:"max_resources"`
+}
+
+// ControllerHeartbeatEvent is published periodically to report health and
+// capabilities to the API.
+type ControllerHeartbeatEvent struct {
+	ControllerID string                 `json:"controller_id"`
+	Platform     string                 `json:"platform"`
+	Timestamp    time.Time              `json:"timestamp"`
+	Status       string                 `json:"status"` // healthy, unhealthy
+	Version      string                 `json:"version"`
+	Capabilities ControllerCapabilities `json:"capabilities"`
+	ClusterInfo  map[string]interface{} `json:"cluster_info,omitempty"`
+}
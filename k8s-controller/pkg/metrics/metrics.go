@@ -1,6 +1,8 @@
 package metrics
 
 import (
+	"strconv"
+
 	"github.com/prometheus/client_golang/prometheus"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
@@ -52,6 +54,35 @@ var (
 		[]string{"namespace"},
 	)
 
+	// SessionReconcileErrors tracks failed reconciliations by error reason
+	SessionReconcileErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "streamspace_session_reconcile_errors_total",
+			Help: "Total number of failed session reconciliations by error reason",
+		},
+		[]string{"namespace", "reason"},
+	)
+
+	// SessionTimeToReady tracks how long sessions take to first become ready
+	SessionTimeToReady = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "streamspace_session_time_to_ready_seconds",
+			Help:    "Time from session creation until the session first became ready in seconds",
+			Buckets: []float64{1, 2, 5, 10, 20, 30, 60, 120, 300, 600}, // 1s to 10m
+		},
+		[]string{"namespace", "template", "warm_pool"},
+	)
+
+	// SessionStartupStage tracks where launch time goes
+	SessionStartupStage = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "streamspace_session_startup_stage_seconds",
+			Help:    "Duration of session startup stages (controller, scheduling, image_pull, readiness) in seconds",
+			Buckets: []float64{0.5, 1, 2, 5, 10, 20, 30, 60, 120, 300, 600},
+		},
+		[]string{"namespace", "stage"},
+	)
+
 	// TemplateValidations tracks template validation results
 	TemplateValidations = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
@@ -124,6 +155,9 @@ func init() {
 		SessionsByTemplate,
 		SessionReconciliations,
 		SessionReconciliationDuration,
+		SessionReconcileErrors,
+		SessionTimeToReady,
+		SessionStartupStage,
 		TemplateValidations,
 		HibernationEvents,
 		WakeEvents,
@@ -159,6 +193,21 @@ func ObserveReconciliationDuration(namespace string, duration float64) {
 	SessionReconciliationDuration.WithLabelValues(namespace).Observe(duration)
 }
 
+// RecordReconcileError records a failed reconciliation and its error reason
+func RecordReconcileError(namespace, reason string) {
+	SessionReconcileErrors.WithLabelValues(namespace, reason).Inc()
+}
+
+// ObserveTimeToReady records how long a session took to first become ready
+func ObserveTimeToReady(namespace, template string, warmPool bool, seconds float64) {
+	SessionTimeToReady.WithLabelValues(namespace, template, strconv.FormatBool(warmPool)).Observe(seconds)
+}
+
+// ObserveStartupStage records the duration of one session startup stage
+func ObserveStartupStage(namespace, stage string, seconds float64) {
+	SessionStartupStage.WithLabelValues(namespace, stage).Observe(seconds)
+}
+
 // RecordTemplateValidation records a template validation
 func RecordTemplateValidation(namespace, result string) {
 	TemplateValidations.WithLabelValues(namespace, result).Inc()
@@ -1,10 +1,18 @@
 import { useEffect, useRef, useCallback, useState, useMemo } from 'react';
 import { useUserStore } from '../store/userStore';
 
+/**
+ * Newest enterprise WebSocket envelope version this client understands.
+ * The server answers with the newest version both sides support.
+ */
+export const WEBSOCKET_PROTOCOL_VERSION = 2;
+
 export interface WebSocketMessage {
   type: string;
   timestamp: string;
   data: Record<string, any>;
+  /** Envelope version; absent on legacy (version 1) envelopes */
+  version?: number;
 }
 
 export type WebSocketMessageHandler = (message: WebSocketMessage) => void;
@@ -154,7 +162,7 @@ export function useEnterpriseWebSocket(
 
       // Include token as query parameter for WebSocket authentication
       // Browsers cannot send custom headers in WebSocket connections
-      return `${protocol}//${host}/api/v1/ws/enterprise?token=${encodeURIComponent(token)}`;
+      return `${protocol}//${host}/api/v1/ws/enterprise?token=${encodeURIComponent(token)}&version=${WEBSOCKET_PROTOCOL_VERSION}`;
     } catch (error) {
       console.error('[useEnterpriseWebSocket] Error building URL:', error);
       return '';
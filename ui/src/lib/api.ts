@@ -55,6 +55,17 @@ export interface SessionStatus {
     status: string;
     message: string;
   }>;
+  // Streaming features the session runs with (absent from older controllers)
+  streamingFeatures?: StreamingFeatures;
+}
+
+export interface StreamingFeatures {
+  clipboard: boolean;
+  audio: boolean;
+  fileTransfer: boolean;
+  maxResolution?: string;
+  // Clipboard and file transfer were disabled because the template is untrusted
+  restricted?: boolean;
 }
 
 export interface Template {
@@ -529,6 +540,7 @@ export interface MFAVerifyRequest {
 
 export interface BackupCodesResponse {
   backup_codes: string[];
+  backup_codes_count?: number;
   message: string;
 }
 
@@ -100,6 +100,7 @@ describe('SecuritySettings', () => {
       const mockVerifyMFA = vi.spyOn(api, 'verifyMFASetup').mockResolvedValue({
         verified: true,
         backup_codes: ['ABC123-DEF456', 'GHI789-JKL012'],
+        backup_codes_count: 2,
       });
 
       renderWithRouter(<SecuritySettings />);
@@ -129,7 +130,7 @@ describe('SecuritySettings', () => {
 
       // Step 4: Backup codes
       await waitFor(() => {
-        expect(screen.getByText(/Save these backup codes/i)).toBeInTheDocument();
+        expect(screen.getByText(/Save these 2 backup codes/i)).toBeInTheDocument();
         expect(screen.getByText('ABC123-DEF456')).toBeInTheDocument();
         expect(screen.getByText('GHI789-JKL012')).toBeInTheDocument();
       });
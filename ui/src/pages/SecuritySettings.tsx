@@ -575,7 +575,7 @@ function SecuritySettingsContent() {
             {mfaStep === 2 && (
               <Box>
                 <Alert severity="warning" sx={{ mb: 2 }}>
-                  Save these backup codes in a safe place. Each code can only be used once.
+                  Save these {backupCodes.length} backup codes in a safe place. Each code can only be used once.
                 </Alert>
                 <Paper variant="outlined" sx={{ p: 2, mb: 2 }}>
                   <Grid container spacing={1}>
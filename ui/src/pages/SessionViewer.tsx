@@ -428,7 +428,7 @@ export default function SessionViewer() {
             display: 'block',
           }}
           title={`Session: ${session.name}`}
-          allow="clipboard-read; clipboard-write"
+          allow={session.status.streamingFeatures?.clipboard === false ? undefined : 'clipboard-read; clipboard-write'}
           sandbox="allow-scripts allow-same-origin allow-forms allow-popups allow-modals"
         />
       </Box>